@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nshekhawat/lighttr/internal/authprofile"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runAuth saves, lists, or deletes auth profiles, the credentials requests
+// send by name with --auth-profile, e.g.
+//
+//	lighttr auth save github --auth-type bearer --auth-token ghp_abc
+//	lighttr auth save billing --auth-type mtls --auth-cert client.p12 --auth-password secret
+//	lighttr auth list
+//	lighttr auth delete github
+func runAuth(args []string) int {
+	fs := flag.NewFlagSet("auth", flag.ContinueOnError)
+	var auth request.AuthData
+	authFlags(fs, &auth)
+
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	// Allow flags after the profile name
+	var name string
+	if (action == "save" || action == "delete") && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	switch action {
+	case "list":
+		profiles, err := authprofile.List()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if len(profiles) == 0 {
+			fmt.Println("No auth profiles are saved; save one with lighttr auth save <name> --auth-type <type>")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTYPE\tDETAILS\tSTORAGE")
+		for _, profile := range profiles {
+			storage := "file"
+			if profile.Keyring {
+				storage = "keyring"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", profile.Name, profile.Auth.Type, profile.Details(), storage)
+		}
+		tw.Flush()
+		return 0
+
+	case "save":
+		if name == "" || fs.NArg() != 0 {
+			break
+		}
+		// Files are found from wherever the profile is used
+		for _, path := range []*string{&auth.CertFile, &auth.KeyFile, &auth.JWTKeyFile, &auth.CAFile} {
+			if *path == "" {
+				continue
+			}
+			abs, err := filepath.Abs(*path)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return 1
+			}
+			*path = abs
+		}
+		// Check the settings as a request would, before they are used
+		check := request.RequestData{Method: "GET", URL: "https://example.com", Auth: auth}
+		if err := check.Validate(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		profile, err := authprofile.Save(name, auth)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if !profile.Keyring && (auth.Password != "" || auth.Token != "" || auth.APIKey != "" || auth.AzureClientSecret != "") {
+			if cfg, err := config.Load(); err == nil && cfg.TokenStorage != "file" {
+				fmt.Fprintln(os.Stderr, "Warning: the system keyring is not available, so the secrets are saved in auth.json")
+			}
+		}
+		fmt.Printf("Saved auth profile %s; use --auth-profile %s to send it\n", name, name)
+		return 0
+
+	case "delete":
+		if name == "" || fs.NArg() != 0 {
+			break
+		}
+		if err := authprofile.Delete(name); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Deleted auth profile %s\n", name)
+		return 0
+	}
+
+	fmt.Println("Usage: lighttr auth [list|save <name> --auth-type <type> [auth flags]|delete <name>]")
+	return 2
+}
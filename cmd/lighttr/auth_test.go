@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/authprofile"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunAuth(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// Keep the test's secrets out of the system keyring
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runAuth(nil) })
+	if exitCode != 0 || !strings.Contains(output, "No auth profiles are saved") {
+		t.Errorf("Expected no profiles, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() {
+		exitCode = runAuth([]string{"save", "billing", "--auth-type", "basic", "--auth-username", "jane", "--auth-password", "s3cret"})
+	})
+	if exitCode != 0 || !strings.Contains(output, "Saved auth profile billing; use --auth-profile billing") {
+		t.Errorf("Expected the profile to be saved, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() {
+		exitCode = runAuth([]string{"save", "ci", "--auth-type", "jwt", "--auth-jwt-key", "signing.key", "--auth-jwt-claims", "{}"})
+	})
+	if exitCode != 1 || !strings.Contains(output, "JWT key file does not exist") {
+		t.Errorf("Expected the settings to be checked before saving, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() {
+		exitCode = runAuth([]string{"save", "internal", "--auth-type", "session", "--auth-username", "jane", "--auth-password", "s3cret",
+			"--auth-session-login", "https://internal.example.com/login", "--auth-session-extract", "cookie:SESSIONID"})
+	})
+	if exitCode != 0 {
+		t.Errorf("Expected the session profile to be saved, got %d:\n%s", exitCode, output)
+	}
+	if auth, err := authprofile.Resolve("internal"); err != nil || auth.SessionLoginURL != "https://internal.example.com/login" || auth.SessionExtract != "cookie:SESSIONID" || auth.Password != "s3cret" {
+		t.Errorf("Expected the session login to be saved, got %+v, %v", auth, err)
+	}
+	output = captureStdout(func() { exitCode = runAuth([]string{"save", "empty"}) })
+	if exitCode != 1 || !strings.Contains(output, "an auth profile needs an auth type") {
+		t.Errorf("Expected a profile without auth to be refused, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runAuth([]string{"list"}) })
+	if exitCode != 0 || !strings.Contains(output, "NAME") || !strings.Contains(output, "billing") || !strings.Contains(output, "jane") || !strings.Contains(output, "file") {
+		t.Errorf("Expected the profile to be listed, got %d:\n%s", exitCode, output)
+	}
+	if strings.Contains(output, "s3cret") {
+		t.Errorf("Expected the password not to be listed, got:\n%s", output)
+	}
+
+	auth, err := authprofile.Resolve("billing")
+	if err != nil || auth.Type != request.BasicAuth || auth.Password != "s3cret" {
+		t.Errorf("Resolve() = %+v, %v", auth, err)
+	}
+
+	output = captureStdout(func() { exitCode = runAuth([]string{"delete", "billing"}) })
+	if exitCode != 0 || !strings.Contains(output, "Deleted auth profile billing") {
+		t.Errorf("Expected the profile to be deleted, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() { exitCode = runAuth([]string{"delete", "billing"}) })
+	if exitCode != 1 || !strings.Contains(output, "auth profile not found: billing") {
+		t.Errorf("Expected a missing profile to be an error, got %d:\n%s", exitCode, output)
+	}
+	for _, args := range [][]string{{"save"}, {"delete"}, {"rename", "billing"}} {
+		if code := runAuth(args); code != 2 {
+			t.Errorf("Expected exit code 2 for %v, got %d", args, code)
+		}
+	}
+}
+
+func TestRunAuth_AbsolutePaths(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, name := range []string{"client.pem", "client.key"} {
+		if err := os.WriteFile(name, []byte("PEM"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var exitCode int
+	output := captureStdout(func() {
+		exitCode = runAuth([]string{"save", "orders", "--auth-type", "mtls", "--auth-cert", "client.pem", "--auth-key", "client.key"})
+	})
+	if exitCode != 0 {
+		t.Fatalf("Expected the profile to be saved, got %d:\n%s", exitCode, output)
+	}
+	auth, err := authprofile.Resolve("orders")
+	if err != nil || auth.CertFile != filepath.Join(dir, "client.pem") || auth.KeyFile != filepath.Join(dir, "client.key") {
+		t.Errorf("Expected the files to be saved by absolute path, got %+v, %v", auth, err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/azure"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runAzure logs in to an Entra ID application with the device code flow,
+// caching the token azure auth sends without a client secret, e.g.
+//
+//	lighttr azure login --tenant contoso.onmicrosoft.com --client-id 11111111-2222-3333-4444-555555555555
+//	lighttr azure login --client-id 11111111-2222-3333-4444-555555555555 --scope api://orders/.default
+func runAzure(args []string) int {
+	fs := flag.NewFlagSet("azure", flag.ContinueOnError)
+	tenant := fs.String("tenant", "", "Tenant ID or domain (default: AZURE_TENANT_ID, or any work or school account)")
+	clientID := fs.String("client-id", "", "Application (client) ID of a public client (default: AZURE_CLIENT_ID)")
+	scope := fs.String("scope", "", "Scope to consent to (default: "+azure.DefaultScope+")")
+
+	if len(args) == 0 || args[0] != "login" {
+		fmt.Println("Usage: lighttr azure login --client-id <id> [--tenant <tenant>] [--scope <scope>]")
+		return 2
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Println("Usage: lighttr azure login --client-id <id> [--tenant <tenant>] [--scope <scope>]")
+		return 2
+	}
+
+	// The device code flow has no use for a client secret
+	app := azure.AppFor(request.AuthData{AzureTenant: *tenant, AzureClientID: *clientID, AzureScope: *scope})
+	app.ClientSecret = ""
+	cached, err := azure.Login(context.Background(), app, os.Stdout)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if !cached.Keyring {
+		if cfg, err := config.Load(); err == nil && cfg.TokenStorage != "file" {
+			fmt.Fprintln(os.Stderr, "Warning: the system keyring is not available, so the token is cached in tokens.json")
+		}
+	}
+
+	fmt.Printf("Logged in to client %s", app.ClientID)
+	if !cached.Expiry.IsZero() {
+		fmt.Printf(": the token expires in %v", time.Until(cached.Expiry).Round(time.Second))
+		if cached.Refreshable {
+			fmt.Print(" and is refreshed automatically")
+		}
+	}
+	use := "--auth-type azure"
+	if *tenant != "" {
+		use += " --auth-azure-tenant " + *tenant
+	}
+	if *clientID != "" {
+		use += " --auth-azure-client-id " + *clientID
+	}
+	fmt.Printf("\nUse %s to send it\n", use)
+	return 0
+}
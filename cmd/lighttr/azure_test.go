@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunAzure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AZURE_CLIENT_ID", "")
+
+	if code := runAzure(nil); code != 2 {
+		t.Errorf("Expected exit code 2 without an action, got %d", code)
+	}
+	if code := runAzure([]string{"login", "extra"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an extra argument, got %d", code)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runAzure([]string{"login"}) })
+	if exitCode != 1 || !strings.Contains(output, "needs a client ID") {
+		t.Errorf("Expected a login without a client ID to fail, got %d:\n%s", exitCode, output)
+	}
+}
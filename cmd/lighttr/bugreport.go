@@ -0,0 +1,431 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/importer"
+	"github.com/nshekhawat/lighttr/internal/lint"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// masked replaces credentials in bug reports
+const masked = "********"
+
+// maxReportBody bounds the response body included in a bug report
+const maxReportBody = 64 << 10
+
+// bugReportListSize is how many of the latest requests lighttr bugreport
+// lists
+const bugReportListSize = 20
+
+// runBugReport writes a report of a request in history for the owner of
+// the API, with credentials masked, e.g.
+//
+//	lighttr bugreport
+//	lighttr bugreport 42
+//	lighttr bugreport 42 --resend --format zip --output report.zip
+//
+// Without an ID it lists the latest requests with their IDs, their
+// positions in history.
+func runBugReport(args []string) int {
+	fs := flag.NewFlagSet("bugreport", flag.ContinueOnError)
+	format := fs.String("format", "md", "Report format: md (markdown) or zip (the report with the curl command and response body as files)")
+	output := fs.String("output", "", "File to write instead of stdout (zip defaults to lighttr-bugreport-<id>.zip)")
+	resend := fs.Bool("resend", false, "Send the request again to include the response body, which history does not keep")
+
+	// Allow flags after the ID
+	var idArg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		idArg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "md" && *format != "zip" {
+		fmt.Printf("Error: unsupported format: %s\n", *format)
+		return 2
+	}
+
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	entries := requestHistory.GetAll()
+
+	if idArg == "" {
+		if len(entries) == 0 {
+			fmt.Println("No requests in history")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTIME\tREQUEST\tSTATUS")
+		for i := max(0, len(entries)-bugReportListSize); i < len(entries); i++ {
+			entry := entries[i]
+			fmt.Fprintf(tw, "%d\t%s\t%s %s\t%s\n", i+1, entryTime(entry), entry.Method, entry.ResolvedURL(), entryStatus(entry))
+		}
+		tw.Flush()
+		fmt.Println("\nWrite a report with: lighttr bugreport <id>")
+		return 0
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(idArg, "#"))
+	if err != nil || id < 1 || id > len(entries) {
+		fmt.Printf("Error: no request %q in history; run lighttr bugreport to list them\n", idArg)
+		return 1
+	}
+	entry := entries[id-1]
+
+	var resp *request.ResponseData
+	if *resend {
+		req := entry.RequestData
+		if resp, err = req.Execute(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	info, err := buildVersionInfo()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	report := newBugReport(id, entry, resp, info)
+
+	if *format == "md" {
+		if *output == "" {
+			fmt.Print(report.markdown())
+			return 0
+		}
+		if err := os.WriteFile(*output, []byte(report.markdown()), 0644); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	} else {
+		if *output == "" {
+			*output = fmt.Sprintf("lighttr-bugreport-%d.zip", id)
+		}
+		if err := report.writeZip(*output); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Printf("Wrote %s\n", *output)
+	return 0
+}
+
+// entryStatus describes the response recorded for a history entry
+func entryStatus(entry history.Entry) string {
+	switch {
+	case entry.Response == nil:
+		return "-"
+	case entry.Response.Error != "":
+		return "error"
+	}
+	return strconv.Itoa(entry.Response.StatusCode)
+}
+
+// bugReport is a request from history with its response, sanitized
+type bugReport struct {
+	id      int
+	entry   history.Entry
+	curl    string
+	resp    *request.ResponseData
+	info    *versionInfo
+	headers map[string]string
+	body    string
+}
+
+// newBugReport sanitizes the entry, and the response it was sent again
+// with when resp is not nil
+func newBugReport(id int, entry history.Entry, resp *request.ResponseData, info *versionInfo) *bugReport {
+	req := sanitizeRequest(entry.RequestData)
+	report := &bugReport{id: id, entry: entry, curl: importer.CurlCommand(&req), resp: resp, info: info}
+	report.entry.RequestData = req
+
+	headers := map[string]string(nil)
+	switch {
+	case resp != nil:
+		headers = resp.Headers
+	case entry.Response != nil:
+		headers = entry.Response.Headers
+	}
+	if headers != nil {
+		report.headers = make(map[string]string, len(headers))
+		for name, value := range headers {
+			report.headers[name] = maskValue(name, value)
+		}
+	}
+	if resp != nil && !resp.Binary {
+		body := resp.Body
+		if len(body) > maxReportBody {
+			body = body[:maxReportBody]
+		}
+		report.body = maskBody(body, resp.Headers["Content-Type"])
+	}
+	return report
+}
+
+// markdown renders the report for an issue tracker
+func (b *bugReport) markdown() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "# %s %s\n\n", b.entry.Method, b.entry.ResolvedURL())
+	if !b.entry.Time.IsZero() {
+		fmt.Fprintf(&s, "Sent %s with lighttr %s.\n\n", b.entry.Time.UTC().Format(time.DateTime+" MST"), b.info.Version)
+	}
+
+	s.WriteString("## Request\n\n```sh\n" + b.curl + "```\n\n")
+	s.WriteString("Credentials are masked as " + masked + ".\n\n")
+
+	s.WriteString("## Response\n\n")
+	summary := b.entry.Response
+	switch {
+	case b.resp != nil && b.resp.Error != "":
+		fmt.Fprintf(&s, "Sent again %s: %s\n\n", time.Now().UTC().Format(time.DateTime+" MST"), b.resp.Error)
+	case b.resp != nil:
+		fmt.Fprintf(&s, "Sent again %s.\n\n", time.Now().UTC().Format(time.DateTime+" MST"))
+		fmt.Fprintf(&s, "- Status: %d %s\n", b.resp.StatusCode, http.StatusText(b.resp.StatusCode))
+		fmt.Fprintf(&s, "- Time: %v\n", b.resp.ResponseTime.Round(time.Millisecond))
+		if b.resp.Protocol != "" {
+			fmt.Fprintf(&s, "- Protocol: %s\n", b.resp.Protocol)
+		}
+		fmt.Fprintf(&s, "- Body: %d bytes (%s)\n", len(b.resp.Body), history.HashBody(b.resp.Body))
+	case summary == nil:
+		s.WriteString("No response was recorded.\n")
+	case summary.Error != "":
+		fmt.Fprintf(&s, "Error: %s\n", summary.Error)
+	default:
+		fmt.Fprintf(&s, "- Status: %d %s\n", summary.StatusCode, http.StatusText(summary.StatusCode))
+		if summary.ResponseTime > 0 {
+			fmt.Fprintf(&s, "- Time: %v\n", summary.ResponseTime.Round(time.Millisecond))
+		}
+		if summary.Protocol != "" {
+			fmt.Fprintf(&s, "- Protocol: %s\n", summary.Protocol)
+		}
+		fmt.Fprintf(&s, "- Body: %d bytes (%s)\n", summary.BodySize, summary.BodyHash)
+	}
+
+	if len(b.headers) > 0 {
+		s.WriteString("\n```http\n")
+		names := make([]string, 0, len(b.headers))
+		for name := range b.headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&s, "%s: %s\n", name, b.headers[name])
+		}
+		s.WriteString("```\n")
+	}
+	switch {
+	case b.resp != nil && b.resp.Binary:
+		fmt.Fprintf(&s, "\nThe body is binary and not included.\n")
+	case b.body != "":
+		s.WriteString("\n```\n" + b.body)
+		if !strings.HasSuffix(b.body, "\n") {
+			s.WriteString("\n")
+		}
+		s.WriteString("```\n")
+		if len(b.resp.Body) > maxReportBody {
+			fmt.Fprintf(&s, "\nThe body is cut off after %d KiB.\n", maxReportBody>>10)
+		}
+	}
+
+	var timing *request.Timing
+	if b.resp != nil {
+		timing = b.resp.Timing
+	} else if summary != nil {
+		timing = summary.Timing
+	}
+	if timing != nil {
+		s.WriteString("\n## Timings\n\n| Phase | Duration |\n| --- | --- |\n")
+		for _, phase := range timing.Phases() {
+			fmt.Fprintf(&s, "| %s | %v |\n", phase.Name, phase.Duration.Round(time.Microsecond))
+		}
+		if timing.Reused {
+			s.WriteString("\nThe connection was reused.\n")
+		}
+	}
+
+	s.WriteString("\n## Environment\n\n")
+	fmt.Fprintf(&s, "- lighttr: %s", b.info.Version)
+	if b.info.Commit != "" {
+		fmt.Fprintf(&s, " (%s)", b.info.Commit)
+	}
+	fmt.Fprintf(&s, "\n- Go: %s\n- Platform: %s\n", b.info.GoVersion, b.info.Platform)
+	if b.entry.Proxy != nil && b.entry.Proxy.URL != "" {
+		fmt.Fprintf(&s, "- Proxy: %s\n", b.entry.Proxy.URL)
+	} else if proxy := proxyFromEnvironment(); proxy != "" {
+		fmt.Fprintf(&s, "- Proxy: %s (from the environment)\n", proxy)
+	}
+	if b.resp != nil && b.resp.RemoteAddr != "" {
+		fmt.Fprintf(&s, "- Server address: %s\n", b.resp.RemoteAddr)
+	}
+	return s.String()
+}
+
+// writeZip writes the report with the curl command and the response body
+// as files of their own
+func (b *bugReport) writeZip(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := zip.NewWriter(file)
+	files := [][2]string{{"report.md", b.markdown()}, {"request.sh", "#!/bin/sh\n" + b.curl}}
+	if b.body != "" {
+		files = append(files, [2]string{"response-body.txt", b.body})
+	}
+	for _, f := range files {
+		entry, err := w.Create(f[0])
+		if err == nil {
+			_, err = entry.Write([]byte(f[1]))
+		}
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return file.Close()
+}
+
+// sanitizeRequest returns a copy of the request with its credentials
+// masked: auth secrets, the values of headers and parameters whose names
+// suggest credentials, such as Authorization and api_key, and tokens of
+// well-known formats anywhere else
+func sanitizeRequest(req request.RequestData) request.RequestData {
+	maskField := func(value *string) {
+		if *value != "" {
+			*value = masked
+		}
+	}
+	maskField(&req.Auth.Password)
+	maskField(&req.Auth.Token)
+	maskField(&req.Auth.APIKey)
+	maskField(&req.Auth.AzureClientSecret)
+	if req.Proxy != nil {
+		proxy := *req.Proxy
+		maskField(&proxy.Password)
+		proxy.URL = maskURL(proxy.URL)
+		req.Proxy = &proxy
+	}
+	req.URL = maskURL(req.URL)
+
+	maskMap := func(values map[string]string) map[string]string {
+		if values == nil {
+			return nil
+		}
+		sanitized := make(map[string]string, len(values))
+		for name, value := range values {
+			sanitized[name] = maskValue(name, value)
+		}
+		return sanitized
+	}
+	req.Headers = maskMap(req.Headers)
+	req.DefaultHeaders = maskMap(req.DefaultHeaders)
+	req.DisabledHeaders = maskMap(req.DisabledHeaders)
+	req.QueryParams = maskMap(req.QueryParams)
+	req.PathParams = maskMap(req.PathParams)
+	req.FormParams = maskMap(req.FormParams)
+	if req.Multipart != nil {
+		parts := make([]request.FormPart, len(req.Multipart))
+		for i, part := range req.Multipart {
+			part.Value = maskValue(part.Name, part.Value)
+			parts[i] = part
+		}
+		req.Multipart = parts
+	}
+	req.Body = maskBody(req.Body, req.EffectiveHeaders()["Content-Type"])
+	return req
+}
+
+// maskValue masks the value of a header or parameter whose name suggests a
+// credential, keeping the auth scheme of Authorization headers
+func maskValue(name, value string) string {
+	if !lint.SensitiveName(name) {
+		return lint.MaskSecrets(value)
+	}
+	if value == "" {
+		return value
+	}
+	if scheme, _, ok := strings.Cut(value, " "); ok && strings.HasSuffix(strings.ToLower(name), "authorization") {
+		return scheme + " " + masked
+	}
+	return masked
+}
+
+// jsonStringField matches a JSON member with a string value
+var jsonStringField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// maskBody masks the credentials of JSON and urlencoded bodies by the
+// names of their fields, and tokens of well-known formats in any body
+func maskBody(body, contentType string) string {
+	if body == "" {
+		return body
+	}
+	body = jsonStringField.ReplaceAllStringFunc(body, func(field string) string {
+		parts := jsonStringField.FindStringSubmatch(field)
+		if !lint.SensitiveName(parts[1]) {
+			return field
+		}
+		return `"` + parts[1] + `"` + parts[2] + `"` + masked + `"`
+	})
+	if strings.HasPrefix(contentType, request.FormContentType) {
+		if values, err := url.ParseQuery(body); err == nil {
+			pairs := strings.Split(body, "&")
+			for i, pair := range pairs {
+				name, _, _ := strings.Cut(pair, "=")
+				if key, err := url.QueryUnescape(name); err == nil && lint.SensitiveName(key) && values.Get(key) != "" {
+					pairs[i] = name + "=" + masked
+				}
+			}
+			body = strings.Join(pairs, "&")
+		}
+	}
+	return lint.MaskSecrets(body)
+}
+
+// maskURL masks the password of credentials in a URL
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), masked)
+	}
+	return u.String()
+}
+
+// proxyFromEnvironment returns the proxy set in the environment, with its
+// credentials masked
+func proxyFromEnvironment() string {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if value := os.Getenv(name); value != "" {
+			return maskURL(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunBugReport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "boom", "access_token": "leaked"}`))
+	}))
+	defer server.Close()
+
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		t.Fatalf("HistoryPath() error = %v", err)
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	req := request.NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL + "/orders"
+	req.Headers["Content-Type"] = "application/json"
+	req.Headers["X-Api-Key"] = "k-123"
+	req.QueryParams["token"] = "q-456"
+	req.Body = `{"item": 1, "password": "hunter2"}`
+	req.Auth = request.AuthData{Type: request.BasicAuth, Username: "jane", Password: "p-789"}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := requestHistory.AddWithResponse(*req, resp); err != nil {
+		t.Fatalf("AddWithResponse() error = %v", err)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runBugReport(nil) })
+	if exitCode != 0 || !strings.Contains(output, "1 ") || !strings.Contains(output, "POST "+server.URL+"/orders") || !strings.Contains(output, "500") {
+		t.Errorf("Expected the request to be listed, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runBugReport([]string{"1"}) })
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d:\n%s", exitCode, output)
+	}
+	for _, want := range []string{"# POST " + server.URL + "/orders", "curl \\", "-u 'jane:********'", "-H 'X-Api-Key: ********'",
+		"token=********", `"password": "********"`, "- Status: 500 Internal Server Error",
+		"Set-Cookie: ********", "| Time to First Byte |", "- lighttr: "} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the report:\n%s", want, output)
+		}
+	}
+	for _, secret := range []string{"k-123", "q-456", "hunter2", "p-789", "abc123"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Expected %q to be masked:\n%s", secret, output)
+		}
+	}
+
+	// Sent again, the body is included with its credentials masked
+	archive := filepath.Join(t.TempDir(), "report.zip")
+	output = captureStdout(func() { exitCode = runBugReport([]string{"1", "--resend", "--format", "zip", "--output", archive}) })
+	if exitCode != 0 || !strings.Contains(output, "Wrote "+archive) {
+		t.Fatalf("Expected the archive to be written, got %d:\n%s", exitCode, output)
+	}
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	defer reader.Close()
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+		if f.Name == "response-body.txt" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			body, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(body) != `{"error": "boom", "access_token": "********"}` {
+				t.Errorf("Unexpected response body %q", body)
+			}
+		}
+	}
+	if strings.Join(names, ",") != "report.md,request.sh,response-body.txt" {
+		t.Errorf("Unexpected archive files %v", names)
+	}
+
+	output = captureStdout(func() { exitCode = runBugReport([]string{"7"}) })
+	if exitCode != 1 || !strings.Contains(output, `no request "7" in history`) {
+		t.Errorf("Expected an unknown ID to be reported, got %d:\n%s", exitCode, output)
+	}
+	if code := runBugReport([]string{"1", "--format", "pdf"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown format, got %d", code)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+)
+
+// runCollections lists the saved collections, or the requests of one with
+// their owner, ticket, and when a run last passed their assertions, and
+// sets the owner and ticket of a request, e.g.
+//
+//	lighttr collections
+//	lighttr collections users
+//	lighttr collections users/create --owner payments-team --ticket https://jira.example.com/browse/PAY-12
+func runCollections(args []string) int {
+	fs := flag.NewFlagSet("collections", flag.ContinueOnError)
+	owner := fs.String("owner", "", "Set the owner of the request, e.g. a person or team")
+	ticket := fs.String("ticket", "", "Set the URL of the ticket or document the request relates to")
+
+	// Allow flags after the collection
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	editing := false
+	fs.Visit(func(*flag.Flag) { editing = true })
+	if fs.NArg() != 0 || (editing && !strings.Contains(name, "/")) {
+		fmt.Println("Usage: lighttr collections [<collection> | <collection>/<request> [--owner <owner>] [--ticket <url>]]")
+		return 2
+	}
+
+	collections, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	now := time.Now()
+
+	if name == "" {
+		names, err := collections.List()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if len(names) == 0 {
+			fmt.Println("No collections are saved")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tREQUESTS\tSTALE")
+		for _, name := range names {
+			c, err := collections.Load(name)
+			if err != nil {
+				fmt.Fprintf(tw, "%s\t-\t%v\n", name, err)
+				continue
+			}
+			stale := 0
+			for i := range c.Requests {
+				if c.Requests[i].Stale(now) {
+					stale++
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", name, len(c.Requests), stale)
+		}
+		tw.Flush()
+		return 0
+	}
+
+	collectionName, requestName, _ := strings.Cut(name, "/")
+	c, err := collections.Load(collectionName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if requestName != "" {
+		saved, ok := c.Find(requestName)
+		if !ok {
+			fmt.Printf("Error: request %q not found in collection %s\n", requestName, collectionName)
+			return 1
+		}
+		if editing {
+			fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "owner":
+					saved.Owner = strings.TrimSpace(*owner)
+				case "ticket":
+					saved.Ticket = strings.TrimSpace(*ticket)
+				}
+			})
+			if saved.Ticket != "" {
+				if u, err := url.Parse(saved.Ticket); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+					fmt.Printf("Error: invalid ticket URL %q\n", saved.Ticket)
+					return 1
+				}
+			}
+			if err := collections.Save(c); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return 1
+			}
+		}
+		printRequestMetadata(saved, now)
+		return 0
+	}
+
+	if len(c.Requests) == 0 {
+		fmt.Printf("Collection %s has no requests\n", c.Name)
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REQUEST\tMETHOD\tOWNER\tTICKET\tLAST VERIFIED")
+	for i := range c.Requests {
+		saved := &c.Requests[i]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", saved.Name, saved.Request.Method, orNone(saved.Owner), orNone(saved.Ticket), lastVerified(saved, now))
+	}
+	tw.Flush()
+	return 0
+}
+
+// printRequestMetadata prints the metadata of a saved request
+func printRequestMetadata(saved *collection.SavedRequest, now time.Time) {
+	fmt.Printf("Request:        %s %s\n", saved.Request.Method, saved.Request.URL)
+	fmt.Printf("Owner:          %s\n", orNone(saved.Owner))
+	fmt.Printf("Ticket:         %s\n", orNone(saved.Ticket))
+	fmt.Printf("Last verified:  %s\n", lastVerified(saved, now))
+}
+
+// lastVerified describes when a run last passed the request's assertions,
+// flagging requests that have gone stale
+func lastVerified(saved *collection.SavedRequest, now time.Time) string {
+	var s string
+	switch {
+	case len(saved.Asserts) == 0:
+		return "no assertions"
+	case saved.LastVerified.IsZero():
+		s = "never"
+	default:
+		s = fmt.Sprintf("%s (%dd ago)", saved.LastVerified.Local().Format("2006-01-02 15:04"), int(now.Sub(saved.LastVerified).Hours()/24))
+	}
+	if saved.Stale(now) {
+		s += " STALE"
+	}
+	return s
+}
+
+// orNone returns s, or "-" when it is empty
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunCollections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	collections, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	c := &collection.Collection{Name: "users", Requests: []collection.SavedRequest{
+		{Name: "create", Request: request.RequestData{Method: "POST", URL: server.URL}, Asserts: []assertion.Assertion{{Query: "status", Predicate: "==", Value: "200"}}},
+		{Name: "list", Request: request.RequestData{Method: "GET", URL: server.URL}},
+	}}
+	if err := collections.Save(c); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var code int
+	output := captureStdout(func() { code = runCollections(nil) })
+	if code != 0 || !strings.Contains(output, "users") || !strings.Contains(output, "STALE") {
+		t.Errorf("Expected the collections to be listed, got %d:\n%s", code, output)
+	}
+
+	output = captureStdout(func() {
+		code = runCollections([]string{"users/create", "--owner", "payments-team", "--ticket", "https://jira.example.com/browse/PAY-12"})
+	})
+	if code != 0 || !strings.Contains(output, "payments-team") || !strings.Contains(output, "never STALE") {
+		t.Errorf("Expected the owner and ticket to be set, got %d:\n%s", code, output)
+	}
+	saved, err := collections.Get("users/create")
+	if err != nil || saved.Owner != "payments-team" || saved.Ticket != "https://jira.example.com/browse/PAY-12" {
+		t.Errorf("Expected the owner and ticket to be saved, got %+v, %v", saved, err)
+	}
+
+	captureStdout(func() { code = runCollections([]string{"users/create", "--ticket", "PAY-12"}) })
+	if code != 1 {
+		t.Errorf("Expected a ticket that is not a URL to be refused, got %d", code)
+	}
+	captureStdout(func() { code = runCollections([]string{"users", "--owner", "me"}) })
+	if code != 2 {
+		t.Errorf("Expected usage for --owner without a request, got %d", code)
+	}
+
+	// A run that passes the assertions verifies the request
+	captureStdout(func() { code = runCollection([]string{"users"}) })
+	if code != 0 {
+		t.Fatalf("Expected the run to pass, got %d", code)
+	}
+	if saved, _ := collections.Get("users/create"); saved.LastVerified.IsZero() || saved.Owner != "payments-team" {
+		t.Errorf("Expected the run to record the verification and keep the owner, got %+v", saved)
+	}
+	output = captureStdout(func() { code = runCollections([]string{"users"}) })
+	if code != 0 || !strings.Contains(output, "(0d ago)") || strings.Contains(output, "STALE") || !strings.Contains(output, "no assertions") {
+		t.Errorf("Expected the request to be shown as verified, got %d:\n%s", code, output)
+	}
+}
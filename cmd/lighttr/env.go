@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nshekhawat/lighttr/internal/environment"
+)
+
+// runEnv generates an environment from the running services of a local
+// stack, e.g.
+//
+//	lighttr env from-compose docker-compose.yml
+//	lighttr env from-k8s my-namespace --name review
+func runEnv(args []string) int {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	name := fs.String("name", "", "Name of the generated environment (default: the Compose project or namespace)")
+
+	var discover func(string) (*environment.Environment, error)
+	if len(args) > 0 {
+		switch args[0] {
+		case "from-compose":
+			discover = environment.FromCompose
+		case "from-k8s":
+			discover = environment.FromKubernetes
+		}
+	}
+
+	// Allow flags after the file or namespace
+	if discover == nil || len(args) < 2 || strings.HasPrefix(args[1], "-") {
+		fmt.Println("Usage: lighttr env from-compose <compose file> | from-k8s <namespace> [--name <environment>]")
+		return 2
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return 2
+	}
+
+	env, err := discover(args[1])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if *name != "" {
+		env.Name = *name
+	}
+	if env.Name == "" {
+		env.Name = "local"
+	}
+
+	// Variables added by hand, such as tokens, are kept when an environment
+	// is generated again
+	if existing, err := environment.Load(env.Name); err == nil {
+		env.Variables = existing.WithOverrides(env.Variables).Variables
+	}
+	if err := environment.Save(env); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIABLE\tVALUE")
+	for _, key := range slices.Sorted(maps.Keys(env.Variables)) {
+		fmt.Fprintf(tw, "%s\t%s\n", key, env.Variables[key])
+	}
+	tw.Flush()
+	fmt.Printf("Saved environment %s: use --env %s\n", env.Name, env.Name)
+	return 0
+}
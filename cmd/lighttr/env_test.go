@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/environment"
+)
+
+func TestRunEnv_FromCompose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as docker")
+	}
+	t.Setenv("HOME", t.TempDir())
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '{\"Project\":\"shop\",\"Service\":\"api\",\"Publishers\":[{\"URL\":\"0.0.0.0\",\"TargetPort\":80,\"PublishedPort\":8080,\"Protocol\":\"tcp\"}]}'\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	if err := environment.Save(&environment.Environment{Name: "shop", Variables: map[string]string{"token": "t", "base_url": "old"}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runEnv([]string{"from-compose", "docker-compose.yml"}) })
+	if exitCode != 0 || !strings.Contains(output, "api_url   http://localhost:8080") || !strings.Contains(output, "Saved environment shop") {
+		t.Errorf("Expected the discovered variables, got %d:\n%s", exitCode, output)
+	}
+	env, err := environment.Load("shop")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if env.Variables["base_url"] != "http://localhost:8080" || env.Variables["token"] != "t" {
+		t.Errorf("Expected discovered variables merged into the environment, got %v", env.Variables)
+	}
+
+	if code := runEnv([]string{"from-compose", "docker-compose.yml", "--name", "local"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if _, err := environment.Load("local"); err != nil {
+		t.Errorf("Expected --name to name the environment, got %v", err)
+	}
+
+	if code := runEnv([]string{"from-swarm", "stack"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown action, got %d", code)
+	}
+	if code := runEnv([]string{"from-k8s"}); code != 2 {
+		t.Errorf("Expected exit code 2 without a namespace, got %d", code)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/importer"
+)
+
+// exportFormats maps --format values to the functions that render a
+// collection's requests
+var exportFormats = map[string]func([]collection.SavedRequest) string{
+	"curl": importer.ExportCurl,
+	"http": importer.ExportHTTPFile,
+	"hurl": importer.ExportHurl,
+}
+
+// runExport writes a saved collection in another tool's format, e.g.
+//
+//	lighttr export my-collection --format http --output api.http
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "http", "Output format (http, hurl, curl)")
+	output := fs.String("output", "", "File to write instead of stdout")
+
+	// Allow flags after the collection name
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lighttr export <collection> [--format http|hurl|curl] [--output file]")
+		return 2
+	}
+	name := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	render, ok := exportFormats[*format]
+	if !ok {
+		fmt.Printf("Error: unsupported format: %s\n", *format)
+		return 2
+	}
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	c, err := manager.Load(name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	text := render(c.Requests)
+	if *output == "" {
+		fmt.Print(text)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(text), 0644); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Exported %d requests to %s\n", len(c.Requests), *output)
+	return 0
+}
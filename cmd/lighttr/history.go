@@ -0,0 +1,268 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/importer"
+	"github.com/nshekhawat/lighttr/pkg/history"
+)
+
+// historyListSize is how many of the latest requests lighttr history lists
+const historyListSize = 20
+
+// runHistory lists, tags, diffs, or exports the requests in history, e.g.
+//
+//	lighttr history
+//	lighttr history tag 42 checkout-bug
+//	lighttr history diff 42
+//	lighttr history diff
+//	lighttr history export --format sh --tag checkout-bug --output replay.sh
+//	lighttr history export --since yesterday --mask
+//
+// Requests are identified by their positions in history, as in lighttr
+// bugreport.
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	format := fs.String("format", "sh", "Export format: sh (a shell script of curl commands)")
+	output := fs.String("output", "", "File to write the export to instead of stdout")
+	tag := fs.String("tag", "", "Export only the requests with this tag")
+	since := fs.String("since", "", "Export only the requests sent on or after this date: YYYY-MM-DD, today, yesterday, or -N days")
+	until := fs.String("until", "", "Export only the requests sent on or before this date")
+	mask := fs.Bool("mask", false, "Mask credentials as in bug reports instead of exporting them as sent")
+
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	// Allow flags after the ID and tags
+	var operands []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		operands, args = append(operands, args[0]), args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	operands = append(operands, fs.Args()...)
+
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	entries := requestHistory.GetAll()
+
+	switch action {
+	case "list":
+		if len(operands) != 0 {
+			break
+		}
+		if len(entries) == 0 {
+			fmt.Println("No requests in history")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTIME\tREQUEST\tSTATUS\tTAGS")
+		for i := max(0, len(entries)-historyListSize); i < len(entries); i++ {
+			entry := entries[i]
+			tags := "-"
+			if len(entry.Tags) > 0 {
+				tags = strings.Join(entry.Tags, ",")
+			}
+			status := entryStatus(entry)
+			if requestHistory.SameAsPrevious(i) {
+				status += " (same as previous)"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s %s\t%s\t%s\n", i+1, entryTime(entry), entry.Method, entry.ResolvedURL(), status, tags)
+		}
+		tw.Flush()
+		return 0
+
+	case "tag":
+		if len(operands) < 2 {
+			break
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(operands[0], "#"))
+		if err != nil || id < 1 || id > len(entries) {
+			fmt.Printf("Error: no request %q in history; run lighttr history to list them\n", operands[0])
+			return 1
+		}
+		if err := requestHistory.Tag(id-1, operands[1:]...); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Tagged request %d with %s\n", id, strings.Join(operands[1:], ", "))
+		return 0
+
+	case "diff":
+		if len(operands) > 2 {
+			break
+		}
+		return diffHistory(requestHistory, entries, operands)
+
+	case "export":
+		if len(operands) != 0 {
+			break
+		}
+		if *format != "sh" {
+			fmt.Printf("Error: unsupported format: %s\n", *format)
+			return 2
+		}
+		now := time.Now()
+		var from, to string
+		if *since != "" {
+			if from, err = history.ParseDate(*since, now); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return 2
+			}
+		}
+		if *until != "" {
+			if to, err = history.ParseDate(*until, now); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return 2
+			}
+		}
+
+		var selected []int
+		for i, entry := range entries {
+			if *tag != "" && !entry.HasTag(*tag) {
+				continue
+			}
+			if from != "" || to != "" {
+				if entry.Time.IsZero() {
+					continue
+				}
+				day := entry.Time.Local().Format(time.DateOnly)
+				if (from != "" && day < from) || (to != "" && day > to) {
+					continue
+				}
+			}
+			selected = append(selected, i)
+		}
+		if len(selected) == 0 {
+			fmt.Println("Error: no requests in history match")
+			return 1
+		}
+
+		script := curlScript(entries, selected, *mask, now)
+		if *output == "" {
+			fmt.Print(script)
+			return 0
+		}
+		// Scripts with credentials are kept to the user
+		mode := os.FileMode(0700)
+		if *mask {
+			mode = 0755
+		}
+		if err := os.WriteFile(*output, []byte(script), mode); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Exported %d requests to %s\n", len(selected), *output)
+		return 0
+	}
+
+	fmt.Println("Usage: lighttr history [list|tag <id> <tag>...|diff [<id> [<other id>]]|export [--format sh] [--tag tag] [--since date] [--until date] [--mask] [--output file]]")
+	return 2
+}
+
+// diffHistory prints how the response of a request differs from that of
+// the previous run of the same request, or of another request, and without
+// IDs, how every request's response differs from its previous run,
+// skipping the runs with identical responses
+func diffHistory(requestHistory *history.Manager, entries []history.Entry, operands []string) int {
+	ids := make([]int, len(operands))
+	for i, operand := range operands {
+		id, err := strconv.Atoi(strings.TrimPrefix(operand, "#"))
+		if err != nil || id < 1 || id > len(entries) {
+			fmt.Printf("Error: no request %q in history; run lighttr history to list them\n", operand)
+			return 1
+		}
+		ids[i] = id - 1
+	}
+
+	if len(ids) == 0 {
+		changes, identical := requestHistory.Changes()
+		for _, change := range changes {
+			printChange(entries, change)
+		}
+		if len(changes) == 0 {
+			fmt.Println("No request's response changed from its previous run")
+		}
+		if identical > 0 {
+			fmt.Printf("Runs skipped for having the same response as the previous run: %d\n", identical)
+		}
+		return 0
+	}
+
+	current, previous := ids[0], -1
+	if len(ids) == 2 {
+		previous = ids[1]
+	} else if previous = requestHistory.Previous(current); previous < 0 {
+		fmt.Printf("Error: request %d has no previous run; give the ID of a request to compare it with\n", current+1)
+		return 1
+	}
+	if history.SameResponse(entries[previous], entries[current]) {
+		fmt.Printf("Request %d has the same response as request %d: %s, %s\n", current+1, previous+1, entryStatus(entries[current]), entries[current].Response.BodyHash)
+		return 0
+	}
+	printChange(entries, history.Change{Previous: previous, Current: current, Fields: history.DiffResponses(entries[previous], entries[current])})
+	return 0
+}
+
+// printChange prints how the responses of two requests differ
+func printChange(entries []history.Entry, change history.Change) {
+	entry := entries[change.Current]
+	fmt.Printf("%d: %s %s, compared with %d\n", change.Current+1, entry.Method, entry.ResolvedURL(), change.Previous+1)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, field := range change.Fields {
+		fmt.Fprintf(tw, "  %s\t%s\t->  %s\n", field.Name, field.Before, field.After)
+	}
+	tw.Flush()
+}
+
+// entryTime formats when a history entry was sent, or - if history did
+// not keep it
+func entryTime(entry history.Entry) string {
+	if entry.Time.IsZero() {
+		return "-"
+	}
+	return entry.Time.Local().Format("2006-01-02 15:04")
+}
+
+// curlScript writes the selected history entries as a shell script of curl
+// commands, each after a comment with its ID, time, status, and tags
+func curlScript(entries []history.Entry, selected []int, mask bool, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# %d requests exported from lighttr history on %s\n", len(selected), now.Format("2006-01-02 15:04 MST"))
+	if mask {
+		b.WriteString("# Credentials are masked as " + masked + ": replace them before running it\n")
+	} else {
+		b.WriteString("# Credentials are included as they were sent: review it before sharing it\n")
+	}
+	for _, i := range selected {
+		entry := entries[i]
+		req := entry.RequestData
+		if mask {
+			req = sanitizeRequest(req)
+		}
+		fmt.Fprintf(&b, "\n# %d: %s %s %s -> %s", i+1, entryTime(entry), req.Method, req.ResolvedURL(), entryStatus(entry))
+		if len(entry.Tags) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(entry.Tags, ", "))
+		}
+		b.WriteString("\n" + importer.CurlCommand(&req))
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		t.Fatalf("HistoryPath() error = %v", err)
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, path := range []string{"/orders", "/users"} {
+		req := request.NewRequestData()
+		req.URL = "https://api.example.com" + path
+		req.Headers["X-Api-Key"] = "k-123"
+		if err := requestHistory.Add(*req); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runHistory([]string{"tag", "2", "bug-42"}) })
+	if exitCode != 0 || !strings.Contains(output, "Tagged request 2 with bug-42") {
+		t.Fatalf("Expected the request to be tagged, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() { exitCode = runHistory(nil) })
+	if exitCode != 0 || !strings.Contains(output, "TAGS") || !strings.Contains(output, "bug-42\n") || strings.Count(output, "\n") != 3 {
+		t.Errorf("Expected the requests to be listed with their tags, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runHistory([]string{"export", "--tag", "bug-42"}) })
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d:\n%s", exitCode, output)
+	}
+	for _, want := range []string{"#!/bin/sh\n", "# 1 requests exported", "# 2: ", "GET https://api.example.com/users -> - [bug-42]\ncurl", "-H 'X-Api-Key: k-123'"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the script:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "/orders") {
+		t.Errorf("Expected only the tagged request, got:\n%s", output)
+	}
+
+	script := filepath.Join(t.TempDir(), "replay.sh")
+	output = captureStdout(func() { exitCode = runHistory([]string{"export", "--since", "today", "--mask", "--output", script}) })
+	if exitCode != 0 || !strings.Contains(output, "Exported 2 requests to "+script) {
+		t.Fatalf("Expected the script to be written, got %d:\n%s", exitCode, output)
+	}
+	data, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "k-123") || !strings.Contains(string(data), "X-Api-Key: ********") {
+		t.Errorf("Expected the credentials to be masked, got:\n%s", data)
+	}
+	if info, err := os.Stat(script); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected the script to be executable, got %v", info.Mode())
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	if output := captureStdout(func() { exitCode = runHistory([]string{"export", "--since", tomorrow}) }); exitCode != 1 {
+		t.Errorf("Expected no requests to match, got %d:\n%s", exitCode, output)
+	}
+	for _, args := range [][]string{{"export", "--format", "har"}, {"tag", "1"}, {"prune"}} {
+		if output := captureStdout(func() { exitCode = runHistory(args) }); exitCode != 2 {
+			t.Errorf("Expected a usage error for %v, got %d:\n%s", args, exitCode, output)
+		}
+	}
+}
+
+func TestRunHistory_Diff(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		t.Fatalf("HistoryPath() error = %v", err)
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	users := request.RequestData{Method: "GET", URL: "https://api.example.com/users"}
+	for _, resp := range []*request.ResponseData{
+		{StatusCode: 200, Body: `[]`},
+		{StatusCode: 200, Body: `[]`},
+		{StatusCode: 200, Body: `[{"id":1}]`, Headers: map[string]string{"Etag": `"v2"`}},
+	} {
+		if err := requestHistory.AddWithResponse(users, resp); err != nil {
+			t.Fatalf("AddWithResponse() error = %v", err)
+		}
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runHistory(nil) })
+	if exitCode != 0 || strings.Count(output, "(same as previous)") != 1 || !strings.Contains(output, "200 (same as previous)") {
+		t.Errorf("Expected request 2 to be marked, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runHistory([]string{"diff"}) })
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d:\n%s", exitCode, output)
+	}
+	for _, want := range []string{"3: GET https://api.example.com/users, compared with 2\n", "  Body", "->  10 bytes, ", "  Etag", `->  "v2"`,
+		"Runs skipped for having the same response as the previous run: 1"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the diff:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "2: GET") {
+		t.Errorf("Expected the identical run to be skipped, got:\n%s", output)
+	}
+
+	output = captureStdout(func() { exitCode = runHistory([]string{"diff", "2"}) })
+	if exitCode != 0 || !strings.Contains(output, "Request 2 has the same response as request 1: 200, sha256:") {
+		t.Errorf("Expected the identical pair to be reported, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() { exitCode = runHistory([]string{"diff", "3", "1"}) })
+	if exitCode != 0 || !strings.Contains(output, "3: GET https://api.example.com/users, compared with 1\n") {
+		t.Errorf("Expected request 3 to be compared with request 1, got %d:\n%s", exitCode, output)
+	}
+	for _, args := range [][]string{{"diff", "1"}, {"diff", "9"}} {
+		if output := captureStdout(func() { exitCode = runHistory(args) }); exitCode != 1 {
+			t.Errorf("Expected an error for %v, got %d:\n%s", args, exitCode, output)
+		}
+	}
+}
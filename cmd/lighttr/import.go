@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/importer"
+)
+
+// runImport saves the requests of a .http/.rest/.hurl file, or of a Postman
+// collection or Insomnia export, as a collection, e.g.
+//
+//	lighttr import checks.hurl --name smoke
+//	lighttr import workspace.postman_collection.json --name api
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	name := fs.String("name", "", "Collection name (default: the file name without its extension)")
+
+	// Allow flags after the file name
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lighttr import <file.http|file.hurl|export.json> [--name collection]")
+		return 2
+	}
+	file := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		c, err := loadExport(file)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		return saveImported(c, *name, file)
+	}
+	if !isRequestFile(file) {
+		fmt.Printf("Error: unsupported file type: %s\n", file)
+		return 2
+	}
+
+	c, err := loadRequestFile(file, nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return saveImported(c, *name, file)
+}
+
+// runImportWSDL saves a collection with one SOAP request per operation of a
+// WSDL document, e.g.
+//
+//	lighttr import-wsdl service.wsdl --name billing
+func runImportWSDL(args []string) int {
+	fs := flag.NewFlagSet("import-wsdl", flag.ContinueOnError)
+	name := fs.String("name", "", "Collection name (default: the file name without its extension)")
+
+	// Allow flags after the file name
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lighttr import-wsdl <service.wsdl> [--name collection]")
+		return 2
+	}
+	file := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	requests, err := importer.ParseWSDL(data)
+	if err != nil {
+		fmt.Printf("Error: %s: %v\n", file, err)
+		return 1
+	}
+	return saveImported(&collection.Collection{Requests: requests}, *name, file)
+}
+
+// loadExport reads a Postman collection or Insomnia export
+func loadExport(file string) (*collection.Collection, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	requests, _, err := importer.ParseExport(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return &collection.Collection{Requests: requests}, nil
+}
+
+// saveImported stores an imported collection under name, defaulting to the
+// base name of the file it came from
+func saveImported(c *collection.Collection, name, file string) int {
+	if err := storeImported(c, name, file); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// storeImported is saveImported returning its error
+func storeImported(c *collection.Collection, name, file string) error {
+	c.Name = name
+	if c.Name == "" {
+		c.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		return err
+	}
+	if err := manager.Save(c); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d requests into collection %s\n", len(c.Requests), c.Name)
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/authprofile"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/lint"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+)
+
+// runLint checks saved collections, all of them when none are named, for
+// hard-coded secrets, missing assertions, undefined variables, references
+// to responses that are not available, duplicate names, and auth settings
+// that are unknown or deprecated. It exits 1 when there are errors, or
+// with --strict any findings, e.g.
+//
+//	lighttr lint
+//	lighttr lint users checkout --env staging --strict
+//	lighttr lint --json
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	strict := fs.Bool("strict", false, "Fail on warnings too")
+	asJSON := fs.Bool("json", false, "Print the findings as JSON")
+	var envNames multiFlag
+	fs.Var(&envNames, "env", "Check variables against this environment only (repeatable; default: every saved environment)")
+
+	// Allow flags after the collections
+	var names []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		names, args = append(names, args[0]), args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Println("Usage: lighttr lint [<collection>...] [--env <environment>] [--strict] [--json]")
+		return 2
+	}
+
+	collections, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if len(names) == 0 {
+		if names, err = collections.List(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	var opts lint.Options
+	if len(envNames) == 0 {
+		if envNames, err = environment.List(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	for _, name := range envNames {
+		env, err := environment.Load(name)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		opts.Environments = append(opts.Environments, env)
+	}
+	if opts.Profiles, err = oauth.Profiles(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	authProfiles, err := authprofile.List()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	opts.AuthProfiles = make(map[string]bool)
+	for _, profile := range authProfiles {
+		opts.AuthProfiles[profile.Name] = true
+	}
+
+	findings := []lint.Finding{}
+	for _, name := range names {
+		c, err := collections.Load(name)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		findings = append(findings, lint.Collection(c, opts)...)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		errors, warnings := 0, 0
+		for _, f := range findings {
+			fmt.Println(f)
+			if f.Severity == lint.Error {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+		if len(findings) > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%d error(s), %d warning(s) in %d collection(s)\n", errors, warnings, len(names))
+	}
+
+	if lint.Failed(findings, *strict) {
+		return 1
+	}
+	return 0
+}
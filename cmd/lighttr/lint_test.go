@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/lint"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunLint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	collections, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	status200 := []assertion.Assertion{{Query: "status", Predicate: "==", Value: "200"}}
+	for _, c := range []*collection.Collection{
+		{Name: "clean", Requests: []collection.SavedRequest{
+			{Name: "list", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/users"}, Asserts: status200},
+		}},
+		{Name: "untested", Requests: []collection.SavedRequest{
+			{Name: "list", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/users"}},
+		}},
+		{Name: "leaky", Requests: []collection.SavedRequest{
+			{Name: "list", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/users", Auth: request.AuthData{Type: request.BearerAuth, Token: "s3cret"}}, Asserts: status200},
+		}},
+	} {
+		if err := collections.Save(c); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if err := environment.Save(&environment.Environment{Name: "dev", Variables: map[string]string{"base_url": "http://localhost:8080"}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var code int
+	output := captureStdout(func() { code = runLint([]string{"clean"}) })
+	if code != 0 || !strings.Contains(output, "0 error(s), 0 warning(s) in 1 collection(s)") {
+		t.Errorf("Expected a clean collection to pass, got %d:\n%s", code, output)
+	}
+
+	output = captureStdout(func() { code = runLint([]string{"untested"}) })
+	if code != 0 || !strings.Contains(output, "untested/list: warning: no assertions") {
+		t.Errorf("Expected warnings alone to pass, got %d:\n%s", code, output)
+	}
+	captureStdout(func() { code = runLint([]string{"untested", "--strict"}) })
+	if code != 1 {
+		t.Errorf("Expected warnings to fail with --strict, got %d", code)
+	}
+
+	output = captureStdout(func() { code = runLint(nil) })
+	if code != 1 || !strings.Contains(output, "leaky/list: error: the bearer token is hard-coded") || !strings.Contains(output, "in 3 collection(s)") {
+		t.Errorf("Expected every collection to be checked and the secret to fail, got %d:\n%s", code, output)
+	}
+
+	// Against an environment without base_url, the variable is undefined
+	if err := environment.Save(&environment.Environment{Name: "empty", Variables: map[string]string{}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	output = captureStdout(func() { code = runLint([]string{"clean", "--json", "--env", "empty"}) })
+	var findings []lint.Finding
+	if err := json.Unmarshal([]byte(output), &findings); err != nil {
+		t.Fatalf("Expected JSON, got %v:\n%s", err, output)
+	}
+	if code != 1 || len(findings) != 1 || findings[0].Rule != lint.RuleUndefinedVariable || findings[0].Severity != lint.Error {
+		t.Errorf("Expected base_url to be undefined in empty, got %d: %+v", code, findings)
+	}
+
+	captureStdout(func() { code = runLint([]string{"missing"}) })
+	if code != 1 {
+		t.Errorf("Expected a missing collection to fail, got %d", code)
+	}
+	if code := runLint([]string{"clean", "--strict", "extra"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an argument after the flags, got %d", code)
+	}
+}
@@ -3,48 +3,503 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/nshekhawat/lighttr/internal/request"
+	"github.com/nshekhawat/lighttr/internal/authprofile"
+	"github.com/nshekhawat/lighttr/internal/azure"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/gcp"
+	"github.com/nshekhawat/lighttr/internal/kerberos"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/internal/queue"
 	"github.com/nshekhawat/lighttr/internal/tui"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
 )
 
 // For testing
-var osExit = os.Exit
+var (
+	osExit           = os.Exit
+	stdin  io.Reader = os.Stdin
+)
+
+// directOptions holds the optional command line settings for a direct request
+type directOptions struct {
+	env             string
+	pin             string
+	checkRevocation bool
+	negotiate       string
+	cacheCheck      bool
+	methodOverride  string
+	timeout         time.Duration
+	connectTimeout  time.Duration
+	tlsTimeout      time.Duration
+	headerTimeout   time.Duration
+	noRedirects     bool
+	maxRedirects    int
+	retries         int
+	retryBackoff    time.Duration
+	retryOn         string
+	cookies         bool
+	form            multiFlag
+	formParams      multiFlag
+	resolve         multiFlag
+	iface           string
+	host            string
+	sni             string
+	ipv4            bool
+	ipv6            bool
+	xpath           string
+	selector        string
+	schema          string
+	output          string
+	remoteName      bool
+	maxSize         string
+	record          string
+	http1           bool
+	http2           bool
+	http3           bool
+	chunked         bool
+	contentLength   bool
+	proxy           string
+	proxyUser       string
+	proxyNTLM       bool
+	proxyProfile    string
+	noProxy         string
+	auth            request.AuthData
+}
+
+// commands maps subcommand names to their entry points. Each returns the
+// process exit code.
+var commands = map[string]func(args []string) int{
+	"send":        runSend,
+	"run":         runCollection,
+	"scaffold":    runScaffold,
+	"serve":       runServe,
+	"export":      runExport,
+	"import":      runImport,
+	"import-wsdl": runImportWSDL,
+	"watch":       runWatch,
+	"queue":       runQueue,
+	"setup":       runSetup,
+	"version":     runVersion,
+	"restore":     runRestore,
+	"delete":      runDelete,
+	"trash":       runTrash,
+	"env":         runEnv,
+	"oauth":       runOAuth,
+	"tokens":      runTokens,
+	"azure":       runAzure,
+	"lint":        runLint,
+	"tutorial":    runTutorial,
+	"collections": runCollections,
+	"bugreport":   runBugReport,
+	"auth":        runAuth,
+	"history":     runHistory,
+	"ws":          runWS,
+}
 
 func main() {
+	// Send the tokens of OAuth2 profiles with oauth2 auth, of Google
+	// credentials with gcp auth, of Entra ID with azure auth, and of
+	// Kerberos with negotiate auth, before the hooks so they see them
+	request.RegisterAuth(request.OAuth2Auth, oauth.Middleware())
+	request.RegisterAuth(request.GCPAuth, gcp.Middleware())
+	request.RegisterAuth(request.AzureAuth, azure.Middleware())
+	request.RegisterAuth(request.NegotiateAuth, kerberos.Middleware())
+
+	// Look up the credentials of profile auth in the auth profile store
+	request.RegisterAuthProfiles(authprofile.Resolve)
+
+	// Run the configured hooks around every request. A configuration that
+	// cannot be read is reported by the command that loads it.
+	if cfg, err := config.Load(); err == nil {
+		if hooks := cfg.HookMiddleware(); hooks != nil {
+			request.Register(hooks)
+		}
+	}
+
+	// Dispatch subcommands before parsing the direct request flags
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			osExit(command(os.Args[2:]))
+			return
+		}
+	}
+
 	// Command line flags
 	method := flag.String("method", "", "HTTP method (GET, POST, PUT, DELETE, etc.)")
 	url := flag.String("url", "", "Target URL")
 	headers := flag.String("headers", "", "Headers in key:value,key2:value2 format")
-	body := flag.String("body", "", "Request body")
+	body := flag.String("body", "", "Request body, @file to stream a file, or @- to stream stdin")
+	reducedMotion := flag.Bool("reduced-motion", false, "Keep the TUI cursor from blinking and the Retry-After countdown from ticking")
+	noAltScreen := flag.Bool("no-alt-screen", false, "Keep the TUI in the main screen and print every response to the scrollback, e.g. for tmux logs and recordings")
+
+	var opts directOptions
+	flag.Var(&opts.form, "F", "Multipart form part as name=value or name=@file[;type=...] (repeatable)")
+	flag.Var(&opts.form, "form", "Same as -F")
+	flag.Var(&opts.formParams, "data-urlencode", "Urlencoded form param as name=value (repeatable)")
+	flag.StringVar(&opts.env, "env", "", "Environment used to resolve {{var}} placeholders")
+	flag.StringVar(&opts.pin, "pin", "", "Server public key pins (sha256//<base64>, ';' separated)")
+	flag.BoolVar(&opts.checkRevocation, "check-revocation", false, "Check the server certificate via OCSP/CRL when no OCSP response is stapled")
+	flag.StringVar(&opts.negotiate, "negotiate", "", "Send the request once per Accept value (e.g. json,xml,html,csv) and summarize the responses")
+	flag.BoolVar(&opts.cacheCheck, "cache-check", false, "Send the request twice and explain how caches and CDNs treat the response")
+	flag.StringVar(&opts.methodOverride, "method-override", "", "Send PUT/PATCH/DELETE as POST with the method in this header (e.g. X-HTTP-Method-Override)")
+	flag.DurationVar(&opts.timeout, "timeout", 0, "Overall request timeout (e.g. 30s); 0 waits indefinitely")
+	flag.DurationVar(&opts.connectTimeout, "connect-timeout", 0, "Timeout for establishing the connection (e.g. 5s)")
+	flag.DurationVar(&opts.tlsTimeout, "tls-timeout", 0, "Timeout for the TLS handshake (default 10s)")
+	flag.DurationVar(&opts.headerTimeout, "response-header-timeout", 0, "Timeout for the response headers once the request is sent (e.g. 10s)")
+	flag.BoolVar(&opts.noRedirects, "no-redirects", false, "Show redirect responses instead of following them")
+	flag.IntVar(&opts.maxRedirects, "max-redirects", 0, "Maximum number of redirects to follow (default 10)")
+	flag.IntVar(&opts.retries, "retries", 0, "Retry failed requests up to this many times with exponential backoff")
+	flag.DurationVar(&opts.retryBackoff, "retry-backoff", request.DefaultRetryBackoff, "Wait before the first retry; doubled for each further retry")
+	flag.StringVar(&opts.retryOn, "retry-on", "", "Comma separated status codes to retry (default 429,502,503,504)")
+	flag.BoolVar(&opts.http1, "http1.1", false, "Use HTTP/1.1 even when the server offers HTTP/2")
+	flag.BoolVar(&opts.http2, "http2", false, "Require HTTP/2, without an upgrade for http:// URLs")
+	flag.BoolVar(&opts.http3, "http3", false, "Send the request over HTTP/3 (QUIC), falling back to HTTP/2 when it can't be")
+	flag.BoolVar(&opts.chunked, "chunked", false, "Send the body with chunked transfer encoding")
+	flag.BoolVar(&opts.contentLength, "content-length", false, "Send the body with a Content-Length, buffering a body streamed from stdin")
+	flag.StringVar(&opts.proxy, "x", "", "Send the request through this proxy (http://, https:// or socks5://)")
+	flag.StringVar(&opts.proxy, "proxy", "", "Same as -x")
+	flag.StringVar(&opts.proxyUser, "proxy-user", "", "Proxy credentials as user:password (default from proxy_auth in the config)")
+	flag.BoolVar(&opts.proxyNTLM, "proxy-ntlm", false, "Authenticate to the proxy with NTLM instead of Basic")
+	flag.StringVar(&opts.proxyProfile, "proxy-profile", "", "Authenticate to the proxy with the credentials of this basic or ntlm auth profile")
+	flag.StringVar(&opts.noProxy, "noproxy", "", "Comma separated hosts, domains, and CIDR ranges reached without the proxy")
+	flag.StringVar(&opts.record, "record", "", "Append every request and response to this HAR file (also in the TUI)")
+	flag.Var(&opts.resolve, "resolve", "Connect to this address for host:port instead of looking it up, as host:port:address (repeatable)")
+	flag.StringVar(&opts.host, "host", "", "Send this Host header, and TLS server name, instead of the URL's host (e.g. with a load balancer IP in --url)")
+	flag.StringVar(&opts.sni, "sni", "", "Send and verify this TLS server name instead of the URL's or --host's host")
+	flag.StringVar(&opts.iface, "interface", "", "Send the request from this local IP address or network interface (e.g. eth1)")
+	flag.BoolVar(&opts.ipv4, "4", false, "Resolve host names to IPv4 addresses only")
+	flag.BoolVar(&opts.ipv6, "6", false, "Resolve host names to IPv6 addresses only")
+	flag.BoolVar(&opts.cookies, "cookies", false, "Load and save cookies in ~/.lighttr/cookies.json")
+	flag.StringVar(&opts.xpath, "xpath", "", "Print the values an XPath expression selects from an XML response instead of the response")
+	flag.StringVar(&opts.selector, "select", "", "Print the text of the elements a CSS selector matches in an HTML response instead of the response")
+	flag.StringVar(&opts.schema, "schema", "", "Validate the response against a protobuf message (file.proto:Message) or Avro schema (file.avsc)")
+	flag.StringVar(&opts.output, "o", "", "Save the response body to this file instead of printing it")
+	flag.StringVar(&opts.output, "output", "", "Same as -o")
+	flag.BoolVar(&opts.remoteName, "O", false, "Save the response body under the name the server or URL suggests, without overwriting files")
+	flag.BoolVar(&opts.remoteName, "remote-name", false, "Same as -O")
+	flag.StringVar(&opts.maxSize, "max-size", "", "Stop reading the response body after this size, e.g. 1GB, or off (default from max_response_size in the config, else 100MB)")
+	authFlags(flag.CommandLine, &opts.auth)
+	flag.StringVar(&opts.auth.Profile, "auth-profile", "", "Send the credentials saved as this auth profile (save them with lighttr auth save)")
 	flag.Parse()
 
 	// If command line arguments are provided, execute request directly
 	if *url != "" {
-		executeDirectRequest(*method, *url, *headers, *body)
+		executeDirectRequest(*method, *url, *headers, *body, opts)
 		return
 	}
 
-	// Otherwise, launch the TUI
-	model := tui.NewModel()
-	p := tea.NewProgram(model)
+	// Otherwise, launch the TUI, after the onboarding wizard on first run
+	tui.SetReducedMotion(*reducedMotion)
+	tui.SetScrollback(*noAltScreen)
+	if config.FirstRun() {
+		if err := onboard(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+	}
+	osExit(runTUI(opts.record, opts.env, nil))
+}
+
+// runTUI runs the interactive mode, resolving placeholders against the
+// named environment when one is given and filling in the request form from
+// the session when one is given, and returns the exit code. A crash is
+// saved so that the session can be restored.
+func runTUI(record, envName string, session *tui.Session) int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	tui.SetTheme(cfg.Theme)
+	applyDisplay(cfg)
+	jar, err := cfg.CookieJar(false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	queuePath, err := queue.Path()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	offlineQueue, err := queue.Open(queuePath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	collections, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	model := tui.NewModel().WithConfig(cfg).WithCookies(jar).WithQueue(offlineQueue).WithHistory(requestHistory).WithCollections(collections)
+	if record != "" {
+		recorder, err := request.OpenHARRecorder(record)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		model = model.WithRecorder(recorder)
+	}
+	if envName != "" {
+		env, err := environment.Load(envName)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		model = model.WithEnvironment(env)
+	}
+	if session != nil {
+		model = model.WithSession(*session)
+	}
+	guard := tui.NewGuard(model)
+	p := tea.NewProgram(guard, tea.WithReportFocus())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
-		osExit(1)
+		return 1
+	}
+	if crash := guard.Crash(); crash != nil {
+		reportCrash(crash)
+		return 1
+	}
+	return 0
+}
+
+// applyDisplay turns on the reduced motion and scrollback output the
+// configuration asks for, on top of those of the flags
+func applyDisplay(cfg *config.Config) {
+	if cfg.ReducedMotion {
+		tui.SetReducedMotion(true)
+	}
+	if cfg.NoAltScreen {
+		tui.SetScrollback(true)
 	}
 }
 
-func executeDirectRequest(method, url, headers, body string) {
+// authFlags defines the flags of the auth settings on the flag set
+func authFlags(fs *flag.FlagSet, auth *request.AuthData) {
+	fs.StringVar((*string)(&auth.Type), "auth-type", string(request.NoAuth), "Authentication type (none/basic/bearer/apikey/mtls/oauth2/gcp/azure/jwt/ntlm/negotiate/profile/session)")
+	fs.StringVar(&auth.Username, "auth-username", "", "Username for basic and session auth, or DOMAIN\\user for ntlm auth")
+	fs.StringVar(&auth.Password, "auth-password", "", "Password for basic, ntlm, and session auth, or the passphrase of the mtls key or PKCS#12 bundle")
+	fs.StringVar(&auth.Token, "auth-token", "", "Token for bearer auth")
+	fs.StringVar(&auth.APIKey, "auth-apikey", "", "API key for API key auth")
+	fs.StringVar(&auth.APIKeyHeader, "auth-apikey-header", "", "Header to send the API key in (default Authorization)")
+	fs.StringVar(&auth.APIKeyPrefix, "auth-apikey-prefix", "", "Prefix of the API key in its header (default Bearer with the Authorization header)")
+	fs.StringVar(&auth.APIKeyQuery, "auth-apikey-query", "", "Send the API key as this query parameter instead of a header")
+	fs.StringVar(&auth.OAuth2Profile, "auth-oauth2-profile", "", "OAuth2 profile whose token oauth2 auth sends (log in with lighttr oauth login)")
+	fs.StringVar(&auth.GCPAudience, "auth-gcp-audience", "", "Send an ID token for this audience, e.g. a Cloud Run URL, with gcp auth (default: an access token)")
+	fs.StringVar(&auth.AzureTenant, "auth-azure-tenant", "", "Entra ID tenant ID or domain of azure auth (default: AZURE_TENANT_ID, or any work or school account)")
+	fs.StringVar(&auth.AzureClientID, "auth-azure-client-id", "", "Application (client) ID of azure auth (default: AZURE_CLIENT_ID)")
+	fs.StringVar(&auth.AzureClientSecret, "auth-azure-client-secret", "", "Client secret of azure auth, to send the application's own token (default: the token of lighttr azure login)")
+	fs.StringVar(&auth.AzureScope, "auth-azure-scope", "", "Scope of the azure auth token (default: https://graph.microsoft.com/.default)")
+	fs.StringVar(&auth.JWTClaims, "auth-jwt-claims", "", `Claims of the JWT jwt auth mints, as a JSON object, e.g. {"sub": "orders-service"}; iat and exp are added`)
+	fs.StringVar(&auth.JWTKeyFile, "auth-jwt-key", "", "File with the secret (HS256) or PEM private key (RS256/ES256) jwt auth signs with")
+	fs.StringVar(&auth.JWTAlg, "auth-jwt-alg", "", "Algorithm jwt auth signs with (HS256/RS256/ES256, default HS256)")
+	fs.StringVar(&auth.SessionLoginURL, "auth-session-login", "", "URL session auth POSTs to log in before the request")
+	fs.StringVar(&auth.SessionLoginBody, "auth-session-body", "", `Body of the session login, with {{username}} and {{password}} filled in, e.g. {"user": "{{username}}", "pass": "{{password}}"} (default: send them with basic auth)`)
+	fs.StringVar(&auth.SessionExtract, "auth-session-extract", "", "Where session auth takes the credential from the login response: $.path in its JSON body, header:<name>, or cookie:<name>")
+	fs.StringVar(&auth.SessionHeader, "auth-session-header", "", `Header and prefix session auth sends the token in, e.g. "X-Auth-Token" (default "Authorization: Bearer")`)
+	fs.StringVar(&auth.CertFile, "auth-cert", "", "Certificate file path for mutual TLS, or a PKCS#12 bundle (.p12/.pfx) with the key")
+	fs.StringVar(&auth.KeyFile, "auth-key", "", "Key file path for mutual TLS")
+	fs.StringVar(&auth.CertSubject, "auth-cert-subject", "", "Select the mutual TLS certificate from the system keystore by subject")
+	fs.StringVar(&auth.CAFile, "ca-file", "", "PEM bundle of additional CAs trusted for the server certificate")
+	fs.BoolVar(&auth.CAFileOnly, "ca-file-only", false, "Trust only the CAs of --ca-file, not the system's")
+	fs.BoolVar(&auth.InsecureSkipVerify, "insecure", false, "Skip server certificate verification")
+}
+
+func executeDirectRequest(method, url, headers, body string, opts directOptions) {
+	// Resolve {{var}} placeholders against the environment and process env
+	var env *environment.Environment
+	if opts.env != "" {
+		var err error
+		if env, err = environment.Load(opts.env); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+	}
+
+	missing := make(map[string]bool)
+	values := []*string{&url, &headers, &body, &opts.proxy, &opts.proxyUser}
+	for _, specs := range []multiFlag{opts.form, opts.formParams, opts.resolve} {
+		for i := range specs {
+			values = append(values, &specs[i])
+		}
+	}
+	for _, value := range values {
+		var unresolved []string
+		*value, unresolved = env.Resolve(*value)
+		for _, name := range unresolved {
+			missing[name] = true
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("Error: unresolved variables: %s\n", strings.Join(sortedKeys(missing), ", "))
+		osExit(1)
+	}
+
 	req := request.NewRequestData()
 	req.Method = method
+	req.URL = url
+	if body == "@-" {
+		req.BodyReader = stdin
+	} else if path, ok := strings.CutPrefix(body, "@"); ok {
+		req.BodyFile = path
+	} else {
+		req.Body = body
+	}
+	for _, spec := range opts.form {
+		part, err := request.ParseFormPart(spec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+		req.Multipart = append(req.Multipart, part)
+	}
+	for _, spec := range opts.formParams {
+		name, value, ok := strings.Cut(spec, "=")
+		if !ok || name == "" {
+			fmt.Printf("Error: invalid form param %q: expected name=value\n", spec)
+			osExit(1)
+		}
+		if req.FormParams == nil {
+			req.FormParams = make(map[string]string)
+		}
+		req.FormParams[name] = value
+	}
 	if req.Method == "" {
+		// Forms are POSTed like curl -F and --data-urlencode
 		req.Method = "GET"
+		if len(req.Multipart) > 0 || len(req.FormParams) > 0 {
+			req.Method = "POST"
+		}
+	}
+	req.Pins = request.ParsePins(opts.pin)
+	req.CheckRevocation = opts.checkRevocation
+	req.MethodOverrideHeader = opts.methodOverride
+	req.Timeout = opts.timeout
+	req.ConnectTimeout = opts.connectTimeout
+	req.TLSTimeout = opts.tlsTimeout
+	req.ResponseHeaderTimeout = opts.headerTimeout
+	req.DisableRedirects = opts.noRedirects
+	req.MaxRedirects = opts.maxRedirects
+	switch {
+	case countSet(opts.http1, opts.http2, opts.http3) > 1:
+		fmt.Println("Error: --http1.1, --http2, and --http3 cannot be combined")
+		osExit(1)
+	case opts.http1:
+		req.ProtocolVersion = request.ProtocolHTTP1
+	case opts.http2:
+		req.ProtocolVersion = request.ProtocolHTTP2
+	case opts.http3:
+		req.ProtocolVersion = request.ProtocolHTTP3
+	}
+	switch {
+	case opts.chunked && opts.contentLength:
+		fmt.Println("Error: --chunked and --content-length cannot be combined")
+		osExit(1)
+	case opts.chunked:
+		req.BodyFraming = request.FramingChunked
+	case opts.contentLength:
+		req.BodyFraming = request.FramingContentLength
+	}
+	if opts.retries > 0 {
+		retryOn, err := parseStatusCodes(opts.retryOn)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+		req.Retry = &request.RetryPolicy{
+			MaxAttempts: opts.retries + 1,
+			Backoff:     opts.retryBackoff,
+			RetryOn:     retryOn,
+		}
+	}
+	if opts.auth.Profile != "" {
+		switch opts.auth.Type {
+		case request.NoAuth, request.ProfileAuth:
+			opts.auth.Type = request.ProfileAuth
+		default:
+			fmt.Printf("Error: --auth-profile cannot be combined with --auth-type %s\n", opts.auth.Type)
+			osExit(1)
+		}
+	}
+	if opts.auth.Type != "" {
+		req.Auth = opts.auth
 	}
-	req.URL = url
-	req.Body = body
+
+	// Merge global default headers from the configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+	}
+	req.DefaultHeaders = cfg.DefaultHeaders
+	if err := applyMaxResponseSize(req, cfg, opts.maxSize); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+	}
+	if opts.proxy != "" {
+		req.Proxy = &request.ProxySettings{URL: opts.proxy, Bypass: request.ParseProxyBypass(opts.noProxy)}
+		if opts.proxyUser != "" {
+			req.Proxy.Username, req.Proxy.Password, _ = strings.Cut(opts.proxyUser, ":")
+		}
+		if opts.proxyNTLM {
+			req.Proxy.AuthType = request.NTLMAuth
+		}
+		req.Proxy.Profile = opts.proxyProfile
+		req.Proxy.ApplyCredentials(cfg.ProxyAuth)
+	}
+
+	for _, spec := range opts.resolve {
+		hostPort, address, err := request.ParseResolve(spec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+		if req.Resolve == nil {
+			req.Resolve = make(map[string]string)
+		}
+		req.Resolve[hostPort] = address
+	}
+	req.Host = opts.host
+	req.ServerName = opts.sni
+	req.Interface = opts.iface
+	switch {
+	case opts.ipv4 && opts.ipv6:
+		fmt.Println("Error: -4 and -6 cannot be combined")
+		osExit(1)
+	case opts.ipv4:
+		req.IPVersion = 4
+	case opts.ipv6:
+		req.IPVersion = 6
+	}
+
+	// Add a missing scheme and encode stray whitespace
+	var warnings []string
+	req.URL, warnings = request.NormalizeURL(req.URL, cfg.DefaultScheme)
+	printWarnings(warnings)
 
 	// Parse headers
 	if headers != "" {
@@ -61,22 +516,237 @@ func executeDirectRequest(method, url, headers, body string) {
 		fmt.Printf("Error: %v\n", err)
 		osExit(1)
 	}
+	query, err := compileQuery(opts.xpath, opts.selector)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+	}
+	schema, err := loadSchema(opts.schema)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+	}
+
+	// Compare the responses for each Accept value instead of printing one
+	if opts.negotiate != "" {
+		results, err := req.Negotiate(request.ParseAcceptList(opts.negotiate))
+		if err != nil {
+			fmt.Printf("Error executing request: %v\n", err)
+			osExit(1)
+		}
+		printNegotiationResults(results)
+		return
+	}
+
+	jar, err := cfg.CookieJar(opts.cookies)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		osExit(1)
+	}
+
+	var recorder *request.HARRecorder
+	if opts.record != "" {
+		if recorder, err = request.OpenHARRecorder(opts.record); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+	}
+
+	// Explain how caches treat the response instead of printing it
+	if opts.cacheCheck {
+		analysis, err := req.AnalyzeCaching(request.WithCookieJar(jar), request.WithHARRecorder(recorder))
+		if err != nil {
+			fmt.Printf("Error executing request: %v\n", err)
+			osExit(1)
+		}
+		if err := jar.Save(); err != nil {
+			fmt.Printf("Error: failed to save cookies: %v\n", err)
+			osExit(1)
+		}
+		printCacheAnalysis(analysis)
+		return
+	}
 
 	// Execute request
-	resp, err := req.Execute()
+	resp, err := req.ExecuteWith(request.WithCookieJar(jar), request.WithHARRecorder(recorder))
 	if err != nil {
 		fmt.Printf("Error executing request: %v\n", err)
 		osExit(1)
 	}
+	if err := jar.Save(); err != nil {
+		fmt.Printf("Error: failed to save cookies: %v\n", err)
+		osExit(1)
+	}
 
 	if resp.Error != "" {
 		fmt.Printf("Error: %s\n", resp.Error)
+		printSuggestions(resp.ErrorKind)
 		osExit(1)
 	}
 
-	// Print response
+	if query != nil {
+		if err := printMatches(resp, query); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+	} else {
+		savedTo, err := saveResponse(resp, req.ResolvedURL(), opts.output, opts.remoteName)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			osExit(1)
+		}
+		printResponse(resp, savedTo)
+	}
+	if schema != nil && !printSchemaCheck(resp, schema) {
+		osExit(1)
+	}
+}
+
+// responseQuery extracts values from a response body, such as a compiled
+// --xpath expression or --select selector
+type responseQuery interface {
+	Query(body string) ([]string, error)
+	String() string
+}
+
+// compileQuery compiles the --xpath or --select flag, or returns nil when
+// neither is set
+func compileQuery(xpath, selector string) (responseQuery, error) {
+	switch {
+	case xpath != "" && selector != "":
+		return nil, fmt.Errorf("--xpath and --select cannot be combined")
+	case xpath != "":
+		compiled, err := request.CompileXPath(xpath)
+		if err != nil {
+			return nil, err
+		}
+		return compiled, nil
+	case selector != "":
+		compiled, err := request.CompileSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		return compiled, nil
+	}
+	return nil, nil
+}
+
+// loadSchema loads the --schema flag, or returns nil when it is not set
+func loadSchema(spec string) (*request.Schema, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	return request.LoadSchema(spec)
+}
+
+// printSchemaCheck validates the response body against the schema and
+// prints each mismatch with its field path, returning false when there are
+// any
+func printSchemaCheck(resp *request.ResponseData, schema *request.Schema) bool {
+	errs := resp.ValidateResponse(schema)
+	if len(errs) == 0 {
+		fmt.Printf("\nSchema: valid %s\n", schema)
+		return true
+	}
+	noun := "errors"
+	if len(errs) == 1 {
+		noun = "error"
+	}
+	fmt.Printf("\nSchema: %d %s against %s\n", len(errs), noun, schema)
+	for _, err := range errs {
+		fmt.Printf("  %v\n", err)
+	}
+	return false
+}
+
+// printMatches writes the values the query selects from the response body,
+// one per line
+func printMatches(resp *request.ResponseData, query responseQuery) error {
+	values, err := query.Query(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("%q matched nothing", query.String())
+	}
+	for _, value := range values {
+		fmt.Println(value)
+	}
+	return nil
+}
+
+// saveResponse writes the body for --output and --remote-name and returns
+// the path written, or an empty string when neither is set. With
+// --remote-name, or an --output directory, the file is named after the
+// response and existing files are kept; an --output file is overwritten.
+func saveResponse(resp *request.ResponseData, rawURL, output string, remoteName bool) (string, error) {
+	if output == "" && !remoteName {
+		return "", nil
+	}
+	isDir := strings.HasSuffix(output, string(os.PathSeparator))
+	if info, err := os.Stat(output); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if remoteName || isDir {
+		if output == "" {
+			output = "."
+		}
+		return resp.SaveBodyAs(output, rawURL)
+	}
+	if err := resp.SaveBody(output); err != nil {
+		return "", err
+	}
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+	return output, nil
+}
+
+// printResponse writes the response status, headers, and body to stdout. A
+// body saved to output, and a binary body, are summarized instead.
+func printResponse(resp *request.ResponseData, output string) {
 	fmt.Printf("Status: %d\n", resp.StatusCode)
+	if resp.Protocol != "" {
+		fmt.Printf("Protocol: %s\n", resp.ProtocolSummary())
+	}
+	if resp.RemoteAddr != "" {
+		fmt.Printf("Connected to: %s\n", resp.RemoteAddr)
+	}
 	fmt.Printf("Time: %v\n", resp.ResponseTime)
+	if resp.ContentEncoding != "" {
+		fmt.Printf("Encoding: %s\n", resp.EncodingSummary())
+	}
+	if resp.Charset != "" {
+		fmt.Printf("Charset: %s\n", resp.CharsetSummary())
+	}
+	if resp.Truncated {
+		fmt.Printf("Truncated: %s; raise --max-size to read all of it\n", resp.TruncatedSummary())
+	}
+	if resp.RetryAfter > 0 {
+		fmt.Printf("Retry-After: %v\n", resp.RetryAfter)
+	}
+
+	if resp.Timing != nil {
+		printTiming(resp.Timing)
+	}
+
+	if len(resp.Attempts) > 1 {
+		fmt.Println("\nAttempts:")
+		for i, attempt := range resp.Attempts {
+			fmt.Printf("%d. %s\n", i+1, attempt)
+		}
+	}
+
+	if len(resp.Redirects) > 0 {
+		fmt.Println("\nRedirects:")
+		for _, hop := range resp.Redirects {
+			fmt.Printf("%d %s -> %s\n", hop.StatusCode, hop.URL, hop.Location)
+		}
+	}
+
+	if resp.TLS != nil {
+		printTLSInfo(resp.TLS)
+	}
 
 	if len(resp.Headers) > 0 {
 		fmt.Println("\nHeaders:")
@@ -85,8 +755,176 @@ func executeDirectRequest(method, url, headers, body string) {
 		}
 	}
 
-	if resp.Body != "" {
+	switch {
+	case output != "":
+		fmt.Printf("\nBody: saved to %s (%d bytes)\n", output, len(resp.Body))
+	case resp.Binary:
+		fmt.Printf("\nBody: binary (%s), use --output to save it\n", resp.BinarySummary())
+		fmt.Print(resp.HexPreview())
+	case resp.Body != "":
 		fmt.Println("\nBody:")
 		fmt.Println(resp.Body)
 	}
 }
+
+// countSet returns how many of the flags are set
+func countSet(flags ...bool) int {
+	count := 0
+	for _, set := range flags {
+		if set {
+			count++
+		}
+	}
+	return count
+}
+
+// printSuggestions explains a recognized failure to reach the server and
+// lists what to try about it
+func printSuggestions(kind request.ErrorKind) {
+	if kind == "" {
+		return
+	}
+	fmt.Printf("\n%s. Try:\n", kind.Summary())
+	for _, suggestion := range kind.Suggestions() {
+		fmt.Printf("  - %s\n", suggestion)
+	}
+}
+
+// printWarnings writes warnings to stderr, keeping stdout for the response
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// parseStatusCodes parses a comma separated list of HTTP status codes
+func parseStatusCodes(value string) ([]int, error) {
+	var codes []int
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", field)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// printTiming writes the timing breakdown to stdout
+func printTiming(timing *request.Timing) {
+	fmt.Println("\nTiming:")
+	for _, phase := range timing.Phases() {
+		fmt.Printf("%-19s %v\n", phase.Name+":", phase.Duration.Round(time.Microsecond))
+	}
+	if timing.Reused {
+		fmt.Printf("(reused connection, idle %v)\n", timing.IdleTime.Round(time.Millisecond))
+	}
+}
+
+// printTLSInfo writes the TLS connection details to stdout
+func printTLSInfo(info *request.TLSInfo) {
+	fmt.Println("\nTLS:")
+	fmt.Printf("Version: %s (%s)\n", info.Version, info.CipherSuite)
+	if info.Subject != "" {
+		fmt.Printf("Certificate: %s\n", info.Subject)
+		fmt.Printf("Issuer: %s\n", info.Issuer)
+		fmt.Printf("Expires: %s\n", info.NotAfter.Format("2006-01-02"))
+	}
+	if info.OCSPStapled {
+		fmt.Println("OCSP Stapled: yes")
+	} else {
+		fmt.Println("OCSP Stapled: no")
+	}
+	if info.Revocation != "" {
+		if info.RevocationSource != "" {
+			fmt.Printf("Revocation: %s (%s)\n", info.Revocation, info.RevocationSource)
+		} else {
+			fmt.Printf("Revocation: %s\n", info.Revocation)
+		}
+	}
+	for _, warning := range info.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+}
+
+// printNegotiationResults writes a table of the responses to each Accept value
+func printNegotiationResults(results []request.NegotiationResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCEPT\tSTATUS\tCONTENT-TYPE\tSIZE")
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(w, "%s\terror\t%s\t\n", result.Accept, result.Error)
+			continue
+		}
+		contentType := result.ContentType
+		if contentType == "" {
+			contentType = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d B\n", result.Accept, result.StatusCode, contentType, result.Size)
+	}
+	w.Flush()
+}
+
+// printCacheAnalysis writes the caching headers of the response, whether a
+// shared cache stores it and for how long, and how revalidating it went
+func printCacheAnalysis(a *request.CacheAnalysis) {
+	fmt.Printf("Status: %d\n", a.StatusCode)
+	fmt.Println("\nCaching headers:")
+	if len(a.Headers) == 0 {
+		fmt.Println("(none)")
+	}
+	for _, name := range request.CacheHeaders {
+		if value, ok := a.Headers[name]; ok {
+			fmt.Printf("%s: %s\n", name, value)
+		}
+	}
+
+	fmt.Println()
+	if a.Shared {
+		fmt.Printf("Shared caches: store it: %s\n", a.SharedReason)
+	} else {
+		fmt.Printf("Shared caches: don't store it: %s\n", a.SharedReason)
+	}
+	switch {
+	case a.LifetimeSource == "":
+		fmt.Println("Lifetime: none set")
+	case a.LifetimeSource == "no-cache":
+		fmt.Println("Lifetime: none, revalidated before every reuse (no-cache)")
+	default:
+		fmt.Printf("Lifetime: %v (%s)\n", a.Lifetime, a.LifetimeSource)
+	}
+	fmt.Printf("Age: %v\n", a.Age)
+	if a.LifetimeSource != "" && a.LifetimeSource != "no-cache" {
+		if a.TTL > 0 {
+			fmt.Printf("TTL: %v left\n", a.TTL)
+		} else {
+			fmt.Printf("TTL: stale for %v, revalidated before reuse\n", -a.TTL)
+		}
+	}
+
+	rv := a.Revalidation
+	fmt.Println("\nRevalidation:")
+	for _, name := range []string{"If-None-Match", "If-Modified-Since"} {
+		if value, ok := rv.Validators[name]; ok {
+			fmt.Printf("%s: %s\n", name, value)
+		}
+	}
+	switch {
+	case rv.Error != "":
+		fmt.Printf("Failed: %s\n", rv.Error)
+	case rv.Succeeded:
+		fmt.Printf("Succeeded: %s\n", rv.Result)
+	default:
+		fmt.Printf("Failed: %s\n", rv.Result)
+	}
+
+	if len(a.Notes) > 0 {
+		fmt.Println("\nNotes:")
+		for _, note := range a.Notes {
+			fmt.Printf("  - %s\n", note)
+		}
+	}
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
 )
 
 func TestExecuteDirectRequest(t *testing.T) {
@@ -40,6 +47,7 @@ func TestExecuteDirectRequest(t *testing.T) {
 		server.URL,
 		"Content-Type:application/json",
 		`{"test":"data"}`,
+		directOptions{},
 	)
 
 	// Restore stdout
@@ -86,8 +94,628 @@ func TestExecuteDirectRequest_Error(t *testing.T) {
 		"not-a-url",
 		"",
 		"",
+		directOptions{},
 	)
 
 	// If we get here, executeDirectRequest didn't call os.Exit
 	t.Error("Expected executeDirectRequest to exit")
 }
+
+func TestExecuteDirectRequest_Placeholders(t *testing.T) {
+	// Create a test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users" {
+			t.Errorf("Expected path /users, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			t.Errorf("Expected resolved Authorization header, got %s", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Create a temporary home with a staging environment
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	envDir := filepath.Join(tmpDir, ".lighttr", "environments")
+	if err := os.MkdirAll(envDir, 0755); err != nil {
+		t.Fatalf("Failed to create environments dir: %v", err)
+	}
+	content := `{"variables": {"base_url": "` + server.URL + `"}}`
+	if err := os.WriteFile(filepath.Join(envDir, "staging.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write environment file: %v", err)
+	}
+
+	// The token comes from the process environment
+	os.Setenv("LIGHTTR_TEST_TOKEN", "secret-token")
+	defer os.Unsetenv("LIGHTTR_TEST_TOKEN")
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	executeDirectRequest(
+		"GET",
+		"{{base_url}}/users",
+		"Authorization:Bearer {{LIGHTTR_TEST_TOKEN}}",
+		"",
+		directOptions{env: "staging"},
+	)
+
+	// Restore stdout
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	if !bytes.Contains(buf.Bytes(), []byte("Status: 200")) {
+		t.Errorf("Expected output to contain %q, got %s", "Status: 200", buf.String())
+	}
+}
+
+func TestExecuteDirectRequest_UnresolvedPlaceholder(t *testing.T) {
+	// Mock os.Exit
+	oldOsExit := osExit
+	defer func() { osExit = oldOsExit }()
+	osExit = func(code int) {
+		panic("os.Exit called")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if r != "os.Exit called" {
+				t.Errorf("unexpected panic: %v", r)
+			}
+		}
+	}()
+
+	executeDirectRequest(
+		"GET",
+		"https://{{lighttr_undefined_host}}/users",
+		"",
+		"",
+		directOptions{},
+	)
+
+	t.Error("Expected executeDirectRequest to exit")
+}
+
+func TestExecuteDirectRequest_InvalidPin(t *testing.T) {
+	// Mock os.Exit
+	oldOsExit := osExit
+	defer func() { osExit = oldOsExit }()
+	osExit = func(code int) {
+		panic("os.Exit called")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if r != "os.Exit called" {
+				t.Errorf("unexpected panic: %v", r)
+			}
+		}
+	}()
+
+	executeDirectRequest(
+		"GET",
+		"https://api.example.com",
+		"",
+		"",
+		directOptions{pin: "md5//abc"},
+	)
+
+	t.Error("Expected executeDirectRequest to exit")
+}
+
+func TestExecuteDirectRequest_Negotiate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotAcceptable)
+	}))
+	defer server.Close()
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	executeDirectRequest("GET", server.URL, "", "", directOptions{negotiate: "json,xml"})
+
+	// Restore stdout
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	for _, expected := range []string{"ACCEPT", "application/json  200", "application/xml   406"} {
+		if !bytes.Contains(buf.Bytes(), []byte(expected)) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestExecuteDirectRequest_CacheCheck(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{cacheCheck: true})
+	})
+
+	for _, expected := range []string{
+		"Cache-Control: public, max-age=300",
+		"Shared caches: store it: Cache-Control: public",
+		"Lifetime: 5m0s (max-age)",
+		"TTL: 5m0s left",
+		"If-None-Match: \"v1\"",
+		"Succeeded: 304 Not Modified",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestExecuteDirectRequest_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// Mock os.Exit
+	oldOsExit := osExit
+	defer func() { osExit = oldOsExit }()
+	osExit = func(code int) {
+		panic("os.Exit called")
+	}
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	func() {
+		defer func() {
+			if r := recover(); r != "os.Exit called" {
+				t.Errorf("Expected executeDirectRequest to exit, got %v", r)
+			}
+		}()
+		executeDirectRequest("GET", server.URL, "", "", directOptions{timeout: 50 * time.Millisecond})
+	}()
+
+	// Restore stdout
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if !bytes.Contains(buf.Bytes(), []byte("Timeout")) {
+		t.Errorf("Expected a timeout error, got:\n%s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Server did not respond in time. Try:\n  - Allow more time with --timeout")) {
+		t.Errorf("Expected suggestions for the timeout, got:\n%s", buf.String())
+	}
+}
+
+func TestExecuteDirectRequest_Redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("moved"))
+	}))
+	defer server.Close()
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	executeDirectRequest("GET", server.URL+"/old", "", "", directOptions{})
+	executeDirectRequest("GET", server.URL+"/old", "", "", directOptions{noRedirects: true})
+
+	// Restore stdout
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	for _, expected := range []string{"Redirects:\n301 " + server.URL + "/old -> /new", "Status: 301", "Time to First Byte:"} {
+		if !bytes.Contains(buf.Bytes(), []byte(expected)) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestExecuteDirectRequest_Retries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("recovered"))
+	}))
+	defer server.Close()
+
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	executeDirectRequest("GET", server.URL, "", "", directOptions{retries: 2, retryBackoff: time.Millisecond, retryOn: "500"})
+
+	// Restore stdout
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	for _, expected := range []string{"Status: 200", "Attempts:\n1. 500", "retried after 1ms", "recovered"} {
+		if !bytes.Contains(buf.Bytes(), []byte(expected)) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestExecuteDirectRequest_BodyFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("received " + string(body)))
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(file, []byte(`{"name": "Jane"}`), 0644); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	output := captureStdout(func() {
+		executeDirectRequest("POST", server.URL, "", "@"+file, directOptions{})
+	})
+	if !strings.Contains(output, `received {"name": "Jane"}`) {
+		t.Errorf("Expected the file to be sent as the body, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_Stdin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "received %s (%v, length %d)", body, r.TransferEncoding, r.ContentLength)
+	}))
+	defer server.Close()
+
+	defer func() { stdin = os.Stdin }()
+	stdin = strings.NewReader(`{"generated": true}`)
+	output := captureStdout(func() {
+		executeDirectRequest("POST", server.URL, "", "@-", directOptions{})
+	})
+	if !strings.Contains(output, `received {"generated": true} ([chunked], length -1)`) {
+		t.Errorf("Expected stdin to be streamed chunked, got:\n%s", output)
+	}
+
+	stdin = strings.NewReader(`{"generated": true}`)
+	output = captureStdout(func() {
+		executeDirectRequest("POST", server.URL, "", "@-", directOptions{contentLength: true})
+	})
+	if !strings.Contains(output, `received {"generated": true} ([], length 19)`) {
+		t.Errorf("Expected stdin to be sent with a Content-Length, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_Form(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm() error = %v", err)
+			return
+		}
+		file, header, err := r.FormFile("avatar")
+		if err != nil {
+			t.Errorf("FormFile() error = %v", err)
+			return
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+		fmt.Fprintf(w, "%s name=%s %s=%s", r.Method, r.FormValue("name"), header.Filename, content)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "avatar.png")
+	if err := os.WriteFile(file, []byte("PNGDATA"), 0644); err != nil {
+		t.Fatalf("Failed to write form file: %v", err)
+	}
+
+	output := captureStdout(func() {
+		executeDirectRequest("", server.URL, "", "", directOptions{form: multiFlag{"name=Jane", "avatar=@" + file}})
+	})
+	if !strings.Contains(output, "POST name=Jane avatar.png=PNGDATA") {
+		t.Errorf("Expected the form to be POSTed, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_XPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<Envelope><Body><Price currency="USD">34.5</Price><Price currency="EUR">31.0</Price></Body></Envelope>`))
+	}))
+	defer server.Close()
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{xpath: "//Price/@currency"})
+	})
+	if output != "USD\nEUR\n" {
+		t.Errorf("Expected only the selected values, got:\n%s", output)
+	}
+
+	oldOsExit := osExit
+	defer func() { osExit = oldOsExit }()
+	var exitCode int
+	osExit = func(code int) { exitCode = code }
+
+	output = captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{xpath: "//Missing"})
+	})
+	if exitCode != 1 || !strings.Contains(output, `Error: "//Missing" matched nothing`) {
+		t.Errorf("Expected an error for an XPath without matches, got exit %d:\n%s", exitCode, output)
+	}
+}
+
+func TestExecuteDirectRequest_Select(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<table class="prices"><tr><td>Apple<td>1.20<tr><td>Banana<td>0.99</table>`))
+	}))
+	defer server.Close()
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{selector: "table.prices td:last-child"})
+	})
+	if output != "1.20\n0.99\n" {
+		t.Errorf("Expected only the selected text, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_FormParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), body)
+	}))
+	defer server.Close()
+
+	output := captureStdout(func() {
+		executeDirectRequest("", server.URL, "", "", directOptions{formParams: multiFlag{"user=jane doe", "next=/a?b=c"}})
+	})
+	if !strings.Contains(output, "POST application/x-www-form-urlencoded next=%2Fa%3Fb%3Dc&user=jane+doe") {
+		t.Errorf("Expected an encoded form body, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_BinaryResponse(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(png))
+	}))
+	defer server.Close()
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{})
+	})
+	if strings.Contains(output, png) {
+		t.Errorf("Expected the raw bytes to stay out of the output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Body: binary (image/png, 16 bytes), use --output to save it") ||
+		!strings.Contains(output, "|.PNG........IHDR|") {
+		t.Errorf("Expected a summary and hexdump of the body, got:\n%s", output)
+	}
+
+	path := filepath.Join(t.TempDir(), "logo.png")
+	output = captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{output: path})
+	})
+	if !strings.Contains(output, "Body: saved to "+path+" (16 bytes)") {
+		t.Errorf("Expected the body to be reported as saved, got:\n%s", output)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != png {
+		t.Errorf("Expected the saved file to hold the body bytes, got %q", saved)
+	}
+}
+
+func TestExecuteDirectRequest_RemoteName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="invoice.pdf"`)
+		w.Write([]byte("%PDF-1.7"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, want := range []string{"invoice.pdf", "invoice (1).pdf"} {
+		output := captureStdout(func() {
+			executeDirectRequest("GET", server.URL+"/download", "", "", directOptions{remoteName: true})
+		})
+		if !strings.Contains(output, "Body: saved to "+filepath.Join(dir, want)+" (8 bytes)") {
+			t.Errorf("Expected the body to be saved to %s, got:\n%s", want, output)
+		}
+	}
+
+	// An --output directory is filled the same way
+	sub := filepath.Join(dir, "downloads")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{output: sub})
+	})
+	if !strings.Contains(output, "Body: saved to "+filepath.Join(sub, "invoice.pdf")) {
+		t.Errorf("Expected the body to be saved into the directory, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_Record(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	for range 2 {
+		captureStdout(func() {
+			executeDirectRequest("GET", server.URL, "", "", directOptions{record: path})
+		})
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the HAR file to be written, got %v", err)
+	}
+	var har struct {
+		Log struct {
+			Entries []json.RawMessage `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &har); err != nil || len(har.Log.Entries) != 2 {
+		t.Errorf("Expected both requests in the archive, got %v:\n%s", err, data)
+	}
+}
+
+func TestExecuteDirectRequest_Resolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "host %s", r.Host)
+	}))
+	defer server.Close()
+
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	output := captureStdout(func() {
+		executeDirectRequest("GET", "http://staging.example.test:"+port, "", "", directOptions{
+			resolve: multiFlag{"staging.example.test:" + port + ":127.0.0.1"},
+		})
+	})
+	if !strings.Contains(output, "host staging.example.test:"+port) {
+		t.Errorf("Expected the host to be resolved to the local server, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_DefaultScheme(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "path %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	// Local servers are usually plain HTTP, so configure that as the default
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".lighttr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := []byte(`{"default_scheme": "http"}`)
+	if err := os.WriteFile(filepath.Join(home, ".lighttr", "config.json"), config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", " "+strings.TrimPrefix(server.URL, "http://")+"/users ", "", "", directOptions{})
+	})
+	if !strings.Contains(output, "Status: 200") || !strings.Contains(output, "path /users") {
+		t.Errorf("Expected the URL to default to http, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_MaxSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 3000)))
+	}))
+	defer server.Close()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".lighttr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := []byte(`{"max_response_size": "2KB"}`)
+	if err := os.WriteFile(filepath.Join(home, ".lighttr", "config.json"), config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{})
+	})
+	if !strings.Contains(output, "Truncated: showing the first 2 KB of the body") || strings.Contains(output, strings.Repeat("a", 2001)) {
+		t.Errorf("Expected the body cut off at the configured size, got:\n%s", output)
+	}
+
+	// The flag overrides the configuration
+	output = captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{maxSize: "off"})
+	})
+	if strings.Contains(output, "Truncated") || !strings.Contains(output, strings.Repeat("a", 3000)) {
+		t.Errorf("Expected the whole body with --max-size off, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_HTTPVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "server saw %s", r.Proto)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	insecure := request.AuthData{Type: request.NoAuth, InsecureSkipVerify: true}
+	output := captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{auth: insecure})
+	})
+	if !strings.Contains(output, "Protocol: HTTP/2.0") {
+		t.Errorf("Expected HTTP/2 to be negotiated, got:\n%s", output)
+	}
+
+	output = captureStdout(func() {
+		executeDirectRequest("GET", server.URL, "", "", directOptions{auth: insecure, http1: true})
+	})
+	if !strings.Contains(output, "Protocol: HTTP/1.1") || !strings.Contains(output, "server saw HTTP/1.1") {
+		t.Errorf("Expected --http1.1 to keep HTTP/1.1, got:\n%s", output)
+	}
+}
+
+func TestExecuteDirectRequest_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := strings.Cut(r.Header.Get("Proxy-Authorization"), " ")
+		fmt.Fprintf(w, "proxied %s with %s", r.URL, user)
+	}))
+	defer proxy.Close()
+
+	output := captureStdout(func() {
+		executeDirectRequest("GET", "http://api.example.test/users", "", "", directOptions{proxy: proxy.URL, proxyUser: "jane:secret"})
+	})
+	if !strings.Contains(output, "proxied http://api.example.test/users with Basic") {
+		t.Errorf("Expected the request to go through the proxy, got:\n%s", output)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+)
+
+// runOAuth logs in to an OAuth2 profile, saving the client settings given
+// the first time so later logins only need the profile name, e.g.
+//
+//	lighttr oauth login github --auth-url https://github.com/login/oauth/authorize \
+//		--token-url https://github.com/login/oauth/access_token --client-id abc --scope repo
+//	lighttr oauth login github
+//	lighttr oauth login github --device --device-url https://github.com/login/device/code
+//	lighttr oauth debug github
+func runOAuth(args []string) int {
+	if len(args) > 0 && args[0] == "debug" {
+		return runOAuthDebug(args[1:])
+	}
+
+	fs := flag.NewFlagSet("oauth", flag.ContinueOnError)
+	authURL := fs.String("auth-url", "", "Authorization endpoint URL")
+	tokenURL := fs.String("token-url", "", "Token endpoint URL")
+	clientID := fs.String("client-id", "", "Client ID")
+	clientSecret := fs.String("client-secret", "", "Client secret, for clients that have one")
+	port := fs.Int("port", 0, "Loopback port the browser is redirected to (default: any free port)")
+	deviceURL := fs.String("device-url", "", "Device authorization endpoint URL, for the device code flow")
+	device := fs.Bool("device", false, "Log in with the device code flow, entering a code on any device, e.g. over SSH")
+	var scopes multiFlag
+	fs.Var(&scopes, "scope", "Scope to request (repeatable)")
+
+	// Allow flags after the profile name
+	if len(args) < 2 || args[0] != "login" || strings.HasPrefix(args[1], "-") {
+		fmt.Println("Usage: lighttr oauth login <profile> [--auth-url <url> --token-url <url> --client-id <id> --scope <scope>] [--device --device-url <url>]")
+		fmt.Println("       lighttr oauth debug [profile]")
+		return 2
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return 2
+	}
+
+	name := args[1]
+	profile, err := oauth.LoadProfile(name)
+	if err != nil {
+		profile = &oauth.Profile{Name: name}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "auth-url":
+			profile.AuthURL = *authURL
+		case "token-url":
+			profile.TokenURL = *tokenURL
+		case "client-id":
+			profile.ClientID = *clientID
+		case "client-secret":
+			profile.ClientSecret = *clientSecret
+		case "port":
+			profile.RedirectPort = *port
+		case "device-url":
+			profile.DeviceAuthURL = *deviceURL
+		case "scope":
+			profile.Scopes = scopes
+		}
+	})
+
+	// Profiles with only a device authorization endpoint have no other way
+	// to log in
+	login := oauth.Login
+	if *device || (profile.AuthURL == "" && profile.DeviceAuthURL != "") {
+		login = oauth.DeviceLogin
+	}
+	token, err := login(context.Background(), profile, os.Stdout)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Printf("Run lighttr oauth debug %s to see every leg of the login\n", name)
+		return 1
+	}
+	if err := oauth.SaveProfile(profile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	cached, err := oauth.SaveToken(profile, token)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if !cached.Keyring {
+		if cfg, err := config.Load(); err == nil && cfg.TokenStorage != "file" {
+			fmt.Fprintln(os.Stderr, "Warning: the system keyring is not available, so the token is cached in tokens.json")
+		}
+	}
+
+	fmt.Printf("Logged in to %s", name)
+	if !token.Expiry.IsZero() {
+		fmt.Printf(": the token expires in %v", time.Until(token.Expiry).Round(time.Second))
+		if token.RefreshToken != "" {
+			fmt.Print(" and is refreshed automatically")
+		}
+	}
+	fmt.Printf("\nUse --auth-type oauth2 --auth-oauth2-profile %s to send it\n", name)
+	return 0
+}
+
+// runOAuthDebug prints the legs of the OAuth2 flows recorded for the
+// client of a profile, or for every client, in full with their secrets
+// masked
+func runOAuthDebug(args []string) int {
+	if len(args) > 1 || (len(args) == 1 && strings.HasPrefix(args[0], "-")) {
+		fmt.Println("Usage: lighttr oauth debug [profile]")
+		return 2
+	}
+	clientID := ""
+	if len(args) == 1 {
+		profile, err := oauth.LoadProfile(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		clientID = profile.ClientID
+	}
+	legs, err := oauth.Legs(clientID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if len(legs) == 0 {
+		fmt.Println("No OAuth2 requests recorded yet")
+		return 0
+	}
+	for i, leg := range legs {
+		if i > 0 {
+			fmt.Println()
+		}
+		printLeg(leg)
+	}
+	return 0
+}
+
+// printLeg writes the request and response of a leg to stdout
+func printLeg(leg oauth.Leg) {
+	fmt.Println("# " + leg.Summary())
+	fmt.Println(strings.TrimSpace(leg.Method + " " + leg.URL))
+	printLegHeaders(leg.RequestHeaders)
+	if leg.RequestBody != "" {
+		fmt.Println("\n" + leg.RequestBody)
+	}
+	if leg.Status != 0 {
+		fmt.Printf("\nStatus: %d (%v)\n", leg.Status, leg.Duration.Round(time.Millisecond))
+		printLegHeaders(leg.ResponseHeaders)
+		if leg.ResponseBody != "" {
+			fmt.Println("\n" + leg.ResponseBody)
+		}
+	}
+	if leg.Error != "" {
+		fmt.Println("Error: " + leg.Error)
+	}
+}
+
+// printLegHeaders writes headers sorted by name
+func printLegHeaders(headers map[string]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, headers[name])
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/oauth"
+)
+
+func TestRunOAuth(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if code := runOAuth([]string{"login"}); code != 2 {
+		t.Errorf("Expected exit code 2 without a profile, got %d", code)
+	}
+	if code := runOAuth([]string{"logout", "github"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown action, got %d", code)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runOAuth([]string{"login", "github", "--client-id", "abc"}) })
+	if exitCode != 1 || !strings.Contains(output, "needs an authorization URL") {
+		t.Errorf("Expected a profile without URLs to fail, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() { exitCode = runOAuth([]string{"login", "github", "--device", "--client-id", "abc"}) })
+	if exitCode != 1 || !strings.Contains(output, "needs a device authorization URL") {
+		t.Errorf("Expected --device to use the device code flow, got %d:\n%s", exitCode, output)
+	}
+	if _, err := oauth.LoadProfile("github"); err == nil {
+		t.Error("Expected a failed login not to save the profile")
+	}
+}
+
+func TestRunOAuth_Debug(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runOAuth([]string{"debug"}) })
+	if exitCode != 0 || !strings.Contains(output, "No OAuth2 requests recorded yet") {
+		t.Errorf("Expected no legs, got %d:\n%s", exitCode, output)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error": "invalid_client"}`)
+	}))
+	defer server.Close()
+	output = captureStdout(func() {
+		exitCode = runOAuth([]string{"login", "github", "--device", "--device-url", server.URL, "--token-url", server.URL, "--client-id", "abc", "--client-secret", "s3cret"})
+	})
+	if exitCode != 1 || !strings.Contains(output, "Run lighttr oauth debug github") {
+		t.Errorf("Expected the failed login to point to the legs, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runOAuth([]string{"debug"}) })
+	for _, expected := range []string{"device authorization  POST 127.0.0.1", "-> 400  invalid_client", "client_id=abc&client_secret=********", "Status: 400"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if exitCode != 0 || strings.Contains(output, "s3cret") {
+		t.Errorf("Expected the legs with the secret masked, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runOAuth([]string{"debug", "github"}) })
+	if exitCode != 1 || !strings.Contains(output, "OAuth2 profile not found") {
+		t.Errorf("Expected an unknown profile to fail, got %d:\n%s", exitCode, output)
+	}
+}
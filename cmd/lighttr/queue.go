@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/queue"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runQueue lists, sends, or clears the requests queued in the TUI while
+// offline, e.g.
+//
+//	lighttr queue
+//	lighttr queue flush --cookies
+func runQueue(args []string) int {
+	fs := flag.NewFlagSet("queue", flag.ContinueOnError)
+	cookies := fs.Bool("cookies", false, "Load and save cookies in ~/.lighttr/cookies.json")
+
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path, err := queue.Path()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	q, err := queue.Open(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	switch action {
+	case "list":
+		if q.Len() == 0 {
+			fmt.Println("No queued requests")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tREQUEST\tQUEUED\tLAST ERROR")
+		for _, item := range q.Items() {
+			lastError := "-"
+			if item.LastError != "" {
+				lastError = item.LastError
+			}
+			fmt.Fprintf(tw, "%d\t%s %s\t%s\t%s\n", item.ID, item.Request.Method, request.DisplayURL(item.Request.ResolvedURL()),
+				item.QueuedAt.Format("2006-01-02 15:04"), lastError)
+		}
+		tw.Flush()
+		return 0
+
+	case "flush":
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		jar, err := cfg.CookieJar(*cookies)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		results, err := q.Flush(request.WithCookieJar(jar))
+		for _, result := range results {
+			label := fmt.Sprintf("#%d %s %s", result.Item.ID, result.Item.Request.Method, result.Item.Request.URL)
+			if result.Response != nil {
+				fmt.Printf("%s: %d (%v)\n", label, result.Response.StatusCode, result.Response.ResponseTime.Round(time.Millisecond))
+			} else {
+				fmt.Printf("%s: error: %s\n", label, result.Error)
+			}
+		}
+		if err == nil {
+			err = jar.Save()
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if q.Len() > 0 {
+			fmt.Printf("%d requests still queued\n", q.Len())
+			return 1
+		}
+		return 0
+
+	case "clear":
+		if err := q.Clear(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Println("Usage: lighttr queue [list|flush|clear] [--cookies]")
+	return 2
+}
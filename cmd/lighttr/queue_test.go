@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/queue"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunQueue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	path, err := queue.Path()
+	if err != nil {
+		t.Fatalf("Path() error = %v", err)
+	}
+	q, err := queue.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := q.Add(&request.RequestData{Method: "POST", URL: server.URL + "/events"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runQueue(nil) })
+	if exitCode != 0 || !strings.Contains(output, "POST "+server.URL+"/events") {
+		t.Errorf("Expected the queued request to be listed, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runQueue([]string{"flush"}) })
+	if exitCode != 0 || !strings.Contains(output, "#1 POST "+server.URL+"/events: 202") {
+		t.Errorf("Expected the queued request to be sent, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runQueue([]string{"list"}) })
+	if !strings.Contains(output, "No queued requests") {
+		t.Errorf("Expected the flush to empty the queue, got:\n%s", output)
+	}
+
+	if code := runQueue([]string{"drop"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown action, got %d", code)
+	}
+}
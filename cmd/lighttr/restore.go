@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/tui"
+)
+
+// crashDir returns the directory crashes of the TUI are saved in
+func crashDir() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "crash"), nil
+}
+
+// reportCrash saves a crash of the TUI and tells how to restore the
+// session. When it cannot be saved, the panic and stack trace are printed
+// instead.
+func reportCrash(crash *tui.Crash) {
+	fmt.Printf("Lighttr crashed: %s\n", crash.Panic)
+	dir, err := crashDir()
+	if err == nil {
+		var path string
+		if path, err = crash.Save(dir); err == nil {
+			fmt.Printf("The request form and a stack trace were saved to %s\n", path)
+			fmt.Println("Restore the session with: lighttr restore")
+			return
+		}
+	}
+	fmt.Printf("The session could not be saved: %v\n\n%s", err, crash.Stack)
+}
+
+// runRestore opens the TUI with the session saved by the last crash, or by
+// the given crash file, e.g.
+//
+//	lighttr restore
+//	lighttr restore ~/.lighttr/crash/crash-20260102-150405.json
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		var err error
+		if path, err = lastCrash(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	crash, err := tui.LoadCrash(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return runTUI("", "", &crash.Session)
+}
+
+// lastCrash returns the newest crash file
+func lastCrash() (string, error) {
+	dir, err := crashDir()
+	if err != nil {
+		return "", err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "crash-*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no crashes saved in %s", dir)
+	}
+	// The names sort by the time of the crash
+	slices.Sort(files)
+	return files[len(files)-1], nil
+}
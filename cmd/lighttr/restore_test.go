@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/tui"
+)
+
+func TestReportCrash(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runRestore(nil) })
+	if exitCode != 1 || !strings.Contains(output, "no crashes saved") {
+		t.Errorf("Expected an error without a saved crash, got %d:\n%s", exitCode, output)
+	}
+
+	for _, at := range []time.Time{time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)} {
+		output = captureStdout(func() { reportCrash(&tui.Crash{Time: at, Panic: "boom"}) })
+	}
+	if !strings.Contains(output, "Lighttr crashed: boom") || !strings.Contains(output, "lighttr restore") {
+		t.Errorf("Expected the crash to be reported with how to restore it, got:\n%s", output)
+	}
+	path, err := lastCrash()
+	if err != nil || !strings.HasSuffix(path, "crash-20260103-090000.json") || !strings.Contains(output, path) {
+		t.Errorf("Expected the newest crash to be restored, got %s (%v)", path, err)
+	}
+}
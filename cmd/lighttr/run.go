@@ -0,0 +1,332 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/importer"
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/internal/runner"
+	"github.com/nshekhawat/lighttr/internal/tui"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runCollection executes every request of a saved collection or a
+// .http/.hurl file and prints a report, e.g.
+//
+//	lighttr run my-collection --env staging --enforce-budgets
+//	lighttr run my-collection --env staging --set base_url=http://localhost:8080
+//	lighttr run api.http --request-name login
+//	lighttr run checkout --waterfall-file checkout.html
+//	lighttr run checkout --report-html run.html
+//	lighttr run checkout --replay session.har --as-of 2026-09-01
+func runCollection(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	envName := fs.String("env", "", "Environment used to resolve {{var}} placeholders")
+	enforceBudgets := fs.Bool("enforce-budgets", false, "Treat budget violations as failures")
+	requestName := fs.String("request-name", "", "Run only this request and the requests it depends on")
+	cookies := fs.Bool("cookies", false, "Load and save cookies in ~/.lighttr/cookies.json")
+	record := fs.String("record", "", "Append every request and response to this HAR file")
+	graph := fs.Bool("graph", false, "Show the dependency graph of the requests and their status during the run")
+	waterfall := fs.Bool("waterfall", false, "Print a waterfall of the requests' timing phases on one timeline after the report")
+	waterfallFile := fs.String("waterfall-file", "", "Write the waterfall to this .json or .html file")
+	reportHTML := fs.String("report-html", "", "Write a standalone HTML report of the run to this file")
+	replay := fs.String("replay", "", "Answer requests with the responses recorded in this HAR file, or in history, instead of the live API")
+	asOf := fs.String("as-of", "", "Replay the responses recorded on or before this date: YYYY-MM-DD, today, yesterday, or -N days")
+	var sets multiFlag
+	fs.Var(&sets, "set", "Override a variable for this run as name=value (repeatable)")
+
+	// Allow flags after the collection name
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lighttr run <collection|file.http|file.hurl> [--env name] [--set name=value] [--enforce-budgets] [--request-name name] [--cookies] [--record file.har] [--graph] [--waterfall] [--waterfall-file file.html] [--report-html file.html] [--replay file.har|history] [--as-of date]")
+		return 2
+	}
+	name := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	variables := make(map[string]string)
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			fmt.Printf("Error: invalid --set %q: expected name=value\n", set)
+			return 1
+		}
+		variables[strings.TrimSpace(key)] = value
+	}
+
+	c, err := loadRunTarget(name, variables)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	// Verification times are recorded in the whole collection when a
+	// selection of it runs
+	full := c
+	if *requestName != "" {
+		if c, err = runner.Select(c, *requestName); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	jar, err := cfg.CookieJar(*cookies)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	opts := runner.Options{
+		DefaultHeaders:  cfg.DefaultHeaders,
+		ProxyAuth:       cfg.ProxyAuth,
+		MaxResponseSize: cfg.ResponseSizeLimit(),
+		EnforceBudgets:  *enforceBudgets,
+		Cookies:         jar,
+	}
+	if *record != "" {
+		if opts.Recorder, err = request.OpenHARRecorder(*record); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	if *asOf != "" && *replay == "" {
+		fmt.Println("Error: --as-of requires --replay")
+		return 2
+	}
+	if *replay != "" {
+		if opts.Snapshot, err = loadSnapshot(*replay, *asOf); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Replaying %d recorded responses from %s\n", opts.Snapshot.Len(), *replay)
+	}
+	if *envName != "" {
+		if opts.Env, err = environment.Load(*envName); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	if len(variables) > 0 {
+		opts.Env = opts.Env.WithOverrides(variables)
+	}
+
+	var report *runner.Report
+	if *graph {
+		final, err := tea.NewProgram(tui.NewGraphModel(c, opts)).Run()
+		if err != nil {
+			fmt.Printf("Error running program: %v\n", err)
+			return 1
+		}
+		if report = final.(tui.GraphModel).Report(); report == nil {
+			fmt.Println("Run aborted")
+			return 1
+		}
+	} else {
+		report = runner.Run(c, opts)
+	}
+	report.Write(os.Stdout)
+	if *waterfall {
+		fmt.Println()
+		report.Waterfall().WriteText(os.Stdout)
+	}
+	if *waterfallFile != "" {
+		if err := writeWaterfall(report.Waterfall(), *waterfallFile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Wrote the waterfall to %s\n", *waterfallFile)
+	}
+	if *reportHTML != "" {
+		if err := writeFile(*reportHTML, report.WriteHTML); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Wrote the HTML report to %s\n", *reportHTML)
+	}
+	// Replayed responses verify nothing about the live API
+	if !isRequestFile(name) && opts.Snapshot == nil {
+		recordVerified(full, report)
+	}
+	postWebhooks(cfg.Webhooks, runEvent(report))
+	if err := jar.Save(); err != nil {
+		fmt.Printf("Error: failed to save cookies: %v\n", err)
+		return 1
+	}
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// loadSnapshot loads the responses to replay a run against from a HAR file
+// or, given "history", from request history, keeping those recorded on or
+// before the date asOf if it is set
+func loadSnapshot(source, asOf string) (*request.Snapshot, error) {
+	var until time.Time
+	if asOf != "" {
+		day, err := history.ParseDate(asOf, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		date, err := time.ParseInLocation(time.DateOnly, day, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		until = date.AddDate(0, 0, 1)
+	}
+	if source != "history" {
+		return request.LoadHARSnapshot(source, until)
+	}
+
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	requestHistory, err := history.Open(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	return requestHistory.Snapshot(until), nil
+}
+
+// recordVerified saves when the requests of a saved collection last passed
+// their assertions, warning without failing the command when it cannot
+func recordVerified(c *collection.Collection, report *runner.Report) {
+	if c.MarkVerified(report.Verified(), report.Started.Add(report.WallTime)) == 0 {
+		return
+	}
+	manager, err := collection.NewManager()
+	if err == nil {
+		err = manager.Save(c)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record when the requests were verified: %v\n", err)
+	}
+}
+
+// runEvent describes a completed run for webhooks
+func runEvent(report *runner.Report) notify.Event {
+	event := notify.Event{Type: notify.EventRun, Name: report.Collection, Failed: report.Failed(), Time: report.Started.Add(report.WallTime)}
+	passed, failed := 0, 0
+	for _, result := range report.Results {
+		if result.Status(report.EnforceBudgets) != "FAIL" {
+			passed++
+			continue
+		}
+		failed++
+		switch {
+		case result.Error != "":
+			event.Details = append(event.Details, result.Name+": "+result.Error)
+		case len(result.Failures) > 0:
+			event.Details = append(event.Details, result.Name+": "+strings.Join(result.Failures, "; "))
+		case len(result.Violations) > 0:
+			event.Details = append(event.Details, result.Name+": "+strings.Join(result.Violations, "; "))
+		default:
+			event.Details = append(event.Details, fmt.Sprintf("%s: status %d", result.Name, result.StatusCode))
+		}
+	}
+	event.Summary = fmt.Sprintf("%d passed, %d failed in %v", passed, failed, report.WallTime.Round(time.Millisecond))
+	return event
+}
+
+// postWebhooks posts the event to the configured webhooks that want it,
+// warning about those that fail without failing the command
+func postWebhooks(webhooks []notify.Webhook, event notify.Event) {
+	for _, err := range notify.PostAll(webhooks, event) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// writeWaterfall writes the waterfall of a run as JSON or HTML, going by
+// the file extension
+func writeWaterfall(w *runner.Waterfall, path string) error {
+	var write func(io.Writer) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		write = w.WriteJSON
+	case ".html", ".htm":
+		write = w.WriteHTML
+	default:
+		return fmt.Errorf("unsupported waterfall file %s: expected .json or .html", path)
+	}
+	return writeFile(path, write)
+}
+
+// writeFile creates the file at path and writes it with write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// requestFileFormats maps request file extensions to their parsers, which
+// take variables that override the ones defined in the file. Hurl files
+// define none: their variables are resolved when the requests run.
+var requestFileFormats = map[string]func(string, map[string]string) ([]collection.SavedRequest, error){
+	".http": importer.ParseHTTPFileWith,
+	".rest": importer.ParseHTTPFileWith,
+	".hurl": func(text string, _ map[string]string) ([]collection.SavedRequest, error) {
+		return importer.ParseHurl(text)
+	},
+}
+
+// loadRunTarget loads a saved collection, or parses a .http/.rest/.hurl
+// file into an unsaved one with the given variables overriding the file's
+func loadRunTarget(name string, variables map[string]string) (*collection.Collection, error) {
+	if !isRequestFile(name) {
+		manager, err := collection.NewManager()
+		if err != nil {
+			return nil, err
+		}
+		return manager.Load(name)
+	}
+	return loadRequestFile(name, variables)
+}
+
+// loadRequestFile parses a request file into a collection named after it,
+// with the given variables, if any, overriding the file's
+func loadRequestFile(name string, variables map[string]string) (*collection.Collection, error) {
+	parse, ok := requestFileFormats[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type: %s", name)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := parse(string(data), variables)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return &collection.Collection{Name: filepath.Base(name), Requests: requests}, nil
+}
+
+// isRequestFile reports whether name refers to a REST Client or Hurl file
+func isRequestFile(name string) bool {
+	_, ok := requestFileFormats[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// captureStdout returns what f writes to stdout
+func captureStdout(f func()) string {
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	f()
+
+	w.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String()
+}
+
+func TestRunCollection_HTTPFile(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/login" {
+			w.Write([]byte(`{"token": "secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "api.http")
+	text := "@base = " + server.URL + `
+
+### login
+POST {{base}}/login
+
+### health
+GET {{base}}/health
+Authorization: Bearer {{login.response.body.$.token}}
+
+### orders
+GET {{base}}/orders
+Authorization: Bearer {{login.response.body.$.token}}
+`
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatalf("Failed to write .http file: %v", err)
+	}
+
+	var code int
+	output := captureStdout(func() {
+		code = runCollection([]string{file, "--request-name", "orders"})
+	})
+	if code != 0 {
+		t.Fatalf("runCollection() = %d, want 0\n%s", code, output)
+	}
+	if strings.Join(paths, ",") != "/login,/orders" {
+		t.Errorf("Expected only orders and its dependency to run, got %v", paths)
+	}
+	if !strings.Contains(output, "Collection: api.http") || !strings.Contains(output, "PASS  orders") {
+		t.Errorf("Unexpected report:\n%s", output)
+	}
+
+	output = captureStdout(func() {
+		code = runCollection([]string{file, "--request-name", "missing"})
+	})
+	if code != 1 || !strings.Contains(output, "request not found") {
+		t.Errorf("Expected unknown request error, got %d %s", code, output)
+	}
+}
+
+func TestRunCollection_SetVariables(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path+"?"+r.URL.RawQuery)
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	c := &collection.Collection{Name: "smoke", Requests: []collection.SavedRequest{
+		{Name: "health", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/health", QueryParams: map[string]string{"tenant": "{{tenant}}"}}},
+	}}
+	if err := manager.Save(c); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	env := &environment.Environment{Name: "staging", Variables: map[string]string{"base_url": "http://staging.invalid", "tenant": "acme"}}
+	if err := environment.Save(env); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var code int
+	output := captureStdout(func() {
+		code = runCollection([]string{"smoke", "--env", "staging", "--set", "base_url=" + server.URL})
+	})
+	if code != 0 || strings.Join(requested, ",") != "/health?tenant=acme" {
+		t.Errorf("Expected the override to point the run at the test server, got %d %v\n%s", code, requested, output)
+	}
+
+	// Overrides work without an environment, for .http file variables too
+	file := filepath.Join(t.TempDir(), "smoke.http")
+	if err := os.WriteFile(file, []byte("@base_url = http://production.invalid\n\nGET {{base_url}}/health?tenant={{tenant}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	requested = nil
+	output = captureStdout(func() {
+		code = runCollection([]string{file, "--set", "base_url=" + server.URL, "--set", "tenant=beta"})
+	})
+	if code != 0 || strings.Join(requested, ",") != "/health?tenant=beta" {
+		t.Errorf("Expected the file variable to be overridden, got %d %v\n%s", code, requested, output)
+	}
+
+	output = captureStdout(func() { code = runCollection([]string{"smoke", "--set", "base_url"}) })
+	if code != 1 || !strings.Contains(output, `invalid --set "base_url"`) {
+		t.Errorf("Expected an invalid override to be reported, got %d %s", code, output)
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	// Create a temporary home with a saved collection
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	manager.Save(&collection.Collection{
+		Name:     "smoke",
+		Requests: []collection.SavedRequest{{Name: "health", Request: request.RequestData{Method: "GET", URL: "https://api.example.com/health"}}},
+	})
+
+	var code int
+	output := captureStdout(func() { code = runExport([]string{"smoke"}) })
+	if code != 0 || !strings.Contains(output, "### health\n# @name health\nGET https://api.example.com/health\n") {
+		t.Errorf("Unexpected export: %d\n%s", code, output)
+	}
+
+	file := filepath.Join(tmpDir, "smoke.http")
+	captureStdout(func() { code = runExport([]string{"smoke", "--output", file}) })
+	if data, err := os.ReadFile(file); code != 0 || err != nil || !strings.Contains(string(data), "GET https://api.example.com/health") {
+		t.Errorf("Expected export file to be written, got %d %v", code, err)
+	}
+
+	captureStdout(func() { code = runExport([]string{"smoke", "--format", "postman"}) })
+	if code != 2 {
+		t.Errorf("Expected usage error for unsupported format, got %d", code)
+	}
+}
+
+func TestRunCollection_HurlFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Write([]byte(`{"token": "secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		w.Write([]byte(`{"name": "Jane"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "checks.hurl")
+	text := "POST " + server.URL + `/login
+HTTP 200
+[Captures]
+token: jsonpath "$.token"
+
+GET ` + server.URL + `/me
+Authorization: Bearer {{token}}
+HTTP 200
+[Asserts]
+jsonpath "$.name" == "{{expected_name}}"
+`
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatalf("Failed to write .hurl file: %v", err)
+	}
+
+	// Asserts are checked against the expected values from the environment
+	var code int
+	t.Setenv("expected_name", "John")
+	output := captureStdout(func() { code = runCollection([]string{file}) })
+	if code != 1 || !strings.Contains(output, `assert: jsonpath "$.name" == "John": got "Jane"`) {
+		t.Errorf("Expected assert failure, got %d\n%s", code, output)
+	}
+
+	t.Setenv("expected_name", "Jane")
+	output = captureStdout(func() { code = runCollection([]string{file}) })
+	if code != 0 || !strings.Contains(output, "PASS  request-2") {
+		t.Errorf("Expected .hurl run to pass, got %d\n%s", code, output)
+	}
+
+	// The file can be imported as a collection
+	t.Setenv("HOME", dir)
+	output = captureStdout(func() { code = runImport([]string{file, "--name", "checks"}) })
+	if code != 0 || !strings.Contains(output, "Imported 2 requests into collection checks") {
+		t.Errorf("Unexpected import result: %d %s", code, output)
+	}
+	output = captureStdout(func() { code = runCollection([]string{"checks"}) })
+	if code != 0 {
+		t.Errorf("Expected imported collection to pass, got %d\n%s", code, output)
+	}
+	output = captureStdout(func() { code = runExport([]string{"checks", "--format", "hurl"}) })
+	if code != 0 || !strings.Contains(output, "[Captures]\nrequest_1_token: jsonpath \"$.token\"") {
+		t.Errorf("Unexpected hurl export: %d\n%s", code, output)
+	}
+}
+
+func TestRunImportWSDL(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	file := filepath.Join(dir, "weather.wsdl")
+	wsdl := `<definitions targetNamespace="urn:weather" xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:tns="urn:weather" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <message name="GetForecast"><part name="city" type="xsd:string"/></message>
+  <portType name="WeatherPort"><operation name="GetForecast"><input message="tns:GetForecast"/></operation></portType>
+  <binding name="WeatherBinding" type="tns:WeatherPort"><operation name="GetForecast"><soap:operation soapAction="urn:GetForecast"/></operation></binding>
+  <service name="Weather"><port name="WeatherPort" binding="tns:WeatherBinding"><soap:address location="http://weather.example.com/rpc"/></port></service>
+</definitions>`
+	if err := os.WriteFile(file, []byte(wsdl), 0644); err != nil {
+		t.Fatalf("Failed to write WSDL: %v", err)
+	}
+
+	var code int
+	output := captureStdout(func() { code = runImportWSDL([]string{file}) })
+	if code != 0 || !strings.Contains(output, "Imported 1 requests into collection weather") {
+		t.Fatalf("Unexpected import result: %d %s", code, output)
+	}
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	saved, err := manager.Get("weather/GetForecast")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if saved.Request.Headers["SOAPAction"] != `"urn:GetForecast"` || !strings.Contains(saved.Request.Body, "<city>{{city}}</city>") {
+		t.Errorf("Unexpected imported request: %+v", saved.Request)
+	}
+
+	captureStdout(func() { code = runImportWSDL([]string{filepath.Join(dir, "missing.wsdl")}) })
+	if code != 1 {
+		t.Errorf("Expected missing file to fail, got %d", code)
+	}
+}
+
+func TestRunCollection_Waterfall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "flow.http")
+	if err := os.WriteFile(file, []byte("GET "+server.URL+"/login\n\n###\n\nGET "+server.URL+"/cart\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	html := filepath.Join(t.TempDir(), "flow.html")
+
+	var code int
+	report := filepath.Join(t.TempDir(), "run.html")
+	output := captureStdout(func() {
+		code = runCollection([]string{file, "--waterfall", "--waterfall-file", html, "--report-html", report})
+	})
+	if code != 0 || !strings.Contains(output, "Waterfall: 2 requests, wall time") || !strings.Contains(output, "Wrote the waterfall to "+html) {
+		t.Errorf("Expected the waterfall to be printed and written, got %d:\n%s", code, output)
+	}
+	data, err := os.ReadFile(html)
+	if err != nil || !strings.Contains(string(data), "<!DOCTYPE html>") {
+		t.Errorf("Expected an HTML waterfall, got %v", err)
+	}
+	if !strings.Contains(output, "Wrote the HTML report to "+report) {
+		t.Errorf("Expected the HTML report to be written, got:\n%s", output)
+	}
+	if data, err := os.ReadFile(report); err != nil || !strings.Contains(string(data), "flow.http run report") {
+		t.Errorf("Expected an HTML report of the run, got %v", err)
+	}
+
+	output = captureStdout(func() { code = runCollection([]string{file, "--waterfall-file", "flow.svg"}) })
+	if code != 1 || !strings.Contains(output, "expected .json or .html") {
+		t.Errorf("Expected an unsupported format to be rejected, got %d:\n%s", code, output)
+	}
+}
+
+func TestRunCollection_Webhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cart" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	var posts []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts = append(posts, string(body))
+	}))
+	defer webhook.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{Webhooks: []notify.Webhook{
+		{URL: webhook.URL, Template: "{{.Name}}: {{.Summary}}"},
+		{URL: webhook.URL, On: []string{notify.EventMonitorFailure}},
+	}}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "flow.http")
+	if err := os.WriteFile(file, []byte("GET "+server.URL+"/login\n\n###\n\nGET "+server.URL+"/cart\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	captureStdout(func() { code = runCollection([]string{file}) })
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if len(posts) != 1 || !strings.Contains(posts[0], `Run of flow.http failed`) || !strings.Contains(posts[0], `flow.http: 1 passed, 1 failed in`) {
+		t.Errorf("Expected one templated post about the run, got %q", posts)
+	}
+}
+
+func TestRunCollection_Replay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	token := "old"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Write([]byte(`{"token": "` + token + `"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer old" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+
+	file := filepath.Join(t.TempDir(), "api.http")
+	text := "@base = " + server.URL + `
+
+### login
+POST {{base}}/login
+
+### orders
+GET {{base}}/orders
+Authorization: Bearer {{login.response.body.$.token}}
+`
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatalf("Failed to write .http file: %v", err)
+	}
+	har := filepath.Join(t.TempDir(), "session.har")
+	var code int
+	output := captureStdout(func() { code = runCollection([]string{file, "--record", har}) })
+	if code != 0 {
+		t.Fatalf("Expected the recorded run to pass, got %d:\n%s", code, output)
+	}
+
+	// The upstream change breaks the chain, which the recorded responses
+	// still reproduce without the server
+	token = "new"
+	if output := captureStdout(func() { code = runCollection([]string{file}) }); code != 1 {
+		t.Fatalf("Expected the live run to fail, got %d:\n%s", code, output)
+	}
+	server.Close()
+	output = captureStdout(func() { code = runCollection([]string{file, "--replay", har, "--as-of", "today"}) })
+	if code != 0 || !strings.Contains(output, "Replaying 2 recorded responses from "+har) {
+		t.Errorf("Expected the replayed run to pass, got %d:\n%s", code, output)
+	}
+
+	output = captureStdout(func() { code = runCollection([]string{file, "--replay", har, "--as-of", "yesterday"}) })
+	if code != 1 || !strings.Contains(output, "no response to POST "+server.URL+"/login was recorded before") {
+		t.Errorf("Expected nothing recorded before today, got %d:\n%s", code, output)
+	}
+	if output := captureStdout(func() { code = runCollection([]string{file, "--as-of", "today"}) }); code != 2 {
+		t.Errorf("Expected --as-of without --replay to be a usage error, got %d:\n%s", code, output)
+	}
+}
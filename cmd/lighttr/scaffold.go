@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/importer"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runScaffold builds a request from a pasted API documentation snippet, e.g.
+//
+//	pbpaste | lighttr scaffold from-text --save my-collection/create-user
+func runScaffold(args []string) int {
+	if len(args) == 0 || args[0] != "from-text" {
+		fmt.Println("Usage: lighttr scaffold from-text [file] [--save collection/request]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("scaffold from-text", flag.ContinueOnError)
+	save := fs.String("save", "", "Save the request to a collection as collection/request")
+
+	// Allow flags after the file
+	var file string
+	args = args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Println("Usage: lighttr scaffold from-text [file] [--save collection/request]")
+		return 2
+	}
+
+	// Read the snippet from a file or stdin. Questions are answered on the
+	// terminal when the snippet is piped in.
+	var text []byte
+	var err error
+	answers := os.Stdin
+	if file != "" {
+		text, err = os.ReadFile(file)
+	} else {
+		text, err = io.ReadAll(os.Stdin)
+		answers, _ = os.Open("/dev/tty")
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	var in *bufio.Reader
+	if answers != nil {
+		defer answers.Close()
+		in = bufio.NewReader(answers)
+	}
+
+	req, err := scaffoldFromText(string(text), in, os.Stdout)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if *save != "" {
+		if err := saveScaffold(req, *save); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Saved %s\n", *save)
+		return 0
+	}
+
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+// scaffoldFromText parses the snippet and asks about anything ambiguous.
+// With no input reader the guessed values are kept and a missing URL is an
+// error.
+func scaffoldFromText(text string, in *bufio.Reader, out io.Writer) (*request.RequestData, error) {
+	result, err := importer.ParseText(text)
+	if err != nil {
+		return nil, err
+	}
+	req := result.Request
+	fmt.Fprintf(out, "Recognized %s snippet: %s %s\n", result.Format, req.Method, req.URL)
+
+	ask := func(question, defaultValue string) string {
+		if in == nil {
+			return defaultValue
+		}
+		if defaultValue != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+		answer, _ := in.ReadString('\n')
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer
+		}
+		return defaultValue
+	}
+
+	for _, field := range result.Ambiguous {
+		switch field {
+		case importer.FieldScheme:
+			if u, err := url.Parse(req.URL); err == nil {
+				u.Scheme = ask(fmt.Sprintf("Scheme for %s", u.Host), u.Scheme)
+				req.URL = u.String()
+			}
+		case importer.FieldURL:
+			if req.URL == "" {
+				req.URL = ask("URL", "")
+			} else {
+				base := ask(fmt.Sprintf("Base URL for %s", req.URL), "")
+				if base != "" {
+					req.URL = strings.TrimRight(base, "/") + "/" + strings.TrimLeft(req.URL, "/")
+				}
+			}
+		case importer.FieldMethod:
+			req.Method = strings.ToUpper(ask("Method", req.Method))
+		}
+	}
+
+	// A URL starting with a placeholder such as {{base}} is only complete
+	// once variables are substituted, so check the rest of it behind a
+	// stand-in host
+	check := *req
+	if strings.HasPrefix(check.URL, "{{") {
+		if end := strings.Index(check.URL, "}}"); end >= 0 {
+			check.URL = "https://placeholder" + check.URL[end+2:]
+		}
+	}
+	if err := check.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// saveScaffold stores the request in a collection, creating the collection
+// or replacing a request with the same name as needed
+func saveScaffold(req *request.RequestData, ref string) error {
+	collectionName, requestName, ok := strings.Cut(ref, "/")
+	if !ok || collectionName == "" || requestName == "" {
+		return fmt.Errorf("invalid request reference %q: expected collection/request", ref)
+	}
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		return err
+	}
+	c, err := manager.Load(collectionName)
+	if errors.Is(err, collection.ErrNotFound) {
+		c = &collection.Collection{Name: collectionName}
+	} else if err != nil {
+		return err
+	}
+
+	if saved, ok := c.Find(requestName); ok {
+		saved.Request = *req
+	} else {
+		c.Requests = append(c.Requests, collection.SavedRequest{Name: requestName, Request: *req})
+	}
+	return manager.Save(c)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+)
+
+func TestScaffoldFromText(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		answers    string
+		wantMethod string
+		wantURL    string
+		wantErr    bool
+	}{
+		{
+			name:       "curl needs no questions",
+			text:       "curl -X DELETE https://api.example.com/users/1",
+			wantMethod: "DELETE",
+			wantURL:    "https://api.example.com/users/1",
+		},
+		{
+			name:       "json body asks for url and method",
+			text:       "```json\n{\"name\": \"Jane\"}\n```",
+			answers:    "https://api.example.com/users\nput\n",
+			wantMethod: "PUT",
+			wantURL:    "https://api.example.com/users",
+		},
+		{
+			name:       "http block keeps default scheme",
+			text:       "GET /users HTTP/1.1\nHost: api.example.com\n",
+			answers:    "\n",
+			wantMethod: "GET",
+			wantURL:    "https://api.example.com/users",
+		},
+		{
+			name:       "relative http target asks for base url",
+			text:       "GET /users HTTP/1.1\n",
+			answers:    "http://localhost:8080/\n",
+			wantMethod: "GET",
+			wantURL:    "http://localhost:8080/users",
+		},
+		{
+			name:       "placeholder base url is kept",
+			text:       "curl {{base}}/users",
+			wantMethod: "GET",
+			wantURL:    "{{base}}/users",
+		},
+		{
+			name:    "unanswered url is an error",
+			text:    "{\"name\": \"Jane\"}",
+			answers: "\n\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := bufio.NewReader(strings.NewReader(tt.answers))
+			req, err := scaffoldFromText(tt.text, in, io.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scaffoldFromText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if req.Method != tt.wantMethod {
+				t.Errorf("Method = %s, want %s", req.Method, tt.wantMethod)
+			}
+			if req.URL != tt.wantURL {
+				t.Errorf("URL = %s, want %s", req.URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestSaveScaffold(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	req, err := scaffoldFromText("curl https://api.example.com/users", nil, io.Discard)
+	if err != nil {
+		t.Fatalf("scaffoldFromText() error = %v", err)
+	}
+	if err := saveScaffold(req, "docs/list-users"); err != nil {
+		t.Fatalf("saveScaffold() error = %v", err)
+	}
+	req.Method = "HEAD"
+	if err := saveScaffold(req, "docs/list-users"); err != nil {
+		t.Fatalf("saveScaffold() error = %v", err)
+	}
+
+	manager, _ := collection.NewManager()
+	saved, err := manager.Get("docs/list-users")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if saved.Request.Method != "HEAD" {
+		t.Errorf("Expected saved request to be replaced, got %s", saved.Request.Method)
+	}
+
+	if err := saveScaffold(req, "invalid"); err == nil {
+		t.Error("Expected error for invalid reference")
+	}
+}
+
+func TestRunScaffold_SaveAfterFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	file := filepath.Join(t.TempDir(), "snippet.md")
+	if err := os.WriteFile(file, []byte("curl -X POST https://api.example.com/users"), 0644); err != nil {
+		t.Fatalf("Failed to write snippet: %v", err)
+	}
+
+	var code int
+	output := captureStdout(func() {
+		code = runScaffold([]string{"from-text", file, "--save", "demo/create"})
+	})
+	if code != 0 {
+		t.Fatalf("runScaffold() = %d, want 0; output: %s", code, output)
+	}
+	if !strings.Contains(output, "Saved demo/create") {
+		t.Errorf("Expected save confirmation, got: %s", output)
+	}
+
+	manager, _ := collection.NewManager()
+	saved, err := manager.Get("demo/create")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if saved.Request.Method != "POST" {
+		t.Errorf("Method = %s, want POST", saved.Request.Method)
+	}
+
+	captureStdout(func() {
+		code = runScaffold([]string{"from-text", file, "extra"})
+	})
+	if code != 2 {
+		t.Errorf("runScaffold() with extra argument = %d, want 2", code)
+	}
+}
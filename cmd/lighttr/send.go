@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// multiFlag collects the values of a repeatable string flag
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *multiFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runSend executes a saved request, e.g.
+//
+//	lighttr send my-collection/create-user --set body.name=Jane --header 'X-Debug: 1'
+func runSend(args []string) int {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	var sets, headers multiFlag
+	fs.Var(&sets, "set", "Override a field (url, method, body, header.<name>, query.<name>, path.<name>, form.<name>, body.<json.path>)")
+	fs.Var(&headers, "header", "Additional header in 'Name: value' format (repeatable)")
+	env := fs.String("env", "", "Environment used to resolve {{var}} placeholders")
+	cookies := fs.Bool("cookies", false, "Load and save cookies in ~/.lighttr/cookies.json")
+	record := fs.String("record", "", "Append the request and response to this HAR file")
+	xpath := fs.String("xpath", "", "Print the values an XPath expression selects from an XML response instead of the response")
+	selector := fs.String("select", "", "Print the text of the elements a CSS selector matches in an HTML response instead of the response")
+	schemaSpec := fs.String("schema", "", "Validate the response against a protobuf message (file.proto:Message) or Avro schema (file.avsc)")
+	output := fs.String("output", "", "Save the response body to this file instead of printing it")
+	remoteName := fs.Bool("remote-name", false, "Save the response body under the name the server or URL suggests, without overwriting files")
+	authProfile := fs.String("auth-profile", "", "Send the credentials saved as this auth profile instead of the request's auth")
+	maxSize := fs.String("max-size", "", "Stop reading the response body after this size, e.g. 1GB, or off (default from max_response_size in the config, else 100MB)")
+
+	// Allow flags after the request reference
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lighttr send <collection>/<request> [--set path=value] [--header 'Name: value']")
+		return 2
+	}
+	ref := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	saved, err := manager.Get(ref)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	req := saved.Request.Clone()
+	for _, set := range sets {
+		if err := applyOverride(req, set); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			fmt.Printf("Error: invalid header %q: expected 'Name: value'\n", header)
+			return 1
+		}
+		setHeader(req.Headers, strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if *authProfile != "" {
+		// The request's CA settings still apply unless the profile has its
+		// own
+		req.Auth.Type = request.ProfileAuth
+		req.Auth.Profile = *authProfile
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	req.DefaultHeaders = cfg.DefaultHeaders
+	if err := applyMaxResponseSize(req, cfg, *maxSize); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if err := resolvePlaceholders(req, *env); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	req.Proxy.ApplyCredentials(cfg.ProxyAuth)
+	var warnings []string
+	req.URL, warnings = request.NormalizeURL(req.URL, cfg.DefaultScheme)
+	printWarnings(warnings)
+
+	if err := req.Validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	query, err := compileQuery(*xpath, *selector)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	schema, err := loadSchema(*schemaSpec)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	jar, err := cfg.CookieJar(*cookies)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	var recorder *request.HARRecorder
+	if *record != "" {
+		if recorder, err = request.OpenHARRecorder(*record); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	resp, err := req.ExecuteWith(request.WithCookieJar(jar), request.WithHARRecorder(recorder))
+	if err != nil {
+		fmt.Printf("Error executing request: %v\n", err)
+		return 1
+	}
+	if err := jar.Save(); err != nil {
+		fmt.Printf("Error: failed to save cookies: %v\n", err)
+		return 1
+	}
+	if resp.Error != "" {
+		fmt.Printf("Error: %s\n", resp.Error)
+		printSuggestions(resp.ErrorKind)
+		return 1
+	}
+
+	if query != nil {
+		if err := printMatches(resp, query); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	} else {
+		savedTo, err := saveResponse(resp, req.ResolvedURL(), *output, *remoteName)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		printResponse(resp, savedTo)
+	}
+	if schema != nil && !printSchemaCheck(resp, schema) {
+		return 1
+	}
+	return 0
+}
+
+// resolvePlaceholders substitutes {{var}} templates in the request using the
+// named environment and the process environment
+func resolvePlaceholders(req *request.RequestData, envName string) error {
+	var env *environment.Environment
+	if envName != "" {
+		var err error
+		if env, err = environment.Load(envName); err != nil {
+			return err
+		}
+	}
+
+	if missing := env.ResolveRequest(req); len(missing) > 0 {
+		return fmt.Errorf("unresolved variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// applyMaxResponseSize limits the response body the request reads: to
+// maxSize when the flag was given, otherwise to the request's own limit or
+// the configured one
+func applyMaxResponseSize(req *request.RequestData, cfg *config.Config, maxSize string) error {
+	if maxSize != "" {
+		limit, err := config.ParseMaxResponseSize(maxSize)
+		if err != nil {
+			return err
+		}
+		req.MaxResponseSize = limit
+		return nil
+	}
+	if req.MaxResponseSize == 0 {
+		req.MaxResponseSize = cfg.ResponseSizeLimit()
+	}
+	return nil
+}
+
+// applyOverride applies a single path=value override to the request
+func applyOverride(req *request.RequestData, override string) error {
+	path, value, ok := strings.Cut(override, "=")
+	if !ok || path == "" {
+		return fmt.Errorf("invalid override %q: expected path=value", override)
+	}
+
+	field, rest, _ := strings.Cut(path, ".")
+	switch field {
+	case "url":
+		req.URL = value
+	case "method":
+		req.Method = strings.ToUpper(value)
+	case "body":
+		if rest == "" {
+			req.Body = value
+			return nil
+		}
+		body, err := setJSONField(req.Body, rest, value)
+		if err != nil {
+			return fmt.Errorf("failed to override %s: %v", path, err)
+		}
+		req.Body = body
+	case "header":
+		if rest == "" {
+			return fmt.Errorf("invalid override %q: missing header name", override)
+		}
+		setHeader(req.Headers, rest, value)
+	case "query":
+		if rest == "" {
+			return fmt.Errorf("invalid override %q: missing query parameter name", override)
+		}
+		req.QueryParams[rest] = value
+	case "path":
+		if rest == "" {
+			return fmt.Errorf("invalid override %q: missing path parameter name", override)
+		}
+		req.PathParams[rest] = value
+	case "form":
+		if rest == "" {
+			return fmt.Errorf("invalid override %q: missing form param name", override)
+		}
+		if req.FormParams == nil {
+			req.FormParams = make(map[string]string)
+		}
+		req.FormParams[rest] = value
+	default:
+		return fmt.Errorf("invalid override %q: unknown field %s", override, field)
+	}
+	return nil
+}
+
+// setHeader sets a header, replacing its value under the name it is
+// already set with in any case, so a header is never sent twice
+func setHeader(headers map[string]string, name, value string) {
+	for key := range headers {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) {
+			delete(headers, key)
+			name = key
+		}
+	}
+	headers[name] = value
+}
+
+// setJSONField sets the value at a dotted path inside a JSON document.
+// Numeric segments index into arrays and missing objects are created. The
+// value is parsed as JSON when possible and used as a string otherwise.
+// Keys keep their order and numbers their precision.
+func setJSONField(body, path, value string) (string, error) {
+	var doc interface{} = &jsonObject{}
+	if strings.TrimSpace(body) != "" {
+		var err error
+		if doc, err = parseJSON(body); err != nil {
+			return "", fmt.Errorf("body is not valid JSON: %v", err)
+		}
+	}
+
+	parsed, err := parseJSON(value)
+	if err != nil {
+		parsed = value
+	}
+
+	updated, err := setPath(doc, strings.Split(path, "."), parsed)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// jsonObject is a JSON object that keeps the order of its keys
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// set sets the value of a key, adding new keys last
+func (o *jsonObject) set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON writes the keys in their order
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// parseJSON decodes a JSON document with its objects as jsonObjects and
+// its numbers as json.Numbers, so that it is encoded again as written
+func parseJSON(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeJSON(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// decodeJSON decodes the next value from the decoder
+func decodeJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := &jsonObject{values: make(map[string]interface{})}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.(string), value)
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+// setPath recursively sets value at the given path segments
+func setPath(node interface{}, segments []string, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	key := segments[0]
+	switch n := node.(type) {
+	case *jsonObject:
+		child, err := setPath(n.values[key], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n.set(key, child)
+		return n, nil
+	case []interface{}:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(n) {
+			return nil, fmt.Errorf("invalid array index %q", key)
+		}
+		child, err := setPath(n[index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	case nil:
+		child, err := setPath(nil, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		object := &jsonObject{}
+		object.set(key, child)
+		return object, nil
+	default:
+		return nil, fmt.Errorf("cannot set %q on a non-object value", key)
+	}
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
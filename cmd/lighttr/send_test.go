@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestSetJSONField(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		path    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "replace string field",
+			body:  `{"name":"John","age":30}`,
+			path:  "name",
+			value: "Jane",
+			want:  `{"name":"Jane","age":30}`,
+		},
+		{
+			name:  "keep key order and number precision",
+			body:  `{"z":1,"id":9007199254740993,"price":1.50,"m":{"b":2,"a":1e3}}`,
+			path:  "m.c",
+			value: `{"y":12345678901234567890,"x":0.10}`,
+			want:  `{"z":1,"id":9007199254740993,"price":1.50,"m":{"b":2,"a":1e3,"c":{"y":12345678901234567890,"x":0.10}}}`,
+		},
+		{
+			name:  "trailing data is a string",
+			body:  `{}`,
+			path:  "range",
+			value: "1 2",
+			want:  `{"range":"1 2"}`,
+		},
+		{
+			name:  "json typed value",
+			body:  `{"age":30}`,
+			path:  "age",
+			value: "31",
+			want:  `{"age":31}`,
+		},
+		{
+			name:  "create nested object",
+			body:  "",
+			path:  "user.address.city",
+			value: "Paris",
+			want:  `{"user":{"address":{"city":"Paris"}}}`,
+		},
+		{
+			name:  "array index",
+			body:  `{"items":[{"id":1},{"id":2}]}`,
+			path:  "items.1.id",
+			value: "5",
+			want:  `{"items":[{"id":1},{"id":5}]}`,
+		},
+		{
+			name:    "array index out of range",
+			body:    `{"items":[]}`,
+			path:    "items.3",
+			value:   "1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid body",
+			body:    "not json",
+			path:    "name",
+			value:   "Jane",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setJSONField(tt.body, tt.path, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setJSONField() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("setJSONField() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOverride(t *testing.T) {
+	req := request.NewRequestData()
+	req.URL = "https://api.example.com"
+	req.Headers["x-debug"] = "0"
+
+	overrides := []string{
+		"method=post",
+		"url=https://staging.example.com",
+		"header.X-Debug=1",
+		"query.page=2",
+		"path.id=42",
+		"form.user=jane",
+		"body.name=Jane",
+	}
+	for _, override := range overrides {
+		if err := applyOverride(req, override); err != nil {
+			t.Fatalf("applyOverride(%q) error = %v", override, err)
+		}
+	}
+
+	if req.Method != "POST" {
+		t.Errorf("Expected method POST, got %s", req.Method)
+	}
+	if req.URL != "https://staging.example.com" {
+		t.Errorf("Expected overridden URL, got %s", req.URL)
+	}
+	if req.Headers["x-debug"] != "1" || len(req.Headers) != 1 {
+		t.Errorf("Expected the x-debug header to be replaced, got %v", req.Headers)
+	}
+	if req.QueryParams["page"] != "2" {
+		t.Errorf("Expected page query param, got %v", req.QueryParams)
+	}
+	if req.PathParams["id"] != "42" {
+		t.Errorf("Expected id path param, got %v", req.PathParams)
+	}
+	if req.FormParams["user"] != "jane" {
+		t.Errorf("Expected user form param, got %v", req.FormParams)
+	}
+	if req.Body != `{"name":"Jane"}` {
+		t.Errorf("Expected body override, got %s", req.Body)
+	}
+
+	for _, invalid := range []string{"nopath", "header.=1", "form.=1", "unknown.x=1"} {
+		if err := applyOverride(req, invalid); err == nil {
+			t.Errorf("Expected error for override %q", invalid)
+		}
+	}
+}
+
+func TestRunSend(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if values := r.Header.Values("X-Debug"); len(values) != 1 || values[0] != "1" {
+			t.Errorf("Expected the saved x-debug header to be replaced, got %q", values)
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &received)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	// Create a temporary home with a saved collection
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	err = manager.Save(&collection.Collection{
+		Name: "my-collection",
+		Requests: []collection.SavedRequest{
+			{
+				Name: "create-user",
+				Request: request.RequestData{
+					Method:  "POST",
+					URL:     server.URL + "/users",
+					Headers: map[string]string{"x-debug": "0"},
+					Body:    `{"name":"John","role":"admin"}`,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Silence stdout
+	oldStdout := os.Stdout
+	devNull, _ := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	os.Stdout = devNull
+	code := runSend([]string{"my-collection/create-user", "--set", "body.name=Jane", "--header", "X-Debug: 1"})
+	os.Stdout = oldStdout
+
+	if code != 0 {
+		t.Fatalf("runSend() = %d, want 0", code)
+	}
+	want := map[string]interface{}{"name": "Jane", "role": "admin"}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("Expected body %v, got %v", want, received)
+	}
+
+	os.Stdout = devNull
+	code = runSend([]string{"my-collection/missing"})
+	os.Stdout = oldStdout
+	if code == 0 {
+		t.Error("Expected non-zero exit code for missing request")
+	}
+}
+
+func TestRunSend_Schema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id": "7", "name": 42}`)
+	}))
+	defer server.Close()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := manager.Save(&collection.Collection{Name: "users", Requests: []collection.SavedRequest{
+		{Name: "get", Request: request.RequestData{Method: "GET", URL: server.URL}},
+	}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	proto := filepath.Join(home, "user.proto")
+	if err := os.WriteFile(proto, []byte(`syntax = "proto3"; message User { int64 id = 1; string name = 2; }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	output := captureStdout(func() { code = runSend([]string{"users/get", "--schema", proto}) })
+	if code != 1 || !strings.Contains(output, "Schema: 1 error against User\n  .name: expected a string, got a number") {
+		t.Errorf("Expected the schema error to be reported, got %d:\n%s", code, output)
+	}
+
+	output = captureStdout(func() { code = runSend([]string{"users/get", "--schema", proto + ":Missing"}) })
+	if code != 1 || !strings.Contains(output, "message Missing not found") {
+		t.Errorf("Expected the schema to fail to load, got %d:\n%s", code, output)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/nshekhawat/lighttr/internal/server"
+)
+
+// runServe exposes lighttr as a JSON API on a unix socket for editors and
+// scripts, e.g.
+//
+//	lighttr serve --socket ~/.lighttr/ipc.sock
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	socket := fs.String("socket", "", "Unix socket to listen on (default ~/.lighttr/ipc.sock)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *socket == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		*socket = filepath.Join(homeDir, ".lighttr", "ipc.sock")
+	}
+
+	s, err := server.New()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	listener, err := server.Listen(*socket)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	// Close the listener on Ctrl+C so the socket file is removed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	fmt.Printf("Listening on %s\n", *socket)
+	if err := http.Serve(listener, s.Handler()); err != nil && !errors.Is(err, net.ErrClosed) {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
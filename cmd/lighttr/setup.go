@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/importer"
+	"github.com/nshekhawat/lighttr/internal/tui"
+)
+
+// runSetup runs the onboarding wizard that is otherwise shown on first
+// launch, e.g.
+//
+//	lighttr setup
+func runSetup(args []string) int {
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := onboard(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// onboard runs the onboarding wizard and applies the choices made. The
+// configuration is saved even when the wizard is skipped, so that it is not
+// shown on the next launch.
+func onboard() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	applyDisplay(cfg)
+	final, err := tea.NewProgram(tui.NewOnboarding(cfg)).Run()
+	if err != nil {
+		return err
+	}
+	setup, ok := final.(tui.Onboarding).Setup()
+	if !ok {
+		return cfg.Save()
+	}
+	return applySetup(cfg, setup)
+}
+
+// applySetup saves the choices made in the onboarding wizard
+func applySetup(cfg *config.Config, setup tui.Setup) error {
+	cfg.Theme = setup.Theme
+	cfg.Keybindings = setup.Keybindings
+	cfg.DataDir = setup.DataDir
+	if dataDir, err := config.ExpandHome(setup.DataDir); err == nil {
+		if path, err := config.Path(); err == nil && dataDir == filepath.Dir(path) {
+			cfg.DataDir = ""
+		}
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Println("Saved the configuration")
+
+	if setup.Import != "" {
+		c, err := loadSetupImport(setup.Import)
+		if err != nil {
+			return err
+		}
+		name := ""
+		if setup.Import == "curl" {
+			name = "curl-history"
+		}
+		if err := storeImported(c, name, setup.Import); err != nil {
+			return err
+		}
+	}
+
+	if setup.BaseURL != "" {
+		name := setup.EnvName
+		if name == "" {
+			name = "dev"
+		}
+		env := &environment.Environment{Name: name, Variables: map[string]string{"base_url": setup.BaseURL}}
+		if err := environment.Save(env); err != nil {
+			return err
+		}
+		fmt.Printf("Created environment %s: use {{base_url}} in requests and --env %s\n", name, name)
+	}
+	return nil
+}
+
+// loadSetupImport reads the requests to import: the curl commands in the
+// shell history for "curl", or else an export or request file
+func loadSetupImport(source string) (*collection.Collection, error) {
+	if source != "curl" {
+		source, err := config.ExpandHome(source)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(filepath.Ext(source), ".json") {
+			return loadExport(source)
+		}
+		return loadRequestFile(source, nil)
+	}
+
+	var requests []collection.SavedRequest
+	for _, file := range shellHistoryFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		requests = append(requests, importer.ParseShellHistory(string(data))...)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no curl commands found in the shell history")
+	}
+	for i := range requests {
+		requests[i].Name = fmt.Sprintf("curl-%d", i+1)
+	}
+	return &collection.Collection{Requests: requests}, nil
+}
+
+// shellHistoryFiles returns the history files of the user's shells: $HISTFILE
+// when set, otherwise the bash and zsh defaults
+func shellHistoryFiles() []string {
+	if file := os.Getenv("HISTFILE"); file != "" {
+		return []string{file}
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(homeDir, ".bash_history"), filepath.Join(homeDir, ".zsh_history")}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/tui"
+)
+
+func TestApplySetup(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	historyFile := filepath.Join(home, "history")
+	t.Setenv("HISTFILE", historyFile)
+	history := "curl https://api.example.com/health\ngit status\ncurl -X DELETE https://api.example.com/items/1\n"
+	if err := os.WriteFile(historyFile, []byte(history), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	setup := tui.Setup{Theme: "light", Keybindings: "emacs", DataDir: "~/api-data", Import: "curl", EnvName: "local", BaseURL: "http://localhost:8080"}
+	var err error
+	output := captureStdout(func() { err = applySetup(&config.Config{}, setup) })
+	if err != nil {
+		t.Fatalf("applySetup() error = %v", err)
+	}
+	if !strings.Contains(output, "Imported 2 requests into collection curl-history") {
+		t.Errorf("Expected the import to be reported, got:\n%s", output)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Theme != "light" || cfg.Keybindings != "emacs" || cfg.DataDir != "~/api-data" {
+		t.Errorf("Expected the choices to be saved, got %+v", cfg)
+	}
+
+	// Collections and environments go to the chosen data directory
+	if _, err := os.Stat(filepath.Join(home, "api-data", "collections", "curl-history.json")); err != nil {
+		t.Errorf("Expected the collection in the data directory: %v", err)
+	}
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	saved, err := manager.Get("curl-history/curl-2")
+	if err != nil || saved.Request.Method != "DELETE" {
+		t.Errorf("Expected the second curl command to be imported, got %+v, %v", saved, err)
+	}
+	env, err := environment.Load("local")
+	if err != nil || env.Variables["base_url"] != "http://localhost:8080" {
+		t.Errorf("Expected the environment to be created, got %+v, %v", env, err)
+	}
+
+	// Keeping the default data directory leaves it unset
+	if err := applySetup(cfg, tui.Setup{Theme: "default", Keybindings: "default", DataDir: "~/.lighttr"}); err != nil {
+		t.Fatalf("applySetup() error = %v", err)
+	}
+	if cfg, _ := config.Load(); cfg.DataDir != "" {
+		t.Errorf("Expected no data_dir for the default, got %q", cfg.DataDir)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/oauth"
+)
+
+// runTokens lists the cached OAuth2 tokens, or forgets those of a host,
+// e.g.
+//
+//	lighttr tokens
+//	lighttr tokens revoke login.microsoftonline.com --client-id abc
+func runTokens(args []string) int {
+	fs := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	clientID := fs.String("client-id", "", "Only revoke the tokens of this client")
+
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	// Allow flags after the host
+	var host string
+	if action == "revoke" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		host, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	switch action {
+	case "list":
+		tokens, err := oauth.CachedTokens()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No tokens are cached; log in with lighttr oauth login <profile>")
+			return 0
+		}
+		profiles, err := oauth.Profiles()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "HOST\tCLIENT ID\tPROFILES\tEXPIRES\tSTORAGE")
+		for _, token := range tokens {
+			var names []string
+			for name, profile := range profiles {
+				if profile.TokenHost() == token.Host && profile.ClientID == token.ClientID {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				names = []string{"-"}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", token.Host, token.ClientID, strings.Join(names, ", "), tokenExpiry(token), tokenStorage(token))
+		}
+		tw.Flush()
+		return 0
+
+	case "revoke":
+		if host == "" || fs.NArg() != 0 {
+			break
+		}
+		n, err := oauth.RevokeTokens(host, *clientID)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if n == 0 {
+			fmt.Printf("Error: no tokens are cached for %s\n", host)
+			return 1
+		}
+		fmt.Printf("Revoked %d cached token(s) for %s; they stay valid at the provider until they expire\n", n, host)
+		return 0
+	}
+
+	fmt.Println("Usage: lighttr tokens [list|revoke <host> [--client-id <id>]]")
+	return 2
+}
+
+// tokenExpiry describes when a cached token expires
+func tokenExpiry(token *oauth.CachedToken) string {
+	expiry := "never"
+	if !token.Expiry.IsZero() {
+		expiry = token.Expiry.Local().Format("2006-01-02 15:04")
+		if !time.Now().Before(token.Expiry) {
+			expiry += " (expired)"
+		}
+	}
+	if token.Refreshable {
+		expiry += ", refreshable"
+	}
+	return expiry
+}
+
+// tokenStorage names where a cached token is kept
+func tokenStorage(token *oauth.CachedToken) string {
+	if token.Keyring {
+		return "keyring"
+	}
+	return "file"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+)
+
+func TestRunTokens(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// Keep the test's tokens out of the system keyring
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runTokens(nil) })
+	if exitCode != 0 || !strings.Contains(output, "No tokens are cached") {
+		t.Errorf("Expected no tokens, got %d:\n%s", exitCode, output)
+	}
+
+	github := &oauth.Profile{Name: "github", TokenURL: "https://github.com/login/oauth/access_token", ClientID: "abc"}
+	if err := oauth.SaveProfile(github); err != nil {
+		t.Fatal(err)
+	}
+	tenant := &oauth.Profile{Name: "tenant", TokenURL: "https://login.example.com/token", ClientID: "xyz"}
+	for _, p := range []*oauth.Profile{github, tenant} {
+		if _, err := oauth.SaveToken(p, &oauth.Token{AccessToken: "secret", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output = captureStdout(func() { exitCode = runTokens([]string{"list"}) })
+	if exitCode != 0 || !strings.Contains(output, "github.com") || !strings.Contains(output, "login.example.com") || !strings.Contains(output, "refreshable") {
+		t.Errorf("Expected the tokens to be listed, got %d:\n%s", exitCode, output)
+	}
+	if strings.Contains(output, "secret") {
+		t.Errorf("Expected the tokens themselves not to be listed, got:\n%s", output)
+	}
+
+	output = captureStdout(func() { exitCode = runTokens([]string{"revoke", "github.com"}) })
+	if exitCode != 0 || !strings.Contains(output, "Revoked 1 cached token(s) for github.com") {
+		t.Errorf("Expected the host's token to be revoked, got %d:\n%s", exitCode, output)
+	}
+	if token, _ := oauth.LoadToken(github); token != nil {
+		t.Errorf("Expected the revoked token to be gone, got %+v", token)
+	}
+	if token, _ := oauth.LoadToken(tenant); token == nil {
+		t.Error("Expected the other host's token to be kept")
+	}
+
+	output = captureStdout(func() { exitCode = runTokens([]string{"revoke", "github.com"}) })
+	if exitCode != 1 || !strings.Contains(output, "no tokens are cached for github.com") {
+		t.Errorf("Expected nothing to revoke, got %d:\n%s", exitCode, output)
+	}
+	if code := runTokens([]string{"revoke"}); code != 2 {
+		t.Errorf("Expected exit code 2 without a host, got %d", code)
+	}
+	if code := runTokens([]string{"rotate"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown action, got %d", code)
+	}
+}
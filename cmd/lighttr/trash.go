@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+)
+
+// runDelete moves a collection or a saved request to the trash, e.g.
+//
+//	lighttr delete my-collection
+//	lighttr delete my-collection/create-user
+func runDelete(args []string) int {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: lighttr delete <collection>[/<request>]")
+		return 2
+	}
+
+	collections, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	var item collection.TrashItem
+	if strings.Contains(fs.Arg(0), "/") {
+		item, err = collections.DeleteRequest(fs.Arg(0))
+	} else {
+		item, err = collections.Delete(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Moved %s to the trash (#%d); restore it with: lighttr trash restore %d\n", item.Name(), item.ID, item.ID)
+	return 0
+}
+
+// runTrash lists the deleted collections and requests, or restores one,
+// e.g.
+//
+//	lighttr trash
+//	lighttr trash restore 3
+func runTrash(args []string) int {
+	fs := flag.NewFlagSet("trash", flag.ContinueOnError)
+
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	collections, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	switch action {
+	case "list":
+		items, err := collections.Trash()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if len(items) == 0 {
+			fmt.Println("The trash is empty")
+			return 0
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tKIND\tDELETED\tEXPIRES")
+		for _, item := range items {
+			kind := "request"
+			if item.Collection != nil {
+				kind = fmt.Sprintf("collection (%d requests)", len(item.Collection.Requests))
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", item.ID, item.Name(), kind,
+				item.DeletedAt.Format("2006-01-02 15:04"), item.ExpiresAt().Format("2006-01-02"))
+		}
+		tw.Flush()
+		return 0
+
+	case "restore":
+		if fs.NArg() != 1 {
+			break
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fs.Arg(0), "#"))
+		if err != nil {
+			fmt.Printf("Error: invalid trash ID %q\n", fs.Arg(0))
+			return 1
+		}
+		item, err := collections.Restore(id)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Restored %s\n", item.Name())
+		return 0
+	}
+
+	fmt.Println("Usage: lighttr trash [list|restore <id>]")
+	return 2
+}
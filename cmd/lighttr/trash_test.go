@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunDeleteAndTrash(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	collections, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	c := &collection.Collection{Name: "users", Requests: []collection.SavedRequest{
+		{Name: "create", Request: request.RequestData{Method: "POST", URL: "https://api.example.com/users"}},
+	}}
+	if err := collections.Save(c); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runDelete([]string{"users/create"}) })
+	if exitCode != 0 || !strings.Contains(output, "Moved users/create to the trash (#1)") {
+		t.Errorf("Expected the request to be moved to the trash, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runTrash(nil) })
+	if exitCode != 0 || !strings.Contains(output, "users/create") || !strings.Contains(output, "request") {
+		t.Errorf("Expected the request to be listed, got %d:\n%s", exitCode, output)
+	}
+
+	output = captureStdout(func() { exitCode = runTrash([]string{"restore", "1"}) })
+	if exitCode != 0 || !strings.Contains(output, "Restored users/create") {
+		t.Errorf("Expected the request to be restored, got %d:\n%s", exitCode, output)
+	}
+	if _, err := collections.Get("users/create"); err != nil {
+		t.Errorf("Expected the request back in the collection, got %v", err)
+	}
+
+	output = captureStdout(func() { exitCode = runTrash([]string{"list"}) })
+	if !strings.Contains(output, "The trash is empty") {
+		t.Errorf("Expected an empty trash, got:\n%s", output)
+	}
+
+	output = captureStdout(func() { exitCode = runDelete([]string{"missing"}) })
+	if exitCode != 1 || !strings.Contains(output, "collection not found") {
+		t.Errorf("Expected a missing collection to be reported, got %d:\n%s", exitCode, output)
+	}
+	output = captureStdout(func() { exitCode = runTrash([]string{"restore", "x"}) })
+	if exitCode != 1 || !strings.Contains(output, `invalid trash ID "x"`) {
+		t.Errorf("Expected an invalid ID to be reported, got %d:\n%s", exitCode, output)
+	}
+	if code := runTrash([]string{"shred"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown action, got %d", code)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/demo"
+	"github.com/nshekhawat/lighttr/internal/tui"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runTutorial starts the demo API and opens the TUI with hints that walk
+// through building, previewing, sending, asserting, and saving a request
+// against it, e.g.
+//
+//	lighttr tutorial
+func runTutorial(args []string) int {
+	fs := flag.NewFlagSet("tutorial", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Println("Usage: lighttr tutorial")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	tui.SetTheme(cfg.Theme)
+	applyDisplay(cfg)
+	collections, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	api, err := demo.Start()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	defer api.Close()
+
+	// The tutorial keeps out of the history and cookies of real sessions
+	tutorial := tui.NewTutorial(api.URL)
+	model := tui.NewModel().WithConfig(cfg).WithCookies(request.NewCookieJar()).WithCollections(collections).WithTutorial(tutorial)
+	if _, err := tea.NewProgram(model, tea.WithReportFocus()).Run(); err != nil {
+		fmt.Printf("Error running program: %v\n", err)
+		return 1
+	}
+	if tutorial.Done() {
+		name, _, _ := strings.Cut(tutorial.Saved(), "/")
+		fmt.Printf("Tutorial complete: lighttr collections %s lists the request you saved\n", name)
+	}
+	return 0
+}
@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestRunTutorial(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var code int
+	captureStdout(func() { code = runTutorial([]string{"extra"}) })
+	if code != 2 {
+		t.Errorf("Expected exit code 2 for an argument, got %d", code)
+	}
+}
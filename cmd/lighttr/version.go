@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"text/tabwriter"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// version and commit are set when building a release, e.g.
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD)"
+//
+// Otherwise they are read from the build information Go embeds.
+var (
+	version = ""
+	commit  = ""
+)
+
+// versionInfo describes the build for bug reports and for scripts that
+// check for a feature before using it
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+
+	Features struct {
+		// HTTP3 is whether --http3 requests are sent over HTTP/3
+		HTTP3 bool `json:"http3"`
+
+		// Keychain is whether client certificates can be loaded from the
+		// system keystore by subject
+		Keychain bool `json:"keychain"`
+
+		// Plugins is whether plugins can be loaded at run time; hooks are
+		// only available to Go programs using the library
+		Plugins bool `json:"plugins"`
+	} `json:"features"`
+
+	Paths struct {
+		Config       string `json:"config"`
+		DataDir      string `json:"data_dir,omitempty"`
+		Collections  string `json:"collections,omitempty"`
+		Environments string `json:"environments,omitempty"`
+		History      string `json:"history,omitempty"`
+		Cookies      string `json:"cookies,omitempty"`
+		Queue        string `json:"queue,omitempty"`
+		Crash        string `json:"crash,omitempty"`
+		Socket       string `json:"socket,omitempty"`
+	} `json:"paths"`
+
+	// ConfigError explains why the data paths are missing when the
+	// configuration cannot be read
+	ConfigError string `json:"config_error,omitempty"`
+}
+
+// runVersion prints the version, build, features, and data paths, e.g.
+//
+//	lighttr version
+//	lighttr version --json
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the information as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	info, err := buildVersionInfo()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Printf("lighttr %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("Commit: %s\n", info.Commit)
+	}
+	fmt.Printf("Go: %s %s\n", info.GoVersion, info.Platform)
+
+	fmt.Println("\nFeatures:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  HTTP/3\t%s\n", yesNo(info.Features.HTTP3))
+	fmt.Fprintf(tw, "  Keychain\t%s\n", yesNo(info.Features.Keychain))
+	fmt.Fprintf(tw, "  Plugins\t%s\n", yesNo(info.Features.Plugins))
+	tw.Flush()
+
+	fmt.Println("\nPaths:")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	paths := info.Paths
+	for _, path := range [][2]string{
+		{"Config", paths.Config},
+		{"Data directory", paths.DataDir},
+		{"Collections", paths.Collections},
+		{"Environments", paths.Environments},
+		{"History", paths.History},
+		{"Cookies", paths.Cookies},
+		{"Queue", paths.Queue},
+		{"Crashes", paths.Crash},
+		{"Socket", paths.Socket},
+	} {
+		if path[1] != "" {
+			fmt.Fprintf(tw, "  %s\t%s\n", path[0], path[1])
+		}
+	}
+	tw.Flush()
+	if info.ConfigError != "" {
+		fmt.Printf("\nWarning: %s\n", info.ConfigError)
+	}
+	return 0
+}
+
+// buildVersionInfo gathers the version information. A configuration that
+// cannot be read is reported rather than returned as an error, since that
+// is when the information is needed most.
+func buildVersionInfo() (*versionInfo, error) {
+	info := &versionInfo{
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		if info.Commit == "" {
+			info.Commit = buildCommit(build)
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	info.Features.HTTP3 = request.HTTP3Supported
+	info.Features.Keychain = request.KeystoreSupported
+
+	configPath, err := config.Path()
+	if err != nil {
+		return nil, err
+	}
+	info.Paths.Config = configPath
+	info.Paths.Socket = filepath.Join(filepath.Dir(configPath), "ipc.sock")
+
+	dataDir, err := config.DataDir()
+	if err != nil {
+		info.ConfigError = err.Error()
+		return info, nil
+	}
+	info.Paths.DataDir = dataDir
+	info.Paths.Collections = filepath.Join(dataDir, "collections")
+	info.Paths.Environments = filepath.Join(dataDir, "environments")
+	info.Paths.History = filepath.Join(dataDir, "history.json")
+	info.Paths.Cookies = filepath.Join(dataDir, "cookies.json")
+	info.Paths.Queue = filepath.Join(dataDir, "queue.json")
+	info.Paths.Crash = filepath.Join(dataDir, "crash")
+	return info, nil
+}
+
+// buildCommit returns the VCS revision Go recorded for the build, marked
+// -dirty when the tree had uncommitted changes
+func buildCommit(build *debug.BuildInfo) string {
+	var revision string
+	var modified bool
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// yesNo renders a feature flag
+func yesNo(enabled bool) string {
+	if enabled {
+		return "yes"
+	}
+	return "no"
+}
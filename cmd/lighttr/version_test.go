@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunVersion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	var exitCode int
+	output := captureStdout(func() { exitCode = runVersion([]string{"--json"}) })
+	if exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d:\n%s", exitCode, output)
+	}
+	var info versionInfo
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		t.Fatalf("Expected JSON, got %v:\n%s", err, output)
+	}
+	if info.Version == "" || info.GoVersion != runtime.Version() {
+		t.Errorf("Expected the version and Go version, got %+v", info)
+	}
+	if !info.Features.HTTP3 || info.Features.Plugins {
+		t.Errorf("Expected HTTP/3 to be reported as available and plugins as unavailable, got %+v", info.Features)
+	}
+	if want := filepath.Join(home, ".lighttr", "history.json"); info.Paths.History != want {
+		t.Errorf("Expected history in %s, got %s", want, info.Paths.History)
+	}
+
+	// The paths follow a configured data directory
+	dataDir := filepath.Join(home, "data")
+	os.MkdirAll(filepath.Join(home, ".lighttr"), 0755)
+	os.WriteFile(filepath.Join(home, ".lighttr", "config.json"), []byte(`{"data_dir": "`+dataDir+`"}`), 0644)
+	output = captureStdout(func() { exitCode = runVersion(nil) })
+	if exitCode != 0 || !strings.Contains(output, "HTTP/3    yes") || !strings.Contains(output, filepath.Join(dataDir, "collections")) {
+		t.Errorf("Expected features and paths under the data directory, got %d:\n%s", exitCode, output)
+	}
+
+	// A broken configuration is reported, not fatal
+	os.WriteFile(filepath.Join(home, ".lighttr", "config.json"), []byte(`{`), 0644)
+	output = captureStdout(func() { exitCode = runVersion([]string{"--json"}) })
+	if exitCode != 0 || !strings.Contains(output, `"config_error"`) {
+		t.Errorf("Expected the configuration error to be reported, got %d:\n%s", exitCode, output)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/monitor"
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runWatch checks saved requests on an interval and lists the state of each
+// monitor after every round, e.g.
+//
+//	lighttr watch api/health api/orders --interval 30s --failures 3
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", time.Minute, "Time between checks")
+	failures := fs.Int("failures", monitor.DefaultThreshold, "Consecutive failures that open the circuit and stop regular checks")
+	backoff := fs.Duration("probe-backoff", monitor.DefaultBackoff, "Wait before probing an open circuit; doubled after each failed probe")
+	maxBackoff := fs.Duration("max-backoff", monitor.DefaultMaxBackoff, "Longest wait between probes")
+	count := fs.Int("count", 0, "Stop after this many rounds; 0 checks until interrupted")
+	env := fs.String("env", "", "Environment used to resolve {{var}} placeholders")
+
+	// Allow flags after the request references
+	var refs []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		refs, args = append(refs, args[0]), args[1:]
+	}
+	if len(refs) == 0 {
+		fmt.Println("Usage: lighttr watch <collection>/<request>... [--interval 1m] [--failures 5] [--probe-backoff 30s] [--count n]")
+		return 2
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *interval <= 0 {
+		fmt.Println("Error: --interval must be positive")
+		return 1
+	}
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	monitors := make([]*monitor.Monitor, 0, len(refs))
+	for _, ref := range refs {
+		saved, err := manager.Get(ref)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		req := saved.Request.Clone()
+		req.DefaultHeaders = cfg.DefaultHeaders
+		if req.MaxResponseSize == 0 {
+			req.MaxResponseSize = cfg.ResponseSizeLimit()
+		}
+		if err := resolvePlaceholders(req, *env); err != nil {
+			fmt.Printf("Error: %s: %v\n", ref, err)
+			return 1
+		}
+		req.Proxy.ApplyCredentials(cfg.ProxyAuth)
+		var warnings []string
+		req.URL, warnings = request.NormalizeURL(req.URL, cfg.DefaultScheme)
+		printWarnings(warnings)
+		if err := req.Validate(); err != nil {
+			fmt.Printf("Error: %s: %v\n", ref, err)
+			return 1
+		}
+
+		monitors = append(monitors, &monitor.Monitor{
+			Name:    ref,
+			Request: req,
+			Breaker: monitor.Breaker{Threshold: *failures, Backoff: *backoff, MaxBackoff: *maxBackoff},
+		})
+	}
+
+	for round := 1; ; round++ {
+		now := time.Now()
+		for _, m := range monitors {
+			failing := m.Last != nil && m.Last.Failed()
+			if !m.Check(now) {
+				continue
+			}
+			if event, ok := monitorEvent(m, failing); ok {
+				postWebhooks(cfg.Webhooks, event)
+			}
+		}
+		fmt.Printf("%s\n", now.Format("15:04:05"))
+		monitor.WriteList(os.Stdout, monitors, now)
+		if *count > 0 && round >= *count {
+			break
+		}
+		fmt.Println()
+		time.Sleep(*interval)
+	}
+
+	// Scripts running a fixed number of rounds learn whether all is well
+	for _, m := range monitors {
+		if m.Breaker.State() != monitor.Closed || m.Last != nil && m.Last.Failed() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// monitorEvent describes a monitor that started failing or recovered with
+// its last check for webhooks; failing tells whether it was failing before
+func monitorEvent(m *monitor.Monitor, failing bool) (notify.Event, bool) {
+	event := notify.Event{Name: m.Name, Failed: m.Last.Failed(), Summary: "Last check: " + m.Last.String(), Time: m.Last.Time}
+	switch {
+	case event.Failed && !failing:
+		event.Type = notify.EventMonitorFailure
+		event.Details = []string{m.Request.Method + " " + request.DisplayURL(m.Request.URL)}
+	case !event.Failed && failing:
+		event.Type = notify.EventMonitorRecovery
+	default:
+		return event, false
+	}
+	return event, true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunWatch(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var posts []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts = append(posts, string(body))
+	}))
+	defer webhook.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{Webhooks: []notify.Webhook{{URL: webhook.URL}}}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	err = manager.Save(&collection.Collection{
+		Name: "api",
+		Requests: []collection.SavedRequest{
+			{Name: "health", Request: request.RequestData{Method: "GET", URL: server.URL + "/health"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The probe backoff is capped by the default --max-backoff of 10m
+	var exitCode int
+	output := captureStdout(func() {
+		exitCode = runWatch([]string{"api/health", "--interval", "1ms", "--failures", "2", "--probe-backoff", "1h", "--count", "4"})
+	})
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1 with an open circuit, got %d", exitCode)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("Expected checks to stop once the circuit opened, got %d requests", hits.Load())
+	}
+	if !strings.Contains(output, "open   502") || !strings.Contains(output, "in 10m0s") {
+		t.Errorf("Expected the list to show the open circuit, got:\n%s", output)
+	}
+	// Only the first failure is posted
+	if len(posts) != 1 || !strings.Contains(posts[0], "Monitor api/health is failing") || !strings.Contains(posts[0], "Last check: 502") {
+		t.Errorf("Expected one post about the failing monitor, got %q", posts)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// runWS opens a WebSocket, sends every line read from stdin as a text
+// message, and prints every message received on its own line, e.g.
+//
+//	lighttr ws wss://echo.example.com/ws
+//	echo '{"type": "subscribe"}' | lighttr ws wss://stream.example.com --keep-open
+//	lighttr ws wss://api.example.com/events --auth-type bearer --auth-token "$TOKEN"
+func runWS(args []string) int {
+	fs := flag.NewFlagSet("ws", flag.ContinueOnError)
+	var headers multiFlag
+	fs.Var(&headers, "header", "Additional handshake header in 'Name: value' format (repeatable)")
+	subprotocol := fs.String("subprotocol", "", "Comma separated subprotocols to offer in Sec-WebSocket-Protocol")
+	env := fs.String("env", "", "Environment used to resolve {{var}} placeholders")
+	timeout := fs.Duration("timeout", 0, "Timeout for the handshake (e.g. 10s); 0 waits indefinitely")
+	wait := fs.Duration("wait", time.Second, "How long to keep receiving after stdin ends before closing the connection")
+	keepOpen := fs.Bool("keep-open", false, "Keep receiving after stdin ends until the server closes the connection")
+	var auth request.AuthData
+	authFlags(fs, &auth)
+
+	// Allow flags after the URL
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: lighttr ws <ws://|wss://url> [--header 'Name: value'] [--subprotocol name] [--env name] [--timeout 10s] [--wait 1s] [--keep-open] [auth flags]")
+		return 2
+	}
+	req := request.NewRequestData()
+	req.URL = args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			fmt.Printf("Error: invalid header %q: expected 'Name: value'\n", header)
+			return 1
+		}
+		req.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if *subprotocol != "" {
+		req.Headers["Sec-WebSocket-Protocol"] = *subprotocol
+	}
+	req.Auth = auth
+	req.Timeout = *timeout
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	req.DefaultHeaders = cfg.DefaultHeaders
+	if err := resolvePlaceholders(req, *env); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	req.Proxy.ApplyCredentials(cfg.ProxyAuth)
+	var warnings []string
+	req.URL, warnings = request.NormalizeURL(req.URL, "ws")
+	printWarnings(warnings)
+
+	ws, err := req.DialWebSocket()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	defer ws.Close()
+	connected := "Connected to " + req.URL
+	if ws.Subprotocol != "" {
+		connected += " with " + ws.Subprotocol
+	}
+	fmt.Fprintln(os.Stderr, connected)
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := ws.Receive()
+			if err != nil {
+				received <- err
+				return
+			}
+			if msg.Binary {
+				fmt.Println(base64.StdEncoding.EncodeToString([]byte(msg.Data)))
+				continue
+			}
+			fmt.Println(msg.Data)
+		}
+	}()
+	sent := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdin)
+		scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		for scanner.Scan() {
+			if err := ws.Send(scanner.Text()); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- scanner.Err()
+	}()
+
+	select {
+	case err = <-received:
+	case err = <-sent:
+		if err == nil && *keepOpen {
+			err = <-received
+		} else if err == nil {
+			select {
+			case err = <-received:
+			case <-time.After(*wait):
+				ws.Close()
+				err = <-received
+			}
+		}
+	}
+
+	var closeErr *request.WebSocketCloseError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return 0
+	case errors.As(err, &closeErr) && (closeErr.Code == 1000 || closeErr.Code == 1001 || closeErr.Code == 0):
+		fmt.Fprintf(os.Stderr, "Connection %v\n", closeErr)
+		return 0
+	}
+	fmt.Printf("Error: %v\n", err)
+	return 1
+}
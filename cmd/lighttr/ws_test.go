@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRunWS(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "k-123" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		ws, err := request.AcceptWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			msg, err := ws.Receive()
+			if err != nil {
+				return
+			}
+			if msg.Data == "bin" {
+				ws.SendBinary([]byte{0xff, 0x00})
+				continue
+			}
+			ws.Send("echo: " + msg.Data)
+		}
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	oldStdin := stdin
+	defer func() { stdin = oldStdin }()
+	stdin = strings.NewReader("hello\n{\"type\": \"subscribe\"}\nbin\n")
+	var code int
+	output := captureStdout(func() { code = runWS([]string{url, "--header", "X-Api-Key: k-123", "--wait", "200ms"}) })
+	if code != 0 || output != "echo: hello\necho: {\"type\": \"subscribe\"}\n/wA=\n" {
+		t.Errorf("Expected the echoed messages, got %d:\n%s", code, output)
+	}
+
+	stdin = strings.NewReader("")
+	output = captureStdout(func() { code = runWS([]string{url}) })
+	if code != 1 || !strings.Contains(output, "Error: WebSocket handshake failed: 403 Forbidden") {
+		t.Errorf("Expected the refused handshake to be reported, got %d:\n%s", code, output)
+	}
+	output = captureStdout(func() { code = runWS([]string{server.URL, "--header", "X-Api-Key: k-123"}) })
+	if code != 1 || !strings.Contains(output, "is not a WebSocket URL") {
+		t.Errorf("Expected an http:// URL to be refused, got %d:\n%s", code, output)
+	}
+	if output := captureStdout(func() { code = runWS(nil) }); code != 2 {
+		t.Errorf("Expected a usage error, got %d:\n%s", code, output)
+	}
+}
@@ -0,0 +1,286 @@
+// Package authprofile keeps named credentials, such as a basic auth login,
+// a bearer token, an OAuth2 profile, or a mutual TLS certificate, in
+// ~/.lighttr/auth.json so requests reference them by name with profile auth
+// instead of each holding the secrets. The secrets themselves are kept in
+// the system keyring unless the configuration says otherwise.
+package authprofile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/keyring"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// For testing
+var (
+	keyringSet    = keyring.Set
+	keyringGet    = keyring.Get
+	keyringDelete = keyring.Delete
+)
+
+// storeMu serializes reading and writing the profile store
+var storeMu sync.Mutex
+
+// Profile is a set of credentials saved under a name
+type Profile struct {
+	Name string           `json:"name"`
+	Auth request.AuthData `json:"auth"`
+
+	// Keyring is set when the secrets of Auth are kept in the system
+	// keyring rather than in the file
+	Keyring bool `json:"keyring,omitempty"`
+}
+
+// secrets are the fields of AuthData that are kept in the keyring
+type secrets struct {
+	Password          string `json:"password,omitempty"`
+	Token             string `json:"token,omitempty"`
+	APIKey            string `json:"api_key,omitempty"`
+	AzureClientSecret string `json:"azure_client_secret,omitempty"`
+}
+
+func secretsOf(auth request.AuthData) secrets {
+	return secrets{
+		Password:          auth.Password,
+		Token:             auth.Token,
+		APIKey:            auth.APIKey,
+		AzureClientSecret: auth.AzureClientSecret,
+	}
+}
+
+func (s secrets) apply(auth *request.AuthData) {
+	auth.Password = s.Password
+	auth.Token = s.Token
+	auth.APIKey = s.APIKey
+	auth.AzureClientSecret = s.AzureClientSecret
+}
+
+// account is the keyring account of the profile's secrets
+func account(name string) string {
+	return "auth-profile " + name
+}
+
+// Details describes the credentials of the profile without its secrets,
+// such as the username of basic auth or the certificate of mtls auth
+func (p *Profile) Details() string {
+	auth := p.Auth
+	switch auth.Type {
+	case request.BasicAuth, request.NTLMAuth:
+		return auth.Username
+	case request.MutualTLSAuth:
+		if auth.CertSubject != "" {
+			return "subject " + auth.CertSubject
+		}
+		return auth.CertFile
+	case request.OAuth2Auth:
+		return "OAuth2 profile " + auth.OAuth2Profile
+	case request.APIKeyAuth:
+		name, _, query := auth.APIKeyParam()
+		if query {
+			return "query parameter " + name
+		}
+		return "header " + name
+	case request.AzureAuth:
+		return auth.AzureClientID
+	case request.JWTAuth:
+		return auth.JWTKeyFile
+	case request.SessionAuth:
+		if auth.Username != "" {
+			return auth.Username + " at " + auth.SessionLoginURL
+		}
+		return auth.SessionLoginURL
+	}
+	return "-"
+}
+
+// Path returns the location of the profile store
+func Path() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "auth.json"), nil
+}
+
+// read reads the profile store, keyed by profile name, returning no
+// profiles when it does not exist
+func read() (map[string]*Profile, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]*Profile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse auth profiles: %v", err)
+	}
+	for name, profile := range profiles {
+		profile.Name = name
+	}
+	return profiles, nil
+}
+
+func write(profiles map[string]*Profile) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal auth profiles: %v", err)
+	}
+	// Secrets are in the file when there is no keyring
+	return os.WriteFile(path, data, 0600)
+}
+
+// List returns the profiles sorted by name, without the secrets kept in
+// the keyring
+func List() ([]*Profile, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	profiles, err := read()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Load reads the named profile with its secrets
+func Load(name string) (*Profile, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	profiles, err := read()
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("auth profile not found: %s", name)
+	}
+	if !profile.Keyring {
+		return profile, nil
+	}
+
+	secret, err := keyringGet(account(name))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, fmt.Errorf("the secrets of auth profile %s are missing from the system keyring: save it again", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the secrets of auth profile %s from the system keyring: %v", name, err)
+	}
+	var s secrets
+	if err := json.Unmarshal([]byte(secret), &s); err != nil {
+		return nil, fmt.Errorf("failed to parse the secrets of auth profile %s: %v", name, err)
+	}
+	s.apply(&profile.Auth)
+	return profile, nil
+}
+
+// Save adds the profile to the store or replaces the one with the same
+// name, keeping its secrets in the system keyring unless the configuration
+// says otherwise or there is none. It returns the entry as saved.
+func Save(name string, auth request.AuthData) (*Profile, error) {
+	switch auth.Type {
+	case request.NoAuth, "":
+		return nil, fmt.Errorf("an auth profile needs an auth type")
+	case request.ProfileAuth:
+		return nil, fmt.Errorf("an auth profile cannot refer to another profile")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("an auth profile needs a name")
+	}
+	auth.Profile = ""
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	profiles, err := read()
+	if err != nil {
+		return nil, err
+	}
+	profile := &Profile{Name: name, Auth: auth}
+
+	useKeyring := secretsOf(auth) != secrets{}
+	if cfg, err := config.Load(); err == nil && cfg.TokenStorage == "file" {
+		useKeyring = false
+	}
+	if useKeyring {
+		secret, err := json.Marshal(secretsOf(auth))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the secrets of auth profile %s: %v", name, err)
+		}
+		// Without a keyring, e.g. on a server without a desktop session,
+		// the secrets go in the file
+		profile.Keyring = keyringSet(account(name), string(secret)) == nil
+	}
+	if profile.Keyring {
+		secrets{}.apply(&profile.Auth)
+	} else if previous, ok := profiles[name]; ok && previous.Keyring {
+		keyringDelete(account(name))
+	}
+	profiles[name] = profile
+	if err := write(profiles); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Delete removes the named profile from the store and its secrets from
+// the keyring
+func Delete(name string) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	profiles, err := read()
+	if err != nil {
+		return err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("auth profile not found: %s", name)
+	}
+	if profile.Keyring {
+		if err := keyringDelete(account(name)); err != nil {
+			return fmt.Errorf("failed to delete the secrets of auth profile %s from the system keyring: %v", name, err)
+		}
+	}
+	delete(profiles, name)
+	return write(profiles)
+}
+
+// Resolve returns the credentials of the named profile, for
+// request.RegisterAuthProfiles
+func Resolve(name string) (request.AuthData, error) {
+	profile, err := Load(name)
+	if err != nil {
+		return request.AuthData{}, err
+	}
+	return profile.Auth, nil
+}
@@ -0,0 +1,146 @@
+package authprofile
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/keyring"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// fakeKeyring keeps the keyring's secrets in a map for the test
+func fakeKeyring(t *testing.T) map[string]string {
+	secrets := make(map[string]string)
+	keyringSet = func(account, secret string) error {
+		secrets[account] = secret
+		return nil
+	}
+	keyringGet = func(account string) (string, error) {
+		secret, ok := secrets[account]
+		if !ok {
+			return "", keyring.ErrNotFound
+		}
+		return secret, nil
+	}
+	keyringDelete = func(account string) error {
+		delete(secrets, account)
+		return nil
+	}
+	t.Cleanup(func() {
+		keyringSet, keyringGet, keyringDelete = keyring.Set, keyring.Get, keyring.Delete
+	})
+	return secrets
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	secrets := fakeKeyring(t)
+
+	basic := request.AuthData{Type: request.BasicAuth, Username: "jane", Password: "s3cret", Profile: "ignored"}
+	saved, err := Save("billing", basic)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !saved.Keyring || saved.Auth.Password != "" || saved.Auth.Profile != "" {
+		t.Errorf("Expected the password to be kept in the keyring, got %+v", saved)
+	}
+	if !strings.Contains(secrets["auth-profile billing"], `"password":"s3cret"`) {
+		t.Errorf("Expected the password in the keyring under the profile, got %v", secrets)
+	}
+	path, _ := Path()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") || !strings.Contains(string(data), "jane") {
+		t.Errorf("Expected the store to hold the username but not the password, got:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the store to be private, got %v", info.Mode())
+	}
+
+	// Profiles without secrets, such as oauth2, need no keyring
+	if saved, err := Save("github", request.AuthData{Type: request.OAuth2Auth, OAuth2Profile: "github"}); err != nil || saved.Keyring {
+		t.Fatalf("Save() = %+v, %v; expected the profile in the file", saved, err)
+	}
+
+	list, err := List()
+	if err != nil || len(list) != 2 || list[0].Name != "billing" || list[1].Name != "github" {
+		t.Fatalf("List() = %v, %v", list, err)
+	}
+	if list[0].Auth.Password != "" || list[0].Details() != "jane" || list[1].Details() != "OAuth2 profile github" {
+		t.Errorf("Expected the profiles to be listed without secrets, got %+v and %+v", list[0], list[1])
+	}
+	session := Profile{Auth: request.AuthData{Type: request.SessionAuth, Username: "jane", SessionLoginURL: "https://api.example.com/login"}}
+	if details := session.Details(); details != "jane at https://api.example.com/login" {
+		t.Errorf("Expected the session login to be described, got %q", details)
+	}
+
+	loaded, err := Load("billing")
+	if err != nil || loaded.Auth.Username != "jane" || loaded.Auth.Password != "s3cret" {
+		t.Errorf("Load() = %+v, %v; expected the password from the keyring", loaded, err)
+	}
+	auth, err := Resolve("billing")
+	if err != nil || auth.Type != request.BasicAuth || auth.Password != "s3cret" {
+		t.Errorf("Resolve() = %+v, %v", auth, err)
+	}
+	if _, err := Load("missing"); err == nil || !strings.Contains(err.Error(), "auth profile not found: missing") {
+		t.Errorf("Expected a missing profile to be an error, got %v", err)
+	}
+
+	delete(secrets, "auth-profile billing")
+	if _, err := Load("billing"); err == nil || !strings.Contains(err.Error(), "missing from the system keyring") {
+		t.Errorf("Expected secrets missing from the keyring to be an error, got %v", err)
+	}
+
+	if err := Delete("github"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := Delete("github"); err == nil {
+		t.Error("Expected deleting a missing profile to fail")
+	}
+	if list, _ := List(); len(list) != 1 {
+		t.Errorf("Expected one profile to remain, got %v", list)
+	}
+}
+
+func TestSave_FileStorage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	secrets := fakeKeyring(t)
+
+	if _, err := Save("ci", request.AuthData{Type: request.BearerAuth, Token: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Save("ci", request.AuthData{Type: request.BearerAuth, Token: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Keyring || saved.Auth.Token != "second" {
+		t.Errorf("Expected the token in the file, got %+v", saved)
+	}
+	if _, ok := secrets["auth-profile ci"]; ok {
+		t.Error("Expected the token saved before to be removed from the keyring")
+	}
+	if auth, err := Resolve("ci"); err != nil || auth.Token != "second" {
+		t.Errorf("Resolve() = %+v, %v", auth, err)
+	}
+}
+
+func TestSave_Invalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fakeKeyring(t)
+
+	for _, auth := range []request.AuthData{
+		{Type: request.NoAuth},
+		{Type: request.ProfileAuth, Profile: "other"},
+	} {
+		if _, err := Save("bad", auth); err == nil {
+			t.Errorf("Expected %s auth not to be saved as a profile", auth.Type)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package azure obtains Microsoft Entra ID (Azure AD) tokens, for requests
+// with azure auth
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// For testing
+var (
+	now         = time.Now
+	tokenClient = &http.Client{Timeout: 30 * time.Second, Transport: oauth.TraceTransport(nil)}
+)
+
+// DefaultScope is the scope of tokens when the request names none, that of
+// Microsoft Graph with the permissions granted to the application
+const DefaultScope = "https://graph.microsoft.com/.default"
+
+// defaultAuthority is the host of Entra ID in the public cloud; sovereign
+// clouds are selected with AZURE_AUTHORITY_HOST
+const defaultAuthority = "https://login.microsoftonline.com"
+
+// defaultTenant is the tenant of users logging in without naming one: any
+// work or school account
+const defaultTenant = "organizations"
+
+// refreshSkew is how long before they expire tokens are replaced
+const refreshSkew = time.Minute
+
+// App is an Entra ID application registration, the tenant tokens are
+// requested from, and the scope they are requested for
+type App struct {
+	Tenant       string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+}
+
+// AppFor returns the application of the request's azure auth. Without a
+// client ID, the application is taken from AZURE_TENANT_ID,
+// AZURE_CLIENT_ID, and AZURE_CLIENT_SECRET, as the Azure SDKs do.
+func AppFor(auth request.AuthData) App {
+	app := App{Tenant: auth.AzureTenant, ClientID: auth.AzureClientID, ClientSecret: auth.AzureClientSecret, Scope: auth.AzureScope}
+	if app.ClientID == "" {
+		app.ClientID = os.Getenv("AZURE_CLIENT_ID")
+		app.ClientSecret = os.Getenv("AZURE_CLIENT_SECRET")
+	}
+	if app.Tenant == "" {
+		app.Tenant = os.Getenv("AZURE_TENANT_ID")
+	}
+	if app.Scope == "" {
+		app.Scope = DefaultScope
+	}
+	return app
+}
+
+// tenant returns the tenant tokens are requested from
+func (a App) tenant() string {
+	if a.Tenant == "" {
+		return defaultTenant
+	}
+	return a.Tenant
+}
+
+// endpoint returns the URL of an OAuth2 endpoint of the tenant, such as
+// "token" or "devicecode"
+func (a App) endpoint(name string) string {
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = defaultAuthority
+	}
+	return strings.TrimSuffix(authority, "/") + "/" + url.PathEscape(a.tenant()) + "/oauth2/v2.0/" + name
+}
+
+// Profile returns the OAuth2 profile the tokens of users logged in to the
+// application are cached under in the token store
+func (a App) Profile() *oauth.Profile {
+	return &oauth.Profile{
+		Name:          "azure",
+		TokenURL:      a.endpoint("token"),
+		DeviceAuthURL: a.endpoint("devicecode"),
+		ClientID:      a.ClientID,
+		// offline_access asks for a refresh token
+		Scopes: []string{a.Scope, "offline_access"},
+	}
+}
+
+var (
+	// mu guards cache, the client credentials tokens obtained so far by
+	// tenant, client, and scope, and the refreshes of cached user tokens
+	mu    sync.Mutex
+	cache = make(map[string]*oauth.Token)
+)
+
+// Middleware returns the provider of azure auth, for request.RegisterAuth,
+// which sends an Entra ID access token as a Bearer token. Tokens are reused
+// until shortly before they expire.
+func Middleware() request.Middleware {
+	return func(next request.Handler) request.Handler {
+		return func(x *request.Exchange) error {
+			value, err := Token(x.Request.Context(), AppFor(x.Data.Auth))
+			if err != nil {
+				return x.Abort(err)
+			}
+			x.Request.Header.Set("Authorization", "Bearer "+value)
+			return next(x)
+		}
+	}
+}
+
+// Token returns an access token for the application's scope: obtained with
+// the client credentials grant when the application has a client secret,
+// and otherwise the token cached by Login, refreshed for the scope when it
+// was obtained for another or is about to expire
+func Token(ctx context.Context, app App) (string, error) {
+	if app.ClientID == "" {
+		return "", fmt.Errorf("azure auth needs a client ID: set it on the request or in AZURE_CLIENT_ID")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if app.ClientSecret != "" {
+		return app.clientCredentialsToken(ctx)
+	}
+	return app.userToken(ctx)
+}
+
+// clientCredentialsToken returns a token of the application itself
+func (a App) clientCredentialsToken(ctx context.Context) (string, error) {
+	if a.Tenant == "" {
+		return "", fmt.Errorf("azure auth with a client secret needs a tenant: set it on the request or in AZURE_TENANT_ID")
+	}
+	key := a.Tenant + " " + a.ClientID + " " + a.Scope
+	if cached, ok := cache[key]; ok && !expiresSoon(cached) {
+		return cached.AccessToken, nil
+	}
+	token, err := a.requestToken(ctx, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_secret": {a.ClientSecret},
+	})
+	if err != nil {
+		return "", err
+	}
+	cache[key] = token
+	return token.AccessToken, nil
+}
+
+// userToken returns the cached token of the user logged in to the
+// application. Entra ID refresh tokens are good for any scope the user
+// consented to, so one login serves every scope.
+func (a App) userToken(ctx context.Context) (string, error) {
+	profile := a.Profile()
+	token, err := oauth.LoadToken(profile)
+	if err != nil {
+		return "", err
+	}
+	if token == nil || token.AccessToken == "" {
+		return "", fmt.Errorf("no Entra ID token for client %s: run %s", a.ClientID, a.LoginCommand())
+	}
+	if token.Scope == a.Scope && !expiresSoon(token) {
+		return token.AccessToken, nil
+	}
+	if token.RefreshToken == "" {
+		return "", fmt.Errorf("the Entra ID token of client %s expired: run %s", a.ClientID, a.LoginCommand())
+	}
+
+	refreshed, err := a.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+		"scope":         {a.Scope + " offline_access"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh the Entra ID token of client %s: %v: run %s", a.ClientID, err, a.LoginCommand())
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	if _, err := oauth.SaveToken(profile, refreshed); err != nil {
+		return "", fmt.Errorf("failed to cache the refreshed token: %v", err)
+	}
+	return refreshed.AccessToken, nil
+}
+
+// LoginCommand returns the command that logs in to the application
+func (a App) LoginCommand() string {
+	command := "lighttr azure login"
+	if a.Tenant != "" {
+		command += " --tenant " + a.Tenant
+	}
+	command += " --client-id " + a.ClientID
+	if a.Scope != DefaultScope {
+		command += " --scope " + a.Scope
+	}
+	return command
+}
+
+// Login logs in to the application with the device code flow, writing the
+// code to enter and where to out, and caches the token in the token store
+func Login(ctx context.Context, app App, out io.Writer) (*oauth.CachedToken, error) {
+	if app.ClientID == "" {
+		return nil, fmt.Errorf("logging in needs a client ID")
+	}
+	profile := app.Profile()
+	token, err := oauth.DeviceLogin(ctx, profile, out)
+	if err != nil {
+		return nil, err
+	}
+	// Entra ID reports the scope without its resource, so the token is
+	// recorded under the scope it was requested for
+	token.Scope = app.Scope
+	return oauth.SaveToken(profile, token)
+}
+
+// expiresSoon reports whether the token expires within refreshSkew
+func expiresSoon(t *oauth.Token) bool {
+	return !t.Expiry.IsZero() && !now().Add(refreshSkew).Before(t.Expiry)
+}
+
+// tokenResponse is the body of a token endpoint response, successful or not
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts a grant for the application's scope to the tenant's
+// token endpoint
+func (a App) requestToken(ctx context.Context, form url.Values) (*oauth.Token, error) {
+	form.Set("client_id", a.ClientID)
+	if form.Get("scope") == "" {
+		form.Set("scope", a.Scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint("token"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Entra ID token URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Entra ID token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("Entra ID token request failed: %v", err)
+	}
+	parsed := &tokenResponse{}
+	if err := json.Unmarshal(body, parsed); err != nil {
+		return nil, fmt.Errorf("invalid Entra ID token response (status %d): %v", resp.StatusCode, err)
+	}
+	if parsed.Error != "" {
+		message := parsed.Error
+		if parsed.ErrorDescription != "" {
+			// Descriptions run on with trace and correlation IDs after
+			// the first line
+			description, _, _ := strings.Cut(parsed.ErrorDescription, "\r\n")
+			message += ": " + description
+		}
+		return nil, fmt.Errorf("Entra ID token request failed: %s", message)
+	}
+	if resp.StatusCode != http.StatusOK || parsed.AccessToken == "" {
+		return nil, fmt.Errorf("Entra ID token request failed: status %d without an access token", resp.StatusCode)
+	}
+
+	token := &oauth.Token{
+		AccessToken:  parsed.AccessToken,
+		TokenType:    parsed.TokenType,
+		RefreshToken: parsed.RefreshToken,
+		Scope:        a.Scope,
+	}
+	if parsed.ExpiresIn > 0 {
+		token.Expiry = now().Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,175 @@
+package azure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// isolate clears the token cache, keeps user tokens in a file under a
+// temporary home, and points Entra ID at the given server
+func isolate(t *testing.T, authority string) {
+	mu.Lock()
+	cache = make(map[string]*oauth.Token)
+	mu.Unlock()
+	t.Setenv("HOME", t.TempDir())
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	t.Setenv("AZURE_AUTHORITY_HOST", authority)
+	t.Setenv("AZURE_TENANT_ID", "")
+	t.Setenv("AZURE_CLIENT_ID", "")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+}
+
+func TestAppFor(t *testing.T) {
+	t.Setenv("AZURE_TENANT_ID", "env-tenant")
+	t.Setenv("AZURE_CLIENT_ID", "env-client")
+	t.Setenv("AZURE_CLIENT_SECRET", "env-secret")
+
+	app := AppFor(request.AuthData{Type: request.AzureAuth})
+	if app != (App{Tenant: "env-tenant", ClientID: "env-client", ClientSecret: "env-secret", Scope: DefaultScope}) {
+		t.Errorf("Expected the application from the environment, got %+v", app)
+	}
+
+	// The secret in the environment belongs to the client in the
+	// environment
+	app = AppFor(request.AuthData{Type: request.AzureAuth, AzureClientID: "cli", AzureScope: "api://orders/.default"})
+	if app != (App{Tenant: "env-tenant", ClientID: "cli", Scope: "api://orders/.default"}) {
+		t.Errorf("Expected the request's client without the environment's secret, got %+v", app)
+	}
+}
+
+func TestToken_ClientCredentials(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		if r.URL.Path != "/contoso/oauth2/v2.0/token" || r.Form.Get("grant_type") != "client_credentials" ||
+			r.Form.Get("client_id") != "app" || r.Form.Get("client_secret") != "s3cret" || r.Form.Get("scope") != DefaultScope {
+			t.Errorf("Unexpected token request %s %v", r.URL.Path, r.Form)
+		}
+		io.WriteString(w, `{"token_type": "Bearer", "expires_in": 3599, "access_token": "eyJ.app"}`)
+	}))
+	defer server.Close()
+	isolate(t, server.URL)
+
+	app := App{Tenant: "contoso", ClientID: "app", ClientSecret: "s3cret", Scope: DefaultScope}
+	for i := 0; i < 2; i++ {
+		if value, err := Token(context.Background(), app); err != nil || value != "eyJ.app" {
+			t.Errorf("Expected the application's token, got %q, %v", value, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the token to be reused, got %d requests", requests)
+	}
+
+	// Tokens are replaced shortly before they expire
+	now = func() time.Time { return time.Now().Add(59 * time.Minute) }
+	defer func() { now = time.Now }()
+	Token(context.Background(), app)
+	if requests != 2 {
+		t.Errorf("Expected an expiring token to be replaced, got %d requests", requests)
+	}
+}
+
+func TestToken_User(t *testing.T) {
+	var scopes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "rt1" {
+			t.Errorf("Unexpected token request %v", r.Form)
+		}
+		scopes = append(scopes, r.Form.Get("scope"))
+		io.WriteString(w, `{"token_type": "Bearer", "expires_in": 3599, "access_token": "eyJ.orders"}`)
+	}))
+	defer server.Close()
+	isolate(t, server.URL)
+
+	app := App{ClientID: "cli", Scope: DefaultScope}
+	if _, err := Token(context.Background(), app); err == nil || !strings.Contains(err.Error(), "run lighttr azure login --client-id cli") {
+		t.Errorf("Expected to be told to log in, got %v", err)
+	}
+
+	login := &oauth.Token{AccessToken: "eyJ.graph", RefreshToken: "rt1", Scope: DefaultScope, Expiry: time.Now().Add(time.Hour)}
+	if _, err := oauth.SaveToken(app.Profile(), login); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	if value, err := Token(context.Background(), app); err != nil || value != "eyJ.graph" {
+		t.Errorf("Expected the cached token, got %q, %v", value, err)
+	}
+
+	// Another scope is obtained with the refresh token, which is kept
+	app.Scope = "api://orders/.default"
+	if value, err := Token(context.Background(), app); err != nil || value != "eyJ.orders" {
+		t.Errorf("Expected a token for the other scope, got %q, %v", value, err)
+	}
+	if len(scopes) != 1 || scopes[0] != "api://orders/.default offline_access" {
+		t.Errorf("Expected one refresh for the other scope, got %v", scopes)
+	}
+	cached, err := oauth.LoadToken(app.Profile())
+	if err != nil || cached.AccessToken != "eyJ.orders" || cached.RefreshToken != "rt1" || cached.Scope != app.Scope {
+		t.Errorf("Expected the refreshed token to be cached, got %+v, %v", cached, err)
+	}
+}
+
+func TestToken_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error": "invalid_client", "error_description": "AADSTS7000215: Invalid client secret provided.\r\nTrace ID: 1\r\nCorrelation ID: 2"}`)
+	}))
+	defer server.Close()
+	isolate(t, server.URL)
+
+	if _, err := Token(context.Background(), App{Scope: DefaultScope}); err == nil || !strings.Contains(err.Error(), "needs a client ID") {
+		t.Errorf("Expected a missing client ID to be reported, got %v", err)
+	}
+	if _, err := Token(context.Background(), App{ClientID: "app", ClientSecret: "s", Scope: DefaultScope}); err == nil || !strings.Contains(err.Error(), "needs a tenant") {
+		t.Errorf("Expected a missing tenant to be reported, got %v", err)
+	}
+	_, err := Token(context.Background(), App{Tenant: "contoso", ClientID: "app", ClientSecret: "wrong", Scope: DefaultScope})
+	if err == nil || err.Error() != "Entra ID token request failed: invalid_client: AADSTS7000215: Invalid client secret provided." {
+		t.Errorf("Expected the first line of the error description, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	request.RegisterAuth(request.AzureAuth, Middleware())
+	t.Cleanup(func() { request.RegisterAuth(request.AzureAuth, nil) })
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"token_type": "Bearer", "expires_in": 3599, "access_token": "eyJ.app"}`)
+	}))
+	defer tokens.Close()
+	isolate(t, tokens.URL)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	req := request.NewRequestData()
+	req.URL = server.URL
+	req.Auth = request.AuthData{Type: request.AzureAuth, AzureTenant: "contoso", AzureClientID: "app", AzureClientSecret: "s3cret"}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if authorization != "Bearer eyJ.app" {
+		t.Errorf("Expected the access token to be sent, got %q", authorization)
+	}
+
+	// Other auth types pass through untouched
+	authorization = ""
+	req.Auth = request.AuthData{}
+	if _, err := req.Execute(); err != nil || authorization != "" {
+		t.Errorf("Expected no Authorization header without azure auth, got %q, %v", authorization, err)
+	}
+}
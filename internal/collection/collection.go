@@ -0,0 +1,219 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// ErrNotFound is returned when a collection does not exist
+var ErrNotFound = errors.New("collection not found")
+
+// Budget sets performance limits for a request. Zero values mean no limit.
+type Budget struct {
+	MaxLatencyMs int64 `json:"max_latency_ms,omitempty"`
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+}
+
+// SavedRequest is a named request stored in a collection
+type SavedRequest struct {
+	Name    string              `json:"name"`
+	Request request.RequestData `json:"request"`
+
+	// Budget overrides the collection budget for this request
+	Budget *Budget `json:"budget,omitempty"`
+
+	// Asserts are checked against the response when the request runs
+	Asserts []assertion.Assertion `json:"asserts,omitempty"`
+
+	// Owner is who to ask about the request, e.g. a person or team, and
+	// Ticket links to the issue or document it relates to
+	Owner  string `json:"owner,omitempty"`
+	Ticket string `json:"ticket,omitempty"`
+
+	// LastVerified is when a run of the collection last passed the
+	// request's assertions
+	LastVerified time.Time `json:"last_verified,omitzero"`
+}
+
+// StaleAfter is how long after it was last verified a request with
+// assertions is considered stale
+const StaleAfter = 30 * 24 * time.Hour
+
+// Stale reports whether the request has assertions that no run has passed
+// within StaleAfter of now
+func (s *SavedRequest) Stale(now time.Time) bool {
+	return len(s.Asserts) > 0 && (s.LastVerified.IsZero() || now.Sub(s.LastVerified) > StaleAfter)
+}
+
+// Collection groups saved requests under a common name
+type Collection struct {
+	Name     string         `json:"name"`
+	Requests []SavedRequest `json:"requests"`
+
+	// Budget applies to every request that does not define its own
+	Budget *Budget `json:"budget,omitempty"`
+}
+
+// BudgetFor returns the budget that applies to the saved request, or nil
+func (c *Collection) BudgetFor(saved *SavedRequest) *Budget {
+	if saved.Budget != nil {
+		return saved.Budget
+	}
+	return c.Budget
+}
+
+// Find returns the saved request with the given name
+func (c *Collection) Find(name string) (*SavedRequest, bool) {
+	for i := range c.Requests {
+		if c.Requests[i].Name == name {
+			return &c.Requests[i], true
+		}
+	}
+	return nil, false
+}
+
+// MarkVerified records that the named requests passed their assertions at
+// the given time and returns how many requests it updated
+func (c *Collection) MarkVerified(names []string, at time.Time) int {
+	updated := 0
+	for _, name := range names {
+		if saved, ok := c.Find(name); ok {
+			saved.LastVerified = at
+			updated++
+		}
+	}
+	return updated
+}
+
+// Manager handles the storage and retrieval of collections
+type Manager struct {
+	dir string
+}
+
+// NewManager creates a new collection manager
+func NewManager() (*Manager, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create collections directory if it doesn't exist
+	dir := filepath.Join(dataDir, "collections")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Manager{dir: dir}, nil
+}
+
+// List returns the names of all stored collections
+func (m *Manager) List() ([]string, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Load reads the named collection from disk
+func (m *Manager) Load(name string) (*Collection, error) {
+	data, err := os.ReadFile(m.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return nil, err
+	}
+
+	c := &Collection{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse collection %s: %v", name, err)
+	}
+	if c.Name == "" {
+		c.Name = name
+	}
+	return c, nil
+}
+
+// Save writes the collection to disk
+func (m *Manager) Save(c *Collection) error {
+	if c.Name == "" {
+		return fmt.Errorf("collection name cannot be empty")
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection: %v", err)
+	}
+
+	return os.WriteFile(m.path(c.Name), data, 0644)
+}
+
+// Get resolves a "collection/request" reference to a saved request
+func (m *Manager) Get(ref string) (*SavedRequest, error) {
+	collectionName, requestName, ok := strings.Cut(ref, "/")
+	if !ok || collectionName == "" || requestName == "" {
+		return nil, fmt.Errorf("invalid request reference %q: expected collection/request", ref)
+	}
+
+	c, err := m.Load(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, ok := c.Find(requestName)
+	if !ok {
+		return nil, fmt.Errorf("request %q not found in collection %s", requestName, collectionName)
+	}
+	return saved, nil
+}
+
+// SaveRequest stores the request under a "collection/request" reference,
+// replacing the request of that name and keeping its owner, ticket, and
+// budget, or appending it, and creating the collection if needed
+func (m *Manager) SaveRequest(ref string, saved SavedRequest) error {
+	collectionName, requestName, ok := strings.Cut(ref, "/")
+	if !ok || collectionName == "" || requestName == "" {
+		return fmt.Errorf("invalid request reference %q: expected collection/request", ref)
+	}
+	saved.Name = requestName
+
+	c, err := m.Load(collectionName)
+	if errors.Is(err, ErrNotFound) {
+		c = &Collection{Name: collectionName}
+	} else if err != nil {
+		return err
+	}
+
+	if existing, ok := c.Find(requestName); ok {
+		saved.Owner, saved.Ticket, saved.Budget = existing.Owner, existing.Ticket, existing.Budget
+		*existing = saved
+	} else {
+		c.Requests = append(c.Requests, saved)
+	}
+	return m.Save(c)
+}
+
+// path returns the file path for the named collection
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.dir, name+".json")
+}
@@ -0,0 +1,233 @@
+package collection
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	t.Cleanup(func() { os.Setenv("HOME", oldHome) })
+
+	return tmpDir
+}
+
+func TestNewManager(t *testing.T) {
+	tmpDir := setupHome(t)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	expectedDir := filepath.Join(tmpDir, ".lighttr", "collections")
+	if manager.dir != expectedDir {
+		t.Errorf("Expected dir %s, got %s", expectedDir, manager.dir)
+	}
+	if _, err := os.Stat(expectedDir); os.IsNotExist(err) {
+		t.Error("Expected collections directory to be created")
+	}
+}
+
+func TestManager_SaveLoadAndList(t *testing.T) {
+	setupHome(t)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	c := &Collection{
+		Name: "users",
+		Requests: []SavedRequest{
+			{
+				Name: "create-user",
+				Request: request.RequestData{
+					Method: "POST",
+					URL:    "https://api.example.com/users",
+					Body:   `{"name":"John"}`,
+					Auth:   request.AuthData{Type: request.NoAuth},
+				},
+			},
+		},
+	}
+	if err := manager.Save(c); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := manager.Load("users")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Requests[0].Request.Body, c.Requests[0].Request.Body) {
+		t.Errorf("Expected body %s, got %s", c.Requests[0].Request.Body, loaded.Requests[0].Request.Body)
+	}
+
+	names, err := manager.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"users"}) {
+		t.Errorf("Expected [users], got %v", names)
+	}
+
+	if err := manager.Save(&Collection{}); err == nil {
+		t.Error("Expected error saving collection without a name")
+	}
+}
+
+func TestManager_Get(t *testing.T) {
+	setupHome(t)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	c := &Collection{
+		Name: "users",
+		Requests: []SavedRequest{
+			{Name: "create-user", Request: request.RequestData{Method: "POST"}},
+		},
+	}
+	if err := manager.Save(c); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{
+		{name: "existing request", ref: "users/create-user", wantErr: false},
+		{name: "missing request", ref: "users/delete-user", wantErr: true},
+		{name: "missing collection", ref: "orders/create", wantErr: true},
+		{name: "invalid reference", ref: "users", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved, err := manager.Get(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && saved.Request.Method != "POST" {
+				t.Errorf("Expected saved request method POST, got %s", saved.Request.Method)
+			}
+		})
+	}
+}
+
+func TestManager_SaveRequest(t *testing.T) {
+	setupHome(t)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	// The collection is created for its first request
+	if err := manager.SaveRequest("tutorial/get-user", SavedRequest{Request: request.RequestData{Method: "GET"}}); err != nil {
+		t.Fatalf("SaveRequest() error = %v", err)
+	}
+	c, err := manager.Load("tutorial")
+	if err != nil || len(c.Requests) != 1 || c.Requests[0].Name != "get-user" {
+		t.Fatalf("Expected the collection to be created, got %+v, %v", c, err)
+	}
+
+	// A request of the same name is replaced, keeping its owner
+	c.Requests[0].Owner = "identity-team"
+	if err := manager.Save(c); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	asserts := []assertion.Assertion{{Query: "status", Predicate: "==", Value: "200"}}
+	if err := manager.SaveRequest("tutorial/get-user", SavedRequest{Request: request.RequestData{Method: "HEAD"}, Asserts: asserts}); err != nil {
+		t.Fatalf("SaveRequest() error = %v", err)
+	}
+	if err := manager.SaveRequest("tutorial/list-users", SavedRequest{Request: request.RequestData{Method: "GET"}}); err != nil {
+		t.Fatalf("SaveRequest() error = %v", err)
+	}
+	c, err = manager.Load("tutorial")
+	if err != nil || len(c.Requests) != 2 {
+		t.Fatalf("Expected two requests, got %+v, %v", c, err)
+	}
+	if got := c.Requests[0]; got.Request.Method != "HEAD" || got.Owner != "identity-team" || !reflect.DeepEqual(got.Asserts, asserts) {
+		t.Errorf("Expected the request to be replaced, got %+v", got)
+	}
+
+	if err := manager.SaveRequest("tutorial", SavedRequest{}); err == nil {
+		t.Error("Expected an error for a reference without a request name")
+	}
+}
+
+func TestCollection_BudgetFor(t *testing.T) {
+	c := &Collection{
+		Name:   "users",
+		Budget: &Budget{MaxLatencyMs: 500},
+		Requests: []SavedRequest{
+			{Name: "list"},
+			{Name: "export", Budget: &Budget{MaxLatencyMs: 5000, MaxBodyBytes: 1 << 20}},
+		},
+	}
+
+	if got := c.BudgetFor(&c.Requests[0]); got.MaxLatencyMs != 500 {
+		t.Errorf("Expected collection budget, got %+v", got)
+	}
+	if got := c.BudgetFor(&c.Requests[1]); got.MaxLatencyMs != 5000 || got.MaxBodyBytes != 1<<20 {
+		t.Errorf("Expected request budget, got %+v", got)
+	}
+
+	c.Budget = nil
+	if got := c.BudgetFor(&c.Requests[0]); got != nil {
+		t.Errorf("Expected no budget, got %+v", got)
+	}
+}
+
+func TestSavedRequest_Stale(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	asserts := []assertion.Assertion{{Query: "status", Predicate: "==", Value: "200"}}
+	tests := []struct {
+		name  string
+		saved SavedRequest
+		want  bool
+	}{
+		{"no assertions", SavedRequest{}, false},
+		{"never verified", SavedRequest{Asserts: asserts}, true},
+		{"recently verified", SavedRequest{Asserts: asserts, LastVerified: now.Add(-24 * time.Hour)}, false},
+		{"verified long ago", SavedRequest{Asserts: asserts, LastVerified: now.Add(-StaleAfter - time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.saved.Stale(now); got != tt.want {
+			t.Errorf("%s: Stale() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollection_MarkVerified(t *testing.T) {
+	c := &Collection{Name: "users", Requests: []SavedRequest{{Name: "list"}, {Name: "create"}}}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := c.MarkVerified([]string{"create", "missing"}, at); got != 1 {
+		t.Errorf("Expected one request to be updated, got %d", got)
+	}
+	if !c.Requests[1].LastVerified.Equal(at) || !c.Requests[0].LastVerified.IsZero() {
+		t.Errorf("Expected only create to be verified, got %+v", c.Requests)
+	}
+}
@@ -0,0 +1,184 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TrashRetention is how long deleted collections and requests are kept in
+// the trash before they are removed for good
+const TrashRetention = 30 * 24 * time.Hour
+
+// now returns the current time; replaced in tests
+var now = time.Now
+
+// TrashItem is a deleted collection, or a request deleted from a
+// collection, kept so that it can be restored
+type TrashItem struct {
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+
+	// Collection is set when a whole collection was deleted
+	Collection *Collection `json:"collection,omitempty"`
+
+	// Request is set when a request was deleted from the collection named
+	// From
+	From    string        `json:"from,omitempty"`
+	Request *SavedRequest `json:"request,omitempty"`
+}
+
+// Name returns the collection name, or collection/request for a request
+func (t TrashItem) Name() string {
+	if t.Collection != nil {
+		return t.Collection.Name
+	}
+	return t.From + "/" + t.Request.Name
+}
+
+// ExpiresAt returns when the item is removed from the trash
+func (t TrashItem) ExpiresAt() time.Time {
+	return t.DeletedAt.Add(TrashRetention)
+}
+
+// Delete moves the named collection to the trash
+func (m *Manager) Delete(name string) (TrashItem, error) {
+	c, err := m.Load(name)
+	if err != nil {
+		return TrashItem{}, err
+	}
+	item, err := m.trash(TrashItem{Collection: c})
+	if err != nil {
+		return TrashItem{}, err
+	}
+	if err := os.Remove(m.path(name)); err != nil {
+		return TrashItem{}, err
+	}
+	return item, nil
+}
+
+// DeleteRequest moves a saved request, given as a "collection/request"
+// reference, to the trash
+func (m *Manager) DeleteRequest(ref string) (TrashItem, error) {
+	saved, err := m.Get(ref)
+	if err != nil {
+		return TrashItem{}, err
+	}
+	collectionName, _, _ := strings.Cut(ref, "/")
+	c, err := m.Load(collectionName)
+	if err != nil {
+		return TrashItem{}, err
+	}
+	item, err := m.trash(TrashItem{From: c.Name, Request: saved})
+	if err != nil {
+		return TrashItem{}, err
+	}
+	c.Requests = slices.DeleteFunc(c.Requests, func(r SavedRequest) bool { return r.Name == saved.Name })
+	if err := m.Save(c); err != nil {
+		return TrashItem{}, err
+	}
+	return item, nil
+}
+
+// Trash returns the items in the trash, oldest first. Items older than
+// TrashRetention are removed.
+func (m *Manager) Trash() ([]TrashItem, error) {
+	return m.loadTrash()
+}
+
+// Restore puts the trash item with the given ID back: a collection under
+// its name, or a request at the end of the collection it was deleted from,
+// which is created if it no longer exists. Nothing is overwritten.
+func (m *Manager) Restore(id int) (TrashItem, error) {
+	items, err := m.loadTrash()
+	if err != nil {
+		return TrashItem{}, err
+	}
+	index := slices.IndexFunc(items, func(item TrashItem) bool { return item.ID == id })
+	if index < 0 {
+		return TrashItem{}, fmt.Errorf("no item #%d in the trash", id)
+	}
+	item := items[index]
+
+	if item.Collection != nil {
+		if _, err := os.Stat(m.path(item.Collection.Name)); err == nil {
+			return TrashItem{}, fmt.Errorf("collection %s already exists", item.Collection.Name)
+		}
+		if err := m.Save(item.Collection); err != nil {
+			return TrashItem{}, err
+		}
+	} else {
+		c, err := m.Load(item.From)
+		if errors.Is(err, ErrNotFound) {
+			c, err = &Collection{Name: item.From}, nil
+		}
+		if err != nil {
+			return TrashItem{}, err
+		}
+		if _, ok := c.Find(item.Request.Name); ok {
+			return TrashItem{}, fmt.Errorf("collection %s already has a request named %s", c.Name, item.Request.Name)
+		}
+		c.Requests = append(c.Requests, *item.Request)
+		if err := m.Save(c); err != nil {
+			return TrashItem{}, err
+		}
+	}
+	return item, m.saveTrash(slices.Delete(items, index, index+1))
+}
+
+// trash adds an item to the trash, numbering it and recording when it was
+// deleted
+func (m *Manager) trash(item TrashItem) (TrashItem, error) {
+	items, err := m.loadTrash()
+	if err != nil {
+		return TrashItem{}, err
+	}
+	item.ID = 1
+	for _, existing := range items {
+		item.ID = max(item.ID, existing.ID+1)
+	}
+	item.DeletedAt = now()
+	return item, m.saveTrash(append(items, item))
+}
+
+// loadTrash reads the trash, dropping expired items
+func (m *Manager) loadTrash() ([]TrashItem, error) {
+	data, err := os.ReadFile(m.trashPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []TrashItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse trash: %v", err)
+	}
+	kept := slices.DeleteFunc(slices.Clone(items), func(item TrashItem) bool { return now().After(item.ExpiresAt()) })
+	if len(kept) < len(items) {
+		if err := m.saveTrash(kept); err != nil {
+			return nil, err
+		}
+	}
+	return kept, nil
+}
+
+// saveTrash writes the trash to disk
+func (m *Manager) saveTrash(items []TrashItem) error {
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash: %v", err)
+	}
+	return os.WriteFile(m.trashPath(), data, 0644)
+}
+
+// trashPath returns the file the trash is kept in, next to the collections
+// directory
+func (m *Manager) trashPath() string {
+	return filepath.Join(filepath.Dir(m.dir), "trash.json")
+}
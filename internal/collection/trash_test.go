@@ -0,0 +1,111 @@
+package collection
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestManager_TrashAndRestore(t *testing.T) {
+	setupHome(t)
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	users := &Collection{Name: "users", Requests: []SavedRequest{
+		{Name: "list", Request: request.RequestData{Method: "GET", URL: "https://api.example.com/users"}},
+		{Name: "create", Request: request.RequestData{Method: "POST", URL: "https://api.example.com/users"}},
+	}}
+	if err := manager.Save(users); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Deleting a request keeps the rest of the collection
+	item, err := manager.DeleteRequest("users/create")
+	if err != nil {
+		t.Fatalf("DeleteRequest() error = %v", err)
+	}
+	if item.ID != 1 || item.Name() != "users/create" {
+		t.Errorf("Unexpected trash item: %+v", item)
+	}
+	if _, err := manager.Get("users/create"); err == nil {
+		t.Error("Expected the request to be removed from the collection")
+	}
+	if _, err := manager.Get("users/list"); err != nil {
+		t.Errorf("Expected the other request to be kept, got %v", err)
+	}
+
+	// Deleting the collection removes it from the list
+	if item, err = manager.Delete("users"); err != nil || item.ID != 2 {
+		t.Fatalf("Delete() = %+v, %v", item, err)
+	}
+	if names, _ := manager.List(); len(names) != 0 {
+		t.Errorf("Expected no collections, got %v", names)
+	}
+	if _, err := manager.Delete("users"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a missing collection, got %v", err)
+	}
+
+	items, err := manager.Trash()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Trash() = %+v, %v", items, err)
+	}
+
+	// A request comes back into the collection it was deleted from, which
+	// is created again when needed
+	if _, err := manager.Restore(1); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if saved, err := manager.Get("users/create"); err != nil || saved.Request.Method != "POST" {
+		t.Errorf("Expected the request to be restored, got %+v, %v", saved, err)
+	}
+
+	// Restoring does not overwrite the collection that now exists
+	if _, err := manager.Restore(2); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected restoring over an existing collection to fail, got %v", err)
+	}
+	if _, err := manager.Restore(1); err == nil {
+		t.Error("Expected a restored item to leave the trash")
+	}
+	if _, err := manager.Delete("users"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := manager.Restore(2); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if c, err := manager.Load("users"); err != nil || len(c.Requests) != 1 {
+		t.Errorf("Expected the collection as it was deleted, got %+v, %v", c, err)
+	}
+}
+
+func TestManager_TrashRetention(t *testing.T) {
+	setupHome(t)
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	for _, name := range []string{"old", "new"} {
+		if err := manager.Save(&Collection{Name: name}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	start := time.Now()
+	defer func() { now = time.Now }()
+	now = func() time.Time { return start.Add(-31 * 24 * time.Hour) }
+	if _, err := manager.Delete("old"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	now = func() time.Time { return start }
+	if _, err := manager.Delete("new"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	items, err := manager.Trash()
+	if err != nil || len(items) != 1 || items[0].Name() != "new" {
+		t.Errorf("Expected items older than 30 days to be removed, got %+v, %v", items, err)
+	}
+}
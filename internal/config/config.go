@@ -0,0 +1,288 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Config represents the user configuration stored in ~/.lighttr/config.json
+type Config struct {
+	// DefaultHeaders are merged into every request unless the request
+	// overrides or disables them
+	DefaultHeaders map[string]string `json:"default_headers,omitempty"`
+
+	// PersistCookies keeps cookies set by servers in ~/.lighttr/cookies.json
+	// so sessions carry over between runs
+	PersistCookies bool `json:"persist_cookies,omitempty"`
+
+	// DefaultScheme is added to URLs typed without a scheme, e.g. "http"
+	// for local development; request.DefaultScheme when empty
+	DefaultScheme string `json:"default_scheme,omitempty"`
+
+	// ProxyAuth holds proxy credentials by proxy host:port or host, so
+	// requests can name a proxy without embedding credentials in its URL
+	ProxyAuth map[string]request.ProxyCredentials `json:"proxy_auth,omitempty"`
+
+	// NotifyAfter is how long a request must take, e.g. "30s", before the
+	// TUI sends a desktop notification when it completes while the terminal
+	// is unfocused; DefaultNotifyAfter when empty and "off" to disable
+	NotifyAfter string `json:"notify_after,omitempty"`
+
+	// MaxResponseSize is the largest response body read, e.g. "500MB";
+	// longer bodies are truncated. DefaultMaxResponseSize when empty and
+	// "off" for no limit.
+	MaxResponseSize string `json:"max_response_size,omitempty"`
+
+	// Theme selects the TUI colors, one of Themes; "default" when empty
+	Theme string `json:"theme,omitempty"`
+
+	// Keybindings selects additional TUI key bindings, one of
+	// KeybindingPresets; "default" when empty
+	Keybindings string `json:"keybindings,omitempty"`
+
+	// ReducedMotion keeps the TUI cursor from blinking and the Retry-After
+	// countdown from ticking, as --reduced-motion does
+	ReducedMotion bool `json:"reduced_motion,omitempty"`
+
+	// NoAltScreen prints every response and error of the TUI to the
+	// terminal's scrollback, as --no-alt-screen does
+	NoAltScreen bool `json:"no_alt_screen,omitempty"`
+
+	// DataDir is where collections, environments, history, cookies, and the
+	// offline queue are kept; ~/.lighttr when empty. A leading ~/ stands
+	// for the home directory. The configuration itself stays in ~/.lighttr.
+	DataDir string `json:"data_dir,omitempty"`
+
+	// Hooks are shell commands run around every request
+	Hooks Hooks `json:"hooks,omitzero"`
+
+	// Webhooks post summaries of collection runs and monitor failures to
+	// Slack or Microsoft Teams
+	Webhooks []notify.Webhook `json:"webhooks,omitempty"`
+
+	// TokenStorage is where OAuth2 tokens are cached, one of TokenStorages:
+	// "keyring" keeps them in the system keyring, falling back to the token
+	// file when there is none, and "file" in the token file only; "keyring"
+	// when empty. The secrets of auth profiles are kept the same way.
+	TokenStorage string `json:"token_storage,omitempty"`
+}
+
+// Themes lists the TUI color themes
+var Themes = []string{"default", "light", "high-contrast"}
+
+// KeybindingPresets lists the TUI key binding presets: "emacs" adds
+// Ctrl+N and Ctrl+P to move between fields and Ctrl+G to go back
+var KeybindingPresets = []string{"default", "emacs"}
+
+// TokenStorages lists the places OAuth2 tokens can be cached
+var TokenStorages = []string{"keyring", "file"}
+
+// DefaultNotifyAfter is the NotifyAfter used when none is configured
+const DefaultNotifyAfter = 10 * time.Second
+
+// DefaultMaxResponseSize is the MaxResponseSize used when none is
+// configured, 100 MB
+const DefaultMaxResponseSize = 100 * 1000 * 1000
+
+// Path returns the location of the configuration file
+func Path() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".lighttr", "config.json"), nil
+}
+
+// CookiesPath returns the location of the persistent cookie jar
+func CookiesPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cookies.json"), nil
+}
+
+// HistoryPath returns the location of the request history
+func HistoryPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// DataDir returns the directory lighttr keeps its data in: DataDir from the
+// configuration, or ~/.lighttr
+func DataDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	cfg, err := Load()
+	if err != nil {
+		return "", err
+	}
+	if cfg.DataDir == "" {
+		return filepath.Join(homeDir, ".lighttr"), nil
+	}
+	return ExpandHome(cfg.DataDir)
+}
+
+// ExpandHome replaces a leading ~/ in path with the home directory
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
+
+// FirstRun reports whether lighttr has not been set up yet, i.e. ~/.lighttr
+// does not exist
+func FirstRun() bool {
+	path, err := Path()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Dir(path))
+	return os.IsNotExist(err)
+}
+
+// Load reads the configuration file, returning an empty configuration if it
+// does not exist
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	if _, err := cfg.parseNotifyAfter(); err != nil {
+		return nil, err
+	}
+	if _, err := ParseMaxResponseSize(cfg.MaxResponseSize); err != nil {
+		return nil, err
+	}
+	if cfg.Theme != "" && !slices.Contains(Themes, cfg.Theme) {
+		return nil, fmt.Errorf("invalid theme %q: use one of %s", cfg.Theme, strings.Join(Themes, ", "))
+	}
+	if cfg.Keybindings != "" && !slices.Contains(KeybindingPresets, cfg.Keybindings) {
+		return nil, fmt.Errorf("invalid keybindings %q: use one of %s", cfg.Keybindings, strings.Join(KeybindingPresets, ", "))
+	}
+	if cfg.TokenStorage != "" && !slices.Contains(TokenStorages, cfg.TokenStorage) {
+		return nil, fmt.Errorf("invalid token storage %q: use one of %s", cfg.TokenStorage, strings.Join(TokenStorages, ", "))
+	}
+	for _, webhook := range cfg.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration file
+func (c *Config) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	// The configuration can hold proxy credentials
+	return os.WriteFile(path, data, 0600)
+}
+
+// NotifyThreshold returns how long a request must take before its
+// completion is notified, or zero when notifications are off
+func (c *Config) NotifyThreshold() time.Duration {
+	threshold, err := c.parseNotifyAfter()
+	if err != nil {
+		return DefaultNotifyAfter
+	}
+	return threshold
+}
+
+// parseNotifyAfter parses NotifyAfter
+func (c *Config) parseNotifyAfter() (time.Duration, error) {
+	switch c.NotifyAfter {
+	case "":
+		return DefaultNotifyAfter, nil
+	case "off":
+		return 0, nil
+	}
+	threshold, err := time.ParseDuration(c.NotifyAfter)
+	if err != nil || threshold < 0 {
+		return 0, fmt.Errorf("invalid notify_after %q: expected a duration such as 30s, or off", c.NotifyAfter)
+	}
+	return threshold, nil
+}
+
+// ResponseSizeLimit returns the largest response body to read, or zero for
+// no limit
+func (c *Config) ResponseSizeLimit() int64 {
+	limit, err := ParseMaxResponseSize(c.MaxResponseSize)
+	if err != nil {
+		return DefaultMaxResponseSize
+	}
+	return limit
+}
+
+// ParseMaxResponseSize parses a maximum response size such as "100MB",
+// returning DefaultMaxResponseSize for "" and zero for "off"
+func ParseMaxResponseSize(s string) (int64, error) {
+	switch s {
+	case "":
+		return DefaultMaxResponseSize, nil
+	case "off":
+		return 0, nil
+	}
+	limit, err := request.ParseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_response_size %q: expected a size such as 100MB, or off", s)
+	}
+	return limit, nil
+}
+
+// CookieJar opens the persistent cookie jar when persist is requested or
+// PersistCookies is set, and an in-memory jar otherwise
+func (c *Config) CookieJar(persist bool) (*request.CookieJar, error) {
+	if !persist && !c.PersistCookies {
+		return request.NewCookieJar(), nil
+	}
+
+	path, err := CookiesPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return request.LoadCookieJar(path)
+}
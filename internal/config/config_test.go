@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/notify"
+)
+
+func TestLoad(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	// Missing config file yields an empty configuration
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.DefaultHeaders) != 0 {
+		t.Errorf("Expected no default headers, got %v", cfg.DefaultHeaders)
+	}
+
+	lighttrDir := filepath.Join(tmpDir, ".lighttr")
+	if err := os.MkdirAll(lighttrDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	content := `{"default_headers": {"User-Agent": "lighttr", "X-Org-Trace": "on"}}`
+	if err := os.WriteFile(filepath.Join(lighttrDir, "config.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DefaultHeaders["User-Agent"] != "lighttr" || cfg.DefaultHeaders["X-Org-Trace"] != "on" {
+		t.Errorf("Expected default headers to be loaded, got %v", cfg.DefaultHeaders)
+	}
+
+	// Invalid JSON is reported
+	if err := os.WriteFile(filepath.Join(lighttrDir, "config.json"), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid config")
+	}
+
+	// So is an invalid notification threshold
+	if err := os.WriteFile(filepath.Join(lighttrDir, "config.json"), []byte(`{"notify_after": "soon"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for an invalid notify_after")
+	}
+}
+
+func TestConfig_NotifyThreshold(t *testing.T) {
+	tests := []struct {
+		notifyAfter string
+		want        time.Duration
+	}{
+		{"", DefaultNotifyAfter},
+		{"30s", 30 * time.Second},
+		{"off", 0},
+	}
+	for _, tt := range tests {
+		cfg := &Config{NotifyAfter: tt.notifyAfter}
+		if got := cfg.NotifyThreshold(); got != tt.want {
+			t.Errorf("NotifyThreshold() for %q = %v, want %v", tt.notifyAfter, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_ResponseSizeLimit(t *testing.T) {
+	tests := []struct {
+		maxResponseSize string
+		want            int64
+	}{
+		{"", DefaultMaxResponseSize},
+		{"1MiB", 1 << 20},
+		{"off", 0},
+	}
+	for _, tt := range tests {
+		cfg := &Config{MaxResponseSize: tt.maxResponseSize}
+		if got := cfg.ResponseSizeLimit(); got != tt.want {
+			t.Errorf("ResponseSizeLimit() for %q = %d, want %d", tt.maxResponseSize, got, tt.want)
+		}
+	}
+	if _, err := ParseMaxResponseSize("lots"); err == nil {
+		t.Error("Expected an error for an invalid size")
+	}
+}
+
+func TestConfig_CookieJar(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	path := filepath.Join(tmpDir, ".lighttr", "cookies.json")
+
+	// Without persistence the jar lives in memory only
+	jar, err := (&Config{}).CookieJar(false)
+	if err != nil {
+		t.Fatalf("CookieJar() error = %v", err)
+	}
+	if err := jar.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no cookie file for an in-memory jar, got %v", err)
+	}
+
+	// Either the config or the caller can ask for persistence
+	for _, tt := range []struct {
+		cfg     *Config
+		persist bool
+	}{{&Config{PersistCookies: true}, false}, {&Config{}, true}} {
+		jar, err := tt.cfg.CookieJar(tt.persist)
+		if err != nil {
+			t.Fatalf("CookieJar() error = %v", err)
+		}
+		if err := jar.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected cookie file at %s: %v", path, err)
+		}
+		os.Remove(path)
+	}
+}
+
+func TestConfig_SaveAndDataDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if !FirstRun() {
+		t.Error("Expected a first run without ~/.lighttr")
+	}
+	dir, err := DataDir()
+	if err != nil {
+		t.Fatalf("DataDir() error = %v", err)
+	}
+	if dir != filepath.Join(home, ".lighttr") {
+		t.Errorf("Expected the default data directory, got %s", dir)
+	}
+
+	cfg := &Config{Theme: "light", Keybindings: "emacs", DataDir: "~/work/lighttr"}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if FirstRun() {
+		t.Error("Expected saving the configuration to end the first run")
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Theme != "light" || loaded.Keybindings != "emacs" {
+		t.Errorf("Expected the saved configuration, got %+v", loaded)
+	}
+	if dir, _ := DataDir(); dir != filepath.Join(home, "work", "lighttr") {
+		t.Errorf("Expected the configured data directory, got %s", dir)
+	}
+	if path, _ := CookiesPath(); path != filepath.Join(home, "work", "lighttr", "cookies.json") {
+		t.Errorf("Expected cookies in the data directory, got %s", path)
+	}
+
+	// Unknown themes, presets, and token storages, and invalid webhooks,
+	// are rejected
+	for _, bad := range []*Config{{Theme: "neon"}, {Keybindings: "vi"}, {TokenStorage: "vault"}, {Webhooks: []notify.Webhook{{URL: "https://hooks.slack.com/x", On: []string{"deploy"}}}}} {
+		if err := bad.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error loading %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Hooks are shell commands run around every request, e.g. to refresh a VPN
+// token before sending or to append responses to a log. Each command gets
+// the exchange as JSON on stdin; a command that fails stops the request
+// with its error output.
+type Hooks struct {
+	// BeforeSend runs before each attempt is sent, with the request
+	BeforeSend string `json:"before_send,omitempty"`
+
+	// AfterResponse runs after each response, with the request and the
+	// response
+	AfterResponse string `json:"after_response,omitempty"`
+}
+
+// hookRequest is the request as given to hooks
+type hookRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+
+	// Body is omitted when it is streamed from a file, stdin, or multipart
+	// parts
+	Body string `json:"body,omitempty"`
+}
+
+// hookInput is the JSON hooks read on stdin
+type hookInput struct {
+	Hook     string                `json:"hook"`
+	Request  hookRequest           `json:"request"`
+	Response *request.ResponseData `json:"response,omitempty"`
+}
+
+// HookMiddleware returns middleware that runs the hooks, or nil when none
+// are set
+func (c *Config) HookMiddleware() request.Middleware {
+	hooks := c.Hooks
+	if hooks.BeforeSend == "" && hooks.AfterResponse == "" {
+		return nil
+	}
+	return func(next request.Handler) request.Handler {
+		return func(x *request.Exchange) error {
+			input := hookInput{Request: hookRequest{
+				Method:  x.Request.Method,
+				URL:     x.Request.URL.String(),
+				Headers: x.Request.Header,
+			}}
+			if data := x.Data; data.BodyFile == "" && data.BodyReader == nil && len(data.Multipart) == 0 {
+				input.Request.Body = data.Body
+				if len(data.FormParams) > 0 {
+					input.Request.Body = data.EncodedForm()
+				}
+			}
+
+			if hooks.BeforeSend != "" {
+				input.Hook = "before_send"
+				if err := runHook(x.Request.Context(), hooks.BeforeSend, input); err != nil {
+					return x.Abort(err)
+				}
+			}
+			if err := next(x); err != nil {
+				return err
+			}
+			if hooks.AfterResponse != "" && x.Response != nil {
+				input.Hook = "after_response"
+				input.Response = x.Response
+				return runHook(x.Request.Context(), hooks.AfterResponse, input)
+			}
+			return nil
+		}
+	}
+}
+
+// runHook runs the command with the shell, passing the input on stdin and
+// as LIGHTTR_HOOK, LIGHTTR_METHOD, and LIGHTTR_URL. Its output is
+// discarded unless it fails.
+func runHook(ctx context.Context, command string, input hookInput) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("%s hook: %v", input.Hook, err)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"LIGHTTR_HOOK="+input.Hook,
+		"LIGHTTR_METHOD="+input.Request.Method,
+		"LIGHTTR_URL="+input.Request.URL,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s hook failed: %v: %s", input.Hook, err, message)
+		}
+		return fmt.Errorf("%s hook failed: %v", input.Hook, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestConfig_HookMiddleware(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks in this test are POSIX shell commands")
+	}
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	if (&Config{}).HookMiddleware() != nil {
+		t.Error("Expected no middleware without hooks")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "hooks.log")
+	cfg := &Config{Hooks: Hooks{
+		BeforeSend:    `cat > ` + filepath.Join(dir, "before.json") + ` && echo "$LIGHTTR_HOOK $LIGHTTR_METHOD" >> ` + log,
+		AfterResponse: `cat > ` + filepath.Join(dir, "after.json") + ` && echo "$LIGHTTR_HOOK $LIGHTTR_URL" >> ` + log,
+	}}
+	req := request.NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL + "/events"
+	req.Body = `{"id": 1}`
+	if resp, err := req.ExecuteWith(cfg.HookMiddleware()); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("ExecuteWith() = %+v, %v", resp, err)
+	}
+
+	data, _ := os.ReadFile(log)
+	if want := "before_send POST\nafter_response " + server.URL + "/events\n"; string(data) != want {
+		t.Errorf("Expected the hooks to run in order, got %q", data)
+	}
+	var before, after hookInput
+	data, _ = os.ReadFile(filepath.Join(dir, "before.json"))
+	if err := json.Unmarshal(data, &before); err != nil || before.Request.Body != `{"id": 1}` || before.Response != nil {
+		t.Errorf("Expected the request on stdin, got %s (%v)", data, err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "after.json"))
+	if err := json.Unmarshal(data, &after); err != nil || after.Response == nil || after.Response.StatusCode != http.StatusCreated {
+		t.Errorf("Expected the response on stdin, got %s (%v)", data, err)
+	}
+
+	// A failing before_send hook keeps the request from being sent
+	cfg.Hooks = Hooks{BeforeSend: `echo "VPN is down" >&2; exit 3`}
+	_, err := req.ExecuteWith(cfg.HookMiddleware())
+	if err == nil || !strings.Contains(err.Error(), "before_send hook failed: exit status 3: VPN is down") {
+		t.Errorf("Expected the hook's error, got %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("Expected the request not to be sent again, got %d sends", sent)
+	}
+}
@@ -0,0 +1,141 @@
+// Package demo serves a small users API on localhost for lighttr tutorial
+// to send its requests to, so that learning needs no network or account
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// User is a user of the demo API
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// errorResponse is the body of every failed call
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the demo API until it is closed
+type Server struct {
+	// URL is the base URL of the API, e.g. http://127.0.0.1:49152
+	URL string
+
+	server *http.Server
+	mu     sync.Mutex
+	users  []User
+}
+
+// Start serves the demo API on a free port of the loopback interface
+func Start() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the demo API: %v", err)
+	}
+	s := New()
+	s.URL = "http://" + listener.Addr().String()
+	s.server = &http.Server{Handler: s.Handler()}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// New returns the demo API with its initial users, not yet listening
+func New() *Server {
+	return &Server{users: []User{
+		{ID: 1, Name: "Ada Lovelace", Email: "ada@example.com", Role: "admin"},
+		{ID: 2, Name: "Alan Turing", Email: "alan@example.com", Role: "member"},
+		{ID: 3, Name: "Grace Hopper", Email: "grace@example.com", Role: "member"},
+	}}
+}
+
+// Close stops serving the API
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// Handler returns the API routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users", s.listUsers)
+	mux.HandleFunc("POST /users", s.createUser)
+	mux.HandleFunc("GET /users/{id}", s.getUser)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "not found: try GET /users or GET /users/1"})
+	})
+	return mux
+}
+
+// listUsers returns every user, or those with the role given as ?role=
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := []User{}
+	role := r.URL.Query().Get("role")
+	for _, user := range s.users {
+		if role == "" || user.Role == role {
+			users = append(users, user)
+		}
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// getUser returns the user with the ID in the path
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "the user ID must be a number"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.ID == id {
+			writeJSON(w, http.StatusOK, user)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("user %d not found", id)})
+}
+
+// createUser adds the user in the JSON body and returns it with its ID
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var user User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid JSON body: %v", err)})
+		return
+	}
+	if strings.TrimSpace(user.Name) == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: "name is required"})
+		return
+	}
+	if user.Role == "" {
+		user.Role = "member"
+	}
+
+	s.mu.Lock()
+	user.ID = s.users[len(s.users)-1].ID + 1
+	s.users = append(s.users, user)
+	s.mu.Unlock()
+
+	w.Header().Set("Location", fmt.Sprintf("/users/%d", user.ID))
+	writeJSON(w, http.StatusCreated, user)
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
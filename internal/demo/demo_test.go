@@ -0,0 +1,70 @@
+package demo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// call sends a request to the demo API and decodes the JSON response
+func call(t *testing.T, method, url, body string, v interface{}) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("Expected JSON, got %q", data)
+	}
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	s, err := Start()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Close()
+
+	var user User
+	if resp := call(t, "GET", s.URL+"/users/1", "", &user); resp.StatusCode != http.StatusOK || user.Name != "Ada Lovelace" {
+		t.Errorf("Expected Ada, got %d %+v", resp.StatusCode, user)
+	}
+
+	var created User
+	resp := call(t, "POST", s.URL+"/users", `{"name": "Katherine Johnson", "email": "katherine@example.com"}`, &created)
+	if resp.StatusCode != http.StatusCreated || created.ID != 4 || created.Role != "member" || resp.Header.Get("Location") != "/users/4" {
+		t.Errorf("Expected the user to be created, got %d %+v", resp.StatusCode, created)
+	}
+
+	var members []User
+	if call(t, "GET", s.URL+"/users?role=member", "", &members); len(members) != 3 {
+		t.Errorf("Expected three members, got %+v", members)
+	}
+
+	var failure errorResponse
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/users/9", "", http.StatusNotFound},
+		{"GET", "/users/ada", "", http.StatusBadRequest},
+		{"POST", "/users", `{"email": "nobody@example.com"}`, http.StatusUnprocessableEntity},
+		{"GET", "/orders", "", http.StatusNotFound},
+	} {
+		if resp := call(t, tt.method, s.URL+tt.path, tt.body, &failure); resp.StatusCode != tt.status || failure.Error == "" {
+			t.Errorf("%s %s: expected %d with an error, got %d %+v", tt.method, tt.path, tt.status, resp.StatusCode, failure)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package environment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// runCommand runs a command and returns its standard output; replaced in
+// tests
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s failed: %s", name, message)
+		}
+		return nil, fmt.Errorf("%s failed: %v", name, err)
+	}
+	return out, nil
+}
+
+// composeService is a running service as reported by docker compose ps
+type composeService struct {
+	Project    string `json:"Project"`
+	Service    string `json:"Service"`
+	Publishers []struct {
+		URL           string `json:"URL"`
+		TargetPort    int    `json:"TargetPort"`
+		PublishedPort int    `json:"PublishedPort"`
+		Protocol      string `json:"Protocol"`
+	} `json:"Publishers"`
+}
+
+// FromCompose generates an environment from the running services of a
+// Docker Compose file. Each published TCP port becomes <service>_url,
+// <service>_host, and <service>_port variables; further ports of a service
+// are suffixed with the container port, e.g. api_9090_url. base_url is set
+// when a single service publishes a port. The environment is named after
+// the Compose project.
+func FromCompose(file string) (*Environment, error) {
+	out, err := runCommand("docker", "compose", "-f", file, "ps", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+	services, err := parseComposeServices(out)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Environment{Variables: make(map[string]string)}
+	var endpoints []endpoint
+	for _, service := range services {
+		env.Name = service.Project
+		seen := make(map[int]bool)
+		for _, p := range service.Publishers {
+			if p.PublishedPort == 0 || (p.Protocol != "" && p.Protocol != "tcp") || seen[p.TargetPort] {
+				continue
+			}
+			seen[p.TargetPort] = true
+			host := p.URL
+			if host == "" || net.ParseIP(host).IsUnspecified() {
+				host = "localhost"
+			}
+			endpoints = append(endpoints, endpoint{
+				service: service.Service,
+				target:  p.TargetPort,
+				scheme:  schemeFor(p.TargetPort, ""),
+				host:    host,
+				port:    p.PublishedPort,
+			})
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no running services with published ports in %s", file)
+	}
+	addEndpoints(env, endpoints)
+	return env, nil
+}
+
+// parseComposeServices reads docker compose ps output, which is a JSON
+// array in older releases and one JSON object per line in newer ones
+func parseComposeServices(out []byte) ([]composeService, error) {
+	out = bytes.TrimSpace(out)
+	var services []composeService
+	if bytes.HasPrefix(out, []byte("[")) {
+		if err := json.Unmarshal(out, &services); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps output: %v", err)
+		}
+		return services, nil
+	}
+	for line := range bytes.Lines(out) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var service composeService
+		if err := json.Unmarshal(line, &service); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps output: %v", err)
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// kubernetesServices is the part of kubectl get services -o json used
+type kubernetesServices struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Type  string `json:"type"`
+			Ports []struct {
+				Name     string `json:"name"`
+				Port     int    `json:"port"`
+				NodePort int    `json:"nodePort"`
+				Protocol string `json:"protocol"`
+			} `json:"ports"`
+		} `json:"spec"`
+		Status struct {
+			LoadBalancer struct {
+				Ingress []struct {
+					IP       string `json:"ip"`
+					Hostname string `json:"hostname"`
+				} `json:"ingress"`
+			} `json:"loadBalancer"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// FromKubernetes generates an environment, named after the namespace, from
+// the services in a Kubernetes namespace of the current kubectl context.
+// Load balancers are addressed by their ingress, node ports on localhost,
+// and other services by their cluster DNS name, which resolves inside the
+// cluster or through a VPN or port-forwarding tool that provides it.
+// Variables are named as for FromCompose.
+func FromKubernetes(namespace string) (*Environment, error) {
+	out, err := runCommand("kubectl", "get", "services", "--namespace", namespace, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list kubernetesServices
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %v", err)
+	}
+
+	var endpoints []endpoint
+	for _, service := range list.Items {
+		ingress := ""
+		if lb := service.Status.LoadBalancer.Ingress; len(lb) > 0 {
+			ingress = lb[0].Hostname
+			if ingress == "" {
+				ingress = lb[0].IP
+			}
+		}
+		for _, p := range service.Spec.Ports {
+			if p.Protocol != "" && p.Protocol != "TCP" {
+				continue
+			}
+			e := endpoint{
+				service: service.Metadata.Name,
+				target:  p.Port,
+				scheme:  schemeFor(p.Port, p.Name),
+				host:    service.Metadata.Name + "." + namespace + ".svc.cluster.local",
+				port:    p.Port,
+			}
+			switch {
+			case service.Spec.Type == "LoadBalancer" && ingress != "":
+				e.host = ingress
+			case service.Spec.Type == "NodePort" || service.Spec.Type == "LoadBalancer" && p.NodePort != 0:
+				e.host, e.port = "localhost", p.NodePort
+			}
+			endpoints = append(endpoints, e)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("no TCP services found in namespace " + namespace)
+	}
+	env := &Environment{Name: namespace, Variables: make(map[string]string)}
+	addEndpoints(env, endpoints)
+	return env, nil
+}
+
+// endpoint is an address a discovered service is reachable at
+type endpoint struct {
+	service string
+	target  int
+	scheme  string
+	host    string
+	port    int
+}
+
+// variableUnsafe matches characters replaced in variable names
+var variableUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// addEndpoints sets the url, host, and port variables of the endpoints.
+// The lowest container port of a service gets the plain names.
+func addEndpoints(env *Environment, endpoints []endpoint) {
+	slices.SortStableFunc(endpoints, func(a, b endpoint) int {
+		if c := strings.Compare(a.service, b.service); c != 0 {
+			return c
+		}
+		return a.target - b.target
+	})
+	services := 0
+	for i, e := range endpoints {
+		prefix := strings.ToLower(variableUnsafe.ReplaceAllString(e.service, "_"))
+		if i > 0 && endpoints[i-1].service == e.service {
+			prefix += "_" + strconv.Itoa(e.target)
+		} else {
+			services++
+		}
+		hostPort := net.JoinHostPort(e.host, strconv.Itoa(e.port))
+		env.Variables[prefix+"_url"] = e.scheme + "://" + hostPort
+		env.Variables[prefix+"_host"] = e.host
+		env.Variables[prefix+"_port"] = strconv.Itoa(e.port)
+	}
+	if services == 1 {
+		e := endpoints[0]
+		env.Variables["base_url"] = e.scheme + "://" + net.JoinHostPort(e.host, strconv.Itoa(e.port))
+	}
+}
+
+// schemeFor guesses the scheme of a port from its number or name
+func schemeFor(port int, name string) string {
+	if port == 443 || port == 8443 || name == "https" || strings.HasPrefix(name, "https-") {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,102 @@
+package environment
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func stubCommand(t *testing.T, out string, err error) *[]string {
+	t.Helper()
+	var called []string
+	old := runCommand
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		called = append([]string{name}, args...)
+		return []byte(out), err
+	}
+	t.Cleanup(func() { runCommand = old })
+	return &called
+}
+
+func TestFromCompose(t *testing.T) {
+	out := `{"Project":"shop","Service":"api","Publishers":[{"URL":"0.0.0.0","TargetPort":8080,"PublishedPort":18080,"Protocol":"tcp"},{"URL":"::","TargetPort":8080,"PublishedPort":18080,"Protocol":"tcp"},{"URL":"127.0.0.1","TargetPort":9090,"PublishedPort":19090,"Protocol":"tcp"}]}
+{"Project":"shop","Service":"web-ui","Publishers":[{"URL":"0.0.0.0","TargetPort":443,"PublishedPort":8443,"Protocol":"tcp"}]}
+{"Project":"shop","Service":"db","Publishers":[{"URL":"","TargetPort":5432,"PublishedPort":0,"Protocol":"tcp"}]}
+`
+	called := stubCommand(t, out, nil)
+
+	env, err := FromCompose("compose.yml")
+	if err != nil {
+		t.Fatalf("FromCompose() error = %v", err)
+	}
+	if strings.Join(*called, " ") != "docker compose -f compose.yml ps --format json" {
+		t.Errorf("Unexpected command %v", *called)
+	}
+	want := map[string]string{
+		"api_url":       "http://localhost:18080",
+		"api_host":      "localhost",
+		"api_port":      "18080",
+		"api_9090_url":  "http://127.0.0.1:19090",
+		"api_9090_host": "127.0.0.1",
+		"api_9090_port": "19090",
+		"web_ui_url":    "https://localhost:8443",
+		"web_ui_host":   "localhost",
+		"web_ui_port":   "8443",
+	}
+	if env.Name != "shop" || !reflect.DeepEqual(env.Variables, want) {
+		t.Errorf("FromCompose() = %s %v, want shop %v", env.Name, env.Variables, want)
+	}
+}
+
+func TestFromCompose_Array(t *testing.T) {
+	stubCommand(t, `[{"Project":"app","Service":"api","Publishers":[{"URL":"0.0.0.0","TargetPort":80,"PublishedPort":8080,"Protocol":"tcp"}]}]`, nil)
+	env, err := FromCompose("compose.yml")
+	if err != nil {
+		t.Fatalf("FromCompose() error = %v", err)
+	}
+	if env.Variables["base_url"] != "http://localhost:8080" {
+		t.Errorf("Expected base_url for the only service, got %v", env.Variables)
+	}
+
+	stubCommand(t, "", nil)
+	if _, err := FromCompose("compose.yml"); err == nil {
+		t.Error("Expected an error when no service publishes a port")
+	}
+	stubCommand(t, "", errors.New("docker failed: no such file"))
+	if _, err := FromCompose("compose.yml"); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("Expected the docker error, got %v", err)
+	}
+}
+
+func TestFromKubernetes(t *testing.T) {
+	out := `{"items": [
+		{"metadata": {"name": "api"}, "spec": {"type": "ClusterIP", "ports": [{"name": "http", "port": 80, "protocol": "TCP"}]}},
+		{"metadata": {"name": "gateway"}, "spec": {"type": "LoadBalancer", "ports": [{"name": "https", "port": 443, "nodePort": 30443, "protocol": "TCP"}]},
+		 "status": {"loadBalancer": {"ingress": [{"ip": "203.0.113.7"}]}}},
+		{"metadata": {"name": "web"}, "spec": {"type": "NodePort", "ports": [{"port": 3000, "nodePort": 31000, "protocol": "TCP"}, {"port": 53, "protocol": "UDP"}]}}
+	]}`
+	called := stubCommand(t, out, nil)
+
+	env, err := FromKubernetes("review-42")
+	if err != nil {
+		t.Fatalf("FromKubernetes() error = %v", err)
+	}
+	if strings.Join(*called, " ") != "kubectl get services --namespace review-42 --output json" {
+		t.Errorf("Unexpected command %v", *called)
+	}
+	want := map[string]string{
+		"api_url":      "http://api.review-42.svc.cluster.local:80",
+		"api_host":     "api.review-42.svc.cluster.local",
+		"api_port":     "80",
+		"gateway_url":  "https://203.0.113.7:443",
+		"gateway_host": "203.0.113.7",
+		"gateway_port": "443",
+		"web_url":      "http://localhost:31000",
+		"web_host":     "localhost",
+		"web_port":     "31000",
+	}
+	if env.Name != "review-42" || !reflect.DeepEqual(env.Variables, want) {
+		t.Errorf("FromKubernetes() = %s %v, want review-42 %v", env.Name, env.Variables, want)
+	}
+}
@@ -0,0 +1,207 @@
+package environment
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// placeholderPattern matches {{var}} templates, allowing surrounding spaces
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// Environment represents a named set of variables used to resolve placeholders
+type Environment struct {
+	Name      string            `json:"name"`
+	Variables map[string]string `json:"variables"`
+}
+
+// Dir returns the directory where environments are stored
+func Dir() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "environments"), nil
+}
+
+// Load reads the named environment from ~/.lighttr/environments/<name>.json
+func Load(name string) (*Environment, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("environment not found: %s", name)
+		}
+		return nil, err
+	}
+
+	env := &Environment{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, fmt.Errorf("failed to parse environment %s: %v", name, err)
+	}
+	if env.Name == "" {
+		env.Name = name
+	}
+	if env.Variables == nil {
+		env.Variables = make(map[string]string)
+	}
+	return env, nil
+}
+
+// List returns the names of the stored environments, none when there are
+// none
+func List() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Save writes the environment to ~/.lighttr/environments/<name>.json
+func Save(env *Environment) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal environment: %v", err)
+	}
+	return os.WriteFile(filepath.Join(dir, env.Name+".json"), data, 0644)
+}
+
+// WithOverrides returns a copy of the environment, or of an empty one when
+// e is nil, with the given variables taking precedence
+func (e *Environment) WithOverrides(variables map[string]string) *Environment {
+	overridden := &Environment{Variables: make(map[string]string, len(variables))}
+	if e != nil {
+		overridden.Name = e.Name
+		maps.Copy(overridden.Variables, e.Variables)
+	}
+	maps.Copy(overridden.Variables, variables)
+	return overridden
+}
+
+// Lookup returns the value of a variable, checking the environment first
+// and falling back to the process environment
+func (e *Environment) Lookup(key string) (string, bool) {
+	if e != nil {
+		if value, ok := e.Variables[key]; ok {
+			return value, true
+		}
+	}
+	return os.LookupEnv(key)
+}
+
+// Resolve substitutes {{var}} placeholders in s. Placeholders that cannot be
+// resolved are left untouched and their names are returned in sorted order.
+func (e *Environment) Resolve(s string) (string, []string) {
+	missing := make(map[string]bool)
+	resolved := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		key := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := e.Lookup(key); ok {
+			return value
+		}
+		missing[key] = true
+		return match
+	})
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return resolved, names
+}
+
+// Placeholder is a {{var}} placeholder of a template, at s[Start:End]
+type Placeholder struct {
+	Name       string
+	Start, End int
+}
+
+// Placeholders returns the placeholders of s in the order they appear
+func Placeholders(s string) []Placeholder {
+	var placeholders []Placeholder
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(s, -1) {
+		placeholders = append(placeholders, Placeholder{Name: s[match[2]:match[3]], Start: match[0], End: match[1]})
+	}
+	return placeholders
+}
+
+// ResolveRequest substitutes placeholders in the request URL, headers, query
+// params, path params, body, form params, form parts, credentials, session
+// login URL, proxy settings, and resolve addresses. The names of unresolved placeholders are returned in
+// sorted order.
+func (e *Environment) ResolveRequest(req *request.RequestData) []string {
+	missing := make(map[string]bool)
+	resolve := func(s string) string {
+		resolved, unresolved := e.Resolve(s)
+		for _, name := range unresolved {
+			missing[name] = true
+		}
+		return resolved
+	}
+
+	req.URL = resolve(req.URL)
+	req.Body = resolve(req.Body)
+	for _, values := range []map[string]string{req.Headers, req.QueryParams, req.PathParams, req.FormParams, req.Resolve} {
+		for k, v := range values {
+			values[k] = resolve(v)
+		}
+	}
+	for i := range req.Multipart {
+		req.Multipart[i].Value = resolve(req.Multipart[i].Value)
+		req.Multipart[i].File = resolve(req.Multipart[i].File)
+	}
+	// The {{username}} and {{password}} of the session login body are
+	// filled in from the auth when it logs in
+	req.Auth.SessionLoginURL = resolve(req.Auth.SessionLoginURL)
+	for _, credential := range []*string{&req.Auth.Username, &req.Auth.Password, &req.Auth.Token, &req.Auth.APIKey, &req.Auth.AzureClientSecret, &req.Auth.JWTClaims} {
+		*credential = resolve(*credential)
+	}
+	if req.Proxy != nil {
+		req.Proxy.URL = resolve(req.Proxy.URL)
+		req.Proxy.Username = resolve(req.Proxy.Username)
+		req.Proxy.Password = resolve(req.Proxy.Password)
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
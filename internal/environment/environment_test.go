@@ -0,0 +1,216 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestLoad(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	envDir := filepath.Join(tmpDir, ".lighttr", "environments")
+	if err := os.MkdirAll(envDir, 0755); err != nil {
+		t.Fatalf("Failed to create environments dir: %v", err)
+	}
+	content := `{"variables": {"base_url": "https://staging.example.com"}}`
+	if err := os.WriteFile(filepath.Join(envDir, "staging.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write environment file: %v", err)
+	}
+
+	env, err := Load("staging")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if env.Name != "staging" {
+		t.Errorf("Expected name staging, got %s", env.Name)
+	}
+	if env.Variables["base_url"] != "https://staging.example.com" {
+		t.Errorf("Expected base_url to be loaded, got %q", env.Variables["base_url"])
+	}
+
+	if _, err := Load("missing"); err == nil {
+		t.Error("Expected error for missing environment")
+	}
+}
+
+func TestEnvironment_Resolve(t *testing.T) {
+	os.Setenv("LIGHTTR_TEST_TOKEN", "from-process")
+	defer os.Unsetenv("LIGHTTR_TEST_TOKEN")
+
+	env := &Environment{
+		Name: "test",
+		Variables: map[string]string{
+			"base_url":           "https://api.example.com",
+			"LIGHTTR_TEST_TOKEN": "from-env",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		env         *Environment
+		input       string
+		want        string
+		wantMissing []string
+	}{
+		{
+			name:  "environment variable",
+			env:   env,
+			input: "{{base_url}}/users",
+			want:  "https://api.example.com/users",
+		},
+		{
+			name:  "environment takes precedence over process env",
+			env:   env,
+			input: "Bearer {{ LIGHTTR_TEST_TOKEN }}",
+			want:  "Bearer from-env",
+		},
+		{
+			name:  "process env fallback",
+			env:   nil,
+			input: "Bearer {{LIGHTTR_TEST_TOKEN}}",
+			want:  "Bearer from-process",
+		},
+		{
+			name:        "unresolved placeholders are kept",
+			env:         env,
+			input:       "{{base_url}}/{{id}}/{{id}}/{{a}}",
+			want:        "https://api.example.com/{{id}}/{{id}}/{{a}}",
+			wantMissing: []string{"a", "id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, missing := tt.env.Resolve(tt.input)
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+			if len(missing) == 0 && len(tt.wantMissing) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("Resolve() missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	s := "{{base_url}}/users/{{ id }}?q={broken}"
+	want := []Placeholder{{Name: "base_url", Start: 0, End: 12}, {Name: "id", Start: 19, End: 27}}
+	if got := Placeholders(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("Placeholders() = %+v, want %+v", got, want)
+	}
+	if got := Placeholders("no placeholders"); got != nil {
+		t.Errorf("Expected no placeholders, got %+v", got)
+	}
+}
+
+func TestEnvironment_ResolveRequest(t *testing.T) {
+	env := &Environment{
+		Name: "test",
+		Variables: map[string]string{
+			"base_url": "https://api.example.com",
+			"token":    "secret",
+			"id":       "42",
+		},
+	}
+
+	req := request.NewRequestData()
+	req.URL = "{{base_url}}/users/{id}"
+	req.Headers["Authorization"] = "Bearer {{token}}"
+	req.QueryParams["page"] = "{{page}}"
+	req.PathParams["id"] = "{{id}}"
+	req.Body = `{"owner":"{{owner}}"}`
+	req.Proxy = &request.ProxySettings{URL: "http://proxy.corp:3128", Username: "svc", Password: "{{token}}"}
+	req.Auth = request.AuthData{Type: request.BearerAuth, Token: "{{token}}"}
+
+	missing := env.ResolveRequest(req)
+	if !reflect.DeepEqual(missing, []string{"owner", "page"}) {
+		t.Errorf("ResolveRequest() missing = %v, want [owner page]", missing)
+	}
+	if req.URL != "https://api.example.com/users/{id}" {
+		t.Errorf("Expected resolved URL, got %s", req.URL)
+	}
+	if req.Headers["Authorization"] != "Bearer secret" {
+		t.Errorf("Expected resolved header, got %s", req.Headers["Authorization"])
+	}
+	if req.PathParams["id"] != "42" {
+		t.Errorf("Expected resolved path param, got %s", req.PathParams["id"])
+	}
+	if req.Proxy.Password != "secret" {
+		t.Errorf("Expected resolved proxy password, got %s", req.Proxy.Password)
+	}
+	if req.Auth.Token != "secret" {
+		t.Errorf("Expected resolved bearer token, got %s", req.Auth.Token)
+	}
+
+	// The session login body's own placeholders are left to the login
+	req = request.NewRequestData()
+	req.Auth = request.AuthData{Type: request.SessionAuth, SessionLoginURL: "{{base_url}}/login", SessionLoginBody: `{"user": "{{username}}"}`}
+	if missing := env.ResolveRequest(req); len(missing) != 0 || req.Auth.SessionLoginURL != "https://api.example.com/login" || req.Auth.SessionLoginBody != `{"user": "{{username}}"}` {
+		t.Errorf("Expected the login URL only to be resolved, got %+v, missing %v", req.Auth, missing)
+	}
+}
+
+func TestEnvironment_WithOverrides(t *testing.T) {
+	env := &Environment{Name: "staging", Variables: map[string]string{"base_url": "https://staging.example.com", "token": "secret"}}
+	overridden := env.WithOverrides(map[string]string{"base_url": "http://localhost:8080"})
+	if overridden.Name != "staging" || overridden.Variables["base_url"] != "http://localhost:8080" || overridden.Variables["token"] != "secret" {
+		t.Errorf("Unexpected overridden environment: %+v", overridden)
+	}
+	if env.Variables["base_url"] != "https://staging.example.com" {
+		t.Errorf("Expected the environment to be left unchanged, got %+v", env)
+	}
+
+	var none *Environment
+	if value, _ := none.WithOverrides(map[string]string{"id": "7"}).Resolve("/users/{{id}}"); value != "/users/7" {
+		t.Errorf("Expected overrides without an environment to resolve, got %s", value)
+	}
+}
+
+func TestSave(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	env := &Environment{Name: "dev", Variables: map[string]string{"base_url": "http://localhost:8080"}}
+	if err := Save(env); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load("dev")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, env) {
+		t.Errorf("Load() = %+v, want %+v", loaded, env)
+	}
+}
+
+func TestList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if names, err := List(); err != nil || len(names) != 0 {
+		t.Errorf("Expected no environments, got %v, %v", names, err)
+	}
+	for _, name := range []string{"staging", "dev"} {
+		if err := Save(&Environment{Name: name}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if names, err := List(); err != nil || !reflect.DeepEqual(names, []string{"dev", "staging"}) {
+		t.Errorf("Expected the environments in order, got %v, %v", names, err)
+	}
+}
@@ -0,0 +1,392 @@
+// Package gcp obtains Google Cloud access and ID tokens with Application
+// Default Credentials, for requests with gcp auth
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// For testing
+var (
+	now            = time.Now
+	tokenClient    = &http.Client{Timeout: 30 * time.Second}
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// cloudPlatformScope is the scope of access tokens, which covers every
+// Google Cloud API
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// refreshSkew is how long before they expire tokens are replaced
+const refreshSkew = time.Minute
+
+// credentials is a credentials file of Application Default Credentials:
+// a service account key, or the user credentials gcloud auth
+// application-default login writes
+type credentials struct {
+	Type string `json:"type"`
+
+	// Service accounts
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	// Users
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+
+	// path is the file the credentials were read from
+	path string
+}
+
+// token is an access or ID token and when it expires
+type token struct {
+	value  string
+	expiry time.Time
+}
+
+var (
+	// mu guards cache, the tokens obtained so far by audience, "" for the
+	// access token
+	mu    sync.Mutex
+	cache = make(map[string]token)
+)
+
+// Middleware returns the provider of gcp auth, for request.RegisterAuth,
+// which sends a Google access token, or an ID token for the request's
+// audience, as a Bearer token. Tokens are reused until shortly before they
+// expire.
+func Middleware() request.Middleware {
+	return func(next request.Handler) request.Handler {
+		return func(x *request.Exchange) error {
+			value, err := Token(x.Request.Context(), x.Data.Auth.GCPAudience)
+			if err != nil {
+				return x.Abort(err)
+			}
+			x.Request.Header.Set("Authorization", "Bearer "+value)
+			return next(x)
+		}
+	}
+}
+
+// Token returns an ID token for the audience, such as the URL of a Cloud
+// Run service, or an access token when the audience is empty. It looks for
+// credentials where Application Default Credentials do: the file named by
+// GOOGLE_APPLICATION_CREDENTIALS, then the file of gcloud auth
+// application-default login, then the metadata server of the Google Cloud
+// resource lighttr runs on.
+func Token(ctx context.Context, audience string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cached, ok := cache[audience]; ok && now().Add(refreshSkew).Before(cached.expiry) {
+		return cached.value, nil
+	}
+
+	creds, err := findCredentials()
+	if err != nil {
+		return "", err
+	}
+	var t token
+	switch {
+	case creds == nil:
+		t, err = metadataToken(ctx, audience)
+	case creds.Type == "service_account":
+		t, err = creds.serviceAccountToken(ctx, audience)
+	case creds.Type == "authorized_user":
+		t, err = creds.userToken(ctx, audience)
+	default:
+		return "", fmt.Errorf("unsupported Google credentials type %q in %s: use a service account key or gcloud auth application-default login", creds.Type, creds.path)
+	}
+	if err != nil {
+		return "", err
+	}
+	cache[audience] = t
+	return t.value, nil
+}
+
+// findCredentials reads the credentials file of Application Default
+// Credentials, returning nil when there is none and the metadata server is
+// to be asked
+func findCredentials() (*credentials, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = wellKnownFile()
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google credentials: %v", err)
+	}
+	creds := &credentials{path: path}
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("failed to parse Google credentials in %s: %v", path, err)
+	}
+	return creds, nil
+}
+
+// wellKnownFile returns where gcloud auth application-default login saves
+// the user's credentials
+func wellKnownFile() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// tokenResponse is the body of a Google token endpoint response
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// serviceAccountToken signs an assertion with the service account's key
+// and exchanges it for a token
+func (c *credentials) serviceAccountToken(ctx context.Context, audience string) (token, error) {
+	key, err := parsePrivateKey(c.PrivateKey)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid private key in %s: %v", c.path, err)
+	}
+	tokenURL := c.TokenURI
+	if tokenURL == "" {
+		tokenURL = googleTokenURL
+	}
+
+	issued := now()
+	claims := map[string]interface{}{
+		"iss": c.ClientEmail,
+		"aud": tokenURL,
+		"iat": issued.Unix(),
+		"exp": issued.Add(time.Hour).Unix(),
+	}
+	if audience != "" {
+		claims["sub"] = c.ClientEmail
+		claims["target_audience"] = audience
+	} else {
+		claims["scope"] = cloudPlatformScope
+	}
+	assertion, err := signJWT(key, c.PrivateKeyID, claims)
+	if err != nil {
+		return token{}, err
+	}
+
+	resp, err := postToken(ctx, tokenURL, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return token{}, err
+	}
+	if audience != "" {
+		return idToken(resp.IDToken)
+	}
+	return accessToken(resp)
+}
+
+// userToken refreshes the user's token. Google issues users ID tokens for
+// the gcloud client only, which Cloud Run accepts, so the audience is not
+// asked for.
+func (c *credentials) userToken(ctx context.Context, audience string) (token, error) {
+	resp, err := postToken(ctx, googleTokenURL, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.RefreshToken},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+	})
+	if err != nil {
+		return token{}, err
+	}
+	if audience != "" {
+		return idToken(resp.IDToken)
+	}
+	return accessToken(resp)
+}
+
+// postToken posts a grant to a Google token endpoint
+func postToken(ctx context.Context, tokenURL string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google token URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Google token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("Google token request failed: %v", err)
+	}
+	parsed := &tokenResponse{}
+	if err := json.Unmarshal(body, parsed); err != nil {
+		return nil, fmt.Errorf("invalid Google token response (status %d): %v", resp.StatusCode, err)
+	}
+	if parsed.Error != "" {
+		message := parsed.Error
+		if parsed.ErrorDescription != "" {
+			message += ": " + parsed.ErrorDescription
+		}
+		return nil, fmt.Errorf("Google token request failed: %s", message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Google token request failed: status %d", resp.StatusCode)
+	}
+	return parsed, nil
+}
+
+func accessToken(resp *tokenResponse) (token, error) {
+	if resp.AccessToken == "" {
+		return token{}, errors.New("Google token response has no access token")
+	}
+	return token{value: resp.AccessToken, expiry: now().Add(time.Duration(resp.ExpiresIn) * time.Second)}, nil
+}
+
+func idToken(value string) (token, error) {
+	if value == "" {
+		return token{}, errors.New("Google token response has no ID token")
+	}
+	return token{value: value, expiry: jwtExpiry(value)}, nil
+}
+
+// metadataToken asks the metadata server of the Google Cloud resource
+// lighttr runs on for a token of its service account
+func metadataToken(ctx context.Context, audience string) (token, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	endpoint := "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/"
+	if audience != "" {
+		endpoint += "identity?" + url.Values{"audience": {audience}, "format": {"full"}}.Encode()
+	} else {
+		endpoint += "token"
+	}
+
+	// Off Google Cloud the metadata server does not resolve, or does not
+	// answer; do not wait long for it
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return token{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return token{}, fmt.Errorf("no Google credentials found: set GOOGLE_APPLICATION_CREDENTIALS to a service account key, run gcloud auth application-default login, or run on Google Cloud (metadata server: %v)", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return token{}, fmt.Errorf("metadata server request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return token{}, fmt.Errorf("metadata server responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if audience != "" {
+		return idToken(strings.TrimSpace(string(body)))
+	}
+	parsed := &tokenResponse{}
+	if err := json.Unmarshal(body, parsed); err != nil {
+		return token{}, fmt.Errorf("invalid metadata server token: %v", err)
+	}
+	return accessToken(parsed)
+}
+
+// parsePrivateKey reads the PEM RSA key of a service account
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// signJWT returns the claims as a JWT signed with RS256
+func signJWT(key *rsa.PrivateKey, keyID string, claims map[string]interface{}) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the assertion: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// jwtExpiry reads the expiry of a JWT without verifying it, returning the
+// zero time, so the token is not reused, when it has none
+func jwtExpiry(value string) time.Time {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,252 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// isolate clears the token cache and points Application Default
+// Credentials at an empty gcloud directory and an unreachable metadata
+// server
+func isolate(t *testing.T) {
+	mu.Lock()
+	cache = make(map[string]token)
+	mu.Unlock()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	t.Setenv("GCE_METADATA_HOST", "127.0.0.1:1")
+}
+
+// testJWT returns an unsigned JWT that expires at the given time
+func testJWT(exp time.Time) string {
+	payload, _ := json.Marshal(map[string]int64{"exp": exp.Unix()})
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+// writeServiceAccount writes a service account key whose tokens come from
+// tokenURL and returns its public key
+func writeServiceAccount(t *testing.T, tokenURL string) *rsa.PublicKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "runner@project.iam.gserviceaccount.com",
+		"private_key_id": "key1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURL,
+	})
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	return &key.PublicKey
+}
+
+// assertionClaims decodes the claims of a signed assertion
+func assertionClaims(t *testing.T, assertion string) map[string]interface{} {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT, got %q", assertion)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func TestToken_ServiceAccount(t *testing.T) {
+	isolate(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("Unexpected grant %v", r.Form)
+		}
+		claims := assertionClaims(t, r.Form.Get("assertion"))
+		if claims["iss"] != "runner@project.iam.gserviceaccount.com" || !strings.HasSuffix(claims["aud"].(string), "/token") {
+			t.Errorf("Unexpected assertion %v", claims)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if audience, ok := claims["target_audience"]; ok {
+			fmt.Fprintf(w, `{"id_token": %q}`, testJWT(time.Now().Add(time.Hour))+"-"+audience.(string))
+			return
+		}
+		if claims["scope"] != cloudPlatformScope {
+			t.Errorf("Expected the cloud-platform scope, got %v", claims["scope"])
+		}
+		io.WriteString(w, `{"access_token": "ya29.sa", "expires_in": 3599, "token_type": "Bearer"}`)
+	}))
+	defer server.Close()
+	writeServiceAccount(t, server.URL+"/token")
+
+	for range 2 {
+		value, err := Token(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+		if value != "ya29.sa" {
+			t.Errorf("Expected the access token, got %q", value)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the access token to be reused, got %d token requests", requests)
+	}
+
+	value, err := Token(context.Background(), "https://svc.a.run.app")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if !strings.HasSuffix(value, "-https://svc.a.run.app") {
+		t.Errorf("Expected an ID token for the audience, got %q", value)
+	}
+}
+
+func TestToken_AuthorizedUser(t *testing.T) {
+	isolate(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "1//rt" || r.Form.Get("client_id") != "gcloud" {
+			t.Errorf("Unexpected refresh request %v", r.Form)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "ya29.user", "expires_in": 3599, "id_token": %q}`, testJWT(time.Now().Add(time.Hour)))
+	}))
+	defer server.Close()
+	googleTokenURL = server.URL
+	defer func() { googleTokenURL = "https://oauth2.googleapis.com/token" }()
+
+	// gcloud auth application-default login writes the well-known file
+	data := `{"type": "authorized_user", "client_id": "gcloud", "client_secret": "s", "refresh_token": "1//rt"}`
+	if err := os.WriteFile(wellKnownFile(), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := Token(context.Background(), ""); err != nil || value != "ya29.user" {
+		t.Errorf("Expected the user's access token, got %q, %v", value, err)
+	}
+	if value, err := Token(context.Background(), "https://svc.a.run.app"); err != nil || !strings.HasPrefix(value, "e30.") {
+		t.Errorf("Expected the user's ID token, got %q, %v", value, err)
+	}
+}
+
+func TestToken_MetadataServer(t *testing.T) {
+	isolate(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			io.WriteString(w, `{"access_token": "ya29.vm", "expires_in": 3599}`)
+		case "/computeMetadata/v1/instance/service-accounts/default/identity":
+			if r.URL.Query().Get("audience") != "https://svc.a.run.app" {
+				t.Errorf("Unexpected identity request %s", r.URL)
+			}
+			io.WriteString(w, testJWT(time.Now().Add(time.Hour)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	if value, err := Token(context.Background(), ""); err != nil || value != "ya29.vm" {
+		t.Errorf("Expected the VM's access token, got %q, %v", value, err)
+	}
+	if value, err := Token(context.Background(), "https://svc.a.run.app"); err != nil || !strings.HasPrefix(value, "e30.") {
+		t.Errorf("Expected the VM's ID token, got %q, %v", value, err)
+	}
+}
+
+func TestToken_Errors(t *testing.T) {
+	isolate(t)
+
+	if _, err := Token(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "no Google credentials found") {
+		t.Errorf("Expected missing credentials to be reported, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "external.json")
+	os.WriteFile(path, []byte(`{"type": "external_account"}`), 0600)
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	if _, err := Token(context.Background(), ""); err == nil || !strings.Contains(err.Error(), `unsupported Google credentials type "external_account"`) {
+		t.Errorf("Expected an unsupported type to be reported, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	request.RegisterAuth(request.GCPAuth, Middleware())
+	t.Cleanup(func() { request.RegisterAuth(request.GCPAuth, nil) })
+	isolate(t)
+
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"access_token": "ya29.sa", "expires_in": 3599}`)
+	}))
+	defer tokens.Close()
+	writeServiceAccount(t, tokens.URL)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	req := request.NewRequestData()
+	req.URL = server.URL
+	req.Auth = request.AuthData{Type: request.GCPAuth}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if authorization != "Bearer ya29.sa" {
+		t.Errorf("Expected the access token to be sent, got %q", authorization)
+	}
+
+	// Other auth types pass through untouched
+	authorization = ""
+	req.Auth = request.AuthData{}
+	if _, err := req.Execute(); err != nil || authorization != "" {
+		t.Errorf("Expected no Authorization header without gcp auth, got %q, %v", authorization, err)
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	exp := time.Unix(1900000000, 0)
+	if got := jwtExpiry(testJWT(exp)); !got.Equal(exp) {
+		t.Errorf("jwtExpiry() = %v, want %v", got, exp)
+	}
+	if got := jwtExpiry("opaque"); !got.IsZero() {
+		t.Errorf("Expected no expiry for an opaque token, got %v", got)
+	}
+}
@@ -0,0 +1,426 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// ParseCurl builds a request from a curl command line
+func ParseCurl(command string) (*request.RequestData, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("not a curl command")
+	}
+
+	req := request.NewRequestData()
+	req.Method = ""
+	var data []string
+	get := false
+	ntlm, negotiate := false, false
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		// Support --flag=value as well as --flag value
+		name, inline, hasInline := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			name, inline, hasInline = strings.Cut(arg, "=")
+		}
+		value := func() (string, error) {
+			if hasInline {
+				return inline, nil
+			}
+			// Short flags may carry their value directly, e.g. -XPOST
+			if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+				return arg[2:], nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("missing value for %s", arg)
+			}
+			i++
+			return args[i], nil
+		}
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 && strings.HasPrefix(arg, "-") {
+			name = arg[:2]
+		}
+
+		switch name {
+		case "-X", "--request":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.Method = strings.ToUpper(v)
+		case "-H", "--header":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if key, val, ok := strings.Cut(v, ":"); ok {
+				req.Headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, v)
+		case "--data-urlencode":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if key, val, ok := strings.Cut(v, "="); ok {
+				data = append(data, key+"="+url.QueryEscape(val))
+			} else {
+				data = append(data, url.QueryEscape(v))
+			}
+		case "--json":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, v)
+			req.Headers["Content-Type"] = "application/json"
+			req.Headers["Accept"] = "application/json"
+		case "-u", "--user":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			username, password, _ := strings.Cut(v, ":")
+			req.Auth = request.AuthData{Type: request.BasicAuth, Username: username, Password: password}
+		case "--oauth2-bearer":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.Auth = request.AuthData{Type: request.BearerAuth, Token: v}
+		case "-A", "--user-agent":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.Headers["User-Agent"] = v
+		case "-e", "--referer":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.Headers["Referer"] = v
+		case "-b", "--cookie":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.Headers["Cookie"] = v
+		case "--url":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			req.URL = v
+		case "-G", "--get":
+			get = true
+		case "--ntlm":
+			ntlm = true
+		case "--negotiate":
+			negotiate = true
+		case "-I", "--head":
+			req.Method = "HEAD"
+		case "-o", "--output", "-w", "--write-out", "-m", "--max-time", "--connect-timeout", "--cacert", "--cert", "--cert-type", "--key", "-x", "--proxy":
+			// Flags with values that don't affect the request itself
+			if _, err := value(); err != nil {
+				return nil, err
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				// Ignore boolean flags such as -s, -v, -k, -L, --compressed
+				continue
+			}
+			if req.URL == "" {
+				req.URL = arg
+			}
+		}
+	}
+
+	if req.URL == "" {
+		return nil, fmt.Errorf("curl command has no URL")
+	}
+	if err := splitQuery(req); err != nil {
+		return nil, err
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case get && body != "":
+		values, err := url.ParseQuery(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query data: %v", err)
+		}
+		for key := range values {
+			req.QueryParams[key] = values.Get(key)
+		}
+	case body != "":
+		req.Body = body
+		if _, ok := req.Headers["Content-Type"]; !ok && !strings.HasPrefix(strings.TrimSpace(body), "{") {
+			req.Headers["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+	}
+
+	if req.Method == "" {
+		req.Method = "GET"
+		if req.Body != "" {
+			req.Method = "POST"
+		}
+	}
+
+	// With --ntlm the credentials of -u are for the NTLM handshake, and
+	// with --negotiate they are a placeholder, usually -u :, as the
+	// Kerberos ticket comes from kinit
+	switch {
+	case negotiate:
+		req.Auth = request.AuthData{Type: request.NegotiateAuth}
+	case ntlm && req.Auth.Type == request.BasicAuth:
+		req.Auth.Type = request.NTLMAuth
+	}
+
+	// Prefer the basic auth type over a raw Authorization header
+	if auth, ok := req.Headers["Authorization"]; ok && req.Auth.Type == request.NoAuth {
+		if encoded, ok := strings.CutPrefix(auth, "Basic "); ok {
+			if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				if username, password, ok := strings.Cut(string(decoded), ":"); ok {
+					req.Auth = request.AuthData{Type: request.BasicAuth, Username: username, Password: password}
+					delete(req.Headers, "Authorization")
+				}
+			}
+		}
+	}
+
+	return req, nil
+}
+
+// splitQuery moves the query string of the request URL into QueryParams
+func splitQuery(req *request.RequestData) error {
+	base, rawQuery, ok := strings.Cut(req.URL, "?")
+	if !ok {
+		return nil
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return fmt.Errorf("invalid query string: %v", err)
+	}
+	for key := range values {
+		req.QueryParams[key] = values.Get(key)
+	}
+	req.URL = base
+	return nil
+}
+
+// splitShellWords splits a command line into words following POSIX shell
+// quoting rules for single quotes, double quotes, and backslashes. Line
+// continuations are removed.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) && (s[i+1] == '\n' || s[i+1] == '\r') {
+				// Line continuation
+				i++
+				if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+				}
+				continue
+			}
+			if i+1 < len(s) {
+				i++
+				current.WriteByte(s[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			current.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+				}
+				current.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// CurlCommand renders a request as a curl command line, one option per
+// line, that ParseCurl reads back. Auth that lighttr obtains tokens for,
+// such as oauth2 and gcp, is sent as a Bearer token from $TOKEN.
+func CurlCommand(req *request.RequestData) string {
+	args := []string{"curl"}
+	add := func(option string, values ...string) {
+		words := []string{option}
+		for _, value := range values {
+			words = append(words, shellQuote(value))
+		}
+		args = append(args, strings.Join(words, " "))
+	}
+
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	if method != "GET" || req.Body != "" || req.BodyFile != "" || len(req.FormParams) > 0 || len(req.Multipart) > 0 {
+		add("-X", method)
+	}
+
+	target := req.ResolvedURL()
+	query := url.Values{}
+	for key, value := range req.QueryParams {
+		query.Set(key, value)
+	}
+	switch auth := req.Auth; auth.Type {
+	case request.BasicAuth:
+		add("-u", auth.Username+":"+auth.Password)
+	case request.NTLMAuth:
+		add("--ntlm")
+		add("-u", auth.Username+":"+auth.Password)
+	case request.NegotiateAuth:
+		add("--negotiate")
+		add("-u", ":")
+	case request.BearerAuth:
+		add("--oauth2-bearer", auth.Token)
+	case request.APIKeyAuth:
+		name, value, inQuery := auth.APIKeyParam()
+		if inQuery {
+			query.Set(name, value)
+		} else {
+			add("-H", name+": "+value)
+		}
+	case request.MutualTLSAuth:
+		cert := auth.CertFile
+		if auth.Password != "" {
+			cert += ":" + auth.Password
+		}
+		if ext := strings.ToLower(filepath.Ext(auth.CertFile)); auth.KeyFile == "" && (ext == ".p12" || ext == ".pfx") {
+			add("--cert-type", "P12")
+		}
+		add("--cert", cert)
+		if auth.KeyFile != "" {
+			add("--key", auth.KeyFile)
+		}
+	case request.OAuth2Auth, request.GCPAuth, request.AzureAuth, request.JWTAuth:
+		args = append(args, `--oauth2-bearer "$TOKEN"`)
+	}
+	if len(query) > 0 {
+		// * needs no escaping in a query, and masked values stay readable
+		target += "?" + strings.ReplaceAll(query.Encode(), "%2A", "*")
+	}
+
+	headers := req.EffectiveHeaders()
+	for _, name := range sortedHeaderNames(headers) {
+		add("-H", name+": "+headers[name])
+	}
+	switch {
+	case req.BodyFile != "":
+		add("--data-binary", "@"+req.BodyFile)
+	case len(req.Multipart) > 0:
+		for _, part := range req.Multipart {
+			spec := part.Name + "=" + part.Value
+			if part.File != "" {
+				spec = part.Name + "=@" + part.File
+			}
+			if part.ContentType != "" {
+				spec += ";type=" + part.ContentType
+			}
+			add("-F", spec)
+		}
+	case len(req.FormParams) > 0:
+		for _, name := range sortedHeaderNames(req.FormParams) {
+			add("--data-urlencode", name+"="+req.FormParams[name])
+		}
+	case req.Body != "":
+		add("--data-raw", req.Body)
+	}
+
+	if req.Auth.CAFile != "" {
+		add("--cacert", req.Auth.CAFile)
+	}
+	if req.Auth.InsecureSkipVerify {
+		add("-k")
+	}
+	if !req.DisableRedirects {
+		add("-L")
+	}
+	if req.Timeout > 0 {
+		add("-m", strconv.FormatFloat(req.Timeout.Seconds(), 'f', -1, 64))
+	}
+	if req.ConnectTimeout > 0 {
+		add("--connect-timeout", strconv.FormatFloat(req.ConnectTimeout.Seconds(), 'f', -1, 64))
+	}
+	if req.Proxy != nil && req.Proxy.URL != "" {
+		add("-x", req.Proxy.URL)
+	}
+	add(shellQuote(target))
+	return strings.Join(args, " \\\n  ") + "\n"
+}
+
+// shellQuote quotes a word for POSIX shells, leaving words that need no
+// quoting as they are
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@%+=,", r)
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ExportCurl writes saved requests as curl commands, each after a comment
+// with its name
+func ExportCurl(requests []collection.SavedRequest) string {
+	var b strings.Builder
+	for i, saved := range requests {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %s\n%s", saved.Name, CurlCommand(&saved.Request))
+	}
+	return b.String()
+}
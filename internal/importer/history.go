@@ -0,0 +1,38 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+)
+
+// zshHistoryPrefix matches the ": <time>:<duration>;" prefix of zsh's
+// extended history format
+var zshHistoryPrefix = regexp.MustCompile(`^: \d+:\d+;`)
+
+// ParseShellHistory collects the curl commands in a bash or zsh history
+// file as saved requests named curl-1, curl-2, and so on, newest last.
+// Commands that do not parse are skipped and repeated commands are kept
+// once.
+func ParseShellHistory(text string) []collection.SavedRequest {
+	// Commands continued over several lines end in a backslash
+	text = strings.ReplaceAll(text, "\\\n", " ")
+
+	seen := make(map[string]bool)
+	var requests []collection.SavedRequest
+	for _, line := range strings.Split(text, "\n") {
+		command := strings.TrimSpace(zshHistoryPrefix.ReplaceAllString(line, ""))
+		if !strings.HasPrefix(command, "curl ") || seen[command] {
+			continue
+		}
+		seen[command] = true
+		req, err := ParseCurl(command)
+		if err != nil || req.URL == "" {
+			continue
+		}
+		requests = append(requests, collection.SavedRequest{Name: fmt.Sprintf("curl-%d", len(requests)+1), Request: *req})
+	}
+	return requests
+}
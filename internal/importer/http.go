@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// httpMethods lists the methods recognized at the start of a raw HTTP block
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true,
+	"HEAD": true, "OPTIONS": true, "TRACE": true, "CONNECT": true,
+}
+
+// ParseHTTP builds a request from a raw HTTP message such as
+//
+//	POST /users HTTP/1.1
+//	Host: api.example.com
+//	Content-Type: application/json
+//
+//	{"name": "Jane"}
+//
+// Origin-form targets are combined with the Host header. The returned
+// boolean reports whether the scheme had to be guessed.
+func ParseHTTP(text string) (*request.RequestData, bool, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	head, body, _ := strings.Cut(strings.TrimLeft(text, "\n"), "\n\n")
+	lines := strings.Split(head, "\n")
+
+	fields := strings.Fields(lines[0])
+	if len(fields) < 2 || !httpMethods[strings.ToUpper(fields[0])] {
+		return nil, false, fmt.Errorf("not an HTTP request line: %q", lines[0])
+	}
+
+	req := request.NewRequestData()
+	req.Method = strings.ToUpper(fields[0])
+	target := fields[1]
+
+	host := ""
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if strings.EqualFold(key, "Host") {
+			host = value
+			continue
+		}
+		if strings.EqualFold(key, "Content-Length") {
+			// Recomputed when the request is sent
+			continue
+		}
+		req.Headers[key] = value
+	}
+
+	guessedScheme := false
+	switch {
+	case strings.Contains(target, "://"):
+		req.URL = target
+	case strings.HasPrefix(target, "/") && host != "":
+		req.URL = guessScheme(host) + "://" + host + target
+		guessedScheme = true
+	default:
+		req.URL = target
+	}
+
+	if err := splitQuery(req); err != nil {
+		return nil, false, err
+	}
+	req.Body = strings.TrimRight(body, "\n")
+	return req, guessedScheme, nil
+}
+
+// guessScheme picks http for local hosts and https otherwise
+func guessScheme(host string) string {
+	hostname := host
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		hostname = h
+	}
+	if hostname == "localhost" || hostname == "127.0.0.1" || hostname == "[::1]" || strings.HasSuffix(hostname, ".local") {
+		return "http"
+	}
+	return "https"
+}
@@ -0,0 +1,307 @@
+package importer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+var (
+	// fileVariablePattern matches "@name = value" definitions
+	fileVariablePattern = regexp.MustCompile(`^@([A-Za-z0-9_\-]+)\s*=\s*(.*)$`)
+
+	// namePattern matches "# @name login" and "// @name login" comments
+	namePattern = regexp.MustCompile(`^(?:#|//)\s*@name\s+(\S+)`)
+
+	// systemVariablePattern matches {{$guid}}, {{$randomInt 1 10}}, etc.
+	systemVariablePattern = regexp.MustCompile(`\{\{\s*\$(\w+)([^}]*)\}\}`)
+
+	// httpVersionPattern matches the optional protocol after the target
+	httpVersionPattern = regexp.MustCompile(`\s+HTTP/[0-9.]+$`)
+
+	// placeholderPattern matches {{var}} placeholders
+	placeholderPattern = regexp.MustCompile(`\{\{[^}]*\}\}`)
+)
+
+// ParseHTTPFile parses a .http/.rest file in the VS Code REST Client format:
+// requests separated by "###" lines, named by "### name" or "# @name name",
+// with "@var = value" file variables. File and system variables ($guid,
+// $timestamp, $datetime, $randomInt, $processEnv) are substituted; other
+// {{placeholders}}, including request variables such as
+// {{login.response.body.$.token}}, are left for the runner.
+func ParseHTTPFile(text string) ([]collection.SavedRequest, error) {
+	return ParseHTTPFileWith(text, nil)
+}
+
+// ParseHTTPFileWith parses a .http/.rest file like ParseHTTPFile, using the
+// given values in place of the file variables of the same names
+func ParseHTTPFileWith(text string, overrides map[string]string) ([]collection.SavedRequest, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	variables := maps.Clone(overrides)
+	if variables == nil {
+		variables = make(map[string]string)
+	}
+
+	var requests []collection.SavedRequest
+	for i, block := range splitHTTPFileBlocks(text) {
+		saved, ok, err := parseHTTPFileBlock(block.lines, variables, overrides)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %v", i+1, err)
+		}
+		if !ok {
+			continue
+		}
+		if saved.Name == "" {
+			saved.Name = block.name
+		}
+		if saved.Name == "" {
+			saved.Name = fmt.Sprintf("request-%d", len(requests)+1)
+		}
+		requests = append(requests, saved)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests found")
+	}
+	return requests, nil
+}
+
+// httpFileBlock is the text between "###" separators
+type httpFileBlock struct {
+	name  string
+	lines []string
+}
+
+// splitHTTPFileBlocks splits a file on "###" separator lines, taking any
+// text after the separator as the name of the following request
+func splitHTTPFileBlocks(text string) []httpFileBlock {
+	blocks := []httpFileBlock{{}}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "###") {
+			blocks = append(blocks, httpFileBlock{name: strings.TrimSpace(strings.TrimLeft(line, "#"))})
+			continue
+		}
+		current := &blocks[len(blocks)-1]
+		current.lines = append(current.lines, line)
+	}
+	return blocks
+}
+
+// parseHTTPFileBlock parses a single request. File variables defined in the
+// block are added to variables for the blocks that follow, unless they are
+// overridden. The boolean is false for blocks without a request line.
+func parseHTTPFileBlock(lines []string, variables, overrides map[string]string) (collection.SavedRequest, bool, error) {
+	var saved collection.SavedRequest
+	expand := func(s string) string {
+		return expandSystemVariables(expandFileVariables(s, variables))
+	}
+
+	// Skip comments, blank lines, and variable definitions before the request line
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if match := namePattern.FindStringSubmatch(line); match != nil {
+			saved.Name = match[1]
+			continue
+		}
+		if match := fileVariablePattern.FindStringSubmatch(line); match != nil {
+			if _, ok := overrides[match[1]]; !ok {
+				variables[match[1]] = expand(strings.TrimSpace(match[2]))
+			}
+			continue
+		}
+		if line == "" || isHTTPFileComment(line) {
+			continue
+		}
+		break
+	}
+	if i == len(lines) {
+		return saved, false, nil
+	}
+
+	req := request.NewRequestData()
+	requestLine := httpVersionPattern.ReplaceAllString(strings.TrimSpace(lines[i]), "")
+	if method, target, ok := strings.Cut(requestLine, " "); ok && httpMethods[strings.ToUpper(method)] {
+		req.Method = strings.ToUpper(method)
+		requestLine = strings.TrimSpace(target)
+	}
+	target := requestLine
+	i++
+
+	// Query string continuation lines start with ? or &
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "?") && !strings.HasPrefix(line, "&") {
+			break
+		}
+		target += line
+	}
+	req.URL = expand(target)
+	if err := splitQuery(req); err != nil {
+		return saved, false, err
+	}
+
+	// Headers run until the first blank line
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if isHTTPFileComment(line) {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return saved, false, fmt.Errorf("invalid header line: %q", line)
+		}
+		req.Headers[strings.TrimSpace(name)] = expand(strings.TrimSpace(value))
+	}
+
+	if i < len(lines) {
+		req.Body = expand(strings.TrimSpace(strings.Join(lines[i:], "\n")))
+	}
+	saved.Request = *req
+	return saved, true, nil
+}
+
+// isHTTPFileComment reports whether a line is a # or // comment
+func isHTTPFileComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
+
+// expandFileVariables substitutes {{name}} placeholders defined as file
+// variables, leaving other placeholders untouched
+func expandFileVariables(s string, variables map[string]string) string {
+	for name, value := range variables {
+		s = regexp.MustCompile(`\{\{\s*`+regexp.QuoteMeta(name)+`\s*\}\}`).ReplaceAllLiteralString(s, value)
+	}
+	return s
+}
+
+// expandSystemVariables substitutes the REST Client system variables that
+// do not depend on the editor
+func expandSystemVariables(s string) string {
+	return systemVariablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := systemVariablePattern.FindStringSubmatch(match)
+		args := strings.Fields(parts[2])
+		switch parts[1] {
+		case "guid":
+			return newUUID()
+		case "timestamp":
+			return strconv.FormatInt(time.Now().Unix(), 10)
+		case "datetime":
+			if len(args) > 0 && args[0] == "rfc1123" {
+				return time.Now().UTC().Format(time.RFC1123)
+			}
+			return time.Now().UTC().Format(time.RFC3339)
+		case "randomInt":
+			if len(args) == 2 {
+				low, errLow := strconv.Atoi(args[0])
+				high, errHigh := strconv.Atoi(args[1])
+				if errLow == nil && errHigh == nil && high > low {
+					n, _ := rand.Int(rand.Reader, big.NewInt(int64(high-low)))
+					return strconv.FormatInt(int64(low)+n.Int64(), 10)
+				}
+			}
+		case "processEnv":
+			if len(args) == 1 {
+				return os.Getenv(strings.TrimPrefix(args[0], "%"))
+			}
+		}
+		return match
+	})
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// ExportHTTPFile writes saved requests in the REST Client .http format
+func ExportHTTPFile(requests []collection.SavedRequest) string {
+	var b strings.Builder
+	for i, saved := range requests {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		req := saved.Request
+		fmt.Fprintf(&b, "### %s\n# @name %s\n", saved.Name, saved.Name)
+
+		// The format has no path templates, so substitute known values
+		target := req.ResolvedURL()
+		if len(req.QueryParams) > 0 {
+			query := url.Values{}
+			for key, value := range req.QueryParams {
+				query.Set(key, value)
+			}
+			target += "?" + query.Encode()
+		}
+		method := req.Method
+		if method == "" {
+			method = "GET"
+		}
+		fmt.Fprintf(&b, "%s %s\n", method, target)
+
+		for _, name := range sortedHeaderNames(req.Headers) {
+			fmt.Fprintf(&b, "%s: %s\n", name, req.Headers[name])
+		}
+		body := req.Body
+		if len(req.FormParams) > 0 {
+			if _, ok := req.Headers["Content-Type"]; !ok {
+				fmt.Fprintf(&b, "Content-Type: %s\n", request.FormContentType)
+			}
+			body = encodeFormBody(req.FormParams)
+		}
+		if body != "" {
+			fmt.Fprintf(&b, "\n%s\n", body)
+		}
+	}
+	return b.String()
+}
+
+// encodeFormBody encodes form params as a urlencoded body, leaving {{var}}
+// placeholders unescaped so they are still substituted
+func encodeFormBody(params map[string]string) string {
+	escape := func(s string) string {
+		var b strings.Builder
+		last := 0
+		for _, loc := range placeholderPattern.FindAllStringIndex(s, -1) {
+			b.WriteString(url.QueryEscape(s[last:loc[0]]))
+			b.WriteString(s[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		b.WriteString(url.QueryEscape(s[last:]))
+		return b.String()
+	}
+
+	pairs := make([]string, 0, len(params))
+	for _, name := range sortedHeaderNames(params) {
+		pairs = append(pairs, escape(name)+"="+escape(params[name]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sortedHeaderNames returns the header names in a stable order
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,554 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+var (
+	// hurlRequestPattern matches a request line such as "GET https://..."
+	hurlRequestPattern = regexp.MustCompile(`^([A-Z]+)\s+(\S.*)$`)
+
+	// hurlResponsePattern matches the status line of the expected response,
+	// "HTTP 200", "HTTP/1.1 404", or "HTTP *"
+	hurlResponsePattern = regexp.MustCompile(`^HTTP(?:/[0-9.]+)?\s+(\d{3}|\*)\s*$`)
+
+	// hurlSectionPattern matches a section header such as [Asserts]
+	hurlSectionPattern = regexp.MustCompile(`^\[([A-Za-z]+)\]$`)
+
+	// hurlKeyValuePattern matches "name: value" lines
+	hurlKeyValuePattern = regexp.MustCompile("^([A-Za-z0-9!#$%&'*+.^_`|~-]+)\\s*:\\s*(.*)$")
+
+	// hurlPredicates lists the supported assert predicates
+	hurlPredicates = map[string]bool{
+		"==": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true,
+		"contains": true, "startsWith": true, "endsWith": true, "matches": true, "exists": true,
+	}
+
+	// hurlQueries lists the supported queries and whether they take an argument
+	hurlQueries = map[string]bool{
+		"status": false, "header": true, "jsonpath": true, "xpath": true, "body": false, "duration": false,
+	}
+)
+
+// ParseHurl parses a Hurl file into saved requests named request-1,
+// request-2, and so on. Response status lines, implicit header and body
+// checks, and [Asserts] become assertions. [Captures] become request
+// variables, so {{token}} captured from request-1 is rewritten to
+// {{request-1.response.body.$.token}}. Other {{placeholders}} are left for
+// the environment.
+func ParseHurl(text string) ([]collection.SavedRequest, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	captures := make(map[string]string)
+
+	var requests []collection.SavedRequest
+	for i, entry := range splitHurlEntries(text) {
+		name := fmt.Sprintf("request-%d", i+1)
+		for j, line := range entry {
+			entry[j] = substituteCaptures(line, captures)
+		}
+		saved, err := parseHurlEntry(name, entry, captures)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		requests = append(requests, saved)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests found")
+	}
+	return requests, nil
+}
+
+// splitHurlEntries splits a file into entries, each starting at a request
+// line. Comments and text before the first request are dropped.
+func splitHurlEntries(text string) [][]string {
+	var entries [][]string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !inFence && line == trimmed && hurlRequestPattern.MatchString(line) && httpMethods[strings.Fields(line)[0]] {
+			entries = append(entries, []string{line})
+			continue
+		}
+		if len(entries) > 0 {
+			entries[len(entries)-1] = append(entries[len(entries)-1], line)
+		}
+	}
+	return entries
+}
+
+// substituteCaptures rewrites {{name}} placeholders for captured values
+func substituteCaptures(line string, captures map[string]string) string {
+	for name, reference := range captures {
+		pattern := regexp.MustCompile(`\{\{\s*` + regexp.QuoteMeta(name) + `\s*\}\}`)
+		line = pattern.ReplaceAllLiteralString(line, reference)
+	}
+	return line
+}
+
+// parseHurlEntry parses a request and its expected response
+func parseHurlEntry(name string, lines []string, captures map[string]string) (collection.SavedRequest, error) {
+	saved := collection.SavedRequest{Name: name}
+	req := request.NewRequestData()
+
+	match := hurlRequestPattern.FindStringSubmatch(lines[0])
+	req.Method = match[1]
+	req.URL = strings.TrimSpace(match[2])
+	if err := splitQuery(req); err != nil {
+		return saved, err
+	}
+
+	// The response starts at the HTTP status line
+	requestLines, responseLines := lines[1:], []string(nil)
+	for i, line := range requestLines {
+		if hurlResponsePattern.MatchString(strings.TrimSpace(line)) {
+			requestLines, responseLines = requestLines[:i], requestLines[i:]
+			break
+		}
+	}
+
+	if err := parseHurlRequest(req, requestLines); err != nil {
+		return saved, err
+	}
+	saved.Request = *req
+
+	if len(responseLines) > 0 {
+		asserts, err := parseHurlResponse(name, responseLines, captures)
+		if err != nil {
+			return saved, err
+		}
+		saved.Asserts = asserts
+	}
+	return saved, nil
+}
+
+// parseHurlRequest reads headers, sections, and the body of a request
+func parseHurlRequest(req *request.RequestData, lines []string) error {
+	section := ""
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if match := hurlSectionPattern.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			switch section {
+			case "QueryStringParams", "Query", "FormParams", "Form", "BasicAuth":
+			default:
+				return fmt.Errorf("unsupported section [%s]", section)
+			}
+			continue
+		}
+
+		key, value, ok := hurlKeyValue(line)
+		if !ok {
+			// Everything from here on is the body
+			body, err := hurlBody(lines[i:])
+			if err != nil {
+				return err
+			}
+			req.Body = body
+			break
+		}
+
+		switch section {
+		case "":
+			req.Headers[key] = value
+		case "QueryStringParams", "Query":
+			req.QueryParams[key] = value
+		case "FormParams", "Form":
+			if req.FormParams == nil {
+				req.FormParams = make(map[string]string)
+			}
+			req.FormParams[key] = value
+		case "BasicAuth":
+			req.Auth = request.AuthData{Type: request.BasicAuth, Username: key, Password: value}
+		}
+	}
+	return nil
+}
+
+// parseHurlResponse turns the expected response into assertions and records
+// its captures as request variables
+func parseHurlResponse(name string, lines []string, captures map[string]string) ([]assertion.Assertion, error) {
+	var asserts []assertion.Assertion
+
+	status := hurlResponsePattern.FindStringSubmatch(strings.TrimSpace(lines[0]))[1]
+	if status == "*" {
+		asserts = append(asserts, assertion.Assertion{Query: "status", Predicate: "exists"})
+	} else {
+		asserts = append(asserts, assertion.Assertion{Query: "status", Predicate: "==", Value: status})
+	}
+
+	section := ""
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if match := hurlSectionPattern.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			if section != "Asserts" && section != "Captures" {
+				return nil, fmt.Errorf("unsupported section [%s]", section)
+			}
+			continue
+		}
+
+		switch section {
+		case "Asserts":
+			assert, err := ParseAssertion(line)
+			if err != nil {
+				return nil, err
+			}
+			asserts = append(asserts, assert)
+
+		case "Captures":
+			variable, query, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid capture: %q", line)
+			}
+			reference, err := hurlCaptureReference(name, strings.TrimSpace(query))
+			if err != nil {
+				return nil, err
+			}
+			captures[strings.TrimSpace(variable)] = reference
+
+		default:
+			// Implicit checks: headers, then the expected body
+			if key, value, ok := hurlKeyValue(line); ok {
+				asserts = append(asserts, assertion.Assertion{Query: "header", Argument: key, Predicate: "==", Value: strconv.Quote(value)})
+				continue
+			}
+			body, err := hurlBody(lines[i:])
+			if err != nil {
+				return nil, err
+			}
+			if json.Valid([]byte(body)) {
+				asserts = append(asserts, assertion.Assertion{Query: "jsonpath", Argument: "$", Predicate: "==", Value: body})
+			} else {
+				asserts = append(asserts, assertion.Assertion{Query: "body", Predicate: "==", Value: strconv.Quote(body)})
+			}
+			return asserts, nil
+		}
+	}
+	return asserts, nil
+}
+
+// ParseAssertion parses an assertion in the Hurl syntax, such as
+// jsonpath "$.id" == 42
+func ParseAssertion(line string) (assertion.Assertion, error) {
+	var assert assertion.Assertion
+	query, rest, _ := strings.Cut(line, " ")
+	takesArgument, ok := hurlQueries[query]
+	if !ok {
+		return assert, fmt.Errorf("unsupported assert query %q", query)
+	}
+	assert.Query = query
+
+	rest = strings.TrimSpace(rest)
+	if takesArgument {
+		argument, remaining, err := hurlQuotedString(rest)
+		if err != nil {
+			return assert, fmt.Errorf("invalid assert %q: %v", line, err)
+		}
+		assert.Argument = argument
+		rest = strings.TrimSpace(remaining)
+	}
+
+	predicate, value, _ := strings.Cut(rest, " ")
+	if !hurlPredicates[predicate] {
+		return assert, fmt.Errorf("unsupported assert predicate %q", predicate)
+	}
+	assert.Predicate = predicate
+
+	value = strings.TrimSpace(value)
+	if predicate == "exists" {
+		return assert, nil
+	}
+	if strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") && len(value) > 1 {
+		// Regex literals become plain strings
+		value = strconv.Quote(strings.ReplaceAll(value[1:len(value)-1], `\/`, "/"))
+	}
+	if !json.Valid([]byte(value)) {
+		return assert, fmt.Errorf("unsupported assert value %q", value)
+	}
+	assert.Value = value
+	return assert, nil
+}
+
+// hurlCaptureReference converts a capture query into a request variable
+func hurlCaptureReference(name, query string) (string, error) {
+	kind, rest, _ := strings.Cut(query, " ")
+	argument, _, err := hurlQuotedString(strings.TrimSpace(rest))
+	switch {
+	case err != nil && kind != "body":
+		return "", fmt.Errorf("invalid capture %q: %v", query, err)
+	case kind == "jsonpath":
+		return fmt.Sprintf("{{%s.response.body.%s}}", name, argument), nil
+	case kind == "header":
+		return fmt.Sprintf("{{%s.response.headers.%s}}", name, argument), nil
+	case kind == "body":
+		return fmt.Sprintf("{{%s.response.body.*}}", name), nil
+	}
+	return "", fmt.Errorf("unsupported capture query %q", kind)
+}
+
+// hurlQuotedString reads a leading double-quoted string and returns it along
+// with the text that follows
+func hurlQuotedString(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, fmt.Errorf("expected a quoted string")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+	return "", s, fmt.Errorf("unterminated string")
+}
+
+// hurlKeyValue splits a "name: value" line
+func hurlKeyValue(line string) (string, string, bool) {
+	match := hurlKeyValuePattern.FindStringSubmatch(line)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], strings.TrimSpace(match[2]), true
+}
+
+// hurlBody reads a body: a ``` fenced block, a `oneline` string, or raw
+// JSON/XML text running to the end of the lines
+func hurlBody(lines []string) (string, error) {
+	first := strings.TrimSpace(lines[0])
+	if strings.HasPrefix(first, "```") {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "```" {
+				return strings.Join(lines[1:i], "\n"), nil
+			}
+		}
+		return "", fmt.Errorf("unterminated ``` body")
+	}
+	if len(first) > 1 && strings.HasPrefix(first, "`") && strings.HasSuffix(first, "`") {
+		return first[1 : len(first)-1], nil
+	}
+	if strings.HasPrefix(first, "{") || strings.HasPrefix(first, "[") || strings.HasPrefix(first, "<") {
+		return strings.TrimSpace(strings.Join(lines, "\n")), nil
+	}
+	return "", fmt.Errorf("unsupported body: %q", first)
+}
+
+// ExportHurl writes saved requests in the Hurl format. Request variables
+// that refer to earlier responses become [Captures] on those requests.
+func ExportHurl(requests []collection.SavedRequest) string {
+	captures := hurlCaptures(requests)
+
+	var b strings.Builder
+	for i, saved := range requests {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		req := saved.Request
+		rewrite := func(s string) string {
+			return referenceToCapture(s, captures)
+		}
+
+		fmt.Fprintf(&b, "# %s\n", saved.Name)
+		method := req.Method
+		if method == "" {
+			method = "GET"
+		}
+		fmt.Fprintf(&b, "%s %s\n", method, rewrite(req.ResolvedURL()))
+
+		for _, name := range sortedHeaderNames(req.Headers) {
+			fmt.Fprintf(&b, "%s: %s\n", name, rewrite(req.Headers[name]))
+		}
+		if req.Auth.Type == request.BearerAuth && req.Auth.Token != "" {
+			fmt.Fprintf(&b, "Authorization: Bearer %s\n", req.Auth.Token)
+		}
+		queryParams := req.QueryParams
+		if req.Auth.Type == request.APIKeyAuth && req.Auth.APIKey != "" {
+			name, value, query := req.Auth.APIKeyParam()
+			if query {
+				queryParams = maps.Clone(queryParams)
+				if queryParams == nil {
+					queryParams = make(map[string]string)
+				}
+				queryParams[name] = value
+			} else {
+				fmt.Fprintf(&b, "%s: %s\n", name, value)
+			}
+		}
+		if len(queryParams) > 0 {
+			b.WriteString("[QueryStringParams]\n")
+			for _, name := range sortedHeaderNames(queryParams) {
+				fmt.Fprintf(&b, "%s: %s\n", name, rewrite(queryParams[name]))
+			}
+		}
+		if len(req.FormParams) > 0 {
+			b.WriteString("[FormParams]\n")
+			for _, name := range sortedHeaderNames(req.FormParams) {
+				fmt.Fprintf(&b, "%s: %s\n", name, rewrite(req.FormParams[name]))
+			}
+		}
+		if req.Auth.Type == request.BasicAuth {
+			fmt.Fprintf(&b, "[BasicAuth]\n%s: %s\n", req.Auth.Username, req.Auth.Password)
+		}
+		if req.Body != "" {
+			body := rewrite(req.Body)
+			if json.Valid([]byte(body)) {
+				fmt.Fprintf(&b, "%s\n", body)
+			} else {
+				fmt.Fprintf(&b, "```\n%s\n```\n", body)
+			}
+		}
+
+		writeHurlResponse(&b, saved, captures)
+	}
+	return b.String()
+}
+
+// hurlCapture is a value captured from an earlier response
+type hurlCapture struct {
+	variable string
+	query    string
+}
+
+// hurlCaptures assigns a capture variable to every request variable used
+// in the requests, keyed by the placeholder it replaces
+func hurlCaptures(requests []collection.SavedRequest) map[string]map[string]hurlCapture {
+	captures := make(map[string]map[string]hurlCapture)
+	used := make(map[string]bool)
+	for _, saved := range requests {
+		req := saved.Request
+		fields := []string{req.URL, req.Body}
+		for _, values := range []map[string]string{req.Headers, req.QueryParams, req.FormParams} {
+			for _, value := range values {
+				fields = append(fields, value)
+			}
+		}
+		for _, field := range fields {
+			for _, match := range hurlReferencePattern.FindAllStringSubmatch(field, -1) {
+				name, source, path := match[1], match[2], match[3]
+				if captures[name] == nil {
+					captures[name] = make(map[string]hurlCapture)
+				}
+				if _, ok := captures[name][match[0]]; ok {
+					continue
+				}
+
+				query := fmt.Sprintf("header %q", path)
+				if source == "body" {
+					query = fmt.Sprintf("jsonpath %q", path)
+					if path == "*" {
+						query = "body"
+					}
+				}
+				variable := captureVariable(name, path, used)
+				captures[name][match[0]] = hurlCapture{variable: variable, query: query}
+			}
+		}
+	}
+	return captures
+}
+
+// hurlReferencePattern matches request variables such as
+// {{login.response.body.$.token}}
+var hurlReferencePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_\-]+)\.response\.(body|headers)\.([^}]*?)\s*\}\}`)
+
+// captureVariable derives a unique variable name such as login_token
+func captureVariable(name, path string, used map[string]bool) string {
+	fields := strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	base := strings.ReplaceAll(name, "-", "_")
+	if len(fields) > 0 {
+		base += "_" + strings.ToLower(fields[len(fields)-1])
+	} else {
+		base += "_body"
+	}
+
+	variable := base
+	for n := 2; used[variable]; n++ {
+		variable = fmt.Sprintf("%s_%d", base, n)
+	}
+	used[variable] = true
+	return variable
+}
+
+// referenceToCapture replaces request variables with their capture names
+func referenceToCapture(s string, captures map[string]map[string]hurlCapture) string {
+	return hurlReferencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := hurlReferencePattern.FindStringSubmatch(match)[1]
+		if capture, ok := captures[name][match]; ok {
+			return "{{" + capture.variable + "}}"
+		}
+		return match
+	})
+}
+
+// writeHurlResponse writes the status line, captures, and asserts of a
+// request, if it has any
+func writeHurlResponse(b *strings.Builder, saved collection.SavedRequest, captures map[string]map[string]hurlCapture) {
+	status := ""
+	var asserts []assertion.Assertion
+	for _, assert := range saved.Asserts {
+		switch {
+		case status == "" && assert.Query == "status" && assert.Predicate == "==":
+			status = assert.Value
+		case status == "" && assert.Query == "status" && assert.Predicate == "exists":
+			status = "*"
+		default:
+			asserts = append(asserts, assert)
+		}
+	}
+
+	requestCaptures := make([]hurlCapture, 0, len(captures[saved.Name]))
+	for _, capture := range captures[saved.Name] {
+		requestCaptures = append(requestCaptures, capture)
+	}
+	sort.Slice(requestCaptures, func(i, j int) bool {
+		return requestCaptures[i].variable < requestCaptures[j].variable
+	})
+
+	if status == "" && len(asserts) == 0 && len(requestCaptures) == 0 {
+		return
+	}
+	if status == "" {
+		// Without a status check lighttr fails error statuses; say so
+		// explicitly since Hurl would accept any status
+		status = "*"
+		asserts = append([]assertion.Assertion{{Query: "status", Predicate: "<", Value: "400"}}, asserts...)
+	}
+	fmt.Fprintf(b, "HTTP %s\n", status)
+	if len(requestCaptures) > 0 {
+		b.WriteString("[Captures]\n")
+		for _, capture := range requestCaptures {
+			fmt.Fprintf(b, "%s: %s\n", capture.variable, capture.query)
+		}
+	}
+	if len(asserts) > 0 {
+		b.WriteString("[Asserts]\n")
+		for _, assert := range asserts {
+			fmt.Fprintf(b, "%s\n", assert)
+		}
+	}
+}
@@ -0,0 +1,919 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		wantMethod  string
+		wantURL     string
+		wantHeaders map[string]string
+		wantQuery   map[string]string
+		wantBody    string
+		wantAuth    request.AuthData
+		wantErr     bool
+	}{
+		{
+			name: "post with headers and data",
+			command: `curl -X POST 'https://api.example.com/users?debug=1' \
+  -H 'Content-Type: application/json' \
+  -H "Authorization: Bearer abc" \
+  -d '{"name": "Jane"}'`,
+			wantMethod:  "POST",
+			wantURL:     "https://api.example.com/users",
+			wantHeaders: map[string]string{"Content-Type": "application/json", "Authorization": "Bearer abc"},
+			wantQuery:   map[string]string{"debug": "1"},
+			wantBody:    `{"name": "Jane"}`,
+			wantAuth:    request.AuthData{Type: request.NoAuth},
+		},
+		{
+			name:        "data implies post and form content type",
+			command:     `curl -s https://api.example.com/login --data "user=jane&pass=x"`,
+			wantMethod:  "POST",
+			wantURL:     "https://api.example.com/login",
+			wantHeaders: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			wantQuery:   map[string]string{},
+			wantBody:    "user=jane&pass=x",
+			wantAuth:    request.AuthData{Type: request.NoAuth},
+		},
+		{
+			name:        "get with data and basic auth",
+			command:     `curl -G -u jane:secret --data-urlencode "q=hello world" -XGET https://api.example.com/search`,
+			wantMethod:  "GET",
+			wantURL:     "https://api.example.com/search",
+			wantHeaders: map[string]string{},
+			wantQuery:   map[string]string{"q": "hello world"},
+			wantAuth:    request.AuthData{Type: request.BasicAuth, Username: "jane", Password: "secret"},
+		},
+		{
+			name:        "basic authorization header",
+			command:     `curl --url=https://api.example.com -H 'Authorization: Basic amFuZTpzZWNyZXQ='`,
+			wantMethod:  "GET",
+			wantURL:     "https://api.example.com",
+			wantHeaders: map[string]string{},
+			wantQuery:   map[string]string{},
+			wantAuth:    request.AuthData{Type: request.BasicAuth, Username: "jane", Password: "secret"},
+		},
+		{
+			name:        "oauth2 bearer token",
+			command:     `curl --oauth2-bearer abc123 https://api.example.com/me`,
+			wantMethod:  "GET",
+			wantURL:     "https://api.example.com/me",
+			wantHeaders: map[string]string{},
+			wantQuery:   map[string]string{},
+			wantAuth:    request.AuthData{Type: request.BearerAuth, Token: "abc123"},
+		},
+		{
+			name:        "ntlm auth",
+			command:     `curl --ntlm -u 'CORP\jane:secret' https://intranet.corp/ews/exchange.asmx`,
+			wantMethod:  "GET",
+			wantURL:     "https://intranet.corp/ews/exchange.asmx",
+			wantHeaders: map[string]string{},
+			wantQuery:   map[string]string{},
+			wantAuth:    request.AuthData{Type: request.NTLMAuth, Username: `CORP\jane`, Password: "secret"},
+		},
+		{
+			name:        "negotiate auth",
+			command:     `curl --negotiate -u : https://wiki.corp.example.com/api/pages`,
+			wantMethod:  "GET",
+			wantURL:     "https://wiki.corp.example.com/api/pages",
+			wantHeaders: map[string]string{},
+			wantQuery:   map[string]string{},
+			wantAuth:    request.AuthData{Type: request.NegotiateAuth},
+		},
+		{
+			name:    "missing url",
+			command: `curl -X POST`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			command: `curl 'https://api.example.com`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ParseCurl(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCurl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if req.Method != tt.wantMethod {
+				t.Errorf("Method = %s, want %s", req.Method, tt.wantMethod)
+			}
+			if req.URL != tt.wantURL {
+				t.Errorf("URL = %s, want %s", req.URL, tt.wantURL)
+			}
+			if !reflect.DeepEqual(req.Headers, tt.wantHeaders) {
+				t.Errorf("Headers = %v, want %v", req.Headers, tt.wantHeaders)
+			}
+			if !reflect.DeepEqual(req.QueryParams, tt.wantQuery) {
+				t.Errorf("QueryParams = %v, want %v", req.QueryParams, tt.wantQuery)
+			}
+			if req.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", req.Body, tt.wantBody)
+			}
+			if req.Auth != tt.wantAuth {
+				t.Errorf("Auth = %+v, want %+v", req.Auth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestParseHTTP(t *testing.T) {
+	text := "PUT /users/42?notify=true HTTP/1.1\r\nHost: api.example.com\r\nContent-Type: application/json\r\nContent-Length: 16\r\n\r\n{\"name\": \"Jane\"}\r\n"
+
+	req, guessed, err := ParseHTTP(text)
+	if err != nil {
+		t.Fatalf("ParseHTTP() error = %v", err)
+	}
+	if !guessed {
+		t.Error("Expected scheme to be guessed")
+	}
+	if req.Method != "PUT" || req.URL != "https://api.example.com/users/42" {
+		t.Errorf("Unexpected request line: %s %s", req.Method, req.URL)
+	}
+	if req.QueryParams["notify"] != "true" {
+		t.Errorf("Expected notify query param, got %v", req.QueryParams)
+	}
+	if _, ok := req.Headers["Content-Length"]; ok {
+		t.Error("Expected Content-Length header to be dropped")
+	}
+	if req.Body != `{"name": "Jane"}` {
+		t.Errorf("Body = %q", req.Body)
+	}
+
+	req, _, err = ParseHTTP("GET /health HTTP/1.1\nHost: localhost:8080\n")
+	if err != nil || req.URL != "http://localhost:8080/health" {
+		t.Errorf("Expected local host to use http, got %v (%v)", req, err)
+	}
+
+	if _, _, err := ParseHTTP("hello world"); err == nil {
+		t.Error("Expected error for non-HTTP text")
+	}
+}
+
+func TestParseText(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		wantFormat    string
+		wantAmbiguous []string
+		wantURL       string
+	}{
+		{
+			name:       "fenced curl with prompt",
+			text:       "To create a user:\n\n```bash\n$ curl -X POST https://api.example.com/users -d '{}'\n```\n",
+			wantFormat: FormatCurl,
+			wantURL:    "https://api.example.com/users",
+		},
+		{
+			name:          "http block",
+			text:          "```http\nGET /users HTTP/1.1\nHost: api.example.com\n```",
+			wantFormat:    FormatHTTP,
+			wantAmbiguous: []string{FieldScheme},
+			wantURL:       "https://api.example.com/users",
+		},
+		{
+			name:          "http block without host",
+			text:          "DELETE /users/1 HTTP/1.1",
+			wantFormat:    FormatHTTP,
+			wantAmbiguous: []string{FieldURL},
+			wantURL:       "/users/1",
+		},
+		{
+			name:          "fenced json",
+			text:          "Request body:\n```json\n{\"name\": \"Jane\"}\n```",
+			wantFormat:    FormatJSON,
+			wantAmbiguous: []string{FieldURL, FieldMethod},
+		},
+		{
+			name:          "bare url",
+			text:          "https://api.example.com/users?page=2\n",
+			wantFormat:    FormatURL,
+			wantAmbiguous: []string{FieldMethod},
+			wantURL:       "https://api.example.com/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseText(tt.text)
+			if err != nil {
+				t.Fatalf("ParseText() error = %v", err)
+			}
+			if result.Format != tt.wantFormat {
+				t.Errorf("Format = %s, want %s", result.Format, tt.wantFormat)
+			}
+			if !reflect.DeepEqual(result.Ambiguous, tt.wantAmbiguous) {
+				t.Errorf("Ambiguous = %v, want %v", result.Ambiguous, tt.wantAmbiguous)
+			}
+			if result.Request.URL != tt.wantURL {
+				t.Errorf("URL = %s, want %s", result.Request.URL, tt.wantURL)
+			}
+		})
+	}
+
+	if _, err := ParseText("just some prose"); err == nil {
+		t.Error("Expected error for unrecognized text")
+	}
+}
+
+func TestParseTextAll(t *testing.T) {
+	text := "Create a user:\n\n```bash\ncurl -X POST https://api.example.com/users -d @body.json\n```\n\n" +
+		"with body.json:\n\n```json\n{\"name\": \"Jane\"}\n```\n\nand again:\n\n```json\n{\"name\": \"Jane\"}\n```\n"
+	results, err := ParseTextAll(text)
+	if err != nil {
+		t.Fatalf("ParseTextAll() error = %v", err)
+	}
+	var formats []string
+	for _, result := range results {
+		formats = append(formats, result.Format)
+	}
+	if want := []string{FormatCurl, FormatJSON}; !reflect.DeepEqual(formats, want) {
+		t.Errorf("Formats = %v, want %v", formats, want)
+	}
+}
+
+func TestParseHTTPFile(t *testing.T) {
+	t.Setenv("LIGHTTR_TEST_TOKEN", "from-env")
+	text := `@host = api.example.com
+@base = https://{{host}}/v1
+
+### Log in
+# @name login
+POST {{base}}/login HTTP/1.1
+Content-Type: application/json
+
+{"user": "jane", "nonce": "{{$guid}}"}
+
+###
+
+// List orders with the login token
+GET {{base}}/orders
+    ?status=open
+    &limit=10
+Authorization: Bearer {{login.response.body.$.token}}
+X-Env-Token: {{$processEnv LIGHTTR_TEST_TOKEN}}
+X-Tenant: {{tenant}}
+
+### health
+https://{{host}}/health
+`
+
+	requests, err := ParseHTTPFile(text)
+	if err != nil {
+		t.Fatalf("ParseHTTPFile() error = %v", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+
+	login := requests[0]
+	if login.Name != "login" || login.Request.Method != "POST" || login.Request.URL != "https://api.example.com/v1/login" {
+		t.Errorf("Unexpected login request: %s %+v", login.Name, login.Request)
+	}
+	if login.Request.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Expected Content-Type header, got %v", login.Request.Headers)
+	}
+	if strings.Contains(login.Request.Body, "$guid") || !strings.HasPrefix(login.Request.Body, `{"user": "jane", "nonce": "`) {
+		t.Errorf("Expected $guid to be substituted, got %q", login.Request.Body)
+	}
+
+	orders := requests[1]
+	if orders.Name != "request-2" || orders.Request.Method != "GET" || orders.Request.URL != "https://api.example.com/v1/orders" {
+		t.Errorf("Unexpected orders request: %s %+v", orders.Name, orders.Request)
+	}
+	if !reflect.DeepEqual(orders.Request.QueryParams, map[string]string{"status": "open", "limit": "10"}) {
+		t.Errorf("Expected continued query string, got %v", orders.Request.QueryParams)
+	}
+	wantHeaders := map[string]string{
+		"Authorization": "Bearer {{login.response.body.$.token}}",
+		"X-Env-Token":   "from-env",
+		"X-Tenant":      "{{tenant}}",
+	}
+	if !reflect.DeepEqual(orders.Request.Headers, wantHeaders) {
+		t.Errorf("Headers = %v, want %v", orders.Request.Headers, wantHeaders)
+	}
+
+	health := requests[2]
+	if health.Name != "health" || health.Request.Method != "GET" || health.Request.URL != "https://api.example.com/health" {
+		t.Errorf("Unexpected health request: %s %+v", health.Name, health.Request)
+	}
+
+	if _, err := ParseHTTPFile("# just a comment\n"); err == nil {
+		t.Error("Expected error for a file without requests")
+	}
+
+	// Overrides replace file variables, including in the ones derived
+	// from them, and fill in other placeholders
+	requests, err = ParseHTTPFileWith(text, map[string]string{"host": "localhost:8080", "tenant": "acme"})
+	if err != nil {
+		t.Fatalf("ParseHTTPFileWith() error = %v", err)
+	}
+	if requests[0].Request.URL != "https://localhost:8080/v1/login" || requests[1].Request.Headers["X-Tenant"] != "acme" {
+		t.Errorf("Expected the overrides to apply, got %s and %v", requests[0].Request.URL, requests[1].Request.Headers)
+	}
+}
+
+func TestExportHTTPFile(t *testing.T) {
+	requests := []collection.SavedRequest{
+		{
+			Name: "create-user",
+			Request: request.RequestData{
+				Method:      "POST",
+				URL:         "https://api.example.com/orgs/{org}/users",
+				Headers:     map[string]string{"Content-Type": "application/json", "Accept": "application/json"},
+				QueryParams: map[string]string{"notify": "true"},
+				PathParams:  map[string]string{"org": "acme"},
+				Body:        `{"name": "Jane"}`,
+			},
+		},
+		{Name: "health", Request: request.RequestData{Method: "GET", URL: "https://api.example.com/health"}},
+	}
+
+	text := ExportHTTPFile(requests)
+	want := `### create-user
+# @name create-user
+POST https://api.example.com/orgs/acme/users?notify=true
+Accept: application/json
+Content-Type: application/json
+
+{"name": "Jane"}
+
+### health
+# @name health
+GET https://api.example.com/health
+`
+	if text != want {
+		t.Errorf("ExportHTTPFile() =\n%s\nwant\n%s", text, want)
+	}
+
+	// Exported files parse back into the same requests
+	parsed, err := ParseHTTPFile(text)
+	if err != nil {
+		t.Fatalf("ParseHTTPFile() error = %v", err)
+	}
+	if len(parsed) != 2 || parsed[0].Name != "create-user" || parsed[0].Request.Body != `{"name": "Jane"}` || parsed[0].Request.QueryParams["notify"] != "true" {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+}
+
+func TestCurlCommand(t *testing.T) {
+	req := &request.RequestData{
+		Method:      "POST",
+		URL:         "https://api.example.com/orgs/{org}/users",
+		Headers:     map[string]string{"Content-Type": "application/json"},
+		QueryParams: map[string]string{"notify": "true"},
+		PathParams:  map[string]string{"org": "acme"},
+		Body:        `{"name": "Jane's"}`,
+		Auth:        request.AuthData{Type: request.BasicAuth, Username: "jane", Password: "s3cret"},
+	}
+	want := `curl \
+  -X POST \
+  -u jane:s3cret \
+  -H 'Content-Type: application/json' \
+  --data-raw '{"name": "Jane'\''s"}' \
+  -L \
+  'https://api.example.com/orgs/acme/users?notify=true'
+`
+	if got := CurlCommand(req); got != want {
+		t.Errorf("CurlCommand() =\n%s\nwant\n%s", got, want)
+	}
+
+	// The command parses back into the same request
+	parsed, err := ParseCurl(strings.ReplaceAll(want, "\\\n", ""))
+	if err != nil {
+		t.Fatalf("ParseCurl() error = %v", err)
+	}
+	if parsed.Method != "POST" || parsed.URL != "https://api.example.com/orgs/acme/users" || parsed.QueryParams["notify"] != "true" ||
+		parsed.Body != req.Body || parsed.Auth.Username != "jane" || parsed.Auth.Password != "s3cret" {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+
+	tests := []struct {
+		name string
+		auth request.AuthData
+		want []string
+	}{
+		{name: "api key in query", auth: request.AuthData{Type: request.APIKeyAuth, APIKey: "k", APIKeyQuery: "api_key"}, want: []string{"?api_key=k"}},
+		{name: "api key header", auth: request.AuthData{Type: request.APIKeyAuth, APIKey: "k", APIKeyHeader: "X-API-Key"}, want: []string{"-H 'X-API-Key: k'"}},
+		{name: "PKCS#12 bundle", auth: request.AuthData{Type: request.MutualTLSAuth, CertFile: "client.p12", Password: "pw"}, want: []string{"--cert-type P12", "--cert client.p12:pw"}},
+		{name: "negotiate", auth: request.AuthData{Type: request.NegotiateAuth}, want: []string{"--negotiate", "-u :"}},
+		{name: "oauth2 profile", auth: request.AuthData{Type: request.OAuth2Auth, OAuth2Profile: "github"}, want: []string{`--oauth2-bearer "$TOKEN"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CurlCommand(&request.RequestData{Method: "GET", URL: "https://api.example.com", Auth: tt.auth})
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHurl(t *testing.T) {
+	text := "# Log in and fetch the profile\n" +
+		"POST https://api.example.com/login\n" +
+		"[FormParams]\n" +
+		"user: jane\n" +
+		"password: {{password}}\n" +
+		"HTTP 200\n" +
+		"[Captures]\n" +
+		"token: jsonpath \"$.token\"\n" +
+		"next: header \"Location\"\n" +
+		"\n" +
+		"GET https://api.example.com/me?verbose=1\n" +
+		"Authorization: Bearer {{token}}\n" +
+		"HTTP/1.1 200\n" +
+		"Content-Type: application/json\n" +
+		"[Asserts]\n" +
+		"jsonpath \"$.name\" == \"Jane\"\n" +
+		"jsonpath \"$.age\" >= 18\n" +
+		"header \"X-Trace\" exists\n" +
+		"body matches /\"id\":\\s*\\d+/\n" +
+		"\n" +
+		"PUT {{next}}\n" +
+		"```json\n" +
+		"{\"theme\": \"dark\"}\n" +
+		"```\n" +
+		"HTTP *\n"
+
+	requests, err := ParseHurl(text)
+	if err != nil {
+		t.Fatalf("ParseHurl() error = %v", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+
+	login := requests[0]
+	if login.Name != "request-1" || login.Request.Method != "POST" || login.Request.FormParams["user"] != "jane" || login.Request.FormParams["password"] != "{{password}}" {
+		t.Errorf("Unexpected login request: %+v", login.Request)
+	}
+	if len(login.Asserts) != 1 || login.Asserts[0].String() != "status == 200" {
+		t.Errorf("Unexpected login asserts: %v", login.Asserts)
+	}
+
+	me := requests[1]
+	if me.Request.Headers["Authorization"] != "Bearer {{request-1.response.body.$.token}}" {
+		t.Errorf("Expected capture to become a request variable, got %q", me.Request.Headers["Authorization"])
+	}
+	if me.Request.QueryParams["verbose"] != "1" || me.Request.URL != "https://api.example.com/me" {
+		t.Errorf("Expected query to be split from the URL, got %q %v", me.Request.URL, me.Request.QueryParams)
+	}
+	var asserts []string
+	for _, assert := range me.Asserts {
+		asserts = append(asserts, assert.String())
+	}
+	want := []string{
+		`status == 200`,
+		`header "Content-Type" == "application/json"`,
+		`jsonpath "$.name" == "Jane"`,
+		`jsonpath "$.age" >= 18`,
+		`header "X-Trace" exists`,
+		`body matches "\"id\":\\s*\\d+"`,
+	}
+	if strings.Join(asserts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected asserts:\n%s\nwant\n%s", strings.Join(asserts, "\n"), strings.Join(want, "\n"))
+	}
+
+	update := requests[2]
+	if update.Request.URL != "{{request-1.response.headers.Location}}" || update.Request.Body != `{"theme": "dark"}` {
+		t.Errorf("Unexpected update request: %+v", update.Request)
+	}
+	if len(update.Asserts) != 1 || update.Asserts[0].Predicate != "exists" {
+		t.Errorf("Expected HTTP * to accept any status, got %v", update.Asserts)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"GET https://api.example.com\nHTTP 200\n[Asserts]\ncookie \"session\" exists\n",
+		"GET https://api.example.com\nHTTP 200\n[Captures]\nid: regex \"id=(\\d+)\"\n",
+		"GET https://api.example.com\n[Options]\ninsecure: true\n",
+	} {
+		if _, err := ParseHurl(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestExportHurl(t *testing.T) {
+	requests := []collection.SavedRequest{
+		{
+			Name: "login",
+			Request: request.RequestData{
+				Method:  "POST",
+				URL:     "https://api.example.com/login",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"user": "jane"}`,
+			},
+			Asserts: []assertion.Assertion{{Query: "status", Predicate: "==", Value: "200"}},
+		},
+		{
+			Name: "me",
+			Request: request.RequestData{
+				Method:      "GET",
+				URL:         "https://api.example.com/users/{org}",
+				Headers:     map[string]string{"Authorization": "Bearer {{login.response.body.$.token}}"},
+				QueryParams: map[string]string{"id": "{{login.response.body.$.user.id}}"},
+				PathParams:  map[string]string{"org": "acme"},
+			},
+			Asserts: []assertion.Assertion{{Query: "jsonpath", Argument: "$.name", Predicate: "==", Value: `"Jane"`}},
+		},
+	}
+
+	text := ExportHurl(requests)
+	want := `# login
+POST https://api.example.com/login
+Content-Type: application/json
+{"user": "jane"}
+HTTP 200
+[Captures]
+login_id: jsonpath "$.user.id"
+login_token: jsonpath "$.token"
+
+# me
+GET https://api.example.com/users/acme
+Authorization: Bearer {{login_token}}
+[QueryStringParams]
+id: {{login_id}}
+HTTP *
+[Asserts]
+status < 400
+jsonpath "$.name" == "Jane"
+`
+	if text != want {
+		t.Errorf("ExportHurl() =\n%s\nwant\n%s", text, want)
+	}
+
+	// Exported files parse back into equivalent requests
+	parsed, err := ParseHurl(text)
+	if err != nil {
+		t.Fatalf("ParseHurl() error = %v", err)
+	}
+	if len(parsed) != 2 || parsed[1].Request.Headers["Authorization"] != "Bearer {{request-1.response.body.$.token}}" || len(parsed[1].Asserts) != 3 {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+}
+
+func TestExportHurl_APIKey(t *testing.T) {
+	requests := []collection.SavedRequest{
+		{Name: "header", Request: request.RequestData{Method: "GET", URL: "https://api.example.com/a",
+			Auth: request.AuthData{Type: request.APIKeyAuth, APIKey: "k1", APIKeyHeader: "X-API-Key"}}},
+		{Name: "query", Request: request.RequestData{Method: "GET", URL: "https://api.example.com/b",
+			QueryParams: map[string]string{"page": "2"},
+			Auth:        request.AuthData{Type: request.APIKeyAuth, APIKey: "k2", APIKeyQuery: "api_key"}}},
+	}
+	text := ExportHurl(requests)
+	if !strings.Contains(text, "GET https://api.example.com/a\nX-API-Key: k1\n") {
+		t.Errorf("Expected the key in its header, got\n%s", text)
+	}
+	if !strings.Contains(text, "[QueryStringParams]\napi_key: k2\npage: 2\n") {
+		t.Errorf("Expected the key in the query, got\n%s", text)
+	}
+	if len(requests[1].Request.QueryParams) != 1 {
+		t.Errorf("Expected the request's query to be left unchanged, got %v", requests[1].Request.QueryParams)
+	}
+}
+
+func TestExport_FormParams(t *testing.T) {
+	requests := []collection.SavedRequest{{
+		Name: "login",
+		Request: request.RequestData{
+			Method:     "POST",
+			URL:        "https://api.example.com/login",
+			FormParams: map[string]string{"user": "jane doe", "password": "{{password}}"},
+		},
+	}}
+
+	hurl := ExportHurl(requests)
+	if !strings.Contains(hurl, "[FormParams]\npassword: {{password}}\nuser: jane doe\n") {
+		t.Errorf("Expected a FormParams section, got:\n%s", hurl)
+	}
+	parsed, err := ParseHurl(hurl)
+	if err != nil {
+		t.Fatalf("ParseHurl() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed[0].Request.FormParams, requests[0].Request.FormParams) {
+		t.Errorf("Round trip mismatch: %v", parsed[0].Request.FormParams)
+	}
+
+	http := ExportHTTPFile(requests)
+	if !strings.Contains(http, "Content-Type: application/x-www-form-urlencoded\n\npassword={{password}}&user=jane+doe\n") {
+		t.Errorf("Expected an encoded form body, got:\n%s", http)
+	}
+}
+
+const testWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions name="Billing" targetNamespace="http://example.com/billing"
+    xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+    xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+    xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+    xmlns:xs="http://www.w3.org/2001/XMLSchema"
+    xmlns:tns="http://example.com/billing">
+  <wsdl:types>
+    <xs:schema targetNamespace="http://example.com/billing" elementFormDefault="qualified">
+      <xs:complexType name="Address">
+        <xs:sequence>
+          <xs:element name="Street" type="xs:string"/>
+          <xs:element name="City" type="xs:string"/>
+        </xs:sequence>
+      </xs:complexType>
+      <xs:complexType name="Customer">
+        <xs:sequence>
+          <xs:element name="Name" type="xs:string"/>
+          <xs:element name="Address" type="tns:Address" minOccurs="0"/>
+          <xs:element name="Referrer" type="tns:Customer" minOccurs="0"/>
+        </xs:sequence>
+      </xs:complexType>
+      <xs:element name="CreateInvoice">
+        <xs:complexType>
+          <xs:sequence>
+            <xs:element name="Customer" type="tns:Customer"/>
+            <xs:element name="Amount" type="xs:decimal"/>
+            <xs:element name="Line" type="xs:string" maxOccurs="unbounded"/>
+          </xs:sequence>
+        </xs:complexType>
+      </xs:element>
+      <xs:element name="Ping">
+        <xs:complexType/>
+      </xs:element>
+    </xs:schema>
+  </wsdl:types>
+  <wsdl:message name="CreateInvoiceRequest"><wsdl:part name="parameters" element="tns:CreateInvoice"/></wsdl:message>
+  <wsdl:message name="PingRequest"><wsdl:part name="parameters" element="tns:Ping"/></wsdl:message>
+  <wsdl:portType name="BillingPort">
+    <wsdl:operation name="CreateInvoice"><wsdl:input message="tns:CreateInvoiceRequest"/></wsdl:operation>
+    <wsdl:operation name="Ping"><wsdl:input message="tns:PingRequest"/></wsdl:operation>
+  </wsdl:portType>
+  <wsdl:binding name="BillingSoap12" type="tns:BillingPort">
+    <soap12:binding transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsdl:operation name="CreateInvoice"><soap12:operation soapAction="urn:CreateInvoice"/></wsdl:operation>
+    <wsdl:operation name="Ping"><soap12:operation soapAction="urn:Ping"/></wsdl:operation>
+  </wsdl:binding>
+  <wsdl:binding name="BillingSoap" type="tns:BillingPort">
+    <soap:binding transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsdl:operation name="CreateInvoice"><soap:operation soapAction="urn:CreateInvoice"/></wsdl:operation>
+    <wsdl:operation name="Ping"><soap:operation soapAction="urn:Ping"/></wsdl:operation>
+  </wsdl:binding>
+  <wsdl:service name="BillingService">
+    <wsdl:port name="BillingSoap12" binding="tns:BillingSoap12"><soap12:address location="https://billing.example.com/soap12"/></wsdl:port>
+    <wsdl:port name="BillingSoap" binding="tns:BillingSoap"><soap:address location="https://billing.example.com/soap"/></wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`
+
+func TestParseWSDL(t *testing.T) {
+	requests, err := ParseWSDL([]byte(testWSDL))
+	if err != nil {
+		t.Fatalf("ParseWSDL() error = %v", err)
+	}
+	if len(requests) != 2 || requests[0].Name != "CreateInvoice" || requests[1].Name != "Ping" {
+		t.Fatalf("Expected one request per operation, got %+v", requests)
+	}
+
+	// The SOAP 1.1 port is preferred
+	invoice := requests[0].Request
+	if invoice.Method != "POST" || invoice.URL != "https://billing.example.com/soap" {
+		t.Errorf("Unexpected target: %s %s", invoice.Method, invoice.URL)
+	}
+	if invoice.Headers["SOAPAction"] != `"urn:CreateInvoice"` || invoice.Headers["Content-Type"] != "text/xml; charset=utf-8" {
+		t.Errorf("Unexpected headers: %v", invoice.Headers)
+	}
+
+	want := `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <CreateInvoice xmlns="http://example.com/billing">
+      <Customer>
+        <Name>{{Name}}</Name>
+        <!-- optional -->
+        <Address>
+          <Street>{{Street}}</Street>
+          <City>{{City}}</City>
+        </Address>
+        <!-- optional -->
+        <Referrer>{{Referrer}}</Referrer>
+      </Customer>
+      <Amount>{{Amount}}</Amount>
+      <!-- repeatable -->
+      <Line>{{Line}}</Line>
+    </CreateInvoice>
+  </soap:Body>
+</soap:Envelope>`
+	if invoice.Body != want {
+		t.Errorf("Unexpected envelope:\n%s\nwant\n%s", invoice.Body, want)
+	}
+	if !strings.Contains(requests[1].Request.Body, `<Ping xmlns="http://example.com/billing"/>`) {
+		t.Errorf("Expected an empty Ping element, got %s", requests[1].Request.Body)
+	}
+
+	// Services with only a SOAP 1.2 port use its envelope and action
+	soap12Only := strings.Replace(testWSDL, `<wsdl:port name="BillingSoap" binding="tns:BillingSoap"><soap:address location="https://billing.example.com/soap"/></wsdl:port>`, "", 1)
+	requests, err = ParseWSDL([]byte(soap12Only))
+	if err != nil {
+		t.Fatalf("ParseWSDL() error = %v", err)
+	}
+	if got := requests[0].Request.Headers["Content-Type"]; got != `application/soap+xml; charset=utf-8; action="urn:CreateInvoice"` {
+		t.Errorf("Unexpected SOAP 1.2 Content-Type: %q", got)
+	}
+	if !strings.Contains(requests[0].Request.Body, "http://www.w3.org/2003/05/soap-envelope") {
+		t.Errorf("Expected a SOAP 1.2 envelope, got %s", requests[0].Request.Body)
+	}
+
+	for _, invalid := range []string{"not xml", `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"/>`} {
+		if _, err := ParseWSDL([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseWSDL_RPC(t *testing.T) {
+	wsdl := `<definitions targetNamespace="urn:weather" xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/" xmlns:tns="urn:weather" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <message name="GetForecast"><part name="city" type="xsd:string"/><part name="days" type="xsd:int"/></message>
+  <portType name="WeatherPort"><operation name="GetForecast"><input message="tns:GetForecast"/></operation></portType>
+  <binding name="WeatherBinding" type="tns:WeatherPort">
+    <soap:binding style="rpc" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetForecast"><soap:operation soapAction=""/></operation>
+  </binding>
+  <service name="Weather"><port name="WeatherPort" binding="tns:WeatherBinding"><soap:address location="http://weather.example.com/rpc"/></port></service>
+</definitions>`
+
+	requests, err := ParseWSDL([]byte(wsdl))
+	if err != nil {
+		t.Fatalf("ParseWSDL() error = %v", err)
+	}
+	body := requests[0].Request.Body
+	want := `    <tns:GetForecast xmlns:tns="urn:weather">
+      <city>{{city}}</city>
+      <days>{{days}}</days>
+    </tns:GetForecast>
+`
+	if !strings.Contains(body, want) {
+		t.Errorf("Expected RPC wrapper with parts, got\n%s", body)
+	}
+	if requests[0].Request.Headers["SOAPAction"] != `""` {
+		t.Errorf("Expected empty SOAPAction, got %q", requests[0].Request.Headers["SOAPAction"])
+	}
+}
+
+func TestParsePostman(t *testing.T) {
+	data := `{
+		"info": {"name": "API", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+		"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+		"item": [
+			{"name": "Users", "item": [
+				{"name": "Get user", "request": {
+					"method": "GET",
+					"url": {"raw": "{{base_url}}/users/:id?verbose=1", "variable": [{"key": "id", "value": "42"}]},
+					"header": [{"key": "Accept", "value": "application/json"}, {"key": "X-Debug", "value": "1", "disabled": true}]
+				}},
+				{"name": "Create user", "request": {
+					"method": "post",
+					"url": "{{base_url}}/users",
+					"body": {"mode": "raw", "raw": "{\"name\": \"Jane\"}"},
+					"auth": {"type": "basic", "basic": [{"key": "username", "value": "jane"}, {"key": "password", "value": "secret"}]}
+				}}
+			]},
+			{"name": "Login", "request": {
+				"method": "POST",
+				"url": "{{base_url}}/login",
+				"body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "jane"}, {"key": "skip", "value": "1", "disabled": true}]},
+				"auth": {"type": "apikey", "apikey": [{"key": "key", "value": "X-API-Key"}, {"key": "value", "value": "k"}, {"key": "in", "value": "header"}]}
+			}},
+			{"name": "Login", "request": {
+				"method": "POST",
+				"url": "{{base_url}}/upload",
+				"body": {"mode": "formdata", "formdata": [{"key": "note", "value": "hi", "type": "text"}, {"key": "file", "src": "/tmp/a.png", "type": "file"}]},
+				"auth": {"type": "ntlm", "ntlm": [{"key": "username", "value": "jane"}, {"key": "password", "value": "{{password}}"}, {"key": "domain", "value": "CORP"}]}
+			}}
+		]
+	}`
+	requests, format, err := ParseExport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseExport() error = %v", err)
+	}
+	if format != "Postman" || len(requests) != 4 {
+		t.Fatalf("Expected 4 Postman requests, got %s with %d", format, len(requests))
+	}
+
+	get := requests[0]
+	if get.Name != "Get user" || get.Request.URL != "{{base_url}}/users/{id}" || get.Request.PathParams["id"] != "42" || get.Request.QueryParams["verbose"] != "1" {
+		t.Errorf("Unexpected URL conversion: %+v", get.Request)
+	}
+	if get.Request.Headers["Accept"] != "application/json" || get.Request.DisabledHeaders["X-Debug"] != "1" {
+		t.Errorf("Unexpected headers: %v, disabled %v", get.Request.Headers, get.Request.DisabledHeaders)
+	}
+	if get.Request.Auth.Type != request.BearerAuth || get.Request.Auth.Token != "{{token}}" {
+		t.Errorf("Expected the collection's bearer auth to be inherited, got %+v", get.Request.Auth)
+	}
+
+	create := requests[1].Request
+	if create.Method != "POST" || create.Body != `{"name": "Jane"}` || create.Auth.Type != request.BasicAuth || create.Auth.Password != "secret" {
+		t.Errorf("Unexpected request: %+v", create)
+	}
+
+	login := requests[2].Request
+	wantAuth := request.AuthData{Type: request.APIKeyAuth, APIKey: "k", APIKeyHeader: "X-API-Key"}
+	if !reflect.DeepEqual(login.FormParams, map[string]string{"user": "jane"}) || login.Auth != wantAuth {
+		t.Errorf("Unexpected form or API key: %v, %+v", login.FormParams, login.Auth)
+	}
+
+	upload := requests[3]
+	wantParts := []request.FormPart{{Name: "note", Value: "hi"}, {Name: "file", File: "/tmp/a.png"}}
+	if upload.Name != "Login-2" || !reflect.DeepEqual(upload.Request.Multipart, wantParts) {
+		t.Errorf("Unexpected upload: %s %+v", upload.Name, upload.Request.Multipart)
+	}
+	if upload.Request.Auth != (request.AuthData{Type: request.NTLMAuth, Username: `CORP\jane`, Password: "{{password}}"}) {
+		t.Errorf("Expected NTLM auth with the domain, got %+v", upload.Request.Auth)
+	}
+}
+
+func TestParseInsomnia(t *testing.T) {
+	data := `{
+		"_type": "export", "__export_format": 4,
+		"resources": [
+			{"_type": "workspace", "name": "API"},
+			{"_type": "request", "name": "Create", "method": "POST", "url": "{{ _.base_url }}/items?draft=true",
+			 "headers": [{"name": "X-Trace", "value": "{{ _.trace }}"}],
+			 "body": {"mimeType": "application/json", "text": "{\"id\": 1}"},
+			 "authentication": {"type": "bearer", "token": "{{ _.token }}"}},
+			{"_type": "request", "name": "Login", "method": "POST", "url": "https://api.example.com/login",
+			 "body": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "jane"}]},
+			 "authentication": {"type": "basic", "username": "jane", "password": "pw", "disabled": true}},
+			{"_type": "request", "name": "Search", "method": "GET", "url": "https://api.example.com/search",
+			 "authentication": {"type": "apikey", "key": "api_key", "value": "{{ _.key }}", "addTo": "queryParams"}}
+		]
+	}`
+	requests, format, err := ParseExport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseExport() error = %v", err)
+	}
+	if format != "Insomnia" || len(requests) != 3 {
+		t.Fatalf("Expected 3 Insomnia requests, got %s with %d", format, len(requests))
+	}
+
+	create := requests[0].Request
+	if create.URL != "{{base_url}}/items" || create.QueryParams["draft"] != "true" || create.Headers["X-Trace"] != "{{trace}}" {
+		t.Errorf("Expected placeholders to be converted, got %+v", create)
+	}
+	if create.Body != `{"id": 1}` || create.Headers["Content-Type"] != "application/json" || create.Auth.Type != request.BearerAuth || create.Auth.Token != "{{token}}" {
+		t.Errorf("Unexpected body or auth: %+v", create)
+	}
+
+	login := requests[1].Request
+	if login.FormParams["user"] != "jane" || login.Auth.Type != request.NoAuth {
+		t.Errorf("Expected form params and no disabled auth, got %+v", login)
+	}
+
+	search := requests[2].Request
+	if search.Auth != (request.AuthData{Type: request.APIKeyAuth, APIKey: "{{key}}", APIKeyQuery: "api_key"}) {
+		t.Errorf("Expected an API key sent in the query, got %+v", search.Auth)
+	}
+
+	if _, _, err := ParseExport([]byte(`{"name": "other"}`)); err == nil {
+		t.Error("Expected an error for JSON that is not an export")
+	}
+}
+
+func TestParseShellHistory(t *testing.T) {
+	text := "ls -la\n" +
+		": 1700000000:0;curl -X POST https://api.example.com/items -d '{\"a\":1}'\n" +
+		"curl https://api.example.com/health\n" +
+		"curl https://api.example.com/health\n" +
+		"curl -H 'Accept: application/json' \\\n  https://api.example.com/users\n" +
+		"curl 'unterminated\n" +
+		"curly braces\n"
+	requests := ParseShellHistory(text)
+
+	var got []string
+	for _, saved := range requests {
+		got = append(got, saved.Name+" "+saved.Request.Method+" "+saved.Request.URL)
+	}
+	want := []string{
+		"curl-1 POST https://api.example.com/items",
+		"curl-2 GET https://api.example.com/health",
+		"curl-3 GET https://api.example.com/users",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseShellHistory() = %v, want %v", got, want)
+	}
+	if requests[2].Request.Headers["Accept"] != "application/json" {
+		t.Errorf("Expected continued lines to be joined, got %+v", requests[2].Request)
+	}
+}
@@ -0,0 +1,379 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// insomniaVariablePattern matches Insomnia's {{ _.name }} placeholders
+var insomniaVariablePattern = regexp.MustCompile(`\{\{\s*_\.([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// ParseExport parses a Postman collection (v2.0 or v2.1) or an Insomnia
+// export (v4), telling them apart by their contents. It returns the
+// requests and the name of the format.
+func ParseExport(data []byte) ([]collection.SavedRequest, string, error) {
+	var probe struct {
+		Info *struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, "", fmt.Errorf("invalid JSON: %v", err)
+	}
+	switch {
+	case probe.Info != nil && strings.Contains(probe.Info.Schema, "getpostman.com"):
+		requests, err := ParsePostman(data)
+		return requests, "Postman", err
+	case probe.Type == "export":
+		requests, err := ParseInsomnia(data)
+		return requests, "Insomnia", err
+	}
+	return nil, "", fmt.Errorf("not a Postman collection or Insomnia export")
+}
+
+// postmanItem is a request or a folder of items in a Postman collection
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+}
+
+// postmanRequest is the request of a Postman item
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    json.RawMessage   `json:"url"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanKeyValue `json:"urlencoded"`
+		FormData   []postmanKeyValue `json:"formdata"`
+	} `json:"body"`
+	Auth *postmanAuth `json:"auth"`
+}
+
+// postmanKeyValue is a header, form field, or variable
+type postmanKeyValue struct {
+	Key      string          `json:"key"`
+	Value    string          `json:"value"`
+	Disabled bool            `json:"disabled"`
+	Type     string          `json:"type"`
+	Src      json.RawMessage `json:"src"`
+}
+
+// postmanAuth holds the settings of the selected auth type, as a list of
+// key/value pairs (v2.1) or an object (v2.0)
+type postmanAuth struct {
+	Type   string          `json:"type"`
+	Basic  json.RawMessage `json:"basic"`
+	Bearer json.RawMessage `json:"bearer"`
+	APIKey json.RawMessage `json:"apikey"`
+	NTLM   json.RawMessage `json:"ntlm"`
+}
+
+// ParsePostman parses a Postman collection. Requests in folders are
+// flattened in order; auth set on a folder or the collection applies to the
+// requests in it that do not set their own. Postman's {{variables}} are
+// kept for an environment and :name path variables become {name}.
+func ParsePostman(data []byte) ([]collection.SavedRequest, error) {
+	var root postmanItem
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid Postman collection: %v", err)
+	}
+	names := make(map[string]int)
+	var requests []collection.SavedRequest
+	var walk func(items []postmanItem, auth *postmanAuth) error
+	walk = func(items []postmanItem, auth *postmanAuth) error {
+		for _, item := range items {
+			inherited := auth
+			if item.Auth != nil {
+				inherited = item.Auth
+			}
+			if item.Request == nil {
+				if err := walk(item.Item, inherited); err != nil {
+					return err
+				}
+				continue
+			}
+			req, err := item.Request.toRequestData(inherited)
+			if err != nil {
+				return fmt.Errorf("%s: %v", item.Name, err)
+			}
+			requests = append(requests, collection.SavedRequest{Name: uniqueName(names, item.Name), Request: *req})
+		}
+		return nil
+	}
+	if err := walk(root.Item, root.Auth); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// toRequestData converts a Postman request, applying auth when the request
+// does not set its own
+func (p *postmanRequest) toRequestData(auth *postmanAuth) (*request.RequestData, error) {
+	req := request.NewRequestData()
+	if p.Method != "" {
+		req.Method = strings.ToUpper(p.Method)
+	}
+
+	// The URL is a string or an object with the raw URL and path variables
+	var raw string
+	if err := json.Unmarshal(p.URL, &raw); err != nil {
+		var u struct {
+			Raw      string            `json:"raw"`
+			Variable []postmanKeyValue `json:"variable"`
+		}
+		if err := json.Unmarshal(p.URL, &u); err != nil {
+			return nil, fmt.Errorf("invalid url: %v", err)
+		}
+		raw = u.Raw
+		for _, v := range u.Variable {
+			raw = strings.Replace(raw, "/:"+v.Key, "/{"+v.Key+"}", 1)
+			req.PathParams[v.Key] = v.Value
+		}
+	}
+	req.URL = raw
+	if err := splitQuery(req); err != nil {
+		return nil, err
+	}
+
+	for _, h := range p.Header {
+		if h.Disabled {
+			disableHeader(req, h.Key, h.Value)
+			continue
+		}
+		req.Headers[h.Key] = h.Value
+	}
+
+	if p.Body != nil {
+		switch p.Body.Mode {
+		case "raw":
+			req.Body = p.Body.Raw
+		case "urlencoded":
+			req.FormParams = make(map[string]string)
+			for _, field := range p.Body.URLEncoded {
+				if !field.Disabled {
+					req.FormParams[field.Key] = field.Value
+				}
+			}
+		case "formdata":
+			for _, field := range p.Body.FormData {
+				if field.Disabled {
+					continue
+				}
+				part := request.FormPart{Name: field.Key, Value: field.Value}
+				if field.Type == "file" {
+					var src string
+					json.Unmarshal(field.Src, &src)
+					part = request.FormPart{Name: field.Key, File: src}
+				}
+				req.Multipart = append(req.Multipart, part)
+			}
+		}
+	}
+
+	if p.Auth != nil {
+		auth = p.Auth
+	}
+	if auth != nil {
+		auth.apply(req)
+	}
+	return req, nil
+}
+
+// apply sets the request's credentials from basic, bearer, API key, and
+// NTLM auth; other types are left for the user to set up
+func (a *postmanAuth) apply(req *request.RequestData) {
+	switch a.Type {
+	case "basic":
+		values := postmanAuthValues(a.Basic)
+		req.Auth = request.AuthData{Type: request.BasicAuth, Username: values["username"], Password: values["password"]}
+	case "bearer":
+		req.Auth = request.AuthData{Type: request.BearerAuth, Token: postmanAuthValues(a.Bearer)["token"]}
+	case "ntlm":
+		values := postmanAuthValues(a.NTLM)
+		username := values["username"]
+		if values["domain"] != "" {
+			username = values["domain"] + `\` + username
+		}
+		req.Auth = request.AuthData{Type: request.NTLMAuth, Username: username, Password: values["password"]}
+	case "apikey":
+		values := postmanAuthValues(a.APIKey)
+		req.Auth = request.AuthData{Type: request.APIKeyAuth, APIKey: values["value"]}
+		if values["in"] == "query" {
+			req.Auth.APIKeyQuery = values["key"]
+		} else {
+			req.Auth.APIKeyHeader = values["key"]
+		}
+	}
+}
+
+// postmanAuthValues reads auth settings in either format
+func postmanAuthValues(data json.RawMessage) map[string]string {
+	values := make(map[string]string)
+	var list []postmanKeyValue
+	if err := json.Unmarshal(data, &list); err == nil {
+		for _, kv := range list {
+			values[kv.Key] = kv.Value
+		}
+		return values
+	}
+	var object map[string]any
+	json.Unmarshal(data, &object)
+	for k, v := range object {
+		values[k] = fmt.Sprint(v)
+	}
+	return values
+}
+
+// insomniaResource is an entry in an Insomnia export; only requests are
+// imported
+type insomniaResource struct {
+	Type    string `json:"_type"`
+	Name    string `json:"name"`
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	Headers []struct {
+		Name     string `json:"name"`
+		Value    string `json:"value"`
+		Disabled bool   `json:"disabled"`
+	} `json:"headers"`
+	Body struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Params   []struct {
+			Name     string `json:"name"`
+			Value    string `json:"value"`
+			Type     string `json:"type"`
+			FileName string `json:"fileName"`
+			Disabled bool   `json:"disabled"`
+		} `json:"params"`
+	} `json:"body"`
+	Authentication struct {
+		Type     string `json:"type"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Token    string `json:"token"`
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		AddTo    string `json:"addTo"`
+		Disabled bool   `json:"disabled"`
+	} `json:"authentication"`
+}
+
+// ParseInsomnia parses an Insomnia export. Its {{ _.name }} placeholders
+// become {{name}}.
+func ParseInsomnia(data []byte) ([]collection.SavedRequest, error) {
+	var export struct {
+		Resources []insomniaResource `json:"resources"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid Insomnia export: %v", err)
+	}
+
+	names := make(map[string]int)
+	var requests []collection.SavedRequest
+	for _, r := range export.Resources {
+		if r.Type != "request" {
+			continue
+		}
+		req := request.NewRequestData()
+		if r.Method != "" {
+			req.Method = strings.ToUpper(r.Method)
+		}
+		req.URL = insomniaVariables(r.URL)
+		if err := splitQuery(req); err != nil {
+			return nil, fmt.Errorf("%s: %v", r.Name, err)
+		}
+		for _, h := range r.Headers {
+			if h.Disabled {
+				disableHeader(req, h.Name, insomniaVariables(h.Value))
+				continue
+			}
+			req.Headers[h.Name] = insomniaVariables(h.Value)
+		}
+
+		switch r.Body.MimeType {
+		case "application/x-www-form-urlencoded":
+			req.FormParams = make(map[string]string)
+			for _, p := range r.Body.Params {
+				if !p.Disabled {
+					req.FormParams[p.Name] = insomniaVariables(p.Value)
+				}
+			}
+		case "multipart/form-data":
+			for _, p := range r.Body.Params {
+				if p.Disabled {
+					continue
+				}
+				if p.Type == "file" {
+					req.Multipart = append(req.Multipart, request.FormPart{Name: p.Name, File: p.FileName})
+				} else {
+					req.Multipart = append(req.Multipart, request.FormPart{Name: p.Name, Value: insomniaVariables(p.Value)})
+				}
+			}
+		default:
+			req.Body = insomniaVariables(r.Body.Text)
+		}
+		if req.Headers["Content-Type"] == "" && r.Body.MimeType != "" && req.Body != "" {
+			req.Headers["Content-Type"] = r.Body.MimeType
+		}
+
+		auth := r.Authentication
+		switch {
+		case auth.Disabled:
+		case auth.Type == "basic":
+			req.Auth = request.AuthData{Type: request.BasicAuth, Username: insomniaVariables(auth.Username), Password: insomniaVariables(auth.Password)}
+		case auth.Type == "ntlm":
+			req.Auth = request.AuthData{Type: request.NTLMAuth, Username: insomniaVariables(auth.Username), Password: insomniaVariables(auth.Password)}
+		case auth.Type == "bearer":
+			req.Auth = request.AuthData{Type: request.BearerAuth, Token: insomniaVariables(auth.Token)}
+		case auth.Type == "apikey":
+			req.Auth = request.AuthData{Type: request.APIKeyAuth, APIKey: insomniaVariables(auth.Value)}
+			if auth.AddTo == "queryParams" {
+				req.Auth.APIKeyQuery = auth.Key
+			} else {
+				req.Auth.APIKeyHeader = auth.Key
+			}
+		}
+
+		requests = append(requests, collection.SavedRequest{Name: uniqueName(names, r.Name), Request: *req})
+	}
+	return requests, nil
+}
+
+// insomniaVariables rewrites {{ _.name }} placeholders as {{name}}
+func insomniaVariables(s string) string {
+	return insomniaVariablePattern.ReplaceAllString(s, "{{$1}}")
+}
+
+// disableHeader keeps a header that the export had turned off
+func disableHeader(req *request.RequestData, name, value string) {
+	if req.DisabledHeaders == nil {
+		req.DisabledHeaders = make(map[string]string)
+	}
+	req.DisabledHeaders[name] = value
+}
+
+// uniqueName returns name, or "request" when it is empty, with a numeric
+// suffix when it was already used
+func uniqueName(used map[string]int, name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "request"
+	}
+	used[name]++
+	if n := used[name]; n > 1 {
+		return name + "-" + strconv.Itoa(n)
+	}
+	return name
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Snippet formats recognized by ParseText
+const (
+	FormatCurl = "curl"
+	FormatHTTP = "http"
+	FormatJSON = "json"
+	FormatURL  = "url"
+)
+
+// Fields that ParseText may be unable to determine
+const (
+	FieldURL    = "url"
+	FieldMethod = "method"
+	FieldScheme = "scheme"
+)
+
+// fencePattern matches Markdown fenced code blocks
+var fencePattern = regexp.MustCompile("(?s)```([A-Za-z0-9_-]*)[^\\n]*\\n(.*?)```")
+
+// Result is the best-effort request built from a snippet
+type Result struct {
+	Request *request.RequestData
+	Format  string
+
+	// Ambiguous lists the fields that were guessed or are missing and
+	// should be confirmed by the user
+	Ambiguous []string
+}
+
+// ParseText recognizes a pasted snippet of API documentation: a curl
+// command, a raw HTTP request, a JSON body, or a bare URL. Fenced code blocks
+// are tried in order before the text as a whole.
+func ParseText(text string) (*Result, error) {
+	results, err := ParseTextAll(text)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// ParseTextAll is ParseText returning every snippet recognized, one per
+// fenced code block and the text as a whole, such as the curl command and
+// the JSON body of a documentation page. The same request is returned
+// once.
+func ParseTextAll(text string) ([]*Result, error) {
+	var candidates []string
+	for _, match := range fencePattern.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, match[2])
+	}
+	candidates = append(candidates, text)
+
+	seen := make(map[string]bool)
+	var results []*Result
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if result := parseCandidate(candidate); result != nil {
+			results = append(results, result)
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("could not recognize a curl command, HTTP request, JSON body, or URL")
+	}
+	return results, nil
+}
+
+// parseCandidate tries each format on a single block of text
+func parseCandidate(text string) *Result {
+	if text == "" {
+		return nil
+	}
+
+	// Skip shell prompts in front of curl commands
+	trimmed := strings.TrimLeft(text, "$> ")
+	if strings.HasPrefix(trimmed, "curl ") {
+		if req, err := ParseCurl(trimmed); err == nil {
+			return &Result{Request: req, Format: FormatCurl}
+		}
+	}
+
+	if req, guessed, err := ParseHTTP(text); err == nil {
+		result := &Result{Request: req, Format: FormatHTTP}
+		if guessed {
+			result.Ambiguous = append(result.Ambiguous, FieldScheme)
+		}
+		if !isAbsoluteURL(req.URL) {
+			result.Ambiguous = append(result.Ambiguous, FieldURL)
+		}
+		return result
+	}
+
+	if json.Valid([]byte(text)) && (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) {
+		req := request.NewRequestData()
+		req.Method = "POST"
+		req.Body = text
+		req.Headers["Content-Type"] = "application/json"
+		return &Result{Request: req, Format: FormatJSON, Ambiguous: []string{FieldURL, FieldMethod}}
+	}
+
+	if !strings.ContainsAny(text, " \n\t") && isAbsoluteURL(text) {
+		req := request.NewRequestData()
+		req.URL = text
+		if err := splitQuery(req); err != nil {
+			return nil
+		}
+		return &Result{Request: req, Format: FormatURL, Ambiguous: []string{FieldMethod}}
+	}
+
+	return nil
+}
+
+// isAbsoluteURL reports whether s has an http(s) scheme and a host
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
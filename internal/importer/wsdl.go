@@ -0,0 +1,395 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Namespaces of the SOAP 1.1 and 1.2 envelopes
+const (
+	soap11Envelope = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Envelope = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// maxSchemaDepth stops expanding recursive schema types
+const maxSchemaDepth = 8
+
+type wsdlDefinitions struct {
+	Name            string         `xml:"name,attr"`
+	TargetNamespace string         `xml:"targetNamespace,attr"`
+	Schemas         []xsdSchema    `xml:"types>schema"`
+	Messages        []wsdlMessage  `xml:"message"`
+	PortTypes       []wsdlPortType `xml:"portType"`
+	Bindings        []wsdlBinding  `xml:"binding"`
+	Services        []wsdlService  `xml:"service"`
+}
+
+type xsdSchema struct {
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Elements           []xsdElement     `xml:"element"`
+	ComplexTypes       []xsdComplexType `xml:"complexType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	Ref         string          `xml:"ref,attr"`
+	MinOccurs   string          `xml:"minOccurs,attr"`
+	MaxOccurs   string          `xml:"maxOccurs,attr"`
+	ComplexType *xsdComplexType `xml:"complexType"`
+}
+
+type xsdComplexType struct {
+	Name           string             `xml:"name,attr"`
+	Sequence       *xsdGroup          `xml:"sequence"`
+	All            *xsdGroup          `xml:"all"`
+	Choice         *xsdGroup          `xml:"choice"`
+	ComplexContent *xsdComplexContent `xml:"complexContent"`
+}
+
+type xsdComplexContent struct {
+	Extension *struct {
+		Base     string    `xml:"base,attr"`
+		Sequence *xsdGroup `xml:"sequence"`
+	} `xml:"extension"`
+}
+
+type xsdGroup struct {
+	Elements  []xsdElement `xml:"element"`
+	Sequences []xsdGroup   `xml:"sequence"`
+	Choices   []xsdGroup   `xml:"choice"`
+}
+
+type wsdlMessage struct {
+	Name  string     `xml:"name,attr"`
+	Parts []wsdlPart `xml:"part"`
+}
+
+type wsdlPart struct {
+	Name    string `xml:"name,attr"`
+	Element string `xml:"element,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+type wsdlPortType struct {
+	Name       string `xml:"name,attr"`
+	Operations []struct {
+		Name  string `xml:"name,attr"`
+		Input struct {
+			Message string `xml:"message,attr"`
+		} `xml:"input"`
+	} `xml:"operation"`
+}
+
+type wsdlBinding struct {
+	Name       string                 `xml:"name,attr"`
+	Type       string                 `xml:"type,attr"`
+	Operations []wsdlBindingOperation `xml:"operation"`
+}
+
+type wsdlBindingOperation struct {
+	Name   string             `xml:"name,attr"`
+	SOAP11 *wsdlSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	SOAP12 *wsdlSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+}
+
+type wsdlSOAPOperation struct {
+	SOAPAction string `xml:"soapAction,attr"`
+}
+
+type wsdlService struct {
+	Name  string `xml:"name,attr"`
+	Ports []struct {
+		Binding string       `xml:"binding,attr"`
+		SOAP11  *wsdlAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+		SOAP12  *wsdlAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
+	} `xml:"port"`
+}
+
+type wsdlAddress struct {
+	Location string `xml:"location,attr"`
+}
+
+// wsdlEndpoint is a SOAP port of a service
+type wsdlEndpoint struct {
+	location string
+	binding  string
+	soap12   bool
+}
+
+// ParseWSDL generates one POST request per operation of a WSDL 1.1
+// document. Each body is a SOAP envelope with a skeleton of the operation's
+// input derived from the schema, using {{name}} placeholders for the simple
+// values. SOAP 1.1 ports are preferred when a service offers both versions.
+func ParseWSDL(data []byte) ([]collection.SavedRequest, error) {
+	var defs wsdlDefinitions
+	if err := xml.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("invalid WSDL: %v", err)
+	}
+	schema := newSchemaIndex(defs.Schemas)
+
+	var endpoints []wsdlEndpoint
+	for _, service := range defs.Services {
+		for _, port := range service.Ports {
+			switch {
+			case port.SOAP11 != nil:
+				endpoints = append(endpoints, wsdlEndpoint{port.SOAP11.Location, localName(port.Binding), false})
+			case port.SOAP12 != nil:
+				endpoints = append(endpoints, wsdlEndpoint{port.SOAP12.Location, localName(port.Binding), true})
+			}
+		}
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool { return !endpoints[i].soap12 && endpoints[j].soap12 })
+
+	seen := make(map[string]bool)
+	var requests []collection.SavedRequest
+	for _, endpoint := range endpoints {
+		binding, ok := findBinding(defs.Bindings, endpoint.binding)
+		if !ok {
+			return nil, fmt.Errorf("binding not found: %s", endpoint.binding)
+		}
+		for _, operation := range binding.Operations {
+			if seen[operation.Name] {
+				continue
+			}
+			seen[operation.Name] = true
+
+			body, err := defs.operationBody(schema, binding, operation.Name)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %v", operation.Name, err)
+			}
+			requests = append(requests, soapRequest(operation, endpoint, body))
+		}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no SOAP operations found")
+	}
+	return requests, nil
+}
+
+// soapRequest wraps an operation body in the envelope for the endpoint's
+// SOAP version
+func soapRequest(operation wsdlBindingOperation, endpoint wsdlEndpoint, body string) collection.SavedRequest {
+	req := request.NewRequestData()
+	req.Method = "POST"
+	req.URL = endpoint.location
+
+	envelope := soap11Envelope
+	if endpoint.soap12 {
+		envelope = soap12Envelope
+		contentType := "application/soap+xml; charset=utf-8"
+		if operation.SOAP12 != nil && operation.SOAP12.SOAPAction != "" {
+			contentType += fmt.Sprintf("; action=%q", operation.SOAP12.SOAPAction)
+		}
+		req.Headers["Content-Type"] = contentType
+	} else {
+		req.Headers["Content-Type"] = "text/xml; charset=utf-8"
+		action := ""
+		if operation.SOAP11 != nil {
+			action = operation.SOAP11.SOAPAction
+		}
+		req.Headers["SOAPAction"] = fmt.Sprintf("%q", action)
+	}
+
+	req.Body = fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="%s">
+  <soap:Body>
+%s  </soap:Body>
+</soap:Envelope>`, envelope, body)
+	return collection.SavedRequest{Name: operation.Name, Request: *req}
+}
+
+// operationBody renders the skeleton of an operation's input message
+func (d *wsdlDefinitions) operationBody(schema *schemaIndex, binding wsdlBinding, name string) (string, error) {
+	message, ok := d.inputMessage(localName(binding.Type), name)
+	if !ok {
+		return "", fmt.Errorf("input message not found")
+	}
+
+	var b strings.Builder
+	if len(message.Parts) > 0 && message.Parts[0].Element == "" {
+		// RPC style: a wrapper named after the operation holds the parts
+		fmt.Fprintf(&b, "    <tns:%s xmlns:tns=%q>\n", name, d.TargetNamespace)
+		for _, part := range message.Parts {
+			schema.renderElement(&b, xsdElement{Name: part.Name, Type: part.Type}, "", 3, nil)
+		}
+		fmt.Fprintf(&b, "    </tns:%s>\n", name)
+		return b.String(), nil
+	}
+
+	for _, part := range message.Parts {
+		element, ok := schema.elements[localName(part.Element)]
+		if !ok {
+			return "", fmt.Errorf("element not found: %s", part.Element)
+		}
+		schema.renderRoot(&b, element)
+	}
+	return b.String(), nil
+}
+
+// inputMessage finds the input message of an operation of a port type
+func (d *wsdlDefinitions) inputMessage(portType, operation string) (wsdlMessage, bool) {
+	for _, pt := range d.PortTypes {
+		if pt.Name != portType {
+			continue
+		}
+		for _, op := range pt.Operations {
+			if op.Name != operation {
+				continue
+			}
+			for _, message := range d.Messages {
+				if message.Name == localName(op.Input.Message) {
+					return message, true
+				}
+			}
+		}
+	}
+	return wsdlMessage{}, false
+}
+
+// findBinding looks up a binding by name
+func findBinding(bindings []wsdlBinding, name string) (wsdlBinding, bool) {
+	for _, binding := range bindings {
+		if binding.Name == name {
+			return binding, true
+		}
+	}
+	return wsdlBinding{}, false
+}
+
+// schemaElement is a global element along with the schema defining it
+type schemaElement struct {
+	xsdElement
+	schema *xsdSchema
+}
+
+// schemaIndex looks up global elements and complex types by local name
+type schemaIndex struct {
+	elements map[string]schemaElement
+	types    map[string]xsdComplexType
+}
+
+func newSchemaIndex(schemas []xsdSchema) *schemaIndex {
+	index := &schemaIndex{elements: make(map[string]schemaElement), types: make(map[string]xsdComplexType)}
+	for i := range schemas {
+		for _, element := range schemas[i].Elements {
+			index.elements[element.Name] = schemaElement{element, &schemas[i]}
+		}
+		for _, complexType := range schemas[i].ComplexTypes {
+			index.types[complexType.Name] = complexType
+		}
+	}
+	return index
+}
+
+// renderRoot renders a global element in its schema's namespace. Children
+// inherit the namespace when the schema qualifies local elements.
+func (s *schemaIndex) renderRoot(b *strings.Builder, element schemaElement) {
+	if element.schema.ElementFormDefault == "qualified" {
+		s.renderElement(b, element.xsdElement, fmt.Sprintf(" xmlns=%q", element.schema.TargetNamespace), 2, nil)
+		return
+	}
+	root := element.xsdElement
+	root.Name = "tns:" + root.Name
+	s.renderElement(b, root, fmt.Sprintf(" xmlns:tns=%q", element.schema.TargetNamespace), 2, nil)
+}
+
+// renderElement writes an element with a {{placeholder}} for simple content
+// or its child elements for complex content
+func (s *schemaIndex) renderElement(b *strings.Builder, element xsdElement, attrs string, depth int, visiting map[string]bool) {
+	if element.Ref != "" {
+		if ref, ok := s.elements[localName(element.Ref)]; ok {
+			ref.MinOccurs, ref.MaxOccurs = element.MinOccurs, element.MaxOccurs
+			element = ref.xsdElement
+		}
+	}
+	indent := strings.Repeat("  ", depth)
+
+	var note []string
+	if element.MinOccurs == "0" {
+		note = append(note, "optional")
+	}
+	if element.MaxOccurs == "unbounded" || (element.MaxOccurs != "" && element.MaxOccurs != "1") {
+		note = append(note, "repeatable")
+	}
+	if len(note) > 0 {
+		fmt.Fprintf(b, "%s<!-- %s -->\n", indent, strings.Join(note, ", "))
+	}
+
+	complexType := element.ComplexType
+	typeName := localName(element.Type)
+	if complexType == nil {
+		if named, ok := s.types[typeName]; ok {
+			complexType = &named
+		}
+	}
+	placeholder := strings.TrimPrefix(element.Name, "tns:")
+	if complexType == nil || depth > maxSchemaDepth || visiting[typeName] {
+		fmt.Fprintf(b, "%s<%s%s>{{%s}}</%s>\n", indent, element.Name, attrs, placeholder, element.Name)
+		return
+	}
+
+	if typeName != "" {
+		if visiting == nil {
+			visiting = make(map[string]bool)
+		}
+		visiting[typeName] = true
+		defer delete(visiting, typeName)
+	}
+
+	children := s.children(*complexType, 0)
+	if len(children) == 0 {
+		fmt.Fprintf(b, "%s<%s%s/>\n", indent, element.Name, attrs)
+		return
+	}
+	fmt.Fprintf(b, "%s<%s%s>\n", indent, element.Name, attrs)
+	for _, child := range children {
+		s.renderElement(b, child, "", depth+1, visiting)
+	}
+	fmt.Fprintf(b, "%s</%s>\n", indent, element.Name)
+}
+
+// children lists the elements of a complex type, including those of the
+// base type it extends
+func (s *schemaIndex) children(complexType xsdComplexType, depth int) []xsdElement {
+	var elements []xsdElement
+	if extension := complexType.ComplexContent; extension != nil && extension.Extension != nil {
+		if base, ok := s.types[localName(extension.Extension.Base)]; ok && depth < maxSchemaDepth {
+			elements = append(elements, s.children(base, depth+1)...)
+		}
+		elements = append(elements, groupElements(extension.Extension.Sequence)...)
+	}
+	for _, group := range []*xsdGroup{complexType.Sequence, complexType.All, complexType.Choice} {
+		elements = append(elements, groupElements(group)...)
+	}
+	return elements
+}
+
+// groupElements flattens a sequence, all, or choice group
+func groupElements(group *xsdGroup) []xsdElement {
+	if group == nil {
+		return nil
+	}
+	elements := append([]xsdElement{}, group.Elements...)
+	for i := range group.Sequences {
+		elements = append(elements, groupElements(&group.Sequences[i])...)
+	}
+	for i := range group.Choices {
+		elements = append(elements, groupElements(&group.Choices[i])...)
+	}
+	return elements
+}
+
+// localName strips the namespace prefix from a qualified name
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
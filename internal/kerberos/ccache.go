@@ -0,0 +1,188 @@
+package kerberos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Principal name types (RFC 4120 6.2)
+const (
+	nameTypeSrvInst = 2
+	nameTypeSrvHst  = 3
+)
+
+// principal is a Kerberos principal, such as jane@CORP.EXAMPLE.COM or
+// HTTP/intranet.corp.example.com@CORP.EXAMPLE.COM
+type principal struct {
+	nameType   int32
+	realm      string
+	components []string
+}
+
+// String returns the principal as name/instance@REALM
+func (p principal) String() string {
+	return strings.Join(p.components, "/") + "@" + p.realm
+}
+
+// equal reports whether the principals have the same name and realm; the
+// name type does not matter
+func (p principal) equal(other principal) bool {
+	return strings.EqualFold(p.realm, other.realm) && strings.EqualFold(strings.Join(p.components, "/"), strings.Join(other.components, "/"))
+}
+
+// tgs returns the realm a ticket granting ticket is for, or false for a
+// service ticket
+func (p principal) tgs() (string, bool) {
+	if len(p.components) == 2 && p.components[0] == "krbtgt" {
+		return p.components[1], true
+	}
+	return "", false
+}
+
+// credential is a ticket and its session key
+type credential struct {
+	client  principal
+	server  principal
+	key     key
+	endTime time.Time
+
+	// ticket is the DER encoding of the ticket, sent as is
+	ticket []byte
+}
+
+// ccachePath returns the file of the credential cache: KRB5CCNAME, the
+// default_ccache_name of krb5.conf, or the MIT default /tmp/krb5cc_<uid>.
+// Only file caches can be read; KCM, keyring, and macOS API caches cannot.
+func ccachePath(cfg *config) (string, error) {
+	name := os.Getenv("KRB5CCNAME")
+	if name == "" {
+		name = cfg.ccacheName
+	}
+	if name == "" {
+		name = fmt.Sprintf("FILE:/tmp/krb5cc_%d", os.Getuid())
+	}
+	name = strings.NewReplacer("%{uid}", fmt.Sprint(os.Getuid()), "%{TEMP}", os.TempDir()).Replace(name)
+
+	kind, path, ok := strings.Cut(name, ":")
+	if !ok {
+		return name, nil
+	}
+	switch kind {
+	case "FILE":
+		return path, nil
+	case "DIR":
+		// A directory collection names its primary cache in the file primary
+		data, err := os.ReadFile(filepath.Join(path, "primary"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read the Kerberos credential cache collection %s: %v", path, err)
+		}
+		return filepath.Join(path, strings.TrimSpace(string(data))), nil
+	}
+	return "", fmt.Errorf("the Kerberos credential cache %s is not a file: set KRB5CCNAME=FILE:/tmp/krb5cc_%d and run kinit", name, os.Getuid())
+}
+
+// readCCache reads the default principal and credentials of an MIT file
+// credential cache, version 3 or 4
+func readCCache(path string) (principal, []credential, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return principal{}, nil, fmt.Errorf("no Kerberos credential cache at %s: run kinit", path)
+		}
+		return principal{}, nil, fmt.Errorf("failed to read the Kerberos credential cache: %v", err)
+	}
+	r := &ccacheReader{data: data}
+	version := r.uint16()
+	switch version {
+	case 0x0504:
+		// Header fields, such as the KDC time offset, are not needed
+		r.bytes(int(r.uint16()))
+	case 0x0503:
+	default:
+		return principal{}, nil, fmt.Errorf("unsupported Kerberos credential cache version %#x in %s", version, path)
+	}
+
+	client := r.principal()
+	var creds []credential
+	for r.err == nil && len(r.data) > 0 {
+		c := credential{client: r.principal(), server: r.principal()}
+		c.key.etype = int32(r.uint16())
+		c.key.value = r.data32()
+		r.uint32() // authtime
+		r.uint32() // starttime
+		c.endTime = time.Unix(int64(r.uint32()), 0)
+		r.uint32() // renew till
+		r.bytes(1) // is_skey
+		r.uint32() // ticket flags
+		for n := r.uint32(); n > 0 && r.err == nil; n-- {
+			r.uint16() // address type
+			r.data32()
+		}
+		for n := r.uint32(); n > 0 && r.err == nil; n-- {
+			r.uint16() // authorization data type
+			r.data32()
+		}
+		c.ticket = r.data32()
+		r.data32() // second ticket
+		if r.err == nil && !strings.HasPrefix(c.server.realm, "X-CACHECONF:") {
+			creds = append(creds, c)
+		}
+	}
+	if r.err != nil {
+		return principal{}, nil, fmt.Errorf("invalid Kerberos credential cache %s: %v", path, r.err)
+	}
+	return client, creds, nil
+}
+
+// ccacheReader reads the big-endian fields of a credential cache, keeping
+// the first error
+type ccacheReader struct {
+	data []byte
+	err  error
+}
+
+func (r *ccacheReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = fmt.Errorf("truncated")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *ccacheReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *ccacheReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// data32 reads data prefixed with its 32-bit length
+func (r *ccacheReader) data32() []byte {
+	return r.bytes(int(r.uint32()))
+}
+
+func (r *ccacheReader) principal() principal {
+	p := principal{nameType: int32(r.uint32())}
+	n := r.uint32()
+	p.realm = string(r.data32())
+	for ; n > 0 && r.err == nil; n-- {
+		p.components = append(p.components, string(r.data32()))
+	}
+	return p
+}
@@ -0,0 +1,125 @@
+package kerberos
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultConfigPath is where krb5.conf is read from unless KRB5_CONFIG says
+// otherwise
+const defaultConfigPath = "/etc/krb5.conf"
+
+// config is what lighttr uses of krb5.conf
+type config struct {
+	ccacheName   string
+	kdcs         map[string][]string
+	domainRealms map[string]string
+}
+
+// loadConfig reads the krb5.conf files of KRB5_CONFIG, a colon separated
+// list, or /etc/krb5.conf. Missing files are skipped: KDCs are then looked
+// up in DNS.
+func loadConfig() (*config, error) {
+	paths := defaultConfigPath
+	if env := os.Getenv("KRB5_CONFIG"); env != "" {
+		paths = env
+	}
+	cfg := &config{kdcs: make(map[string][]string), domainRealms: make(map[string]string)}
+	for _, path := range strings.Split(paths, ":") {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		cfg.parse(string(data))
+	}
+	return cfg, nil
+}
+
+// parse adds the settings of a krb5.conf: default_ccache_name of
+// [libdefaults], the kdc entries of [realms], and [domain_realm]. The first
+// value of a setting wins, as with MIT Kerberos.
+func (c *config) parse(data string) {
+	var section, realm string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, realm = strings.Trim(line, "[]"), ""
+			continue
+		}
+		if line == "}" {
+			realm = ""
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		switch {
+		case value == "{":
+			realm = name
+		case section == "libdefaults" && name == "default_ccache_name" && c.ccacheName == "":
+			c.ccacheName = value
+		case section == "realms" && realm != "" && name == "kdc":
+			c.kdcs[realm] = append(c.kdcs[realm], value)
+		case section == "domain_realm":
+			if _, ok := c.domainRealms[strings.ToLower(name)]; !ok {
+				c.domainRealms[strings.ToLower(name)] = value
+			}
+		}
+	}
+}
+
+// realmFor returns the realm [domain_realm] maps the host to, or false when
+// the KDC of the user's realm is to refer lighttr on
+func (c *config) realmFor(host string) (string, bool) {
+	host = strings.ToLower(host)
+	if realm, ok := c.domainRealms[host]; ok {
+		return realm, true
+	}
+	for domain := host; ; {
+		if realm, ok := c.domainRealms["."+domain]; ok {
+			return realm, true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return "", false
+		}
+		domain = parent
+	}
+}
+
+// kdcAddresses returns the addresses of the KDCs of the realm: those of
+// krb5.conf, or the _kerberos._tcp SRV records of the realm
+func (c *config) kdcAddresses(ctx context.Context, realm string) ([]string, error) {
+	var addrs []string
+	for _, kdc := range c.kdcs[realm] {
+		kdc = strings.TrimPrefix(strings.TrimPrefix(kdc, "tcp/"), "udp/")
+		if _, _, err := net.SplitHostPort(kdc); err != nil {
+			kdc = net.JoinHostPort(strings.Trim(kdc, "[]"), "88")
+		}
+		addrs = append(addrs, kdc)
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+
+	_, records, err := lookupSRV(ctx, "kerberos", "tcp", realm)
+	if err != nil || len(records) == 0 {
+		return nil, fmt.Errorf("no KDC found for the Kerberos realm %s: add it to the [realms] of krb5.conf", realm)
+	}
+	for _, record := range records {
+		addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+	}
+	return addrs, nil
+}
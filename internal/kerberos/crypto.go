@@ -0,0 +1,257 @@
+package kerberos
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+)
+
+// Encryption types of the AES profile (RFC 3962), the only ones supported
+const (
+	etypeAES128 = 17
+	etypeAES256 = 18
+)
+
+// Checksum types of the AES profile
+const (
+	checksumAES128 = 15
+	checksumAES256 = 16
+)
+
+// Key usages (RFC 4120 7.5.1)
+const (
+	usageTGSReqChecksum      = 6
+	usageTGSReqAuthenticator = 7
+	usageTGSRepEncPart       = 8
+	usageAPReqAuthenticator  = 11
+)
+
+// hmacSize is the length of the truncated HMAC-SHA1 of encrypted data and
+// checksums
+const hmacSize = 12
+
+// key is a session key of the etype
+type key struct {
+	etype int32
+	value []byte
+}
+
+// check reports an etype lighttr cannot encrypt with, or a key of the wrong
+// length
+func (k key) check() error {
+	switch {
+	case k.etype != etypeAES128 && k.etype != etypeAES256:
+		return fmt.Errorf("unsupported Kerberos encryption type %d: only AES (17 and 18) is supported", k.etype)
+	case k.etype == etypeAES128 && len(k.value) != 16, k.etype == etypeAES256 && len(k.value) != 32:
+		return fmt.Errorf("invalid Kerberos key of %d bytes for encryption type %d", len(k.value), k.etype)
+	}
+	return nil
+}
+
+// checksumType returns the checksum type keyed with the key
+func (k key) checksumType() int32 {
+	if k.etype == etypeAES128 {
+		return checksumAES128
+	}
+	return checksumAES256
+}
+
+// encrypt encrypts the plaintext with a random confounder and appends its
+// HMAC, as the AES profile does
+func (k key) encrypt(usage uint32, plaintext []byte) ([]byte, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	ke, ki, err := k.usageKeys(usage)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, aes.BlockSize, aes.BlockSize+len(plaintext))
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	data = append(data, plaintext...)
+	ciphertext, err := encryptCTS(ke, data)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, ki)
+	mac.Write(data)
+	return append(ciphertext, mac.Sum(nil)[:hmacSize]...), nil
+}
+
+// decrypt checks the HMAC of the ciphertext and decrypts it, dropping the
+// confounder
+func (k key) decrypt(usage uint32, ciphertext []byte) ([]byte, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aes.BlockSize+hmacSize {
+		return nil, fmt.Errorf("Kerberos ciphertext too short")
+	}
+	ke, ki, err := k.usageKeys(usage)
+	if err != nil {
+		return nil, err
+	}
+	sum := ciphertext[len(ciphertext)-hmacSize:]
+	data, err := decryptCTS(ke, ciphertext[:len(ciphertext)-hmacSize])
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, ki)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil)[:hmacSize], sum) {
+		return nil, fmt.Errorf("Kerberos integrity check failed: wrong key")
+	}
+	return data[aes.BlockSize:], nil
+}
+
+// checksum returns the keyed checksum of the data
+func (k key) checksum(usage uint32, data []byte) ([]byte, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	kc, err := deriveKey(k.value, usageConstant(usage, 0x99))
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, kc)
+	mac.Write(data)
+	return mac.Sum(nil)[:hmacSize], nil
+}
+
+// usageKeys derives the encryption and integrity keys of the usage
+func (k key) usageKeys(usage uint32) (ke, ki []byte, err error) {
+	if ke, err = deriveKey(k.value, usageConstant(usage, 0xAA)); err != nil {
+		return nil, nil, err
+	}
+	if ki, err = deriveKey(k.value, usageConstant(usage, 0x55)); err != nil {
+		return nil, nil, err
+	}
+	return ke, ki, nil
+}
+
+// usageConstant is the constant keys of the usage are derived with
+func usageConstant(usage uint32, kind byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, usage), kind)
+}
+
+// deriveKey is DK of RFC 3961 for AES, whose random-to-key is the identity:
+// the n-folded constant encrypted repeatedly until there are enough bytes
+// for a key
+func deriveKey(base, constant []byte) ([]byte, error) {
+	block, err := aes.NewCipher(base)
+	if err != nil {
+		return nil, err
+	}
+	in := nfold(constant, aes.BlockSize)
+	out := make([]byte, 0, len(base)+aes.BlockSize)
+	for len(out) < len(base) {
+		next := make([]byte, aes.BlockSize)
+		block.Encrypt(next, in)
+		out = append(out, next...)
+		in = next
+	}
+	return out[:len(base)], nil
+}
+
+// nfold stretches or shrinks the input to n bytes (RFC 3961 5.1): copies
+// of the input, each rotated 13 bits further right, added up in n-byte
+// chunks with one's complement addition
+func nfold(in []byte, n int) []byte {
+	k := len(in)
+	lcm := k * n / gcd(k, n)
+	out := make([]byte, n)
+	carry := 0
+	for i := lcm - 1; i >= 0; i-- {
+		// The most significant bit of the input that lands in this byte
+		msbit := ((k << 3) - 1 + ((k<<3)+13)*(i/k) + ((k - i%k) << 3)) % (k << 3)
+		carry += (int(in[(k-1-(msbit>>3))%k])<<8 | int(in[(k-(msbit>>3))%k])) >> ((msbit & 7) + 1) & 0xff
+		carry += int(out[i%n])
+		out[i%n] = byte(carry)
+		carry >>= 8
+	}
+	for i := n - 1; carry != 0 && i >= 0; i-- {
+		carry += int(out[i])
+		out[i] = byte(carry)
+		carry >>= 8
+	}
+	return out
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// encryptCTS encrypts with AES in CBC mode with ciphertext stealing and a
+// zero IV: the last two blocks are swapped and the last one truncated, so
+// the ciphertext is as long as the plaintext
+func encryptCTS(k, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) < aes.BlockSize {
+		return nil, fmt.Errorf("Kerberos plaintext too short")
+	}
+	padded := make([]byte, (len(plaintext)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, plaintext)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(ciphertext, padded)
+	if len(ciphertext) == aes.BlockSize {
+		return ciphertext, nil
+	}
+	n := len(ciphertext)
+	swapped := append(ciphertext[:n-2*aes.BlockSize:n-2*aes.BlockSize], ciphertext[n-aes.BlockSize:]...)
+	swapped = append(swapped, ciphertext[n-2*aes.BlockSize:n-aes.BlockSize]...)
+	return swapped[:len(plaintext)], nil
+}
+
+// decryptCTS reverses encryptCTS
+func decryptCTS(k, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	n := len(ciphertext)
+	if n < aes.BlockSize {
+		return nil, fmt.Errorf("Kerberos ciphertext too short")
+	}
+	if n == aes.BlockSize {
+		plaintext := make([]byte, n)
+		block.Decrypt(plaintext, ciphertext)
+		return plaintext, nil
+	}
+
+	// The blocks before the last two are plain CBC
+	last := n - (n-1)/aes.BlockSize*aes.BlockSize
+	prefix := ciphertext[:n-aes.BlockSize-last]
+	plaintext := make([]byte, n)
+	iv := make([]byte, aes.BlockSize)
+	if len(prefix) > 0 {
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, prefix)
+		iv = prefix[len(prefix)-aes.BlockSize:]
+	}
+
+	// The full block sent last but one is the encryption of the padded last
+	// block, chained to the block that was truncated
+	d := make([]byte, aes.BlockSize)
+	block.Decrypt(d, ciphertext[len(prefix):len(prefix)+aes.BlockSize])
+	partial := ciphertext[len(prefix)+aes.BlockSize:]
+	previous := append(append([]byte{}, partial...), d[last:]...)
+	for i := 0; i < last; i++ {
+		plaintext[n-last+i] = d[i] ^ partial[i]
+	}
+	block.Decrypt(plaintext[len(prefix):len(prefix)+aes.BlockSize], previous)
+	for i := 0; i < aes.BlockSize; i++ {
+		plaintext[len(prefix)+i] ^= iv[i]
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,111 @@
+package kerberos
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNFold(t *testing.T) {
+	// RFC 3961 A.1
+	tests := []struct {
+		in   string
+		bits int
+		want string
+	}{
+		{"012345", 64, "be072631276b1955"},
+		{"password", 56, "78a07b6caf85fa"},
+		{"Rough Consensus, and Running Code", 64, "bb6ed30870b7f0e0"},
+		{"password", 168, "59e4a8ca7c0385c3c37b3f6d2000247cb6e6bd5b3e"},
+		{"MASSACHVSETTS INSTITVTE OF TECHNOLOGY", 192, "db3b0d8f0b061e603282b308a50841229ad798fab9540c1b"},
+		{"Q", 168, "518a54a215a8452a518a54a215a8452a518a54a215"},
+		{"ba", 168, "fb25d531ae8974499f52fd92ea9857c4ba24cf297e"},
+		{"kerberos", 64, "6b65726265726f73"},
+		{"kerberos", 128, "6b65726265726f737b9b5b2b93132b93"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(nfold([]byte(tt.in), tt.bits/8)); got != tt.want {
+			t.Errorf("%d-fold(%q) = %s, want %s", tt.bits, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	// The string-to-key vectors of RFC 3962 B check DK, which derives the
+	// key from the PBKDF2 output with the constant "kerberos"
+	for _, tt := range []struct {
+		size int
+		want string
+	}{
+		{16, "42263c6e89f4fc28b8df68ee09799f15"},
+		{32, "fe697b52bc0d3ce14432ba036a92e65bbb52280990a2fa27883998d72af30161"},
+	} {
+		tkey := pbkdf2.Key([]byte("password"), []byte("ATHENA.MIT.EDUraeburn"), 1, tt.size, sha1.New)
+		got, err := deriveKey(tkey, []byte("kerberos"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("%d byte key = %x, want %s", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestCTS(t *testing.T) {
+	// RFC 3962 B
+	k := []byte("chicken teriyaki")
+	for _, tt := range []struct{ in, want string }{
+		{"I would like the ", "c6353568f2bf8cb4d8a580362da7ff7f97"},
+		{"I would like the General Gau's ", "fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5"},
+		{"I would like the General Gau's C", "39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584"},
+	} {
+		got, err := encryptCTS(k, []byte(tt.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("encryptCTS(%q) = %x, want %s", tt.in, got, tt.want)
+		}
+		plain, err := decryptCTS(k, got)
+		if err != nil || string(plain) != tt.in {
+			t.Errorf("decryptCTS() = %q, %v, want %q", plain, err, tt.in)
+		}
+	}
+}
+
+func TestKey_EncryptDecrypt(t *testing.T) {
+	for _, k := range []key{
+		{etypeAES128, bytes.Repeat([]byte{1}, 16)},
+		{etypeAES256, bytes.Repeat([]byte{2}, 32)},
+	} {
+		for _, size := range []int{0, 1, 16, 33} {
+			plaintext := bytes.Repeat([]byte("x"), size)
+			ciphertext, err := k.encrypt(usageAPReqAuthenticator, plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := k.decrypt(usageAPReqAuthenticator, ciphertext); err != nil || !bytes.Equal(got, plaintext) {
+				t.Errorf("etype %d: decrypt() = %q, %v, want %q", k.etype, got, err, plaintext)
+			}
+			if _, err := k.decrypt(usageTGSRepEncPart, ciphertext); err == nil {
+				t.Errorf("etype %d: expected another usage to fail the integrity check", k.etype)
+			}
+		}
+	}
+
+	if _, err := (key{etype: 23, value: make([]byte, 16)}).encrypt(usageAPReqAuthenticator, nil); err == nil {
+		t.Error("Expected RC4 keys to be unsupported")
+	}
+}
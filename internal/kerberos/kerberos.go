@@ -0,0 +1,180 @@
+// Package kerberos authenticates requests with negotiate auth with the
+// Kerberos tickets of the user's credential cache, sending SPNEGO tokens as
+// browsers and curl --negotiate do
+package kerberos
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// For testing
+var (
+	now       = time.Now
+	dialKDC   = (&net.Dialer{}).DialContext
+	lookupSRV = net.DefaultResolver.LookupSRV
+)
+
+// kdcTimeout bounds an exchange with a KDC when the request has no deadline
+const kdcTimeout = 10 * time.Second
+
+// maxReferrals bounds the realms a ticket request is referred through
+const maxReferrals = 5
+
+// refreshSkew is how long before they expire tickets are replaced
+const refreshSkew = time.Minute
+
+var (
+	// mu guards cache, the service tickets obtained so far by client and
+	// service principal
+	mu    sync.Mutex
+	cache = make(map[string]credential)
+)
+
+// Middleware returns the provider of negotiate auth, for
+// request.RegisterAuth, which sends a SPNEGO token for the HTTP service of
+// the request's host in the Authorization header
+func Middleware() request.Middleware {
+	return func(next request.Handler) request.Handler {
+		return func(x *request.Exchange) error {
+			token, err := Token(x.Request.Context(), x.Request.URL.Hostname())
+			if err != nil {
+				return x.Abort(err)
+			}
+			x.Request.Header.Set("Authorization", "Negotiate "+token)
+			return next(x)
+		}
+	}
+}
+
+// Token returns a base64 SPNEGO token for the service principal
+// HTTP/<host>. The service ticket is taken from the credential cache, or
+// obtained from the KDC with the ticket granting ticket of kinit and kept
+// in memory until it expires; every token has an authenticator of its own.
+func Token(ctx context.Context, host string) (string, error) {
+	cfg, client, creds, err := load()
+	if err != nil {
+		return "", err
+	}
+
+	spn := principal{nameType: nameTypeSrvHst, components: []string{"HTTP", strings.ToLower(host)}}
+	ticket, err := serviceTicket(ctx, cfg, client, creds, spn)
+	if err != nil {
+		return "", err
+	}
+	token, err := spnegoToken(ticket)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(token), nil
+}
+
+// load reads krb5.conf and the credential cache
+func load() (*config, principal, []credential, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, principal{}, nil, err
+	}
+	path, err := ccachePath(cfg)
+	if err != nil {
+		return nil, principal{}, nil, err
+	}
+	client, creds, err := readCCache(path)
+	if err != nil {
+		return nil, principal{}, nil, err
+	}
+	return cfg, client, creds, nil
+}
+
+// serviceTicket returns a ticket for the service, the realm of which is
+// unknown until found
+func serviceTicket(ctx context.Context, cfg *config, client principal, creds []credential, spn principal) (credential, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	name := strings.Join(spn.components, "/")
+	cacheKey := client.String() + " " + name
+	if cached, ok := cache[cacheKey]; ok && fresh(cached) {
+		return cached, nil
+	}
+
+	for _, c := range creds {
+		if c.client.equal(client) && strings.EqualFold(strings.Join(c.server.components, "/"), name) && fresh(c) {
+			// kinit -S or another client already got one
+			return c, nil
+		}
+	}
+	tgt, err := findTGT(client, creds)
+	if err != nil {
+		return credential{}, err
+	}
+	if !fresh(tgt) {
+		return credential{}, fmt.Errorf("the Kerberos ticket of %s expired at %s: run kinit", client, tgt.endTime.Local().Format(time.DateTime))
+	}
+
+	// Without [domain_realm], the KDC of the user's realm refers lighttr to
+	// the realm of the service
+	realm, mapped := cfg.realmFor(spn.components[1])
+	if !mapped {
+		realm = client.realm
+	}
+	current := tgt
+	for hops := 0; hops <= maxReferrals; hops++ {
+		issuer, _ := current.server.tgs()
+		want := spn
+		want.realm = realm
+		if !strings.EqualFold(issuer, realm) {
+			want = principal{nameType: nameTypeSrvInst, realm: issuer, components: []string{"krbtgt", realm}}
+		}
+		got, err := tgsExchange(ctx, cfg, current, want)
+		if err != nil {
+			return credential{}, err
+		}
+		if next, ok := got.server.tgs(); ok && !strings.EqualFold(next, issuer) {
+			// A referral, or the cross-realm ticket asked for
+			current = got
+			if !mapped || strings.EqualFold(want.components[0], spn.components[0]) {
+				realm = next
+			}
+			continue
+		}
+		cache[cacheKey] = got
+		return got, nil
+	}
+	return credential{}, fmt.Errorf("failed to get a Kerberos ticket for %s: too many referrals", name)
+}
+
+// Login returns the user kinit logged in, whose ticket granting ticket is in
+// the credential cache, and when the ticket expires
+func Login() (string, time.Time, error) {
+	_, client, creds, err := load()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	tgt, err := findTGT(client, creds)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return client.String(), tgt.endTime, nil
+}
+
+// findTGT returns the ticket granting ticket of the user's own realm
+func findTGT(client principal, creds []credential) (credential, error) {
+	for _, c := range creds {
+		if realm, ok := c.server.tgs(); ok && c.client.equal(client) && realm == client.realm && c.server.realm == client.realm {
+			return c, nil
+		}
+	}
+	return credential{}, fmt.Errorf("no Kerberos ticket granting ticket for %s in the credential cache: run kinit", client)
+}
+
+// fresh reports whether the ticket is valid for a while yet
+func fresh(c credential) bool {
+	return now().Add(refreshSkew).Before(c.endTime)
+}
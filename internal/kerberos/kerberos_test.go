@@ -0,0 +1,529 @@
+package kerberos
+
+import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// usageTicket is the key usage of the encrypted part of a ticket
+const usageTicket = 2
+
+// Realms of the tests: users are in CORP, and the KDC of CORP refers
+// lighttr to APPS for the hosts there
+const (
+	corp = "CORP.EXAMPLE.COM"
+	apps = "APPS.EXAMPLE.COM"
+)
+
+var jane = principal{nameType: 1, realm: corp, components: []string{"jane"}}
+
+// testKeys are the long-term keys of the KDCs and services
+var testKeys = map[string]key{
+	"krbtgt/" + corp + "@" + corp:        {etypeAES256, bytes.Repeat([]byte{1}, 32)},
+	"krbtgt/" + apps + "@" + corp:        {etypeAES256, bytes.Repeat([]byte{2}, 32)},
+	"HTTP/127.0.0.1@" + corp:             {etypeAES128, bytes.Repeat([]byte{3}, 16)},
+	"HTTP/wiki.apps.example.com@" + apps: {etypeAES256, bytes.Repeat([]byte{4}, 32)},
+}
+
+type testTicketPart struct {
+	VNO     int           `asn1:"explicit,tag:0"`
+	Realm   string        `asn1:"explicit,tag:1"`
+	SName   principalName `asn1:"explicit,tag:2"`
+	EncPart encryptedData `asn1:"explicit,tag:3"`
+}
+
+// openTicket returns the server and session key of a test ticket
+func openTicket(der []byte) (principal, key, error) {
+	var tkt testTicketPart
+	if _, err := asn1.UnmarshalWithParams(der, &tkt, "application,explicit,tag:1"); err != nil {
+		return principal{}, key{}, err
+	}
+	server := tkt.SName.principal(tkt.Realm)
+	plain, err := testKeys[server.String()].decrypt(usageTicket, tkt.EncPart.Cipher)
+	if err != nil {
+		return principal{}, key{}, err
+	}
+	return server, key{etype: int32(plain[0]), value: plain[1:]}, nil
+}
+
+type testAPReq struct {
+	PVNO          int            `asn1:"explicit,tag:0"`
+	MsgType       int            `asn1:"explicit,tag:1"`
+	APOptions     asn1.BitString `asn1:"explicit,tag:2"`
+	Ticket        asn1.RawValue  `asn1:"explicit,tag:3"`
+	Authenticator encryptedData  `asn1:"explicit,tag:4"`
+}
+
+type testAuthenticator struct {
+	VNO      int           `asn1:"explicit,tag:0"`
+	CRealm   string        `asn1:"explicit,tag:1"`
+	CName    principalName `asn1:"explicit,tag:2"`
+	Checksum struct {
+		Type  int32  `asn1:"explicit,tag:0"`
+		Value []byte `asn1:"explicit,tag:1"`
+	} `asn1:"explicit,tag:3"`
+	Cusec int       `asn1:"explicit,tag:4"`
+	CTime time.Time `asn1:"generalized,explicit,tag:5"`
+}
+
+// verifyAPReq checks the AP-REQ as a server would, returning the ticket's
+// server and session key and the authenticator
+func verifyAPReq(der []byte, usage uint32) (principal, key, testAuthenticator, error) {
+	var req testAPReq
+	var auth testAuthenticator
+	if _, err := asn1.UnmarshalWithParams(der, &req, "application,explicit,tag:14"); err != nil {
+		return principal{}, key{}, auth, err
+	}
+	server, session, err := openTicket(req.Ticket.Bytes)
+	if err != nil {
+		return principal{}, key{}, auth, err
+	}
+	plain, err := session.decrypt(usage, req.Authenticator.Cipher)
+	if err != nil {
+		return principal{}, key{}, auth, err
+	}
+	if _, err := asn1.UnmarshalWithParams(plain, &auth, "application,explicit,tag:2"); err != nil {
+		return principal{}, key{}, auth, err
+	}
+	if client := auth.CName.principal(auth.CRealm); !client.equal(jane) {
+		return principal{}, key{}, auth, fmt.Errorf("authenticator of %s", client)
+	}
+	return server, session, auth, nil
+}
+
+type testKDCReq struct {
+	PVNO    int `asn1:"explicit,tag:1"`
+	MsgType int `asn1:"explicit,tag:2"`
+	PAData  []struct {
+		Type  int32  `asn1:"explicit,tag:1"`
+		Value []byte `asn1:"explicit,tag:2"`
+	} `asn1:"explicit,tag:3"`
+	Body asn1.RawValue `asn1:"explicit,tag:4"`
+}
+
+type testKDCReqBody struct {
+	Options asn1.BitString `asn1:"explicit,tag:0"`
+	Realm   string         `asn1:"explicit,tag:2"`
+	SName   principalName  `asn1:"explicit,tag:3"`
+	Till    time.Time      `asn1:"generalized,explicit,tag:5"`
+	Nonce   int64          `asn1:"explicit,tag:7"`
+	ETypes  []int32        `asn1:"explicit,tag:8"`
+}
+
+// testKDC is the KDC of a realm, which answers TGS-REQs for the services
+// of its realm and refers requests for hosts of other realms on
+type testKDC struct {
+	realm    string
+	hosts    map[string]string
+	requests []string
+}
+
+// serve answers the TGS-REQs sent to the listener
+func (k *testKDC) serve(t *testing.T, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			conn.Close()
+			continue
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(header))
+		io.ReadFull(conn, msg)
+		reply, err := k.reply(msg)
+		if err != nil {
+			t.Errorf("KDC of %s: %v", k.realm, err)
+		}
+		conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(reply))), reply...))
+		conn.Close()
+	}
+}
+
+func (k *testKDC) reply(msg []byte) ([]byte, error) {
+	var req testKDCReq
+	if _, err := asn1.UnmarshalWithParams(msg, &req, "application,explicit,tag:12"); err != nil {
+		return nil, err
+	}
+	var body testKDCReqBody
+	if _, err := asn1.Unmarshal(req.Body.Bytes, &body); err != nil {
+		return nil, err
+	}
+	if len(req.PAData) != 1 || req.PAData[0].Type != paTGSReq {
+		return nil, fmt.Errorf("no PA-TGS-REQ")
+	}
+	tgs, session, auth, err := verifyAPReq(req.PAData[0].Value, usageTGSReqAuthenticator)
+	if err != nil {
+		return nil, err
+	}
+	if realm, ok := tgs.tgs(); !ok || realm != k.realm || body.Realm != k.realm {
+		return nil, fmt.Errorf("TGS-REQ for %s with a ticket for %s", body.Realm, tgs)
+	}
+	sum, err := session.checksum(usageTGSReqChecksum, req.Body.Bytes)
+	if err != nil || auth.Checksum.Type != session.checksumType() || !bytes.Equal(sum, auth.Checksum.Value) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+
+	server := body.SName.principal(body.Realm)
+	k.requests = append(k.requests, server.String())
+	if len(server.components) == 2 && server.components[0] == "HTTP" {
+		if realm := k.hosts[server.components[1]]; realm != k.realm {
+			// A referral to the realm of the host
+			server = principal{nameType: nameTypeSrvInst, realm: k.realm, components: []string{"krbtgt", realm}}
+		}
+	}
+	if _, ok := testKeys[server.String()]; !ok {
+		return k.krbError(7, server), nil
+	}
+
+	issued := key{etypeAES256, bytes.Repeat([]byte{byte(len(k.requests) + 10)}, 32)}
+	end := now().Add(10 * time.Hour)
+	var b cryptobyte.Builder
+	b.AddASN1(application(tagTGSRepPart), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1(explicit(0), func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					addInt(b, 0, int64(issued.etype))
+					addOctets(b, 1, issued.value)
+				})
+			})
+			b.AddASN1(explicit(1), func(b *cryptobyte.Builder) { b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {}) })
+			addInt(b, 2, body.Nonce)
+			b.AddASN1(explicit(4), func(b *cryptobyte.Builder) { b.AddASN1BitString(make([]byte, 4)) })
+			addTime(b, 5, now())
+			addTime(b, 7, end)
+			addString(b, 9, server.realm)
+			addPrincipal(b, 10, server)
+		})
+	})
+	part, err := session.encrypt(usageTGSRepEncPart, b.BytesOrPanic())
+	if err != nil {
+		return nil, err
+	}
+	ticket := testTicket(server, issued)
+	b = cryptobyte.Builder{}
+	b.AddASN1(application(tagTGSRep), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, 5)
+			addInt(b, 1, tagTGSRep)
+			addString(b, 3, auth.CRealm)
+			addPrincipal(b, 4, auth.CName.principal(auth.CRealm))
+			b.AddASN1(explicit(5), func(b *cryptobyte.Builder) { b.AddBytes(ticket) })
+			addEncrypted(b, 6, session.etype, part)
+		})
+	})
+	return b.BytesOrPanic(), nil
+}
+
+// testTicket returns a ticket for the server, whose encrypted part is just
+// the session key, encrypted with the server's key
+func testTicket(server principal, session key) []byte {
+	long := testKeys[server.String()]
+	cipher, _ := long.encrypt(usageTicket, append([]byte{byte(session.etype)}, session.value...))
+	var b cryptobyte.Builder
+	b.AddASN1(application(1), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, 5)
+			addString(b, 1, server.realm)
+			addPrincipal(b, 2, server)
+			addEncrypted(b, 3, long.etype, cipher)
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func (k *testKDC) krbError(code int64, server principal) []byte {
+	var b cryptobyte.Builder
+	b.AddASN1(application(tagError), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, 5)
+			addInt(b, 1, tagError)
+			addTime(b, 4, now())
+			addInt(b, 5, 0)
+			addInt(b, 6, code)
+			addString(b, 9, server.realm)
+			addPrincipal(b, 10, server)
+			addString(b, 11, "LOOKING_UP_SERVER")
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+// writeCCache writes a version 4 credential cache
+func writeCCache(t *testing.T, client principal, creds []credential) string {
+	t.Helper()
+	var b bytes.Buffer
+	u16 := func(v int) { binary.Write(&b, binary.BigEndian, uint16(v)) }
+	u32 := func(v int64) { binary.Write(&b, binary.BigEndian, uint32(v)) }
+	data := func(d []byte) { u32(int64(len(d))); b.Write(d) }
+	writePrincipal := func(p principal) {
+		u32(int64(p.nameType))
+		u32(int64(len(p.components)))
+		data([]byte(p.realm))
+		for _, c := range p.components {
+			data([]byte(c))
+		}
+	}
+
+	u16(0x0504)
+	u16(12)
+	b.Write(make([]byte, 12)) // a KDC time offset tag
+	writePrincipal(client)
+	for _, c := range creds {
+		writePrincipal(c.client)
+		writePrincipal(c.server)
+		u16(int(c.key.etype))
+		data(c.key.value)
+		u32(now().Unix())
+		u32(now().Unix())
+		u32(c.endTime.Unix())
+		u32(0)
+		b.WriteByte(0)
+		u32(0)
+		u32(0)
+		u32(0)
+		data(c.ticket)
+		data(nil)
+	}
+
+	path := filepath.Join(t.TempDir(), "krb5cc")
+	if err := os.WriteFile(path, b.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KRB5CCNAME", "FILE:"+path)
+	return path
+}
+
+// setup starts the KDCs of both realms, writes their krb5.conf, and logs
+// jane in with a TGT that expires at the time
+func setup(t *testing.T, expires time.Time) (corpKDC, appsKDC *testKDC) {
+	mu.Lock()
+	cache = make(map[string]credential)
+	mu.Unlock()
+
+	hosts := map[string]string{"127.0.0.1": corp, "wiki.apps.example.com": apps}
+	conf := "[libdefaults]\n  default_realm = " + corp + "\n[realms]\n"
+	var kdcs []*testKDC
+	for _, realm := range []string{corp, apps} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		kdc := &testKDC{realm: realm, hosts: hosts}
+		go kdc.serve(t, l)
+		kdcs = append(kdcs, kdc)
+		conf += fmt.Sprintf("  %s = {\n    kdc = %s\n  }\n", realm, l.Addr())
+	}
+	path := filepath.Join(t.TempDir(), "krb5.conf")
+	if err := os.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KRB5_CONFIG", path)
+
+	krbtgt := principal{nameType: nameTypeSrvInst, realm: corp, components: []string{"krbtgt", corp}}
+	session := key{etypeAES256, bytes.Repeat([]byte{9}, 32)}
+	writeCCache(t, jane, []credential{
+		{client: jane, server: principal{realm: "X-CACHECONF:", components: []string{"krb5_ccache_conf_data", "pa_type"}}, ticket: []byte("2")},
+		{client: jane, server: krbtgt, key: session, endTime: expires, ticket: testTicket(krbtgt, session)},
+	})
+	return kdcs[0], kdcs[1]
+}
+
+// spnegoServer is an HTTP service of CORP that checks the SPNEGO tokens it
+// is sent
+func spnegoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Negotiate ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Negotiate")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		server, auth, err := parseSPNEGO(token)
+		if err != nil || server.String() != "HTTP/127.0.0.1@"+corp {
+			t.Errorf("Invalid SPNEGO token for %s: %v", server, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "hello %s@%s", strings.Join(auth.CName.NameString, "/"), auth.CRealm)
+	}))
+}
+
+// parseSPNEGO checks the NegTokenInit and its Kerberos AP-REQ
+func parseSPNEGO(token string) (principal, testAuthenticator, error) {
+	der, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return principal{}, testAuthenticator{}, err
+	}
+	var outer asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &outer); err != nil || outer.Class != asn1.ClassApplication || outer.Tag != 0 {
+		return principal{}, testAuthenticator{}, fmt.Errorf("not a GSS-API token")
+	}
+	var mech asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(outer.Bytes, &mech)
+	if err != nil || !mech.Equal(oidSPNEGO) {
+		return principal{}, testAuthenticator{}, fmt.Errorf("not SPNEGO: %v", mech)
+	}
+	var init struct {
+		MechTypes []asn1.ObjectIdentifier `asn1:"explicit,tag:0"`
+		MechToken []byte                  `asn1:"explicit,tag:2"`
+	}
+	if _, err := asn1.UnmarshalWithParams(rest, &init, "explicit,tag:0"); err != nil {
+		return principal{}, testAuthenticator{}, err
+	}
+	if len(init.MechTypes) != 1 || !init.MechTypes[0].Equal(oidKerberos) {
+		return principal{}, testAuthenticator{}, fmt.Errorf("mechanisms %v", init.MechTypes)
+	}
+
+	if _, err := asn1.Unmarshal(init.MechToken, &outer); err != nil {
+		return principal{}, testAuthenticator{}, err
+	}
+	if rest, err = asn1.Unmarshal(outer.Bytes, &mech); err != nil || !mech.Equal(oidKerberos) || !bytes.HasPrefix(rest, []byte{1, 0}) {
+		return principal{}, testAuthenticator{}, fmt.Errorf("not a Kerberos AP-REQ token")
+	}
+	server, _, auth, err := verifyAPReq(rest[2:], usageAPReqAuthenticator)
+	if err != nil {
+		return principal{}, testAuthenticator{}, err
+	}
+	if auth.Checksum.Type != gssChecksumType || len(auth.Checksum.Value) != 24 {
+		return principal{}, testAuthenticator{}, fmt.Errorf("GSS checksum %d", auth.Checksum.Type)
+	}
+	return server, auth, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	request.RegisterAuth(request.NegotiateAuth, Middleware())
+	t.Cleanup(func() { request.RegisterAuth(request.NegotiateAuth, nil) })
+	corpKDC, _ := setup(t, now().Add(8*time.Hour))
+	server := spnegoServer(t)
+	defer server.Close()
+
+	req := request.NewRequestData()
+	req.URL = server.URL
+	req.Auth = request.AuthData{Type: request.NegotiateAuth}
+	for i := 0; i < 2; i++ {
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("ExecuteWith() error = %v", err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Body != "hello jane@"+corp {
+			t.Fatalf("Expected the service to accept the ticket, got %d %s", resp.StatusCode, resp.Body)
+		}
+	}
+	// The service ticket is kept for the second request
+	if len(corpKDC.requests) != 1 || corpKDC.requests[0] != "HTTP/127.0.0.1@"+corp {
+		t.Errorf("Expected one TGS-REQ for the service, got %v", corpKDC.requests)
+	}
+
+	// Other auth types pass through untouched
+	req.Auth = request.AuthData{}
+	if resp, err := req.Execute(); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected no token without negotiate auth, got %v", err)
+	}
+}
+
+func TestToken_Referral(t *testing.T) {
+	corpKDC, appsKDC := setup(t, now().Add(8*time.Hour))
+
+	token, err := Token(context.Background(), "WIKI.apps.example.com")
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if len(corpKDC.requests) != 1 || len(appsKDC.requests) != 1 || appsKDC.requests[0] != "HTTP/wiki.apps.example.com@"+apps {
+		t.Fatalf("Expected to be referred to %s, got %v and %v", apps, corpKDC.requests, appsKDC.requests)
+	}
+	der, _ := base64.StdEncoding.DecodeString(token)
+	if !bytes.Contains(der, []byte("wiki.apps.example.com")) {
+		t.Errorf("Expected a ticket for the wiki, got %x", der)
+	}
+}
+
+func TestToken_Errors(t *testing.T) {
+	setup(t, now().Add(-time.Hour))
+	if _, err := Token(context.Background(), "127.0.0.1"); err == nil || !strings.Contains(err.Error(), "expired") || !strings.Contains(err.Error(), "run kinit") {
+		t.Errorf("Expected an expired ticket to be reported, got %v", err)
+	}
+
+	setup(t, now().Add(time.Hour))
+	if _, err := Token(context.Background(), "unknown.example.com"); err == nil || !strings.Contains(err.Error(), "server not found in the Kerberos database") {
+		t.Errorf("Expected the KDC error to be reported, got %v", err)
+	}
+
+	t.Setenv("KRB5CCNAME", "FILE:"+filepath.Join(t.TempDir(), "missing"))
+	if _, err := Token(context.Background(), "127.0.0.1"); err == nil || !strings.Contains(err.Error(), "no Kerberos credential cache") {
+		t.Errorf("Expected a missing cache to be reported, got %v", err)
+	}
+	t.Setenv("KRB5CCNAME", "KCM:")
+	if _, err := Token(context.Background(), "127.0.0.1"); err == nil || !strings.Contains(err.Error(), "is not a file") {
+		t.Errorf("Expected a KCM cache to be reported, got %v", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	expires := now().Add(8 * time.Hour).Truncate(time.Second)
+	setup(t, expires)
+	user, expiry, err := Login()
+	if err != nil || user != "jane@"+corp || !expiry.Equal(expires) {
+		t.Errorf("Login() = %s, %v, %v", user, expiry, err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg := &config{kdcs: make(map[string][]string), domainRealms: make(map[string]string)}
+	cfg.parse(`
+[libdefaults]
+    default_ccache_name = FILE:/tmp/krb5cc_%{uid}
+# A comment
+[realms]
+    CORP.EXAMPLE.COM = {
+        kdc = dc1.corp.example.com
+        kdc = dc2.corp.example.com:750
+        admin_server = dc1.corp.example.com
+    }
+[domain_realm]
+    .apps.example.com = APPS.EXAMPLE.COM
+    legacy.example.com = CORP.EXAMPLE.COM
+`)
+	if cfg.ccacheName != "FILE:/tmp/krb5cc_%{uid}" {
+		t.Errorf("Unexpected ccache name %q", cfg.ccacheName)
+	}
+	addrs, err := cfg.kdcAddresses(context.Background(), corp)
+	if err != nil || strings.Join(addrs, ",") != "dc1.corp.example.com:88,dc2.corp.example.com:750" {
+		t.Errorf("kdcAddresses() = %v, %v", addrs, err)
+	}
+	for host, want := range map[string]string{
+		"wiki.apps.example.com":  apps,
+		"apps.example.com":       apps,
+		"legacy.example.com":     corp,
+		"www.legacy.example.com": "",
+		"intranet.example.com":   "",
+	} {
+		if got, _ := cfg.realmFor(host); got != want {
+			t.Errorf("realmFor(%s) = %q, want %q", host, got, want)
+		}
+	}
+
+	t.Setenv("KRB5CCNAME", "")
+	if path, err := ccachePath(cfg); err != nil || path != fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()) {
+		t.Errorf("ccachePath() = %s, %v", path, err)
+	}
+}
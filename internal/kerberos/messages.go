@@ -0,0 +1,420 @@
+package kerberos
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// Message types and application tags (RFC 4120 5.10)
+const (
+	tagAuthenticator = 2
+	tagASRepPart     = 25
+	tagTGSReq        = 12
+	tagTGSRep        = 13
+	tagAPReq         = 14
+	tagTGSRepPart    = 26
+	tagError         = 30
+)
+
+// kdcOptionCanonicalize lets the KDC refer lighttr to the realm of the
+// service (RFC 6806)
+const kdcOptionCanonicalize = 0x00010000
+
+// paTGSReq is the pre-authentication data type of the AP-REQ of a TGS-REQ
+const paTGSReq = 1
+
+// gssChecksumType is the checksum type of the authenticator of a GSS-API
+// token (RFC 4121 4.1.1)
+const gssChecksumType = 0x8003
+
+// GSS-API context flags asked for (RFC 2744)
+const (
+	gssConfFlag  = 0x10
+	gssIntegFlag = 0x20
+)
+
+var (
+	oidKerberos = asn1.ObjectIdentifier{1, 2, 840, 113554, 1, 2, 2}
+	oidSPNEGO   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 2}
+)
+
+// maxMessageSize bounds the replies read from a KDC
+const maxMessageSize = 1 << 20
+
+// application returns the tag of an [APPLICATION n] type
+func application(n int) cbasn1.Tag {
+	return cbasn1.Tag(0x40 | n).Constructed()
+}
+
+// explicit returns the tag of an [n] field
+func explicit(n int) cbasn1.Tag {
+	return cbasn1.Tag(n).ContextSpecific().Constructed()
+}
+
+func addInt(b *cryptobyte.Builder, field int, v int64) {
+	b.AddASN1(explicit(field), func(b *cryptobyte.Builder) { b.AddASN1Int64(v) })
+}
+
+func addOctets(b *cryptobyte.Builder, field int, v []byte) {
+	b.AddASN1(explicit(field), func(b *cryptobyte.Builder) { b.AddASN1OctetString(v) })
+}
+
+func addTime(b *cryptobyte.Builder, field int, t time.Time) {
+	b.AddASN1(explicit(field), func(b *cryptobyte.Builder) { b.AddASN1GeneralizedTime(t.UTC().Truncate(time.Second)) })
+}
+
+// addString adds a KerberosString field, a GeneralString
+func addString(b *cryptobyte.Builder, field int, s string) {
+	b.AddASN1(explicit(field), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.Tag(asn1.TagGeneralString), func(b *cryptobyte.Builder) { b.AddBytes([]byte(s)) })
+	})
+}
+
+func addPrincipal(b *cryptobyte.Builder, field int, p principal) {
+	b.AddASN1(explicit(field), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, int64(p.nameType))
+			b.AddASN1(explicit(1), func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for _, component := range p.components {
+						b.AddASN1(cbasn1.Tag(asn1.TagGeneralString), func(b *cryptobyte.Builder) { b.AddBytes([]byte(component)) })
+					}
+				})
+			})
+		})
+	})
+}
+
+func addEncrypted(b *cryptobyte.Builder, field int, etype int32, cipher []byte) {
+	b.AddASN1(explicit(field), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, int64(etype))
+			addOctets(b, 2, cipher)
+		})
+	})
+}
+
+// apRequest returns an AP-REQ presenting the ticket, with an authenticator
+// that carries the checksum, encrypted with the session key for the usage
+func apRequest(c credential, usage uint32, checksumType int32, checksum []byte) ([]byte, error) {
+	t := now()
+	var b cryptobyte.Builder
+	b.AddASN1(application(tagAuthenticator), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, 5)
+			addString(b, 1, c.client.realm)
+			addPrincipal(b, 2, c.client)
+			b.AddASN1(explicit(3), func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					addInt(b, 0, int64(checksumType))
+					addOctets(b, 1, checksum)
+				})
+			})
+			addInt(b, 4, int64(t.Nanosecond()/1000))
+			addTime(b, 5, t)
+		})
+	})
+	authenticator, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := c.key.encrypt(usage, authenticator)
+	if err != nil {
+		return nil, err
+	}
+
+	b = cryptobyte.Builder{}
+	b.AddASN1(application(tagAPReq), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 0, 5)
+			addInt(b, 1, tagAPReq)
+			b.AddASN1(explicit(2), func(b *cryptobyte.Builder) { b.AddASN1BitString(make([]byte, 4)) })
+			b.AddASN1(explicit(3), func(b *cryptobyte.Builder) { b.AddBytes(c.ticket) })
+			addEncrypted(b, 4, c.key.etype, encrypted)
+		})
+	})
+	return b.Bytes()
+}
+
+// spnegoToken returns the SPNEGO token of the Authorization header that
+// presents the service ticket: a NegTokenInit offering Kerberos, with the
+// GSS-API initial context token as its mechToken (RFC 4178, RFC 4121)
+func spnegoToken(c credential) ([]byte, error) {
+	// Lgth, Bnd (no channel bindings), and Flags of RFC 4121 4.1.1
+	checksum := binary.LittleEndian.AppendUint32(nil, 16)
+	checksum = append(checksum, make([]byte, 16)...)
+	checksum = binary.LittleEndian.AppendUint32(checksum, gssConfFlag|gssIntegFlag)
+	req, err := apRequest(c, usageAPReqAuthenticator, gssChecksumType, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(application(0), func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oidKerberos)
+		b.AddBytes([]byte{0x01, 0x00}) // KRB_AP_REQ
+		b.AddBytes(req)
+	})
+	mechToken, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	b = cryptobyte.Builder{}
+	b.AddASN1(application(0), func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oidSPNEGO)
+		b.AddASN1(explicit(0), func(b *cryptobyte.Builder) {
+			b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1(explicit(0), func(b *cryptobyte.Builder) {
+					b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) { b.AddASN1ObjectIdentifier(oidKerberos) })
+				})
+				addOctets(b, 2, mechToken)
+			})
+		})
+	})
+	return b.Bytes()
+}
+
+// principalName is a PrincipalName as decoded
+type principalName struct {
+	NameType   int32    `asn1:"explicit,tag:0"`
+	NameString []string `asn1:"explicit,tag:1"`
+}
+
+func (n principalName) principal(realm string) principal {
+	return principal{nameType: n.NameType, realm: realm, components: n.NameString}
+}
+
+type encryptedData struct {
+	EType  int32  `asn1:"explicit,tag:0"`
+	KVNO   int64  `asn1:"optional,explicit,tag:1"`
+	Cipher []byte `asn1:"explicit,tag:2"`
+}
+
+type encryptionKey struct {
+	KeyType  int32  `asn1:"explicit,tag:0"`
+	KeyValue []byte `asn1:"explicit,tag:1"`
+}
+
+// kdcReply is a TGS-REP
+type kdcReply struct {
+	PVNO    int           `asn1:"explicit,tag:0"`
+	MsgType int           `asn1:"explicit,tag:1"`
+	PAData  asn1.RawValue `asn1:"optional,explicit,tag:2"`
+	CRealm  string        `asn1:"explicit,tag:3"`
+	CName   principalName `asn1:"explicit,tag:4"`
+	Ticket  asn1.RawValue `asn1:"explicit,tag:5"`
+	EncPart encryptedData `asn1:"explicit,tag:6"`
+}
+
+// encKDCRepPart is the encrypted part of a TGS-REP
+type encKDCRepPart struct {
+	Key           encryptionKey  `asn1:"explicit,tag:0"`
+	LastReq       asn1.RawValue  `asn1:"explicit,tag:1"`
+	Nonce         int64          `asn1:"explicit,tag:2"`
+	KeyExpiration time.Time      `asn1:"generalized,optional,explicit,tag:3"`
+	Flags         asn1.BitString `asn1:"explicit,tag:4"`
+	AuthTime      time.Time      `asn1:"generalized,explicit,tag:5"`
+	StartTime     time.Time      `asn1:"generalized,optional,explicit,tag:6"`
+	EndTime       time.Time      `asn1:"generalized,explicit,tag:7"`
+	RenewTill     time.Time      `asn1:"generalized,optional,explicit,tag:8"`
+	SRealm        string         `asn1:"explicit,tag:9"`
+	SName         principalName  `asn1:"explicit,tag:10"`
+	CAddr         asn1.RawValue  `asn1:"optional,explicit,tag:11"`
+	EncPAData     asn1.RawValue  `asn1:"optional,explicit,tag:12"`
+}
+
+// krbError is a KRB-ERROR
+type krbError struct {
+	PVNO      int           `asn1:"explicit,tag:0"`
+	MsgType   int           `asn1:"explicit,tag:1"`
+	CTime     time.Time     `asn1:"generalized,optional,explicit,tag:2"`
+	CUsec     int           `asn1:"optional,explicit,tag:3"`
+	STime     time.Time     `asn1:"generalized,explicit,tag:4"`
+	SUsec     int           `asn1:"explicit,tag:5"`
+	ErrorCode int32         `asn1:"explicit,tag:6"`
+	CRealm    string        `asn1:"optional,explicit,tag:7"`
+	CName     principalName `asn1:"optional,explicit,tag:8"`
+	Realm     string        `asn1:"explicit,tag:9"`
+	SName     principalName `asn1:"explicit,tag:10"`
+	EText     string        `asn1:"optional,explicit,tag:11"`
+	EData     []byte        `asn1:"optional,explicit,tag:12"`
+}
+
+// errorMessages explain the KDC errors users run into
+var errorMessages = map[int32]string{
+	6:  "client not found in the Kerberos database",
+	7:  "server not found in the Kerberos database: check the host name, or ask for an HTTP service principal to be registered for it",
+	12: "KDC policy rejects the request",
+	14: "KDC has no key for the service of an encryption type lighttr supports: only AES is",
+	32: "ticket expired: run kinit",
+	37: "clock skew too great: check the system clock",
+}
+
+// error describes the KDC's error
+func (e krbError) error(spn principal) error {
+	message, ok := errorMessages[e.ErrorCode]
+	if !ok {
+		message = fmt.Sprintf("KDC error %d", e.ErrorCode)
+	}
+	if e.EText != "" {
+		message += " (" + e.EText + ")"
+	}
+	return fmt.Errorf("failed to get a Kerberos ticket for %s: %s", spn, message)
+}
+
+// tgsExchange asks the KDC of the TGT's realm for a ticket for the server
+// principal, presenting the TGT
+func tgsExchange(ctx context.Context, cfg *config, tgt credential, server principal) (credential, error) {
+	realm, _ := tgt.server.tgs()
+	nonceBig, err := rand.Int(rand.Reader, big.NewInt(1<<31))
+	if err != nil {
+		return credential{}, err
+	}
+	nonce := nonceBig.Int64()
+
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(explicit(0), func(b *cryptobyte.Builder) {
+			b.AddASN1BitString(binary.BigEndian.AppendUint32(nil, kdcOptionCanonicalize))
+		})
+		addString(b, 2, realm)
+		addPrincipal(b, 3, server)
+		addTime(b, 5, tgt.endTime)
+		addInt(b, 7, nonce)
+		b.AddASN1(explicit(8), func(b *cryptobyte.Builder) {
+			b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(etypeAES256)
+				b.AddASN1Int64(etypeAES128)
+			})
+		})
+	})
+	body, err := b.Bytes()
+	if err != nil {
+		return credential{}, err
+	}
+	checksum, err := tgt.key.checksum(usageTGSReqChecksum, body)
+	if err != nil {
+		return credential{}, err
+	}
+	apReq, err := apRequest(tgt, usageTGSReqAuthenticator, tgt.key.checksumType(), checksum)
+	if err != nil {
+		return credential{}, err
+	}
+
+	b = cryptobyte.Builder{}
+	b.AddASN1(application(tagTGSReq), func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addInt(b, 1, 5)
+			addInt(b, 2, tagTGSReq)
+			b.AddASN1(explicit(3), func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						addInt(b, 1, paTGSReq)
+						addOctets(b, 2, apReq)
+					})
+				})
+			})
+			b.AddASN1(explicit(4), func(b *cryptobyte.Builder) { b.AddBytes(body) })
+		})
+	})
+	req, err := b.Bytes()
+	if err != nil {
+		return credential{}, err
+	}
+
+	reply, err := sendToKDC(ctx, cfg, realm, req)
+	if err != nil {
+		return credential{}, err
+	}
+	if len(reply) > 0 && reply[0] == byte(application(tagError)) {
+		var e krbError
+		if _, err := asn1.UnmarshalWithParams(reply, &e, fmt.Sprintf("application,explicit,tag:%d", tagError)); err != nil {
+			return credential{}, fmt.Errorf("invalid KRB-ERROR from the KDC of %s: %v", realm, err)
+		}
+		return credential{}, e.error(server)
+	}
+
+	var rep kdcReply
+	if _, err := asn1.UnmarshalWithParams(reply, &rep, fmt.Sprintf("application,explicit,tag:%d", tagTGSRep)); err != nil {
+		return credential{}, fmt.Errorf("invalid TGS-REP from the KDC of %s: %v", realm, err)
+	}
+	plain, err := tgt.key.decrypt(usageTGSRepEncPart, rep.EncPart.Cipher)
+	if err != nil {
+		return credential{}, fmt.Errorf("failed to decrypt the TGS-REP from the KDC of %s: %v", realm, err)
+	}
+	// Some KDCs tag the part as that of an AS-REP
+	var part encKDCRepPart
+	if _, err := asn1.UnmarshalWithParams(plain, &part, fmt.Sprintf("application,explicit,tag:%d", tagTGSRepPart)); err != nil {
+		if _, err := asn1.UnmarshalWithParams(plain, &part, fmt.Sprintf("application,explicit,tag:%d", tagASRepPart)); err != nil {
+			return credential{}, fmt.Errorf("invalid TGS-REP from the KDC of %s: %v", realm, err)
+		}
+	}
+	if part.Nonce != nonce {
+		return credential{}, fmt.Errorf("invalid TGS-REP from the KDC of %s: nonce mismatch", realm)
+	}
+	return credential{
+		client:  rep.CName.principal(rep.CRealm),
+		server:  part.SName.principal(part.SRealm),
+		key:     key{etype: part.Key.KeyType, value: part.Key.KeyValue},
+		endTime: part.EndTime,
+		ticket:  rep.Ticket.Bytes,
+	}, nil
+}
+
+// sendToKDC sends the message to a KDC of the realm over TCP, trying each
+// until one answers
+func sendToKDC(ctx context.Context, cfg *config, realm string, msg []byte) ([]byte, error) {
+	addrs, err := cfg.kdcAddresses(ctx, realm)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		var reply []byte
+		if reply, err = exchange(ctx, addr, msg); err == nil {
+			return reply, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to reach the KDC of %s: %v", realm, err)
+}
+
+// exchange sends the message to the KDC at the address, framed with its
+// length as Kerberos over TCP is (RFC 4120 7.2.2), and reads the reply
+func exchange(ctx context.Context, addr string, msg []byte) ([]byte, error) {
+	conn, err := dialKDC(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(now().Add(kdcTimeout))
+	}
+
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(msg))), msg...)); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header)
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("reply of %d bytes too large", size)
+	}
+	reply := make([]byte, size)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
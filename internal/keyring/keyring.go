@@ -0,0 +1,29 @@
+// Package keyring keeps secrets in the operating system's credential store:
+// the login keychain on macOS, the Secret Service (GNOME Keyring, KWallet)
+// through secret-tool on Linux and the BSDs, and the Credential Manager on
+// Windows.
+package keyring
+
+import "errors"
+
+// Service is the service the secrets are stored under
+const Service = "lighttr"
+
+// ErrNotFound is returned by Get when the account has no secret
+var ErrNotFound = errors.New("secret not found in the system keyring")
+
+// Set stores the secret of the account, replacing any it had
+func Set(account, secret string) error {
+	return set(account, secret)
+}
+
+// Get returns the secret of the account
+func Get(account string) (string, error) {
+	return get(account)
+}
+
+// Delete removes the secret of the account; accounts without one are not
+// an error
+func Delete(account string) error {
+	return remove(account)
+}
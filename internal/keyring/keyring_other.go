@@ -0,0 +1,110 @@
+//go:build !windows
+
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// run executes a keyring command with the given standard input and returns
+// its standard output; replaced in tests
+var run = defaultRun
+
+// commandError is a keyring command that ran and failed
+type commandError struct {
+	name   string
+	code   int
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	if e.stderr != "" {
+		return e.name + " failed: " + e.stderr
+	}
+	return fmt.Sprintf("%s failed: exit status %d", e.name, e.code)
+}
+
+func defaultRun(stdin, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", &commandError{name: name, code: exitErr.ExitCode(), stderr: strings.TrimSpace(stderr.String())}
+		}
+		return "", fmt.Errorf("%s failed: %v", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// securityNotFound is the exit code of macOS's security command for items
+// that do not exist
+const securityNotFound = 44
+
+// notFound reports whether a lookup failed because the account has no
+// secret: security exits with 44, secret-tool with 1 and no message
+func notFound(err error) bool {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	if runtime.GOOS == "darwin" {
+		return cmdErr.code == securityNotFound
+	}
+	return cmdErr.code == 1 && cmdErr.stderr == ""
+}
+
+func set(account, secret string) error {
+	if runtime.GOOS == "darwin" {
+		// Commands are read from standard input, and the secret given in
+		// hex, so it is not in the arguments other users can see
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", quote(Service), quote(account), hex.EncodeToString([]byte(secret)))
+		_, err := run(command, "security", "-i")
+		return err
+	}
+	_, err := run(secret, "secret-tool", "store", "--label", Service+": "+account, "service", Service, "account", account)
+	return err
+}
+
+func get(account string) (string, error) {
+	var out string
+	var err error
+	if runtime.GOOS == "darwin" {
+		out, err = run("", "security", "find-generic-password", "-s", Service, "-a", account, "-w")
+		out = strings.TrimSuffix(out, "\n")
+	} else {
+		out, err = run("", "secret-tool", "lookup", "service", Service, "account", account)
+	}
+	if notFound(err) {
+		return "", ErrNotFound
+	}
+	return out, err
+}
+
+func remove(account string) error {
+	var err error
+	if runtime.GOOS == "darwin" {
+		_, err = run("", "security", "delete-generic-password", "-s", Service, "-a", account)
+	} else {
+		_, err = run("", "secret-tool", "clear", "service", Service, "account", account)
+	}
+	if notFound(err) {
+		return nil
+	}
+	return err
+}
+
+// quote quotes s as a single argument of security's interactive mode,
+// which splits commands like a shell
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
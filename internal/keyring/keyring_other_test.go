@@ -0,0 +1,110 @@
+//go:build !windows
+
+package keyring
+
+import (
+	"encoding/hex"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeKeyring stands in for security and secret-tool, keeping secrets by
+// account
+func fakeKeyring(t *testing.T) map[string]string {
+	secrets := make(map[string]string)
+	run = func(stdin, name string, args ...string) (string, error) {
+		missing := &commandError{name: name, code: 1}
+		if name == "security" {
+			missing.code = securityNotFound
+		}
+		// The account follows -a for security and "account" for secret-tool
+		account := ""
+		for i, arg := range args[:max(len(args)-1, 0)] {
+			if arg == "-a" || arg == "account" {
+				account = args[i+1]
+			}
+		}
+		switch {
+		case name == "security" && args[0] == "-i":
+			start, end := strings.Index(stdin, " -a '"), strings.Index(stdin, "' -X ")
+			if !strings.HasPrefix(stdin, "add-generic-password -U -s 'lighttr'") || start < 0 || end < start {
+				t.Fatalf("Unexpected security command %q", stdin)
+			}
+			secret, err := hex.DecodeString(strings.TrimSpace(stdin[end+5:]))
+			if err != nil {
+				t.Errorf("Expected the secret in hex, got %q", stdin)
+			}
+			secrets[strings.ReplaceAll(stdin[start+5:end], `'\''`, "'")] = string(secret)
+		case args[0] == "store":
+			secrets[account] = stdin
+		case args[0] == "find-generic-password" || args[0] == "lookup":
+			secret, ok := secrets[account]
+			if !ok {
+				return "", missing
+			}
+			if name == "security" {
+				secret += "\n"
+			}
+			return secret, nil
+		case args[0] == "delete-generic-password" || args[0] == "clear":
+			if _, ok := secrets[account]; !ok {
+				return "", missing
+			}
+			delete(secrets, account)
+		default:
+			t.Errorf("Unexpected keyring command %s %v", name, args)
+		}
+		return "", nil
+	}
+	t.Cleanup(func() { run = defaultRun })
+	return secrets
+}
+
+func TestKeyring(t *testing.T) {
+	secrets := fakeKeyring(t)
+
+	if _, err := Get("github.com abc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before the secret is set, got %v", err)
+	}
+	if err := Set("github.com abc", `{"access_token": "it's"}`); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if secrets["github.com abc"] != `{"access_token": "it's"}` {
+		t.Errorf("Expected the secret to be stored under the account, got %v", secrets)
+	}
+	secret, err := Get("github.com abc")
+	if err != nil || secret != `{"access_token": "it's"}` {
+		t.Errorf("Get() = %q, %v", secret, err)
+	}
+	if err := Delete("github.com abc"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := Delete("github.com abc"); err != nil {
+		t.Errorf("Expected deleting a missing secret to succeed, got %v", err)
+	}
+}
+
+func TestKeyring_Unavailable(t *testing.T) {
+	run = func(stdin, name string, args ...string) (string, error) {
+		return "", &commandError{name: name, code: 1, stderr: "Cannot autolaunch D-Bus without X11 $DISPLAY"}
+	}
+	defer func() { run = defaultRun }()
+
+	if runtime.GOOS == "darwin" {
+		t.Skip("security reports missing items with its own exit code")
+	}
+	if _, err := Get("github.com abc"); err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "D-Bus") {
+		t.Errorf("Expected an unavailable keyring to be reported, got %v", err)
+	}
+	if err := Set("github.com abc", "secret"); err == nil {
+		t.Error("Expected Set() to fail without a keyring")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote("it's"); got != `'it'\''s'` {
+		t.Errorf("quote() = %s", got)
+	}
+}
@@ -0,0 +1,310 @@
+// Package lint checks saved collections for problems that do not stop them
+// from running but make them unsafe to share or unreliable to run, such as
+// hard-coded secrets and requests without assertions
+package lint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/internal/runner"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Severity ranks findings: errors fail lighttr lint, warnings only with
+// --strict
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Rules
+const (
+	RuleHardcodedSecret      = "hardcoded-secret"
+	RuleMissingAssertions    = "missing-assertions"
+	RuleUndefinedVariable    = "undefined-variable"
+	RuleUnreachableReference = "unreachable-reference"
+	RuleDuplicateName        = "duplicate-name"
+	RuleUnknownAuthProfile   = "unknown-auth-profile"
+	RuleDeprecatedAuth       = "deprecated-auth"
+)
+
+// Finding is a problem found in a saved request
+type Finding struct {
+	Collection string   `json:"collection"`
+	Request    string   `json:"request"`
+	Rule       string   `json:"rule"`
+	Severity   Severity `json:"severity"`
+	Message    string   `json:"message"`
+}
+
+// String formats the finding as "collection/request: severity: message
+// (rule)"
+func (f Finding) String() string {
+	return fmt.Sprintf("%s/%s: %s: %s (%s)", f.Collection, f.Request, f.Severity, f.Message, f.Rule)
+}
+
+// Options are what the collections are checked against
+type Options struct {
+	// Environments define the variables requests may use; the process
+	// environment is always consulted too
+	Environments []*environment.Environment
+
+	// Profiles are the OAuth2 profiles, by name
+	Profiles map[string]*oauth.Profile
+
+	// AuthProfiles are the names of the saved auth profiles
+	AuthProfiles map[string]bool
+}
+
+// report adds a finding about the request being checked
+type report func(rule string, severity Severity, format string, args ...interface{})
+
+// Collection checks the requests of a collection and returns the findings
+// in request order
+func Collection(c *collection.Collection, opts Options) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+	for i := range c.Requests {
+		saved := &c.Requests[i]
+		add := func(rule string, severity Severity, format string, args ...interface{}) {
+			findings = append(findings, Finding{Collection: c.Name, Request: saved.Name, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if seen[saved.Name] {
+			add(RuleDuplicateName, Error, "another request is named %s, so only the first can be run by name or referenced", saved.Name)
+		}
+		for _, field := range secretFields(&saved.Request) {
+			add(RuleHardcodedSecret, Error, "%s is hard-coded: move it to an environment variable and use a {{placeholder}}", field)
+		}
+		if len(saved.Asserts) == 0 {
+			add(RuleMissingAssertions, Warning, "no assertions: runs only fail on errors and error statuses")
+		}
+		for _, ref := range runner.References(&saved.Request) {
+			switch {
+			case ref == saved.Name:
+				add(RuleUnreachableReference, Error, "uses its own response")
+			case !seen[ref]:
+				if _, ok := c.Find(ref); ok {
+					add(RuleUnreachableReference, Error, "uses the response of %s, which runs after it", ref)
+				} else {
+					add(RuleUnreachableReference, Error, "uses the response of %s, which is not in the collection", ref)
+				}
+			}
+		}
+		checkVariables(saved, opts.Environments, add)
+		checkAuth(saved.Request.Auth, opts, add)
+		seen[saved.Name] = true
+	}
+	return findings
+}
+
+// Failed reports whether the findings fail the lint: any error, or any
+// finding at all when strict
+func Failed(findings []Finding, strict bool) bool {
+	for _, f := range findings {
+		if f.Severity == Error || strict {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderPattern matches {{var}} placeholders and request variables
+var placeholderPattern = regexp.MustCompile(`\{\{[^}]*\}\}`)
+
+// templated reports whether a value takes part of itself from a placeholder,
+// in which case it is not hard-coded
+func templated(value string) bool {
+	return placeholderPattern.MatchString(value)
+}
+
+// secretPatterns match the formats of well-known credentials wherever they
+// appear
+var secretPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"an AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"a GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"a Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"a JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`)},
+}
+
+// sensitiveNames are substrings of the names of headers and query
+// parameters whose values are credentials
+var sensitiveNames = []string{"authorization", "token", "secret", "password", "passwd", "api-key", "api_key", "apikey", "cookie", "session", "signature", "credential"}
+
+// SensitiveName reports whether a header or parameter name suggests its
+// value is a credential
+func SensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateKeyBlock matches a PEM private key with its contents
+var privateKeyBlock = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+
+// MaskSecrets replaces the credentials of well-known formats in text, such
+// as GitHub tokens and JWTs, with ********
+func MaskSecrets(text string) string {
+	text = privateKeyBlock.ReplaceAllString(text, "********")
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, "********")
+	}
+	return text
+}
+
+// secretFields describes the fields of the request that hold literal
+// credentials, sorted
+func secretFields(req *request.RequestData) []string {
+	found := make(map[string]bool)
+	literal := func(value string) bool {
+		return value != "" && !templated(value)
+	}
+
+	auth := req.Auth
+	for _, credential := range []struct{ name, value string }{
+		{"the " + string(auth.Type) + " auth password", auth.Password},
+		{"the bearer token", auth.Token},
+		{"the API key", auth.APIKey},
+		{"the Azure client secret", auth.AzureClientSecret},
+	} {
+		if literal(credential.value) {
+			found[credential.name] = true
+		}
+	}
+	if req.Proxy != nil && literal(req.Proxy.Password) {
+		found["the proxy password"] = true
+	}
+	if u, err := url.Parse(req.URL); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && literal(password) {
+			found["the password in the URL"] = true
+		}
+	}
+	for name, value := range req.Headers {
+		if SensitiveName(name) && literal(value) {
+			found["the "+name+" header"] = true
+		}
+	}
+	for _, params := range []map[string]string{req.QueryParams, req.FormParams} {
+		for name, value := range params {
+			if SensitiveName(name) && literal(value) {
+				found["the "+name+" parameter"] = true
+			}
+		}
+	}
+
+	values := []struct{ name, value string }{{"the URL", req.URL}, {"the body", req.Body}}
+	for name, value := range req.Headers {
+		if !SensitiveName(name) {
+			values = append(values, struct{ name, value string }{"the " + name + " header", value})
+		}
+	}
+	for _, v := range values {
+		for _, secret := range secretPatterns {
+			if secret.pattern.MatchString(v.value) {
+				found[secret.name+" in "+v.name] = true
+			}
+		}
+	}
+
+	fields := make([]string, 0, len(found))
+	for field := range found {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// checkVariables reports the {{var}} placeholders of the request and its
+// assertions that no environment defines, as errors, and those that only
+// some environments define, as warnings
+func checkVariables(saved *collection.SavedRequest, environments []*environment.Environment, add report) {
+	undefined := func(env *environment.Environment) []string {
+		missing := env.ResolveRequest(saved.Request.Clone())
+		for _, assert := range saved.Asserts {
+			_, unresolved := env.Resolve(assert.Value)
+			missing = append(missing, unresolved...)
+		}
+		var names []string
+		for _, name := range missing {
+			// Request variables are resolved by the runner, not
+			// environments
+			if !strings.Contains(name, ".response.") {
+				names = append(names, name)
+			}
+		}
+		return dedupe(names)
+	}
+
+	if len(environments) == 0 {
+		for _, name := range undefined(nil) {
+			add(RuleUndefinedVariable, Error, "{{%s}} is not defined: no environment is saved and it is not set in the process environment", name)
+		}
+		return
+	}
+	missingIn := make(map[string][]string)
+	for _, env := range environments {
+		for _, name := range undefined(env) {
+			missingIn[name] = append(missingIn[name], env.Name)
+		}
+	}
+	names := make([]string, 0, len(missingIn))
+	for name := range missingIn {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(missingIn[name]) == len(environments) {
+			add(RuleUndefinedVariable, Error, "{{%s}} is not defined in any environment", name)
+		} else {
+			add(RuleUndefinedVariable, Warning, "{{%s}} is not defined in environment %s", name, strings.Join(missingIn[name], ", "))
+		}
+	}
+}
+
+// dedupe returns the sorted names without duplicates
+func dedupe(names []string) []string {
+	sort.Strings(names)
+	var unique []string
+	for i, name := range names {
+		if i == 0 || names[i-1] != name {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// checkAuth reports OAuth2 and auth profiles that do not exist and auth
+// settings kept only for compatibility
+func checkAuth(auth request.AuthData, opts Options, add report) {
+	switch auth.Type {
+	case request.OAuth2Auth:
+		if _, ok := opts.Profiles[auth.OAuth2Profile]; !ok {
+			add(RuleUnknownAuthProfile, Error, "OAuth2 profile %s does not exist: create it with lighttr oauth login %s", auth.OAuth2Profile, auth.OAuth2Profile)
+		}
+	case request.ProfileAuth:
+		if !opts.AuthProfiles[auth.Profile] {
+			add(RuleUnknownAuthProfile, Error, "auth profile %s does not exist: save it with lighttr auth save %s", auth.Profile, auth.Profile)
+		}
+	case request.APIKeyAuth:
+		if auth.APIKeyHeader == "" && auth.APIKeyQuery == "" && auth.APIKeyPrefix == "" {
+			add(RuleDeprecatedAuth, Warning, "the API key is sent as Authorization: Bearer only by the default kept from before the key could be placed: name the header, or use bearer auth")
+		}
+	}
+}
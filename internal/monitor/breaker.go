@@ -0,0 +1,119 @@
+package monitor
+
+import "time"
+
+// Circuit breaker defaults
+const (
+	DefaultThreshold  = 5
+	DefaultBackoff    = 30 * time.Second
+	DefaultMaxBackoff = 10 * time.Minute
+)
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	// Closed sends every check
+	Closed State = "closed"
+
+	// Open skips checks until the next probe is due
+	Open State = "open"
+
+	// HalfOpen sends a single probe; it closes the circuit on success and
+	// opens it again, with a longer backoff, on failure
+	HalfOpen State = "half-open"
+)
+
+// Breaker stops checking an endpoint after Threshold consecutive failures
+// and probes it with exponential backoff until it recovers. The zero value
+// uses the defaults.
+type Breaker struct {
+	// Threshold is the number of consecutive failures that open the circuit
+	Threshold int
+
+	// Backoff is the wait before the first probe of an open circuit,
+	// doubled after every failed probe up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	state    State
+	failures int
+	wait     time.Duration
+	probeAt  time.Time
+}
+
+// State returns the state of the circuit
+func (b *Breaker) State() State {
+	if b.state == "" {
+		return Closed
+	}
+	return b.state
+}
+
+// Failures returns the number of consecutive failures
+func (b *Breaker) Failures() int {
+	return b.failures
+}
+
+// NextProbe returns when an open circuit is probed next, or the zero time
+// for a circuit that is not open
+func (b *Breaker) NextProbe() time.Time {
+	if b.state != Open {
+		return time.Time{}
+	}
+	return b.probeAt
+}
+
+// Allow reports whether a check may be sent at now. An open circuit allows
+// one probe once its backoff has passed and turns half-open.
+func (b *Breaker) Allow(now time.Time) bool {
+	if b.state != Open {
+		return true
+	}
+	if now.Before(b.probeAt) {
+		return false
+	}
+	b.state = HalfOpen
+	return true
+}
+
+// Record updates the circuit with the outcome of a check sent at now
+func (b *Breaker) Record(ok bool, now time.Time) {
+	if ok {
+		b.state, b.failures, b.wait = Closed, 0, 0
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == HalfOpen:
+		b.wait = min(2*b.wait, b.maxBackoff())
+	case b.failures >= b.threshold():
+		b.wait = b.backoff()
+	default:
+		return
+	}
+	b.state = Open
+	b.probeAt = now.Add(b.wait)
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold > 0 {
+		return b.Threshold
+	}
+	return DefaultThreshold
+}
+
+func (b *Breaker) backoff() time.Duration {
+	if b.Backoff > 0 {
+		return min(b.Backoff, b.maxBackoff())
+	}
+	return min(DefaultBackoff, b.maxBackoff())
+}
+
+func (b *Breaker) maxBackoff() time.Duration {
+	if b.MaxBackoff > 0 {
+		return b.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	b := &Breaker{Threshold: 3, Backoff: 10 * time.Second, MaxBackoff: 30 * time.Second}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Failures below the threshold keep the circuit closed
+	for i := 0; i < 2; i++ {
+		if !b.Allow(now) {
+			t.Fatal("Expected a closed circuit to allow checks")
+		}
+		b.Record(false, now)
+	}
+	if b.State() != Closed || b.Failures() != 2 {
+		t.Fatalf("Expected a closed circuit with 2 failures, got %s with %d", b.State(), b.Failures())
+	}
+
+	b.Record(false, now)
+	if b.State() != Open || !b.NextProbe().Equal(now.Add(10*time.Second)) {
+		t.Fatalf("Expected the third failure to open the circuit until %v, got %s until %v", now.Add(10*time.Second), b.State(), b.NextProbe())
+	}
+	if b.Allow(now.Add(5 * time.Second)) {
+		t.Error("Expected an open circuit to skip checks before the probe")
+	}
+
+	// A failed probe doubles the backoff, up to the maximum
+	now = now.Add(10 * time.Second)
+	if !b.Allow(now) || b.State() != HalfOpen {
+		t.Fatalf("Expected a probe once the backoff passed, got %s", b.State())
+	}
+	b.Record(false, now)
+	if !b.NextProbe().Equal(now.Add(20 * time.Second)) {
+		t.Errorf("Expected the backoff to double, next probe at %v", b.NextProbe())
+	}
+	now = now.Add(20 * time.Second)
+	b.Allow(now)
+	b.Record(false, now)
+	if !b.NextProbe().Equal(now.Add(30 * time.Second)) {
+		t.Errorf("Expected the backoff to stop at the maximum, next probe at %v", b.NextProbe())
+	}
+
+	// A successful probe closes the circuit
+	now = now.Add(30 * time.Second)
+	b.Allow(now)
+	b.Record(true, now)
+	if b.State() != Closed || b.Failures() != 0 || !b.NextProbe().IsZero() {
+		t.Errorf("Expected a successful probe to close the circuit, got %s with %d failures", b.State(), b.Failures())
+	}
+}
+
+func TestBreaker_Defaults(t *testing.T) {
+	var b Breaker
+	now := time.Now()
+	for i := 0; i < DefaultThreshold; i++ {
+		b.Record(false, now)
+	}
+	if b.State() != Open || !b.NextProbe().Equal(now.Add(DefaultBackoff)) {
+		t.Errorf("Expected the default threshold and backoff, got %s until %v", b.State(), b.NextProbe())
+	}
+}
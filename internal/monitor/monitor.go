@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Check is the outcome of one check of a monitor
+type Check struct {
+	Time         time.Time
+	StatusCode   int
+	ResponseTime time.Duration
+	Error        string
+}
+
+// Failed reports whether the check counts against the circuit: the server
+// could not be reached, failed with a 5xx status, or asked to back off with
+// 429
+func (c Check) Failed() bool {
+	return c.Error != "" || c.StatusCode >= 500 || c.StatusCode == http.StatusTooManyRequests
+}
+
+// String summarizes the check, e.g. "200 84ms"
+func (c Check) String() string {
+	if c.Error != "" {
+		return "error: " + c.Error
+	}
+	return fmt.Sprintf("%d %v", c.StatusCode, c.ResponseTime.Round(time.Millisecond))
+}
+
+// Monitor checks a request, guarded by a circuit breaker
+type Monitor struct {
+	Name    string
+	Request *request.RequestData
+	Breaker Breaker
+
+	// Last is the most recent check sent, nil before the first
+	Last *Check
+
+	// Skipped counts the checks skipped while the circuit was open
+	Skipped int
+}
+
+// Check sends the request through the middleware unless the circuit is
+// open, and reports whether it was sent
+func (m *Monitor) Check(now time.Time, middleware ...request.Middleware) bool {
+	if !m.Breaker.Allow(now) {
+		m.Skipped++
+		return false
+	}
+
+	check := Check{Time: now}
+	resp, err := m.Request.ExecuteWith(middleware...)
+	switch {
+	case err != nil:
+		check.Error = err.Error()
+	case resp.Error != "":
+		check.Error = resp.Error
+		check.ResponseTime = resp.ResponseTime
+	default:
+		check.StatusCode = resp.StatusCode
+		check.ResponseTime = resp.ResponseTime
+	}
+	m.Last = &check
+	m.Breaker.Record(!check.Failed(), now)
+	return true
+}
+
+// WriteList writes a table of the monitors with the state of their circuit,
+// the last check, and when an open circuit is probed next
+func WriteList(w io.Writer, monitors []*Monitor, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tLAST CHECK\tFAILURES\tNEXT PROBE")
+	for _, m := range monitors {
+		last := "-"
+		if m.Last != nil {
+			last = m.Last.String()
+		}
+		next := "-"
+		if probe := m.Breaker.NextProbe(); !probe.IsZero() {
+			next = "in " + max(probe.Sub(now), 0).Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", m.Name, m.Breaker.State(), last, m.Breaker.Failures(), next)
+	}
+	tw.Flush()
+}
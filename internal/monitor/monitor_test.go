@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	req := request.NewRequestData()
+	req.URL = server.URL
+	m := &Monitor{Name: "api/health", Request: req, Breaker: Breaker{Threshold: 2, Backoff: time.Minute}}
+
+	now := time.Now()
+	m.Check(now)
+	m.Check(now)
+	if m.Breaker.State() != Open || hits != 2 {
+		t.Fatalf("Expected two failed checks to open the circuit, got %s after %d hits", m.Breaker.State(), hits)
+	}
+	if m.Check(now.Add(time.Second)) || hits != 2 || m.Skipped != 1 {
+		t.Errorf("Expected the open circuit to skip the check, got %d hits", hits)
+	}
+
+	var list bytes.Buffer
+	WriteList(&list, []*Monitor{m}, now.Add(time.Second))
+	for _, want := range []string{"NAME", "api/health", "open", "503", "in 59s"} {
+		if !strings.Contains(list.String(), want) {
+			t.Errorf("Expected the list to contain %q, got:\n%s", want, list.String())
+		}
+	}
+
+	status = http.StatusOK
+	if !m.Check(now.Add(time.Minute)) || m.Breaker.State() != Closed || m.Last.StatusCode != 200 {
+		t.Errorf("Expected the probe to close the circuit, got %s", m.Breaker.State())
+	}
+}
+
+func TestCheck_Failed(t *testing.T) {
+	tests := []struct {
+		check Check
+		want  bool
+	}{
+		{Check{StatusCode: 200}, false},
+		{Check{StatusCode: 404}, false},
+		{Check{StatusCode: 429}, true},
+		{Check{StatusCode: 502}, true},
+		{Check{Error: "connection refused"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.check.Failed(); got != tt.want {
+			t.Errorf("%+v.Failed() = %v, want %v", tt.check, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// run executes a notifier command; replaced in tests
+var run = defaultRun
+
+func defaultRun(name string, args ...string) error {
+	return exec.Command(name, args...).Run()
+}
+
+// Send shows a desktop notification: with notify-send on Linux and the
+// BSDs, osascript on macOS, and a PowerShell balloon tip on Windows. It
+// returns an error when the notifier is missing or fails.
+func Send(title, message string) error {
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		err = run("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(5000, %s, %s, 'Info')
+Start-Sleep -Seconds 5
+$n.Dispose()`, powerShellString(title), powerShellString(message))
+		err = run("powershell", "-NoProfile", "-Command", script)
+	default:
+		err = run("notify-send", "--app-name=lighttr", title, message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+	return nil
+}
+
+// Bell rings the terminal bell, the fallback when no desktop notification
+// can be shown
+func Bell(w io.Writer) {
+	io.WriteString(w, "\a")
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// powerShellString quotes s as a single-quoted PowerShell string literal
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
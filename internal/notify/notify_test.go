@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	var name string
+	var args []string
+	run = func(n string, a ...string) error {
+		name, args = n, a
+		return nil
+	}
+	defer func() { run = defaultRun }()
+
+	if err := Send(`Lighttr: 200 OK`, `GET "https://example.com" (12s)`); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	command := name + " " + strings.Join(args, " ")
+	switch runtime.GOOS {
+	case "darwin":
+		if name != "osascript" || !strings.Contains(command, `display notification "GET \"https://example.com\" (12s)" with title "Lighttr: 200 OK"`) {
+			t.Errorf("Unexpected notifier command %q", command)
+		}
+	case "windows":
+		if name != "powershell" || !strings.Contains(command, `'Lighttr: 200 OK'`) {
+			t.Errorf("Unexpected notifier command %q", command)
+		}
+	default:
+		if name != "notify-send" || args[len(args)-2] != "Lighttr: 200 OK" || args[len(args)-1] != `GET "https://example.com" (12s)` {
+			t.Errorf("Unexpected notifier command %q", command)
+		}
+	}
+
+	run = func(string, ...string) error { return errors.New("executable file not found") }
+	if err := Send("title", "message"); err == nil {
+		t.Error("Expected an error when the notifier is missing")
+	}
+}
+
+func TestBell(t *testing.T) {
+	var b bytes.Buffer
+	Bell(&b)
+	if b.String() != "\a" {
+		t.Errorf("Bell() wrote %q", b.String())
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	if got := appleScriptString(`say "hi" \ bye`); got != `"say \"hi\" \\ bye"` {
+		t.Errorf("appleScriptString() = %s", got)
+	}
+	if got := powerShellString("it's done"); got != "'it''s done'" {
+		t.Errorf("powerShellString() = %s", got)
+	}
+}
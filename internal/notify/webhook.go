@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// webhookClient posts to webhooks; replaced in tests
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Webhook events
+const (
+	// EventRun is posted when a collection run completes, and
+	// EventRunFailure only when it fails
+	EventRun        = "run"
+	EventRunFailure = "run-failure"
+
+	// EventMonitorFailure is posted when a monitor starts failing and
+	// EventMonitorRecovery when it passes again
+	EventMonitorFailure  = "monitor-failure"
+	EventMonitorRecovery = "monitor-recovery"
+)
+
+// Events lists the webhook events
+var Events = []string{EventRun, EventRunFailure, EventMonitorFailure, EventMonitorRecovery}
+
+// DefaultEvents are posted by webhooks that do not list their events
+var DefaultEvents = []string{EventRun, EventMonitorFailure}
+
+// Webhook posts a summary of collection runs and monitor failures to a
+// Slack or Microsoft Teams incoming webhook
+type Webhook struct {
+	URL string `json:"url"`
+
+	// Format is "slack" or "teams"; guessed from the URL when empty, with
+	// Slack's format, which many chat tools accept, as the fallback
+	Format string `json:"format,omitempty"`
+
+	// On lists the events posted, from Events; DefaultEvents when empty
+	On []string `json:"on,omitempty"`
+
+	// Template is a text/template for the message, executed with the Event;
+	// a summary of the event when empty
+	Template string `json:"template,omitempty"`
+}
+
+// Event is something that happened that webhooks may post about
+type Event struct {
+	// Type is EventRun for runs, failed or not, and EventMonitorFailure or
+	// EventMonitorRecovery for monitors
+	Type string
+
+	// Name is the collection run or the monitor
+	Name string
+
+	// Failed is set for failed runs and failing monitors
+	Failed bool
+
+	// Summary is a one line outcome, e.g. "3 passed, 1 failed in 2.1s",
+	// and Details lists what failed
+	Summary string
+	Details []string
+
+	Time time.Time
+}
+
+// Title returns a headline for the event, e.g. "✗ Run of checkout failed"
+func (e Event) Title() string {
+	switch e.Type {
+	case EventRun:
+		if e.Failed {
+			return "✗ Run of " + e.Name + " failed"
+		}
+		return "✓ Run of " + e.Name + " passed"
+	case EventMonitorRecovery:
+		return "✓ Monitor " + e.Name + " recovered"
+	default:
+		return "✗ Monitor " + e.Name + " is failing"
+	}
+}
+
+// defaultTemplate is the message of webhooks without a template
+const defaultTemplate = `{{.Summary}}{{range .Details}}
+• {{.}}{{end}}`
+
+// Validate checks the webhook's URL, format, events, and template
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", w.URL)
+	}
+	if w.Format != "" && w.Format != "slack" && w.Format != "teams" {
+		return fmt.Errorf("invalid webhook format %q: use slack or teams", w.Format)
+	}
+	for _, event := range w.On {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("invalid webhook event %q: use one of %s", event, strings.Join(Events, ", "))
+		}
+	}
+	if _, err := w.template(); err != nil {
+		return fmt.Errorf("invalid webhook template: %v", err)
+	}
+	return nil
+}
+
+// Wants reports whether the webhook posts the event; failed runs are also
+// run-failure events
+func (w Webhook) Wants(e Event) bool {
+	events := w.On
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	if e.Type == EventRun && e.Failed && slices.Contains(events, EventRunFailure) {
+		return true
+	}
+	return slices.Contains(events, e.Type)
+}
+
+func (w Webhook) template() (*template.Template, error) {
+	text := w.Template
+	if text == "" {
+		text = defaultTemplate
+	}
+	return template.New("webhook").Parse(text)
+}
+
+// format returns the payload format of the webhook
+func (w Webhook) format() string {
+	if w.Format != "" {
+		return w.Format
+	}
+	if u, err := url.Parse(w.URL); err == nil {
+		host := strings.ToLower(u.Hostname())
+		if strings.HasSuffix(host, ".office.com") || strings.HasSuffix(host, ".logic.azure.com") || strings.HasSuffix(host, ".powerplatform.com") {
+			return "teams"
+		}
+	}
+	return "slack"
+}
+
+// Post renders the message for the event and posts it to the webhook
+func (w Webhook) Post(e Event) error {
+	tmpl, err := w.template()
+	if err != nil {
+		return fmt.Errorf("invalid webhook template: %v", err)
+	}
+	var message strings.Builder
+	if err := tmpl.Execute(&message, e); err != nil {
+		return fmt.Errorf("failed to render the webhook message: %v", err)
+	}
+
+	var payload interface{}
+	if w.format() == "teams" {
+		// An Adaptive Card, which both Workflows and the older connectors
+		// accept
+		payload = map[string]interface{}{
+			"type": "message",
+			"attachments": []interface{}{map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"type":    "AdaptiveCard",
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"version": "1.4",
+					"body": []interface{}{
+						map[string]interface{}{"type": "TextBlock", "text": e.Title(), "weight": "Bolder", "size": "Medium", "wrap": true},
+						map[string]interface{}{"type": "TextBlock", "text": message.String(), "wrap": true},
+					},
+				},
+			}},
+		}
+	} else {
+		payload = map[string]string{"text": "*" + e.Title() + "*\n" + message.String()}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := webhookClient.Post(w.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to post to the webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// PostAll posts the event to every webhook that wants it and returns the
+// errors of those that failed
+func PostAll(webhooks []Webhook, e Event) []error {
+	var errs []error
+	for _, w := range webhooks {
+		if !w.Wants(e) {
+			continue
+		}
+		if err := w.Post(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhook_Validate(t *testing.T) {
+	tests := []struct {
+		webhook Webhook
+		wantErr string
+	}{
+		{Webhook{URL: "https://hooks.slack.com/services/T/B/X"}, ""},
+		{Webhook{URL: "https://example.com/hook", Format: "teams", On: []string{EventRunFailure}, Template: "{{.Summary}}"}, ""},
+		{Webhook{URL: "hooks.slack.com/services/T/B/X"}, "invalid webhook URL"},
+		{Webhook{URL: "https://example.com/hook", Format: "discord"}, "invalid webhook format"},
+		{Webhook{URL: "https://example.com/hook", On: []string{"deploy"}}, "invalid webhook event"},
+		{Webhook{URL: "https://example.com/hook", Template: "{{.Summary"}, "invalid webhook template"},
+	}
+	for _, tt := range tests {
+		err := tt.webhook.Validate()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.webhook, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhook_Wants(t *testing.T) {
+	passed := Event{Type: EventRun, Name: "checkout"}
+	failed := Event{Type: EventRun, Name: "checkout", Failed: true}
+	monitor := Event{Type: EventMonitorFailure, Name: "api/health", Failed: true}
+	recovery := Event{Type: EventMonitorRecovery, Name: "api/health"}
+
+	tests := []struct {
+		on   []string
+		want [4]bool
+	}{
+		{nil, [4]bool{true, true, true, false}},
+		{[]string{EventRunFailure}, [4]bool{false, true, false, false}},
+		{[]string{EventMonitorFailure, EventMonitorRecovery}, [4]bool{false, false, true, true}},
+	}
+	for _, tt := range tests {
+		w := Webhook{URL: "https://example.com", On: tt.on}
+		got := [4]bool{w.Wants(passed), w.Wants(failed), w.Wants(monitor), w.Wants(recovery)}
+		if got != tt.want {
+			t.Errorf("Wants() with %v = %v, want %v", tt.on, got, tt.want)
+		}
+	}
+}
+
+func TestWebhook_Post(t *testing.T) {
+	var body map[string]interface{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON post, got %q", r.Header.Get("Content-Type"))
+		}
+		data, _ := io.ReadAll(r.Body)
+		body = nil
+		json.Unmarshal(data, &body)
+		w.WriteHeader(status)
+		io.WriteString(w, "no_text")
+	}))
+	defer server.Close()
+
+	event := Event{Type: EventRun, Name: "checkout", Failed: true, Summary: "1 passed, 1 failed in 2s", Details: []string{"pay: status 500"}}
+
+	if err := (Webhook{URL: server.URL}).Post(event); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if text := body["text"]; text != "*✗ Run of checkout failed*\n1 passed, 1 failed in 2s\n• pay: status 500" {
+		t.Errorf("Unexpected Slack message %q", text)
+	}
+
+	if err := (Webhook{URL: server.URL, Format: "teams", Template: "{{.Name}} {{len .Details}}"}).Post(event); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	card := body["attachments"].([]interface{})[0].(map[string]interface{})["content"].(map[string]interface{})
+	blocks := card["body"].([]interface{})
+	if body["type"] != "message" || card["type"] != "AdaptiveCard" || blocks[0].(map[string]interface{})["text"] != "✗ Run of checkout failed" || blocks[1].(map[string]interface{})["text"] != "checkout 1" {
+		t.Errorf("Unexpected Teams card %v", body)
+	}
+
+	status = http.StatusBadRequest
+	if err := (Webhook{URL: server.URL}).Post(event); err == nil || !strings.Contains(err.Error(), "400 Bad Request: no_text") {
+		t.Errorf("Expected the webhook's error, got %v", err)
+	}
+}
+
+func TestWebhook_Format(t *testing.T) {
+	tests := map[string]string{
+		"https://hooks.slack.com/services/T/B/X":                       "slack",
+		"https://contoso.webhook.office.com/webhookb2/abc":             "teams",
+		"https://prod-01.westus.logic.azure.com/workflows/abc":         "teams",
+		"https://default.environment.api.powerplatform.com/powerautom": "teams",
+		"https://chat.example.com/hooks/abc":                           "slack",
+	}
+	for url, want := range tests {
+		if got := (Webhook{URL: url}).format(); got != want {
+			t.Errorf("format() of %s = %s, want %s", url, got, want)
+		}
+	}
+}
+
+func TestPostAll(t *testing.T) {
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+	}))
+	defer server.Close()
+
+	webhooks := []Webhook{{URL: server.URL}, {URL: server.URL, On: []string{EventMonitorRecovery}}, {URL: "http://127.0.0.1:1/unreachable"}}
+	errs := PostAll(webhooks, Event{Type: EventRun, Name: "checkout"})
+	if posts != 1 || len(errs) != 1 {
+		t.Errorf("Expected one post and one error, got %d posts and %v", posts, errs)
+	}
+}
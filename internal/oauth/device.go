@@ -0,0 +1,146 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// wait pauses between polls of the token endpoint; replaced in tests
+var wait = defaultWait
+
+func defaultWait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deviceGrantType is the grant type of device access token requests
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// deviceAuthorization is the response of the device authorization endpoint
+type deviceAuthorization struct {
+	DeviceCode              string      `json:"device_code"`
+	UserCode                string      `json:"user_code"`
+	VerificationURI         string      `json:"verification_uri"`
+	VerificationURIComplete string      `json:"verification_uri_complete"`
+	ExpiresIn               json.Number `json:"expires_in"`
+	Interval                json.Number `json:"interval"`
+	Error                   string      `json:"error"`
+	ErrorDescription        string      `json:"error_description"`
+
+	// VerificationURL is what Google calls VerificationURI
+	VerificationURL string `json:"verification_url"`
+}
+
+// DeviceLogin runs the device authorization grant for the profile, for
+// when no browser can be sent back to lighttr, e.g. over SSH: it writes
+// the code to enter and the URL to enter it at to out, then polls the
+// token endpoint until the user has approved or denied the login on any
+// device, or the code expires
+func DeviceLogin(ctx context.Context, p *Profile, out io.Writer) (*Token, error) {
+	if p.DeviceAuthURL == "" || p.TokenURL == "" || p.ClientID == "" {
+		return nil, fmt.Errorf("OAuth2 profile %s needs a device authorization URL, token URL, and client ID", p.Name)
+	}
+
+	auth, err := p.authorizeDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verificationURI := auth.VerificationURI
+	if verificationURI == "" {
+		verificationURI = auth.VerificationURL
+	}
+	fmt.Fprintf(out, "To log in, visit %s on any device and enter the code:\n\n  %s\n\n", verificationURI, auth.UserCode)
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintf(out, "Or visit %s, which fills in the code.\n\n", auth.VerificationURIComplete)
+	}
+	fmt.Fprintln(out, "Waiting for the login to be approved...")
+
+	// The code is valid for 30 minutes when the server does not say and
+	// polled every 5 seconds
+	expiresIn, interval := 30*time.Minute, 5*time.Second
+	if seconds, err := auth.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		expiresIn = time.Duration(seconds) * time.Second
+	}
+	if seconds, err := auth.Interval.Int64(); err == nil && seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, expiresIn)
+	defer cancel()
+
+	for {
+		if err := wait(ctx, interval); err != nil {
+			return nil, fmt.Errorf("the device code expired before the login was approved")
+		}
+		token, err := p.requestToken(ctx, url.Values{
+			"grant_type":  {deviceGrantType},
+			"device_code": {auth.DeviceCode},
+		})
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			return token, err
+		}
+		switch tokenErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, fmt.Errorf("the login was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("the device code expired before the login was approved")
+		default:
+			return nil, err
+		}
+	}
+}
+
+// authorizeDevice requests a device and user code
+func (p *Profile) authorizeDevice(ctx context.Context) (*deviceAuthorization, error) {
+	form := url.Values{"client_id": {p.ClientID}}
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.DeviceAuthURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid device authorization URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %v", err)
+	}
+
+	auth := &deviceAuthorization{}
+	if err := json.Unmarshal(body, auth); err != nil {
+		return nil, fmt.Errorf("invalid device authorization response (status %d): %v", resp.StatusCode, err)
+	}
+	if auth.Error != "" {
+		return nil, fmt.Errorf("device authorization failed: %s", oauthError(auth.Error, auth.ErrorDescription))
+	}
+	if auth.DeviceCode == "" || auth.UserCode == "" || (auth.VerificationURI == "" && auth.VerificationURL == "") {
+		return nil, fmt.Errorf("device authorization failed: status %d without a device code, user code, and verification URI", resp.StatusCode)
+	}
+	return auth, nil
+}
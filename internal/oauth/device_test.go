@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deviceServer serves the device authorization endpoint at /device and a
+// token endpoint at /token that answers the polls with the given errors
+// in turn, then with a token
+func deviceServer(t *testing.T, pollErrors ...string) *httptest.Server {
+	polls := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/device":
+			if r.Form.Get("client_id") != "abc" || r.Form.Get("scope") != "repo" {
+				t.Errorf("Unexpected device authorization request %v", r.Form)
+			}
+			io.WriteString(w, `{"device_code": "dc", "user_code": "WDJB-MJHT", "verification_uri": "https://example.com/device", "expires_in": 900, "interval": 1}`)
+		case "/token":
+			if r.Form.Get("grant_type") != deviceGrantType || r.Form.Get("device_code") != "dc" {
+				t.Errorf("Unexpected token request %v", r.Form)
+			}
+			if polls < len(pollErrors) {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error": "`+pollErrors[polls]+`"}`)
+				polls++
+				return
+			}
+			io.WriteString(w, `{"access_token": "at", "token_type": "Bearer"}`)
+		}
+	}))
+}
+
+func TestDeviceLogin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var waits []time.Duration
+	wait = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	defer func() { wait = defaultWait }()
+
+	server := deviceServer(t, "authorization_pending", "slow_down", "authorization_pending")
+	defer server.Close()
+
+	profile := &Profile{Name: "github", DeviceAuthURL: server.URL + "/device", TokenURL: server.URL + "/token", ClientID: "abc", Scopes: []string{"repo"}}
+	var out strings.Builder
+	token, err := DeviceLogin(context.Background(), profile, &out)
+	if err != nil {
+		t.Fatalf("DeviceLogin() error = %v", err)
+	}
+	if token.AccessToken != "at" {
+		t.Errorf("Expected the token, got %+v", token)
+	}
+	if !strings.Contains(out.String(), "visit https://example.com/device") || !strings.Contains(out.String(), "WDJB-MJHT") {
+		t.Errorf("Expected the verification URL and user code, got %q", out.String())
+	}
+	// slow_down adds 5 seconds to the interval
+	want := []time.Duration{time.Second, time.Second, 6 * time.Second, 6 * time.Second}
+	if len(waits) != len(want) {
+		t.Fatalf("Expected polls after %v, got %v", want, waits)
+	}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("Expected polls after %v, got %v", want, waits)
+			break
+		}
+	}
+}
+
+func TestDeviceLogin_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	wait = func(ctx context.Context, d time.Duration) error { return nil }
+	defer func() { wait = defaultWait }()
+
+	tests := []struct {
+		pollError string
+		wantErr   string
+	}{
+		{"access_denied", "the login was denied"},
+		{"expired_token", "device code expired"},
+		{"invalid_client", "token request failed: invalid_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pollError, func(t *testing.T) {
+			server := deviceServer(t, tt.pollError)
+			defer server.Close()
+
+			profile := &Profile{Name: "github", DeviceAuthURL: server.URL + "/device", TokenURL: server.URL + "/token", ClientID: "abc", Scopes: []string{"repo"}}
+			_, err := DeviceLogin(context.Background(), profile, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	_, err := DeviceLogin(context.Background(), &Profile{Name: "github", TokenURL: "https://example.com/token", ClientID: "abc"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "device authorization URL") {
+		t.Errorf("Expected a profile without a device URL to be refused, got %v", err)
+	}
+}
+
+func TestDeviceLogin_Expired(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	wait = func(ctx context.Context, d time.Duration) error { return context.DeadlineExceeded }
+	defer func() { wait = defaultWait }()
+
+	server := deviceServer(t)
+	defer server.Close()
+
+	profile := &Profile{Name: "github", DeviceAuthURL: server.URL + "/device", TokenURL: server.URL + "/token", ClientID: "abc", Scopes: []string{"repo"}}
+	if _, err := DeviceLogin(context.Background(), profile, io.Discard); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected the code to expire, got %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+package oauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+)
+
+// maxLegs is how many of the latest legs the leg log keeps, and
+// maxLegBody how much of a body a leg keeps
+const (
+	maxLegs    = 50
+	maxLegBody = 4096
+)
+
+// masked replaces secrets in legs
+const masked = "********"
+
+// secretFields are the form fields and JSON members of OAuth2 requests and
+// responses that legs mask
+var secretFields = map[string]bool{
+	"client_secret":    true,
+	"client_assertion": true,
+	"assertion":        true,
+	"password":         true,
+	"code":             true,
+	"code_verifier":    true,
+	"device_code":      true,
+	"refresh_token":    true,
+	"access_token":     true,
+	"id_token":         true,
+	"subject_token":    true,
+	"actor_token":      true,
+}
+
+// grantSteps names the step of a token request by its grant type
+var grantSteps = map[string]string{
+	"authorization_code": "token exchange",
+	"refresh_token":      "refresh",
+	"client_credentials": "client credentials",
+	deviceGrantType:      "device token",
+}
+
+// Leg is one step of an OAuth2 flow: the authorization URL the browser is
+// sent to, the redirect back to lighttr, or an exchange with a token or
+// device authorization endpoint. Client secrets, codes, verifiers, and
+// tokens are masked, so legs are safe to show and share.
+type Leg struct {
+	Time     time.Time `json:"time"`
+	Step     string    `json:"step"`
+	ClientID string    `json:"client_id,omitempty"`
+	Method   string    `json:"method,omitempty"`
+	URL      string    `json:"url"`
+
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Duration        time.Duration     `json:"duration,omitempty"`
+
+	// Error is why the leg failed: the OAuth2 error of the response, such
+	// as invalid_scope, or the failure to reach the server
+	Error string `json:"error,omitempty"`
+
+	// Repeats counts the identical legs before this one that it replaced,
+	// such as device token polls answered with authorization_pending
+	Repeats int `json:"repeats,omitempty"`
+}
+
+// Summary describes the leg on one line
+func (l Leg) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %s", l.Time.Local().Format(time.TimeOnly), l.Step)
+	if l.Repeats > 0 {
+		fmt.Fprintf(&b, " (x%d)", l.Repeats+1)
+	}
+	target := l.URL
+	if u, err := url.Parse(l.URL); err == nil && u.Host != "" {
+		target = u.Host + u.Path
+	}
+	if l.Method != "" {
+		target = l.Method + " " + target
+	}
+	b.WriteString("  " + target)
+	if l.Status != 0 {
+		fmt.Fprintf(&b, " -> %d", l.Status)
+	}
+	if l.Error != "" {
+		b.WriteString("  " + l.Error)
+	}
+	return b.String()
+}
+
+// repeats reports whether l is the same step to the same URL with the same
+// outcome as prev
+func (l Leg) repeats(prev Leg) bool {
+	return l.Step == prev.Step && l.ClientID == prev.ClientID && l.URL == prev.URL &&
+		l.Status == prev.Status && l.Error == prev.Error
+}
+
+// legsMu guards the leg log
+var legsMu sync.Mutex
+
+// LegsPath returns the location of the leg log
+func LegsPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "oauth-legs.json"), nil
+}
+
+// Legs returns the legs recorded for the client ID, or for every client
+// when it is empty, oldest first
+func Legs(clientID string) ([]Leg, error) {
+	path, err := LegsPath()
+	if err != nil {
+		return nil, err
+	}
+	legsMu.Lock()
+	defer legsMu.Unlock()
+	legs, err := readLegs(path)
+	if err != nil || clientID == "" {
+		return legs, err
+	}
+	var matched []Leg
+	for _, leg := range legs {
+		if leg.ClientID == clientID {
+			matched = append(matched, leg)
+		}
+	}
+	return matched, nil
+}
+
+func readLegs(path string) ([]Leg, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var legs []Leg
+	if err := json.Unmarshal(data, &legs); err != nil {
+		return nil, fmt.Errorf("failed to parse the OAuth2 leg log: %v", err)
+	}
+	return legs, nil
+}
+
+// recordLeg appends the leg to the leg log, keeping the latest maxLegs.
+// The log is a debugging aid, so failing to keep it never fails the flow.
+func recordLeg(leg Leg) {
+	path, err := LegsPath()
+	if err != nil {
+		return
+	}
+	legsMu.Lock()
+	defer legsMu.Unlock()
+
+	// A log that can't be read is started afresh
+	legs, _ := readLegs(path)
+	if n := len(legs); n > 0 && leg.repeats(legs[n-1]) {
+		leg.Repeats = legs[n-1].Repeats + 1
+		legs[n-1] = leg
+	} else {
+		legs = append(legs, leg)
+	}
+	if len(legs) > maxLegs {
+		legs = legs[len(legs)-maxLegs:]
+	}
+	data, err := json.MarshalIndent(legs, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0600)
+}
+
+// TraceTransport returns a transport that sends requests to authorization
+// servers with next, or http.DefaultTransport when nil, recording each as
+// a leg, e.g. for the token requests of other packages
+func TraceTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return legTransport{next: next}
+}
+
+type legTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request and records it, and the response read ahead
+// of the caller, as a leg
+func (t legTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	leg := Leg{
+		Time:           now(),
+		Step:           "token request",
+		Method:         req.Method,
+		URL:            maskURL(req.URL),
+		RequestHeaders: maskHeaders(req.Header),
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			form, _ := url.ParseQuery(string(data))
+			leg.ClientID = form.Get("client_id")
+			switch step, ok := grantSteps[form.Get("grant_type")]; {
+			case ok:
+				leg.Step = step
+			case form.Get("grant_type") == "":
+				leg.Step = "device authorization"
+			default:
+				leg.Step = form.Get("grant_type")
+			}
+			leg.RequestBody = maskBody(req.Header.Get("Content-Type"), data)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	leg.Duration = time.Since(start)
+	if err != nil {
+		leg.Error = err.Error()
+		recordLeg(leg)
+		return nil, err
+	}
+
+	// The body is read ahead for the leg and handed on to the caller
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	leg.Status = resp.StatusCode
+	leg.ResponseHeaders = maskHeaders(resp.Header)
+	leg.ResponseBody = maskBody(resp.Header.Get("Content-Type"), data)
+	if err != nil {
+		leg.Error = err.Error()
+	} else if parsed, err := parseTokenResponse(resp.Header.Get("Content-Type"), data); err == nil && parsed.Error != "" {
+		// Descriptions of some providers, such as Entra ID, run on with
+		// trace and correlation IDs after the first line
+		description, _, _ := strings.Cut(parsed.ErrorDescription, "\n")
+		leg.Error = oauthError(parsed.Error, strings.TrimSpace(description))
+	}
+	recordLeg(leg)
+	return resp, nil
+}
+
+// maskURL returns the URL with the secrets in its query masked
+func maskURL(u *url.URL) string {
+	clean := *u
+	if query := u.Query(); maskValues(query) {
+		clean.RawQuery = query.Encode()
+	}
+	return clean.String()
+}
+
+// maskValues masks the secrets among values, reporting whether there were
+// any
+func maskValues(values url.Values) bool {
+	found := false
+	for name, vals := range values {
+		if !secretFields[name] {
+			continue
+		}
+		for i := range vals {
+			vals[i] = masked
+		}
+		found = true
+	}
+	return found
+}
+
+// maskHeaders flattens headers, masking credentials but the scheme of
+// Authorization
+func maskHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization", "Proxy-Authorization":
+			scheme, _, _ := strings.Cut(value, " ")
+			value = scheme + " " + masked
+		case "Cookie", "Set-Cookie":
+			value = masked
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// maskBody returns a form or JSON body with its secrets masked, JSON
+// indented, and other bodies as they are, cut at maxLegBody
+func maskBody(contentType string, data []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(data) == 0:
+		return ""
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "text/plain":
+		// Token responses of some providers, such as GitHub, are forms
+		// sent as text/plain
+		if values, err := url.ParseQuery(string(data)); err == nil && (maskValues(values) || mediaType != "text/plain") {
+			return encodeForm(values)
+		}
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || json.Valid(data):
+		var members map[string]any
+		if json.Unmarshal(data, &members) == nil {
+			for name := range members {
+				if secretFields[name] {
+					members[name] = masked
+				}
+			}
+			if indented, err := json.MarshalIndent(members, "", "  "); err == nil {
+				data = indented
+			}
+		}
+	}
+	if len(data) > maxLegBody {
+		return string(data[:maxLegBody]) + "..."
+	}
+	return string(data)
+}
+
+// encodeForm encodes form values sorted by name, leaving masks and spaces
+// readable
+func encodeForm(values url.Values) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
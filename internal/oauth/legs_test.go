@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLegs_Login(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error": "invalid_scope", "error_description": "The scope admin is not allowed\r\nTrace ID: 42"}`)
+	}))
+	defer tokenServer.Close()
+
+	defer fakeBrowser(t, func(auth url.Values) url.Values {
+		return url.Values{"code": {"the-code"}, "state": {auth.Get("state")}}
+	})()
+
+	profile := &Profile{Name: "github", AuthURL: "https://example.com/authorize", TokenURL: tokenServer.URL, ClientID: "abc", ClientSecret: "s3cret", Scopes: []string{"admin"}}
+	if _, err := Login(context.Background(), profile, io.Discard); err == nil {
+		t.Fatal("Expected the login to fail")
+	}
+	recordLeg(Leg{Time: now(), Step: "refresh", ClientID: "other", URL: tokenServer.URL})
+
+	legs, err := Legs("abc")
+	if err != nil {
+		t.Fatalf("Legs() error = %v", err)
+	}
+	if len(legs) != 3 {
+		t.Fatalf("Expected the authorize, callback, and token exchange legs, got %+v", legs)
+	}
+
+	authorize, callback, exchange := legs[0], legs[1], legs[2]
+	if authorize.Step != "authorize" || !strings.Contains(authorize.URL, "scope=admin") {
+		t.Errorf("Unexpected authorize leg %+v", authorize)
+	}
+	if callback.Step != "callback" || strings.Contains(callback.URL, "the-code") || !strings.Contains(callback.URL, "code=%2A%2A%2A%2A%2A%2A%2A%2A") {
+		t.Errorf("Expected the code of the callback to be masked, got %+v", callback)
+	}
+	if exchange.Step != "token exchange" || exchange.Method != http.MethodPost || exchange.Status != http.StatusBadRequest {
+		t.Errorf("Unexpected token exchange leg %+v", exchange)
+	}
+	for _, secret := range []string{"the-code", "s3cret"} {
+		if strings.Contains(exchange.RequestBody, secret) {
+			t.Errorf("Expected %q to be masked, got %q", secret, exchange.RequestBody)
+		}
+	}
+	if !strings.Contains(exchange.RequestBody, "client_secret=********") || !strings.Contains(exchange.RequestBody, "grant_type=authorization_code") {
+		t.Errorf("Expected the masked form, got %q", exchange.RequestBody)
+	}
+	if exchange.Error != "invalid_scope: The scope admin is not allowed" {
+		t.Errorf("Expected the OAuth2 error, got %q", exchange.Error)
+	}
+
+	all, err := Legs("")
+	if err != nil || len(all) != 4 {
+		t.Errorf("Expected the legs of every client, got %d, %v", len(all), err)
+	}
+}
+
+func TestRecordLeg(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	poll := Leg{Step: "device token", ClientID: "abc", URL: "https://example.com/token", Status: 400, Error: "authorization_pending"}
+	for i := 0; i < 3; i++ {
+		recordLeg(poll)
+	}
+	recordLeg(Leg{Step: "device token", ClientID: "abc", URL: "https://example.com/token", Status: 200})
+	legs, err := Legs("abc")
+	if err != nil {
+		t.Fatalf("Legs() error = %v", err)
+	}
+	if len(legs) != 2 || legs[0].Repeats != 2 || legs[1].Repeats != 0 {
+		t.Errorf("Expected repeated polls to be collapsed, got %+v", legs)
+	}
+
+	for i := 0; i < maxLegs+10; i++ {
+		recordLeg(Leg{Step: "refresh", ClientID: "abc", URL: fmt.Sprintf("https://example.com/token/%d", i)})
+	}
+	legs, _ = Legs("abc")
+	if len(legs) != maxLegs || !strings.HasSuffix(legs[len(legs)-1].URL, fmt.Sprintf("/%d", maxLegs+9)) {
+		t.Errorf("Expected the latest %d legs, got %d", maxLegs, len(legs))
+	}
+}
+
+func TestMaskBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"form", "application/x-www-form-urlencoded", "refresh_token=rt&grant_type=refresh_token&scope=repo+user", "grant_type=refresh_token&refresh_token=********&scope=repo user"},
+		{"text form", "text/plain", "access_token=gho_1&scope=repo", "access_token=********&scope=repo"},
+		{"text", "text/plain", "Service Unavailable", "Service Unavailable"},
+		{"json", "application/json", `{"access_token": "at", "expires_in": 60}`, "{\n  \"access_token\": \"********\",\n  \"expires_in\": 60\n}"},
+		{"html", "text/html", "<p>oops</p>", "<p>oops</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskBody(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("maskBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeg_Summary(t *testing.T) {
+	leg := Leg{
+		Time:    time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local),
+		Step:    "device token",
+		Method:  http.MethodPost,
+		URL:     "https://example.com/token?x=1",
+		Status:  400,
+		Error:   "authorization_pending",
+		Repeats: 4,
+	}
+	want := "12:00:00  device token (x5)  POST example.com/token -> 400  authorization_pending"
+	if got := leg.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
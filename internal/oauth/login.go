@@ -0,0 +1,292 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// For testing
+var (
+	openBrowser = defaultOpenBrowser
+	tokenClient = &http.Client{Timeout: 30 * time.Second, Transport: TraceTransport(nil)}
+)
+
+// LoginTimeout bounds how long Login waits for the browser to be sent back
+const LoginTimeout = 5 * time.Minute
+
+func defaultOpenBrowser(u string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", u).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+	default:
+		return exec.Command("xdg-open", u).Start()
+	}
+}
+
+// Login runs the authorization code flow with PKCE for the profile: it
+// listens on a loopback port, opens the authorization URL in the browser,
+// and exchanges the code the browser is sent back with for tokens. The URL
+// is also written to out, for when no browser can be opened.
+func Login(ctx context.Context, p *Profile, out io.Writer) (*Token, error) {
+	if p.AuthURL == "" || p.TokenURL == "" || p.ClientID == "" {
+		return nil, fmt.Errorf("OAuth2 profile %s needs an authorization URL, token URL, and client ID", p.Name)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(p.RedirectPort)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the OAuth2 redirect: %v", err)
+	}
+	defer listener.Close()
+	redirectURL := fmt.Sprintf("http://%s/callback", listener.Addr())
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	authURL, err := p.authCodeURL(redirectURL, state, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(chan callbackResult, 1)
+	server := &http.Server{Handler: callbackHandler(state, codes)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	recordLeg(Leg{Time: now(), Step: "authorize", ClientID: p.ClientID, Method: http.MethodGet, URL: authURL})
+	fmt.Fprintf(out, "Opening the browser to log in. If it does not open, visit:\n\n  %s\n\n", authURL)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Fprintf(out, "Could not open the browser: %v\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LoginTimeout)
+	defer cancel()
+	var result callbackResult
+	select {
+	case result = <-codes:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gave up waiting for the OAuth2 redirect: %v", ctx.Err())
+	}
+	callback := Leg{Time: now(), Step: "callback", ClientID: p.ClientID, Method: http.MethodGet, URL: maskURL(result.redirect)}
+	if result.err != nil {
+		callback.Error = result.err.Error()
+	}
+	recordLeg(callback)
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	return p.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	})
+}
+
+// authCodeURL returns the URL the user authorizes the client at, with the
+// S256 challenge of the PKCE verifier
+func (p *Profile) authCodeURL(redirectURL, state, verifier string) (string, error) {
+	u, err := url.Parse(p.AuthURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", redirectURL)
+	if len(p.Scopes) > 0 {
+		query.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	query.Set("state", state)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// pkceChallenge derives the S256 code challenge from a code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded as unpadded base64url, which
+// is within the characters allowed in a PKCE verifier
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// callbackResult is the authorization code, or the error, the browser was
+// sent back with, and the URL it was sent back to
+type callbackResult struct {
+	code     string
+	err      error
+	redirect *url.URL
+}
+
+// callbackHandler serves the redirect URL, passing on the first response
+// with the expected state
+func callbackHandler(state string, results chan<- callbackResult) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Unexpected state: this is not the login lighttr started.", http.StatusBadRequest)
+			return
+		}
+
+		result := callbackResult{redirect: r.URL}
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization failed: %s", oauthError(query.Get("error"), query.Get("error_description")))
+		case query.Get("code") == "":
+			result.err = fmt.Errorf("authorization failed: no code in the redirect")
+		default:
+			result.code = query.Get("code")
+		}
+
+		message := "Logged in. You can close this tab and return to lighttr."
+		if result.err != nil {
+			message = "Login failed: " + result.err.Error()
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>lighttr</title></head><body><p>%s</p></body></html>", html.EscapeString(message))
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	return mux
+}
+
+// tokenResponse is the body of a token endpoint response, successful or not
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	RefreshToken     string      `json:"refresh_token"`
+	IDToken          string      `json:"id_token"`
+	Scope            string      `json:"scope"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// requestToken posts a grant to the token endpoint, authenticating with
+// the client secret in the body when the profile has one
+func (p *Profile) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", p.ClientID)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := tokenClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %v", err)
+	}
+
+	parsed, err := parseTokenResponse(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token response (status %d): %v", resp.StatusCode, err)
+	}
+	if parsed.Error != "" {
+		return nil, &TokenError{Code: parsed.Error, Description: parsed.ErrorDescription}
+	}
+	if resp.StatusCode != http.StatusOK || parsed.AccessToken == "" {
+		return nil, fmt.Errorf("token request failed: status %d without an access token", resp.StatusCode)
+	}
+
+	token := &Token{
+		AccessToken:  parsed.AccessToken,
+		TokenType:    parsed.TokenType,
+		RefreshToken: parsed.RefreshToken,
+		IDToken:      parsed.IDToken,
+		Scope:        parsed.Scope,
+	}
+	if seconds, err := parsed.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.Expiry = now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// parseTokenResponse reads a token response as JSON or, as some providers
+// such as GitHub send by default, as a form
+func parseTokenResponse(contentType string, body []byte) (*tokenResponse, error) {
+	parsed := &tokenResponse{}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "text/plain" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		parsed.AccessToken = values.Get("access_token")
+		parsed.TokenType = values.Get("token_type")
+		parsed.RefreshToken = values.Get("refresh_token")
+		parsed.IDToken = values.Get("id_token")
+		parsed.Scope = values.Get("scope")
+		parsed.ExpiresIn = json.Number(values.Get("expires_in"))
+		parsed.Error = values.Get("error")
+		parsed.ErrorDescription = values.Get("error_description")
+		return parsed, nil
+	}
+	if err := json.Unmarshal(body, parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// TokenError is an error response of the token endpoint, such as
+// invalid_grant
+type TokenError struct {
+	Code        string
+	Description string
+}
+
+func (e *TokenError) Error() string {
+	return "token request failed: " + oauthError(e.Code, e.Description)
+}
+
+// oauthError formats an OAuth2 error code with its description
+func oauthError(code, description string) string {
+	if description == "" {
+		return code
+	}
+	return code + ": " + description
+}
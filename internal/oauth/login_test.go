@@ -0,0 +1,160 @@
+package oauth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBrowser stands in for the browser: it authorizes at the URL given by
+// following the redirect back with the query returned by respond
+func fakeBrowser(t *testing.T, respond func(auth url.Values) url.Values) func() {
+	openBrowser = func(u string) error {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			t.Errorf("Invalid authorization URL %q: %v", u, err)
+			return err
+		}
+		auth := parsed.Query()
+		go func() {
+			resp, err := http.Get(auth.Get("redirect_uri") + "?" + respond(auth).Encode())
+			if err != nil {
+				t.Errorf("Redirect failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+	return func() { openBrowser = defaultOpenBrowser }
+}
+
+func TestLogin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var challenge string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != "the-code" || r.Form.Get("client_id") != "abc" {
+			t.Errorf("Unexpected token request %v", r.Form)
+		}
+		if !strings.HasPrefix(r.Form.Get("redirect_uri"), "http://127.0.0.1:") {
+			t.Errorf("Expected a loopback redirect URI, got %q", r.Form.Get("redirect_uri"))
+		}
+		if pkceChallenge(r.Form.Get("code_verifier")) != challenge {
+			t.Errorf("Expected the verifier of challenge %q, got %q", challenge, r.Form.Get("code_verifier"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token": "at", "token_type": "Bearer", "refresh_token": "rt", "expires_in": 3600, "scope": "repo"}`)
+	}))
+	defer tokenServer.Close()
+
+	defer fakeBrowser(t, func(auth url.Values) url.Values {
+		if auth.Get("response_type") != "code" || auth.Get("client_id") != "abc" || auth.Get("scope") != "repo user" || auth.Get("code_challenge_method") != "S256" || auth.Get("prompt") != "consent" {
+			t.Errorf("Unexpected authorization request %v", auth)
+		}
+		challenge = auth.Get("code_challenge")
+		return url.Values{"code": {"the-code"}, "state": {auth.Get("state")}}
+	})()
+
+	now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	profile := &Profile{Name: "github", AuthURL: "https://example.com/authorize?prompt=consent", TokenURL: tokenServer.URL, ClientID: "abc", Scopes: []string{"repo", "user"}}
+	var out strings.Builder
+	token, err := Login(context.Background(), profile, &out)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	want := Token{AccessToken: "at", TokenType: "Bearer", RefreshToken: "rt", Scope: "repo", Expiry: time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC)}
+	if *token != want {
+		t.Errorf("Login() = %+v, want %+v", *token, want)
+	}
+	if !strings.Contains(out.String(), "https://example.com/authorize?") {
+		t.Errorf("Expected the authorization URL to be printed, got %q", out.String())
+	}
+}
+
+func TestLogin_Denied(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer fakeBrowser(t, func(auth url.Values) url.Values {
+		return url.Values{"error": {"access_denied"}, "error_description": {"The user said no"}, "state": {auth.Get("state")}}
+	})()
+
+	profile := &Profile{Name: "github", AuthURL: "https://example.com/authorize", TokenURL: "https://example.com/token", ClientID: "abc"}
+	_, err := Login(context.Background(), profile, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "access_denied: The user said no") {
+		t.Errorf("Expected the authorization error, got %v", err)
+	}
+}
+
+func TestLogin_IncompleteProfile(t *testing.T) {
+	_, err := Login(context.Background(), &Profile{Name: "github", ClientID: "abc"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "authorization URL") {
+		t.Errorf("Expected a profile without URLs to be refused, got %v", err)
+	}
+}
+
+func TestCallbackHandler_State(t *testing.T) {
+	results := make(chan callbackResult, 1)
+	handler := callbackHandler("expected", results)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback?code=c&state=forged", nil))
+	if rec.Code != http.StatusBadRequest || len(results) != 0 {
+		t.Errorf("Expected a forged state to be rejected, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback?code=c&state=expected", nil))
+	if result := <-results; result.code != "c" || result.err != nil || !strings.Contains(rec.Body.String(), "Logged in") {
+		t.Errorf("Expected the code to be passed on, got %+v, %q", result, rec.Body.String())
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantToken   string
+		wantErr     string
+	}{
+		{"json", 200, "application/json", `{"access_token": "at", "expires_in": "60"}`, "at", ""},
+		{"form", 200, "application/x-www-form-urlencoded", "access_token=gho_1&scope=repo&token_type=bearer", "gho_1", ""},
+		{"oauth error", 400, "application/json", `{"error": "invalid_grant", "error_description": "Code expired"}`, "", "invalid_grant: Code expired"},
+		{"no token", 500, "text/html", "<html>oops</html>", "", "invalid token response (status 500)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				if r.Form.Get("client_secret") != "s3cret" {
+					t.Errorf("Expected the client secret in the body, got %v", r.Form)
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			profile := &Profile{TokenURL: server.URL, ClientID: "abc", ClientSecret: "s3cret"}
+			token, err := profile.requestToken(context.Background(), url.Values{"grant_type": {"authorization_code"}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || token.AccessToken != tt.wantToken {
+				t.Errorf("requestToken() = %+v, %v, want %s", token, err, tt.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// For testing
+var now = time.Now
+
+// Profile is an OAuth2 client registration, saved under a name so requests
+// can reference it. The tokens obtained with it are cached separately, in
+// the token store.
+type Profile struct {
+	Name         string   `json:"name"`
+	AuthURL      string   `json:"auth_url,omitempty"`
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	// DeviceAuthURL is the device authorization endpoint, for logging in
+	// with the device code flow instead of a browser redirect
+	DeviceAuthURL string `json:"device_auth_url,omitempty"`
+
+	// RedirectPort is the loopback port the authorization code is sent back
+	// to, for providers that require the exact redirect URL to be
+	// registered; a free port is picked when zero
+	RedirectPort int `json:"redirect_port,omitempty"`
+}
+
+// Token is the result of a token request
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"`
+}
+
+// Expired reports whether the access token has expired. Tokens without an
+// expiry never do.
+func (t *Token) Expired() bool {
+	return t.expiresWithin(0)
+}
+
+// expiresWithin reports whether the access token expires within d
+func (t *Token) expiresWithin(d time.Duration) bool {
+	return !t.Expiry.IsZero() && !now().Add(d).Before(t.Expiry)
+}
+
+// Path returns the location of the profile store
+func Path() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "oauth.json"), nil
+}
+
+// Profiles reads the profile store, keyed by profile name, returning no
+// profiles when it does not exist
+func Profiles() (map[string]*Profile, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]*Profile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse OAuth2 profiles: %v", err)
+	}
+	for name, profile := range profiles {
+		profile.Name = name
+	}
+	return profiles, nil
+}
+
+// LoadProfile reads the named profile
+func LoadProfile(name string) (*Profile, error) {
+	profiles, err := Profiles()
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("OAuth2 profile not found: %s", name)
+	}
+	return profile, nil
+}
+
+// SaveProfile adds the profile to the store or replaces the one with the
+// same name
+func SaveProfile(p *Profile) error {
+	profiles, err := Profiles()
+	if err != nil {
+		return err
+	}
+	profiles[p.Name] = p
+
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal OAuth2 profiles: %v", err)
+	}
+	// The store holds client secrets
+	return os.WriteFile(path, data, 0600)
+}
+
+// Middleware returns the provider of oauth2 auth, for
+// request.RegisterAuth, which sends the access token of the profile named
+// by the request as a Bearer token, refreshing it first when it has
+// expired. Requests fail before they are sent when the profile has no token
+// or it has expired and cannot be refreshed.
+func Middleware() request.Middleware {
+	return func(next request.Handler) request.Handler {
+		return func(x *request.Exchange) error {
+			token, err := accessToken(x.Request.Context(), x.Data.Auth.OAuth2Profile)
+			if err != nil {
+				return x.Abort(err)
+			}
+			x.Request.Header.Set("Authorization", "Bearer "+token)
+			return next(x)
+		}
+	}
+}
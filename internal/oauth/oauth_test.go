@@ -0,0 +1,130 @@
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestProfiles_SaveAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	profiles, err := Profiles()
+	if err != nil || len(profiles) != 0 {
+		t.Fatalf("Expected no profiles before any is saved, got %v, %v", profiles, err)
+	}
+
+	saved := &Profile{Name: "github", TokenURL: "https://example.com/token", ClientID: "abc", Scopes: []string{"repo"}}
+	if err := SaveProfile(saved); err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+	if err := SaveProfile(&Profile{Name: "other", ClientID: "xyz"}); err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+
+	profile, err := LoadProfile("github")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if profile.ClientID != "abc" || profile.Scopes[0] != "repo" {
+		t.Errorf("Expected the saved profile back, got %+v", profile)
+	}
+	if _, err := LoadProfile("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a missing profile to be reported, got %v", err)
+	}
+
+	path, _ := Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 && os.PathSeparator == '/' {
+		t.Errorf("Expected the store to be readable by the owner only, got %v", perm)
+	}
+}
+
+func TestToken_Expired(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		expiry time.Time
+		want   bool
+	}{
+		{time.Time{}, false},
+		{time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := (&Token{AccessToken: "t", Expiry: tt.expiry}).Expired(); got != tt.want {
+			t.Errorf("Expired() with expiry %v = %v, want %v", tt.expiry, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	request.RegisterAuth(request.OAuth2Auth, Middleware())
+	t.Cleanup(func() { request.RegisterAuth(request.OAuth2Auth, nil) })
+	t.Setenv("HOME", t.TempDir())
+	fakeKeyring(t)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	send := func(profile string) error {
+		req := request.NewRequestData()
+		req.URL = server.URL
+		req.Auth = request.AuthData{Type: request.OAuth2Auth, OAuth2Profile: profile}
+		_, err := req.Execute()
+		return err
+	}
+
+	github := &Profile{Name: "github", TokenURL: "https://github.com/login/oauth/access_token", ClientID: "abc"}
+	if err := SaveProfile(github); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveToken(github, &Token{AccessToken: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("github"); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if authorization != "Bearer abc" {
+		t.Errorf("Expected the profile's token to be sent, got %q", authorization)
+	}
+
+	if err := SaveProfile(&Profile{Name: "new", TokenURL: "https://github.com/login/oauth/access_token", ClientID: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("new"); err == nil || !strings.Contains(err.Error(), "lighttr oauth login new") {
+		t.Errorf("Expected a profile without a token to ask for a login, got %v", err)
+	}
+
+	stale := &Profile{Name: "stale", TokenURL: "https://github.com/login/oauth/access_token", ClientID: "stale"}
+	if err := SaveProfile(stale); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveToken(stale, &Token{AccessToken: "old", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := send("stale"); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected an expired token to be refused, got %v", err)
+	}
+
+	// Other auth types pass through untouched
+	authorization = ""
+	req := request.NewRequestData()
+	req.URL = server.URL
+	if _, err := req.Execute(); err != nil || authorization != "" {
+		t.Errorf("Expected no Authorization header without oauth2 auth, got %q, %v", authorization, err)
+	}
+}
@@ -0,0 +1,303 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/keyring"
+)
+
+// For testing
+var (
+	keyringSet    = keyring.Set
+	keyringGet    = keyring.Get
+	keyringDelete = keyring.Delete
+)
+
+// refreshSkew is how long before they expire tokens are refreshed, so a
+// token does not expire on the way to the server
+const refreshSkew = 30 * time.Second
+
+// tokensMu serializes reading, refreshing, and writing the token store, so
+// concurrent requests refresh a token once
+var tokensMu sync.Mutex
+
+// CachedToken is an entry of the token store. Tokens are scoped to the
+// host of the token endpoint that issued them and the client they were
+// issued to, so a profile pointed at another tenant or client never picks
+// up a token it did not obtain.
+type CachedToken struct {
+	Host     string `json:"host"`
+	ClientID string `json:"client_id"`
+
+	// Scope, Expiry, and Refreshable describe the token without reading
+	// it from the keyring
+	Scope       string    `json:"scope,omitempty"`
+	Expiry      time.Time `json:"expiry,omitzero"`
+	Refreshable bool      `json:"refreshable,omitempty"`
+
+	// Keyring is set when the token is kept in the system keyring rather
+	// than in Token
+	Keyring bool   `json:"keyring,omitempty"`
+	Token   *Token `json:"token,omitempty"`
+}
+
+// account is the keyring account and store key of the token
+func (c *CachedToken) account() string {
+	return tokenAccount(c.Host, c.ClientID)
+}
+
+func tokenAccount(host, clientID string) string {
+	return host + " " + clientID
+}
+
+// TokenHost returns the host tokens of the profile are scoped to, that of
+// its token endpoint
+func (p *Profile) TokenHost() string {
+	u, err := url.Parse(p.TokenURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// TokensPath returns the location of the token store
+func TokensPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tokens.json"), nil
+}
+
+// readTokens reads the token store, keyed by account
+func readTokens() (map[string]*CachedToken, error) {
+	path, err := TokensPath()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]*CachedToken)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tokens, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse OAuth2 tokens: %v", err)
+	}
+	return tokens, nil
+}
+
+func writeTokens(tokens map[string]*CachedToken) error {
+	path, err := TokensPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal OAuth2 tokens: %v", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// CachedTokens lists the token store by host and client ID
+func CachedTokens() ([]*CachedToken, error) {
+	tokens, err := readTokens()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*CachedToken, 0, len(tokens))
+	for _, token := range tokens {
+		list = append(list, token)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Host != list[j].Host {
+			return list[i].Host < list[j].Host
+		}
+		return list[i].ClientID < list[j].ClientID
+	})
+	return list, nil
+}
+
+// LoadToken returns the cached token of the profile's host and client, or
+// nil when there is none
+func LoadToken(p *Profile) (*Token, error) {
+	tokens, err := readTokens()
+	if err != nil {
+		return nil, err
+	}
+	cached, ok := tokens[tokenAccount(p.TokenHost(), p.ClientID)]
+	if !ok {
+		return nil, nil
+	}
+	if !cached.Keyring {
+		return cached.Token, nil
+	}
+
+	secret, err := keyringGet(cached.account())
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the token from the system keyring: %v", err)
+	}
+	token := &Token{}
+	if err := json.Unmarshal([]byte(secret), token); err != nil {
+		return nil, fmt.Errorf("failed to parse the token from the system keyring: %v", err)
+	}
+	return token, nil
+}
+
+// SaveToken caches the token of the profile's host and client, in the
+// system keyring unless the configuration says otherwise or there is
+// none, and returns the entry
+func SaveToken(p *Profile, t *Token) (*CachedToken, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+	return saveToken(p, t)
+}
+
+func saveToken(p *Profile, t *Token) (*CachedToken, error) {
+	tokens, err := readTokens()
+	if err != nil {
+		return nil, err
+	}
+	cached := &CachedToken{
+		Host:        p.TokenHost(),
+		ClientID:    p.ClientID,
+		Scope:       t.Scope,
+		Expiry:      t.Expiry,
+		Refreshable: t.RefreshToken != "",
+	}
+
+	useKeyring := true
+	if cfg, err := config.Load(); err == nil && cfg.TokenStorage == "file" {
+		useKeyring = false
+	}
+	if useKeyring {
+		secret, err := json.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the token: %v", err)
+		}
+		// Without a keyring, e.g. on a server without a desktop session,
+		// the token goes in the file
+		cached.Keyring = keyringSet(cached.account(), string(secret)) == nil
+	}
+	if !cached.Keyring {
+		cached.Token = t
+		if previous, ok := tokens[cached.account()]; ok && previous.Keyring {
+			keyringDelete(cached.account())
+		}
+	}
+	tokens[cached.account()] = cached
+	if err := writeTokens(tokens); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// RevokeTokens removes the cached tokens of the host, and of the client
+// when clientID is not empty, from the store and the keyring, returning
+// how many were removed. The tokens stay valid at the provider until they
+// expire.
+func RevokeTokens(host, clientID string) (int, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	tokens, err := readTokens()
+	if err != nil {
+		return 0, err
+	}
+	host = strings.ToLower(host)
+	removed := 0
+	for account, cached := range tokens {
+		if cached.Host != host || (clientID != "" && cached.ClientID != clientID) {
+			continue
+		}
+		if cached.Keyring {
+			if err := keyringDelete(account); err != nil {
+				return removed, fmt.Errorf("failed to delete the token from the system keyring: %v", err)
+			}
+		}
+		delete(tokens, account)
+		removed++
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, writeTokens(tokens)
+}
+
+// accessToken returns a usable access token of the named profile,
+// refreshing the cached token with its refresh token when it has expired
+// or is about to
+func accessToken(ctx context.Context, name string) (string, error) {
+	tokensMu.Lock()
+	defer tokensMu.Unlock()
+
+	profile, err := LoadProfile(name)
+	if err != nil {
+		return "", err
+	}
+	token, err := LoadToken(profile)
+	if err != nil {
+		return "", err
+	}
+	if token == nil || token.AccessToken == "" {
+		return "", fmt.Errorf("OAuth2 profile %s has no token: run lighttr oauth login %s", name, name)
+	}
+
+	if token.RefreshToken != "" && token.expiresWithin(refreshSkew) {
+		refreshed, err := profile.refresh(ctx, token)
+		if err != nil {
+			return "", fmt.Errorf("failed to refresh the token of OAuth2 profile %s: %v: run lighttr oauth login %s", name, err, name)
+		}
+		if _, err := saveToken(profile, refreshed); err != nil {
+			return "", fmt.Errorf("failed to cache the refreshed token: %v", err)
+		}
+		token = refreshed
+	}
+	if token.Expired() {
+		return "", fmt.Errorf("the token of OAuth2 profile %s expired at %s: run lighttr oauth login %s", name, token.Expiry.Format(time.RFC3339), name)
+	}
+	return token.AccessToken, nil
+}
+
+// refresh exchanges the token's refresh token for a new token. Providers
+// that do not rotate refresh tokens send none back, so the old one, and the
+// ID token, are kept when the response has none.
+func (p *Profile) refresh(ctx context.Context, t *Token) (*Token, error) {
+	if p.TokenURL == "" || p.ClientID == "" {
+		return nil, fmt.Errorf("OAuth2 profile %s needs a token URL and client ID", p.Name)
+	}
+	refreshed, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {t.RefreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = t.RefreshToken
+	}
+	if refreshed.IDToken == "" {
+		refreshed.IDToken = t.IDToken
+	}
+	return refreshed, nil
+}
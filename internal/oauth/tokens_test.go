@@ -0,0 +1,249 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/keyring"
+)
+
+// fakeKeyring keeps the keyring's secrets in a map for the test
+func fakeKeyring(t *testing.T) map[string]string {
+	secrets := make(map[string]string)
+	keyringSet = func(account, secret string) error {
+		secrets[account] = secret
+		return nil
+	}
+	keyringGet = func(account string) (string, error) {
+		secret, ok := secrets[account]
+		if !ok {
+			return "", keyring.ErrNotFound
+		}
+		return secret, nil
+	}
+	keyringDelete = func(account string) error {
+		delete(secrets, account)
+		return nil
+	}
+	t.Cleanup(func() {
+		keyringSet, keyringGet, keyringDelete = keyring.Set, keyring.Get, keyring.Delete
+	})
+	return secrets
+}
+
+func TestTokens_SaveAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	secrets := fakeKeyring(t)
+
+	github := &Profile{Name: "github", TokenURL: "https://GitHub.com/login/oauth/access_token", ClientID: "abc"}
+	if token, err := LoadToken(github); token != nil || err != nil {
+		t.Fatalf("Expected no token before any is saved, got %v, %v", token, err)
+	}
+
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cached, err := SaveToken(github, &Token{AccessToken: "t", RefreshToken: "r", Expiry: expiry})
+	if err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	if !cached.Keyring || cached.Host != "github.com" || !cached.Refreshable || !cached.Expiry.Equal(expiry) {
+		t.Errorf("Expected the token to be cached in the keyring for github.com, got %+v", cached)
+	}
+	if !strings.Contains(secrets["github.com abc"], `"access_token":"t"`) {
+		t.Errorf("Expected the token in the keyring under the host and client ID, got %v", secrets)
+	}
+	path, _ := TokensPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"access_token"`) {
+		t.Errorf("Expected only the token's description in the file, got %s", data)
+	}
+
+	token, err := LoadToken(github)
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "t" || token.RefreshToken != "r" || !token.Expiry.Equal(expiry) {
+		t.Errorf("Expected the saved token back, got %+v", token)
+	}
+
+	// Another tenant or client does not get the token
+	for _, other := range []*Profile{
+		{Name: "github", TokenURL: "https://github.example.com/login/oauth/access_token", ClientID: "abc"},
+		{Name: "github", TokenURL: "https://github.com/login/oauth/access_token", ClientID: "xyz"},
+	} {
+		if token, err := LoadToken(other); token != nil || err != nil {
+			t.Errorf("Expected no token for %s %s, got %+v, %v", other.TokenHost(), other.ClientID, token, err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 && os.PathSeparator == '/' {
+		t.Errorf("Expected the store to be readable by the owner only, got %v", perm)
+	}
+}
+
+func TestTokens_FileStorage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	secrets := fakeKeyring(t)
+
+	github := &Profile{Name: "github", TokenURL: "https://github.com/token", ClientID: "abc"}
+	if _, err := SaveToken(github, &Token{AccessToken: "t"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a keyring the token goes in the file
+	keyringSet = func(account, secret string) error { return errors.New("no keyring") }
+	cached, err := SaveToken(github, &Token{AccessToken: "t2"})
+	if err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	if cached.Keyring || cached.Token.AccessToken != "t2" {
+		t.Errorf("Expected the token in the file, got %+v", cached)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("Expected the keyring's stale token to be removed, got %v", secrets)
+	}
+	if token, err := LoadToken(github); err != nil || token.AccessToken != "t2" {
+		t.Errorf("Expected the token from the file, got %+v, %v", token, err)
+	}
+
+	// As when the configuration asks for it
+	keyringSet = func(account, secret string) error {
+		t.Error("Expected the keyring not to be used")
+		return nil
+	}
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := SaveToken(github, &Token{AccessToken: "t3"}); err != nil || cached.Keyring {
+		t.Errorf("Expected the token in the file, got %+v, %v", cached, err)
+	}
+}
+
+func TestRevokeTokens(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	secrets := fakeKeyring(t)
+
+	for _, p := range []*Profile{
+		{TokenURL: "https://github.com/token", ClientID: "abc"},
+		{TokenURL: "https://github.com/token", ClientID: "xyz"},
+		{TokenURL: "https://login.example.com/token", ClientID: "abc"},
+	} {
+		if _, err := SaveToken(p, &Token{AccessToken: "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := RevokeTokens("GitHub.com", "xyz"); err != nil || n != 1 {
+		t.Errorf("Expected the client's token to be revoked, got %d, %v", n, err)
+	}
+	if n, err := RevokeTokens("github.com", ""); err != nil || n != 1 {
+		t.Errorf("Expected the host's remaining token to be revoked, got %d, %v", n, err)
+	}
+	if n, err := RevokeTokens("github.com", ""); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to revoke, got %d, %v", n, err)
+	}
+
+	tokens, err := CachedTokens()
+	if err != nil || len(tokens) != 1 || tokens[0].Host != "login.example.com" {
+		t.Errorf("Expected only the other host's token to be left, got %v, %v", tokens, err)
+	}
+	if _, ok := secrets["login.example.com abc"]; !ok || len(secrets) != 1 {
+		t.Errorf("Expected the revoked tokens to be deleted from the keyring, got %v", secrets)
+	}
+}
+
+// refreshServer serves a token endpoint that answers refresh grants with
+// the given response and counts them
+func refreshServer(t *testing.T, status int, response string, refreshes *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "r1" || r.Form.Get("client_id") != "abc" {
+			t.Errorf("Unexpected refresh request %v", r.Form)
+		}
+		*refreshes++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+}
+
+func TestAccessToken_Refresh(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fakeKeyring(t)
+
+	refreshes := 0
+	server := refreshServer(t, http.StatusOK, `{"access_token": "new", "expires_in": 3600}`, &refreshes)
+	defer server.Close()
+
+	github := &Profile{Name: "github", TokenURL: server.URL, ClientID: "abc"}
+	if err := SaveProfile(github); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveToken(github, &Token{AccessToken: "old", RefreshToken: "r1", IDToken: "id", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := accessToken(context.Background(), "github")
+	if err != nil {
+		t.Fatalf("accessToken() error = %v", err)
+	}
+	if token != "new" || refreshes != 1 {
+		t.Errorf("Expected the expired token to be refreshed once, got %q after %d refreshes", token, refreshes)
+	}
+
+	// The refreshed token is cached, keeping the refresh and ID tokens the
+	// response did not replace
+	cached, _ := LoadToken(github)
+	if cached.AccessToken != "new" || cached.RefreshToken != "r1" || cached.IDToken != "id" || cached.Expired() {
+		t.Errorf("Expected the refreshed token to be cached, got %+v", cached)
+	}
+	if _, err := accessToken(context.Background(), "github"); err != nil || refreshes != 1 {
+		t.Errorf("Expected the cached token to be used, got %v after %d refreshes", err, refreshes)
+	}
+
+	// Tokens about to expire are refreshed too
+	if _, err := SaveToken(github, &Token{AccessToken: "old", RefreshToken: "r1", Expiry: time.Now().Add(10 * time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := accessToken(context.Background(), "github"); err != nil || token != "new" || refreshes != 2 {
+		t.Errorf("Expected a token about to expire to be refreshed, got %q, %v", token, err)
+	}
+}
+
+func TestAccessToken_RefreshFails(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fakeKeyring(t)
+
+	refreshes := 0
+	server := refreshServer(t, http.StatusBadRequest, `{"error": "invalid_grant", "error_description": "revoked"}`, &refreshes)
+	defer server.Close()
+
+	github := &Profile{Name: "github", TokenURL: server.URL, ClientID: "abc"}
+	if err := SaveProfile(github); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveToken(github, &Token{AccessToken: "old", RefreshToken: "r1", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := accessToken(context.Background(), "github")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant: revoked") || !strings.Contains(err.Error(), "lighttr oauth login github") {
+		t.Errorf("Expected a failed refresh to ask for a login, got %v", err)
+	}
+	if cached, _ := LoadToken(github); cached.AccessToken != "old" {
+		t.Errorf("Expected a failed refresh to keep the cached token, got %+v", cached)
+	}
+}
@@ -0,0 +1,194 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Item is a request waiting to be sent
+type Item struct {
+	ID       int                 `json:"id"`
+	Request  request.RequestData `json:"request"`
+	QueuedAt time.Time           `json:"queued_at"`
+
+	// Attempts counts the flushes that failed to send the request, and
+	// LastError says why the last one failed
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Result is the outcome of sending a queued request during a flush
+type Result struct {
+	Item     Item
+	Response *request.ResponseData
+	Error    string
+}
+
+// Queue holds requests sent while offline until they are flushed. It is
+// safe for concurrent use, so a flush can run while the queue is shown.
+type Queue struct {
+	mu     sync.Mutex
+	path   string
+	items  []Item
+	nextID int
+}
+
+// Path returns the location of the persistent queue
+func Path() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "queue.json"), nil
+}
+
+// New returns an empty queue that lives in memory only
+func New() *Queue {
+	return &Queue{nextID: 1}
+}
+
+// Open loads the queue stored at path, or starts an empty one there. The
+// queue is saved after every change.
+func Open(path string) (*Queue, error) {
+	q := &Queue{path: path, nextID: 1}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.items); err != nil {
+		return nil, fmt.Errorf("failed to parse queue: %v", err)
+	}
+	for _, item := range q.items {
+		q.nextID = max(q.nextID, item.ID+1)
+	}
+	return q, nil
+}
+
+// Add queues a copy of the request
+func (q *Queue) Add(req *request.RequestData) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := Item{ID: q.nextID, Request: *req.Clone(), QueuedAt: time.Now()}
+	q.nextID++
+	q.items = append(q.items, item)
+	return item, q.save()
+}
+
+// Items returns the queued requests, oldest first
+func (q *Queue) Items() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Item{}, q.items...)
+}
+
+// Len returns the number of queued requests
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Remove drops the request with the given ID from the queue
+func (q *Queue) Remove(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, item := range q.items {
+		if item.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return q.save()
+		}
+	}
+	return fmt.Errorf("no queued request %d", id)
+}
+
+// Clear empties the queue
+func (q *Queue) Clear() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = nil
+	return q.save()
+}
+
+// Flush sends the queued requests in order through the middleware. Sent
+// requests leave the queue, whatever their status code; the others stay
+// with their error. Flushing stops at the first request that shows the
+// network is still unreachable.
+func (q *Queue) Flush(middleware ...request.Middleware) ([]Result, error) {
+	var results []Result
+	for _, item := range q.Items() {
+		result := Result{Item: item}
+		resp, err := item.Request.Clone().ExecuteWith(middleware...)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case resp.Error != "":
+			result.Error = resp.Error
+		default:
+			result.Response = resp
+		}
+		results = append(results, result)
+
+		if result.Response != nil {
+			if err := q.Remove(item.ID); err != nil {
+				return results, err
+			}
+			continue
+		}
+		if err := q.recordFailure(item.ID, result.Error); err != nil {
+			return results, err
+		}
+		if resp != nil && Offline(resp.ErrorKind) {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Offline reports whether a failure means the network, rather than one
+// server, cannot be reached
+func Offline(kind request.ErrorKind) bool {
+	switch kind {
+	case request.ErrorDNS, request.ErrorUnreachable, request.ErrorConnectTimeout:
+		return true
+	}
+	return false
+}
+
+// recordFailure notes a failed attempt to send a queued request
+func (q *Queue) recordFailure(id int, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.items {
+		if q.items[i].ID == id {
+			q.items[i].Attempts++
+			q.items[i].LastError = reason
+		}
+	}
+	return q.save()
+}
+
+// save writes the queue to disk, readable only by the user since queued
+// requests hold credentials. Callers hold the lock.
+func (q *Queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(q.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue: %v", err)
+	}
+	return os.WriteFile(q.path, data, 0600)
+}
@@ -0,0 +1,169 @@
+package queue
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if q.Len() != 0 {
+		t.Fatalf("Expected an empty queue, got %d items", q.Len())
+	}
+
+	for _, url := range []string{"http://example.com/a", "http://example.com/b", "http://example.com/c"} {
+		if _, err := q.Add(&request.RequestData{Method: "POST", URL: url, Body: `{"a":1}`}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := q.Remove(2); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := q.Remove(2); err == nil {
+		t.Error("Expected an error removing a request that is not queued")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the queue to be saved with mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	items := reopened.Items()
+	if len(items) != 2 || items[0].ID != 1 || items[1].ID != 3 {
+		t.Fatalf("Expected requests 1 and 3 to be reloaded, got %+v", items)
+	}
+	if items[0].Request.Body != `{"a":1}` || items[0].QueuedAt.IsZero() {
+		t.Errorf("Expected the request to round-trip, got %+v", items[0])
+	}
+
+	// IDs keep increasing after a reload
+	item, err := reopened.Add(&request.RequestData{Method: "GET", URL: "http://example.com/d"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if item.ID != 4 {
+		t.Errorf("Expected the next ID to be 4, got %d", item.ID)
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if cleared, _ := Open(path); cleared.Len() != 0 {
+		t.Errorf("Expected the cleared queue to be saved, got %d items", cleared.Len())
+	}
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Expected an error for an invalid queue file")
+	}
+}
+
+func TestQueue_AddCopiesRequest(t *testing.T) {
+	q := New()
+	req := &request.RequestData{Method: "GET", URL: "http://example.com", Headers: map[string]string{"X-Trace": "1"}}
+	if _, err := q.Add(req); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	req.Headers["X-Trace"] = "2"
+	if got := q.Items()[0].Request.Headers["X-Trace"]; got != "1" {
+		t.Errorf("Expected the queued request to be a copy, got header %q", got)
+	}
+}
+
+func TestQueue_Flush(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	// A port with nothing listening refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := "http://" + listener.Addr().String()
+	listener.Close()
+
+	q := New()
+	for _, url := range []string{server.URL + "/a", refused, server.URL + "/b"} {
+		if _, err := q.Add(&request.RequestData{Method: "GET", URL: url}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	var sent int
+	counter := request.WithHook(request.HookFuncs{BeforeFunc: func(*http.Request) error {
+		sent++
+		return nil
+	}})
+	results, err := q.Flush(counter)
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(results) != 3 || sent != 3 {
+		t.Fatalf("Expected all three requests to be sent through the middleware, got %d results and %d sends", len(results), sent)
+	}
+
+	// Error statuses count as sent; only the refused request stays queued
+	if results[0].Response == nil || results[0].Response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected the first request to get a 500, got %+v", results[0])
+	}
+	if results[1].Response != nil || results[1].Error == "" {
+		t.Errorf("Expected the second request to fail, got %+v", results[1])
+	}
+	items := q.Items()
+	if len(items) != 1 || items[0].ID != 2 {
+		t.Fatalf("Expected only the refused request to stay queued, got %+v", items)
+	}
+	if items[0].Attempts != 1 || items[0].LastError != results[1].Error {
+		t.Errorf("Expected the failure to be recorded, got %+v", items[0])
+	}
+
+	if _, err := q.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if items := q.Items(); len(items) != 1 || items[0].Attempts != 2 {
+		t.Errorf("Expected a second failed attempt, got %+v", items)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	tests := []struct {
+		kind request.ErrorKind
+		want bool
+	}{
+		{request.ErrorDNS, true},
+		{request.ErrorUnreachable, true},
+		{request.ErrorConnectTimeout, true},
+		{request.ErrorRefused, false},
+		{request.ErrorTLS, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Offline(tt.kind); got != tt.want {
+			t.Errorf("Offline(%q) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+}
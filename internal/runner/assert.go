@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// checkAssertions returns the assertions the response satisfies and a
+// description of each one it does not
+func checkAssertions(asserts []assertion.Assertion, resp *request.ResponseData) (passed, failures []string) {
+	for _, assert := range asserts {
+		if err := assert.Check(resp); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", assert, err))
+		} else {
+			passed = append(passed, assert.String())
+		}
+	}
+	return passed, failures
+}
+
+// resolveAsserts substitutes {{var}} placeholders in the expected values
+// and returns the names that could not be resolved
+func resolveAsserts(asserts []assertion.Assertion, env *environment.Environment) ([]assertion.Assertion, []string) {
+	var missing []string
+	resolved := make([]assertion.Assertion, len(asserts))
+	for i, assert := range asserts {
+		var unresolved []string
+		assert.Value, unresolved = env.Resolve(assert.Value)
+		missing = append(missing, unresolved...)
+		resolved[i] = assert
+	}
+	return resolved, missing
+}
+
+// assertsStatus reports whether any assertion checks the status code, in
+// which case error statuses are expected rather than failures
+func assertsStatus(asserts []assertion.Assertion) bool {
+	for _, assert := range asserts {
+		if assert.Query == "status" {
+			return true
+		}
+	}
+	return false
+}
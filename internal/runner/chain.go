@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// referencePattern matches request variables in the REST Client syntax:
+// {{login.response.body.$.token}}, {{login.response.body.*}}, and
+// {{login.response.headers.Location}}
+var referencePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_\-]+)\.response\.(body|headers)\.([^}]*?)\s*\}\}`)
+
+// References returns the names of the earlier requests whose responses req
+// uses, in sorted order
+func References(req *request.RequestData) []string {
+	names := make(map[string]bool)
+	forEachField(req, func(s string) string {
+		for _, match := range referencePattern.FindAllStringSubmatch(s, -1) {
+			names[match[1]] = true
+		}
+		return s
+	})
+	return sortedNames(names)
+}
+
+// Reference is a request variable that takes a value from the response of
+// an earlier request
+type Reference struct {
+	// Request is the name of the request whose response is used
+	Request string
+
+	// Source is "body" or "headers" and Path the JSONPath, "*", or header
+	// name within it
+	Source string
+	Path   string
+}
+
+// String returns the value the reference takes, e.g. "body.$.token"
+func (r Reference) String() string {
+	return r.Source + "." + r.Path
+}
+
+// ReferencesOf returns the request variables req uses, sorted by request
+// and value, without duplicates
+func ReferencesOf(req *request.RequestData) []Reference {
+	seen := make(map[Reference]bool)
+	var refs []Reference
+	forEachField(req, func(s string) string {
+		for _, match := range referencePattern.FindAllStringSubmatch(s, -1) {
+			ref := Reference{Request: match[1], Source: match[2], Path: match[3]}
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+		return s
+	})
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Request != refs[j].Request {
+			return refs[i].Request < refs[j].Request
+		}
+		return refs[i].String() < refs[j].String()
+	})
+	return refs
+}
+
+// Node is a request of a collection in its dependency graph
+type Node struct {
+	Name string
+
+	// Uses lists the request variables the request takes from earlier
+	// requests
+	Uses []Reference
+
+	// Depth is 0 for requests without dependencies and otherwise one more
+	// than the depth of the deepest request they depend on
+	Depth int
+}
+
+// Graph returns the dependency graph of a collection, one node per request
+// in run order
+func Graph(c *collection.Collection) []Node {
+	depths := make(map[string]int)
+	nodes := make([]Node, 0, len(c.Requests))
+	for i := range c.Requests {
+		node := Node{Name: c.Requests[i].Name, Uses: ReferencesOf(&c.Requests[i].Request)}
+		for _, ref := range node.Uses {
+			if depth, ok := depths[ref.Request]; ok {
+				node.Depth = max(node.Depth, depth+1)
+			}
+		}
+		depths[node.Name] = node.Depth
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Select returns a copy of the collection with only the named request and
+// the earlier requests it depends on, in their original order
+func Select(c *collection.Collection, name string) (*collection.Collection, error) {
+	if _, ok := c.Find(name); !ok {
+		return nil, fmt.Errorf("request not found: %s", name)
+	}
+
+	needed := map[string]bool{name: true}
+	for i := len(c.Requests) - 1; i >= 0; i-- {
+		saved := &c.Requests[i]
+		if !needed[saved.Name] {
+			continue
+		}
+		for _, dependency := range References(&saved.Request) {
+			needed[dependency] = true
+		}
+	}
+
+	selected := *c
+	selected.Requests = nil
+	for _, saved := range c.Requests {
+		if needed[saved.Name] {
+			selected.Requests = append(selected.Requests, saved)
+		}
+	}
+	return &selected, nil
+}
+
+// resolveReferences substitutes request variables with values from earlier
+// responses and returns the placeholders that could not be resolved
+func resolveReferences(req *request.RequestData, responses map[string]*request.ResponseData) []string {
+	missing := make(map[string]bool)
+	forEachField(req, func(s string) string {
+		return referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+			parts := referencePattern.FindStringSubmatch(match)
+			name, source, path := parts[1], parts[2], parts[3]
+
+			if resp, ok := responses[name]; ok {
+				if value, ok := responseValue(resp, source, path); ok {
+					return value
+				}
+			}
+			missing[strings.Trim(match, "{} ")] = true
+			return match
+		})
+	})
+	return sortedNames(missing)
+}
+
+// responseValue extracts a header or a JSON body value from a response
+func responseValue(resp *request.ResponseData, source, path string) (string, bool) {
+	if source == "headers" {
+		for key, value := range resp.Headers {
+			if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(path) {
+				return value, true
+			}
+		}
+		return "", false
+	}
+
+	if path == "*" {
+		return resp.Body, true
+	}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(resp.Body), &doc); err != nil {
+		return "", false
+	}
+	value, ok := request.LookupJSONPath(doc, path)
+	if !ok {
+		return "", false
+	}
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// forEachField rewrites every templated field of the request with f
+func forEachField(req *request.RequestData, f func(string) string) {
+	req.URL = f(req.URL)
+	req.Body = f(req.Body)
+	for _, values := range []map[string]string{req.Headers, req.QueryParams, req.PathParams, req.FormParams} {
+		for k, v := range values {
+			values[k] = f(v)
+		}
+	}
+	for i := range req.Multipart {
+		req.Multipart[i].Value = f(req.Multipart[i].Value)
+	}
+}
+
+// sortedNames returns the keys of a set in sorted order
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
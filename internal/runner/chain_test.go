@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestResolveReferences(t *testing.T) {
+	responses := map[string]*request.ResponseData{
+		"login": {
+			Headers: map[string]string{"Location": "/sessions/9"},
+			Body:    `{"token": "abc", "user": {"roles": ["admin", "dev"], "id": 7}}`,
+		},
+	}
+
+	req := &request.RequestData{
+		URL:         "https://api.example.com{{login.response.headers.location}}",
+		Headers:     map[string]string{"Authorization": "Bearer {{login.response.body.$.token}}"},
+		QueryParams: map[string]string{"role": "{{ login.response.body.$.user.roles[1] }}"},
+		PathParams:  map[string]string{},
+		Body:        `{"user": {{login.response.body.$.user}}, "missing": "{{login.response.body.$.nope}}", "other": "{{signup.response.body.*}}"}`,
+	}
+
+	missing := resolveReferences(req, responses)
+	if req.URL != "https://api.example.com/sessions/9" {
+		t.Errorf("URL = %q", req.URL)
+	}
+	if req.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("Authorization = %q", req.Headers["Authorization"])
+	}
+	if req.QueryParams["role"] != "dev" {
+		t.Errorf("role = %q", req.QueryParams["role"])
+	}
+	wantBody := `{"user": {"id":7,"roles":["admin","dev"]}, "missing": "{{login.response.body.$.nope}}", "other": "{{signup.response.body.*}}"}`
+	if req.Body != wantBody {
+		t.Errorf("Body = %s, want %s", req.Body, wantBody)
+	}
+
+	wantMissing := []string{"login.response.body.$.nope", "signup.response.body.*"}
+	if !reflect.DeepEqual(missing, wantMissing) {
+		t.Errorf("missing = %v, want %v", missing, wantMissing)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	c := &collection.Collection{
+		Name: "flow",
+		Requests: []collection.SavedRequest{
+			{Name: "login", Request: request.RequestData{URL: "https://api.example.com/login"}},
+			{Name: "health", Request: request.RequestData{URL: "https://api.example.com/health"}},
+			{Name: "profile", Request: request.RequestData{URL: "https://api.example.com/users/{{login.response.body.$.id}}"}},
+			{Name: "orders", Request: request.RequestData{
+				URL:     "https://api.example.com/orders",
+				Headers: map[string]string{"X-User": "{{profile.response.body.$.name}}"},
+			}},
+		},
+	}
+
+	selected, err := Select(c, "orders")
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	var names []string
+	for _, saved := range selected.Requests {
+		names = append(names, saved.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"login", "profile", "orders"}) {
+		t.Errorf("Expected the request and its dependencies in order, got %v", names)
+	}
+	if len(c.Requests) != 4 {
+		t.Error("Expected the original collection to be unchanged")
+	}
+
+	if _, err := Select(c, "missing"); err == nil {
+		t.Error("Expected error for an unknown request")
+	}
+}
+
+func TestGraph(t *testing.T) {
+	c := &collection.Collection{
+		Name: "flow",
+		Requests: []collection.SavedRequest{
+			{Name: "login", Request: request.RequestData{URL: "https://api.example.com/login"}},
+			{Name: "health", Request: request.RequestData{URL: "https://api.example.com/health"}},
+			{Name: "profile", Request: request.RequestData{
+				URL:     "https://api.example.com/users/{{login.response.body.$.id}}",
+				Headers: map[string]string{"Authorization": "Bearer {{login.response.body.$.token}}"},
+			}},
+			{Name: "orders", Request: request.RequestData{
+				URL: "https://api.example.com{{profile.response.headers.Location}}",
+				Headers: map[string]string{
+					"Authorization": "Bearer {{login.response.body.$.token}}",
+					"X-Trace":       "{{ login.response.body.$.token }}",
+				},
+			}},
+		},
+	}
+
+	want := []Node{
+		{Name: "login"},
+		{Name: "health"},
+		{Name: "profile", Depth: 1, Uses: []Reference{
+			{Request: "login", Source: "body", Path: "$.id"},
+			{Request: "login", Source: "body", Path: "$.token"},
+		}},
+		{Name: "orders", Depth: 2, Uses: []Reference{
+			{Request: "login", Source: "body", Path: "$.token"},
+			{Request: "profile", Source: "headers", Path: "Location"},
+		}},
+	}
+	if got := Graph(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("Graph() = %+v, want %+v", got, want)
+	}
+	if ref := want[3].Uses[1].String(); ref != "headers.Location" {
+		t.Errorf("Reference.String() = %q", ref)
+	}
+}
+
+func TestRun_ChainedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token": "secret"}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name: "flow",
+		Requests: []collection.SavedRequest{
+			{Name: "login", Request: request.RequestData{Method: "POST", URL: server.URL + "/login"}},
+			{Name: "me", Request: request.RequestData{
+				Method:  "GET",
+				URL:     server.URL + "/me",
+				Headers: map[string]string{"Authorization": "Bearer {{login.response.body.$.token}}"},
+			}},
+		},
+	}
+
+	var events []string
+	report := Run(c, Options{
+		OnStart:  func(name string) { events = append(events, "start "+name) },
+		OnResult: func(result Result) { events = append(events, "done "+result.Name) },
+	})
+	if report.Failed() {
+		t.Errorf("Expected the chained request to be authorized, got %+v", report.Results)
+	}
+	if want := []string{"start login", "done login", "start me", "done me"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("Expected progress callbacks %v, got %v", want, events)
+	}
+}
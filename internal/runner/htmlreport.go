@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// htmlReport is what the HTML report template renders
+type htmlReport struct {
+	*Report
+	Passed, Failed, Warned int
+	AssertsPassed          int
+	AssertsTotal           int
+	Slowest                time.Duration
+	Steps                  []htmlStep
+}
+
+// htmlStep is a request of the run with its place on the timeline
+type htmlStep struct {
+	Result
+	Status   string
+	Segments []WaterfallSegment
+}
+
+// WriteHTML writes the report as a self-contained HTML page with the
+// summary, the status, assertions, and response of every request, and
+// charts of their timing, to attach to CI artifacts or share
+func (r *Report) WriteHTML(w io.Writer) error {
+	data := htmlReport{Report: r}
+	steps := r.Waterfall().Steps
+	for i, result := range r.Results {
+		status := result.Status(r.EnforceBudgets)
+		switch status {
+		case "FAIL":
+			data.Failed++
+		case "WARN":
+			data.Warned++
+		default:
+			data.Passed++
+		}
+		data.AssertsPassed += len(result.Passed)
+		data.AssertsTotal += len(result.Passed) + len(result.Failures)
+		data.Slowest = max(data.Slowest, result.ResponseTime)
+		data.Steps = append(data.Steps, htmlStep{Result: result, Status: status, Segments: steps[i].Segments()})
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(templateFuncs).Funcs(template.FuncMap{
+	"shift": func(segment WaterfallSegment, start time.Duration) WaterfallSegment {
+		segment.Start -= start
+		return segment
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Collection}} run report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+.summary { display: flex; gap: 1em; margin: 1.5em 0; }
+.summary div { border: 1px solid #ddd; border-radius: 6px; padding: 0.6em 1em; }
+.summary b { display: block; font-size: 1.6em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { padding: 4px 8px; text-align: left; white-space: nowrap; vertical-align: top; }
+th { border-bottom: 1px solid #ccc; }
+td.track { width: 100%; }
+.track div { position: relative; height: 16px; background: #f4f4f4; }
+.track span { position: absolute; top: 0; height: 16px; min-width: 1px; }
+.legend span { display: inline-block; width: 12px; height: 12px; margin: 0 4px 0 12px; vertical-align: middle; }
+.dns { background: #2a9d8f; } .connect { background: #e9c46a; } .tls { background: #9b5de5; }
+.ttfb { background: #8ecae6; } .transfer { background: #219ebc; } .other { background: #adb5bd; }
+.PASS { color: #1e8449; } .FAIL { color: #c0392b; } .WARN { color: #d68910; }
+details { border: 1px solid #ddd; border-radius: 6px; margin: 0.5em 0; padding: 0.5em 1em; }
+summary { cursor: pointer; }
+ul { margin: 0.3em 0; }
+pre { background: #f4f4f4; padding: 0.8em; overflow-x: auto; max-height: 20em; }
+</style>
+</head>
+<body>
+<h1>{{.Collection}}</h1>
+<p>Run on {{.Started.Format "2006-01-02 15:04:05 MST"}}, wall time {{ms .WallTime}}{{if .Throttled}}, throttled for {{ms .Throttled}}{{end}}</p>
+
+<div class="summary">
+<div><b>{{len .Results}}</b>requests</div>
+<div class="PASS"><b>{{.Passed}}</b>passed</div>
+<div class="FAIL"><b>{{.Failed}}</b>failed</div>
+{{- if .Warned}}
+<div class="WARN"><b>{{.Warned}}</b>over budget</div>
+{{- end}}
+<div><b>{{.AssertsPassed}}/{{.AssertsTotal}}</b>assertions passed</div>
+</div>
+
+<h2>Timeline</h2>
+<table>
+<tr><th>Request</th><th>Status</th><th>Time</th><th>Timeline</th></tr>
+{{- $wall := .WallTime}}
+{{- range .Steps}}
+<tr>
+<td>{{.Name}}</td>
+<td class="{{.Status}}">{{if .Error}}error{{else}}{{.StatusCode}}{{end}}</td>
+<td>{{ms .ResponseTime}}</td>
+<td class="track"><div>
+{{- range .Segments}}<span class="{{.Class}}" style="left: {{percent .Start $wall}}%; width: {{percent .Duration $wall}}%" title="{{.Phase}}: {{ms .Duration}}"></span>{{end -}}
+</div></td>
+</tr>
+{{- end}}
+</table>
+
+<h2>Response Times</h2>
+<table>
+{{- $slowest := .Slowest}}
+{{- range .Steps}}
+{{- $start := .Start}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{ms .ResponseTime}}</td>
+<td class="track"><div>
+{{- range .Segments}}{{with shift . $start}}<span class="{{.Class}}" style="left: {{percent .Start $slowest}}%; width: {{percent .Duration $slowest}}%" title="{{.Phase}}: {{ms .Duration}}"></span>{{end}}{{end -}}
+</div></td>
+</tr>
+{{- end}}
+</table>
+<p class="legend"><span class="dns"></span>DNS<span class="connect"></span>Connect<span class="tls"></span>TLS<span class="ttfb"></span>Waiting (TTFB)<span class="transfer"></span>Transfer<span class="other"></span>Other</p>
+
+<h2>Requests</h2>
+{{- range .Steps}}
+<details{{if eq .Status "FAIL"}} open{{end}}>
+<summary><span class="{{.Status}}">{{.Status}}</span> {{.Name}} {{if .Error}}error{{else}}{{.StatusCode}} in {{ms .ResponseTime}}, {{.BodySize}} B{{end}}</summary>
+{{- if .URL}}
+<p><code>{{.Method}} {{.URL}}</code></p>
+{{- end}}
+{{- if .Error}}
+<p class="FAIL">{{.Error}}</p>
+{{- end}}
+{{- if or .Passed .Failures}}
+<p>Assertions</p>
+<ul>
+{{- range .Failures}}
+<li class="FAIL">✗ {{.}}</li>
+{{- end}}
+{{- range .Passed}}
+<li class="PASS">✓ {{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Violations}}
+<p>Budget</p>
+<ul>
+{{- range .Violations}}
+<li class="WARN">{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .RetriedAfter}}
+<p>Retried after {{ms .RetriedAfter}} (Retry-After)</p>
+{{- end}}
+{{- if .Snippet}}
+<p>Response{{if .ContentType}} ({{.ContentType}}){{end}}</p>
+<pre>{{.Snippet}}</pre>
+{{- end}}
+</details>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestReport_WriteHTML(t *testing.T) {
+	report := &Report{
+		Collection: "checkout",
+		Started:    time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		WallTime:   300 * time.Millisecond,
+		Results: []Result{
+			{
+				Name: "login", Method: "POST", URL: "https://shop.example.com/login", StatusCode: 200,
+				ResponseTime: 120 * time.Millisecond, BodySize: 15, ContentType: "application/json", Snippet: `{"token": "<t>"}`,
+				Passed: []string{"status == 200"}, Timing: &request.Timing{DNS: 10 * time.Millisecond, TTFB: 100 * time.Millisecond},
+			},
+			{
+				Name: "cart", Method: "GET", URL: "https://shop.example.com/cart", StatusCode: 200, Start: 150 * time.Millisecond,
+				ResponseTime: 150 * time.Millisecond, Passed: []string{"status == 200"}, Failures: []string{`jsonpath "$.items" exists: not found`},
+			},
+			{Name: "pay", Error: "connection refused", Start: 300 * time.Millisecond},
+			{Name: "slow", StatusCode: 200, ResponseTime: 20 * time.Millisecond, Violations: []string{"latency 20ms exceeds budget 10ms"}},
+		},
+	}
+
+	var out strings.Builder
+	if err := report.WriteHTML(&out); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	html := out.String()
+	for _, want := range []string{
+		"<title>checkout run report</title>",
+		"Run on 2026-03-01 09:30:00 UTC, wall time 300.0 ms",
+		`<div class="PASS"><b>1</b>passed</div>`,
+		`<div class="FAIL"><b>2</b>failed</div>`,
+		`<div class="WARN"><b>1</b>over budget</div>`,
+		"<b>2/3</b>assertions passed",
+		"<code>POST https://shop.example.com/login</code>",
+		"{&#34;token&#34;: &#34;&lt;t&gt;&#34;}",
+		`<li class="FAIL">✗ jsonpath &#34;$.items&#34; exists: not found</li>`,
+		`<p class="FAIL">connection refused</p>`,
+		`<li class="WARN">latency 20ms exceeds budget 10ms</li>`,
+		`<span class="dns" style="left: 0.000%; width: 3.333%" title="DNS Lookup: 10.0 ms">`,
+		`<details open>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, html)
+		}
+	}
+}
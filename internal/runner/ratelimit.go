@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// rateLimitHeaders lists the remaining/reset header pairs servers commonly
+// use, in order of preference
+var rateLimitHeaders = [][2]string{
+	{"RateLimit-Remaining", "RateLimit-Reset"},
+	{"X-RateLimit-Remaining", "X-RateLimit-Reset"},
+	{"X-Rate-Limit-Remaining", "X-Rate-Limit-Reset"},
+}
+
+// pacer spreads the remaining requests of a run over the server's rate limit
+// window so the run slows down before it is rejected
+type pacer struct {
+	known     bool
+	remaining int
+	resetAt   time.Time
+}
+
+// observe records the rate limit state advertised by a response
+func (p *pacer) observe(headers map[string]string) {
+	remaining, resetAt, ok := parseRateLimit(headers, now())
+	if !ok {
+		return
+	}
+	p.known = true
+	p.remaining = remaining
+	p.resetAt = resetAt
+}
+
+// delay returns how long to wait before the next request when pending
+// requests are still to be sent
+func (p *pacer) delay(pending int) time.Duration {
+	if !p.known {
+		return 0
+	}
+	untilReset := p.resetAt.Sub(now())
+	if untilReset <= 0 || p.remaining >= pending {
+		// The window resets or the run finishes before the limit is hit
+		return 0
+	}
+
+	wait := untilReset
+	if p.remaining > 0 {
+		wait = untilReset / time.Duration(p.remaining)
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait
+}
+
+// parseRateLimit extracts the remaining request count and reset time from
+// response headers. Reset values are either seconds until the reset or, when
+// large enough to be one, a Unix timestamp.
+func parseRateLimit(headers map[string]string, current time.Time) (int, time.Time, bool) {
+	for _, names := range rateLimitHeaders {
+		remainingValue, ok := header(headers, names[0])
+		if !ok {
+			continue
+		}
+		remaining, err := strconv.Atoi(remainingValue)
+		if err != nil || remaining < 0 {
+			continue
+		}
+
+		resetValue, _ := header(headers, names[1])
+		reset, err := strconv.ParseInt(resetValue, 10, 64)
+		if err != nil || reset < 0 {
+			continue
+		}
+		if reset > 1_000_000_000 {
+			return remaining, time.Unix(reset, 0), true
+		}
+		return remaining, current.Add(time.Duration(reset) * time.Second), true
+	}
+	return 0, time.Time{}, false
+}
+
+// header looks up a response header by name, ignoring case
+func header(headers map[string]string, name string) (string, bool) {
+	name = http.CanonicalHeaderKey(name)
+	for key, value := range headers {
+		if http.CanonicalHeaderKey(key) == name {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	current := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		wantRemaining int
+		wantReset     time.Time
+		wantOK        bool
+	}{
+		{
+			name:          "delta seconds",
+			headers:       map[string]string{"Ratelimit-Remaining": "5", "Ratelimit-Reset": "30"},
+			wantRemaining: 5,
+			wantReset:     current.Add(30 * time.Second),
+			wantOK:        true,
+		},
+		{
+			name:          "unix timestamp",
+			headers:       map[string]string{"X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": "1700000060"},
+			wantRemaining: 0,
+			wantReset:     current.Add(time.Minute),
+			wantOK:        true,
+		},
+		{
+			name:    "missing reset",
+			headers: map[string]string{"X-RateLimit-Remaining": "3"},
+		},
+		{
+			name:    "no rate limit headers",
+			headers: map[string]string{"Content-Type": "text/plain"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, reset, ok := parseRateLimit(tt.headers, current)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRateLimit() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (remaining != tt.wantRemaining || !reset.Equal(tt.wantReset)) {
+				t.Errorf("parseRateLimit() = %d, %v, want %d, %v", remaining, reset, tt.wantRemaining, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestPacer_Delay(t *testing.T) {
+	current := time.Unix(1_700_000_000, 0)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	var p pacer
+	if wait := p.delay(10); wait != 0 {
+		t.Errorf("Expected no delay without rate limit headers, got %v", wait)
+	}
+
+	p.observe(map[string]string{"X-RateLimit-Remaining": "20", "X-RateLimit-Reset": "60"})
+	if wait := p.delay(10); wait != 0 {
+		t.Errorf("Expected no delay when the limit covers the run, got %v", wait)
+	}
+
+	p.observe(map[string]string{"X-RateLimit-Remaining": "4", "X-RateLimit-Reset": "60"})
+	if wait := p.delay(10); wait != 15*time.Second {
+		t.Errorf("Expected requests to be spread over the window, got %v", wait)
+	}
+
+	p.observe(map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "60"})
+	if wait := p.delay(1); wait != time.Minute {
+		t.Errorf("Expected to pause until the reset, got %v", wait)
+	}
+}
+
+func TestRun_RateLimitPacing(t *testing.T) {
+	var waited time.Duration
+	sleep = func(d time.Duration) { waited += d }
+	defer func() { sleep = time.Sleep }()
+	current := time.Unix(1_700_000_000, 0)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	// Two requests allowed per 10 second window
+	remaining := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining--
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(remaining))
+		w.Header().Set("X-RateLimit-Reset", "10")
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{Name: "paced"}
+	for i := 0; i < 3; i++ {
+		c.Requests = append(c.Requests, collection.SavedRequest{
+			Name:    fmt.Sprintf("req-%d", i),
+			Request: request.RequestData{Method: "GET", URL: server.URL},
+		})
+	}
+
+	report := Run(c, Options{})
+	if report.Failed() {
+		t.Fatalf("Expected run to pass, got %+v", report.Results)
+	}
+	// One left for two pending requests, then none left for the last one
+	if waited != 20*time.Second || report.Throttled != waited {
+		t.Errorf("Expected 20s of throttling, waited %v, reported %v", waited, report.Throttled)
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	if !strings.Contains(buf.String(), "Throttled for 20s") {
+		t.Errorf("Expected report to show time throttled, got %s", buf.String())
+	}
+}
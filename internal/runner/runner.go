@@ -0,0 +1,327 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// maxRetryAfter caps how long a run waits on a Retry-After response before
+// giving up on the request
+const maxRetryAfter = 2 * time.Minute
+
+// sleep is replaced in tests
+var sleep = time.Sleep
+
+// Options configures a collection run
+type Options struct {
+	// Env resolves {{var}} placeholders; nil uses the process environment only
+	Env *environment.Environment
+
+	// DefaultHeaders are merged into every request
+	DefaultHeaders map[string]string
+
+	// ProxyAuth holds the stored credentials for the proxies requests use
+	ProxyAuth map[string]request.ProxyCredentials
+
+	// MaxResponseSize limits the response body read for requests that do
+	// not set their own limit; zero means no limit
+	MaxResponseSize int64
+
+	// EnforceBudgets turns budget violations into failures
+	EnforceBudgets bool
+
+	// Cookies carries cookies between requests; nil uses a jar for this
+	// run only
+	Cookies *request.CookieJar
+
+	// Recorder, when set, appends every request of the run to a HAR file
+	Recorder *request.HARRecorder
+
+	// Snapshot, when set, answers requests with responses recorded earlier
+	// instead of sending them to the live API
+	Snapshot *request.Snapshot
+
+	// OnStart and OnResult, when set, are called as each request starts and
+	// completes, to follow the progress of a run
+	OnStart  func(name string)
+	OnResult func(result Result)
+}
+
+// Result is the outcome of a single request in a run
+type Result struct {
+	Name         string        `json:"name"`
+	Method       string        `json:"method,omitempty"`
+	URL          string        `json:"url,omitempty"`
+	StatusCode   int           `json:"status_code"`
+	ResponseTime time.Duration `json:"response_time"`
+	BodySize     int64         `json:"body_size"`
+	Error        string        `json:"error,omitempty"`
+	Violations   []string      `json:"violations,omitempty"`
+
+	// Failures lists the assertions the response did not satisfy and
+	// Passed the ones it did
+	Failures []string `json:"failures,omitempty"`
+	Passed   []string `json:"passed,omitempty"`
+
+	// expectsStatus is set when an assertion checks the status code, so an
+	// error status alone does not fail the request
+	expectsStatus bool
+
+	// RetriedAfter is how long the run waited before re-sending a request
+	// that was answered with Retry-After
+	RetriedAfter time.Duration `json:"retried_after,omitempty"`
+
+	// Reused is set when the request went out on a connection kept alive
+	// from an earlier one, so its time includes no connection setup
+	Reused bool `json:"reused,omitempty"`
+
+	// Start is when the request was sent, measured from the start of the
+	// run, and Timing breaks its response time down by phase
+	Start  time.Duration   `json:"start"`
+	Timing *request.Timing `json:"timing,omitempty"`
+
+	// ContentType and Snippet are the type and the start of the response
+	// body, for reports
+	ContentType string `json:"content_type,omitempty"`
+	Snippet     string `json:"snippet,omitempty"`
+}
+
+// Failed reports whether the request could not be executed, failed an
+// assertion, or returned an error status that no assertion expected
+func (r Result) Failed() bool {
+	return r.Error != "" || len(r.Failures) > 0 || (!r.expectsStatus && r.StatusCode >= 400)
+}
+
+// Status returns PASS, FAIL, or WARN for a request that only violated its
+// budget; budget violations fail it when enforceBudgets is set
+func (r Result) Status(enforceBudgets bool) string {
+	switch {
+	case r.Failed(), len(r.Violations) > 0 && enforceBudgets:
+		return "FAIL"
+	case len(r.Violations) > 0:
+		return "WARN"
+	}
+	return "PASS"
+}
+
+// Report summarizes a collection run
+type Report struct {
+	Collection     string   `json:"collection"`
+	Results        []Result `json:"results"`
+	EnforceBudgets bool     `json:"enforce_budgets"`
+
+	// Throttled is the time spent waiting on rate limits and Retry-After
+	Throttled time.Duration `json:"throttled,omitempty"`
+
+	// Started is when the run began and WallTime how long it took
+	Started  time.Time     `json:"started"`
+	WallTime time.Duration `json:"wall_time"`
+}
+
+// Run executes every request in the collection in order
+func Run(c *collection.Collection, opts Options) *Report {
+	report := &Report{
+		Collection:     c.Name,
+		EnforceBudgets: opts.EnforceBudgets,
+	}
+
+	if opts.Cookies == nil {
+		opts.Cookies = request.NewCookieJar()
+	}
+
+	began := time.Now()
+	report.Started = began
+	var pace pacer
+	responses := make(map[string]*request.ResponseData)
+	for i := range c.Requests {
+		// Slow down before the server's rate limit is exhausted
+		if wait := pace.delay(len(c.Requests) - i); wait > 0 {
+			sleep(wait)
+			report.Throttled += wait
+		}
+
+		saved := &c.Requests[i]
+		if opts.OnStart != nil {
+			opts.OnStart(saved.Name)
+		}
+		result, resp := execute(saved, opts, responses)
+		if resp != nil {
+			pace.observe(resp.Headers)
+			responses[saved.Name] = resp
+		}
+		result.Start = max(time.Since(began)-result.ResponseTime, 0)
+		result.Violations = checkBudget(c.BudgetFor(saved), result)
+		report.Results = append(report.Results, result)
+		report.Throttled += result.RetriedAfter
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+	}
+
+	report.WallTime = time.Since(began)
+	return report
+}
+
+// execute runs a single saved request, resolving references to the
+// responses of earlier requests, and returns the result along with the
+// response
+func execute(saved *collection.SavedRequest, opts Options, responses map[string]*request.ResponseData) (Result, *request.ResponseData) {
+	result := Result{Name: saved.Name}
+
+	req := saved.Request.Clone()
+	req.DefaultHeaders = opts.DefaultHeaders
+	if req.MaxResponseSize == 0 {
+		req.MaxResponseSize = opts.MaxResponseSize
+	}
+	missing := resolveReferences(req, responses)
+	missing = append(missing, opts.Env.ResolveRequest(req)...)
+	asserts, unresolved := resolveAsserts(saved.Asserts, opts.Env)
+	missing = append(missing, unresolved...)
+	if len(missing) > 0 {
+		result.Error = fmt.Sprintf("unresolved variables: %s", strings.Join(missing, ", "))
+		return result, nil
+	}
+	req.Proxy.ApplyCredentials(opts.ProxyAuth)
+	result.Method, result.URL = req.Method, request.DisplayURL(req.ResolvedURL())
+
+	resp, err := req.ExecuteWith(request.WithCookieJar(opts.Cookies), request.WithSnapshot(opts.Snapshot), request.WithHARRecorder(opts.Recorder))
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	// Honor Retry-After once when the server asks us to back off, unless the
+	// request's own retry policy already did or the response was recorded
+	if req.Retry == nil && opts.Snapshot == nil && resp.RetryAfter > 0 && resp.RetryAfter <= maxRetryAfter {
+		sleep(resp.RetryAfter)
+		result.RetriedAfter = resp.RetryAfter
+		if resp, err = req.ExecuteWith(request.WithCookieJar(opts.Cookies), request.WithSnapshot(opts.Snapshot), request.WithHARRecorder(opts.Recorder)); err != nil {
+			result.Error = err.Error()
+			return result, nil
+		}
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.ResponseTime = resp.ResponseTime
+	result.BodySize = int64(len(resp.Body))
+	result.Error = resp.Error
+	result.Reused = resp.Timing != nil && resp.Timing.Reused
+	result.Timing = resp.Timing
+	result.ContentType = resp.ContentType()
+	result.Snippet = snippet(resp)
+	if resp.Error == "" {
+		result.Passed, result.Failures = checkAssertions(asserts, resp)
+		result.expectsStatus = assertsStatus(asserts)
+	}
+	return result, resp
+}
+
+// snippetSize is how much of a response body results keep
+const snippetSize = 2048
+
+// snippet returns the start of a text response body
+func snippet(resp *request.ResponseData) string {
+	if resp.Binary {
+		return fmt.Sprintf("(%d bytes of binary data)", len(resp.Body))
+	}
+	if len(resp.Body) <= snippetSize {
+		return resp.Body
+	}
+	// Cut at a rune boundary
+	end := snippetSize
+	for end > 0 && !utf8.RuneStart(resp.Body[end]) {
+		end--
+	}
+	return resp.Body[:end] + "…"
+}
+
+// checkBudget returns a description of each budget limit the result exceeds
+func checkBudget(budget *collection.Budget, result Result) []string {
+	if budget == nil || result.Error != "" {
+		return nil
+	}
+
+	var violations []string
+	if budget.MaxLatencyMs > 0 {
+		limit := time.Duration(budget.MaxLatencyMs) * time.Millisecond
+		if result.ResponseTime > limit {
+			violations = append(violations, fmt.Sprintf("latency %v exceeds budget %v", result.ResponseTime.Round(time.Millisecond), limit))
+		}
+	}
+	if budget.MaxBodyBytes > 0 && result.BodySize > budget.MaxBodyBytes {
+		violations = append(violations, fmt.Sprintf("body size %d B exceeds budget %d B", result.BodySize, budget.MaxBodyBytes))
+	}
+	return violations
+}
+
+// Verified returns the names of the requests that had assertions and
+// passed them all
+func (r *Report) Verified() []string {
+	var names []string
+	for _, result := range r.Results {
+		if len(result.Passed) > 0 && !result.Failed() {
+			names = append(names, result.Name)
+		}
+	}
+	return names
+}
+
+// Failed reports whether any request failed, counting budget violations
+// when budgets are enforced
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Failed() || (r.EnforceBudgets && len(result.Violations) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints a human-readable report
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "Collection: %s\n\n", r.Collection)
+
+	passed, failed, violations, reused := 0, 0, 0, 0
+	for _, result := range r.Results {
+		if result.Reused {
+			reused++
+		}
+		status := result.Status(r.EnforceBudgets)
+		if status == "FAIL" {
+			failed++
+		} else {
+			passed++
+		}
+		violations += len(result.Violations)
+
+		if result.Error != "" {
+			fmt.Fprintf(w, "%s  %s  error: %s\n", status, result.Name, result.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s  %s  %d  %v  %d B\n", status, result.Name, result.StatusCode, result.ResponseTime.Round(time.Millisecond), result.BodySize)
+		if result.RetriedAfter > 0 {
+			fmt.Fprintf(w, "      retried after %v (Retry-After)\n", result.RetriedAfter)
+		}
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "      assert: %s\n", failure)
+		}
+		for _, violation := range result.Violations {
+			fmt.Fprintf(w, "      budget: %s\n", violation)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d budget violations\n", passed, failed, violations)
+	if reused > 0 {
+		fmt.Fprintf(w, "%d of %d requests reused a connection\n", reused, len(r.Results))
+	}
+	if r.Throttled > 0 {
+		fmt.Fprintf(w, "Throttled for %v by rate limits\n", r.Throttled.Round(time.Millisecond))
+	}
+}
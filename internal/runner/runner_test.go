@@ -0,0 +1,284 @@
+package runner
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte("ok"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name:   "smoke",
+		Budget: &collection.Budget{MaxLatencyMs: 10, MaxBodyBytes: 50},
+		Requests: []collection.SavedRequest{
+			{Name: "fast", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/fast"}},
+			{Name: "slow", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/slow"}},
+			{Name: "large", Request: request.RequestData{Method: "GET", URL: "{{base_url}}/large"}},
+			{
+				Name:    "large-allowed",
+				Request: request.RequestData{Method: "GET", URL: "{{base_url}}/large"},
+				Budget:  &collection.Budget{MaxBodyBytes: 1000},
+			},
+		},
+	}
+	env := &environment.Environment{Variables: map[string]string{"base_url": server.URL}}
+
+	report := Run(c, Options{Env: env})
+	if len(report.Results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(report.Results))
+	}
+	if len(report.Results[0].Violations) != 0 {
+		t.Errorf("Expected no violations for fast request, got %v", report.Results[0].Violations)
+	}
+	if len(report.Results[1].Violations) != 1 || !strings.Contains(report.Results[1].Violations[0], "latency") {
+		t.Errorf("Expected latency violation for slow request, got %v", report.Results[1].Violations)
+	}
+	if len(report.Results[2].Violations) != 1 || !strings.Contains(report.Results[2].Violations[0], "body size") {
+		t.Errorf("Expected body size violation for large request, got %v", report.Results[2].Violations)
+	}
+	if len(report.Results[3].Violations) != 0 {
+		t.Errorf("Expected request budget to override collection budget, got %v", report.Results[3].Violations)
+	}
+
+	// Violations are warnings unless budgets are enforced
+	if report.Failed() {
+		t.Error("Expected report to pass without enforced budgets")
+	}
+	report.EnforceBudgets = true
+	if !report.Failed() {
+		t.Error("Expected report to fail with enforced budgets")
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	if !strings.Contains(buf.String(), "budget: latency") || !strings.Contains(buf.String(), "2 budget violations") {
+		t.Errorf("Expected report to list budget violations, got %s", buf.String())
+	}
+
+	// Requests after the first go out on the kept-alive connection
+	if report.Results[0].Reused || !report.Results[3].Reused {
+		t.Errorf("Expected later requests to reuse the first connection, got %+v", report.Results)
+	}
+	if !strings.Contains(buf.String(), "3 of 4 requests reused a connection") {
+		t.Errorf("Expected report to count reused connections, got %s", buf.String())
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name: "broken",
+		Requests: []collection.SavedRequest{
+			{Name: "error-status", Request: request.RequestData{Method: "GET", URL: server.URL}},
+			{Name: "unresolved", Request: request.RequestData{Method: "GET", URL: "{{lighttr_undefined}}/x"}},
+		},
+	}
+
+	report := Run(c, Options{})
+	if !report.Results[0].Failed() {
+		t.Error("Expected 500 response to fail")
+	}
+	if !strings.Contains(report.Results[1].Error, "unresolved variables") {
+		t.Errorf("Expected unresolved variable error, got %q", report.Results[1].Error)
+	}
+	if !report.Failed() {
+		t.Error("Expected report to fail")
+	}
+}
+
+func TestRun_Cookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			return
+		}
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name: "session",
+		Requests: []collection.SavedRequest{
+			{Name: "login", Request: request.RequestData{Method: "POST", URL: server.URL + "/login"}},
+			{Name: "me", Request: request.RequestData{Method: "GET", URL: server.URL + "/me"}},
+		},
+	}
+
+	if report := Run(c, Options{}); report.Failed() {
+		t.Errorf("Expected the session cookie to be sent within the run, got %+v", report.Results)
+	}
+
+	// A shared jar carries the session into later runs
+	jar := request.NewCookieJar()
+	Run(&collection.Collection{Name: "login", Requests: c.Requests[:1]}, Options{Cookies: jar})
+	if report := Run(&collection.Collection{Name: "me", Requests: c.Requests[1:]}, Options{Cookies: jar}); report.Failed() {
+		t.Errorf("Expected the shared jar to carry the session, got %+v", report.Results)
+	}
+	if report := Run(&collection.Collection{Name: "me", Requests: c.Requests[1:]}, Options{}); !report.Failed() {
+		t.Error("Expected a fresh run to start without cookies")
+	}
+}
+
+func TestRun_Asserts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(`{"name": "Jane", "age": 42, "tags": ["admin"]}`))
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name: "asserts",
+		Requests: []collection.SavedRequest{
+			{
+				Name:    "passing",
+				Request: request.RequestData{Method: "GET", URL: server.URL},
+				Asserts: []assertion.Assertion{
+					{Query: "status", Predicate: "==", Value: "200"},
+					{Query: "header", Argument: "content-type", Predicate: "contains", Value: `"json"`},
+					{Query: "jsonpath", Argument: "$.name", Predicate: "==", Value: `"Jane"`},
+					{Query: "jsonpath", Argument: "$.age", Predicate: ">", Value: "40"},
+					{Query: "jsonpath", Argument: "$.tags", Predicate: "contains", Value: `"admin"`},
+					{Query: "body", Predicate: "matches", Value: `"\\d+"`},
+					{Query: "duration", Predicate: "<", Value: "10000"},
+				},
+			},
+			{
+				Name:    "expected-404",
+				Request: request.RequestData{Method: "GET", URL: server.URL + "/missing"},
+				Asserts: []assertion.Assertion{{Query: "status", Predicate: "==", Value: "404"}},
+			},
+			{
+				Name:    "failing",
+				Request: request.RequestData{Method: "GET", URL: server.URL},
+				Asserts: []assertion.Assertion{
+					{Query: "jsonpath", Argument: "$.name", Predicate: "==", Value: `"John"`},
+					{Query: "header", Argument: "X-Trace", Predicate: "exists"},
+				},
+			},
+		},
+	}
+
+	report := Run(c, Options{})
+	if report.Results[0].Failed() {
+		t.Errorf("Expected passing asserts, got %v", report.Results[0].Failures)
+	}
+	if passed := report.Results[0].Passed; len(passed) != 7 || passed[0] != "status == 200" {
+		t.Errorf("Expected every assertion to be listed as passed, got %v", passed)
+	}
+	if result := report.Results[0]; result.Method != "GET" || result.URL != server.URL || result.ContentType != "application/json" || !strings.Contains(result.Snippet, `"Jane"`) {
+		t.Errorf("Expected the request and the start of the response in the result, got %+v", result)
+	}
+	if report.Results[1].Failed() {
+		t.Errorf("Expected asserted 404 to pass, got %+v", report.Results[1])
+	}
+	if verified := report.Verified(); len(verified) != 2 || verified[0] != "passing" || verified[1] != "expected-404" {
+		t.Errorf("Expected the requests that passed their asserts to be verified, got %v", verified)
+	}
+	failures := report.Results[2].Failures
+	if len(failures) != 2 || failures[0] != `jsonpath "$.name" == "John": got "Jane"` || failures[1] != `header "X-Trace" exists: not found` {
+		t.Errorf("Unexpected failures: %v", failures)
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	if !strings.Contains(buf.String(), `FAIL  failing`) || !strings.Contains(buf.String(), `assert: jsonpath "$.name" == "John": got "Jane"`) {
+		t.Errorf("Expected report to list assert failures, got %s", buf.String())
+	}
+}
+
+func TestRun_XPathAsserts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<Envelope><Body><Item id="1">apple</Item><Item id="2">banana</Item></Body></Envelope>`))
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name: "xpath",
+		Requests: []collection.SavedRequest{{
+			Name:    "items",
+			Request: request.RequestData{Method: "GET", URL: server.URL},
+			Asserts: []assertion.Assertion{
+				{Query: "xpath", Argument: "//Item[@id='2']", Predicate: "==", Value: `"banana"`},
+				{Query: "xpath", Argument: "count(//Item)", Predicate: "==", Value: "2"},
+				{Query: "xpath", Argument: "//Item", Predicate: "contains", Value: `"apple"`},
+				{Query: "xpath", Argument: "//Missing", Predicate: "exists"},
+			},
+		}},
+	}
+
+	failures := Run(c, Options{}).Results[0].Failures
+	if len(failures) != 1 || failures[0] != `xpath "//Missing" exists: not found` {
+		t.Errorf("Unexpected failures: %v", failures)
+	}
+}
+
+func TestRun_RetryAfter(t *testing.T) {
+	var waited time.Duration
+	sleep = func(d time.Duration) { waited += d }
+	defer func() { sleep = time.Sleep }()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name:     "limited",
+		Requests: []collection.SavedRequest{{Name: "limited", Request: request.RequestData{Method: "GET", URL: server.URL}}},
+	}
+
+	report := Run(c, Options{})
+	result := report.Results[0]
+	if result.StatusCode != http.StatusOK || result.Failed() {
+		t.Errorf("Expected retried request to succeed, got %+v", result)
+	}
+	if waited != 3*time.Second || result.RetriedAfter != 3*time.Second {
+		t.Errorf("Expected a 3s wait, waited %v, recorded %v", waited, result.RetriedAfter)
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	if !strings.Contains(buf.String(), "retried after 3s") {
+		t.Errorf("Expected report to mention the retry, got %s", buf.String())
+	}
+}
@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// waterfallWidth is the number of columns the text waterfall's timeline
+// spans
+const waterfallWidth = 60
+
+// Waterfall lays the requests of a run out on one timeline, each broken
+// down into its DNS, connect, TLS, time to first byte, and transfer
+// phases, to explain the end-to-end latency of a flow
+type Waterfall struct {
+	Collection string          `json:"collection"`
+	WallTime   time.Duration   `json:"wall_time"`
+	Steps      []WaterfallStep `json:"steps"`
+}
+
+// WaterfallStep is a request on the waterfall timeline
+type WaterfallStep struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// Start is when the request was sent, from the start of the run, and
+	// Total its response time
+	Start  time.Duration   `json:"start"`
+	Total  time.Duration   `json:"total"`
+	Timing *request.Timing `json:"timing,omitempty"`
+}
+
+// WaterfallSegment is a phase of a step placed on the timeline
+type WaterfallSegment struct {
+	Phase    string
+	Class    string
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// waterfallPhases maps the timing phases to the letter the text waterfall
+// draws them with and the class the HTML one styles them with; time not
+// accounted for by a phase, such as earlier retry attempts, is "Other"
+var waterfallPhases = map[string]struct {
+	char  byte
+	class string
+}{
+	"DNS Lookup":         {'d', "dns"},
+	"TCP Connect":        {'c', "connect"},
+	"TLS Handshake":      {'t', "tls"},
+	"Time to First Byte": {'=', "ttfb"},
+	"Content Transfer":   {'#', "transfer"},
+	"Other":              {'-', "other"},
+}
+
+// Waterfall returns the timeline of the run
+func (r *Report) Waterfall() *Waterfall {
+	w := &Waterfall{Collection: r.Collection, WallTime: r.WallTime}
+	for _, result := range r.Results {
+		w.Steps = append(w.Steps, WaterfallStep{
+			Name:       result.Name,
+			Status:     result.Status(r.EnforceBudgets),
+			StatusCode: result.StatusCode,
+			Error:      result.Error,
+			Start:      result.Start,
+			Total:      result.ResponseTime,
+			Timing:     result.Timing,
+		})
+	}
+	return w
+}
+
+// Segments places the phases of the step on the timeline. The phases are
+// measured separately from the response time, so they are cut off where it
+// ends.
+func (s WaterfallStep) Segments() []WaterfallSegment {
+	var segments []WaterfallSegment
+	at, end := s.Start, s.Start+s.Total
+	if s.Timing != nil {
+		for _, phase := range s.Timing.Phases() {
+			duration := min(phase.Duration, end-at)
+			if duration <= 0 {
+				continue
+			}
+			segments = append(segments, WaterfallSegment{Phase: phase.Name, Class: waterfallPhases[phase.Name].class, Start: at, Duration: duration})
+			at += duration
+		}
+	}
+	if rest := end - at; rest > 0 {
+		segments = append(segments, WaterfallSegment{Phase: "Other", Class: "other", Start: at, Duration: rest})
+	}
+	return segments
+}
+
+// WriteText draws the waterfall with one row per request, e.g.
+//
+//	login    |ddcttt=====#                    |  180ms  200
+//	profile  |            =====#              |   95ms  200
+func (w *Waterfall) WriteText(out io.Writer) {
+	fmt.Fprintf(out, "Waterfall: %d requests, wall time %v\n\n", len(w.Steps), w.WallTime.Round(time.Millisecond))
+
+	nameWidth := 0
+	for _, step := range w.Steps {
+		nameWidth = max(nameWidth, len(step.Name))
+	}
+	column := func(d time.Duration) int {
+		if w.WallTime <= 0 {
+			return 0
+		}
+		return min(int(int64(d)*waterfallWidth/int64(w.WallTime)), waterfallWidth)
+	}
+
+	for _, step := range w.Steps {
+		bar := []byte(strings.Repeat(" ", waterfallWidth))
+		// Every phase gets at least one column, after the phase before it
+		next := 0
+		for _, segment := range step.Segments() {
+			from := max(column(segment.Start), next)
+			to := column(segment.Start + segment.Duration)
+			if segment.Phase != "Other" {
+				to = max(to, from+1)
+			}
+			for i := from; i < min(to, waterfallWidth); i++ {
+				bar[i] = waterfallPhases[segment.Phase].char
+			}
+			next = max(next, to)
+		}
+		outcome := fmt.Sprint(step.StatusCode)
+		if step.Error != "" {
+			outcome = "error"
+		}
+		fmt.Fprintf(out, "%-*s  |%s|  %6v  %s\n", nameWidth, step.Name, bar, step.Total.Round(time.Millisecond), outcome)
+	}
+	fmt.Fprintln(out, "\nd DNS  c connect  t TLS  = waiting (TTFB)  # transfer  - other")
+}
+
+// WriteJSON writes the waterfall as JSON, with durations in nanoseconds
+func (w *Waterfall) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(w)
+}
+
+// WriteHTML writes the waterfall as a self-contained HTML page
+func (w *Waterfall) WriteHTML(out io.Writer) error {
+	return waterfallTemplate.Execute(out, w)
+}
+
+// templateFuncs are the functions of the HTML waterfall and report
+var templateFuncs = template.FuncMap{
+	"percent": func(d, total time.Duration) string {
+		if total <= 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%.3f", float64(d)*100/float64(total))
+	},
+	"ms": func(d time.Duration) string {
+		return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond))
+	},
+}
+
+var waterfallTemplate = template.Must(template.New("waterfall").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Collection}} waterfall</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+td, th { padding: 4px 8px; text-align: left; white-space: nowrap; }
+th { border-bottom: 1px solid #ccc; }
+td.track { width: 100%; }
+.track div { position: relative; height: 16px; background: #f4f4f4; }
+.track span { position: absolute; top: 0; height: 16px; min-width: 1px; }
+.legend span { display: inline-block; width: 12px; height: 12px; margin: 0 4px 0 12px; vertical-align: middle; }
+.dns { background: #2a9d8f; } .connect { background: #e9c46a; } .tls { background: #9b5de5; }
+.ttfb { background: #8ecae6; } .transfer { background: #219ebc; } .other { background: #adb5bd; }
+.FAIL { color: #c0392b; } .WARN { color: #d68910; }
+</style>
+</head>
+<body>
+<h1>{{.Collection}}</h1>
+<p>{{len .Steps}} requests, wall time {{ms .WallTime}}</p>
+<table>
+<tr><th>Request</th><th>Status</th><th>Start</th><th>Time</th><th>Timeline</th></tr>
+{{- $wall := .WallTime}}
+{{- range .Steps}}
+<tr>
+<td>{{.Name}}</td>
+<td class="{{.Status}}">{{if .Error}}{{.Error}}{{else}}{{.StatusCode}}{{end}}</td>
+<td>{{ms .Start}}</td>
+<td>{{ms .Total}}</td>
+<td class="track"><div>
+{{- range .Segments}}<span class="{{.Class}}" style="left: {{percent .Start $wall}}%; width: {{percent .Duration $wall}}%" title="{{.Phase}}: {{ms .Duration}}"></span>{{end -}}
+</div></td>
+</tr>
+{{- end}}
+</table>
+<p class="legend"><span class="dns"></span>DNS<span class="connect"></span>Connect<span class="tls"></span>TLS<span class="ttfb"></span>Waiting (TTFB)<span class="transfer"></span>Transfer<span class="other"></span>Other</p>
+</body>
+</html>
+`))
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestReport_Waterfall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{Name: "checkout", Requests: []collection.SavedRequest{
+		{Name: "login", Request: request.RequestData{Method: "POST", URL: server.URL + "/login"}},
+		{Name: "cart", Request: request.RequestData{Method: "GET", URL: server.URL + "/cart"}},
+	}}
+	report := Run(c, Options{})
+	w := report.Waterfall()
+	if len(w.Steps) != 2 || w.WallTime < 20*time.Millisecond {
+		t.Fatalf("Expected two steps over at least 20ms, got %+v", w)
+	}
+	login, cart := w.Steps[0], w.Steps[1]
+	if login.Timing == nil || login.Timing.Connect == 0 || !cart.Timing.Reused {
+		t.Errorf("Expected the login to connect and the cart to reuse the connection, got %+v %+v", login.Timing, cart.Timing)
+	}
+	if cart.Start < login.Start+login.Total || cart.Start+cart.Total > w.WallTime {
+		t.Errorf("Expected the cart to follow the login within the wall time, got %v+%v, %v+%v of %v", login.Start, login.Total, cart.Start, cart.Total, w.WallTime)
+	}
+	segments := cart.Segments()
+	if len(segments) == 0 || segments[0].Start != cart.Start {
+		t.Errorf("Expected the cart's phases to start with it, got %+v", segments)
+	}
+	var end time.Duration
+	for _, segment := range segments {
+		end = segment.Start + segment.Duration
+	}
+	if end != cart.Start+cart.Total {
+		t.Errorf("Expected the phases to add up to the response time, got %v, want %v", end, cart.Start+cart.Total)
+	}
+
+	var text bytes.Buffer
+	w.WriteText(&text)
+	lines := strings.Split(text.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Waterfall: 2 requests, wall time") || !strings.HasPrefix(lines[2], "login  |c") || !strings.HasPrefix(lines[3], "cart   | ") {
+		t.Errorf("Unexpected text waterfall:\n%s", text.String())
+	}
+
+	var encoded bytes.Buffer
+	if err := w.WriteJSON(&encoded); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Waterfall
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil || decoded.Steps[1].Start != cart.Start || decoded.Steps[0].Timing.TTFB != login.Timing.TTFB {
+		t.Errorf("Expected the JSON to round-trip, got %+v, %v", decoded, err)
+	}
+
+	var html bytes.Buffer
+	if err := w.WriteHTML(&html); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	if !strings.Contains(html.String(), "<td>cart</td>") || !strings.Contains(html.String(), `<span class="ttfb" style="left: `) {
+		t.Errorf("Unexpected HTML waterfall:\n%s", html.String())
+	}
+}
+
+func TestWaterfall_WriteText(t *testing.T) {
+	w := &Waterfall{WallTime: 600 * time.Millisecond, Steps: []WaterfallStep{
+		{Name: "a", StatusCode: 200, Start: 0, Total: 300 * time.Millisecond,
+			Timing: &request.Timing{DNS: 50 * time.Millisecond, Connect: 50 * time.Millisecond, TTFB: 150 * time.Millisecond, Transfer: 50 * time.Millisecond}},
+		{Name: "bb", Error: "timeout", Start: 300 * time.Millisecond, Total: 300 * time.Millisecond},
+	}}
+	var out bytes.Buffer
+	w.WriteText(&out)
+	want := "Waterfall: 2 requests, wall time 600ms\n\n" +
+		"a   |dddddccccc===============#####                              |   300ms  200\n" +
+		"bb  |                              ------------------------------|   300ms  error\n" +
+		"\nd DNS  c connect  t TLS  = waiting (TTFB)  # transfer  - other\n"
+	if out.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
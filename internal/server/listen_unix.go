@@ -0,0 +1,16 @@
+//go:build unix
+
+package server
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenPrivate creates the socket under a umask that leaves it to the
+// current user, so no one else can connect before its mode could be changed
+func listenPrivate(socket string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socket)
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/runner"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// ExecuteRequest is the body of POST /execute
+type ExecuteRequest struct {
+	Request request.RequestData `json:"request"`
+
+	// Env resolves {{var}} placeholders in the request
+	Env string `json:"env,omitempty"`
+}
+
+// RunRequest is the optional body of POST /collections/{name}/run
+type RunRequest struct {
+	Env            string `json:"env,omitempty"`
+	EnforceBudgets bool   `json:"enforce_budgets,omitempty"`
+}
+
+// errorResponse is the body of every failed call
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server exposes request execution, history, and collections as a local
+// JSON API
+type Server struct {
+	history     *history.Manager
+	collections *collection.Manager
+	config      *config.Config
+
+	// cookies carries cookies between the requests sent through the server
+	cookies *request.CookieJar
+
+	// mu serializes writing the cookie jar and collections, which the
+	// handlers of concurrent calls share
+	mu sync.Mutex
+}
+
+// New creates a server backed by the user's ~/.lighttr data
+func New() (*Server, error) {
+	historyPath, err := config.HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	historyManager, err := history.Open(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := collection.NewManager()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	jar, err := cfg.CookieJar(false)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{history: historyManager, collections: collections, config: cfg, cookies: jar}, nil
+}
+
+// Handler returns the API routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /execute", s.execute)
+	mux.HandleFunc("GET /history", s.listHistory)
+	mux.HandleFunc("GET /collections", s.listCollections)
+	mux.HandleFunc("GET /collections/{name}", s.getCollection)
+	mux.HandleFunc("POST /collections/{name}/run", s.runCollection)
+	return mux
+}
+
+// Listen opens the unix socket, replacing a stale socket file left by a
+// previous run, restricted to the current user from the start
+func Listen(socket string) (net.Listener, error) {
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another server is already listening on %s", socket)
+		}
+		os.Remove(socket)
+	}
+
+	return listenPrivate(socket)
+}
+
+// execute sends a request and records it in history
+func (s *Server) execute(w http.ResponseWriter, r *http.Request) {
+	var body ExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	env, err := loadEnv(body.Env)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	req := body.Request.Clone()
+	if req.Auth.Type == "" {
+		req.Auth.Type = request.NoAuth
+	}
+	req.DefaultHeaders = s.config.DefaultHeaders
+	if missing := env.ResolveRequest(req); len(missing) > 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unresolved variables: %s", strings.Join(missing, ", ")))
+		return
+	}
+
+	resp, err := req.ExecuteWith(request.WithCookieJar(s.cookies))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.saveCookies(); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to save cookies: %v", err))
+		return
+	}
+	if err := s.history.AddWithResponse(*req, resp); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to record history: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// listHistory returns every history entry, oldest first
+func (s *Server) listHistory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.history.GetAll())
+}
+
+// listCollections returns the names of the saved collections
+func (s *Server) listCollections(w http.ResponseWriter, r *http.Request) {
+	names, err := s.collections.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+// getCollection returns a saved collection
+func (s *Server) getCollection(w http.ResponseWriter, r *http.Request) {
+	c, err := s.collections.Load(r.PathValue("name"))
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// runCollection runs a saved collection and returns the report
+func (s *Server) runCollection(w http.ResponseWriter, r *http.Request) {
+	var body RunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+	}
+
+	c, err := s.collections.Load(r.PathValue("name"))
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	env, err := loadEnv(body.Env)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report := runner.Run(c, runner.Options{
+		Env:            env,
+		DefaultHeaders: s.config.DefaultHeaders,
+		EnforceBudgets: body.EnforceBudgets,
+		Cookies:        s.cookies,
+	})
+	if err := s.saveCookies(); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to save cookies: %v", err))
+		return
+	}
+	if err := s.markVerified(c.Name, report); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to record when the requests were verified: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// saveCookies writes the cookie jar back to disk
+func (s *Server) saveCookies() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cookies.Save()
+}
+
+// markVerified records when the requests of the run were verified in the
+// collection, reloading it so concurrent runs don't undo each other's marks
+func (s *Server) markVerified(name string, report *runner.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.collections.Load(name)
+	if err != nil {
+		return err
+	}
+	if c.MarkVerified(report.Verified(), report.Started.Add(report.WallTime)) > 0 {
+		return s.collections.Save(c)
+	}
+	return nil
+}
+
+// loadEnv loads the named environment, or returns nil for the process
+// environment only
+func loadEnv(name string) (*environment.Environment, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return environment.Load(name)
+}
+
+// statusFor maps a collection error to an HTTP status
+func statusFor(err error) int {
+	if errors.Is(err, collection.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
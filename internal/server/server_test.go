@@ -0,0 +1,296 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/runner"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	t.Cleanup(func() { os.Setenv("HOME", oldHome) })
+
+	return tmpDir
+}
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	s, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	api := httptest.NewServer(s.Handler())
+	t.Cleanup(api.Close)
+	return api
+}
+
+func TestServer_Execute(t *testing.T) {
+	setupHome(t)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + r.URL.Path))
+	}))
+	defer target.Close()
+	t.Setenv("LIGHTTR_TEST_TARGET", target.URL)
+
+	api := newTestAPI(t)
+
+	body, _ := json.Marshal(ExecuteRequest{Request: request.RequestData{Method: "GET", URL: "{{LIGHTTR_TEST_TARGET}}/world"}})
+	resp, err := http.Post(api.URL+"/execute", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /execute error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result request.ResponseData
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK || result.Body != "hello /world" {
+		t.Fatalf("Expected executed response, got %d %+v", resp.StatusCode, result)
+	}
+
+	// The request is recorded in history
+	historyResp, err := http.Get(api.URL + "/history")
+	if err != nil {
+		t.Fatalf("GET /history error = %v", err)
+	}
+	defer historyResp.Body.Close()
+
+	var entries []history.Entry
+	json.NewDecoder(historyResp.Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].URL != target.URL+"/world" || entries[0].Response.StatusCode != http.StatusOK {
+		t.Errorf("Expected the request in history, got %+v", entries)
+	}
+}
+
+func TestServer_ExecuteConcurrent(t *testing.T) {
+	home := setupHome(t)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "visit", Value: r.URL.Path[1:], Path: "/"})
+	}))
+	defer target.Close()
+	lighttrDir := filepath.Join(home, ".lighttr")
+	os.MkdirAll(lighttrDir, 0755)
+	os.WriteFile(filepath.Join(lighttrDir, "config.json"), []byte(`{"persist_cookies": true}`), 0644)
+
+	api := newTestAPI(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, _ := json.Marshal(ExecuteRequest{Request: request.RequestData{Method: "GET", URL: fmt.Sprintf("%s/%d", target.URL, i)}})
+			resp, err := http.Post(api.URL+"/execute", "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Errorf("POST /execute error = %v", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	historyManager, err := history.Open(filepath.Join(lighttrDir, "history.json"))
+	if err != nil {
+		t.Fatalf("history.Open() error = %v", err)
+	}
+	if entries := historyManager.GetAll(); len(entries) != 20 {
+		t.Errorf("Expected every request in history, got %d", len(entries))
+	}
+}
+
+func TestServer_ExecuteCookies(t *testing.T) {
+	home := setupHome(t)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			return
+		}
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer target.Close()
+
+	// Persist cookies so they outlive the server
+	lighttrDir := filepath.Join(home, ".lighttr")
+	os.MkdirAll(lighttrDir, 0755)
+	os.WriteFile(filepath.Join(lighttrDir, "config.json"), []byte(`{"persist_cookies": true}`), 0644)
+
+	execute := func(api *httptest.Server, path string) int {
+		t.Helper()
+		body, _ := json.Marshal(ExecuteRequest{Request: request.RequestData{Method: "GET", URL: target.URL + path}})
+		resp, err := http.Post(api.URL+"/execute", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /execute error = %v", err)
+		}
+		defer resp.Body.Close()
+		var result request.ResponseData
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result.StatusCode
+	}
+
+	api := newTestAPI(t)
+	execute(api, "/login")
+	if status := execute(api, "/me"); status != http.StatusOK {
+		t.Errorf("Expected session cookie to be sent, got status %d", status)
+	}
+
+	if status := execute(newTestAPI(t), "/me"); status != http.StatusOK {
+		t.Errorf("Expected persisted session cookie to be sent, got status %d", status)
+	}
+}
+
+func TestServer_ExecuteErrors(t *testing.T) {
+	setupHome(t)
+	api := newTestAPI(t)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "malformed body", body: "{", want: "invalid request body"},
+		{name: "invalid request", body: `{"request": {"method": "GET", "url": "not-a-url"}}`, want: "invalid URL"},
+		{name: "unresolved variable", body: `{"request": {"method": "GET", "url": "{{lighttr_undefined}}"}}`, want: "unresolved variables"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(api.URL+"/execute", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("POST /execute error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			var result errorResponse
+			json.NewDecoder(resp.Body).Decode(&result)
+			if resp.StatusCode != http.StatusBadRequest || !strings.Contains(result.Error, tt.want) {
+				t.Errorf("Expected 400 with %q, got %d %q", tt.want, resp.StatusCode, result.Error)
+			}
+		})
+	}
+}
+
+func TestServer_Collections(t *testing.T) {
+	setupHome(t)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	manager, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	manager.Save(&collection.Collection{
+		Name:     "smoke",
+		Requests: []collection.SavedRequest{{Name: "ping", Request: request.RequestData{Method: "GET", URL: target.URL}}},
+	})
+
+	api := newTestAPI(t)
+
+	resp, err := http.Get(api.URL + "/collections")
+	if err != nil {
+		t.Fatalf("GET /collections error = %v", err)
+	}
+	var names []string
+	json.NewDecoder(resp.Body).Decode(&names)
+	resp.Body.Close()
+	if len(names) != 1 || names[0] != "smoke" {
+		t.Errorf("Expected [smoke], got %v", names)
+	}
+
+	resp, err = http.Get(api.URL + "/collections/missing")
+	if err != nil {
+		t.Fatalf("GET /collections/missing error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing collection, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(api.URL+"/collections/smoke/run", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /collections/smoke/run error = %v", err)
+	}
+	var report runner.Report
+	json.NewDecoder(resp.Body).Decode(&report)
+	resp.Body.Close()
+	if len(report.Results) != 1 || report.Results[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected a passing run report, got %+v", report)
+	}
+}
+
+func TestListen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lt")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "ipc.sock")
+
+	listener, err := Listen(socket)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	info, err := os.Stat(socket)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket to be private, got %v %v", info.Mode(), err)
+	}
+
+	// A live server is not replaced
+	if _, err := Listen(socket); err == nil || !strings.Contains(err.Error(), "already listening") {
+		t.Errorf("Expected already listening error, got %v", err)
+	}
+
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	}))
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://lighttr/ping")
+	if err != nil {
+		t.Fatalf("GET over socket error = %v", err)
+	}
+	resp.Body.Close()
+
+	// A stale socket file left behind by a crashed server is replaced
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if _, err := os.Stat(socket); err != nil {
+		t.Fatalf("Expected stale socket file to remain, got %v", err)
+	}
+	listener, err = Listen(socket)
+	if err != nil {
+		t.Fatalf("Listen() on a closed socket error = %v", err)
+	}
+	listener.Close()
+}
@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/importer"
+)
+
+// addAssertion parses the assertion typed in the prompt and adds it, or
+// keeps the prompt open with the reason it cannot be parsed
+func (m *Model) addAssertion(value string) {
+	assert, err := importer.ParseAssertion(strings.TrimSpace(value))
+	if err != nil {
+		m.assertStatus = err.Error()
+		return
+	}
+	m.asserts = append(m.asserts, assert)
+	m.asserting = false
+	m.assertInput.Blur()
+	m.assertInput.SetValue("")
+	m.assertStatus = ""
+	m.logf(logInfo, "added assertion %s", assert)
+}
+
+// renderAssertions lists the assertions with whether the response passes
+// them
+func (m Model) renderAssertions() string {
+	var b strings.Builder
+	b.WriteString("Assertions:")
+	for _, assert := range m.asserts {
+		if err := assert.Check(m.response); err != nil {
+			b.WriteString("\n" + warningStyle.Render(fmt.Sprintf("✗ %s: %v", assert, err)))
+		} else {
+			b.WriteString("\n" + focusedStyle.Render("✓") + " " + assert.String())
+		}
+	}
+	return b.String()
+}
+
+// saveRequest saves the request sent and its assertions under the
+// collection/request reference
+func (m *Model) saveRequest(ref string) {
+	saved := collection.SavedRequest{Request: *m.requestData.Clone(), Asserts: slices.Clone(m.asserts)}
+	if err := m.collections.SaveRequest(ref, saved); err != nil {
+		m.saved = err.Error()
+		m.logf(logError, "%v", err)
+		return
+	}
+	m.savedAs = ref
+	name, _, _ := strings.Cut(ref, "/")
+	m.saved = fmt.Sprintf("Saved to %s with %d assertions • lighttr run %s runs the collection", ref, len(m.asserts), name)
+	m.logf(logInfo, "saved the request to %s", ref)
+}
@@ -0,0 +1,154 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/importer"
+)
+
+// readClipboard reads the system clipboard; replaced in tests
+var readClipboard = clipboard.ReadAll
+
+// clipboardMsg carries the text read from the clipboard
+type clipboardMsg struct {
+	text string
+	err  error
+}
+
+// pasteRequest reads the clipboard
+func pasteRequest() tea.Msg {
+	text, err := readClipboard()
+	return clipboardMsg{text: text, err: err}
+}
+
+// clipboardChoice is one way to import the clipboard into the form
+type clipboardChoice struct {
+	label  string
+	result *importer.Result
+
+	// replace fills the whole form with the request; otherwise only the
+	// body, or the URL of a bare URL, is set
+	replace bool
+}
+
+// clipboardChoices lists the interpretations of the clipboard text. A JSON
+// body can start a new request or become the body of the one in the form.
+func (m Model) clipboardChoices(text string) ([]clipboardChoice, error) {
+	results, err := importer.ParseTextAll(text)
+	if err != nil {
+		return nil, err
+	}
+	hasURL := strings.TrimSpace(m.inputs[inputURL].textinput.Value()) != ""
+
+	var choices []clipboardChoice
+	for _, result := range results {
+		req := result.Request
+		switch result.Format {
+		case importer.FormatCurl:
+			choices = append(choices, clipboardChoice{label: "curl command: " + req.Method + " " + req.URL, result: result, replace: true})
+		case importer.FormatHTTP:
+			choices = append(choices, clipboardChoice{label: "HTTP request: " + req.Method + " " + req.URL, result: result, replace: true})
+		case importer.FormatURL:
+			choices = append(choices, clipboardChoice{label: "URL: " + req.URL, result: result})
+		case importer.FormatJSON:
+			if hasURL {
+				choices = append(choices, clipboardChoice{label: "JSON body for the current request: " + abbreviate(req.Body, 40), result: result})
+			}
+			choices = append(choices, clipboardChoice{label: "New POST request with the JSON body: " + abbreviate(req.Body, 40), result: result, replace: true})
+		}
+	}
+	return choices, nil
+}
+
+// importClipboard applies the clipboard text, or asks how to import it
+// when it can be read in more than one way
+func (m *Model) importClipboard(msg clipboardMsg) {
+	if msg.err != nil {
+		m.formStatus = fmt.Sprintf("Could not read the clipboard: %v", msg.err)
+		m.logf(logError, "%s", m.formStatus)
+		return
+	}
+	choices, err := m.clipboardChoices(msg.text)
+	if err != nil {
+		m.formStatus = "Clipboard: " + err.Error()
+		return
+	}
+	if len(choices) == 1 {
+		m.applyClipboardChoice(choices[0])
+		return
+	}
+	m.clipboardOptions = choices
+	m.clipboardCursor = 0
+	m.screen = screenClipboard
+}
+
+// applyClipboardChoice fills the form from the chosen interpretation and
+// focuses the first field that was guessed
+func (m *Model) applyClipboardChoice(choice clipboardChoice) {
+	req := choice.result.Request
+	switch {
+	case choice.replace:
+		m.fillForm(req)
+	case choice.result.Format == importer.FormatURL:
+		m.inputs[inputURL].textinput.SetValue(req.URL)
+		m.inputs[inputQueryParams].textinput.SetValue(joinParams(req.QueryParams))
+	default:
+		m.inputs[inputBody].textinput.SetValue(req.Body)
+		headers := &m.inputs[inputHeaders].textinput
+		if !strings.Contains(strings.ToLower(headers.Value()), "content-type:") {
+			headers.SetValue(joinEntries(headers.Value(), "Content-Type:application/json"))
+		}
+	}
+	m.screen = screenRequest
+	m.clipboardOptions = nil
+
+	m.formStatus = "Imported " + choice.label
+	focus := m.activeInput
+	switch {
+	case slices.Contains(choice.result.Ambiguous, importer.FieldURL) || slices.Contains(choice.result.Ambiguous, importer.FieldScheme):
+		m.formStatus += " • check the URL"
+		focus = inputURL
+	case slices.Contains(choice.result.Ambiguous, importer.FieldMethod) && choice.replace:
+		m.formStatus += " • check the method"
+		focus = inputMethod
+	}
+	m.activeInput = focus
+	focusInput(m.inputs, m.activeInput)
+	m.logf(logInfo, "imported %s from the clipboard", choice.result.Format)
+}
+
+// joinEntries appends an entry to a comma separated field
+func joinEntries(value, entry string) string {
+	if strings.TrimSpace(value) == "" {
+		return entry
+	}
+	return value + "," + entry
+}
+
+// abbreviate shortens s to its first n characters on one line
+func abbreviate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "…"
+	}
+	return s
+}
+
+// renderClipboardScreen asks how to import the clipboard
+func (m Model) renderClipboardScreen() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Import from Clipboard"))
+	b.WriteString("\n\n")
+	b.WriteString("The clipboard can be imported in more than one way:\n\n")
+	labels := make([]string, len(m.clipboardOptions))
+	for i, choice := range m.clipboardOptions {
+		labels[i] = choice.label
+	}
+	b.WriteString(renderChoices(labels, m.clipboardCursor))
+	b.WriteString("\n↑/↓ to choose • Enter to import • ESC to cancel\n")
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestModel_ImportClipboard(t *testing.T) {
+	var clipboardText string
+	oldRead := readClipboard
+	readClipboard = func() (string, error) { return clipboardText, nil }
+	defer func() { readClipboard = oldRead }()
+
+	model := NewModel()
+	update := func(msg tea.Msg) tea.Cmd {
+		newModel, cmd := model.Update(msg)
+		model = newModel.(Model)
+		return cmd
+	}
+	paste := func(text string) {
+		clipboardText = text
+		if cmd := update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}, Alt: true}); cmd != nil {
+			update(cmd())
+		}
+	}
+	value := func(index int) string {
+		return model.inputs[index].textinput.Value()
+	}
+
+	// A curl command fills in the form
+	paste(`curl -X PUT https://api.example.com/users/7 -H "Accept: application/json" -u jane:secret -d '{"name":"Jane"}'`)
+	if value(inputURL) != "https://api.example.com/users/7" || value(inputMethod) != "PUT" || value(inputAuthType) != "basic" ||
+		value(inputAuthPassword) != "secret" || value(inputHeaders) != "Accept:application/json" || value(inputBody) != `{"name":"Jane"}` {
+		t.Errorf("Expected the curl command in the form, got %s", model.View())
+	}
+	if !strings.Contains(model.View(), "Imported curl command: PUT https://api.example.com/users/7") {
+		t.Errorf("Expected the import to be reported, got %s", model.View())
+	}
+
+	// A JSON body with a request in the form asks how to use it
+	paste(`{"name": "Janet"}`)
+	if model.screen != screenClipboard || len(model.clipboardOptions) != 2 {
+		t.Fatalf("Expected a choice of two imports, got screen %d with %d options", model.screen, len(model.clipboardOptions))
+	}
+	if view := model.View(); !strings.Contains(view, "JSON body for the current request") || !strings.Contains(view, "New POST request") {
+		t.Errorf("Expected both interpretations to be offered, got %s", view)
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.screen != screenRequest || value(inputBody) != `{"name": "Janet"}` || value(inputMethod) != "PUT" ||
+		value(inputURL) != "https://api.example.com/users/7" {
+		t.Errorf("Expected only the body to be replaced, got %s", model.View())
+	}
+	if value(inputHeaders) != "Accept:application/json,Content-Type:application/json" {
+		t.Errorf("Expected a JSON content type to be added, got %q", value(inputHeaders))
+	}
+
+	// The other choice starts a new request and asks for its URL
+	paste(`{"name": "Jo"}`)
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if value(inputURL) != "" || value(inputMethod) != "POST" || value(inputAuthType) != "none" || model.activeInput != inputURL {
+		t.Errorf("Expected a new POST request with the URL focused, got %s", model.View())
+	}
+
+	// A bare URL only sets the URL and query
+	paste("https://api.example.com/search?q=jane")
+	if value(inputURL) != "https://api.example.com/search" || value(inputQueryParams) != "q=jane" || value(inputBody) != `{"name": "Jo"}` {
+		t.Errorf("Expected the URL to be set, got %s", model.View())
+	}
+
+	paste("just some prose")
+	if !strings.Contains(model.View(), "Clipboard: could not recognize") {
+		t.Errorf("Expected unrecognized text to be reported, got %s", model.View())
+	}
+}
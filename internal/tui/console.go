@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Console entries older than the most recent maxConsoleEntries are dropped,
+// and the pane shows the last consoleHeight of them
+const (
+	maxConsoleEntries = 500
+	consoleHeight     = 10
+)
+
+// Console entry kinds, which set the marker and style of an entry
+const (
+	logInfo     = "info"
+	logSent     = "sent"
+	logReceived = "received"
+	logRetry    = "retry"
+	logWarning  = "warning"
+	logError    = "error"
+)
+
+// consoleEntry is one line of the session log
+type consoleEntry struct {
+	time time.Time
+	kind string
+	text string
+}
+
+// logTime returns the time entries are logged at; replaced in tests
+var logTime = time.Now
+
+// logf appends an entry to the session log
+func (m *Model) logf(kind, format string, args ...any) {
+	m.console = append(m.console, consoleEntry{time: logTime(), kind: kind, text: fmt.Sprintf(format, args...)})
+	if len(m.console) > maxConsoleEntries {
+		m.console = m.console[len(m.console)-maxConsoleEntries:]
+	}
+}
+
+// logSend records a request about to be sent, with the URL its path
+// parameters and templates resolved to
+func (m *Model) logSend() {
+	resolved := request.DisplayURL(m.requestData.ResolvedURL())
+	m.logf(logSent, "%s %s", m.requestData.WireMethod(), resolved)
+	if m.requestData.URL != resolved {
+		m.logf(logInfo, "resolved from %s", m.requestData.URL)
+	}
+	if m.requestData.Retry != nil {
+		m.logf(logInfo, "retrying up to %d times", m.requestData.Retry.MaxAttempts-1)
+	}
+}
+
+// logResponse records how a request went: the attempts that were retried,
+// the redirects followed, and the final response
+func (m *Model) logResponse(resp *request.ResponseData) {
+	for i, attempt := range resp.Attempts {
+		if i < len(resp.Attempts)-1 {
+			m.logf(logRetry, "attempt %d: %s", i+1, attempt)
+		}
+	}
+	for _, hop := range resp.Redirects {
+		m.logf(logInfo, "redirect %d %s -> %s", hop.StatusCode, hop.URL, hop.Location)
+	}
+	m.logf(logReceived, "%d %s in %v (%s)", resp.StatusCode, http.StatusText(resp.StatusCode),
+		resp.ResponseTime.Round(time.Millisecond), request.FormatSize(int64(len(resp.Body))))
+	if resp.DecodeError != "" {
+		m.logf(logWarning, "body not decoded: %s", resp.DecodeError)
+	}
+	if resp.Truncated {
+		m.logf(logWarning, "body truncated: %s", resp.TruncatedSummary())
+	}
+	if resp.TLS != nil {
+		for _, warning := range resp.TLS.Warnings {
+			m.logf(logWarning, "%s", warning)
+		}
+	}
+	if m.recorder != nil {
+		m.logf(logInfo, "recorded to %s", m.recorder.Path())
+	}
+}
+
+// renderConsole renders the last entries of the session log as a pane
+func (m Model) renderConsole() string {
+	var b strings.Builder
+	b.WriteString("\n" + focusedStyle.Render("Console") + blurredStyle.Render(" • Ctrl+L to hide") + "\n")
+	if len(m.console) == 0 {
+		b.WriteString(blurredStyle.Render("Nothing logged yet") + "\n")
+		return b.String()
+	}
+
+	entries := m.console
+	if len(entries) > consoleHeight {
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("(%d earlier entries)", len(entries)-consoleHeight)) + "\n")
+		entries = entries[len(entries)-consoleHeight:]
+	}
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s %s %s", entry.time.Format("15:04:05"), consoleMarker(entry.kind), entry.text)
+		switch entry.kind {
+		case logError:
+			line = failStyle.Render(line)
+		case logWarning, logRetry:
+			line = warningStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// consoleMarker returns the marker shown before entries of a kind
+func consoleMarker(kind string) string {
+	switch kind {
+	case logSent:
+		return "→"
+	case logReceived:
+		return "←"
+	case logRetry:
+		return "↻"
+	case logWarning:
+		return "!"
+	case logError:
+		return "✗"
+	}
+	return "·"
+}
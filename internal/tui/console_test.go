@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestModel_Console(t *testing.T) {
+	logTime = func() time.Time { return time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC) }
+	defer func() { logTime = time.Now }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	model := NewModel()
+	model.requestData.URL = server.URL + "/users/{id}"
+	model.requestData.PathParams["id"] = "42"
+	model.screen = screenPreview
+	update := func(msg tea.Msg) tea.Cmd {
+		newModel, cmd := model.Update(msg)
+		model = newModel.(Model)
+		return cmd
+	}
+
+	// Send the request and deliver its response
+	cmd := update(tea.KeyMsg{Type: tea.KeyEnter})
+	update(cmd())
+	if view := model.View(); strings.Contains(view, "Console") {
+		t.Errorf("Expected the console to be hidden by default, got %s", view)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	view := model.View()
+	for _, want := range []string{
+		"09:30:00 → GET " + server.URL + "/users/42",
+		"09:30:00 · resolved from " + server.URL + "/users/{id}",
+		"09:30:00 ← 200 OK in",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the console to show %q, got %s", want, view)
+		}
+	}
+
+	// Errors keep the request they belong to and the log in view
+	update(fmt.Errorf("request error: connection refused"))
+	view = model.View()
+	if !strings.Contains(view, "GET "+server.URL+"/users/42\nError: request error: connection refused") ||
+		!strings.Contains(view, "✗ request error: connection refused") {
+		t.Errorf("Expected the error with its context, got %s", view)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if view := model.View(); strings.Contains(view, "Console") {
+		t.Errorf("Expected Ctrl+L to hide the console, got %s", view)
+	}
+}
+
+func TestModel_ConsoleLimit(t *testing.T) {
+	model := NewModel()
+	for i := range maxConsoleEntries + 5 {
+		model.logf(logInfo, "entry %d", i)
+	}
+	if len(model.console) != maxConsoleEntries || model.console[0].text != "entry 5" {
+		t.Errorf("Expected the oldest entries to be dropped, got %d starting with %q", len(model.console), model.console[0].text)
+	}
+
+	model.showConsole = true
+	view := model.View()
+	if !strings.Contains(view, fmt.Sprintf("(%d earlier entries)", maxConsoleEntries-consoleHeight)) ||
+		!strings.Contains(view, fmt.Sprintf("entry %d", maxConsoleEntries+4)) || strings.Contains(view, "entry 5\n") {
+		t.Errorf("Expected only the last entries in the pane, got %s", view)
+	}
+}
+
+func TestModel_ConsoleLogsRetries(t *testing.T) {
+	model := NewModel()
+	model.logResponse(&request.ResponseData{
+		StatusCode: 200,
+		Attempts: []request.Attempt{
+			{StatusCode: 503, ResponseTime: 12 * time.Millisecond, Wait: 500 * time.Millisecond},
+			{StatusCode: 200, ResponseTime: 8 * time.Millisecond},
+		},
+		Truncated: true,
+		Body:      "partial",
+	})
+	var lines []string
+	for _, entry := range model.console {
+		lines = append(lines, entry.kind+": "+entry.text)
+	}
+	want := []string{
+		"retry: attempt 1: 503 (12ms), retried after 500ms",
+		"received: 200 OK in 0s (7 bytes)",
+		"warning: body truncated: showing the first 7 bytes of the body",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected log:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,198 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Session is the state of the request form and the last response, saved
+// when the TUI crashes so that it can be restored
+type Session struct {
+	// Fields maps the labels of the request form inputs to their values
+	Fields map[string]string `json:"fields"`
+
+	// Request and Response are the last request built from the form and its
+	// response, if any; Error is the error the request failed with
+	Request  *request.RequestData  `json:"request,omitempty"`
+	Response *request.ResponseData `json:"response,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// Crash records a panic in the TUI with the session it interrupted
+type Crash struct {
+	Time    time.Time `json:"time"`
+	Panic   string    `json:"panic"`
+	Stack   string    `json:"stack"`
+	Session Session   `json:"session"`
+}
+
+// Save writes the crash to a new file in dir, readable only by the user
+// since the form may hold credentials, and returns its path
+func (c *Crash) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash-"+c.Time.Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadCrash reads a crash saved with Save
+func LoadCrash(path string) (*Crash, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Crash
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid crash file %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Session returns the current state of the request form and response
+func (m Model) Session() Session {
+	s := Session{
+		Fields:   make(map[string]string, len(m.inputs)),
+		Request:  m.requestData,
+		Response: m.response,
+	}
+	for _, input := range m.inputs {
+		s.Fields[input.label] = input.textinput.Value()
+	}
+	if m.err != nil {
+		s.Error = m.err.Error()
+	}
+	return s
+}
+
+// WithSession returns a copy of the model with the request form filled in
+// from the session, showing its response when it has one
+func (m Model) WithSession(s Session) Model {
+	for i := range m.inputs {
+		if value, ok := s.Fields[m.inputs[i].label]; ok {
+			m.inputs[i].textinput.SetValue(value)
+		}
+	}
+	if s.Request != nil && s.Response != nil {
+		m.requestData = s.Request
+		m.response = s.Response
+		m.screen = screenResponse
+	}
+	m.logf(logInfo, "restored the session")
+	return m
+}
+
+// panicMsg reports a panic in a command
+type panicMsg struct {
+	value any
+	stack []byte
+}
+
+// Guard runs the model and recovers from panics in its Update, View, and
+// commands: the program quits normally, so the terminal is restored, and
+// Crash returns the panic with the session as it was before the update
+// that panicked
+type Guard struct {
+	model Model
+
+	// crash is shared by the copies of the guard the program keeps
+	crash **Crash
+}
+
+// NewGuard guards the model
+func NewGuard(model Model) Guard {
+	return Guard{model: model, crash: new(*Crash)}
+}
+
+// Crash returns the recovered panic, or nil when there was none
+func (g Guard) Crash() *Crash {
+	return *g.crash
+}
+
+func (g Guard) Init() tea.Cmd {
+	return guardCmd(g.model.Init())
+}
+
+func (g Guard) Update(msg tea.Msg) (result tea.Model, cmd tea.Cmd) {
+	// A panic in View cannot quit the program itself, so it quits on the
+	// next message
+	if *g.crash != nil {
+		return g, tea.Quit
+	}
+	if p, ok := msg.(panicMsg); ok {
+		g.recordCrash(p.value, p.stack)
+		return g, tea.Quit
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			g.recordCrash(r, debug.Stack())
+			result, cmd = g, tea.Quit
+		}
+	}()
+	model, cmd := g.model.Update(msg)
+	g.model = model.(Model)
+	return g, guardCmd(cmd)
+}
+
+func (g Guard) View() (view string) {
+	if *g.crash != nil {
+		return "Lighttr crashed. Press any key to quit.\n"
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.recordCrash(r, debug.Stack())
+			view = "Lighttr crashed. Press any key to quit.\n"
+		}
+	}()
+	return g.model.View()
+}
+
+// recordCrash keeps the first panic with the session
+func (g Guard) recordCrash(value any, stack []byte) {
+	if *g.crash != nil {
+		return
+	}
+	*g.crash = &Crash{
+		Time:    time.Now(),
+		Panic:   fmt.Sprint(value),
+		Stack:   string(stack),
+		Session: g.model.Session(),
+	}
+}
+
+// guardCmd turns a panic in the command, or in the commands of a batch it
+// returns, into a panicMsg
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() (msg tea.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				msg = panicMsg{value: r, stack: debug.Stack()}
+			}
+		}()
+		msg = cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = guardCmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestGuard_RecoversUpdate(t *testing.T) {
+	model := NewModel()
+	for _, r := range "https://api.example.com/users" {
+		newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		model = newModel.(Model)
+	}
+	// Going back online counts the queue, which panics without one
+	model.queue = nil
+	model.offline = true
+
+	guard := NewGuard(model)
+	next, cmd := guard.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if cmd == nil || cmd() != tea.Quit() {
+		t.Fatal("Expected the program to quit after a panic")
+	}
+	crash := next.(Guard).Crash()
+	if crash == nil || crash.Stack == "" || crash.Session.Fields["URL"] != "https://api.example.com/users" {
+		t.Fatalf("Expected the crash with the typed URL, got %+v", crash)
+	}
+	if guard.Crash() != crash {
+		t.Error("Expected the crash to be shared by copies of the guard")
+	}
+	if !strings.Contains(next.View(), "crashed") {
+		t.Errorf("Expected the view to report the crash, got %s", next.View())
+	}
+}
+
+func TestGuard_RecoversCommand(t *testing.T) {
+	cmd := guardCmd(tea.Batch(
+		func() tea.Msg { return nil },
+		func() tea.Msg { panic("boom") },
+	))
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("Expected a batch of two commands")
+	}
+	msg, ok := batch[1]().(panicMsg)
+	if !ok || msg.value != "boom" {
+		t.Fatalf("Expected the panic to become a message, got %+v", msg)
+	}
+
+	guard := NewGuard(NewModel())
+	if _, cmd := guard.Update(msg); cmd == nil || guard.Crash() == nil || guard.Crash().Panic != "boom" {
+		t.Errorf("Expected the panic to be recorded, got %+v", guard.Crash())
+	}
+}
+
+func TestCrash_SaveAndRestore(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com/users")
+	model.inputs[inputMethod].textinput.SetValue("POST")
+	model.requestData = &request.RequestData{Method: "POST", URL: "https://api.example.com/users"}
+	model.response = &request.ResponseData{StatusCode: 201, Body: `{"id": 7}`}
+
+	crash := &Crash{Panic: "boom", Stack: "goroutine 1", Session: model.Session()}
+	path, err := crash.Save(filepath.Join(t.TempDir(), "crash"))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadCrash(path)
+	if err != nil {
+		t.Fatalf("LoadCrash() error = %v", err)
+	}
+
+	restored := NewModel().WithSession(loaded.Session)
+	if got := restored.inputs[inputMethod].textinput.Value(); got != "POST" {
+		t.Errorf("Expected the method to be restored, got %q", got)
+	}
+	if restored.screen != screenResponse || restored.response.StatusCode != 201 {
+		t.Errorf("Expected the response to be shown, got screen %d", restored.screen)
+	}
+}
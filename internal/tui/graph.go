@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/runner"
+)
+
+var (
+	passStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	failStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+)
+
+// Run progress messages, sent from the goroutine running the collection
+type (
+	runStartedMsg struct{ name string }
+	runResultMsg  struct{ result runner.Result }
+	runDoneMsg    struct{ report *runner.Report }
+)
+
+// GraphModel runs a collection while showing the dependency graph of its
+// requests, which request feeds which variable, and the status of each
+// request as the run progresses
+type GraphModel struct {
+	collection *collection.Collection
+	opts       runner.Options
+	nodes      []runner.Node
+
+	// running names the request in flight; results holds the finished ones
+	running string
+	results map[string]runner.Result
+
+	// cursor selects the node whose details are shown
+	cursor int
+
+	events chan tea.Msg
+	report *runner.Report
+}
+
+// NewGraphModel returns a model that runs the collection with the given
+// options when started
+func NewGraphModel(c *collection.Collection, opts runner.Options) GraphModel {
+	return GraphModel{
+		collection: c,
+		opts:       opts,
+		nodes:      runner.Graph(c),
+		results:    make(map[string]runner.Result),
+		events:     make(chan tea.Msg),
+	}
+}
+
+// Report returns the report of the run, or nil while it is in progress
+func (m GraphModel) Report() *runner.Report {
+	return m.report
+}
+
+func (m GraphModel) Init() tea.Cmd {
+	return tea.Batch(m.run, m.nextEvent)
+}
+
+// run executes the collection, reporting progress on the events channel
+func (m GraphModel) run() tea.Msg {
+	opts := m.opts
+	opts.OnStart = func(name string) { m.events <- runStartedMsg{name} }
+	opts.OnResult = func(result runner.Result) { m.events <- runResultMsg{result} }
+	report := runner.Run(m.collection, opts)
+	m.events <- runDoneMsg{report}
+	close(m.events)
+	return nil
+}
+
+// nextEvent waits for the next progress message of the run
+func (m GraphModel) nextEvent() tea.Msg {
+	msg, ok := <-m.events
+	if !ok {
+		return nil
+	}
+	return msg
+}
+
+func (m GraphModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case runStartedMsg:
+		m.running = msg.name
+		return m, m.nextEvent
+	case runResultMsg:
+		m.running = ""
+		m.results[msg.result.Name] = msg.result
+		return m, m.nextEvent
+	case runDoneMsg:
+		m.report = msg.report
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q", "esc":
+			// Leave only once the run is over, so its report is complete
+			if m.report != nil {
+				return m, tea.Quit
+			}
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.nodes)-1 {
+				m.cursor++
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m GraphModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Run: " + m.collection.Name))
+	b.WriteString("\n\n")
+
+	// Requests are indented by their depth in the graph, and names padded
+	// to line up the statuses
+	width := 0
+	for _, node := range m.nodes {
+		width = max(width, 3*node.Depth+lipgloss.Width(node.Name))
+	}
+	for i, node := range m.nodes {
+		marker, status := m.nodeStatus(node.Name)
+		indent, branch := "", ""
+		if node.Depth > 0 {
+			indent = strings.Repeat("   ", node.Depth)
+			branch = indent[3:] + "└─ "
+		}
+		name := node.Name + strings.Repeat(" ", width-len(indent)-lipgloss.Width(node.Name))
+		if i == m.cursor {
+			name = focusedStyle.Render(name)
+		}
+		b.WriteString(fmt.Sprintf("%s %s%s  %s\n", marker, branch, name, status))
+		for _, ref := range node.Uses {
+			b.WriteString(blurredStyle.Render(fmt.Sprintf("  %s   ← %s %s", indent, ref.Request, ref)) + "\n")
+		}
+	}
+
+	if len(m.nodes) > 0 {
+		b.WriteString(m.renderDetails(m.nodes[m.cursor]))
+	}
+
+	if m.report == nil {
+		b.WriteString("\nRunning... • ↑/↓ to select • Ctrl+C to abort\n")
+		return b.String()
+	}
+	passed, failed := 0, 0
+	for _, result := range m.report.Results {
+		if result.Status(m.report.EnforceBudgets) == "FAIL" {
+			failed++
+		} else {
+			passed++
+		}
+	}
+	b.WriteString(fmt.Sprintf("\nDone: %d passed, %d failed • ↑/↓ to select • q to quit\n", passed, failed))
+	return b.String()
+}
+
+// nodeStatus returns the marker and status text of a request: pending,
+// running, or its outcome
+func (m GraphModel) nodeStatus(name string) (string, string) {
+	result, ok := m.results[name]
+	switch {
+	case name == m.running:
+		return warningStyle.Render("●"), warningStyle.Render("running")
+	case !ok:
+		return blurredStyle.Render("○"), blurredStyle.Render("pending")
+	}
+
+	status := result.Status(m.opts.EnforceBudgets)
+	summary := fmt.Sprintf("%s  %d  %v", status, result.StatusCode, result.ResponseTime.Round(time.Millisecond))
+	if result.Error != "" {
+		summary = status + "  error"
+	}
+	switch status {
+	case "FAIL":
+		return failStyle.Render("✗"), failStyle.Render(summary)
+	case "WARN":
+		return warningStyle.Render("!"), warningStyle.Render(summary)
+	}
+	return passStyle.Render("✓"), passStyle.Render(summary)
+}
+
+// renderDetails describes the selected request: the requests it depends on
+// and feeds, and why it failed
+func (m GraphModel) renderDetails(node runner.Node) string {
+	var b strings.Builder
+	b.WriteString("\n" + focusedStyle.Render(node.Name) + "\n")
+
+	var feeds []string
+	for _, other := range m.nodes {
+		for _, ref := range other.Uses {
+			if ref.Request == node.Name {
+				feeds = append(feeds, fmt.Sprintf("%s (%s)", other.Name, ref))
+			}
+		}
+	}
+	if len(node.Uses) == 0 {
+		b.WriteString("Depends on: nothing\n")
+	} else {
+		var uses []string
+		for _, ref := range node.Uses {
+			uses = append(uses, fmt.Sprintf("%s (%s)", ref.Request, ref))
+		}
+		b.WriteString("Depends on: " + strings.Join(uses, ", ") + "\n")
+	}
+	if len(feeds) > 0 {
+		b.WriteString("Feeds: " + strings.Join(feeds, ", ") + "\n")
+	}
+
+	result, ok := m.results[node.Name]
+	if !ok {
+		return b.String()
+	}
+	if result.Error != "" {
+		b.WriteString(warningStyle.Render("Error: "+result.Error) + "\n")
+	}
+	for _, failure := range result.Failures {
+		b.WriteString(warningStyle.Render("Assert: "+failure) + "\n")
+	}
+	for _, violation := range result.Violations {
+		b.WriteString(warningStyle.Render("Budget: "+violation) + "\n")
+	}
+	return b.String()
+}
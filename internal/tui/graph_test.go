@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/runner"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestGraphModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token": "secret"}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	c := &collection.Collection{
+		Name: "flow",
+		Requests: []collection.SavedRequest{
+			{Name: "login", Request: request.RequestData{Method: "POST", URL: server.URL + "/login"}},
+			{Name: "me", Request: request.RequestData{
+				Method:  "GET",
+				URL:     server.URL + "/me",
+				Headers: map[string]string{"Authorization": "Bearer {{login.response.body.$.token}}"},
+			}},
+			{Name: "broken", Request: request.RequestData{Method: "GET", URL: server.URL + "/broken"}},
+		},
+	}
+	model := NewGraphModel(c, runner.Options{})
+
+	view := model.View()
+	for _, want := range []string{"Run: flow", "login", "pending", "← login body.$.token", "Feeds: me (body.$.token)", "Running..."} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the graph to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	// Drive the run the way the program does, one progress message at a time
+	go model.run()
+	sawRunning := false
+	for model.Report() == nil {
+		msg := model.nextEvent()
+		newModel, _ := model.Update(msg)
+		model = newModel.(GraphModel)
+		if _, ok := msg.(runStartedMsg); ok && strings.Contains(model.View(), "running") {
+			sawRunning = true
+		}
+	}
+	if !sawRunning {
+		t.Error("Expected requests to be shown as running while in flight")
+	}
+
+	view = model.View()
+	for _, want := range []string{"PASS  200", "FAIL  500", "Done: 2 passed, 1 failed"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the finished graph to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	// Selecting a node shows its details
+	update := func(key string) {
+		newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		model = newModel.(GraphModel)
+	}
+	update("j")
+	if view := model.View(); !strings.Contains(view, "Depends on: login (body.$.token)") {
+		t.Errorf("Expected the selected node's dependencies, got:\n%s", view)
+	}
+	if _, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd == nil {
+		t.Error("Expected q to quit after the run")
+	}
+}
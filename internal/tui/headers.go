@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// disabledHeaderPrefix marks a header entry that is kept in the form but not sent
+const disabledHeaderPrefix = "#"
+
+// splitHeaderEntries splits the headers field into its comma-separated entries
+func splitHeaderEntries(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// parseHeaderEntry returns the name and value of a header entry and whether
+// it is disabled
+func parseHeaderEntry(entry string) (name, value string, disabled, ok bool) {
+	entry = strings.TrimSpace(entry)
+	if strings.HasPrefix(entry, disabledHeaderPrefix) {
+		disabled = true
+		entry = strings.TrimSpace(strings.TrimPrefix(entry, disabledHeaderPrefix))
+	}
+	name, value, ok = strings.Cut(entry, ":")
+	return strings.TrimSpace(name), strings.TrimSpace(value), disabled, ok
+}
+
+// toggleHeaderAt enables or disables the header entry under the cursor position
+func toggleHeaderAt(value string, pos int) string {
+	entries := splitHeaderEntries(value)
+	offset := 0
+	for i, entry := range entries {
+		if pos <= offset+len(entry) {
+			trimmed := strings.TrimSpace(entry)
+			if strings.HasPrefix(trimmed, disabledHeaderPrefix) {
+				entries[i] = strings.TrimSpace(strings.TrimPrefix(trimmed, disabledHeaderPrefix))
+			} else if trimmed != "" {
+				entries[i] = disabledHeaderPrefix + trimmed
+			}
+			break
+		}
+		offset += len(entry) + 1
+	}
+	return strings.Join(entries, ",")
+}
+
+// applyHeaderPreset merges the preset headers into the headers field,
+// replacing and re-enabling any existing entries with the same name
+func applyHeaderPreset(value string, preset request.HeaderPreset) string {
+	entries := splitHeaderEntries(value)
+	applied := make(map[string]bool)
+	for i, entry := range entries {
+		name, _, _, ok := parseHeaderEntry(entry)
+		if !ok {
+			continue
+		}
+		for presetName, presetValue := range preset.Headers {
+			if strings.EqualFold(name, presetName) {
+				entries[i] = presetName + ":" + presetValue
+				applied[presetName] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(preset.Headers))
+	for name := range preset.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !applied[name] {
+			entries = append(entries, name+":"+preset.Headers[name])
+		}
+	}
+	return strings.Join(entries, ",")
+}
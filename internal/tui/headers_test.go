@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestToggleHeaderAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		pos   int
+		want  string
+	}{
+		{name: "disable first header", value: "Accept:text/plain,X-Debug:1", pos: 3, want: "#Accept:text/plain,X-Debug:1"},
+		{name: "disable second header", value: "Accept:text/plain,X-Debug:1", pos: 20, want: "Accept:text/plain,#X-Debug:1"},
+		{name: "enable header", value: "Accept:text/plain,#X-Debug:1", pos: 27, want: "Accept:text/plain,X-Debug:1"},
+		{name: "empty value", value: "", pos: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toggleHeaderAt(tt.value, tt.pos); got != tt.want {
+				t.Errorf("toggleHeaderAt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHeaderPreset(t *testing.T) {
+	preset := request.HeaderPreset{
+		Name: "JSON API",
+		Headers: map[string]string{
+			"Accept":       "application/json",
+			"Content-Type": "application/json",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "empty headers", value: "", want: "Accept:application/json,Content-Type:application/json"},
+		{name: "keeps other headers", value: "X-Debug:1", want: "X-Debug:1,Accept:application/json,Content-Type:application/json"},
+		{name: "replaces and enables existing", value: "#accept:text/plain,X-Debug:1", want: "Accept:application/json,X-Debug:1,Content-Type:application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyHeaderPreset(tt.value, preset); got != tt.want {
+				t.Errorf("applyHeaderPreset() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package tui
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/history"
+)
+
+// historyPageRows is how many timeline rows are shown at once
+const historyPageRows = 20
+
+// historyDay groups the history entries of a day by host
+type historyDay struct {
+	// key is the local date, e.g. 2026-10-16, or empty for entries
+	// recorded before history kept times
+	key   string
+	hosts []historyHost
+	count int
+}
+
+// historyHost groups the entries of a day sent to a host
+type historyHost struct {
+	name string
+
+	// entries index the history, newest first
+	entries []int
+}
+
+// historyRow is a line of the timeline: a day, a host within a day, or an
+// entry of a host
+type historyRow struct {
+	day, host, entry int
+}
+
+// isEntry reports whether the row is an entry rather than a group
+func (r historyRow) isEntry() bool {
+	return r.entry >= 0
+}
+
+// historyTimeline groups the entries by day, newest first, and within a
+// day by host, the most recently used first
+func historyTimeline(entries []history.Entry) []historyDay {
+	var days []historyDay
+	dayIndex := make(map[string]int)
+	hostIndex := make(map[string]int)
+	for i := len(entries) - 1; i >= 0; i-- {
+		key := ""
+		if !entries[i].Time.IsZero() {
+			key = entries[i].Time.Local().Format(time.DateOnly)
+		}
+		d, ok := dayIndex[key]
+		if !ok {
+			d = len(days)
+			dayIndex[key] = d
+			days = append(days, historyDay{key: key})
+		}
+		host := entryHost(entries[i])
+		h, ok := hostIndex[key+"\x00"+host]
+		if !ok {
+			h = len(days[d].hosts)
+			hostIndex[key+"\x00"+host] = h
+			days[d].hosts = append(days[d].hosts, historyHost{name: host})
+		}
+		days[d].hosts[h].entries = append(days[d].hosts[h].entries, i)
+		days[d].count++
+	}
+
+	// Entries are appended as they are sent, so days are mostly in order
+	// already; undated entries go last
+	slices.SortStableFunc(days, func(a, b historyDay) int {
+		switch {
+		case a.key == b.key:
+			return 0
+		case a.key == "":
+			return 1
+		case b.key == "":
+			return -1
+		}
+		return strings.Compare(b.key, a.key)
+	})
+	return days
+}
+
+// entryHost returns the host an entry was sent to
+func entryHost(entry history.Entry) string {
+	if u, err := url.Parse(entry.ResolvedURL()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "(no host)"
+}
+
+// historyDayKey and historyHostKey identify groups in historyOpen
+func historyDayKey(day historyDay) string {
+	return "day:" + day.key
+}
+
+func historyHostKey(day historyDay, host historyHost) string {
+	return "host:" + day.key + "\x00" + host.name
+}
+
+// historyRows lists the visible rows of the timeline
+func (m Model) historyRows(days []historyDay) []historyRow {
+	var rows []historyRow
+	for d, day := range days {
+		rows = append(rows, historyRow{day: d, host: -1, entry: -1})
+		if !m.historyOpen[historyDayKey(day)] {
+			continue
+		}
+		for h, host := range day.hosts {
+			rows = append(rows, historyRow{day: d, host: h, entry: -1})
+			if !m.historyOpen[historyHostKey(day, host)] {
+				continue
+			}
+			for _, entry := range host.entries {
+				rows = append(rows, historyRow{day: d, host: h, entry: entry})
+			}
+		}
+	}
+	return rows
+}
+
+// historyEntries returns the recorded entries, if history is kept
+func (m Model) historyEntries() []history.Entry {
+	if m.history == nil {
+		return nil
+	}
+	return m.history.GetAll()
+}
+
+// openHistory shows the timeline, with the newest day expanded the first
+// time
+func (m *Model) openHistory() {
+	if m.historyOpen == nil {
+		m.historyOpen = make(map[string]bool)
+		if days := historyTimeline(m.historyEntries()); len(days) > 0 {
+			m.expandDay(days[0])
+		}
+	}
+	m.historyCursor = 0
+	m.historyStatus = ""
+	m.screen = screenHistory
+}
+
+// expandDay expands a day and its hosts
+func (m *Model) expandDay(day historyDay) {
+	m.historyOpen[historyDayKey(day)] = true
+	for _, host := range day.hosts {
+		m.historyOpen[historyHostKey(day, host)] = true
+	}
+}
+
+// toggleHistoryRow expands or collapses the group under the cursor, or
+// loads the entry under it into the request form
+func (m *Model) toggleHistoryRow() {
+	days := historyTimeline(m.historyEntries())
+	rows := m.historyRows(days)
+	if m.historyCursor >= len(rows) {
+		return
+	}
+	row := rows[m.historyCursor]
+	day := days[row.day]
+	switch {
+	case row.isEntry():
+		entry := m.historyEntries()[row.entry]
+		m.fillForm(&entry.RequestData)
+		m.activeInput = inputURL
+		focusInput(m.inputs, m.activeInput)
+		m.formStatus = fmt.Sprintf("Loaded %s %s from history", entry.Method, entry.URL)
+		m.screen = screenRequest
+	case row.host >= 0:
+		key := historyHostKey(day, day.hosts[row.host])
+		m.historyOpen[key] = !m.historyOpen[key]
+	default:
+		key := historyDayKey(day)
+		m.historyOpen[key] = !m.historyOpen[key]
+	}
+}
+
+// collapseHistoryRow collapses the group under the cursor, or the group
+// the row under it belongs to, moving the cursor to the group
+func (m *Model) collapseHistoryRow() {
+	days := historyTimeline(m.historyEntries())
+	rows := m.historyRows(days)
+	if m.historyCursor >= len(rows) {
+		return
+	}
+	row := rows[m.historyCursor]
+	day := days[row.day]
+	target := historyRow{day: row.day, host: -1, entry: -1}
+	switch {
+	case row.isEntry():
+		m.historyOpen[historyHostKey(day, day.hosts[row.host])] = false
+		target.host = row.host
+	case row.host >= 0 && m.historyOpen[historyHostKey(day, day.hosts[row.host])]:
+		m.historyOpen[historyHostKey(day, day.hosts[row.host])] = false
+		target.host = row.host
+	default:
+		m.historyOpen[historyDayKey(day)] = false
+	}
+	m.historyCursor = max(slices.Index(m.historyRows(days), target), 0)
+}
+
+// expandHistoryRow expands the group under the cursor
+func (m *Model) expandHistoryRow() {
+	days := historyTimeline(m.historyEntries())
+	rows := m.historyRows(days)
+	if m.historyCursor >= len(rows) {
+		return
+	}
+	row := rows[m.historyCursor]
+	day := days[row.day]
+	switch {
+	case row.isEntry():
+	case row.host >= 0:
+		m.historyOpen[historyHostKey(day, day.hosts[row.host])] = true
+	default:
+		m.historyOpen[historyDayKey(day)] = true
+	}
+}
+
+// jumpToDate expands the newest day on or before the date typed in the
+// jump prompt and moves the cursor to it
+func (m *Model) jumpToDate(value string) {
+	target, err := history.ParseDate(value, time.Now())
+	if err != nil {
+		m.historyStatus = err.Error()
+		return
+	}
+	days := historyTimeline(m.historyEntries())
+	for d, day := range days {
+		if day.key != "" && day.key <= target {
+			m.expandDay(day)
+			m.historyCursor = slices.Index(m.historyRows(days), historyRow{day: d, host: -1, entry: -1})
+			m.historyStatus = ""
+			if day.key != target {
+				m.historyStatus = fmt.Sprintf("No history on %s; showing %s", target, day.key)
+			}
+			return
+		}
+	}
+	m.historyStatus = fmt.Sprintf("No history on or before %s", target)
+}
+
+// renderHistoryScreen shows the history timeline
+func (m Model) renderHistoryScreen() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("History"))
+	b.WriteString("\n\n")
+
+	entries := m.historyEntries()
+	if len(entries) == 0 {
+		b.WriteString(blurredStyle.Render("No requests in history yet") + "\n")
+		b.WriteString("\nESC to go back • Ctrl+C to quit\n")
+		return b.String()
+	}
+
+	days := historyTimeline(entries)
+	rows := m.historyRows(days)
+	start := min(max(m.historyCursor-historyPageRows/2, 0), max(len(rows)-historyPageRows, 0))
+	end := min(start+historyPageRows, len(rows))
+	if start > 0 {
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("  ↑ %d more", start)) + "\n")
+	}
+	now := time.Now()
+	for i := start; i < end; i++ {
+		line := m.renderHistoryRow(days, rows[i], entries, now)
+		if i == m.historyCursor {
+			b.WriteString(focusedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if end < len(rows) {
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("  ↓ %d more", len(rows)-end)) + "\n")
+	}
+
+	if m.jumping {
+		b.WriteString("\n" + m.historyJump.View() + "\n")
+	}
+	if m.historyStatus != "" {
+		b.WriteString("\n" + warningStyle.Render(m.historyStatus) + "\n")
+	}
+	b.WriteString("\n↑/↓ to move • Enter to expand or collapse, or to load a request into the form • ←/→ to collapse/expand • g to jump to a date • ESC to go back\n")
+	return b.String()
+}
+
+// renderHistoryRow renders a day, host, or entry line of the timeline
+func (m Model) renderHistoryRow(days []historyDay, row historyRow, entries []history.Entry, now time.Time) string {
+	day := days[row.day]
+	marker := func(open bool) string {
+		if open {
+			return "▾ "
+		}
+		return "▸ "
+	}
+	switch {
+	case row.isEntry():
+		entry := entries[row.entry]
+		when := "--:--:--"
+		if !entry.Time.IsZero() {
+			when = entry.Time.Local().Format(time.TimeOnly)
+		}
+		target := entry.ResolvedURL()
+		if u, err := url.Parse(target); err == nil && u.Host != "" {
+			target = u.RequestURI()
+		}
+		badges := historyBadges(entry)
+		if m.history != nil && m.history.SameAsPrevious(row.entry) {
+			badges += " " + blurredStyle.Render("[same as previous]")
+		}
+		return fmt.Sprintf("    %s  %s %s  %s", when, entry.Method, target, badges)
+	case row.host >= 0:
+		host := day.hosts[row.host]
+		return fmt.Sprintf("  %s%s · %s", marker(m.historyOpen[historyHostKey(day, host)]), host.name, pluralRequests(len(host.entries)))
+	}
+	return fmt.Sprintf("%s%s · %s", marker(m.historyOpen[historyDayKey(day)]), historyDayLabel(day.key, now), pluralRequests(day.count))
+}
+
+// historyBadges renders the status and latency of an entry's response
+func historyBadges(entry history.Entry) string {
+	resp := entry.Response
+	if resp == nil {
+		return blurredStyle.Render("[no response]")
+	}
+	status := fmt.Sprintf("[%d]", resp.StatusCode)
+	switch {
+	case resp.StatusCode >= 500 || resp.StatusCode == 0:
+		status = failStyle.Render(status)
+	case resp.StatusCode >= 400:
+		status = warningStyle.Render(status)
+	default:
+		status = passStyle.Render(status)
+	}
+	if resp.ResponseTime <= 0 {
+		return status
+	}
+	latency := fmt.Sprintf("[%v]", resp.ResponseTime.Round(time.Millisecond))
+	switch {
+	case resp.ResponseTime >= 3*time.Second:
+		latency = failStyle.Render(latency)
+	case resp.ResponseTime >= time.Second:
+		latency = warningStyle.Render(latency)
+	default:
+		latency = blurredStyle.Render(latency)
+	}
+	return status + " " + latency
+}
+
+// historyDayLabel names a day of the timeline
+func historyDayLabel(key string, now time.Time) string {
+	if key == "" {
+		return "Undated (recorded before history kept times)"
+	}
+	date, err := time.ParseInLocation(time.DateOnly, key, now.Location())
+	if err != nil {
+		return key
+	}
+	label := date.Format("Mon 2 Jan 2006")
+	switch key {
+	case now.Format(time.DateOnly):
+		label = "Today, " + label
+	case now.AddDate(0, 0, -1).Format(time.DateOnly):
+		label = "Yesterday, " + label
+	}
+	return label
+}
+
+// pluralRequests counts requests
+func pluralRequests(n int) string {
+	if n == 1 {
+		return "1 request"
+	}
+	return fmt.Sprintf("%d requests", n)
+}
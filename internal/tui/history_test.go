@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestModel_HistoryTimeline(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	earlier := today.AddDate(0, 0, -3)
+	entries := []history.Entry{
+		{RequestData: request.RequestData{Method: "GET", URL: "https://api.example.com/old"}},
+		{RequestData: request.RequestData{Method: "POST", URL: "https://api.example.com/orders", Body: `{"id":1}`},
+			Response: &history.ResponseSummary{StatusCode: 201}, Time: earlier.Add(12 * time.Hour)},
+		{RequestData: request.RequestData{Method: "GET", URL: "https://other.example.com/health"},
+			Response: &history.ResponseSummary{StatusCode: 503, ResponseTime: 4 * time.Second}, Time: today.Add(10 * time.Hour)},
+		{RequestData: request.RequestData{Method: "GET", URL: "https://api.example.com/users?page=2"},
+			Response: &history.ResponseSummary{StatusCode: 200, ResponseTime: 120 * time.Millisecond}, Time: today.Add(11 * time.Hour)},
+		{RequestData: request.RequestData{Method: "DELETE", URL: "https://api.example.com/users/7"}, Time: today.Add(12 * time.Hour)},
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := history.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	model := NewModel().WithHistory(h)
+	update := func(msg tea.Msg) {
+		newModel, _ := model.Update(msg)
+		model = newModel.(Model)
+	}
+	typeText := func(text string) {
+		update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+	}
+	rows := func() int {
+		return len(model.historyRows(historyTimeline(model.historyEntries())))
+	}
+
+	// The newest day opens expanded, with the most recently used host first
+	update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if model.screen != screenHistory {
+		t.Fatalf("Expected the history screen, got %d", model.screen)
+	}
+	view := model.View()
+	for _, want := range []string{"Today, ", "3 requests", "api.example.com · 2 requests", "other.example.com · 1 request",
+		"DELETE /users/7", "[no response]", "GET /users?page=2", "[200]", "[120ms]", "[503]", "[4s]", "Undated"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the timeline, got %s", want, view)
+		}
+	}
+	if strings.Index(view, "api.example.com") > strings.Index(view, "other.example.com") {
+		t.Errorf("Expected the most recently used host first, got %s", view)
+	}
+	if strings.Contains(view, "/orders") || rows() != 8 {
+		t.Errorf("Expected older days to start collapsed, got %d rows: %s", rows(), view)
+	}
+
+	// Left collapses the host, then the day it belongs to, and right
+	// expands the day again
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	update(tea.KeyMsg{Type: tea.KeyLeft})
+	if rows() != 6 || model.historyCursor != 1 {
+		t.Errorf("Expected the host to collapse, got %d rows with the cursor on %d", rows(), model.historyCursor)
+	}
+	update(tea.KeyMsg{Type: tea.KeyLeft})
+	if rows() != 3 || model.historyCursor != 0 {
+		t.Errorf("Expected the day to collapse, got %d rows with the cursor on %d", rows(), model.historyCursor)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRight})
+	if rows() != 6 {
+		t.Errorf("Expected the day to expand, got %d rows", rows())
+	}
+
+	// Jumping finds the newest day on or before the date
+	typeText("g")
+	typeText(today.AddDate(0, 0, -1).Format(time.DateOnly))
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.historyCursor != 4 || rows() != 8 {
+		t.Errorf("Expected the cursor on the expanded earlier day, got %d rows with the cursor on %d", rows(), model.historyCursor)
+	}
+	if !strings.Contains(model.View(), "showing "+earlier.Format(time.DateOnly)) {
+		t.Errorf("Expected the nearest day to be reported, got %s", model.View())
+	}
+	typeText("g")
+	typeText("2000-01-01")
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(model.View(), "No history on or before 2000-01-01") || model.historyCursor != 4 {
+		t.Errorf("Expected a missing date to be reported, got %s", model.View())
+	}
+
+	// Enter on an entry loads it into the form, and the response to it is
+	// recorded
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.screen != screenRequest || model.inputs[inputURL].textinput.Value() != "https://api.example.com/orders" ||
+		model.inputs[inputMethod].textinput.Value() != "POST" || model.inputs[inputBody].textinput.Value() != `{"id":1}` {
+		t.Errorf("Expected the entry in the form, got %s", model.View())
+	}
+	if !strings.Contains(model.View(), "Loaded POST https://api.example.com/orders from history") {
+		t.Errorf("Expected the load to be reported, got %s", model.View())
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	update(&request.ResponseData{StatusCode: 201, ResponseTime: time.Millisecond})
+	if got := h.GetAll(); len(got) != 6 || got[5].URL != "https://api.example.com/orders" || got[5].Time.IsZero() {
+		t.Errorf("Expected the response to be recorded, got %+v", got[len(got)-1])
+	}
+}
+
+func TestModel_HistorySameAsPrevious(t *testing.T) {
+	h, err := history.Open(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	users := request.RequestData{Method: "GET", URL: "https://api.example.com/users"}
+	for _, body := range []string{`[]`, `[]`, `[{"id":1}]`} {
+		if err := h.AddWithResponse(users, &request.ResponseData{StatusCode: 200, Body: body}); err != nil {
+			t.Fatalf("AddWithResponse() error = %v", err)
+		}
+	}
+
+	model := NewModel().WithHistory(h)
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	view := newModel.(Model).View()
+	if strings.Count(view, "[same as previous]") != 1 {
+		t.Errorf("Expected only the repeated response to be marked, got %s", view)
+	}
+}
@@ -0,0 +1,22 @@
+package tui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// keyPresets maps the keys of each key binding preset to the keys they
+// stand for
+var keyPresets = map[string]map[tea.KeyType]tea.KeyType{
+	"emacs": {
+		tea.KeyCtrlN: tea.KeyDown,
+		tea.KeyCtrlP: tea.KeyUp,
+		tea.KeyCtrlG: tea.KeyEsc,
+	},
+}
+
+// remapKey translates a key of the configured preset into the key it
+// stands for
+func (m Model) remapKey(msg tea.KeyMsg) tea.KeyMsg {
+	if key, ok := keyPresets[m.config.Keybindings][msg.Type]; ok {
+		return tea.KeyMsg{Type: key}
+	}
+	return msg
+}
@@ -1,14 +1,32 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/nshekhawat/lighttr/internal/request"
+	"github.com/nshekhawat/lighttr/internal/authprofile"
+	"github.com/nshekhawat/lighttr/internal/azure"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/kerberos"
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/internal/queue"
+	"github.com/nshekhawat/lighttr/pkg/assertion"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
 )
 
 var (
@@ -19,6 +37,10 @@ var (
 	blurredStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("240"))
 
+	warningStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
+
 	titleStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("205")).
 			Bold(true).
@@ -30,38 +52,249 @@ type inputField struct {
 	label     string
 }
 
+// Indexes of the request form inputs
+const (
+	inputURL = iota
+	inputMethod
+	inputAuthType
+	inputAuthUsername
+	inputAuthPassword
+	inputBearerToken
+	inputAPIKey
+	inputAPIKeyPlacement
+	inputTLSCertFile
+	inputTLSKeyFile
+	inputTLSCertSubject
+	inputOAuth2Profile
+	inputGCPAudience
+	inputAzureTenant
+	inputAzureClientID
+	inputAzureClientSecret
+	inputAzureScope
+	inputJWTClaims
+	inputJWTKeyFile
+	inputJWTAlg
+	inputAuthProfile
+	inputSessionLogin
+	inputSessionBody
+	inputSessionExtract
+	inputSessionHeader
+	inputTLSCAFile
+	inputTLSCAOnly
+	inputTLSInsecure
+	inputTLSServerName
+	inputHeaders
+	inputQueryParams
+	inputPathParams
+	inputMethodOverride
+	inputTimeout
+	inputMaxRedirects
+	inputRetries
+	inputHTTPVersion
+	inputProxy
+	inputProxyBypass
+	inputFormParams
+	inputBody
+	inputBodyFile
+	inputMultipart
+)
+
 type screen int
 
 const (
 	screenRequest screen = iota
+	screenPathParams
 	screenPreview
 	screenResponse
+	screenQueue
+	screenClipboard
+	screenHistory
+	screenWebSocket
+	screenOAuth
 )
 
 type Model struct {
 	inputs      []inputField
 	activeInput int
+	paramInputs []inputField
+	activeParam int
 	requestData *request.RequestData
 	response    *request.ResponseData
 	screen      screen
 	viewport    viewport.Model
 	err         error
 	authType    request.AuthType
+	config      *config.Config
+
+	// cookies carries cookies between the requests of a session
+	cookies *request.CookieJar
+
+	// recorder, when set, appends every exchange of the session to a HAR
+	// file
+	recorder *request.HARRecorder
+
+	// focused tracks whether the terminal has focus, as far as it reports
+	// focus changes; sentAt is when the last request was sent, to notify the
+	// completion of slow requests while the terminal is unfocused
+	focused bool
+	sentAt  time.Time
+
+	// retryIn counts down to re-sending a request that was answered with
+	// Retry-After; zero when no retry is pending. retryAt is when the
+	// request is sent again.
+	retryIn time.Duration
+	retryAt time.Time
+
+	// urlWarnings lists the mistakes corrected in the typed URL
+	urlWarnings []string
+
+	// filter narrows the body of an XML response with XPath or of an HTML
+	// response with a CSS selector; filtering is set while the filter bar
+	// has focus
+	filter    textinput.Model
+	filtering bool
+
+	// decodeHeaders shows the decoded value under each response header
+	// that carries a JWT, base64, gzip, or protobuf value
+	decodeHeaders bool
+
+	// savePath holds the file the response body is saved to; saving is set
+	// while it has focus and saved reports the outcome of the last save
+	savePath textinput.Model
+	saving   bool
+	saved    string
+
+	// console is the log of everything done this session, shown in a pane
+	// below the current screen while showConsole is set
+	console     []consoleEntry
+	showConsole bool
+
+	// offline queues requests instead of sending them, until the queue is
+	// flushed from the queue screen; queueCursor selects a queued request
+	// and queueStatus reports the last change to the queue
+	queue       *queue.Queue
+	offline     bool
+	queueCursor int
+	flushing    bool
+	queueStatus string
+
+	// clipboardOptions are the ways to import the clipboard offered on the
+	// clipboard screen and clipboardCursor selects one
+	clipboardOptions []clipboardChoice
+	clipboardCursor  int
+
+	// history records every response and is browsed on the history screen
+	// as a timeline grouped by day and host: historyOpen holds the expanded
+	// groups and historyCursor selects a row; jumping is set while the
+	// jump-to-date prompt has focus and historyStatus reports its outcome
+	history       *history.Manager
+	historyOpen   map[string]bool
+	historyCursor int
+	historyJump   textinput.Model
+	jumping       bool
+	historyStatus string
+
+	// formStatus reports the last change made to the form from elsewhere,
+	// such as an import from the clipboard or history
+	formStatus string
+
+	// suggestion is what recent requests to suggestedHost, the URL's host,
+	// had in common, offered until the form has it and applied with Alt+A
+	suggestion    *history.Suggestion
+	suggestedHost string
+
+	// asserts are checked against every response and saved with the
+	// request; asserting is set while the assertion prompt has focus and
+	// assertStatus reports why the last assertion was not added
+	asserts      []assertion.Assertion
+	assertInput  textinput.Model
+	asserting    bool
+	assertStatus string
+
+	// collections stores the requests saved with Ctrl+S: saveAs holds the
+	// collection/request to save to while savingRequest is set, and savedAs
+	// the last one saved to
+	collections   *collection.Manager
+	saveAs        textinput.Model
+	savingRequest bool
+	savedAs       string
+
+	// env resolves the {{var}} placeholders of the form, before the process
+	// environment, when the request is sent and in the resolved pane
+	env *environment.Environment
+
+	// websocket is the connection open on the WebSocket screen, where
+	// websocketFrames logs the messages sent and received and
+	// websocketStatus reports the state of the connection. websocketSent
+	// holds the messages sent this session, recalled with ↑/↓ from
+	// websocketRecall.
+	websocket       *request.WebSocket
+	websocketInput  textinput.Model
+	websocketFrames []websocketFrame
+	websocketStatus string
+	websocketSent   []string
+	websocketRecall int
+
+	// oauthLegs are the legs of the OAuth2 flows of the request's client
+	// shown on the OAuth screen, oauthClient describes whose they are, and
+	// oauthCursor selects the leg shown in full. Esc returns to
+	// oauthReturn.
+	oauthLegs   []oauth.Leg
+	oauthClient string
+	oauthCursor int
+	oauthStatus string
+	oauthReturn screen
+
+	// tutorial, when set, walks through building, sending, asserting, and
+	// saving a request against the demo API with a hint on every screen
+	tutorial *Tutorial
 }
 
 func NewModel() Model {
 	inputs := []inputField{
-		{label: "URL", textinput: textinput.New()},
-		{label: "Method", textinput: textinput.New()},
-		{label: "Auth Type (none/basic/apikey/mtls)", textinput: textinput.New()},
-		{label: "Auth Username", textinput: textinput.New()},
-		{label: "Auth Password", textinput: textinput.New()},
-		{label: "API Key", textinput: textinput.New()},
-		{label: "TLS Cert File", textinput: textinput.New()},
-		{label: "TLS Key File", textinput: textinput.New()},
-		{label: "Headers (key:value,key2:value2)", textinput: textinput.New()},
-		{label: "Query Params (key=value&key2=value2)", textinput: textinput.New()},
-		{label: "Body", textinput: textinput.New()},
+		{label: "URL", textinput: newInput()},
+		{label: "Method", textinput: newInput()},
+		{label: "Auth Type (none/basic/bearer/apikey/mtls/oauth2/gcp/azure/jwt/ntlm/negotiate/profile/session)", textinput: newInput()},
+		{label: "Auth Username", textinput: newInput()},
+		{label: "Auth Password", textinput: newInput()},
+		{label: "Bearer Token", textinput: newInput()},
+		{label: "API Key", textinput: newInput()},
+		{label: "API Key In (Header[: Prefix] or ?query)", textinput: newInput()},
+		{label: "TLS Cert File", textinput: newInput()},
+		{label: "TLS Key File", textinput: newInput()},
+		{label: "TLS Cert Subject (system keystore, instead of files)", textinput: newInput()},
+		{label: "OAuth2 Profile (log in with lighttr oauth login)", textinput: newInput()},
+		{label: "GCP Audience (ID token for Cloud Run; empty for an access token)", textinput: newInput()},
+		{label: "Azure Tenant (ID or domain)", textinput: newInput()},
+		{label: "Azure Client ID", textinput: newInput()},
+		{label: "Azure Client Secret (empty for lighttr azure login)", textinput: newInput()},
+		{label: "Azure Scope (default Microsoft Graph)", textinput: newInput()},
+		{label: "JWT Claims (JSON; iat and exp are added)", textinput: newInput()},
+		{label: "JWT Key File (secret for HS256, PEM key otherwise)", textinput: newInput()},
+		{label: "JWT Algorithm (HS256/RS256/ES256)", textinput: newInput()},
+		{label: "Auth Profile (save with lighttr auth save)", textinput: newInput()},
+		{label: "Session Login URL (POSTed to log in before the request)", textinput: newInput()},
+		{label: "Session Login Body ({{username}} and {{password}} filled in; empty for basic auth)", textinput: newInput()},
+		{label: "Session Credential ($.path, header:Name, or cookie:Name of the login response)", textinput: newInput()},
+		{label: "Session Token Header (Header[: Prefix])", textinput: newInput()},
+		{label: "TLS CA File (trust an internal CA)", textinput: newInput()},
+		{label: "TLS CA Only (true/false; not the system CAs)", textinput: newInput()},
+		{label: "TLS Skip Verify (true/false)", textinput: newInput()},
+		{label: "TLS Server Name (SNI and name verified; default the URL's host)", textinput: newInput()},
+		{label: "Headers (key:value,key2:value2)", textinput: newInput()},
+		{label: "Query Params (key=value&key2=value2)", textinput: newInput()},
+		{label: "Path Params (key=value&key2=value2)", textinput: newInput()},
+		{label: "Method Override Header (sends PUT/PATCH/DELETE as POST)", textinput: newInput()},
+		{label: "Timeouts (total[,connect,tls,headers])", textinput: newInput()},
+		{label: "Max Redirects (0 to not follow)", textinput: newInput()},
+		{label: "Retries (count[,backoff])", textinput: newInput()},
+		{label: "HTTP Version (auto/1.1/2/3)", textinput: newInput()},
+		{label: "Proxy (blank for HTTP_PROXY/HTTPS_PROXY)", textinput: newInput()},
+		{label: "Proxy Bypass (hosts, .domains, CIDRs)", textinput: newInput()},
+		{label: "Form Params (key=value&key2=value2, sent urlencoded)", textinput: newInput()},
+		{label: "Body", textinput: newInput()},
+		{label: "Body File (streamed instead of Body)", textinput: newInput()},
+		{label: "Multipart Form (name=value,file=@path)", textinput: newInput()},
 	}
 
 	// Configure inputs
@@ -78,20 +311,70 @@ func NewModel() Model {
 	}
 
 	// Configure input placeholders
-	inputs[0].textinput.Placeholder = "https://api.example.com/path"
-	inputs[1].textinput.Placeholder = "GET"
-	inputs[1].textinput.SetValue("GET")
-	inputs[2].textinput.Placeholder = "none"
-	inputs[2].textinput.SetValue("none")
-	inputs[3].textinput.Placeholder = "username"
-	inputs[4].textinput.Placeholder = "password"
-	inputs[4].textinput.EchoMode = textinput.EchoPassword
-	inputs[5].textinput.Placeholder = "your-api-key"
-	inputs[6].textinput.Placeholder = "/path/to/cert.pem"
-	inputs[7].textinput.Placeholder = "/path/to/key.pem"
-	inputs[8].textinput.Placeholder = "Content-Type:application/json"
-	inputs[9].textinput.Placeholder = "key=value&key2=value2"
-	inputs[10].textinput.Placeholder = "{\"key\": \"value\"}"
+	inputs[inputURL].textinput.Placeholder = "https://api.example.com/path"
+	inputs[inputMethod].textinput.Placeholder = "GET"
+	inputs[inputMethod].textinput.SetValue("GET")
+	inputs[inputAuthType].textinput.Placeholder = "none"
+	inputs[inputAuthType].textinput.SetValue("none")
+	inputs[inputAuthUsername].textinput.Placeholder = "username"
+	inputs[inputAuthPassword].textinput.Placeholder = "password"
+	inputs[inputAuthPassword].textinput.EchoMode = textinput.EchoPassword
+	inputs[inputBearerToken].textinput.Placeholder = "your-token"
+	inputs[inputBearerToken].textinput.EchoMode = textinput.EchoPassword
+	inputs[inputAPIKey].textinput.Placeholder = "your-api-key"
+	inputs[inputAPIKeyPlacement].textinput.Placeholder = "Authorization: Bearer"
+	inputs[inputTLSCertFile].textinput.Placeholder = "/path/to/cert.pem or bundle.p12"
+	inputs[inputTLSKeyFile].textinput.Placeholder = "/path/to/key.pem"
+	inputs[inputTLSCertSubject].textinput.Placeholder = "CN=jane.doe"
+	inputs[inputOAuth2Profile].textinput.Placeholder = "github"
+	inputs[inputGCPAudience].textinput.Placeholder = "https://my-service-abc123-uc.a.run.app"
+	inputs[inputAzureTenant].textinput.Placeholder = "contoso.onmicrosoft.com"
+	inputs[inputAzureClientID].textinput.Placeholder = "11111111-2222-3333-4444-555555555555"
+	inputs[inputAzureClientSecret].textinput.Placeholder = "client-secret"
+	inputs[inputAzureClientSecret].textinput.EchoMode = textinput.EchoPassword
+	inputs[inputAzureScope].textinput.Placeholder = "https://graph.microsoft.com/.default"
+	inputs[inputJWTClaims].textinput.Placeholder = `{"sub": "orders-service", "aud": "billing"}`
+	inputs[inputJWTKeyFile].textinput.Placeholder = "/path/to/signing-key.pem"
+	inputs[inputJWTAlg].textinput.Placeholder = "HS256"
+	inputs[inputAuthProfile].textinput.Placeholder = "billing"
+	inputs[inputSessionLogin].textinput.Placeholder = "https://api.example.com/login"
+	inputs[inputSessionBody].textinput.Placeholder = `{"username": "{{username}}", "password": "{{password}}"}`
+	inputs[inputSessionExtract].textinput.Placeholder = "$.token"
+	inputs[inputSessionHeader].textinput.Placeholder = "Authorization: Bearer"
+	inputs[inputTLSCAFile].textinput.Placeholder = "/path/to/ca.pem"
+	inputs[inputTLSCAOnly].textinput.Placeholder = "false"
+	inputs[inputTLSInsecure].textinput.Placeholder = "false"
+	inputs[inputTLSServerName].textinput.Placeholder = "api.internal.corp"
+	inputs[inputHeaders].textinput.Placeholder = "Content-Type:application/json,#X-Disabled:1"
+	inputs[inputQueryParams].textinput.Placeholder = "key=value&key2=value2"
+	inputs[inputPathParams].textinput.Placeholder = "id=123"
+	inputs[inputMethodOverride].textinput.Placeholder = request.DefaultMethodOverrideHeader
+	inputs[inputTimeout].textinput.Placeholder = "30s,5s"
+	inputs[inputMaxRedirects].textinput.Placeholder = strconv.Itoa(request.DefaultMaxRedirects)
+	inputs[inputRetries].textinput.Placeholder = "3,500ms"
+	inputs[inputHTTPVersion].textinput.Placeholder = "auto"
+	inputs[inputProxy].textinput.Placeholder = "http://proxy.corp:3128"
+	inputs[inputProxyBypass].textinput.Placeholder = "localhost,.internal.corp,10.0.0.0/8"
+	inputs[inputFormParams].textinput.Placeholder = "user=jane&remember=true"
+	inputs[inputBody].textinput.Placeholder = "{\"key\": \"value\"}"
+	inputs[inputBodyFile].textinput.Placeholder = "/path/to/payload.json"
+	inputs[inputMultipart].textinput.Placeholder = "name=Jane,avatar=@/path/to/avatar.png"
+
+	filter := newInput()
+	savePath := newInput()
+	savePath.Prompt = "Save to: "
+	historyJump := newInput()
+	historyJump.Prompt = "Jump to: "
+	historyJump.Placeholder = "2006-01-02, today, yesterday, or -3"
+	assertInput := newInput()
+	assertInput.Prompt = "Assert: "
+	assertInput.Placeholder = `status == 200, jsonpath "$.id" == 42, or header "Content-Type" contains "json"`
+	saveAs := newInput()
+	saveAs.Prompt = "Save as: "
+	saveAs.Placeholder = "collection/request"
+	websocketInput := newInput()
+	websocketInput.Prompt = "Message: "
+	websocketInput.Placeholder = `{"type": "subscribe"}`
 
 	return Model{
 		inputs:      inputs,
@@ -100,14 +383,83 @@ func NewModel() Model {
 		screen:      screenRequest,
 		viewport:    viewport.New(0, 0),
 		authType:    request.NoAuth,
+		config:      &config.Config{},
+		cookies:     request.NewCookieJar(),
+		queue:       queue.New(),
+		filter:      filter,
+		savePath:    savePath,
+		historyJump: historyJump,
+		assertInput: assertInput,
+		saveAs:      saveAs,
+		focused:     true,
+
+		websocketInput: websocketInput,
 	}
 }
 
+// WithConfig returns a copy of the model that uses the given configuration
+func (m Model) WithConfig(cfg *config.Config) Model {
+	m.config = cfg
+	return m
+}
+
+// WithCookies returns a copy of the model that sends and stores cookies in
+// the given jar
+func (m Model) WithCookies(jar *request.CookieJar) Model {
+	m.cookies = jar
+	return m
+}
+
+// WithRecorder returns a copy of the model that records every request of
+// the session to the HAR recorder
+func (m Model) WithRecorder(recorder *request.HARRecorder) Model {
+	m.recorder = recorder
+	return m
+}
+
+// WithQueue returns a copy of the model that queues requests sent while
+// offline in the given queue
+func (m Model) WithQueue(q *queue.Queue) Model {
+	m.queue = q
+	return m
+}
+
+// WithHistory returns a copy of the model that records every response in
+// the given history and shows it on the history screen
+func (m Model) WithHistory(h *history.Manager) Model {
+	m.history = h
+	return m
+}
+
+// WithCollections returns a copy of the model that saves requests and their
+// assertions to the given collections with Ctrl+S
+func (m Model) WithCollections(collections *collection.Manager) Model {
+	m.collections = collections
+	return m
+}
+
+// WithEnvironment returns a copy of the model that resolves placeholders
+// against the given environment before the process environment
+func (m Model) WithEnvironment(env *environment.Environment) Model {
+	m.env = env
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
-	return textinput.Blink
+	return blink()
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	if m, ok := model.(Model); ok && m.tutorial != nil {
+		// The tutorial moves on once a step is done, however it was done
+		m.tutorial.observe(&m)
+		return m, cmd
+	}
+	return model, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
@@ -115,49 +467,390 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case error:
 		// Handle error messages
 		m.err = msg
-		return m, nil
+		m.logf(logError, "%v", msg)
+		return m, tea.Batch(m.printTranscript(), m.notifyCompletion("Request failed", msg.Error()))
 	case *request.ResponseData:
 		// Handle the response from request execution
 		m.response = msg
+		m.logResponse(msg)
+		if m.history != nil {
+			if err := m.history.AddWithResponse(*m.requestData, msg); err != nil {
+				m.logf(logError, "recording history: %v", err)
+			}
+		}
+		return m, tea.Batch(m.printTranscript(), m.notifyCompletion(fmt.Sprintf("%d %s", msg.StatusCode, http.StatusText(msg.StatusCode)),
+			m.requestData.Method+" "+m.requestData.URL))
+	case queueFlushedMsg:
+		m.applyFlush(msg)
+		return m, nil
+	case clipboardMsg:
+		m.importClipboard(msg)
 		return m, nil
+	case websocketConnectedMsg, websocketReceivedMsg, websocketClosedMsg:
+		return m.updateWebSocket(msg)
+	case tea.FocusMsg:
+		m.focused = true
+		return m, nil
+	case tea.BlurMsg:
+		m.focused = false
+		return m, nil
+	case retryTickMsg:
+		if m.retryIn <= 0 || m.screen != screenResponse {
+			return m, nil
+		}
+		m.retryIn -= msg.elapsed
+		if m.retryIn > 0 {
+			return m, m.retryTick()
+		}
+		m.response = nil
+		m.err = nil
+		m.sentAt = time.Now()
+		m.logSend()
+		return m, m.executeRequest
 	case tea.KeyMsg:
+		msg = m.remapKey(msg)
+
+		// The WebSocket screen takes all keys but the console's
+		if m.screen == screenWebSocket && msg.String() != "ctrl+l" {
+			return m.updateWebSocket(msg)
+		}
+		if m.screen == screenOAuth && msg.String() != "ctrl+l" {
+			return m.updateOAuth(msg)
+		}
+
+		// The console can be toggled from any screen and prompt
+		switch msg.String() {
+		case "ctrl+l":
+			m.showConsole = !m.showConsole
+			return m, nil
+		case "ctrl+o":
+			m.offline = !m.offline
+			if m.offline {
+				m.logf(logInfo, "offline: sends are queued")
+			} else {
+				m.logf(logInfo, "online: %d requests queued", m.queue.Len())
+			}
+			return m, nil
+		case "ctrl+q":
+			if !m.saving && !m.filtering && !m.asserting && !m.savingRequest {
+				m.queueStatus = ""
+				m.queueCursor = 0
+				m.screen = screenQueue
+				return m, nil
+			}
+		case "ctrl+r":
+			if !m.saving && !m.filtering && !m.jumping && !m.asserting && !m.savingRequest {
+				m.openHistory()
+				return m, nil
+			}
+		case "alt+o":
+			if !m.saving && !m.filtering && !m.jumping && !m.asserting && !m.savingRequest {
+				m.openOAuth()
+				return m, nil
+			}
+		}
+
+		// The save prompt takes all keys while it has focus
+		if m.saving {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "enter":
+				m.saving = false
+				m.savePath.Blur()
+				// Existing files are kept; the body goes to a numbered name
+				path, err := m.response.SaveBodyNew(strings.TrimSpace(m.savePath.Value()))
+				if err != nil {
+					m.saved = err.Error()
+					m.logf(logError, "%v", err)
+				} else {
+					m.saved = fmt.Sprintf("Saved %d bytes to %s", len(m.response.Body), path)
+					m.logf(logInfo, "saved body to %s", path)
+				}
+				return m, nil
+			case "esc":
+				m.saving = false
+				m.savePath.Blur()
+				return m, nil
+			}
+			m.savePath, cmd = m.savePath.Update(msg)
+			return m, cmd
+		}
+
+		// The assertion prompt takes all keys while it has focus
+		if m.asserting {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "enter":
+				m.addAssertion(m.assertInput.Value())
+				return m, nil
+			case "esc":
+				m.asserting = false
+				m.assertInput.Blur()
+				m.assertInput.SetValue("")
+				return m, nil
+			}
+			m.assertInput, cmd = m.assertInput.Update(msg)
+			return m, cmd
+		}
+
+		// The save-as prompt takes all keys while it has focus
+		if m.savingRequest {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "enter":
+				m.savingRequest = false
+				m.saveAs.Blur()
+				m.saveRequest(strings.TrimSpace(m.saveAs.Value()))
+				return m, nil
+			case "esc":
+				m.savingRequest = false
+				m.saveAs.Blur()
+				return m, nil
+			}
+			m.saveAs, cmd = m.saveAs.Update(msg)
+			return m, cmd
+		}
+
+		// The filter bar takes all keys while it has focus
+		if m.filtering {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "enter":
+				m.filtering = false
+				m.filter.Blur()
+				return m, nil
+			case "esc":
+				m.filtering = false
+				m.filter.Blur()
+				m.filter.SetValue("")
+				return m, nil
+			}
+			m.filter, cmd = m.filter.Update(msg)
+			return m, cmd
+		}
+
+		// The jump-to-date prompt takes all keys while it has focus
+		if m.jumping {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "enter":
+				m.jumping = false
+				m.historyJump.Blur()
+				m.jumpToDate(m.historyJump.Value())
+				m.historyJump.SetValue("")
+				return m, nil
+			case "esc":
+				m.jumping = false
+				m.historyJump.Blur()
+				m.historyJump.SetValue("")
+				return m, nil
+			}
+			m.historyJump, cmd = m.historyJump.Update(msg)
+			return m, cmd
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
 
 		case "tab", "shift+tab", "up", "down":
+			if m.screen == screenPathParams {
+				m.activeParam = nextIndex(m.activeParam, len(m.paramInputs), msg.String())
+				focusInput(m.paramInputs, m.activeParam)
+				return m, nil
+			}
+
+			if m.screen == screenQueue {
+				m.queueCursor = nextIndex(m.queueCursor, m.queue.Len(), msg.String())
+				return m, nil
+			}
+
+			if m.screen == screenClipboard {
+				m.clipboardCursor = nextIndex(m.clipboardCursor, len(m.clipboardOptions), msg.String())
+				return m, nil
+			}
+
+			if m.screen == screenHistory {
+				rows := m.historyRows(historyTimeline(m.historyEntries()))
+				m.historyCursor = nextIndex(m.historyCursor, len(rows), msg.String())
+				return m, nil
+			}
+
 			// Handle navigation between inputs
 			if m.screen == screenRequest {
-				s := msg.String()
+				m.activeInput = nextIndex(m.activeInput, len(m.inputs), msg.String())
+				focusInput(m.inputs, m.activeInput)
+				return m, nil
+			}
 
-				if s == "up" || s == "shift+tab" {
-					m.activeInput--
-				} else {
-					m.activeInput++
+		case "alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8", "alt+9":
+			// Apply a header preset with a single keystroke
+			if m.screen == screenRequest {
+				index := int(msg.String()[len("alt+")] - '1')
+				if index < len(request.HeaderPresets) {
+					headers := &m.inputs[inputHeaders].textinput
+					headers.SetValue(applyHeaderPreset(headers.Value(), request.HeaderPresets[index]))
 				}
+				return m, nil
+			}
+
+		case "alt+v":
+			// Import a curl command, HTTP request, URL, or JSON body from
+			// the clipboard
+			if m.screen == screenRequest {
+				m.formStatus = ""
+				return m, pasteRequest
+			}
+
+		case "alt+a":
+			// Apply the headers and auth recent requests to the host used
+			if m.screen == screenRequest {
+				m.applySuggestion()
+				return m, nil
+			}
+
+		case "ctrl+t":
+			// Toggle the header under the cursor on or off
+			if m.screen == screenRequest && m.activeInput == inputHeaders {
+				headers := &m.inputs[inputHeaders].textinput
+				pos := headers.Position()
+				headers.SetValue(toggleHeaderAt(headers.Value(), pos))
+				headers.SetCursor(pos)
+				return m, nil
+			}
 
-				if m.activeInput >= len(m.inputs) {
-					m.activeInput = 0
-				} else if m.activeInput < 0 {
-					m.activeInput = len(m.inputs) - 1
+		case "r":
+			// Send a request that failed to reach the server again
+			if m.screen == screenResponse && m.err != nil {
+				m.err = nil
+				m.sentAt = time.Now()
+				m.logSend()
+				return m, m.executeRequest
+			}
+			// Wait out Retry-After, then send the request again
+			if m.screen == screenResponse && m.response != nil && m.response.RetryAfter > 0 && m.retryIn == 0 {
+				m.retryIn = m.response.RetryAfter.Round(time.Second)
+				if m.retryIn < time.Second {
+					m.retryIn = time.Second
 				}
+				m.retryAt = time.Now().Add(m.retryIn)
+				m.logf(logRetry, "waiting %v for Retry-After", m.retryIn)
+				return m, m.retryTick()
+			}
 
-				for i := range m.inputs {
-					if i == m.activeInput {
-						m.inputs[i].textinput.Focus()
-						continue
+		case "f":
+			// Send the queued requests
+			if m.screen == screenQueue && !m.flushing && m.queue.Len() > 0 {
+				m.flushing = true
+				m.queueStatus = ""
+				return m, m.flushQueue
+			}
+
+		case "d":
+			// Show or hide the decoded values of response headers
+			if m.screen == screenResponse && m.response != nil {
+				m.decodeHeaders = !m.decodeHeaders
+				return m, nil
+			}
+			// Drop the selected request from the queue
+			if m.screen == screenQueue && !m.flushing {
+				if items := m.queue.Items(); m.queueCursor < len(items) {
+					item := items[m.queueCursor]
+					if err := m.queue.Remove(item.ID); err != nil {
+						m.queueStatus = err.Error()
+					} else {
+						m.queueStatus = fmt.Sprintf("Deleted #%d", item.ID)
+						m.logf(logInfo, "deleted #%d from the queue", item.ID)
 					}
-					m.inputs[i].textinput.Blur()
+					m.queueCursor = min(m.queueCursor, max(m.queue.Len()-1, 0))
 				}
+				return m, nil
+			}
 
+		case "left", "right":
+			// Collapse or expand a group of the history timeline
+			if m.screen == screenHistory {
+				if msg.String() == "left" {
+					m.collapseHistoryRow()
+				} else {
+					m.expandHistoryRow()
+				}
 				return m, nil
 			}
 
+		case "g":
+			// Jump to a date in the history timeline
+			if m.screen == screenHistory && len(m.historyEntries()) > 0 {
+				m.jumping = true
+				m.historyStatus = ""
+				return m, m.historyJump.Focus()
+			}
+
+		case "/":
+			// Filter an XML or HTML response body
+			if m.screen == screenResponse && m.response != nil {
+				switch filterLanguage(m.response) {
+				case filterXPath:
+					m.filter.Prompt = "XPath: "
+					m.filter.Placeholder = "//Body/*/Price"
+				case filterCSS:
+					m.filter.Prompt = "CSS: "
+					m.filter.Placeholder = "table.prices td"
+				default:
+					return m, nil
+				}
+				m.filtering = true
+				return m, m.filter.Focus()
+			}
+
+		case "s":
+			// Save the response body to a file
+			if m.screen == screenResponse && m.response != nil && m.response.Body != "" {
+				if m.savePath.Value() == "" {
+					m.savePath.SetValue(m.response.SuggestedFilename(m.requestData.ResolvedURL()))
+				}
+				m.saving = true
+				m.saved = ""
+				return m, m.savePath.Focus()
+			}
+
+		case "a":
+			// Add an assertion on the response
+			if m.screen == screenResponse && m.response != nil {
+				m.asserting = true
+				m.assertStatus = ""
+				return m, m.assertInput.Focus()
+			}
+
+		case "ctrl+s":
+			// Save the request and its assertions to a collection
+			if m.screen == screenResponse && m.response != nil && m.collections != nil {
+				if m.saveAs.Value() == "" && m.tutorial != nil {
+					m.saveAs.SetValue(tutorialRequest)
+				}
+				m.savingRequest = true
+				m.saved = ""
+				return m, m.saveAs.Focus()
+			}
+
 		case "esc":
+			// Clear a filter before leaving the response
+			if m.screen == screenResponse && m.filter.Value() != "" {
+				m.filter.SetValue("")
+				return m, nil
+			}
 			if m.screen != screenRequest {
+				m.retryIn = 0 // Cancel any pending retry
 				m.screen = screenRequest
 				m.response = nil // Clear the response when going back
 				m.err = nil      // Clear any errors
+				m.saved = ""
+				m.savePath.SetValue("")
 				return m, nil
 			}
 
@@ -165,14 +858,52 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch m.screen {
 			case screenRequest:
 				// Build request data
-				m.buildRequestData()
+				if err := m.buildRequestData(); err != nil {
+					m.err = err
+					m.logf(logError, "%v", err)
+					return m, nil
+				}
+				m.err = nil
+				for _, warning := range m.urlWarnings {
+					m.logf(logWarning, "%s", warning)
+				}
+
+				// Prompt for any path parameters the URL template still needs
+				if missing := m.requestData.MissingPathParams(); len(missing) > 0 {
+					m.promptPathParams(missing)
+					return m, nil
+				}
+				m.screen = screenPreview
+				return m, nil
+			case screenClipboard:
+				m.applyClipboardChoice(m.clipboardOptions[m.clipboardCursor])
+				return m, nil
+			case screenHistory:
+				m.toggleHistoryRow()
+				return m, nil
+			case screenPathParams:
+				m.applyPathParams()
+				if len(m.requestData.MissingPathParams()) > 0 {
+					return m, nil
+				}
 				m.screen = screenPreview
 				return m, nil
 			case screenPreview:
+				// ws:// and wss:// URLs open the WebSocket screen instead
+				if req, err := m.resolvedRequest(); err == nil && req.IsWebSocket() {
+					return m, m.openWebSocket()
+				}
+				if m.offline {
+					m.enqueue()
+					return m, nil
+				}
+
 				// Execute request
 				m.screen = screenResponse
 				m.response = nil // Clear previous response
 				m.err = nil      // Clear previous errors
+				m.sentAt = time.Now()
+				m.logSend()
 				return m, m.executeRequest
 			}
 		}
@@ -190,45 +921,169 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.inputs[i].textinput, cmd = m.inputs[i].textinput.Update(msg)
 			cmds = append(cmds, cmd)
 		}
+		m.refreshSuggestion()
+	}
+	if m.screen == screenPathParams {
+		for i := range m.paramInputs {
+			m.paramInputs[i].textinput, cmd = m.paramInputs[i].textinput.Update(msg)
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
-func (m *Model) buildRequestData() {
+// nextIndex moves index forward or backward based on the navigation key,
+// wrapping around at both ends
+func nextIndex(index, count int, key string) int {
+	if count == 0 {
+		return 0
+	}
+	if key == "up" || key == "shift+tab" {
+		index--
+	} else {
+		index++
+	}
+	return (index + count) % count
+}
+
+// focusInput focuses the input at index and blurs the others
+func focusInput(inputs []inputField, index int) {
+	for i := range inputs {
+		if i == index {
+			inputs[i].textinput.Focus()
+			continue
+		}
+		inputs[i].textinput.Blur()
+	}
+}
+
+// promptPathParams switches to the path parameter screen with one input per
+// missing path template name
+func (m *Model) promptPathParams(names []string) {
+	m.paramInputs = make([]inputField, len(names))
+	for i, name := range names {
+		m.paramInputs[i] = inputField{label: name, textinput: newInput()}
+		m.paramInputs[i].textinput.Placeholder = "value for {" + name + "}"
+	}
+	m.activeParam = 0
+	focusInput(m.paramInputs, m.activeParam)
+	m.screen = screenPathParams
+}
+
+// applyPathParams copies the prompted values into the request and the path
+// params form field so they persist when editing the request again
+func (m *Model) applyPathParams() {
+	for _, input := range m.paramInputs {
+		if value := input.textinput.Value(); value != "" {
+			m.requestData.PathParams[input.label] = value
+		}
+	}
+
+	pairs := make([]string, 0, len(m.requestData.PathParams))
+	for _, name := range request.PathParamNames(m.requestData.URL) {
+		if value, ok := m.requestData.PathParams[name]; ok {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	m.inputs[inputPathParams].textinput.SetValue(strings.Join(pairs, "&"))
+}
+
+func (m *Model) buildRequestData() error {
 	m.requestData = request.NewRequestData()
-	m.requestData.URL = m.inputs[0].textinput.Value()
-	m.requestData.Method = m.inputs[1].textinput.Value()
+	m.requestData.URL, m.urlWarnings = request.NormalizeURL(m.inputs[inputURL].textinput.Value(), m.config.DefaultScheme)
+	m.requestData.Method = m.inputs[inputMethod].textinput.Value()
 
 	// Handle authentication
-	authType := request.AuthType(m.inputs[2].textinput.Value())
+	authType := request.AuthType(m.inputs[inputAuthType].textinput.Value())
 	m.requestData.Auth = request.AuthData{
 		Type: authType,
 	}
 
 	switch authType {
-	case request.BasicAuth:
-		m.requestData.Auth.Username = m.inputs[3].textinput.Value()
-		m.requestData.Auth.Password = m.inputs[4].textinput.Value()
+	case request.BasicAuth, request.NTLMAuth:
+		m.requestData.Auth.Username = m.inputs[inputAuthUsername].textinput.Value()
+		m.requestData.Auth.Password = m.inputs[inputAuthPassword].textinput.Value()
+	case request.BearerAuth:
+		m.requestData.Auth.Token = m.inputs[inputBearerToken].textinput.Value()
 	case request.APIKeyAuth:
-		m.requestData.Auth.APIKey = m.inputs[5].textinput.Value()
+		m.requestData.Auth.APIKey = m.inputs[inputAPIKey].textinput.Value()
+		setAPIKeyPlacement(&m.requestData.Auth, m.inputs[inputAPIKeyPlacement].textinput.Value())
 	case request.MutualTLSAuth:
-		m.requestData.Auth.CertFile = m.inputs[6].textinput.Value()
-		m.requestData.Auth.KeyFile = m.inputs[7].textinput.Value()
+		m.requestData.Auth.CertFile = m.inputs[inputTLSCertFile].textinput.Value()
+		m.requestData.Auth.KeyFile = m.inputs[inputTLSKeyFile].textinput.Value()
+		m.requestData.Auth.Password = m.inputs[inputAuthPassword].textinput.Value()
+		m.requestData.Auth.CertSubject = m.inputs[inputTLSCertSubject].textinput.Value()
+	case request.OAuth2Auth:
+		m.requestData.Auth.OAuth2Profile = strings.TrimSpace(m.inputs[inputOAuth2Profile].textinput.Value())
+	case request.GCPAuth:
+		m.requestData.Auth.GCPAudience = strings.TrimSpace(m.inputs[inputGCPAudience].textinput.Value())
+	case request.AzureAuth:
+		m.requestData.Auth.AzureTenant = strings.TrimSpace(m.inputs[inputAzureTenant].textinput.Value())
+		m.requestData.Auth.AzureClientID = strings.TrimSpace(m.inputs[inputAzureClientID].textinput.Value())
+		m.requestData.Auth.AzureClientSecret = m.inputs[inputAzureClientSecret].textinput.Value()
+		m.requestData.Auth.AzureScope = strings.TrimSpace(m.inputs[inputAzureScope].textinput.Value())
+	case request.JWTAuth:
+		m.requestData.Auth.JWTClaims = strings.TrimSpace(m.inputs[inputJWTClaims].textinput.Value())
+		m.requestData.Auth.JWTKeyFile = strings.TrimSpace(m.inputs[inputJWTKeyFile].textinput.Value())
+		m.requestData.Auth.JWTAlg = strings.TrimSpace(m.inputs[inputJWTAlg].textinput.Value())
+	case request.ProfileAuth:
+		m.requestData.Auth.Profile = strings.TrimSpace(m.inputs[inputAuthProfile].textinput.Value())
+	case request.SessionAuth:
+		m.requestData.Auth.Username = m.inputs[inputAuthUsername].textinput.Value()
+		m.requestData.Auth.Password = m.inputs[inputAuthPassword].textinput.Value()
+		m.requestData.Auth.SessionLoginURL = strings.TrimSpace(m.inputs[inputSessionLogin].textinput.Value())
+		m.requestData.Auth.SessionLoginBody = strings.TrimSpace(m.inputs[inputSessionBody].textinput.Value())
+		m.requestData.Auth.SessionExtract = strings.TrimSpace(m.inputs[inputSessionExtract].textinput.Value())
+		m.requestData.Auth.SessionHeader = strings.TrimSpace(m.inputs[inputSessionHeader].textinput.Value())
 	}
 
-	// Parse headers
-	if headers := m.inputs[8].textinput.Value(); headers != "" {
-		for _, header := range strings.Split(headers, ",") {
-			parts := strings.SplitN(header, ":", 2)
-			if len(parts) == 2 {
-				m.requestData.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	// Server certificate trust applies to every auth type
+	m.requestData.Auth.CAFile = strings.TrimSpace(m.inputs[inputTLSCAFile].textinput.Value())
+	if value := strings.TrimSpace(m.inputs[inputTLSCAOnly].textinput.Value()); value != "" {
+		only, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid TLS CA only %q: use true or false", value)
+		}
+		m.requestData.Auth.CAFileOnly = only
+	}
+	if value := strings.TrimSpace(m.inputs[inputTLSInsecure].textinput.Value()); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid TLS skip verify %q: use true or false", value)
+		}
+		m.requestData.Auth.InsecureSkipVerify = insecure
+	}
+	m.requestData.ServerName = strings.TrimSpace(m.inputs[inputTLSServerName].textinput.Value())
+
+	// Parse headers, keeping disabled entries aside
+	for _, entry := range splitHeaderEntries(m.inputs[inputHeaders].textinput.Value()) {
+		name, value, disabled, ok := parseHeaderEntry(entry)
+		if !ok {
+			continue
+		}
+		if disabled {
+			if m.requestData.DisabledHeaders == nil {
+				m.requestData.DisabledHeaders = make(map[string]string)
 			}
+			m.requestData.DisabledHeaders[name] = value
+			continue
+		}
+		m.requestData.Headers[name] = value
+	}
+
+	// Merge global default headers from the configuration
+	if len(m.config.DefaultHeaders) > 0 {
+		m.requestData.DefaultHeaders = make(map[string]string)
+		for name, value := range m.config.DefaultHeaders {
+			m.requestData.DefaultHeaders[name] = value
 		}
 	}
 
+	m.requestData.MaxResponseSize = m.config.ResponseSizeLimit()
+
 	// Parse query params
-	if params := m.inputs[9].textinput.Value(); params != "" {
+	if params := m.inputs[inputQueryParams].textinput.Value(); params != "" {
 		for _, param := range strings.Split(params, "&") {
 			parts := strings.SplitN(param, "=", 2)
 			if len(parts) == 2 {
@@ -237,38 +1092,340 @@ func (m *Model) buildRequestData() {
 		}
 	}
 
-	m.requestData.Body = m.inputs[10].textinput.Value()
+	// Parse path params
+	if params := m.inputs[inputPathParams].textinput.Value(); params != "" {
+		for _, param := range strings.Split(params, "&") {
+			parts := strings.SplitN(param, "=", 2)
+			if len(parts) == 2 {
+				m.requestData.PathParams[strings.TrimSpace(parts[0])] = parts[1]
+			}
+		}
+	}
+
+	// Parse form params
+	m.requestData.FormParams = nil
+	if params := m.inputs[inputFormParams].textinput.Value(); params != "" {
+		m.requestData.FormParams = make(map[string]string)
+		for _, param := range strings.Split(params, "&") {
+			parts := strings.SplitN(param, "=", 2)
+			if len(parts) == 2 {
+				m.requestData.FormParams[strings.TrimSpace(parts[0])] = parts[1]
+			}
+		}
+	}
+
+	m.requestData.MethodOverrideHeader = strings.TrimSpace(m.inputs[inputMethodOverride].textinput.Value())
+	m.requestData.Body = m.inputs[inputBody].textinput.Value()
+	m.requestData.BodyFile = strings.TrimSpace(m.inputs[inputBodyFile].textinput.Value())
+	m.requestData.Multipart = nil
+	for _, spec := range strings.Split(m.inputs[inputMultipart].textinput.Value(), ",") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		part, err := request.ParseFormPart(spec)
+		if err != nil {
+			return err
+		}
+		m.requestData.Multipart = append(m.requestData.Multipart, part)
+	}
+
+	timeouts, err := parseTimeouts(m.inputs[inputTimeout].textinput.Value())
+	if err != nil {
+		return err
+	}
+	m.requestData.Timeout = timeouts[0]
+	m.requestData.ConnectTimeout = timeouts[1]
+	m.requestData.TLSTimeout = timeouts[2]
+	m.requestData.ResponseHeaderTimeout = timeouts[3]
+
+	if value := strings.TrimSpace(m.inputs[inputMaxRedirects].textinput.Value()); value != "" {
+		maxRedirects, err := strconv.Atoi(value)
+		if err != nil || maxRedirects < 0 {
+			return fmt.Errorf("invalid max redirects %q: use a number, 0 to not follow redirects", value)
+		}
+		m.requestData.DisableRedirects = maxRedirects == 0
+		m.requestData.MaxRedirects = maxRedirects
+	}
+
+	retry, err := parseRetries(m.inputs[inputRetries].textinput.Value())
+	if err != nil {
+		return err
+	}
+	m.requestData.Retry = retry
+
+	version, err := request.ParseProtocolVersion(m.inputs[inputHTTPVersion].textinput.Value())
+	if err != nil {
+		return err
+	}
+	m.requestData.ProtocolVersion = version
+
+	if proxy := strings.TrimSpace(m.inputs[inputProxy].textinput.Value()); proxy != "" {
+		m.requestData.Proxy = &request.ProxySettings{
+			URL:    proxy,
+			Bypass: request.ParseProxyBypass(m.inputs[inputProxyBypass].textinput.Value()),
+		}
+		m.requestData.Proxy.ApplyCredentials(m.config.ProxyAuth)
+	}
+	return nil
+}
+
+// fillForm replaces the request form with the request, the reverse of
+// buildRequestData
+func (m *Model) fillForm(req *request.RequestData) {
+	for i := range m.inputs {
+		m.inputs[i].textinput.SetValue("")
+	}
+	// Assertions and the name it was saved as belong to the request
+	// replaced
+	m.asserts = nil
+	m.saveAs.SetValue("")
+	set := func(index int, value string) {
+		m.inputs[index].textinput.SetValue(value)
+	}
+	set(inputURL, req.URL)
+	set(inputMethod, req.Method)
+
+	m.fillAuth(req.Auth)
+	set(inputTLSCAFile, req.Auth.CAFile)
+	if req.Auth.CAFileOnly {
+		set(inputTLSCAOnly, "true")
+	}
+	if req.Auth.InsecureSkipVerify {
+		set(inputTLSInsecure, "true")
+	}
+	set(inputTLSServerName, req.ServerName)
+
+	var headers []string
+	for name, value := range req.Headers {
+		headers = append(headers, name+":"+value)
+	}
+	for name, value := range req.DisabledHeaders {
+		headers = append(headers, "#"+name+":"+value)
+	}
+	slices.Sort(headers)
+	set(inputHeaders, strings.Join(headers, ","))
+	set(inputQueryParams, joinParams(req.QueryParams))
+	set(inputPathParams, joinParams(req.PathParams))
+	set(inputMethodOverride, req.MethodOverrideHeader)
+
+	set(inputTimeout, formatTimeouts(req.Timeout, req.ConnectTimeout, req.TLSTimeout, req.ResponseHeaderTimeout))
+	if req.DisableRedirects {
+		set(inputMaxRedirects, "0")
+	} else if req.MaxRedirects > 0 {
+		set(inputMaxRedirects, strconv.Itoa(req.MaxRedirects))
+	}
+	if req.Retry != nil && req.Retry.MaxAttempts > 1 {
+		retries := strconv.Itoa(req.Retry.MaxAttempts - 1)
+		if req.Retry.Backoff > 0 {
+			retries += "," + req.Retry.Backoff.String()
+		}
+		set(inputRetries, retries)
+	}
+	set(inputHTTPVersion, string(req.ProtocolVersion))
+	if req.Proxy != nil {
+		set(inputProxy, req.Proxy.URL)
+		set(inputProxyBypass, strings.Join(req.Proxy.Bypass, ","))
+	}
+
+	set(inputFormParams, joinParams(req.FormParams))
+	set(inputBody, req.Body)
+	set(inputBodyFile, req.BodyFile)
+	var parts []string
+	for _, part := range req.Multipart {
+		parts = append(parts, part.String())
+	}
+	set(inputMultipart, strings.Join(parts, ","))
+}
+
+// fillAuth sets the auth type and its inputs from auth, leaving the server
+// trust inputs as they are
+func (m *Model) fillAuth(auth request.AuthData) {
+	set := func(index int, value string) {
+		m.inputs[index].textinput.SetValue(value)
+	}
+	authType := auth.Type
+	if authType == "" {
+		authType = request.NoAuth
+	}
+	set(inputAuthType, string(authType))
+	set(inputAuthUsername, auth.Username)
+	set(inputAuthPassword, auth.Password)
+	set(inputBearerToken, auth.Token)
+	set(inputAPIKey, auth.APIKey)
+	set(inputAPIKeyPlacement, formatAPIKeyPlacement(auth))
+	set(inputTLSCertFile, auth.CertFile)
+	set(inputTLSKeyFile, auth.KeyFile)
+	set(inputTLSCertSubject, auth.CertSubject)
+	set(inputOAuth2Profile, auth.OAuth2Profile)
+	set(inputGCPAudience, auth.GCPAudience)
+	set(inputAzureTenant, auth.AzureTenant)
+	set(inputAzureClientID, auth.AzureClientID)
+	set(inputAzureClientSecret, auth.AzureClientSecret)
+	set(inputAzureScope, auth.AzureScope)
+	set(inputJWTClaims, auth.JWTClaims)
+	set(inputJWTKeyFile, auth.JWTKeyFile)
+	set(inputJWTAlg, auth.JWTAlg)
+	set(inputAuthProfile, auth.Profile)
+	set(inputSessionLogin, auth.SessionLoginURL)
+	set(inputSessionBody, auth.SessionLoginBody)
+	set(inputSessionExtract, auth.SessionExtract)
+	set(inputSessionHeader, auth.SessionHeader)
+}
+
+// joinParams formats parameters as the form fields take them, key=value
+// pairs joined by &, sorted by key
+func joinParams(params map[string]string) string {
+	var pairs []string
+	for key, value := range params {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// parseRetries parses "count[,backoff]" such as "3,500ms" into a retry
+// policy, or nil when retries are off
+func parseRetries(value string) (*request.RetryPolicy, error) {
+	count, backoff, _ := strings.Cut(value, ",")
+	if count = strings.TrimSpace(count); count == "" || count == "0" {
+		return nil, nil
+	}
+	retries, err := strconv.Atoi(count)
+	if err != nil || retries < 0 {
+		return nil, fmt.Errorf("invalid retries %q: use a count like 3 or 3,500ms", value)
+	}
+	policy := &request.RetryPolicy{MaxAttempts: retries + 1}
+	if backoff = strings.TrimSpace(backoff); backoff != "" {
+		if policy.Backoff, err = time.ParseDuration(backoff); err != nil {
+			return nil, fmt.Errorf("invalid retry backoff %q: use durations like 500ms or 2s", backoff)
+		}
+	}
+	return policy, nil
+}
+
+// parseTimeouts parses "total[,connect,tls,headers]" durations such as
+// "30s,5s" or ",,,2s"; empty ones are zero
+func parseTimeouts(value string) ([4]time.Duration, error) {
+	var durations [4]time.Duration
+	parts := strings.Split(value, ",")
+	if len(parts) > len(durations) {
+		return durations, fmt.Errorf("invalid timeouts %q: use total,connect,tls,headers", value)
+	}
+	for i, part := range parts {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		var err error
+		if durations[i], err = time.ParseDuration(part); err != nil {
+			return durations, fmt.Errorf("invalid timeout %q: use durations like 30s or 1m", part)
+		}
+	}
+	return durations, nil
+}
+
+// formatTimeouts formats timeouts as parseTimeouts parses them, leaving
+// out trailing zero ones
+func formatTimeouts(timeouts ...time.Duration) string {
+	parts := make([]string, len(timeouts))
+	for i, timeout := range timeouts {
+		if timeout > 0 {
+			parts[i] = timeout.String()
+		}
+	}
+	return strings.TrimRight(strings.Join(parts, ","), ",")
+}
+
+// Notifiers for completed requests; replaced in tests
+var (
+	sendNotification = notify.Send
+	ringBell         = func() { notify.Bell(os.Stdout) }
+)
+
+// notifyCompletion returns a command that sends a desktop notification, or
+// rings the bell when that fails, for a request that completed while the
+// terminal was unfocused and took at least the configured threshold
+func (m Model) notifyCompletion(title, message string) tea.Cmd {
+	threshold := m.config.NotifyThreshold()
+	elapsed := time.Since(m.sentAt)
+	if m.focused || m.sentAt.IsZero() || threshold <= 0 || elapsed < threshold {
+		return nil
+	}
+	return func() tea.Msg {
+		message := fmt.Sprintf("%s (%v)", message, elapsed.Round(100*time.Millisecond))
+		if err := sendNotification("Lighttr: "+title, message); err != nil {
+			ringBell()
+		}
+		return nil
+	}
 }
 
 func (m Model) executeRequest() tea.Msg {
-	// Validate request data first
-	if err := m.requestData.Validate(); err != nil {
+	// Substitute placeholders and validate request data first
+	req, err := m.resolvedRequest()
+	if err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
 		return fmt.Errorf("invalid request: %v", err)
 	}
 
-	resp, err := m.requestData.Execute()
+	resp, err := req.ExecuteWith(request.WithCookieJar(m.cookies), request.WithHARRecorder(m.recorder))
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %v", err)
 	}
+	if err := m.cookies.Save(); err != nil {
+		return fmt.Errorf("failed to save cookies: %v", err)
+	}
 
 	if resp.Error != "" {
-		return fmt.Errorf("request error: %s", resp.Error)
+		return requestError{message: resp.Error, kind: resp.ErrorKind}
 	}
 
 	return resp
 }
 
+// requestError is a failure to reach the server, classified so the error
+// screen can suggest what to try
+type requestError struct {
+	message string
+	kind    request.ErrorKind
+}
+
+func (e requestError) Error() string {
+	return "request error: " + e.message
+}
+
 func (m Model) View() string {
+	var view string
 	switch m.screen {
 	case screenRequest:
-		return m.renderRequestScreen()
+		view = m.renderRequestScreen()
+	case screenPathParams:
+		view = m.renderPathParamsScreen()
 	case screenPreview:
-		return m.renderPreviewScreen()
+		view = m.renderPreviewScreen()
 	case screenResponse:
-		return m.renderResponseScreen()
+		view = m.renderResponseScreen()
+	case screenQueue:
+		view = m.renderQueueScreen()
+	case screenClipboard:
+		view = m.renderClipboardScreen()
+	case screenHistory:
+		view = m.renderHistoryScreen()
+	case screenWebSocket:
+		view = m.renderWebSocketScreen()
+	case screenOAuth:
+		view = m.renderOAuthScreen()
 	default:
 		return "Unknown screen"
 	}
+	if m.tutorial != nil {
+		view = m.renderTutorial() + view
+	}
+	if m.showConsole {
+		view += m.renderConsole()
+	}
+	return view
 }
 
 func (m Model) renderRequestScreen() string {
@@ -276,9 +1433,17 @@ func (m Model) renderRequestScreen() string {
 
 	b.WriteString(titleStyle.Render("Lighttr - HTTP Request Builder"))
 	b.WriteString("\n\n")
+	if m.recorder != nil {
+		b.WriteString(warningStyle.Render(fmt.Sprintf("● Recording to %s (%d entries)", m.recorder.Path(), m.recorder.Len())) + "\n\n")
+	}
+	if m.offline {
+		b.WriteString(warningStyle.Render(fmt.Sprintf("● Offline: sends are queued (%d queued) • Ctrl+Q to review • Ctrl+O to go online", m.queue.Len())) + "\n\n")
+	} else if n := m.queue.Len(); n > 0 {
+		b.WriteString(warningStyle.Render(fmt.Sprintf("%d queued requests • Ctrl+Q to review and send", n)) + "\n\n")
+	}
 
 	// Get current auth type
-	currentAuthType := request.AuthType(m.inputs[2].textinput.Value())
+	currentAuthType := request.AuthType(m.inputs[inputAuthType].textinput.Value())
 
 	for i, input := range m.inputs {
 		// Skip auth fields that aren't relevant for the current auth type
@@ -294,28 +1459,103 @@ func (m Model) renderRequestScreen() string {
 		b.WriteString(input.textinput.View() + "\n\n")
 	}
 
-	b.WriteString("\nPress Enter to preview request • ESC to go back • Ctrl+C to quit\n")
+	b.WriteString("\nHeader presets: ")
+	for i, preset := range request.HeaderPresets {
+		if i > 0 {
+			b.WriteString(" • ")
+		}
+		b.WriteString(fmt.Sprintf("Alt+%d %s", i+1, preset.Name))
+	}
+	b.WriteString("\nCtrl+T toggles the header under the cursor (disabled headers start with #)\n")
+	b.WriteString("Alt+V imports a curl command, HTTP request, URL, or JSON body from the clipboard\n")
+	if m.history != nil {
+		b.WriteString("Ctrl+R browses the history of requests sent\n")
+	}
+	if m.formStatus != "" {
+		b.WriteString("\n" + warningStyle.Render(m.formStatus) + "\n")
+	}
+	b.WriteString(m.renderSuggestion())
+	b.WriteString(m.renderResolvedPane())
+	if m.err != nil {
+		b.WriteString("\n" + warningStyle.Render(fmt.Sprintf("Error: %v", m.err)) + "\n")
+	}
+	b.WriteString("\nPress Enter to preview request • Ctrl+L for the console • Ctrl+C to quit\n")
 	return b.String()
 }
 
+// setAPIKeyPlacement sets where an API key is sent from "Header",
+// "Header: Prefix", or "?query"; empty keeps the default
+func setAPIKeyPlacement(auth *request.AuthData, value string) {
+	value = strings.TrimSpace(value)
+	if query, ok := strings.CutPrefix(value, "?"); ok {
+		auth.APIKeyQuery = strings.TrimSpace(query)
+		return
+	}
+	header, prefix, _ := strings.Cut(value, ":")
+	auth.APIKeyHeader = strings.TrimSpace(header)
+	auth.APIKeyPrefix = strings.TrimSpace(prefix)
+}
+
+// formatAPIKeyPlacement is the reverse of setAPIKeyPlacement
+func formatAPIKeyPlacement(auth request.AuthData) string {
+	switch {
+	case auth.APIKeyQuery != "":
+		return "?" + auth.APIKeyQuery
+	case auth.APIKeyPrefix != "":
+		return auth.APIKeyHeader + ": " + auth.APIKeyPrefix
+	}
+	return auth.APIKeyHeader
+}
+
 // shouldSkipAuthField determines if an auth-related field should be shown based on the current auth type
 func shouldSkipAuthField(fieldIndex int, authType request.AuthType) bool {
-	switch authType {
-	case request.NoAuth:
-		// Hide all auth fields except the auth type selector
-		return fieldIndex >= 3 && fieldIndex <= 7
-	case request.BasicAuth:
-		// Show only username and password fields
-		return (fieldIndex >= 5 && fieldIndex <= 7)
-	case request.APIKeyAuth:
-		// Show only API key field
-		return (fieldIndex >= 3 && fieldIndex <= 4) || (fieldIndex >= 6 && fieldIndex <= 7)
-	case request.MutualTLSAuth:
-		// Show only cert and key file fields
-		return (fieldIndex >= 3 && fieldIndex <= 5)
-	default:
+	// The fields before the auth type's and the server trust fields after
+	// them are shown for every auth type
+	if fieldIndex < inputAuthUsername || fieldIndex >= inputTLSCAFile {
 		return false
 	}
+	fields, ok := authFields[authType]
+	if !ok {
+		return false
+	}
+	return !slices.Contains(fields, fieldIndex)
+}
+
+// authFields lists the fields shown for each auth type
+var authFields = map[request.AuthType][]int{
+	request.NoAuth:        nil,
+	request.BasicAuth:     {inputAuthUsername, inputAuthPassword},
+	request.BearerAuth:    {inputBearerToken},
+	request.APIKeyAuth:    {inputAPIKey, inputAPIKeyPlacement},
+	request.MutualTLSAuth: {inputTLSCertFile, inputTLSKeyFile, inputAuthPassword, inputTLSCertSubject},
+	request.OAuth2Auth:    {inputOAuth2Profile},
+	request.GCPAuth:       {inputGCPAudience},
+	request.AzureAuth:     {inputAzureTenant, inputAzureClientID, inputAzureClientSecret, inputAzureScope},
+	request.JWTAuth:       {inputJWTClaims, inputJWTKeyFile, inputJWTAlg},
+	request.NTLMAuth:      {inputAuthUsername, inputAuthPassword},
+	request.NegotiateAuth: nil,
+	request.ProfileAuth:   {inputAuthProfile},
+	request.SessionAuth:   {inputAuthUsername, inputAuthPassword, inputSessionLogin, inputSessionBody, inputSessionExtract, inputSessionHeader},
+}
+
+func (m Model) renderPathParamsScreen() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Path Parameters"))
+	b.WriteString("\n\n")
+	b.WriteString(fmt.Sprintf("%s\n\n", m.requestData.URL))
+
+	for i, input := range m.paramInputs {
+		style := blurredStyle
+		if i == m.activeParam {
+			style = focusedStyle
+		}
+		b.WriteString(style.Render("{"+input.label+"}") + "\n")
+		b.WriteString(input.textinput.View() + "\n\n")
+	}
+
+	b.WriteString("\nPress Enter to preview request • ESC to go back • Ctrl+C to quit\n")
+	return b.String()
 }
 
 func (m Model) renderPreviewScreen() string {
@@ -324,7 +1564,22 @@ func (m Model) renderPreviewScreen() string {
 	b.WriteString(titleStyle.Render("Request Preview"))
 	b.WriteString("\n\n")
 
-	b.WriteString(fmt.Sprintf("%s %s\n", m.requestData.Method, m.requestData.URL))
+	b.WriteString(fmt.Sprintf("%s %s\n", m.requestData.WireMethod(), request.DisplayURL(m.requestData.ResolvedURL())))
+	if wireURL, err := m.requestData.WireURL(); err != nil {
+		b.WriteString(warningStyle.Render("Invalid URL: "+err.Error()) + "\n")
+	} else if wireURL != request.DisplayURL(wireURL) {
+		// Internationalized hosts and paths are encoded on the wire
+		b.WriteString(fmt.Sprintf("Wire URL: %s\n", wireURL))
+	}
+	for _, warning := range m.urlWarnings {
+		b.WriteString(warningStyle.Render("Warning: "+warning) + "\n")
+	}
+	if _, err := m.resolvedRequest(); err != nil {
+		b.WriteString(failStyle.Render("Cannot send: "+err.Error()) + "\n")
+	}
+	if wire := m.requestData.WireMethod(); wire != m.requestData.Method {
+		b.WriteString(warningStyle.Render(fmt.Sprintf("Sent as %s with %s: %s", wire, m.requestData.MethodOverrideHeader, strings.ToUpper(m.requestData.Method))) + "\n")
+	}
 
 	// Show authentication details
 	b.WriteString(fmt.Sprintf("\nAuthentication: %s\n", m.requestData.Auth.Type))
@@ -332,17 +1587,118 @@ func (m Model) renderPreviewScreen() string {
 	case request.BasicAuth:
 		b.WriteString(fmt.Sprintf("Username: %s\n", m.requestData.Auth.Username))
 		b.WriteString("Password: ********\n")
+	case request.NTLMAuth:
+		domain, user := m.requestData.Auth.NTLMCredentials()
+		if domain != "" {
+			b.WriteString(fmt.Sprintf("Domain: %s\n", domain))
+		}
+		b.WriteString(fmt.Sprintf("Username: %s\n", user))
+		b.WriteString("Password: ********\n")
+		b.WriteString("Handshake: NTLMv2 on one HTTP/1.1 connection\n")
+	case request.NegotiateAuth:
+		if u, err := url.Parse(m.requestData.ResolvedURL()); err == nil {
+			b.WriteString(fmt.Sprintf("Service Principal: HTTP/%s\n", u.Hostname()))
+		}
+		b.WriteString("Kerberos Login: " + kerberosStatus() + "\n")
+	case request.BearerAuth:
+		b.WriteString("Token: ********\n")
 	case request.APIKeyAuth:
-		b.WriteString("API Key: ********\n")
+		name, _, query := m.requestData.Auth.APIKeyParam()
+		if query {
+			b.WriteString(fmt.Sprintf("API Key: ******** (query parameter %s)\n", name))
+		} else {
+			b.WriteString(fmt.Sprintf("API Key: ******** (%s header)\n", name))
+		}
 	case request.MutualTLSAuth:
+		if m.requestData.Auth.CertSubject != "" {
+			b.WriteString(fmt.Sprintf("Keystore Certificate: %s\n", m.requestData.Auth.CertSubject))
+			break
+		}
 		b.WriteString(fmt.Sprintf("Certificate File: %s\n", m.requestData.Auth.CertFile))
-		b.WriteString(fmt.Sprintf("Key File: %s\n", m.requestData.Auth.KeyFile))
+		if m.requestData.Auth.KeyFile != "" {
+			b.WriteString(fmt.Sprintf("Key File: %s\n", m.requestData.Auth.KeyFile))
+		}
+		if m.requestData.Auth.Password != "" {
+			b.WriteString("Passphrase: ********\n")
+		}
+	case request.SessionAuth:
+		auth := m.requestData.Auth
+		b.WriteString(fmt.Sprintf("Login: POST %s\n", auth.SessionLoginURL))
+		if auth.Username != "" {
+			b.WriteString(fmt.Sprintf("Username: %s\n", auth.Username))
+			b.WriteString("Password: ********\n")
+		}
+		if source, name, _ := strings.Cut(auth.SessionExtract, ":"); strings.EqualFold(source, "cookie") {
+			b.WriteString(fmt.Sprintf("Credential: cookie %s, reused until it expires\n", strings.TrimSpace(name)))
+		} else {
+			header, _ := auth.SessionHeaderParam("")
+			b.WriteString(fmt.Sprintf("Credential: %s of the login response in the %s header, reused until it expires\n", auth.SessionExtract, header))
+		}
+	case request.ProfileAuth:
+		b.WriteString(fmt.Sprintf("Auth Profile: %s\n", m.requestData.Auth.Profile))
+		b.WriteString("Credentials: " + authProfileStatus(m.requestData.Auth.Profile) + "\n")
+	case request.OAuth2Auth:
+		b.WriteString(fmt.Sprintf("OAuth2 Profile: %s\n", m.requestData.Auth.OAuth2Profile))
+		b.WriteString("Token: " + oauthTokenStatus(m.requestData.Auth.OAuth2Profile) + "\n")
+		b.WriteString(blurredStyle.Render("Alt+O shows the legs of its login and refreshes") + "\n")
+	case request.GCPAuth:
+		if m.requestData.Auth.GCPAudience != "" {
+			b.WriteString(fmt.Sprintf("Token: ID token for %s (Application Default Credentials)\n", m.requestData.Auth.GCPAudience))
+		} else {
+			b.WriteString("Token: access token (Application Default Credentials)\n")
+		}
+	case request.AzureAuth:
+		app := azure.AppFor(m.requestData.Auth)
+		if app.Tenant != "" {
+			b.WriteString(fmt.Sprintf("Tenant: %s\n", app.Tenant))
+		} else {
+			b.WriteString("Tenant: any work or school account\n")
+		}
+		b.WriteString(fmt.Sprintf("Client ID: %s\n", app.ClientID))
+		b.WriteString(fmt.Sprintf("Scope: %s\n", app.Scope))
+		if app.ClientSecret != "" {
+			b.WriteString("Token: ******** (client credentials)\n")
+		} else {
+			b.WriteString("Token: " + azureTokenStatus(app) + "\n")
+		}
+		b.WriteString(blurredStyle.Render("Alt+O shows the legs of its token requests") + "\n")
+	case request.JWTAuth:
+		b.WriteString(fmt.Sprintf("Claims: %s\n", m.requestData.Auth.JWTClaims))
+		b.WriteString(fmt.Sprintf("Signed with: %s (%s)\n", m.requestData.Auth.JWTKeyFile, m.requestData.Auth.JWTAlgorithm()))
+		b.WriteString(fmt.Sprintf("Token: minted per request, valid for %v unless the claims set exp\n", request.JWTLifetime))
+	}
+	if m.requestData.Auth.CAFile != "" {
+		if m.requestData.Auth.CAFileOnly {
+			b.WriteString(fmt.Sprintf("CA File: %s (only, not the system CAs)\n", m.requestData.Auth.CAFile))
+		} else {
+			b.WriteString(fmt.Sprintf("CA File: %s\n", m.requestData.Auth.CAFile))
+		}
+	}
+	if m.requestData.ServerName != "" {
+		b.WriteString(fmt.Sprintf("TLS Server Name: %s\n", m.requestData.ServerName))
+	}
+	if m.requestData.Auth.InsecureSkipVerify {
+		b.WriteString(warningStyle.Render("TLS certificate verification is disabled") + "\n")
 	}
 
-	if len(m.requestData.Headers) > 0 {
+	if headers := m.requestData.EffectiveHeaders(); len(headers) > 0 {
 		b.WriteString("\nHeaders:\n")
-		for k, v := range m.requestData.Headers {
-			b.WriteString(fmt.Sprintf("%s: %s\n", k, v))
+		for k, v := range headers {
+			b.WriteString(fmt.Sprintf("%s: %s", k, v))
+			if _, ok := m.requestData.Headers[k]; !ok && m.requestData.ActiveDefaultHeader(k) {
+				b.WriteString(blurredStyle.Render(" (default)"))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(m.requestData.DisabledHeaders) > 0 {
+		b.WriteString("\nDisabled Headers (not sent):\n")
+		for k, v := range m.requestData.DisabledHeaders {
+			if value, ok := m.requestData.DefaultHeaders[k]; ok && v == "" {
+				v = value + " (default)"
+			}
+			b.WriteString(blurredStyle.Render(fmt.Sprintf("%s: %s", k, v)) + "\n")
 		}
 	}
 
@@ -353,11 +1709,91 @@ func (m Model) renderPreviewScreen() string {
 		}
 	}
 
+	if len(m.requestData.PathParams) > 0 {
+		b.WriteString("\nPath Parameters:\n")
+		for k, v := range m.requestData.PathParams {
+			b.WriteString(fmt.Sprintf("{%s}=%s\n", k, v))
+		}
+	}
+
+	if m.requestData.DisableRedirects {
+		b.WriteString("\nRedirects: not followed\n")
+	} else if m.requestData.MaxRedirects > 0 {
+		b.WriteString(fmt.Sprintf("\nRedirects: up to %d\n", m.requestData.MaxRedirects))
+	}
+
+	if proxy := m.requestData.Proxy; proxy != nil {
+		b.WriteString(fmt.Sprintf("\nProxy: %s\n", proxy.URL))
+		if proxy.Username != "" {
+			b.WriteString(fmt.Sprintf("Proxy User: %s\n", proxy.Username))
+		}
+		if len(proxy.Bypass) > 0 {
+			b.WriteString(fmt.Sprintf("Bypassed For: %s\n", strings.Join(proxy.Bypass, ", ")))
+		}
+	}
+
+	if m.requestData.ProtocolVersion != request.ProtocolAuto {
+		b.WriteString(fmt.Sprintf("\nHTTP Version: %s\n", m.requestData.ProtocolVersion))
+	}
+
+	if retry := m.requestData.Retry; retry != nil {
+		backoff := retry.Backoff
+		if backoff == 0 {
+			backoff = request.DefaultRetryBackoff
+		}
+		b.WriteString(fmt.Sprintf("\nRetries: up to %d (backoff %v)\n", retry.MaxAttempts-1, backoff))
+	}
+
+	if formatTimeouts(m.requestData.Timeout, m.requestData.ConnectTimeout, m.requestData.TLSTimeout, m.requestData.ResponseHeaderTimeout) != "" {
+		b.WriteString("\nTimeouts:\n")
+		for _, timeout := range []struct {
+			name  string
+			value time.Duration
+		}{
+			{"Total", m.requestData.Timeout},
+			{"Connect", m.requestData.ConnectTimeout},
+			{"TLS Handshake", m.requestData.TLSTimeout},
+			{"Response Headers", m.requestData.ResponseHeaderTimeout},
+		} {
+			if timeout.value > 0 {
+				b.WriteString(fmt.Sprintf("%s: %v\n", timeout.name, timeout.value))
+			}
+		}
+	}
+
+	if len(m.requestData.FormParams) > 0 {
+		b.WriteString("\nForm Body (urlencoded):\n")
+		b.WriteString(m.requestData.EncodedForm())
+	}
 	if m.requestData.Body != "" {
 		b.WriteString("\nBody:\n")
 		b.WriteString(m.requestData.Body)
 	}
+	if m.requestData.BodyFile != "" {
+		b.WriteString(fmt.Sprintf("\nBody File: %s", m.requestData.BodyFile))
+		if info, err := os.Stat(m.requestData.BodyFile); err == nil {
+			b.WriteString(fmt.Sprintf(" (%d bytes, streamed)", info.Size()))
+		} else {
+			b.WriteString(warningStyle.Render(" (not found)"))
+		}
+	}
+	if len(m.requestData.Multipart) > 0 {
+		b.WriteString("\nMultipart Form:\n")
+		for _, part := range m.requestData.Multipart {
+			b.WriteString(part.String())
+			if part.File != "" {
+				if _, err := os.Stat(part.File); err != nil {
+					b.WriteString(warningStyle.Render(" (not found)"))
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
 
+	if m.requestData.IsWebSocket() {
+		b.WriteString("\n\nPress Enter to connect • ESC to go back • Ctrl+C to quit\n")
+		return b.String()
+	}
 	b.WriteString("\n\nPress Enter to send request • ESC to go back • Ctrl+C to quit\n")
 	return b.String()
 }
@@ -368,7 +1804,23 @@ func (m Model) renderResponseScreen() string {
 	if m.err != nil {
 		b.WriteString(titleStyle.Render("Error"))
 		b.WriteString("\n\n")
-		b.WriteString(fmt.Sprintf("Error: %v\n", m.err))
+		b.WriteString(fmt.Sprintf("%s %s\n", m.requestData.WireMethod(), request.DisplayURL(m.requestData.ResolvedURL())))
+		b.WriteString(warningStyle.Render(fmt.Sprintf("Error: %v", m.err)) + "\n")
+		var reqErr requestError
+		if errors.As(m.err, &reqErr) && reqErr.kind != "" {
+			b.WriteString("\n" + focusedStyle.Render(reqErr.kind.Summary()) + "\n")
+			b.WriteString("Try:\n")
+			for _, suggestion := range reqErr.kind.Suggestions() {
+				b.WriteString("  • " + suggestion + "\n")
+			}
+			if queue.Offline(reqErr.kind) {
+				b.WriteString("  • Work offline with Ctrl+O: requests are queued and sent when you flush the queue\n")
+			}
+		}
+		if t := m.requestData.Auth.Type; t == request.OAuth2Auth || t == request.AzureAuth {
+			b.WriteString("\n" + focusedStyle.Render("Alt+O shows every leg of the OAuth2 flow, with the token requests and responses") + "\n")
+		}
+		b.WriteString("\nr to retry • Ctrl+L for the session log • ESC to go back • Ctrl+C to quit\n")
 		return b.String()
 	}
 
@@ -380,20 +1832,267 @@ func (m Model) renderResponseScreen() string {
 	b.WriteString("\n\n")
 
 	b.WriteString(fmt.Sprintf("Status: %d\n", m.response.StatusCode))
+	if m.response.Protocol != "" {
+		b.WriteString(fmt.Sprintf("Protocol: %s\n", m.response.ProtocolSummary()))
+	}
+	if m.response.RemoteAddr != "" {
+		b.WriteString(fmt.Sprintf("Connected to: %s\n", m.response.RemoteAddr))
+	}
 	b.WriteString(fmt.Sprintf("Time: %v\n", m.response.ResponseTime))
+	if m.response.DecodeError != "" {
+		b.WriteString(warningStyle.Render("Encoding: "+m.response.EncodingSummary()) + "\n")
+	} else if m.response.ContentEncoding != "" {
+		b.WriteString(fmt.Sprintf("Encoding: %s\n", m.response.EncodingSummary()))
+	}
+	if m.response.Charset != "" {
+		b.WriteString(fmt.Sprintf("Charset: %s\n", m.response.CharsetSummary()))
+	}
+	if m.response.Truncated {
+		b.WriteString(warningStyle.Render("Truncated: "+m.response.TruncatedSummary()+" (max_response_size)") + "\n")
+	}
+
+	if m.response.RetryAfter > 0 {
+		if m.retryIn > 0 && reducedMotion {
+			b.WriteString(warningStyle.Render(fmt.Sprintf("Retrying at %s (ESC to cancel)", m.retryAt.Format(time.TimeOnly))) + "\n")
+		} else if m.retryIn > 0 {
+			b.WriteString(warningStyle.Render(fmt.Sprintf("Retrying in %v... (ESC to cancel)", m.retryIn)) + "\n")
+		} else {
+			b.WriteString(warningStyle.Render(fmt.Sprintf("Retry-After: %v • press r to wait and retry", m.response.RetryAfter)) + "\n")
+		}
+	}
+
+	if timing := m.response.Timing; timing != nil {
+		b.WriteString("\nTiming:\n")
+		for _, phase := range timing.Phases() {
+			b.WriteString(fmt.Sprintf("%-19s %v\n", phase.Name+":", phase.Duration.Round(time.Microsecond)))
+		}
+		if timing.Reused {
+			b.WriteString(fmt.Sprintf("(reused connection, idle %v)\n", timing.IdleTime.Round(time.Millisecond)))
+		}
+		stats := request.ConnectionStats()
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("Connections this session: %d opened, %d reused", stats.Opened, stats.Reused)) + "\n")
+	}
+
+	if len(m.response.Attempts) > 1 {
+		b.WriteString("\nAttempts:\n")
+		for i, attempt := range m.response.Attempts {
+			b.WriteString(fmt.Sprintf("%d. %s\n", i+1, attempt))
+		}
+	}
+
+	if len(m.response.Redirects) > 0 {
+		b.WriteString("\nRedirects:\n")
+		for _, hop := range m.response.Redirects {
+			b.WriteString(fmt.Sprintf("%d %s -> %s\n", hop.StatusCode, hop.URL, hop.Location))
+		}
+	}
+
+	if info := m.response.TLS; info != nil {
+		b.WriteString("\nTLS:\n")
+		b.WriteString(fmt.Sprintf("Version: %s (%s)\n", info.Version, info.CipherSuite))
+		if info.Subject != "" {
+			b.WriteString(fmt.Sprintf("Certificate: %s\n", info.Subject))
+			b.WriteString(fmt.Sprintf("Expires: %s\n", info.NotAfter.Format("2006-01-02")))
+		}
+		stapled := "no"
+		if info.OCSPStapled {
+			stapled = "yes"
+		}
+		b.WriteString(fmt.Sprintf("OCSP Stapled: %s\n", stapled))
+		if info.Revocation != "" {
+			b.WriteString(fmt.Sprintf("Revocation: %s\n", info.Revocation))
+		}
+		for _, warning := range info.Warnings {
+			b.WriteString(warningStyle.Render("Warning: "+warning) + "\n")
+		}
+	}
 
+	decodable := false
 	if len(m.response.Headers) > 0 {
 		b.WriteString("\nHeaders:\n")
 		for k, v := range m.response.Headers {
-			b.WriteString(fmt.Sprintf("%s: %s\n", k, v))
+			b.WriteString(fmt.Sprintf("%s: %s", k, v))
+			decoded, ok := request.DecodeHeaderValue(k, v)
+			decodable = decodable || ok
+			switch {
+			case ok && m.decodeHeaders:
+				b.WriteString("\n" + blurredStyle.Render("  "+decoded.Encoding+":") + "\n")
+				for _, line := range strings.Split(decoded.Text, "\n") {
+					b.WriteString("    " + line + "\n")
+				}
+			case ok:
+				b.WriteString(" " + blurredStyle.Render("["+decoded.Encoding+"]") + "\n")
+			default:
+				b.WriteString("\n")
+			}
 		}
 	}
 
-	if m.response.Body != "" {
+	language := filterLanguage(m.response)
+	if expr := strings.TrimSpace(m.filter.Value()); expr != "" && language != "" {
+		b.WriteString(fmt.Sprintf("\nBody (%s %s):\n", language, expr))
+		b.WriteString(filterBody(m.response.Body, language, expr))
+	} else if m.response.Binary {
+		b.WriteString(fmt.Sprintf("\nBody: binary (%s)\n", m.response.BinarySummary()))
+		b.WriteString(strings.TrimSuffix(m.response.HexPreview(), "\n"))
+	} else if m.response.Body != "" {
 		b.WriteString("\nBody:\n")
 		b.WriteString(m.response.Body)
 	}
 
-	b.WriteString("\n\nESC to go back • Ctrl+C to quit\n")
+	if len(m.asserts) > 0 {
+		b.WriteString("\n\n" + m.renderAssertions())
+	}
+	if m.assertStatus != "" {
+		b.WriteString("\n\n" + warningStyle.Render(m.assertStatus))
+	}
+	if m.saved != "" {
+		b.WriteString("\n\n" + warningStyle.Render(m.saved))
+	}
+	if m.asserting {
+		b.WriteString("\n\n" + m.assertInput.View())
+		b.WriteString("\n\nEnter to add • ESC to cancel\n")
+		return b.String()
+	}
+	if m.savingRequest {
+		b.WriteString("\n\n" + m.saveAs.View())
+		b.WriteString("\n\nEnter to save the request and its assertions • ESC to cancel\n")
+		return b.String()
+	}
+	if m.saving {
+		b.WriteString("\n\n" + m.savePath.View())
+		b.WriteString("\n\nEnter to save • ESC to cancel\n")
+		return b.String()
+	}
+	if m.filtering {
+		b.WriteString("\n\n" + m.filter.View())
+		b.WriteString("\n\nEnter to apply • ESC to clear\n")
+		return b.String()
+	}
+	keys := "a to assert • s to save the body"
+	if decodable && m.decodeHeaders {
+		keys = "d to hide decoded headers • " + keys
+	} else if decodable {
+		keys = "d to decode headers • " + keys
+	}
+	if m.collections != nil {
+		keys += " • Ctrl+S to save the request"
+	}
+	if language != "" {
+		keys = fmt.Sprintf("/ to filter with %s • %s", language, keys)
+	}
+	b.WriteString("\n\n" + keys + " • ESC to go back • Ctrl+C to quit\n")
 	return b.String()
 }
+
+// Response body filter languages
+const (
+	filterXPath = "XPath"
+	filterCSS   = "CSS"
+)
+
+// filterLanguage returns the language used to filter the response body, or
+// an empty string when the body cannot be filtered
+func filterLanguage(resp *request.ResponseData) string {
+	switch {
+	case resp.IsXML():
+		return filterXPath
+	case resp.IsHTML():
+		return filterCSS
+	}
+	return ""
+}
+
+// filterBody renders the values an XPath expression or CSS selector picks
+// from the body, or why it picks nothing
+func filterBody(body, language, expr string) string {
+	var values []string
+	var err error
+	if language == filterXPath {
+		var xpath *request.XPath
+		if xpath, err = request.CompileXPath(expr); err == nil {
+			values, err = xpath.Query(body)
+		}
+	} else {
+		var selector *request.Selector
+		if selector, err = request.CompileSelector(expr); err == nil {
+			values, err = selector.Query(body)
+		}
+	}
+	if err != nil {
+		return warningStyle.Render(err.Error())
+	}
+	if len(values) == 0 {
+		return warningStyle.Render("(no matches)")
+	}
+	return strings.Join(values, "\n")
+}
+
+// oauthTokenStatus describes the cached token of an OAuth2 profile without
+// revealing it
+func oauthTokenStatus(profile string) string {
+	p, err := oauth.LoadProfile(profile)
+	if err != nil {
+		return err.Error()
+	}
+	token, err := oauth.LoadToken(p)
+	return tokenStatus(token, err, "lighttr oauth login "+profile)
+}
+
+// authProfileStatus describes the credentials saved as an auth profile
+// without reading its secrets
+func authProfileStatus(name string) string {
+	profiles, err := authprofile.List()
+	if err != nil {
+		return err.Error()
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			if details := profile.Details(); details != "-" {
+				return fmt.Sprintf("%s (%s)", profile.Auth.Type, details)
+			}
+			return string(profile.Auth.Type)
+		}
+	}
+	return "not saved (save it with lighttr auth save " + name + ")"
+}
+
+// azureTokenStatus describes the cached Entra ID token of the user logged
+// in to the application without revealing it
+func azureTokenStatus(app azure.App) string {
+	if app.ClientID == "" {
+		return "none (set the client ID)"
+	}
+	token, err := oauth.LoadToken(app.Profile())
+	return tokenStatus(token, err, app.LoginCommand())
+}
+
+// kerberosStatus describes the login of kinit the ticket is obtained with
+func kerberosStatus() string {
+	user, expiry, err := kerberos.Login()
+	switch {
+	case err != nil:
+		return err.Error()
+	case time.Now().After(expiry):
+		return fmt.Sprintf("%s (expired %s, run kinit)", user, expiry.Local().Format(time.DateTime))
+	}
+	return fmt.Sprintf("%s (expires %s)", user, expiry.Local().Format(time.DateTime))
+}
+
+// tokenStatus describes a cached token, telling to run the login command
+// when there is none or it expired
+func tokenStatus(token *oauth.Token, err error, login string) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case token == nil || token.AccessToken == "":
+		return "none (run " + login + ")"
+	case token.Expiry.IsZero():
+		return "********"
+	case token.RefreshToken != "":
+		return fmt.Sprintf("******** (expires %s, refreshed automatically)", token.Expiry.Local().Format(time.DateTime))
+	case token.Expired():
+		return fmt.Sprintf("******** (expired %s, run %s)", token.Expiry.Local().Format(time.DateTime), login)
+	}
+	return fmt.Sprintf("******** (expires %s)", token.Expiry.Local().Format(time.DateTime))
+}
@@ -1,10 +1,23 @@
 package tui
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/bubbles/cursor"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/nshekhawat/lighttr/internal/request"
+	"github.com/nshekhawat/lighttr/internal/azure"
+	"github.com/nshekhawat/lighttr/internal/config"
+	"github.com/nshekhawat/lighttr/internal/notify"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/pkg/request"
 )
 
 func TestNewModel(t *testing.T) {
@@ -19,8 +32,8 @@ func TestNewModel(t *testing.T) {
 		t.Errorf("Expected initial active input to be 0, got %d", model.activeInput)
 	}
 
-	if len(model.inputs) != 11 {
-		t.Errorf("Expected 11 input fields, got %d", len(model.inputs))
+	if len(model.inputs) != 43 {
+		t.Errorf("Expected 43 input fields, got %d", len(model.inputs))
 	}
 
 	// Check input field configuration
@@ -31,15 +44,47 @@ func TestNewModel(t *testing.T) {
 	}{
 		{label: "URL", placeholder: "https://api.example.com/path", value: ""},
 		{label: "Method", placeholder: "GET", value: "GET"},
-		{label: "Auth Type (none/basic/apikey/mtls)", placeholder: "none", value: "none"},
+		{label: "Auth Type (none/basic/bearer/apikey/mtls/oauth2/gcp/azure/jwt/ntlm/negotiate/profile/session)", placeholder: "none", value: "none"},
 		{label: "Auth Username", placeholder: "username", value: ""},
 		{label: "Auth Password", placeholder: "password", value: ""},
+		{label: "Bearer Token", placeholder: "your-token", value: ""},
 		{label: "API Key", placeholder: "your-api-key", value: ""},
-		{label: "TLS Cert File", placeholder: "/path/to/cert.pem", value: ""},
+		{label: "API Key In (Header[: Prefix] or ?query)", placeholder: "Authorization: Bearer", value: ""},
+		{label: "TLS Cert File", placeholder: "/path/to/cert.pem or bundle.p12", value: ""},
 		{label: "TLS Key File", placeholder: "/path/to/key.pem", value: ""},
-		{label: "Headers (key:value,key2:value2)", placeholder: "Content-Type:application/json", value: ""},
+		{label: "TLS Cert Subject (system keystore, instead of files)", placeholder: "CN=jane.doe", value: ""},
+		{label: "OAuth2 Profile (log in with lighttr oauth login)", placeholder: "github", value: ""},
+		{label: "GCP Audience (ID token for Cloud Run; empty for an access token)", placeholder: "https://my-service-abc123-uc.a.run.app", value: ""},
+		{label: "Azure Tenant (ID or domain)", placeholder: "contoso.onmicrosoft.com", value: ""},
+		{label: "Azure Client ID", placeholder: "11111111-2222-3333-4444-555555555555", value: ""},
+		{label: "Azure Client Secret (empty for lighttr azure login)", placeholder: "client-secret", value: ""},
+		{label: "Azure Scope (default Microsoft Graph)", placeholder: "https://graph.microsoft.com/.default", value: ""},
+		{label: "JWT Claims (JSON; iat and exp are added)", placeholder: `{"sub": "orders-service", "aud": "billing"}`, value: ""},
+		{label: "JWT Key File (secret for HS256, PEM key otherwise)", placeholder: "/path/to/signing-key.pem", value: ""},
+		{label: "JWT Algorithm (HS256/RS256/ES256)", placeholder: "HS256", value: ""},
+		{label: "Auth Profile (save with lighttr auth save)", placeholder: "billing", value: ""},
+		{label: "Session Login URL (POSTed to log in before the request)", placeholder: "https://api.example.com/login", value: ""},
+		{label: "Session Login Body ({{username}} and {{password}} filled in; empty for basic auth)", placeholder: `{"username": "{{username}}", "password": "{{password}}"}`, value: ""},
+		{label: "Session Credential ($.path, header:Name, or cookie:Name of the login response)", placeholder: "$.token", value: ""},
+		{label: "Session Token Header (Header[: Prefix])", placeholder: "Authorization: Bearer", value: ""},
+		{label: "TLS CA File (trust an internal CA)", placeholder: "/path/to/ca.pem", value: ""},
+		{label: "TLS CA Only (true/false; not the system CAs)", placeholder: "false", value: ""},
+		{label: "TLS Skip Verify (true/false)", placeholder: "false", value: ""},
+		{label: "TLS Server Name (SNI and name verified; default the URL's host)", placeholder: "api.internal.corp", value: ""},
+		{label: "Headers (key:value,key2:value2)", placeholder: "Content-Type:application/json,#X-Disabled:1", value: ""},
 		{label: "Query Params (key=value&key2=value2)", placeholder: "key=value&key2=value2", value: ""},
+		{label: "Path Params (key=value&key2=value2)", placeholder: "id=123", value: ""},
+		{label: "Method Override Header (sends PUT/PATCH/DELETE as POST)", placeholder: "X-HTTP-Method-Override", value: ""},
+		{label: "Timeouts (total[,connect,tls,headers])", placeholder: "30s,5s", value: ""},
+		{label: "Max Redirects (0 to not follow)", placeholder: "10", value: ""},
+		{label: "Retries (count[,backoff])", placeholder: "3,500ms", value: ""},
+		{label: "HTTP Version (auto/1.1/2/3)", placeholder: "auto", value: ""},
+		{label: "Proxy (blank for HTTP_PROXY/HTTPS_PROXY)", placeholder: "http://proxy.corp:3128", value: ""},
+		{label: "Proxy Bypass (hosts, .domains, CIDRs)", placeholder: "localhost,.internal.corp,10.0.0.0/8", value: ""},
+		{label: "Form Params (key=value&key2=value2, sent urlencoded)", placeholder: "user=jane&remember=true", value: ""},
 		{label: "Body", placeholder: "{\"key\": \"value\"}", value: ""},
+		{label: "Body File (streamed instead of Body)", placeholder: "/path/to/payload.json", value: ""},
+		{label: "Multipart Form (name=value,file=@path)", placeholder: "name=Jane,avatar=@/path/to/avatar.png", value: ""},
 	}
 
 	for i, expected := range expectedFields {
@@ -97,27 +142,53 @@ func TestModel_buildRequestData(t *testing.T) {
 				Password: "testpass",
 			},
 		},
+		{
+			name: "bearer auth",
+			inputs: map[int]string{
+				0: "https://api.example.com",
+				1: "GET",
+				2: "bearer",
+				5: "test-token",
+			},
+			wantAuth: request.AuthData{
+				Type:  request.BearerAuth,
+				Token: "test-token",
+			},
+		},
 		{
 			name: "api key auth",
 			inputs: map[int]string{
 				0: "https://api.example.com",
 				1: "GET",
 				2: "apikey",
-				5: "test-api-key",
+				6: "test-api-key",
 			},
 			wantAuth: request.AuthData{
 				Type:   request.APIKeyAuth,
 				APIKey: "test-api-key",
 			},
 		},
+		{
+			name: "mutual TLS auth from system keystore",
+			inputs: map[int]string{
+				0:                   "https://api.example.com",
+				1:                   "GET",
+				2:                   "mtls",
+				inputTLSCertSubject: "CN=jane.doe",
+			},
+			wantAuth: request.AuthData{
+				Type:        request.MutualTLSAuth,
+				CertSubject: "CN=jane.doe",
+			},
+		},
 		{
 			name: "mutual TLS auth",
 			inputs: map[int]string{
 				0: "https://api.example.com",
 				1: "GET",
 				2: "mtls",
-				6: "/path/to/cert.pem",
-				7: "/path/to/key.pem",
+				8: "/path/to/cert.pem",
+				9: "/path/to/key.pem",
 			},
 			wantAuth: request.AuthData{
 				Type:     request.MutualTLSAuth,
@@ -125,6 +196,145 @@ func TestModel_buildRequestData(t *testing.T) {
 				KeyFile:  "/path/to/key.pem",
 			},
 		},
+		{
+			name: "mutual TLS with PKCS#12 bundle",
+			inputs: map[int]string{
+				0: "https://api.example.com",
+				1: "GET",
+				2: "mtls",
+				4: "secret",
+				8: "/path/to/client.p12",
+			},
+			wantAuth: request.AuthData{
+				Type:     request.MutualTLSAuth,
+				CertFile: "/path/to/client.p12",
+				Password: "secret",
+			},
+		},
+		{
+			name: "oauth2 auth",
+			inputs: map[int]string{
+				0:                  "https://api.example.com",
+				1:                  "GET",
+				2:                  "oauth2",
+				inputBearerToken:   "ignored",
+				inputOAuth2Profile: "github",
+			},
+			wantAuth: request.AuthData{
+				Type:          request.OAuth2Auth,
+				OAuth2Profile: "github",
+			},
+		},
+		{
+			name: "auth profile",
+			inputs: map[int]string{
+				0:                 "https://api.example.com",
+				1:                 "GET",
+				2:                 "profile",
+				inputAuthPassword: "ignored",
+				inputAuthProfile:  " billing ",
+			},
+			wantAuth: request.AuthData{
+				Type:    request.ProfileAuth,
+				Profile: "billing",
+			},
+		},
+		{
+			name: "session auth",
+			inputs: map[int]string{
+				0:                   "https://api.example.com/orders",
+				1:                   "GET",
+				2:                   "session",
+				inputBearerToken:    "ignored",
+				inputAuthUsername:   "jane",
+				inputAuthPassword:   "s3cret",
+				inputSessionLogin:   " https://api.example.com/login ",
+				inputSessionBody:    `{"user": "{{username}}", "pass": "{{password}}"}`,
+				inputSessionExtract: "cookie:SESSIONID",
+			},
+			wantAuth: request.AuthData{
+				Type:             request.SessionAuth,
+				Username:         "jane",
+				Password:         "s3cret",
+				SessionLoginURL:  "https://api.example.com/login",
+				SessionLoginBody: `{"user": "{{username}}", "pass": "{{password}}"}`,
+				SessionExtract:   "cookie:SESSIONID",
+			},
+		},
+		{
+			name: "gcp auth",
+			inputs: map[int]string{
+				0:                  "https://my-service-abc123-uc.a.run.app",
+				1:                  "GET",
+				2:                  "gcp",
+				inputOAuth2Profile: "ignored",
+				inputGCPAudience:   "https://my-service-abc123-uc.a.run.app",
+			},
+			wantAuth: request.AuthData{
+				Type:        request.GCPAuth,
+				GCPAudience: "https://my-service-abc123-uc.a.run.app",
+			},
+		},
+		{
+			name: "azure auth",
+			inputs: map[int]string{
+				0:                      "https://graph.microsoft.com/v1.0/users",
+				1:                      "GET",
+				2:                      "azure",
+				inputGCPAudience:       "ignored",
+				inputAzureTenant:       " contoso.onmicrosoft.com ",
+				inputAzureClientID:     "11111111-2222-3333-4444-555555555555",
+				inputAzureClientSecret: "s3cret",
+			},
+			wantAuth: request.AuthData{
+				Type:              request.AzureAuth,
+				AzureTenant:       "contoso.onmicrosoft.com",
+				AzureClientID:     "11111111-2222-3333-4444-555555555555",
+				AzureClientSecret: "s3cret",
+			},
+		},
+		{
+			name: "jwt auth",
+			inputs: map[int]string{
+				0:               "https://api.example.com/orders",
+				1:               "GET",
+				2:               "jwt",
+				inputJWTClaims:  `{"sub": "orders-service"}`,
+				inputJWTKeyFile: " /keys/orders.pem ",
+				inputJWTAlg:     "RS256",
+			},
+			wantAuth: request.AuthData{
+				Type:       request.JWTAuth,
+				JWTClaims:  `{"sub": "orders-service"}`,
+				JWTKeyFile: "/keys/orders.pem",
+				JWTAlg:     "RS256",
+			},
+		},
+		{
+			name: "ntlm auth",
+			inputs: map[int]string{
+				0:                 "https://intranet.corp.example.com/ews/exchange.asmx",
+				1:                 "POST",
+				2:                 "ntlm",
+				inputAuthUsername: `CORP\jane`,
+				inputAuthPassword: "s3cret",
+			},
+			wantAuth: request.AuthData{
+				Type:     request.NTLMAuth,
+				Username: `CORP\jane`,
+				Password: "s3cret",
+			},
+		},
+		{
+			name: "negotiate auth ignores hidden credentials",
+			inputs: map[int]string{
+				0:                 "https://wiki.corp.example.com/api/pages",
+				1:                 "GET",
+				2:                 "negotiate",
+				inputAuthUsername: "jane",
+			},
+			wantAuth: request.AuthData{Type: request.NegotiateAuth},
+		},
 	}
 
 	for _, tt := range tests {
@@ -152,12 +362,31 @@ func TestModel_buildRequestData(t *testing.T) {
 			if model.requestData.Auth.APIKey != tt.wantAuth.APIKey {
 				t.Errorf("Expected API key %s, got %s", tt.wantAuth.APIKey, model.requestData.Auth.APIKey)
 			}
+			if model.requestData.Auth.Token != tt.wantAuth.Token {
+				t.Errorf("Expected token %s, got %s", tt.wantAuth.Token, model.requestData.Auth.Token)
+			}
 			if model.requestData.Auth.CertFile != tt.wantAuth.CertFile {
 				t.Errorf("Expected cert file %s, got %s", tt.wantAuth.CertFile, model.requestData.Auth.CertFile)
 			}
 			if model.requestData.Auth.KeyFile != tt.wantAuth.KeyFile {
 				t.Errorf("Expected key file %s, got %s", tt.wantAuth.KeyFile, model.requestData.Auth.KeyFile)
 			}
+			if model.requestData.Auth.CertSubject != tt.wantAuth.CertSubject {
+				t.Errorf("Expected cert subject %s, got %s", tt.wantAuth.CertSubject, model.requestData.Auth.CertSubject)
+			}
+			if model.requestData.Auth.OAuth2Profile != tt.wantAuth.OAuth2Profile {
+				t.Errorf("Expected OAuth2 profile %s, got %s", tt.wantAuth.OAuth2Profile, model.requestData.Auth.OAuth2Profile)
+			}
+			if model.requestData.Auth.GCPAudience != tt.wantAuth.GCPAudience {
+				t.Errorf("Expected GCP audience %s, got %s", tt.wantAuth.GCPAudience, model.requestData.Auth.GCPAudience)
+			}
+			if got := model.requestData.Auth; got.AzureTenant != tt.wantAuth.AzureTenant || got.AzureClientID != tt.wantAuth.AzureClientID ||
+				got.AzureClientSecret != tt.wantAuth.AzureClientSecret || got.AzureScope != tt.wantAuth.AzureScope {
+				t.Errorf("Expected Azure settings %+v, got %+v", tt.wantAuth, got)
+			}
+			if got := model.requestData.Auth; got.JWTClaims != tt.wantAuth.JWTClaims || got.JWTKeyFile != tt.wantAuth.JWTKeyFile || got.JWTAlg != tt.wantAuth.JWTAlg {
+				t.Errorf("Expected JWT settings %+v, got %+v", tt.wantAuth, got)
+			}
 		})
 	}
 }
@@ -184,8 +413,8 @@ func TestModel_Update(t *testing.T) {
 			name: "handle shift+tab key",
 			msg:  tea.KeyMsg{Type: tea.KeyShiftTab},
 			checkState: func(t *testing.T, m Model) {
-				if m.activeInput != 10 {
-					t.Errorf("Expected active input to be 10, got %d", m.activeInput)
+				if m.activeInput != 42 {
+					t.Errorf("Expected active input to be 42, got %d", m.activeInput)
 				}
 			},
 		},
@@ -220,6 +449,464 @@ func TestModel_Update(t *testing.T) {
 	}
 }
 
+func TestModel_PathParamsPrompt(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com/orgs/{org}/users/{id}")
+	model.inputs[inputPathParams].textinput.SetValue("org=acme")
+
+	// Enter on the request screen prompts for the missing path parameter
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.screen != screenPathParams {
+		t.Fatalf("Expected screen to be screenPathParams, got %v", model.screen)
+	}
+	if len(model.paramInputs) != 1 || model.paramInputs[0].label != "id" {
+		t.Fatalf("Expected a single prompt for id, got %+v", model.paramInputs)
+	}
+
+	// Submitting without a value keeps the prompt open
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.screen != screenPathParams {
+		t.Errorf("Expected screen to stay on screenPathParams, got %v", model.screen)
+	}
+
+	model.paramInputs[0].textinput.SetValue("42")
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = newModel.(Model)
+	if model.screen != screenPreview {
+		t.Fatalf("Expected screen to be screenPreview, got %v", model.screen)
+	}
+	if got := model.requestData.ResolvedURL(); got != "https://api.example.com/orgs/acme/users/42" {
+		t.Errorf("Expected resolved URL, got %s", got)
+	}
+	if got := model.inputs[inputPathParams].textinput.Value(); got != "org=acme&id=42" {
+		t.Errorf("Expected path params field to be updated, got %s", got)
+	}
+}
+
+func TestModel_HeaderPresetsAndToggles(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputHeaders].textinput.SetValue("X-Debug:1")
+
+	// Alt+1 applies the first preset
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'1'}, Alt: true})
+	model = newModel.(Model)
+	want := "X-Debug:1,Accept:application/json,Content-Type:application/json"
+	if got := model.inputs[inputHeaders].textinput.Value(); got != want {
+		t.Fatalf("Expected headers %q, got %q", want, got)
+	}
+
+	// Ctrl+T on the headers field disables the header under the cursor
+	model.activeInput = inputHeaders
+	model.inputs[inputHeaders].textinput.SetCursor(0)
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	model = newModel.(Model)
+
+	model.buildRequestData()
+	if _, ok := model.requestData.Headers["X-Debug"]; ok {
+		t.Error("Expected X-Debug header to be disabled")
+	}
+	if model.requestData.DisabledHeaders["X-Debug"] != "1" {
+		t.Errorf("Expected X-Debug in disabled headers, got %v", model.requestData.DisabledHeaders)
+	}
+	if model.requestData.Headers["Accept"] != "application/json" {
+		t.Errorf("Expected Accept header from preset, got %v", model.requestData.Headers)
+	}
+}
+
+func TestModel_DefaultHeaders(t *testing.T) {
+	model := NewModel().WithConfig(&config.Config{
+		DefaultHeaders: map[string]string{
+			"User-Agent":  "lighttr",
+			"X-Org-Trace": "on",
+			"Accept":      "*/*",
+		},
+	})
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputHeaders].textinput.SetValue("Accept:application/json,#X-Org-Trace:")
+	model.buildRequestData()
+
+	headers := model.requestData.EffectiveHeaders()
+	if headers["User-Agent"] != "lighttr" {
+		t.Errorf("Expected default User-Agent header, got %v", headers)
+	}
+	if headers["Accept"] != "application/json" {
+		t.Errorf("Expected request Accept header to override default, got %v", headers)
+	}
+	if _, ok := headers["X-Org-Trace"]; ok {
+		t.Errorf("Expected disabled default header to be removed, got %v", headers)
+	}
+
+	model.screen = screenPreview
+	view := model.View()
+	if !strings.Contains(view, "User-Agent: lighttr") || !strings.Contains(view, "(default)") {
+		t.Errorf("Expected preview to mark default headers, got %s", view)
+	}
+}
+
+func TestModel_ResponseTLSDetails(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		TLS: &request.TLSInfo{
+			Version:     "TLS 1.3",
+			CipherSuite: "TLS_AES_128_GCM_SHA256",
+			OCSPStapled: true,
+			Revocation:  request.RevocationRevoked,
+			Warnings:    []string{"certificate has been revoked"},
+		},
+	}
+
+	view := model.View()
+	for _, expected := range []string{"TLS 1.3", "OCSP Stapled: yes", "Revocation: revoked", "certificate has been revoked"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected response view to contain %q", expected)
+		}
+	}
+}
+
+func TestModel_RetryAfter(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.requestData = &request.RequestData{Method: "GET", URL: "not-a-url"}
+	model.response = &request.ResponseData{StatusCode: 429, RetryAfter: 2 * time.Second}
+
+	if view := model.View(); !strings.Contains(view, "Retry-After: 2s") {
+		t.Errorf("Expected response view to show Retry-After, got %s", view)
+	}
+
+	// r starts the countdown
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m := updated.(Model)
+	if m.retryIn != 2*time.Second || cmd == nil {
+		t.Fatalf("Expected a 2s countdown, got %v", m.retryIn)
+	}
+	if view := m.View(); !strings.Contains(view, "Retrying in 2s") {
+		t.Errorf("Expected response view to show the countdown, got %s", view)
+	}
+
+	updated, _ = m.Update(retryTickMsg{elapsed: time.Second})
+	m = updated.(Model)
+	if m.retryIn != time.Second {
+		t.Errorf("Expected 1s remaining, got %v", m.retryIn)
+	}
+
+	// The last tick re-sends the request
+	updated, cmd = m.Update(retryTickMsg{elapsed: time.Second})
+	m = updated.(Model)
+	if m.retryIn != 0 || m.response != nil || cmd == nil {
+		t.Fatal("Expected the request to be re-sent")
+	}
+	if _, ok := cmd().(error); !ok {
+		t.Error("Expected the re-sent invalid request to report an error")
+	}
+
+	// ESC cancels a pending retry
+	m.response = &request.ResponseData{StatusCode: 503, RetryAfter: time.Second}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	updated, _ = updated.(Model).Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m = updated.(Model); m.retryIn != 0 {
+		t.Errorf("Expected ESC to cancel the retry, got %v", m.retryIn)
+	}
+}
+
+func TestModel_ReducedMotion(t *testing.T) {
+	SetReducedMotion(true)
+	defer SetReducedMotion(false)
+
+	model := NewModel()
+	if model.Init() != nil {
+		t.Error("Expected no cursor blinking under reduced motion")
+	}
+	if mode := model.inputs[inputURL].textinput.Cursor.Mode(); mode != cursor.CursorStatic {
+		t.Errorf("Expected a steady cursor, got %v", mode)
+	}
+
+	// The countdown waits out Retry-After in one step
+	model.screen = screenResponse
+	model.requestData = &request.RequestData{Method: "GET", URL: "not-a-url"}
+	model.response = &request.ResponseData{StatusCode: 429, RetryAfter: 30 * time.Second}
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m := updated.(Model)
+	if view := m.View(); !strings.Contains(view, "Retrying at "+m.retryAt.Format(time.TimeOnly)) {
+		t.Errorf("Expected response view to show when the request is retried, got %s", view)
+	}
+	updated, cmd := m.Update(retryTickMsg{elapsed: 30 * time.Second})
+	if m = updated.(Model); m.retryIn != 0 || m.response != nil || cmd == nil {
+		t.Fatal("Expected the request to be re-sent")
+	}
+}
+
+func TestModel_Scrollback(t *testing.T) {
+	model := NewModel()
+	model.requestData = &request.RequestData{Method: "GET", URL: "https://api.example.com/users"}
+	resp := &request.ResponseData{
+		StatusCode:   200,
+		ResponseTime: 120 * time.Millisecond,
+		Headers:      map[string]string{"Content-Type": "application/json", "Age": "3"},
+		Body:         `{"id": 1}`,
+	}
+	if _, cmd := model.Update(resp); cmd != nil && cmd() != nil {
+		t.Error("Expected nothing printed without scrollback")
+	}
+
+	SetScrollback(true)
+	defer SetScrollback(false)
+	if _, cmd := model.Update(resp); cmd == nil {
+		t.Fatal("Expected the response to be printed to the scrollback")
+	}
+
+	expected := "> GET https://api.example.com/users\n" +
+		"< 200 OK (120ms)\n" +
+		"< Age: 3\n" +
+		"< Content-Type: application/json\n" +
+		"\n" +
+		`{"id": 1}` + "\n"
+	if got := transcript(model.requestData, resp, nil); got != expected {
+		t.Errorf("Expected transcript %q, got %q", expected, got)
+	}
+	expected = "> GET https://api.example.com/users\nError: connection refused\n"
+	if got := transcript(model.requestData, nil, fmt.Errorf("connection refused")); got != expected {
+		t.Errorf("Expected transcript %q, got %q", expected, got)
+	}
+}
+
+func TestModel_Timeouts(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputTimeout].textinput.SetValue("30s, 5s")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if model.requestData.Timeout != 30*time.Second || model.requestData.ConnectTimeout != 5*time.Second {
+		t.Errorf("Expected 30s/5s timeouts, got %v/%v", model.requestData.Timeout, model.requestData.ConnectTimeout)
+	}
+
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "Total: 30s") || !strings.Contains(view, "Connect: 5s") {
+		t.Errorf("Expected preview to show timeouts, got %s", view)
+	}
+
+	// Phases can be set on their own
+	model.inputs[inputTimeout].textinput.SetValue(",,2s,10s")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if model.requestData.Timeout != 0 || model.requestData.TLSTimeout != 2*time.Second || model.requestData.ResponseHeaderTimeout != 10*time.Second {
+		t.Errorf("Expected 2s TLS and 10s header timeouts, got %v/%v", model.requestData.TLSTimeout, model.requestData.ResponseHeaderTimeout)
+	}
+	if view := model.View(); !strings.Contains(view, "TLS Handshake: 2s") || !strings.Contains(view, "Response Headers: 10s") || strings.Contains(view, "Total:") {
+		t.Errorf("Expected preview to show the phase timeouts, got %s", view)
+	}
+	if got := formatTimeouts(0, 0, 2*time.Second, 10*time.Second); got != ",,2s,10s" {
+		t.Errorf("formatTimeouts() = %q, want %q", got, ",,2s,10s")
+	}
+	if got := formatTimeouts(30*time.Second, 0, 0, 0); got != "30s" {
+		t.Errorf("formatTimeouts() = %q, want %q", got, "30s")
+	}
+
+	// An invalid timeout keeps the user on the request screen
+	model.screen = screenRequest
+	model.inputs[inputTimeout].textinput.SetValue("soon")
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m := updated.(Model)
+	if m.screen != screenRequest || m.err == nil {
+		t.Fatalf("Expected to stay on the request screen with an error")
+	}
+	if view := m.View(); !strings.Contains(view, "invalid timeout") {
+		t.Errorf("Expected request screen to show the error, got %s", view)
+	}
+}
+
+func TestModel_Redirects(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputMaxRedirects].textinput.SetValue("0")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if !model.requestData.DisableRedirects {
+		t.Error("Expected 0 max redirects to disable following redirects")
+	}
+
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "Redirects: not followed") {
+		t.Errorf("Expected preview to show the redirect policy, got %s", view)
+	}
+
+	model.inputs[inputMaxRedirects].textinput.SetValue("many")
+	if err := model.buildRequestData(); err == nil {
+		t.Error("Expected an invalid max redirects value to be rejected")
+	}
+
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Redirects: []request.RedirectHop{
+			{URL: "http://example.com/old", StatusCode: 301, Location: "https://example.com/new"},
+		},
+	}
+	if view := model.View(); !strings.Contains(view, "301 http://example.com/old -> https://example.com/new") {
+		t.Errorf("Expected response view to show the redirect chain, got %s", view)
+	}
+}
+
+func TestModel_ServerTrust(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://internal.example.com")
+	model.inputs[inputAuthType].textinput.SetValue("basic")
+	model.inputs[inputAuthUsername].textinput.SetValue("user")
+	model.inputs[inputAuthPassword].textinput.SetValue("pass")
+	model.inputs[inputTLSCAFile].textinput.SetValue("/etc/ssl/internal-ca.pem")
+	model.inputs[inputTLSCAOnly].textinput.SetValue("true")
+	model.inputs[inputTLSInsecure].textinput.SetValue("true")
+	model.inputs[inputTLSServerName].textinput.SetValue("api.internal.corp")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if model.requestData.Auth.CAFile != "/etc/ssl/internal-ca.pem" || !model.requestData.Auth.CAFileOnly || !model.requestData.Auth.InsecureSkipVerify {
+		t.Errorf("Expected CA file and skip verify to apply to basic auth, got %+v", model.requestData.Auth)
+	}
+	if model.requestData.ServerName != "api.internal.corp" {
+		t.Errorf("Expected server name api.internal.corp, got %q", model.requestData.ServerName)
+	}
+
+	model.screen = screenPreview
+	view := model.View()
+	if !strings.Contains(view, "CA File: /etc/ssl/internal-ca.pem (only") || !strings.Contains(view, "verification is disabled") ||
+		!strings.Contains(view, "TLS Server Name: api.internal.corp") {
+		t.Errorf("Expected preview to show server trust settings, got %s", view)
+	}
+
+	// Loading the request back fills the trust fields
+	loaded := NewModel()
+	loaded.fillForm(model.requestData)
+	if loaded.inputs[inputTLSCAOnly].textinput.Value() != "true" || loaded.inputs[inputTLSServerName].textinput.Value() != "api.internal.corp" {
+		t.Errorf("Expected loaded trust fields, got %q %q", loaded.inputs[inputTLSCAOnly].textinput.Value(), loaded.inputs[inputTLSServerName].textinput.Value())
+	}
+
+	// The trust fields stay visible for every auth type
+	for _, authType := range []request.AuthType{request.NoAuth, request.BasicAuth, request.BearerAuth, request.APIKeyAuth, request.MutualTLSAuth, request.OAuth2Auth, request.GCPAuth, request.AzureAuth, request.JWTAuth} {
+		if shouldSkipAuthField(inputTLSCAFile, authType) || shouldSkipAuthField(inputTLSCAOnly, authType) ||
+			shouldSkipAuthField(inputTLSInsecure, authType) || shouldSkipAuthField(inputTLSServerName, authType) {
+			t.Errorf("Expected server trust fields to be shown for %s", authType)
+		}
+	}
+
+	model.inputs[inputTLSInsecure].textinput.SetValue("maybe")
+	if err := model.buildRequestData(); err == nil {
+		t.Error("Expected an invalid skip verify value to be rejected")
+	}
+}
+
+func TestOAuthTokenStatus(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// Keep the test's token out of the system keyring
+	if err := (&config.Config{TokenStorage: "file"}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	github := &oauth.Profile{Name: "github", TokenURL: "https://github.com/login/oauth/access_token", ClientID: "abc"}
+	if err := oauth.SaveProfile(github); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := oauthTokenStatus("github"); !strings.Contains(status, "lighttr oauth login github") {
+		t.Errorf("Expected a profile without a token to ask for a login, got %q", status)
+	}
+	if _, err := oauth.SaveToken(github, &oauth.Token{AccessToken: "secret", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	status := oauthTokenStatus("github")
+	if strings.Contains(status, "secret") || !strings.Contains(status, "********") || !strings.Contains(status, "refreshed automatically") {
+		t.Errorf("Expected the token to be masked, got %q", status)
+	}
+
+	app := azure.App{Tenant: "contoso", ClientID: "cli", Scope: azure.DefaultScope}
+	if status := azureTokenStatus(app); status != "none (run lighttr azure login --tenant contoso --client-id cli)" {
+		t.Errorf("Expected an application without a token to ask for a login, got %q", status)
+	}
+	if _, err := oauth.SaveToken(app.Profile(), &oauth.Token{AccessToken: "secret", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if status := azureTokenStatus(app); strings.Contains(status, "secret") || !strings.Contains(status, "expired") {
+		t.Errorf("Expected the expired token to be masked, got %q", status)
+	}
+}
+
+func TestModel_ResponseTiming(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Timing: &request.Timing{
+			DNS:      2 * time.Millisecond,
+			Connect:  3 * time.Millisecond,
+			TTFB:     120 * time.Millisecond,
+			Transfer: 5 * time.Millisecond,
+		},
+	}
+
+	view := model.View()
+	for _, expected := range []string{"Timing:", "DNS Lookup:         2ms", "Time to First Byte: 120ms", "Content Transfer:   5ms"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected response view to contain %q, got %s", expected, view)
+		}
+	}
+}
+
+func TestModel_Retries(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputRetries].textinput.SetValue("2, 1s")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if retry := model.requestData.Retry; retry == nil || retry.MaxAttempts != 3 || retry.Backoff != time.Second {
+		t.Fatalf("Expected 3 attempts with 1s backoff, got %+v", retry)
+	}
+
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "Retries: up to 2 (backoff 1s)") {
+		t.Errorf("Expected preview to show the retry policy, got %s", view)
+	}
+
+	model.inputs[inputRetries].textinput.SetValue("lots")
+	if err := model.buildRequestData(); err == nil {
+		t.Error("Expected invalid retries to be rejected")
+	}
+
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Attempts: []request.Attempt{
+			{StatusCode: 503, ResponseTime: 10 * time.Millisecond, Wait: time.Second},
+			{StatusCode: 200, ResponseTime: 10 * time.Millisecond},
+		},
+	}
+	if view := model.View(); !strings.Contains(view, "1. 503 (10ms), retried after 1s") {
+		t.Errorf("Expected response view to list attempts, got %s", view)
+	}
+}
+
+func TestModel_MethodOverridePreview(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com/users/1")
+	model.inputs[inputMethod].textinput.SetValue("DELETE")
+	model.inputs[inputMethodOverride].textinput.SetValue("X-HTTP-Method-Override")
+	model.buildRequestData()
+	model.screen = screenPreview
+
+	view := model.View()
+	if !strings.Contains(view, "POST https://api.example.com/users/1") {
+		t.Errorf("Expected preview to show the wire method, got %s", view)
+	}
+	if !strings.Contains(view, "X-HTTP-Method-Override: DELETE") {
+		t.Errorf("Expected preview to show the override header, got %s", view)
+	}
+}
+
 func TestModel_executeRequest(t *testing.T) {
 	model := NewModel()
 
@@ -235,10 +922,15 @@ func TestModel_executeRequest(t *testing.T) {
 		t.Error("Expected error message for invalid request")
 	}
 
-	// Test with valid request (using a mock would be better in a real implementation)
+	// Test with valid request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
 	model.requestData = &request.RequestData{
 		Method: "GET",
-		URL:    "http://example.com",
+		URL:    server.URL,
 		Auth:   request.AuthData{Type: request.NoAuth},
 	}
 
@@ -279,3 +971,482 @@ func TestModel_View(t *testing.T) {
 		t.Error("Expected non-empty view for response screen")
 	}
 }
+
+func TestModel_BodyFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(file, []byte(`{"name": "Jane"}`), 0644); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputMethod].textinput.SetValue("POST")
+	model.inputs[inputBodyFile].textinput.SetValue(" " + file + " ")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if model.requestData.BodyFile != file {
+		t.Errorf("BodyFile = %q, want %q", model.requestData.BodyFile, file)
+	}
+
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "Body File: "+file+" (16 bytes, streamed)") {
+		t.Errorf("Expected preview to show the body file, got %s", view)
+	}
+
+	model.inputs[inputBodyFile].textinput.SetValue(file + ".missing")
+	model.buildRequestData()
+	if view := model.View(); !strings.Contains(view, "(not found)") {
+		t.Errorf("Expected preview to flag a missing body file, got %s", view)
+	}
+}
+
+func TestModel_Multipart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "avatar.png")
+	if err := os.WriteFile(file, []byte("PNGDATA"), 0644); err != nil {
+		t.Fatalf("Failed to write form file: %v", err)
+	}
+
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputMethod].textinput.SetValue("POST")
+	model.inputs[inputMultipart].textinput.SetValue("name=Jane, avatar=@" + file + ", doc=@missing.pdf;type=application/pdf")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	want := []request.FormPart{
+		{Name: "name", Value: "Jane"},
+		{Name: "avatar", File: file},
+		{Name: "doc", File: "missing.pdf", ContentType: "application/pdf"},
+	}
+	if !reflect.DeepEqual(model.requestData.Multipart, want) {
+		t.Errorf("Multipart = %+v, want %+v", model.requestData.Multipart, want)
+	}
+
+	model.screen = screenPreview
+	view := model.View()
+	for _, expected := range []string{"Multipart Form:", "name=Jane", "avatar=@" + file + "\n", "doc=@missing.pdf;type=application/pdf (not found)"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected preview to contain %q, got %s", expected, view)
+		}
+	}
+
+	model.inputs[inputMultipart].textinput.SetValue("novalue")
+	if err := model.buildRequestData(); err == nil {
+		t.Error("Expected an error for an invalid form part")
+	}
+}
+
+func TestModel_ResponseFilter(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "text/xml"},
+		Body:       `<Envelope><Body><Price currency="USD">34.5</Price></Body></Envelope>`,
+	}
+	if view := model.View(); !strings.Contains(view, "/ to filter with XPath") {
+		t.Errorf("Expected the XPath filter hint for an XML response, got %s", view)
+	}
+
+	update := func(msg tea.KeyMsg) {
+		newModel, _ := model.Update(msg)
+		model = newModel.(Model)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if !model.filtering {
+		t.Fatal("Expected / to open the filter bar")
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("//Price/@currency")})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.filtering {
+		t.Error("Expected Enter to close the filter bar")
+	}
+	view := model.View()
+	if !strings.Contains(view, "Body (XPath //Price/@currency):\nUSD") {
+		t.Errorf("Expected the filtered body, got %s", view)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyEscape})
+	if model.screen != screenResponse || model.filter.Value() != "" {
+		t.Error("Expected ESC to clear the filter and stay on the response")
+	}
+
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "text/html"},
+		Body:       `<table class="prices"><tr><td>Apple<td>1.20<tr><td>Banana<td>0.99</table>`,
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("td:last-child")})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if view := model.View(); !strings.Contains(view, "Body (CSS td:last-child):\n1.20\n0.99") {
+		t.Errorf("Expected the selected cells, got %s", view)
+	}
+	update(tea.KeyMsg{Type: tea.KeyEscape})
+
+	model.response = &request.ResponseData{StatusCode: 200, Body: `{"price": 34.5}`}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	if model.filtering {
+		t.Error("Expected no filter bar for a JSON response")
+	}
+}
+
+func TestModel_FormParams(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com/login")
+	model.inputs[inputMethod].textinput.SetValue("POST")
+	model.inputs[inputFormParams].textinput.SetValue("user=jane doe&next=/home?tab=1")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	want := map[string]string{"user": "jane doe", "next": "/home?tab=1"}
+	if !reflect.DeepEqual(model.requestData.FormParams, want) {
+		t.Errorf("FormParams = %v, want %v", model.requestData.FormParams, want)
+	}
+
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "Form Body (urlencoded):\nnext=%2Fhome%3Ftab%3D1&user=jane+doe") {
+		t.Errorf("Expected preview to show the encoded form, got %s", view)
+	}
+}
+
+func TestModel_ResponseEncoding(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode:       200,
+		Body:             "hello",
+		ContentEncoding:  "gzip",
+		CompressedSize:   29,
+		DecompressedSize: 5,
+	}
+	if view := model.View(); !strings.Contains(view, "Encoding: gzip (29 bytes, 5 decompressed)") {
+		t.Errorf("Expected the encoding summary, got %s", view)
+	}
+
+	model.response.DecodeError = `unsupported content encoding "br"`
+	if view := model.View(); !strings.Contains(view, `not decoded: unsupported content encoding "br"`) {
+		t.Errorf("Expected the decode error, got %s", view)
+	}
+}
+
+func TestModel_BinaryResponse(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	model := NewModel()
+	model.screen = screenResponse
+	model.requestData.URL = "https://example.com/images/logo.png?size=2"
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "image/png"},
+		Body:       png,
+		Binary:     true,
+	}
+	view := model.View()
+	if strings.Contains(view, png) || !strings.Contains(view, "Body: binary (image/png, 16 bytes)") ||
+		!strings.Contains(view, "|.PNG........IHDR|") {
+		t.Errorf("Expected a summary and hexdump instead of the raw body, got %s", view)
+	}
+
+	update := func(msg tea.KeyMsg) {
+		newModel, _ := model.Update(msg)
+		model = newModel.(Model)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if !model.saving || model.savePath.Value() != "logo.png" {
+		t.Fatalf("Expected s to prompt for a file named after the URL, got %q", model.savePath.Value())
+	}
+
+	path := filepath.Join(t.TempDir(), "logo.png")
+	model.savePath.SetValue(path)
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.saving {
+		t.Error("Expected Enter to close the save prompt")
+	}
+	if view := model.View(); !strings.Contains(view, "Saved 16 bytes to "+path) {
+		t.Errorf("Expected the save to be reported, got %s", view)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != png {
+		t.Errorf("Expected the saved file to hold the body bytes, got %q", saved)
+	}
+
+	// Saving again keeps the first file
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	renamed := filepath.Join(filepath.Dir(path), "logo (1).png")
+	if view := model.View(); !strings.Contains(view, "Saved 16 bytes to "+renamed) {
+		t.Errorf("Expected the second save to be numbered, got %s", view)
+	}
+}
+
+func TestModel_DecodeHeaders(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.response = &request.ResponseData{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"X-User-Info":  "eyJpZCI6NDIsInJvbGUiOiJhZG1pbiJ9",
+		},
+	}
+	view := model.View()
+	if !strings.Contains(view, "X-User-Info: eyJpZCI6NDIsInJvbGUiOiJhZG1pbiJ9 [base64]") || !strings.Contains(view, "d to decode headers") {
+		t.Errorf("Expected the base64 header to be marked as decodable, got %s", view)
+	}
+	if strings.Contains(view, `"role": "admin"`) {
+		t.Errorf("Expected decoded values to be hidden until d is pressed, got %s", view)
+	}
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	model = updated.(Model)
+	view = model.View()
+	if !strings.Contains(view, "  base64:\n    {\n      \"id\": 42,\n      \"role\": \"admin\"\n    }") {
+		t.Errorf("Expected the decoded JSON under the header, got %s", view)
+	}
+	if !strings.Contains(view, "Content-Type: application/json\n") || !strings.Contains(view, "d to hide decoded headers") {
+		t.Errorf("Expected plain headers to be shown as they are, got %s", view)
+	}
+}
+
+func TestModel_PreviewInternationalizedURL(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://bücher.de/straße")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	model.screen = screenPreview
+	view := model.View()
+	if !strings.Contains(view, "GET https://bücher.de/straße\n") {
+		t.Errorf("Expected the display form of the URL, got %s", view)
+	}
+	if !strings.Contains(view, "Wire URL: https://xn--bcher-kva.de/stra%C3%9Fe") {
+		t.Errorf("Expected the encoded wire form of the URL, got %s", view)
+	}
+
+	model.inputs[inputURL].textinput.SetValue("https://example.com/users")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if view := model.View(); strings.Contains(view, "Wire URL:") {
+		t.Errorf("Expected no wire form for an ASCII URL, got %s", view)
+	}
+}
+
+func TestModel_NormalizesURL(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue(" api.example.com/search?q=red shoes")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if want := "https://api.example.com/search?q=red%20shoes"; model.requestData.URL != want {
+		t.Errorf("URL = %q, want %q", model.requestData.URL, want)
+	}
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "Warning: URL contains whitespace") {
+		t.Errorf("Expected the preview to warn about the spaces, got %s", view)
+	}
+
+	model = model.WithConfig(&config.Config{DefaultScheme: "http"})
+	model.inputs[inputURL].textinput.SetValue("localhost:8080")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if model.requestData.URL != "http://localhost:8080" {
+		t.Errorf("Expected the configured default scheme, got %q", model.requestData.URL)
+	}
+}
+
+func TestModel_HTTPVersion(t *testing.T) {
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputHTTPVersion].textinput.SetValue("1.1")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	if model.requestData.ProtocolVersion != request.ProtocolHTTP1 {
+		t.Errorf("ProtocolVersion = %q, want HTTP/1.1", model.requestData.ProtocolVersion)
+	}
+	model.screen = screenPreview
+	if view := model.View(); !strings.Contains(view, "HTTP Version: HTTP/1.1") {
+		t.Errorf("Expected the preview to show the HTTP version, got %s", view)
+	}
+
+	model.screen = screenResponse
+	model.response = &request.ResponseData{StatusCode: 200, Protocol: "HTTP/1.1"}
+	if view := model.View(); !strings.Contains(view, "Protocol: HTTP/1.1") {
+		t.Errorf("Expected the response to show the protocol, got %s", view)
+	}
+
+	model.inputs[inputHTTPVersion].textinput.SetValue("spdy")
+	if err := model.buildRequestData(); err == nil {
+		t.Error("Expected an unsupported HTTP version to be rejected")
+	}
+}
+
+func TestModel_Proxy(t *testing.T) {
+	model := NewModel().WithConfig(&config.Config{
+		ProxyAuth: map[string]request.ProxyCredentials{"proxy.corp:3128": {Username: "jane", Password: "secret"}},
+	})
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com")
+	model.inputs[inputProxy].textinput.SetValue("http://proxy.corp:3128")
+	model.inputs[inputProxyBypass].textinput.SetValue("localhost, .internal.corp")
+	if err := model.buildRequestData(); err != nil {
+		t.Fatalf("buildRequestData() error = %v", err)
+	}
+	want := &request.ProxySettings{
+		URL:      "http://proxy.corp:3128",
+		Username: "jane",
+		Password: "secret",
+		Bypass:   []string{"localhost", ".internal.corp"},
+	}
+	if !reflect.DeepEqual(model.requestData.Proxy, want) {
+		t.Errorf("Proxy = %+v, want %+v", model.requestData.Proxy, want)
+	}
+
+	model.screen = screenPreview
+	view := model.View()
+	for _, line := range []string{"Proxy: http://proxy.corp:3128", "Proxy User: jane", "Bypassed For: localhost, .internal.corp"} {
+		if !strings.Contains(view, line) {
+			t.Errorf("Expected the preview to show %q, got %s", line, view)
+		}
+	}
+	if strings.Contains(view, "secret") {
+		t.Error("Expected the proxy password to stay out of the preview")
+	}
+}
+
+func TestModel_ResponseCharset(t *testing.T) {
+	model := NewModel()
+	model.screen = screenResponse
+	model.response = &request.ResponseData{StatusCode: 200, Body: "café"}
+	if view := model.View(); strings.Contains(view, "Charset:") {
+		t.Errorf("Expected no charset line for a UTF-8 body, got %s", view)
+	}
+
+	model.response.Charset = "iso-8859-1"
+	model.response.CharsetSource = request.CharsetFromHeader
+	if view := model.View(); !strings.Contains(view, "Charset: iso-8859-1 from Content-Type, converted to UTF-8") {
+		t.Errorf("Expected the charset summary, got %s", view)
+	}
+}
+
+func TestModel_Recording(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	recorder, err := request.OpenHARRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewModel().WithRecorder(recorder)
+	if view := model.View(); !strings.Contains(view, "Recording to "+path+" (0 entries)") {
+		t.Errorf("Expected the request screen to show the recording, got %s", view)
+	}
+
+	model.requestData = request.NewRequestData()
+	model.requestData.URL = server.URL
+	if _, ok := model.executeRequest().(*request.ResponseData); !ok {
+		t.Fatal("Expected response data for valid request")
+	}
+	if view := model.View(); !strings.Contains(view, "(1 entries)") {
+		t.Errorf("Expected the request to be recorded, got %s", view)
+	}
+}
+
+func TestModel_NotifiesSlowRequests(t *testing.T) {
+	var notified []string
+	rang := 0
+	sendNotification = func(title, message string) error {
+		notified = append(notified, title+": "+message)
+		return nil
+	}
+	ringBell = func() { rang++ }
+	defer func() {
+		sendNotification = notify.Send
+		ringBell = func() { notify.Bell(os.Stdout) }
+	}()
+
+	model := NewModel().WithConfig(&config.Config{NotifyAfter: "5s"})
+	model.requestData.URL = "https://example.com/export"
+	model.screen = screenResponse
+	respond := func() tea.Cmd {
+		newModel, cmd := model.Update(&request.ResponseData{StatusCode: 200})
+		model = newModel.(Model)
+		return cmd
+	}
+
+	// A slow request while the terminal has focus is not notified
+	model.sentAt = time.Now().Add(-10 * time.Second)
+	if cmd := respond(); cmd != nil {
+		t.Error("Expected no notification while the terminal has focus")
+	}
+
+	newModel, _ := model.Update(tea.BlurMsg{})
+	model = newModel.(Model)
+	model.sentAt = time.Now().Add(-time.Second)
+	if cmd := respond(); cmd != nil {
+		t.Error("Expected no notification for a fast request")
+	}
+
+	model.sentAt = time.Now().Add(-10 * time.Second)
+	cmd := respond()
+	if cmd == nil {
+		t.Fatal("Expected a notification for a slow request while unfocused")
+	}
+	cmd()
+	if len(notified) != 1 || !strings.HasPrefix(notified[0], "Lighttr: 200 OK: GET https://example.com/export (10") {
+		t.Errorf("Unexpected notification %v", notified)
+	}
+
+	// The bell rings when no notification can be shown
+	sendNotification = func(string, string) error { return fmt.Errorf("notify-send not found") }
+	newModel, cmd = model.Update(fmt.Errorf("request error: timeout"))
+	model = newModel.(Model)
+	if cmd == nil {
+		t.Fatal("Expected failed requests to be notified too")
+	}
+	cmd()
+	if rang != 1 {
+		t.Errorf("Expected the bell as a fallback, rang %d times", rang)
+	}
+}
+
+func TestModel_ErrorSuggestions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := server.URL
+	server.Close()
+
+	model := NewModel()
+	model.requestData.URL = closedURL
+	model.screen = screenPreview
+	update := func(msg tea.Msg) tea.Cmd {
+		newModel, cmd := model.Update(msg)
+		model = newModel.(Model)
+		return cmd
+	}
+
+	cmd := update(tea.KeyMsg{Type: tea.KeyEnter})
+	update(cmd())
+	view := model.View()
+	for _, want := range []string{
+		"Connection refused: nothing is listening on the port",
+		"• Check that the server is running and the port is right",
+		"r to retry",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the error screen to show %q, got %s", want, view)
+		}
+	}
+
+	// r sends the request again
+	cmd = update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if model.err != nil || cmd == nil {
+		t.Fatal("Expected r to clear the error and resend the request")
+	}
+	if _, ok := cmd().(requestError); !ok {
+		t.Error("Expected the resent request to fail again")
+	}
+}
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+var (
+	// reducedMotion keeps the cursor from blinking and the Retry-After
+	// countdown from ticking
+	reducedMotion bool
+
+	// scrollback prints every response and error above the TUI, where it
+	// stays in the terminal's scrollback, tmux logs, and recordings
+	scrollback bool
+)
+
+// SetReducedMotion turns off the blinking cursor and the ticking countdown
+// of the TUI; it applies to the inputs created afterwards
+func SetReducedMotion(on bool) {
+	reducedMotion = on
+}
+
+// SetScrollback makes the TUI print every response and error to the
+// terminal's scrollback as it arrives
+func SetScrollback(on bool) {
+	scrollback = on
+}
+
+// newInput returns a text input, with a steady cursor under reduced motion
+func newInput() textinput.Model {
+	input := textinput.New()
+	if reducedMotion {
+		input.Cursor.SetMode(cursor.CursorStatic)
+	}
+	return input
+}
+
+// blink starts the cursor blinking, unless motion is reduced
+func blink() tea.Cmd {
+	if reducedMotion {
+		return nil
+	}
+	return textinput.Blink
+}
+
+// retryTickMsg advances the Retry-After countdown by elapsed
+type retryTickMsg struct {
+	elapsed time.Duration
+}
+
+// retryTick returns the next step of the Retry-After countdown: a second,
+// or all of it at once under reduced motion
+func (m Model) retryTick() tea.Cmd {
+	step := time.Second
+	if reducedMotion {
+		step = m.retryIn
+	}
+	return tea.Tick(step, func(time.Time) tea.Msg { return retryTickMsg{elapsed: step} })
+}
+
+// printTranscript returns a command that prints the request and its
+// response or error to the scrollback, or nil unless scrollback is on
+func (m Model) printTranscript() tea.Cmd {
+	if !scrollback {
+		return nil
+	}
+	return tea.Println(transcript(m.requestData, m.response, m.err))
+}
+
+// transcript renders an exchange as plain text, without the styles and key
+// hints of the response screen
+func transcript(data *request.RequestData, resp *request.ResponseData, err error) string {
+	var b strings.Builder
+	if data != nil {
+		b.WriteString(fmt.Sprintf("> %s %s\n", data.Method, data.URL))
+	}
+	if err != nil {
+		b.WriteString(fmt.Sprintf("Error: %v\n", err))
+		return b.String()
+	}
+	if resp == nil {
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("< %d %s (%v)\n", resp.StatusCode, http.StatusText(resp.StatusCode), resp.ResponseTime))
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.WriteString(fmt.Sprintf("< %s: %s\n", name, resp.Headers[name]))
+	}
+	if resp.Binary {
+		b.WriteString(fmt.Sprintf("\nbinary body (%s)\n", resp.BinarySummary()))
+	} else if resp.Body != "" {
+		b.WriteString("\n" + strings.TrimSuffix(resp.Body, "\n") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/azure"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// oauthPageRows is how many legs the OAuth screen lists at a time
+const oauthPageRows = 10
+
+// openOAuth switches to the OAuth screen with the legs of the OAuth2 flows
+// of the request's client, or of every client when the request has no
+// oauth2 or azure auth, selecting the last leg that failed
+func (m *Model) openOAuth() {
+	if m.screen == screenRequest {
+		// The form may have changed since the request was last built
+		m.buildRequestData()
+	}
+	req, err := m.resolvedRequest()
+	if err != nil {
+		req = m.requestData
+	}
+
+	clientID := ""
+	m.oauthClient = "all clients"
+	switch req.Auth.Type {
+	case request.OAuth2Auth:
+		if profile, err := oauth.LoadProfile(req.Auth.OAuth2Profile); err == nil {
+			clientID = profile.ClientID
+			m.oauthClient = fmt.Sprintf("OAuth2 profile %s (client %s)", profile.Name, clientID)
+		}
+	case request.AzureAuth:
+		if app := azure.AppFor(req.Auth); app.ClientID != "" {
+			clientID = app.ClientID
+			m.oauthClient = "Entra ID client " + clientID
+		}
+	}
+
+	m.oauthLegs, err = oauth.Legs(clientID)
+	m.oauthStatus = ""
+	if err != nil {
+		m.oauthStatus = err.Error()
+	}
+	m.oauthCursor = max(len(m.oauthLegs)-1, 0)
+	for i := len(m.oauthLegs) - 1; i >= 0; i-- {
+		if m.oauthLegs[i].Error != "" {
+			m.oauthCursor = i
+			break
+		}
+	}
+	if m.screen != screenOAuth {
+		m.oauthReturn = m.screen
+	}
+	m.screen = screenOAuth
+}
+
+// updateOAuth handles the keys of the OAuth screen: ↑/↓ to select a leg, r
+// to reload the legs, and Esc to go back
+func (m Model) updateOAuth(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc", "alt+o":
+		m.screen = m.oauthReturn
+	case "up", "shift+tab":
+		m.oauthCursor = max(m.oauthCursor-1, 0)
+	case "down", "tab":
+		m.oauthCursor = min(m.oauthCursor+1, max(len(m.oauthLegs)-1, 0))
+	case "r":
+		m.openOAuth()
+	}
+	return m, nil
+}
+
+func (m Model) renderOAuthScreen() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("OAuth2 Flows"))
+	b.WriteString("\n\n")
+	b.WriteString(blurredStyle.Render("Legs of "+m.oauthClient+", with secrets masked") + "\n\n")
+
+	if m.oauthStatus != "" {
+		b.WriteString(warningStyle.Render(m.oauthStatus) + "\n")
+	}
+	if len(m.oauthLegs) == 0 {
+		b.WriteString(blurredStyle.Render("No OAuth2 requests recorded yet: they are recorded as lighttr logs in, refreshes tokens, and obtains client credentials") + "\n")
+		b.WriteString("\nESC to go back • Ctrl+C to quit\n")
+		return b.String()
+	}
+
+	start := min(max(m.oauthCursor-oauthPageRows/2, 0), max(len(m.oauthLegs)-oauthPageRows, 0))
+	end := min(start+oauthPageRows, len(m.oauthLegs))
+	if start > 0 {
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("  ↑ %d more", start)) + "\n")
+	}
+	for i := start; i < end; i++ {
+		leg := m.oauthLegs[i]
+		line := leg.Summary()
+		switch {
+		case i == m.oauthCursor:
+			b.WriteString(focusedStyle.Render("> "+line) + "\n")
+		case leg.Error != "":
+			b.WriteString("  " + failStyle.Render(line) + "\n")
+		default:
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if end < len(m.oauthLegs) {
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("  ↓ %d more", len(m.oauthLegs)-end)) + "\n")
+	}
+
+	b.WriteString("\n" + renderLeg(m.oauthLegs[m.oauthCursor]))
+	b.WriteString("\n↑/↓ to select a leg • r to reload • ESC to go back • Ctrl+C to quit\n")
+	return b.String()
+}
+
+// renderLeg renders the request and response of a leg in full
+func renderLeg(leg oauth.Leg) string {
+	var b strings.Builder
+	title := leg.Step
+	if title == "" {
+		title = "request"
+	}
+	if leg.Repeats > 0 {
+		title += fmt.Sprintf(", %d times", leg.Repeats+1)
+	}
+	b.WriteString(focusedStyle.Render(strings.ToUpper(title[:1])+title[1:]) + blurredStyle.Render(" at "+leg.Time.Local().Format(time.DateTime)) + "\n")
+	b.WriteString(strings.TrimSpace(leg.Method+" "+leg.URL) + "\n")
+	writeLegHeaders(&b, leg.RequestHeaders)
+	if leg.RequestBody != "" {
+		b.WriteString("\n" + leg.RequestBody + "\n")
+	}
+	if leg.Status != 0 {
+		b.WriteString(fmt.Sprintf("\nStatus: %d (%v)\n", leg.Status, leg.Duration.Round(time.Millisecond)))
+		writeLegHeaders(&b, leg.ResponseHeaders)
+		if leg.ResponseBody != "" {
+			b.WriteString("\n" + leg.ResponseBody + "\n")
+		}
+	}
+	if leg.Error != "" {
+		b.WriteString("\n" + failStyle.Render("Error: "+leg.Error) + "\n")
+	}
+	return b.String()
+}
+
+// writeLegHeaders writes headers sorted by name
+func writeLegHeaders(b *strings.Builder, headers map[string]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(blurredStyle.Render(name+": ") + headers[name] + "\n")
+	}
+}
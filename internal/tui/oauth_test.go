@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/oauth"
+)
+
+func TestModel_OAuthLegs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := oauth.SaveProfile(&oauth.Profile{Name: "github", TokenURL: "https://example.com/token", ClientID: "abc"}); err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	legs := []oauth.Leg{
+		{Time: at, Step: "authorize", ClientID: "abc", Method: "GET", URL: "https://example.com/authorize?scope=admin"},
+		{Time: at, Step: "token exchange", ClientID: "abc", Method: "POST", URL: "https://example.com/token", RequestBody: "client_id=abc&code=********", Status: 400, ResponseBody: `{"error": "invalid_scope"}`, Error: "invalid_scope"},
+		{Time: at, Step: "refresh", ClientID: "other", Method: "POST", URL: "https://example.com/token", Status: 200},
+	}
+	path, err := oauth.LegsPath()
+	if err != nil {
+		t.Fatalf("LegsPath() error = %v", err)
+	}
+	data, _ := json.Marshal(legs)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModel()
+	update := func(msg tea.Msg) {
+		newModel, _ := model.Update(msg)
+		model = newModel.(Model)
+	}
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com/repos")
+	model.inputs[inputAuthType].textinput.SetValue("oauth2")
+	model.inputs[inputOAuth2Profile].textinput.SetValue("github")
+
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}, Alt: true})
+	if model.screen != screenOAuth {
+		t.Fatalf("Expected the OAuth screen, got %v", model.screen)
+	}
+	if len(model.oauthLegs) != 2 || model.oauthCursor != 1 {
+		t.Fatalf("Expected the legs of client abc with the failed one selected, got %+v at %d", model.oauthLegs, model.oauthCursor)
+	}
+	view := model.View()
+	for _, want := range []string{"OAuth2 profile github (client abc)", "authorize  GET example.com/authorize", "token exchange  POST example.com/token -> 400  invalid_scope", "client_id=abc&code=********", "Error: invalid_scope"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the view to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyUp})
+	if view := model.View(); !strings.Contains(view, "https://example.com/authorize?scope=admin") {
+		t.Errorf("Expected the authorize leg in full, got:\n%s", view)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.screen != screenRequest || model.inputs[inputOAuth2Profile].textinput.Value() != "github" {
+		t.Errorf("Expected Esc to return to the form, got %v", model.screen)
+	}
+}
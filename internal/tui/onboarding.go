@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/config"
+)
+
+// Setup holds the choices made in the onboarding wizard
+type Setup struct {
+	Theme       string
+	Keybindings string
+	DataDir     string
+
+	// Import names a Postman or Insomnia export or a .http or .hurl file
+	// to import as a collection, or "curl" for the curl commands in the
+	// shell history; empty skips the import
+	Import string
+
+	// EnvName and BaseURL describe a first environment with a base_url
+	// variable; an empty BaseURL skips it
+	EnvName string
+	BaseURL string
+}
+
+// Steps of the onboarding wizard
+const (
+	stepTheme = iota
+	stepKeybindings
+	stepDataDir
+	stepImport
+	stepEnvironment
+	stepCount
+)
+
+// Indexes of the onboarding inputs
+const (
+	onboardDataDir = iota
+	onboardImport
+	onboardEnvName
+	onboardBaseURL
+)
+
+// Onboarding is the wizard shown on first launch to pick a theme, key
+// bindings, and data directory, import existing requests, and create a
+// first environment
+type Onboarding struct {
+	step        int
+	theme       int
+	keybindings int
+	inputs      []inputField
+	activeInput int
+
+	// done is set when the wizard is finished and skipped when it is left
+	// without saving
+	done    bool
+	skipped bool
+}
+
+// NewOnboarding returns the wizard with the current configuration selected
+func NewOnboarding(cfg *config.Config) Onboarding {
+	inputs := []inputField{
+		{label: "Data directory", textinput: newInput()},
+		{label: "Import from", textinput: newInput()},
+		{label: "Environment name", textinput: newInput()},
+		{label: "Base URL", textinput: newInput()},
+	}
+	inputs[onboardDataDir].textinput.SetValue("~/.lighttr")
+	if cfg.DataDir != "" {
+		inputs[onboardDataDir].textinput.SetValue(cfg.DataDir)
+	}
+	inputs[onboardImport].textinput.Placeholder = "collection.postman_collection.json, insomnia.json, requests.http, or curl"
+	inputs[onboardEnvName].textinput.SetValue("dev")
+	inputs[onboardBaseURL].textinput.Placeholder = "https://api.example.com"
+
+	o := Onboarding{
+		theme:       max(slices.Index(config.Themes, cfg.Theme), 0),
+		keybindings: max(slices.Index(config.KeybindingPresets, cfg.Keybindings), 0),
+		inputs:      inputs,
+	}
+	SetTheme(config.Themes[o.theme])
+	return o
+}
+
+// Setup returns the choices made, and false if the wizard was skipped or
+// has not finished
+func (o Onboarding) Setup() (Setup, bool) {
+	value := func(i int) string {
+		return strings.TrimSpace(o.inputs[i].textinput.Value())
+	}
+	return Setup{
+		Theme:       config.Themes[o.theme],
+		Keybindings: config.KeybindingPresets[o.keybindings],
+		DataDir:     value(onboardDataDir),
+		Import:      value(onboardImport),
+		EnvName:     value(onboardEnvName),
+		BaseURL:     value(onboardBaseURL),
+	}, o.done && !o.skipped
+}
+
+func (o Onboarding) Init() tea.Cmd {
+	return blink()
+}
+
+func (o Onboarding) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return o, nil
+	}
+
+	switch key.String() {
+	case "ctrl+c":
+		o.skipped = true
+		return o, tea.Quit
+
+	case "esc":
+		if o.step == stepTheme {
+			o.skipped = true
+			return o, tea.Quit
+		}
+		o.step--
+		o.focusStep()
+		return o, nil
+
+	case "up", "down", "tab", "shift+tab":
+		switch o.step {
+		case stepTheme:
+			o.theme = nextIndex(o.theme, len(config.Themes), key.String())
+			SetTheme(config.Themes[o.theme])
+			return o, nil
+		case stepKeybindings:
+			o.keybindings = nextIndex(o.keybindings, len(config.KeybindingPresets), key.String())
+			return o, nil
+		case stepEnvironment:
+			if o.activeInput == onboardEnvName {
+				o.activeInput = onboardBaseURL
+			} else {
+				o.activeInput = onboardEnvName
+			}
+			focusInput(o.inputs, o.activeInput)
+			return o, nil
+		}
+
+	case "enter":
+		if o.step == stepEnvironment && o.activeInput == onboardEnvName {
+			o.activeInput = onboardBaseURL
+			focusInput(o.inputs, o.activeInput)
+			return o, nil
+		}
+		o.step++
+		if o.step == stepCount {
+			o.done = true
+			return o, tea.Quit
+		}
+		o.focusStep()
+		return o, nil
+	}
+
+	if o.step < stepDataDir {
+		return o, nil
+	}
+	var cmd tea.Cmd
+	o.inputs[o.activeInput].textinput, cmd = o.inputs[o.activeInput].textinput.Update(msg)
+	return o, cmd
+}
+
+// focusStep focuses the first input of the current step
+func (o *Onboarding) focusStep() {
+	switch o.step {
+	case stepDataDir:
+		o.activeInput = onboardDataDir
+	case stepImport:
+		o.activeInput = onboardImport
+	case stepEnvironment:
+		o.activeInput = onboardEnvName
+	default:
+		focusInput(o.inputs, -1)
+		return
+	}
+	focusInput(o.inputs, o.activeInput)
+}
+
+func (o Onboarding) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Welcome to Lighttr"))
+	b.WriteString("\n\n")
+	b.WriteString(blurredStyle.Render(fmt.Sprintf("Step %d of %d", o.step+1, stepCount)) + "\n\n")
+
+	switch o.step {
+	case stepTheme:
+		b.WriteString("Choose a color theme:\n\n")
+		b.WriteString(renderChoices(config.Themes, o.theme))
+	case stepKeybindings:
+		b.WriteString("Choose key bindings:\n\n")
+		b.WriteString(renderChoices(config.KeybindingPresets, o.keybindings))
+		b.WriteString("\n" + blurredStyle.Render("emacs adds Ctrl+N/Ctrl+P to move between fields and Ctrl+G to go back") + "\n")
+	case stepDataDir:
+		b.WriteString("Where should collections, environments, and history be kept?\n\n")
+		b.WriteString(o.renderInput(onboardDataDir))
+	case stepImport:
+		b.WriteString("Import existing requests as a collection (leave blank to skip):\n\n")
+		b.WriteString(o.renderInput(onboardImport))
+		b.WriteString("\n" + blurredStyle.Render("A Postman collection, Insomnia export, .http or .hurl file, or curl for the curl commands in your shell history") + "\n")
+	case stepEnvironment:
+		b.WriteString("Create a first environment, used as {{base_url}} in requests (leave the URL blank to skip):\n\n")
+		b.WriteString(o.renderInput(onboardEnvName))
+		b.WriteString(o.renderInput(onboardBaseURL))
+	}
+
+	help := "\nEnter to continue • ESC to go back • Ctrl+C to skip setup\n"
+	switch o.step {
+	case stepTheme:
+		help = "\n↑/↓ to choose • Enter to continue • ESC to skip setup\n"
+	case stepKeybindings:
+		help = "\n↑/↓ to choose • Enter to continue • ESC to go back • Ctrl+C to skip setup\n"
+	case stepEnvironment:
+		help = "\nTab to switch fields • Enter to finish • ESC to go back • Ctrl+C to skip setup\n"
+	}
+	b.WriteString(help)
+	return b.String()
+}
+
+// renderInput renders a labelled input
+func (o Onboarding) renderInput(index int) string {
+	label := o.inputs[index].label
+	if index == o.activeInput {
+		label = focusedStyle.Render(label)
+	}
+	return label + "\n" + o.inputs[index].textinput.View() + "\n\n"
+}
+
+// renderChoices renders a list with the selected choice marked
+func renderChoices(choices []string, selected int) string {
+	var b strings.Builder
+	for i, choice := range choices {
+		if i == selected {
+			b.WriteString(focusedStyle.Render("> "+choice) + "\n")
+		} else {
+			b.WriteString("  " + choice + "\n")
+		}
+	}
+	return b.String()
+}
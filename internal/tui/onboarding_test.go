@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/config"
+)
+
+func TestOnboarding(t *testing.T) {
+	defer SetTheme("default")
+
+	o := NewOnboarding(&config.Config{})
+	update := func(msg tea.Msg) tea.Cmd {
+		newModel, cmd := o.Update(msg)
+		o = newModel.(Onboarding)
+		return cmd
+	}
+	typeText := func(s string) {
+		update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+	}
+
+	if view := o.View(); !strings.Contains(view, "Welcome to Lighttr") || !strings.Contains(view, "> default") {
+		t.Errorf("Expected the theme step, got %s", view)
+	}
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	update(tea.KeyMsg{Type: tea.KeyDown})
+
+	// ESC goes back a step and keeps the choices made
+	update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !strings.Contains(o.View(), "> light") {
+		t.Errorf("Expected to be back on the theme step, got %s", o.View())
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// The data directory defaults to ~/.lighttr
+	if !strings.Contains(o.View(), "~/.lighttr") {
+		t.Errorf("Expected the default data directory, got %s", o.View())
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	typeText("curl")
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Enter moves from the environment name to the base URL, then finishes
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	typeText("http://localhost:8080")
+	if _, ok := o.Setup(); ok {
+		t.Error("Expected no setup before the wizard finishes")
+	}
+	if cmd := update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("Expected the wizard to quit when finished")
+	}
+
+	setup, ok := o.Setup()
+	if !ok {
+		t.Fatal("Expected the wizard to be finished")
+	}
+	want := Setup{Theme: "light", Keybindings: "emacs", DataDir: "~/.lighttr", Import: "curl", EnvName: "dev", BaseURL: "http://localhost:8080"}
+	if setup != want {
+		t.Errorf("Setup() = %+v, want %+v", setup, want)
+	}
+}
+
+func TestOnboarding_Skip(t *testing.T) {
+	o := NewOnboarding(&config.Config{Theme: "high-contrast"})
+	defer SetTheme("default")
+	if !strings.Contains(o.View(), "> high-contrast") {
+		t.Errorf("Expected the configured theme to be selected, got %s", o.View())
+	}
+	newModel, cmd := o.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd == nil {
+		t.Error("Expected ESC on the first step to quit")
+	}
+	if _, ok := newModel.(Onboarding).Setup(); ok {
+		t.Error("Expected a skipped wizard to report no setup")
+	}
+}
+
+func TestModel_EmacsKeybindings(t *testing.T) {
+	model := NewModel().WithConfig(&config.Config{Keybindings: "emacs"})
+	newModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	model = newModel.(Model)
+	if model.activeInput != 1 {
+		t.Errorf("Expected Ctrl+N to move to the next field, got %d", model.activeInput)
+	}
+
+	model.screen = screenPreview
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	if newModel.(Model).screen != screenRequest {
+		t.Error("Expected Ctrl+G to go back")
+	}
+
+	// Without the preset the keys are left alone
+	model = NewModel()
+	newModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	if newModel.(Model).activeInput != 0 {
+		t.Error("Expected Ctrl+N to do nothing without the emacs preset")
+	}
+}
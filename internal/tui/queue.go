@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/queue"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// queueFlushedMsg reports the outcome of flushing the offline queue
+type queueFlushedMsg struct {
+	results []queue.Result
+	err     error
+}
+
+// flushQueue sends the queued requests
+func (m Model) flushQueue() tea.Msg {
+	results, err := m.queue.Flush(request.WithCookieJar(m.cookies), request.WithHARRecorder(m.recorder))
+	if err == nil {
+		err = m.cookies.Save()
+	}
+	return queueFlushedMsg{results: results, err: err}
+}
+
+// enqueue queues the request instead of sending it, and shows the queue
+func (m *Model) enqueue() {
+	req, err := m.resolvedRequest()
+	if err == nil {
+		if err = req.Validate(); err != nil {
+			err = fmt.Errorf("invalid request: %v", err)
+		}
+	}
+	if err != nil {
+		m.err = err
+		m.logf(logError, "%v", m.err)
+		m.screen = screenRequest
+		return
+	}
+	item, err := m.queue.Add(req)
+	if err != nil {
+		m.queueStatus = fmt.Sprintf("Failed to queue the request: %v", err)
+		m.logf(logError, "%s", m.queueStatus)
+	} else {
+		m.queueStatus = fmt.Sprintf("Queued #%d: offline", item.ID)
+		m.logf(logInfo, "queued #%d %s %s while offline", item.ID, item.Request.Method, request.DisplayURL(item.Request.ResolvedURL()))
+	}
+	m.queueCursor = max(m.queue.Len()-1, 0)
+	m.screen = screenQueue
+}
+
+// applyFlush logs the outcome of a flush and summarizes it
+func (m *Model) applyFlush(msg queueFlushedMsg) {
+	m.flushing = false
+	sent, failed := 0, 0
+	for _, result := range msg.results {
+		label := fmt.Sprintf("#%d %s %s", result.Item.ID, result.Item.Request.Method, result.Item.Request.URL)
+		if result.Response != nil {
+			sent++
+			m.logf(logReceived, "%s: %d in %v", label, result.Response.StatusCode, result.Response.ResponseTime.Round(time.Millisecond))
+			continue
+		}
+		failed++
+		m.logf(logError, "%s: %s", label, result.Error)
+	}
+	m.queueStatus = fmt.Sprintf("Flushed: %d sent, %d failed, %d still queued", sent, failed, m.queue.Len())
+	if msg.err != nil {
+		m.queueStatus += fmt.Sprintf(" (%v)", msg.err)
+		m.logf(logError, "%v", msg.err)
+	}
+	m.queueCursor = min(m.queueCursor, max(m.queue.Len()-1, 0))
+}
+
+// renderQueueScreen lists the queued requests
+func (m Model) renderQueueScreen() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Offline Queue"))
+	b.WriteString("\n\n")
+	if m.offline {
+		b.WriteString(warningStyle.Render("● Offline: sends are queued • Ctrl+O to go online") + "\n\n")
+	}
+
+	items := m.queue.Items()
+	if len(items) == 0 {
+		b.WriteString(blurredStyle.Render("No queued requests") + "\n")
+	}
+	for i, item := range items {
+		line := fmt.Sprintf("#%d %s %s", item.ID, item.Request.Method, request.DisplayURL(item.Request.ResolvedURL()))
+		if i == m.queueCursor {
+			line = focusedStyle.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line + blurredStyle.Render("  queued "+item.QueuedAt.Format("15:04:05")) + "\n")
+		if item.LastError != "" {
+			b.WriteString(warningStyle.Render(fmt.Sprintf("    failed %d times: %s", item.Attempts, item.LastError)) + "\n")
+		}
+	}
+
+	if m.queueStatus != "" {
+		b.WriteString("\n" + warningStyle.Render(m.queueStatus) + "\n")
+	}
+	if m.flushing {
+		b.WriteString("\nSending...\n")
+		return b.String()
+	}
+	b.WriteString("\nf to send all • d to delete • ↑/↓ to select • ESC to go back • Ctrl+C to quit\n")
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestModel_OfflineQueue(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	model := NewModel()
+	update := func(msg tea.Msg) tea.Cmd {
+		newModel, cmd := model.Update(msg)
+		model = newModel.(Model)
+		return cmd
+	}
+
+	// Sends are queued while offline
+	update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !strings.Contains(model.View(), "Offline: sends are queued") {
+		t.Errorf("Expected an offline banner, got %s", model.View())
+	}
+	for _, path := range []string{"/a", "/b"} {
+		model.requestData.URL = server.URL + path
+		model.screen = screenPreview
+		if cmd := update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+			t.Fatal("Expected the request to be queued, not sent")
+		}
+	}
+	if hits.Load() != 0 || model.screen != screenQueue || model.queue.Len() != 2 {
+		t.Fatalf("Expected two queued requests on the queue screen, got %d queued and %d sent", model.queue.Len(), hits.Load())
+	}
+	view := model.View()
+	for _, want := range []string{"Offline Queue", "#1 GET " + server.URL + "/a", "#2 GET " + server.URL + "/b", "Queued #2"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the queue screen to show %q, got %s", want, view)
+		}
+	}
+
+	// d drops the selected request
+	update(tea.KeyMsg{Type: tea.KeyUp})
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if items := model.queue.Items(); len(items) != 1 || items[0].ID != 2 {
+		t.Fatalf("Expected #1 to be deleted, got %+v", items)
+	}
+
+	// f sends what is left
+	update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	cmd := update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if cmd == nil || !model.flushing {
+		t.Fatal("Expected f to flush the queue")
+	}
+	update(cmd())
+	if hits.Load() != 1 || model.queue.Len() != 0 {
+		t.Errorf("Expected the queued request to be sent, got %d sent and %d still queued", hits.Load(), model.queue.Len())
+	}
+	if !strings.Contains(model.View(), "Flushed: 1 sent, 0 failed, 0 still queued") {
+		t.Errorf("Expected a flush summary, got %s", model.View())
+	}
+	if !strings.Contains(model.renderConsole(), "201") {
+		t.Errorf("Expected the flushed response in the console, got %+v", model.console)
+	}
+
+	// Back online, Enter sends again
+	update(tea.KeyMsg{Type: tea.KeyEsc})
+	model.screen = screenPreview
+	if cmd := update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || model.screen != screenResponse {
+		t.Error("Expected Enter to send the request once online")
+	}
+}
@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/internal/lint"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// suggestionRequests is how many recent requests to the URL's host the
+// suggested headers and auth are drawn from
+const suggestionRequests = 5
+
+// refreshSuggestion looks up what recent requests to the URL's host had in
+// common when the host changes
+func (m *Model) refreshSuggestion() {
+	if m.history == nil {
+		return
+	}
+	host := history.Host(m.inputs[inputURL].textinput.Value())
+	if host == m.suggestedHost {
+		return
+	}
+	m.suggestedHost = host
+	m.suggestion = m.history.Suggest(host, suggestionRequests)
+}
+
+// pendingSuggestion returns the suggested headers the form does not set,
+// enabled or not, and the suggested auth if the form has none
+func (m Model) pendingSuggestion() (map[string]string, *request.AuthData) {
+	if m.suggestion == nil || m.suggestion.Host != history.Host(m.inputs[inputURL].textinput.Value()) {
+		return nil, nil
+	}
+	set := make(map[string]bool)
+	for _, entry := range splitHeaderEntries(m.inputs[inputHeaders].textinput.Value()) {
+		if name, _, _, ok := parseHeaderEntry(entry); ok {
+			set[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+	headers := make(map[string]string)
+	for name, value := range m.suggestion.Headers {
+		if !set[name] {
+			headers[name] = value
+		}
+	}
+	var auth *request.AuthData
+	if authType := request.AuthType(m.inputs[inputAuthType].textinput.Value()); authType == "" || authType == request.NoAuth {
+		auth = m.suggestion.Auth
+	}
+	return headers, auth
+}
+
+// renderSuggestion offers the headers and auth recent requests to the
+// URL's host used, with credentials cut to their scheme
+func (m Model) renderSuggestion() string {
+	headers, auth := m.pendingSuggestion()
+	if len(headers) == 0 && auth == nil {
+		return ""
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var used []string
+	for _, name := range names {
+		value := headers[name]
+		if lint.SensitiveName(name) {
+			scheme, _, ok := strings.Cut(value, " ")
+			value = "…"
+			if ok {
+				value = scheme + " …"
+			}
+		}
+		used = append(used, name+": "+value)
+	}
+	if auth != nil {
+		described := string(auth.Type) + " auth"
+		switch {
+		case auth.Username != "":
+			described += " as " + auth.Username
+		case auth.Profile != "":
+			described += " " + auth.Profile
+		}
+		used = append(used, described)
+	}
+
+	requests := fmt.Sprintf("Last %d requests", m.suggestion.Requests)
+	if m.suggestion.Requests == 1 {
+		requests = "The last request"
+	}
+	return "\n" + focusedStyle.Render(fmt.Sprintf("%s to %s used %s • Alt+A to apply", requests, m.suggestion.Host, strings.Join(used, ", "))) + "\n"
+}
+
+// applySuggestion adds the suggested headers the form lacks and, if the
+// form has no auth, the suggested auth
+func (m *Model) applySuggestion() {
+	headers, auth := m.pendingSuggestion()
+	if len(headers) == 0 && auth == nil {
+		return
+	}
+	if len(headers) > 0 {
+		input := &m.inputs[inputHeaders].textinput
+		input.SetValue(applyHeaderPreset(input.Value(), request.HeaderPreset{Headers: headers}))
+	}
+	if auth != nil {
+		m.fillAuth(*auth)
+	}
+	m.formStatus = "Applied what recent requests to " + m.suggestion.Host + " used"
+}
@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/history"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestModel_HistorySuggestion(t *testing.T) {
+	h, err := history.Open(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/user", "/repos", "/issues"} {
+		err := h.Add(request.RequestData{
+			Method:  "GET",
+			URL:     "https://api.github.com" + path,
+			Headers: map[string]string{"Authorization": "token ghp_secret", "Accept": "application/vnd.github+json"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Add(request.RequestData{Method: "GET", URL: "https://api.example.com/", Auth: request.AuthData{Type: request.BasicAuth, Username: "jane", Password: "s3cret"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModel().WithHistory(h)
+	update := func(msg tea.Msg) {
+		newModel, _ := model.Update(msg)
+		model = newModel.(Model)
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("api.github.com/gists")})
+	view := model.View()
+	want := "Last 3 requests to api.github.com used Accept: application/vnd.github+json, Authorization: token … • Alt+A to apply"
+	if !strings.Contains(view, want) {
+		t.Fatalf("Expected the suggestion %q, got:\n%s", want, view)
+	}
+	if strings.Contains(view, "ghp_secret") {
+		t.Errorf("Expected the token not to be shown, got:\n%s", view)
+	}
+
+	// Headers the form already sets are not suggested again
+	model.inputs[inputHeaders].textinput.SetValue("#accept:text/plain")
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}, Alt: true})
+	if got := model.inputs[inputHeaders].textinput.Value(); got != "#accept:text/plain,Authorization:token ghp_secret" {
+		t.Errorf("Expected the missing header to be added, got %q", got)
+	}
+	if !strings.Contains(model.View(), "Applied what recent requests to api.github.com used") {
+		t.Errorf("Expected the form status to report the change, got:\n%s", model.View())
+	}
+	if view := model.View(); strings.Contains(view, "Alt+A to apply") {
+		t.Errorf("Expected nothing left to suggest, got:\n%s", view)
+	}
+
+	// Auth is applied when the form has none
+	model.inputs[inputURL].textinput.SetValue("")
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("https://api.example.com/users")})
+	if !strings.Contains(model.View(), "The last request to api.example.com used basic auth as jane • Alt+A to apply") {
+		t.Fatalf("Expected the auth to be suggested, got:\n%s", model.View())
+	}
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}, Alt: true})
+	if err := model.buildRequestData(); err != nil {
+		t.Fatal(err)
+	}
+	if auth := model.requestData.Auth; auth.Type != request.BasicAuth || auth.Username != "jane" || auth.Password != "s3cret" {
+		t.Errorf("Expected the auth from history, got %+v", auth)
+	}
+}
@@ -0,0 +1,30 @@
+package tui
+
+import "github.com/charmbracelet/lipgloss"
+
+// theme holds the colors of the TUI styles
+type theme struct {
+	accent, muted, warning, pass, fail lipgloss.Color
+}
+
+// themes maps the names in config.Themes to their colors
+var themes = map[string]theme{
+	"default":       {accent: "205", muted: "240", warning: "214", pass: "42", fail: "196"},
+	"light":         {accent: "125", muted: "244", warning: "166", pass: "28", fail: "160"},
+	"high-contrast": {accent: "14", muted: "7", warning: "11", pass: "10", fail: "9"},
+}
+
+// SetTheme switches the TUI to the named theme; unknown names select the
+// default theme
+func SetTheme(name string) {
+	t, ok := themes[name]
+	if !ok {
+		t = themes["default"]
+	}
+	focusedStyle = lipgloss.NewStyle().Foreground(t.accent).Bold(true)
+	blurredStyle = lipgloss.NewStyle().Foreground(t.muted)
+	warningStyle = lipgloss.NewStyle().Foreground(t.warning).Bold(true)
+	titleStyle = lipgloss.NewStyle().Foreground(t.accent).Bold(true).Padding(1, 2)
+	passStyle = lipgloss.NewStyle().Foreground(t.pass)
+	failStyle = lipgloss.NewStyle().Foreground(t.fail).Bold(true)
+}
@@ -0,0 +1,134 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// tutorialRequest is the collection/request the tutorial saves to
+const tutorialRequest = "tutorial/get-user"
+
+// tutorialStep is how far the tutorial has got
+type tutorialStep int
+
+// Steps of the tutorial
+const (
+	tutorialBuild tutorialStep = iota
+	tutorialSend
+	tutorialAssert
+	tutorialSave
+	tutorialDone
+)
+
+var tutorialStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("205")).
+	Padding(0, 1).
+	Width(76)
+
+// Tutorial walks a new user through building, previewing, sending,
+// asserting, and saving a request against a demo API, showing a hint for
+// the current step above every screen of the TUI
+type Tutorial struct {
+	// BaseURL is the demo API the requests are sent to
+	BaseURL string
+
+	step  tutorialStep
+	saved string
+}
+
+// NewTutorial returns a tutorial against the demo API at the base URL
+func NewTutorial(baseURL string) *Tutorial {
+	return &Tutorial{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Done reports whether every step of the tutorial was done
+func (t *Tutorial) Done() bool {
+	return t.step == tutorialDone
+}
+
+// Saved returns the collection/request the tutorial saved the request to,
+// or an empty string before it was saved
+func (t *Tutorial) Saved() string {
+	return t.saved
+}
+
+// WithTutorial returns a copy of the model that runs the tutorial
+func (m Model) WithTutorial(t *Tutorial) Model {
+	m.tutorial = t
+	m.inputs[inputURL].textinput.Placeholder = t.BaseURL + "/users/1"
+	return m
+}
+
+// demo reports whether the request goes to the demo API
+func (t *Tutorial) demo(req *request.RequestData) bool {
+	url := req.ResolvedURL()
+	return url == t.BaseURL || strings.HasPrefix(url, t.BaseURL+"/") || strings.HasPrefix(url, t.BaseURL+"?")
+}
+
+// observe moves on to the next step once the model shows the current one
+// was done
+func (t *Tutorial) observe(m *Model) {
+	switch t.step {
+	case tutorialBuild:
+		if m.screen == screenPreview && t.demo(m.requestData) {
+			t.step = tutorialSend
+		}
+	case tutorialSend:
+		if m.screen == screenResponse && m.response != nil && t.demo(m.requestData) {
+			t.step = tutorialAssert
+		}
+	case tutorialAssert:
+		if m.response == nil {
+			return
+		}
+		for _, assert := range m.asserts {
+			if assert.Check(m.response) == nil {
+				t.step = tutorialSave
+				return
+			}
+		}
+	case tutorialSave:
+		if m.savedAs != "" {
+			t.saved = m.savedAs
+			t.step = tutorialDone
+		}
+	}
+}
+
+// renderTutorial renders the hint for the current step of the tutorial
+func (m Model) renderTutorial() string {
+	t := m.tutorial
+	var title, hint string
+	switch t.step {
+	case tutorialBuild:
+		title = "Tutorial 1/4 · Build a request"
+		hint = fmt.Sprintf("Type %s/users/1 in the URL field. Tab and Shift+Tab move between the fields: add Accept:application/json to the Headers while you are at it. Press Enter to preview the request.", t.BaseURL)
+		if m.screen == screenPreview {
+			hint = fmt.Sprintf("This request does not go to the demo API. Press ESC and use %s/users/1 as the URL.", t.BaseURL)
+		}
+	case tutorialSend:
+		title = "Tutorial 2/4 · Preview and send"
+		hint = "The preview shows exactly what will be sent, with path parameters, templates, and encoding applied. Press Enter to send it, or ESC to change it."
+		if m.screen == screenResponse && m.err != nil {
+			hint = "The request failed: press ESC, check the URL, and try again."
+		}
+	case tutorialAssert:
+		title = "Tutorial 3/4 · Assert on the response"
+		hint = `The demo API answered with a user. Press a and type jsonpath "$.name" == "Ada Lovelace" to check the response, or status == 200 for the status. Assertions are checked every time the request runs.`
+		if len(m.asserts) > 0 {
+			hint = "That assertion fails on this response, so it would fail the run too. Press a to add one that passes."
+		}
+	case tutorialSave:
+		title = "Tutorial 4/4 · Save the request"
+		hint = fmt.Sprintf("Press Ctrl+S and save the request with its assertions as %s.", tutorialRequest)
+	case tutorialDone:
+		title = "Tutorial complete"
+		name, _, _ := strings.Cut(t.saved, "/")
+		hint = fmt.Sprintf("lighttr collections %s lists the saved request and lighttr run %s checks its assertions in CI. The demo API stops when you quit, so point the URL at your own API next. Ctrl+C quits.", name, name)
+	}
+	return tutorialStyle.Render(focusedStyle.Render(title)+"\n"+hint) + "\n"
+}
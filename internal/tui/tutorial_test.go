@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/internal/collection"
+	"github.com/nshekhawat/lighttr/internal/demo"
+)
+
+func TestModel_Tutorial(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	api, err := demo.Start()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer api.Close()
+	collections, err := collection.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	tutorial := NewTutorial(api.URL)
+	model := NewModel().WithCollections(collections).WithTutorial(tutorial)
+	update := func(msgs ...tea.Msg) {
+		for _, msg := range msgs {
+			updated, _ := model.Update(msg)
+			model = updated.(Model)
+		}
+	}
+	text := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	if view := model.View(); !strings.Contains(view, "Tutorial 1/4") || !strings.Contains(view, api.URL+"/users/1") {
+		t.Errorf("Expected the first hint, got:\n%s", view)
+	}
+
+	// Only a request to the demo API moves the tutorial on
+	model.inputs[inputURL].textinput.SetValue("https://api.example.com/users/1")
+	update(enter)
+	if model.screen != screenPreview || tutorial.step != tutorialBuild || !strings.Contains(model.View(), "does not go to the demo API") {
+		t.Fatalf("Expected the tutorial to wait for the demo API, got step %d:\n%s", tutorial.step, model.View())
+	}
+	update(tea.KeyMsg{Type: tea.KeyEscape})
+	model.inputs[inputURL].textinput.SetValue(api.URL + "/users/1")
+	update(enter)
+	if tutorial.step != tutorialSend || !strings.Contains(model.View(), "Tutorial 2/4") {
+		t.Fatalf("Expected the preview step, got step %d", tutorial.step)
+	}
+
+	update(enter)
+	update(model.executeRequest())
+	if model.response == nil || model.response.StatusCode != 200 || tutorial.step != tutorialAssert {
+		t.Fatalf("Expected the demo API to answer, got %+v, step %d", model.response, tutorial.step)
+	}
+
+	// An assertion that cannot be parsed keeps the prompt open, and one
+	// that fails does not complete the step
+	update(text("a"), text("status ~ 200"), enter)
+	if !model.asserting || !strings.Contains(model.assertStatus, "unsupported assert predicate") {
+		t.Fatalf("Expected the prompt to stay open with the error, got %q", model.assertStatus)
+	}
+	update(tea.KeyMsg{Type: tea.KeyEscape})
+	update(text("a"), text("status == 404"), enter)
+	if len(model.asserts) != 1 || tutorial.step != tutorialAssert || !strings.Contains(model.View(), "✗ status == 404") {
+		t.Fatalf("Expected the failing assertion to be shown, got step %d:\n%s", tutorial.step, model.View())
+	}
+	update(text("a"), text(`jsonpath "$.name" == "Ada Lovelace"`), enter)
+	if tutorial.step != tutorialSave || !strings.Contains(model.View(), `jsonpath "$.name" == "Ada Lovelace"`) {
+		t.Fatalf("Expected the passing assertion to complete the step, got step %d", tutorial.step)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if !model.savingRequest || model.saveAs.Value() != tutorialRequest {
+		t.Fatalf("Expected the save prompt with %s, got %q", tutorialRequest, model.saveAs.Value())
+	}
+	update(enter)
+	if !tutorial.Done() || tutorial.Saved() != tutorialRequest || !strings.Contains(model.View(), "Tutorial complete") {
+		t.Fatalf("Expected the tutorial to be done, got step %d: %s", tutorial.step, model.saved)
+	}
+	saved, err := collections.Get(tutorialRequest)
+	if err != nil || saved.Request.URL != api.URL+"/users/1" || len(saved.Asserts) != 2 {
+		t.Errorf("Expected the request and its assertions to be saved, got %+v, %v", saved, err)
+	}
+}
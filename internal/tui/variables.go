@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/nshekhawat/lighttr/internal/environment"
+	"github.com/nshekhawat/lighttr/internal/lint"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// templatedInputs are the inputs whose {{var}} placeholders are resolved
+// when the request is sent, those environment.ResolveRequest resolves
+var templatedInputs = []int{
+	inputURL, inputAuthUsername, inputAuthPassword, inputBearerToken, inputAPIKey,
+	inputAzureClientSecret, inputJWTClaims, inputSessionLogin, inputHeaders, inputQueryParams, inputPathParams,
+	inputProxy, inputFormParams, inputBody, inputMultipart,
+}
+
+// listInputs are the templated inputs that hold name/value lists, where a
+// value is masked when its name suggests a credential
+var listInputs = []int{inputHeaders, inputQueryParams, inputFormParams, inputMultipart}
+
+// resolvedField is a form input with placeholders as it is sent
+type resolvedField struct {
+	label string
+
+	// text is the value with its placeholders substituted, sensitive values
+	// masked and unresolved placeholders highlighted
+	text string
+
+	// missing names the placeholders that could not be resolved
+	missing []string
+}
+
+// resolvedFields substitutes the placeholders of the shown inputs that
+// have any, in form order
+func (m Model) resolvedFields() []resolvedField {
+	authType := request.AuthType(m.inputs[inputAuthType].textinput.Value())
+	var fields []resolvedField
+	for _, i := range templatedInputs {
+		if shouldSkipAuthField(i, authType) {
+			continue
+		}
+		value := m.inputs[i].textinput.Value()
+		placeholders := environment.Placeholders(value)
+		if len(placeholders) == 0 {
+			continue
+		}
+
+		field := resolvedField{label: shortLabel(m.inputs[i].label)}
+		// The text around the placeholders of secret inputs is as secret
+		// as they are, so only unresolved placeholders are shown
+		secret := m.inputs[i].textinput.EchoMode == textinput.EchoPassword
+		var b strings.Builder
+		if secret {
+			b.WriteString(masked)
+		}
+		end := 0
+		for _, p := range placeholders {
+			if !secret {
+				b.WriteString(value[end:p.Start])
+			}
+			end = p.End
+			resolved, ok := m.env.Lookup(p.Name)
+			switch {
+			case !ok:
+				field.missing = append(field.missing, p.Name)
+				if secret {
+					b.WriteString(" ")
+				}
+				b.WriteString(failStyle.Render(value[p.Start:p.End]))
+			case secret:
+			case lint.SensitiveName(p.Name) || sensitiveEntry(i, value, p.Start):
+				b.WriteString(masked)
+			default:
+				b.WriteString(resolved)
+			}
+		}
+		if !secret {
+			b.WriteString(value[end:])
+		}
+		field.text = b.String()
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// masked replaces sensitive values in the resolved pane
+const masked = "********"
+
+// sensitiveEntry reports whether the placeholder at start is in the value
+// of a list entry, such as a header, whose name suggests a credential
+func sensitiveEntry(input int, value string, start int) bool {
+	if !slices.Contains(listInputs, input) {
+		return false
+	}
+	entry := value[:start]
+	if i := strings.LastIndexAny(entry, ",&"); i >= 0 {
+		entry = entry[i+1:]
+	}
+	name, _, ok := strings.Cut(entry, ":")
+	if !ok {
+		name, _, _ = strings.Cut(entry, "=")
+	}
+	return lint.SensitiveName(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+}
+
+// shortLabel drops the hint in parentheses from an input label
+func shortLabel(label string) string {
+	name, _, _ := strings.Cut(label, " (")
+	return name
+}
+
+// environmentName names the environment placeholders are resolved
+// against before the process environment
+func (m Model) environmentName() string {
+	if m.env == nil || m.env.Name == "" {
+		return "process environment"
+	}
+	return "environment " + m.env.Name
+}
+
+// renderResolvedPane shows the inputs with placeholders as they will be
+// sent, updated as they are typed, with unresolved placeholders flagged
+func (m Model) renderResolvedPane() string {
+	fields := m.resolvedFields()
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n" + focusedStyle.Render("Resolved ("+m.environmentName()+")") + "\n")
+	var missing []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		b.WriteString(fmt.Sprintf("  %s: %s\n", field.label, field.text))
+		for _, name := range field.missing {
+			if !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		b.WriteString(failStyle.Render("  Unresolved: "+strings.Join(missing, ", ")) + "\n")
+	}
+	return b.String()
+}
+
+// resolvedRequest returns a copy of the request with its placeholders
+// substituted, as it is sent, or an error naming those that are unresolved
+func (m Model) resolvedRequest() (*request.RequestData, error) {
+	req := m.requestData.Clone()
+	if missing := m.env.ResolveRequest(req); len(missing) > 0 {
+		where := "the process environment, or start lighttr with --env"
+		if m.env != nil && m.env.Name != "" {
+			where = "environment " + m.env.Name + " or the process environment"
+		}
+		return nil, fmt.Errorf("unresolved variables: %s: define them in %s", strings.Join(missing, ", "), where)
+	}
+	return req, nil
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/internal/environment"
+)
+
+func TestRenderResolvedPane(t *testing.T) {
+	t.Setenv("LIGHTTR_TEST_TEAM", "payments")
+	m := NewModel().WithEnvironment(&environment.Environment{Name: "dev", Variables: map[string]string{
+		"base_url": "https://api.dev.example.com",
+		"user":     "jane",
+		"pass":     "hunter2",
+		"key":      "k-123",
+	}})
+	if pane := m.renderResolvedPane(); pane != "" {
+		t.Errorf("Expected no pane without placeholders, got %q", pane)
+	}
+
+	m.inputs[inputURL].textinput.SetValue("{{base_url}}/users/{{id}}")
+	m.inputs[inputAuthType].textinput.SetValue("basic")
+	m.inputs[inputAuthUsername].textinput.SetValue("{{user}}")
+	m.inputs[inputAuthPassword].textinput.SetValue("prefix-{{pass}}")
+	m.inputs[inputHeaders].textinput.SetValue("X-Api-Key:{{key}},X-Team:{{LIGHTTR_TEST_TEAM}}")
+	m.inputs[inputBearerToken].textinput.SetValue("{{hidden}}")
+
+	pane := m.renderResolvedPane()
+	for _, want := range []string{
+		"Resolved (environment dev)",
+		"URL: https://api.dev.example.com/users/{{id}}",
+		"Auth Username: jane",
+		"Auth Password: ********\n",
+		"Headers: X-Api-Key:********,X-Team:payments",
+		"Unresolved: id",
+	} {
+		if !strings.Contains(pane, want) {
+			t.Errorf("Expected the pane to contain %q, got:\n%s", want, pane)
+		}
+	}
+	for _, leaked := range []string{"hunter2", "prefix", "k-123", "Bearer Token"} {
+		if strings.Contains(pane, leaked) {
+			t.Errorf("Expected %q not to be shown, got:\n%s", leaked, pane)
+		}
+	}
+
+	if err := m.buildRequestData(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.resolvedRequest(); err == nil || err.Error() != "unresolved variables: id: define them in environment dev or the process environment" {
+		t.Errorf("Expected the unresolved variable to stop the send, got %v", err)
+	}
+
+	m.inputs[inputURL].textinput.SetValue("{{base_url}}/users/1")
+	if err := m.buildRequestData(); err != nil {
+		t.Fatal(err)
+	}
+	req, err := m.resolvedRequest()
+	if err != nil {
+		t.Fatalf("resolvedRequest() error = %v", err)
+	}
+	if req.URL != "https://api.dev.example.com/users/1" || req.Auth.Password != "prefix-hunter2" || req.Headers["X-Team"] != "payments" {
+		t.Errorf("Expected the placeholders to be substituted, got %s %+v %v", req.URL, req.Auth, req.Headers)
+	}
+	if m.requestData.URL != "{{base_url}}/users/1" {
+		t.Errorf("Expected the form's request to keep its placeholders, got %s", m.requestData.URL)
+	}
+}
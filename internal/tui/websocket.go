@@ -0,0 +1,233 @@
+package tui
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// websocketLogSize is how many of the latest messages the WebSocket screen
+// shows
+const websocketLogSize = 20
+
+// websocketFrame is a message sent or received on the WebSocket screen
+type websocketFrame struct {
+	sent bool
+	at   time.Time
+	msg  request.WebSocketMessage
+}
+
+// websocketConnectedMsg, websocketReceivedMsg, and websocketClosedMsg report
+// what happened to the connection ws; messages from a connection that is no
+// longer open on the screen are dropped
+type websocketConnectedMsg struct {
+	ws *request.WebSocket
+}
+
+type websocketReceivedMsg struct {
+	ws  *request.WebSocket
+	msg request.WebSocketMessage
+}
+
+type websocketClosedMsg struct {
+	ws  *request.WebSocket
+	err error
+}
+
+// openWebSocket switches to the WebSocket screen and connects to the ws://
+// or wss:// URL of the request
+func (m *Model) openWebSocket() tea.Cmd {
+	m.screen = screenWebSocket
+	m.websocketFrames = nil
+	m.websocketStatus = "Connecting to " + m.requestData.URL + "..."
+	m.websocketRecall = len(m.websocketSent)
+	m.logf(logInfo, "connecting to %s", m.requestData.URL)
+	return tea.Batch(m.dialWebSocket, m.websocketInput.Focus())
+}
+
+// dialWebSocket opens the connection with the form's headers, auth, and
+// connection settings
+func (m Model) dialWebSocket() tea.Msg {
+	req, err := m.resolvedRequest()
+	if err == nil {
+		err = req.Validate()
+	}
+	if err != nil {
+		return websocketClosedMsg{err: fmt.Errorf("invalid request: %v", err)}
+	}
+	ws, err := req.DialWebSocket(request.WithCookieJar(m.cookies), request.WithHARRecorder(m.recorder))
+	if err != nil {
+		return websocketClosedMsg{err: err}
+	}
+	return websocketConnectedMsg{ws: ws}
+}
+
+// receiveWebSocket waits for the next message on ws
+func receiveWebSocket(ws *request.WebSocket) tea.Cmd {
+	return func() tea.Msg {
+		msg, err := ws.Receive()
+		if err != nil {
+			return websocketClosedMsg{ws: ws, err: err}
+		}
+		return websocketReceivedMsg{ws: ws, msg: msg}
+	}
+}
+
+// updateWebSocket handles the connection's messages and the keys of the
+// WebSocket screen, where the message input takes all keys but Enter to
+// send, ↑/↓ to recall the messages sent, and Esc to close the connection
+func (m Model) updateWebSocket(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case websocketConnectedMsg:
+		if m.screen != screenWebSocket || m.websocket != nil {
+			msg.ws.Close()
+			return m, nil
+		}
+		m.websocket = msg.ws
+		m.websocketStatus = "Connected to " + m.requestData.URL
+		if msg.ws.Subprotocol != "" {
+			m.websocketStatus += " with " + msg.ws.Subprotocol
+		}
+		m.logf(logInfo, "connected to %s", m.requestData.URL)
+		return m, receiveWebSocket(msg.ws)
+
+	case websocketReceivedMsg:
+		if msg.ws != m.websocket {
+			return m, nil
+		}
+		m.websocketFrames = append(m.websocketFrames, websocketFrame{at: time.Now(), msg: msg.msg})
+		return m, receiveWebSocket(msg.ws)
+
+	case websocketClosedMsg:
+		if msg.ws != m.websocket || m.screen != screenWebSocket {
+			return m, nil
+		}
+		m.websocket = nil
+		switch {
+		case errors.Is(msg.err, io.EOF):
+			m.websocketStatus = "Closed"
+		case msg.ws == nil:
+			m.websocketStatus = "Failed to connect: " + msg.err.Error()
+			m.logf(logError, "%v", msg.err)
+		default:
+			m.websocketStatus = "Connection " + msg.err.Error()
+			m.logf(logInfo, "connection %v", msg.err)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			m.closeWebSocket()
+			return m, tea.Quit
+		case "esc":
+			m.closeWebSocket()
+			m.websocketInput.Blur()
+			m.screen = screenRequest
+			return m, nil
+		case "enter":
+			m.sendWebSocket()
+			return m, nil
+		case "up", "down":
+			m.recallWebSocket(msg.String())
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.websocketInput, cmd = m.websocketInput.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+// sendWebSocket sends the message input as a text message
+func (m *Model) sendWebSocket() {
+	text := m.websocketInput.Value()
+	if m.websocket == nil || text == "" {
+		return
+	}
+	if err := m.websocket.Send(text); err != nil {
+		m.websocketStatus = "Failed to send: " + err.Error()
+		m.logf(logError, "sending: %v", err)
+		return
+	}
+	m.websocketFrames = append(m.websocketFrames, websocketFrame{sent: true, at: time.Now(), msg: request.WebSocketMessage{Data: text}})
+	if n := len(m.websocketSent); n == 0 || m.websocketSent[n-1] != text {
+		m.websocketSent = append(m.websocketSent, text)
+	}
+	m.websocketRecall = len(m.websocketSent)
+	m.websocketInput.SetValue("")
+}
+
+// recallWebSocket fills the message input with an earlier or later message
+// sent this session
+func (m *Model) recallWebSocket(key string) {
+	if key == "up" && m.websocketRecall > 0 {
+		m.websocketRecall--
+	} else if key == "down" && m.websocketRecall < len(m.websocketSent) {
+		m.websocketRecall++
+	}
+	text := ""
+	if m.websocketRecall < len(m.websocketSent) {
+		text = m.websocketSent[m.websocketRecall]
+	}
+	m.websocketInput.SetValue(text)
+	m.websocketInput.CursorEnd()
+}
+
+// closeWebSocket closes the connection open on the screen, if any
+func (m *Model) closeWebSocket() {
+	if m.websocket == nil {
+		return
+	}
+	m.websocket.Close()
+	m.websocket = nil
+	m.logf(logInfo, "closed the connection to %s", m.requestData.URL)
+}
+
+func (m Model) renderWebSocketScreen() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("WebSocket"))
+	b.WriteString("\n\n")
+	status := focusedStyle
+	if m.websocket == nil {
+		status = warningStyle
+	}
+	sent := 0
+	for _, frame := range m.websocketFrames {
+		if frame.sent {
+			sent++
+		}
+	}
+	b.WriteString(status.Render(m.websocketStatus))
+	b.WriteString(blurredStyle.Render(fmt.Sprintf(" • %d sent • %d received", sent, len(m.websocketFrames)-sent)) + "\n\n")
+
+	frames := m.websocketFrames
+	if len(frames) > websocketLogSize {
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("... %d earlier messages", len(frames)-websocketLogSize)) + "\n")
+		frames = frames[len(frames)-websocketLogSize:]
+	}
+	if len(frames) == 0 {
+		b.WriteString(blurredStyle.Render("No messages yet") + "\n")
+	}
+	for _, frame := range frames {
+		arrow := passStyle.Render("←")
+		if frame.sent {
+			arrow = focusedStyle.Render("→")
+		}
+		data := frame.msg.Data
+		if frame.msg.Binary {
+			data = fmt.Sprintf("[binary %d bytes] %s", len(data), base64.StdEncoding.EncodeToString([]byte(data)))
+		}
+		b.WriteString(blurredStyle.Render(frame.at.Format("15:04:05")) + " " + arrow + " " + data + "\n")
+	}
+
+	b.WriteString("\n" + m.websocketInput.View() + "\n")
+	b.WriteString("\nEnter to send • ↑/↓ to recall sent messages • ESC to close the connection • Ctrl+C to quit\n")
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestModel_WebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := request.AcceptWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		ws.Send("welcome")
+		for {
+			msg, err := ws.Receive()
+			if err != nil {
+				return
+			}
+			ws.SendBinary([]byte(msg.Data))
+		}
+	}))
+	defer server.Close()
+
+	model := NewModel()
+	var cmd tea.Cmd
+	update := func(msg tea.Msg) {
+		var newModel tea.Model
+		newModel, cmd = model.Update(msg)
+		model = newModel.(Model)
+	}
+	model.inputs[inputURL].textinput.SetValue("ws" + strings.TrimPrefix(server.URL, "http") + "/events")
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(model.View(), "Press Enter to connect") {
+		t.Fatalf("Expected the preview to offer to connect, got:\n%s", model.View())
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model.screen != screenWebSocket {
+		t.Fatalf("Expected the WebSocket screen, got %v", model.screen)
+	}
+
+	update(model.dialWebSocket())
+	if model.websocket == nil {
+		t.Fatalf("Expected to be connected, got:\n%s", model.View())
+	}
+	update(cmd()) // welcome
+
+	for _, text := range []string{"hello", "world"} {
+		update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+		update(tea.KeyMsg{Type: tea.KeyEnter})
+		update(receiveWebSocket(model.websocket)())
+	}
+	view := model.View()
+	for _, want := range []string{"Connected to ws://", "2 sent • 3 received", "← welcome", "→ hello", "← [binary 5 bytes] aGVsbG8=", "→ world"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q on the WebSocket screen:\n%s", want, view)
+		}
+	}
+
+	// The messages sent are recalled with ↑ and ↓
+	update(tea.KeyMsg{Type: tea.KeyUp})
+	update(tea.KeyMsg{Type: tea.KeyUp})
+	if got := model.websocketInput.Value(); got != "hello" {
+		t.Errorf("Expected the first message to be recalled, got %q", got)
+	}
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := model.websocketInput.Value(); got != "" {
+		t.Errorf("Expected an empty input past the last message, got %q", got)
+	}
+
+	// q is typed, not quitting, and Esc closes the connection
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if model.websocketInput.Value() != "q" {
+		t.Errorf("Expected q to be typed into the message")
+	}
+	ws := model.websocket
+	update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model.screen != screenRequest || model.websocket != nil {
+		t.Errorf("Expected the connection to be closed, got screen %v", model.screen)
+	}
+	if _, err := ws.Receive(); err == nil {
+		t.Error("Expected the connection to be closed")
+	}
+}
+
+func TestModel_WebSocketRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	model := NewModel()
+	model.inputs[inputURL].textinput.SetValue("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err := model.buildRequestData(); err != nil {
+		t.Fatal(err)
+	}
+	model.openWebSocket()
+	newModel, _ := model.Update(model.dialWebSocket())
+	model = newModel.(Model)
+	if view := model.View(); !strings.Contains(view, "Failed to connect: WebSocket handshake failed: 404 Not Found") {
+		t.Errorf("Expected the refused handshake to be reported, got:\n%s", view)
+	}
+}
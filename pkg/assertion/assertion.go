@@ -0,0 +1,224 @@
+// Package assertion checks HTTP responses against assertions written in the
+// Hurl syntax, as lighttr does for the assertions of saved requests, so Go
+// programs can check responses of the request package the same way:
+//
+//	a := assertion.Assertion{Query: "jsonpath", Argument: "$.name", Predicate: "==", Value: `"Jane"`}
+//	resp, err := req.Execute()
+//	if err != nil {
+//		return err
+//	}
+//	if err := a.Check(resp); err != nil {
+//		log.Printf("%s: %v", a, err) // jsonpath "$.name" == "Jane": got "John"
+//	}
+//
+// Assertion serializes to the JSON used for the asserts of saved requests.
+// The format and the exported API are kept backwards compatible.
+package assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Assertion checks a value taken from a response, written in the Hurl
+// syntax as e.g. jsonpath "$.id" == 42 or header "Content-Type" contains "json"
+type Assertion struct {
+	// Query selects the value: status, header, jsonpath, xpath, body, or
+	// duration (milliseconds)
+	Query string `json:"query"`
+
+	// Argument is the header name, JSON path, or XPath expression for
+	// header, jsonpath, and xpath queries
+	Argument string `json:"argument,omitempty"`
+
+	// Predicate is one of ==, !=, >, >=, <, <=, contains, startsWith,
+	// endsWith, matches, or exists
+	Predicate string `json:"predicate"`
+
+	// Value is the expected value as a JSON literal; matches takes a
+	// regular expression string and exists takes no value
+	Value string `json:"value,omitempty"`
+}
+
+// String formats the assertion in the Hurl syntax
+func (a Assertion) String() string {
+	s := a.Query
+	if a.Argument != "" {
+		s += " " + strconv.Quote(a.Argument)
+	}
+	s += " " + a.Predicate
+	if a.Value != "" {
+		s += " " + a.Value
+	}
+	return s
+}
+
+// Check evaluates the assertion against the response. The error describes
+// why it does not hold: the value was not found, the actual value, e.g.
+// got "John", or an invalid query, predicate, or expected value.
+func (a Assertion) Check(resp *request.ResponseData) error {
+	actual, found, err := queryResponse(a, resp)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("not found")
+	}
+	if a.Predicate == "exists" {
+		return nil
+	}
+
+	var expected interface{}
+	if err := json.Unmarshal([]byte(a.Value), &expected); err != nil {
+		return fmt.Errorf("invalid expected value %s", a.Value)
+	}
+
+	ok, err := evaluate(a.Predicate, actual, expected)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("got %s", formatValue(actual))
+	}
+	return nil
+}
+
+// queryResponse returns the value an assertion's query selects. JSON values
+// keep their type; numbers are float64 as decoded by encoding/json.
+func queryResponse(assert Assertion, resp *request.ResponseData) (interface{}, bool, error) {
+	switch assert.Query {
+	case "status":
+		return float64(resp.StatusCode), true, nil
+	case "duration":
+		return float64(resp.ResponseTime.Milliseconds()), true, nil
+	case "body":
+		return resp.Body, true, nil
+	case "header":
+		for key, value := range resp.Headers {
+			if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(assert.Argument) {
+				return value, true, nil
+			}
+		}
+		return nil, false, nil
+	case "jsonpath":
+		var doc interface{}
+		if err := json.Unmarshal([]byte(resp.Body), &doc); err != nil {
+			return nil, false, fmt.Errorf("body is not JSON")
+		}
+		value, ok := request.LookupJSONPath(doc, assert.Argument)
+		return value, ok, nil
+	case "xpath":
+		return queryXPath(assert.Argument, resp.Body)
+	}
+	return nil, false, fmt.Errorf("unsupported query %q", assert.Query)
+}
+
+// queryXPath selects values from an XML body. A single match is a string,
+// several are a list, and count() is a number.
+func queryXPath(expr, body string) (interface{}, bool, error) {
+	xpath, err := request.CompileXPath(expr)
+	if err != nil {
+		return nil, false, err
+	}
+	values, err := xpath.Query(body)
+	if err != nil {
+		return nil, false, err
+	}
+	if strings.HasPrefix(xpath.String(), "count(") {
+		count, err := strconv.ParseFloat(values[0], 64)
+		return count, err == nil, err
+	}
+	switch len(values) {
+	case 0:
+		return nil, false, nil
+	case 1:
+		return values[0], true, nil
+	}
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+	return items, true, nil
+}
+
+// evaluate applies a predicate to the actual and expected values
+func evaluate(predicate string, actual, expected interface{}) (bool, error) {
+	switch predicate {
+	case "==":
+		return reflect.DeepEqual(actual, expected), nil
+	case "!=":
+		return !reflect.DeepEqual(actual, expected), nil
+	case ">", ">=", "<", "<=":
+		a, aok := actual.(float64)
+		e, eok := expected.(float64)
+		if !aok || !eok {
+			return false, fmt.Errorf("%s compares numbers, got %s", predicate, formatValue(actual))
+		}
+		switch predicate {
+		case ">":
+			return a > e, nil
+		case ">=":
+			return a >= e, nil
+		case "<":
+			return a < e, nil
+		}
+		return a <= e, nil
+	case "contains":
+		if items, ok := actual.([]interface{}); ok {
+			for _, item := range items {
+				if reflect.DeepEqual(item, expected) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		a, e, err := stringOperands(predicate, actual, expected)
+		return err == nil && strings.Contains(a, e), err
+	case "startsWith":
+		a, e, err := stringOperands(predicate, actual, expected)
+		return err == nil && strings.HasPrefix(a, e), err
+	case "endsWith":
+		a, e, err := stringOperands(predicate, actual, expected)
+		return err == nil && strings.HasSuffix(a, e), err
+	case "matches":
+		a, e, err := stringOperands(predicate, actual, expected)
+		if err != nil {
+			return false, err
+		}
+		pattern, err := regexp.Compile(e)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression: %v", err)
+		}
+		return pattern.MatchString(a), nil
+	}
+	return false, fmt.Errorf("unsupported predicate %q", predicate)
+}
+
+// stringOperands returns both values as strings for the string predicates
+func stringOperands(predicate string, actual, expected interface{}) (string, string, error) {
+	a, aok := actual.(string)
+	e, eok := expected.(string)
+	if !aok || !eok {
+		return "", "", fmt.Errorf("%s compares strings, got %s", predicate, formatValue(actual))
+	}
+	return a, e, nil
+}
+
+// formatValue renders a value the way it would be written in an assertion
+func formatValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(encoded) > 80 {
+		return string(encoded[:77]) + "..."
+	}
+	return string(encoded)
+}
@@ -0,0 +1,74 @@
+package assertion
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestAssertion_String(t *testing.T) {
+	tests := []struct {
+		assert Assertion
+		want   string
+	}{
+		{Assertion{Query: "status", Predicate: "==", Value: "200"}, `status == 200`},
+		{Assertion{Query: "jsonpath", Argument: "$.name", Predicate: "==", Value: `"Jane"`}, `jsonpath "$.name" == "Jane"`},
+		{Assertion{Query: "header", Argument: "X-Trace", Predicate: "exists"}, `header "X-Trace" exists`},
+	}
+	for _, tt := range tests {
+		if got := tt.assert.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestAssertion_Check(t *testing.T) {
+	resp := &request.ResponseData{
+		StatusCode:   http.StatusOK,
+		Headers:      map[string]string{"content-type": "application/json"},
+		Body:         `{"name": "Jane", "age": 42, "tags": ["admin"]}`,
+		ResponseTime: 120 * time.Millisecond,
+	}
+	tests := []struct {
+		assert Assertion
+		err    string
+	}{
+		{Assertion{Query: "status", Predicate: "==", Value: "200"}, ""},
+		{Assertion{Query: "header", Argument: "Content-Type", Predicate: "contains", Value: `"json"`}, ""},
+		{Assertion{Query: "jsonpath", Argument: "$.age", Predicate: ">=", Value: "42"}, ""},
+		{Assertion{Query: "jsonpath", Argument: "$.tags", Predicate: "contains", Value: `"admin"`}, ""},
+		{Assertion{Query: "body", Predicate: "startsWith", Value: `"{"`}, ""},
+		{Assertion{Query: "duration", Predicate: "<", Value: "1000"}, ""},
+		{Assertion{Query: "jsonpath", Argument: "$.name", Predicate: "==", Value: `"John"`}, `got "Jane"`},
+		{Assertion{Query: "header", Argument: "X-Trace", Predicate: "exists"}, "not found"},
+		{Assertion{Query: "jsonpath", Argument: "$.name", Predicate: ">", Value: "1"}, `> compares numbers, got "Jane"`},
+		{Assertion{Query: "body", Predicate: "matches", Value: `"("`}, "invalid regular expression"},
+		{Assertion{Query: "status", Predicate: "==", Value: "two hundred"}, "invalid expected value two hundred"},
+		{Assertion{Query: "cookie", Predicate: "exists"}, `unsupported query "cookie"`},
+	}
+	for _, tt := range tests {
+		err := tt.assert.Check(resp)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: Check() = %v, want %q", tt.assert, err, tt.err)
+		}
+	}
+}
+
+func TestAssertion_CheckXPath(t *testing.T) {
+	resp := &request.ResponseData{StatusCode: http.StatusOK, Body: `<Items><Item id="1">apple</Item><Item id="2">banana</Item></Items>`}
+	for _, assert := range []Assertion{
+		{Query: "xpath", Argument: "//Item[@id='2']", Predicate: "==", Value: `"banana"`},
+		{Query: "xpath", Argument: "count(//Item)", Predicate: "==", Value: "2"},
+		{Query: "xpath", Argument: "//Item", Predicate: "contains", Value: `"apple"`},
+	} {
+		if err := assert.Check(resp); err != nil {
+			t.Errorf("%s: Check() = %v", assert, err)
+		}
+	}
+	if err := (Assertion{Query: "xpath", Argument: "//Missing", Predicate: "exists"}).Check(resp); err == nil || err.Error() != "not found" {
+		t.Errorf("Expected a missing element not to be found, got %v", err)
+	}
+}
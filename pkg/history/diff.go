@@ -0,0 +1,131 @@
+package history
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// volatileHeaders change with every response, so diffs leave them out
+var volatileHeaders = map[string]bool{
+	"Date": true,
+	"Age":  true,
+}
+
+// FieldChange is a part of a response that differs between two runs
+type FieldChange struct {
+	Name   string `json:"name"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Change is how the response of an entry differs from that of the
+// previous run of the same request
+type Change struct {
+	// Previous and Current index the two entries in history
+	Previous int           `json:"previous"`
+	Current  int           `json:"current"`
+	Fields   []FieldChange `json:"fields"`
+}
+
+// DiffResponses compares the responses of two entries: their status, body,
+// and headers but Date and Age. History keeps no bodies, so bodies are
+// compared by size and hash. It returns nil for identical responses, as
+// SameResponse reports them, without comparing headers.
+func DiffResponses(before, after Entry) []FieldChange {
+	if SameResponse(before, after) {
+		return nil
+	}
+	a, b := before.Response, after.Response
+	if a == nil || b == nil {
+		return []FieldChange{{Name: "Response", Before: describeResponse(a), After: describeResponse(b)}}
+	}
+
+	var changes []FieldChange
+	if a.StatusCode != b.StatusCode {
+		changes = append(changes, FieldChange{Name: "Status", Before: fmt.Sprint(a.StatusCode), After: fmt.Sprint(b.StatusCode)})
+	}
+	if a.Error != b.Error {
+		changes = append(changes, FieldChange{Name: "Error", Before: orDash(a.Error), After: orDash(b.Error)})
+	}
+	if a.BodyHash != b.BodyHash {
+		changes = append(changes, FieldChange{Name: "Body", Before: describeBody(a), After: describeBody(b)})
+	}
+
+	names := make(map[string]bool)
+	for name := range a.Headers {
+		names[http.CanonicalHeaderKey(name)] = true
+	}
+	for name := range b.Headers {
+		names[http.CanonicalHeaderKey(name)] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !volatileHeaders[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	slices.Sort(sorted)
+	for _, name := range sorted {
+		if was, is := headerValue(a.Headers, name), headerValue(b.Headers, name); was != is {
+			changes = append(changes, FieldChange{Name: name, Before: was, After: is})
+		}
+	}
+	return changes
+}
+
+// Changes compares every entry with a previous run to that run, oldest
+// first, returning the pairs whose responses differ and how many pairs
+// were identical. Identical pairs are told apart by their body hashes
+// alone, so they are skipped without being compared further.
+func (m *Manager) Changes() (changes []Change, identical int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.history {
+		previous := m.previous(i)
+		if previous < 0 {
+			continue
+		}
+		if SameResponse(m.history[previous], m.history[i]) {
+			identical++
+			continue
+		}
+		changes = append(changes, Change{Previous: previous, Current: i, Fields: DiffResponses(m.history[previous], m.history[i])})
+	}
+	return changes, identical
+}
+
+// headerValue returns the value of the header whatever the case of its
+// name, or - when it is absent
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if http.CanonicalHeaderKey(key) == name {
+			return orDash(value)
+		}
+	}
+	return "-"
+}
+
+// orDash returns the value, or - when it is empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// describeBody describes a body by its size and hash
+func describeBody(resp *ResponseSummary) string {
+	return fmt.Sprintf("%d bytes, %s", resp.BodySize, resp.BodyHash)
+}
+
+// describeResponse describes a response for a diff with a missing one
+func describeResponse(resp *ResponseSummary) string {
+	switch {
+	case resp == nil:
+		return "none recorded"
+	case resp.Error != "":
+		return "error: " + resp.Error
+	}
+	return fmt.Sprintf("%d, %s", resp.StatusCode, describeBody(resp))
+}
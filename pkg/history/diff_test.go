@@ -0,0 +1,71 @@
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestDiffResponses(t *testing.T) {
+	ok := &ResponseSummary{StatusCode: 200, BodySize: 2, BodyHash: HashBody("[]"),
+		Headers: map[string]string{"Content-Type": "application/json", "Date": "Mon", "X-Cache": "HIT"}}
+	tests := []struct {
+		name   string
+		before *ResponseSummary
+		after  *ResponseSummary
+		want   []FieldChange
+	}{
+		{"identical", ok, &ResponseSummary{StatusCode: 200, BodySize: 2, BodyHash: HashBody("[]")}, nil},
+		{"no response", nil, ok, []FieldChange{{Name: "Response", Before: "none recorded", After: "200, 2 bytes, " + HashBody("[]")}}},
+		{"status, body, and headers", ok, &ResponseSummary{StatusCode: 500, BodySize: 4, BodyHash: HashBody("oops"),
+			Headers: map[string]string{"content-type": "text/plain", "Date": "Tue"}}, []FieldChange{
+			{Name: "Status", Before: "200", After: "500"},
+			{Name: "Body", Before: "2 bytes, " + HashBody("[]"), After: "4 bytes, " + HashBody("oops")},
+			{Name: "Content-Type", Before: "application/json", After: "text/plain"},
+			{Name: "X-Cache", Before: "HIT", After: "-"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffResponses(Entry{Response: tt.before}, Entry{Response: tt.after})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffResponses() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_Changes(t *testing.T) {
+	manager, err := Open(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	users := request.RequestData{Method: "GET", URL: "https://api.example.com/users"}
+	orders := request.RequestData{Method: "GET", URL: "https://api.example.com/orders"}
+	runs := []struct {
+		req  request.RequestData
+		resp *request.ResponseData
+	}{
+		{users, &request.ResponseData{StatusCode: 200, Body: `[]`}},
+		{orders, &request.ResponseData{StatusCode: 200, Body: `[]`}},
+		{users, &request.ResponseData{StatusCode: 200, Body: `[]`}},
+		{users, &request.ResponseData{StatusCode: 503, Body: `[]`}},
+		{orders, &request.ResponseData{StatusCode: 200, Body: `[]`}},
+	}
+	for _, run := range runs {
+		if err := manager.AddWithResponse(run.req, run.resp); err != nil {
+			t.Fatalf("AddWithResponse() error = %v", err)
+		}
+	}
+
+	changes, identical := manager.Changes()
+	if identical != 2 {
+		t.Errorf("Expected 2 identical runs to be skipped, got %d", identical)
+	}
+	want := []Change{{Previous: 2, Current: 3, Fields: []FieldChange{{Name: "Status", Before: "200", After: "503"}}}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Changes() = %+v, want %+v", changes, want)
+	}
+}
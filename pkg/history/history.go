@@ -0,0 +1,284 @@
+// Package history reads and writes lighttr's request history, the file
+// ~/.lighttr/history.json that the server and other tools append to, so Go
+// programs can record requests in the same format or analyze them:
+//
+//	h, err := history.NewManager()
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := req.Execute()
+//	if err != nil {
+//		return err
+//	}
+//	if err := h.AddWithResponse(*req, resp); err != nil {
+//		return err
+//	}
+//
+// Each entry is a request.RequestData with a summary of its response. The
+// file format and the exported API are kept backwards compatible.
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Entry is a request in history, with a summary of its response when one
+// was recorded
+type Entry struct {
+	request.RequestData
+	Response *ResponseSummary `json:"response,omitempty"`
+
+	// Time is when the entry was recorded; zero for entries recorded
+	// before times were kept
+	Time time.Time `json:"time,omitzero"`
+
+	// Tags label the entry, e.g. with the bug being debugged, to pick it
+	// out of history later
+	Tags []string `json:"tags,omitempty"`
+}
+
+// HasTag reports whether the entry is labeled with the tag
+func (e Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+// ResponseSummary identifies a response without storing its body
+type ResponseSummary struct {
+	StatusCode int    `json:"status_code"`
+	BodySize   int    `json:"body_size"`
+	BodyHash   string `json:"body_hash"`
+
+	// ResponseTime is how long the response took
+	ResponseTime time.Duration `json:"response_time,omitempty"`
+
+	// Headers, Protocol, and Timing describe the response for bug reports,
+	// and Error why there was none; entries recorded before they were kept
+	// have none of them
+	Headers  map[string]string `json:"headers,omitempty"`
+	Protocol string            `json:"protocol,omitempty"`
+	Timing   *request.Timing   `json:"timing,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Manager handles the storage and retrieval of request history. It is safe
+// for concurrent use, e.g. by the handlers of a server.
+type Manager struct {
+	mu       sync.Mutex
+	filePath string
+	history  []Entry
+}
+
+// NewManager creates a new history manager for ~/.lighttr/history.json
+func NewManager() (*Manager, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filepath.Join(homeDir, ".lighttr", "history.json"))
+}
+
+// Open creates a history manager for the history file at filePath, which
+// is created on the first change if it does not exist
+func Open(filePath string) (*Manager, error) {
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+
+	manager := &Manager{
+		filePath: filePath,
+		history:  make([]Entry, 0),
+	}
+
+	// Load existing history if it exists
+	if err := manager.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+// Add adds a new request to history
+func (m *Manager) Add(req request.RequestData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = append(m.history, Entry{RequestData: req, Time: time.Now()})
+	return m.save()
+}
+
+// AddWithResponse adds a new request to history along with a summary of
+// its response
+func (m *Manager) AddWithResponse(req request.RequestData, resp *request.ResponseData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = append(m.history, Entry{
+		RequestData: req,
+		Response: &ResponseSummary{
+			StatusCode:   resp.StatusCode,
+			BodySize:     len(resp.Body),
+			BodyHash:     HashBody(resp.Body),
+			ResponseTime: resp.ResponseTime,
+			Headers:      resp.Headers,
+			Protocol:     resp.Protocol,
+			Timing:       resp.Timing,
+			Error:        resp.Error,
+		},
+		Time: time.Now(),
+	})
+	return m.save()
+}
+
+// GetAll returns all historical requests
+func (m *Manager) GetAll() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.history)
+}
+
+// Previous returns the index of the most recent earlier entry for the same
+// method and URL, or -1 if there is none
+func (m *Manager) Previous(index int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.previous(index)
+}
+
+func (m *Manager) previous(index int) int {
+	if index < 0 || index >= len(m.history) {
+		return -1
+	}
+	current := m.history[index]
+	for i := index - 1; i >= 0; i-- {
+		if m.history[i].Method == current.Method && m.history[i].URL == current.URL {
+			return i
+		}
+	}
+	return -1
+}
+
+// SameAsPrevious reports whether the entry's response matches the previous
+// run of the same request
+func (m *Manager) SameAsPrevious(index int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous := m.previous(index)
+	return previous >= 0 && SameResponse(m.history[previous], m.history[index])
+}
+
+// Tag labels the entry at index with the tags it does not have yet
+func (m *Manager) Tag(index int, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if index < 0 || index >= len(m.history) {
+		return fmt.Errorf("no entry %d in history", index+1)
+	}
+	for _, tag := range tags {
+		if !m.history[index].HasTag(tag) {
+			m.history[index].Tags = append(m.history[index].Tags, tag)
+		}
+	}
+	return m.save()
+}
+
+// Snapshot returns a snapshot of the responses in history recorded before
+// until, or all of them if until is zero, to replay requests against.
+// History keeps no response bodies, so the responses have a status and
+// headers only.
+func (m *Manager) Snapshot(until time.Time) *request.Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := request.NewSnapshot(until)
+	for _, entry := range m.history {
+		if entry.Response == nil || entry.Response.Error != "" || entry.Response.StatusCode == 0 {
+			continue
+		}
+		wireURL, err := entry.WireURL()
+		if err != nil {
+			continue
+		}
+		s.Add(entry.WireMethod(), wireURL, entry.Time, &request.ResponseData{
+			StatusCode: entry.Response.StatusCode,
+			Headers:    entry.Response.Headers,
+			Protocol:   entry.Response.Protocol,
+		})
+	}
+	return s
+}
+
+// Clear removes all history
+func (m *Manager) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = make([]Entry, 0)
+	return m.save()
+}
+
+// SameResponse reports whether two entries recorded identical responses,
+// comparing status codes and body hashes only
+func SameResponse(a, b Entry) bool {
+	if a.Response == nil || b.Response == nil {
+		return false
+	}
+	return a.Response.StatusCode == b.Response.StatusCode && a.Response.BodyHash == b.Response.BodyHash
+}
+
+// HashBody returns the SHA-256 content hash of a response body
+func HashBody(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ParseDate parses a date as typed to pick history entries, today,
+// yesterday, a number of days ago such as -3, or a date such as
+// 2026-10-16, into the local date YYYY-MM-DD
+func ParseDate(value string, now time.Time) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	var days int
+	switch {
+	case value == "today":
+	case value == "yesterday":
+		days = 1
+	case strings.HasPrefix(value, "-"):
+		if _, err := fmt.Sscanf(value, "-%d", &days); err != nil || days < 0 {
+			return "", fmt.Errorf("invalid date %q: use YYYY-MM-DD, today, yesterday, or -N days", value)
+		}
+	default:
+		date, err := time.ParseInLocation(time.DateOnly, value, now.Location())
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q: use YYYY-MM-DD, today, yesterday, or -N days", value)
+		}
+		return date.Format(time.DateOnly), nil
+	}
+	return now.AddDate(0, 0, -days).Format(time.DateOnly), nil
+}
+
+// load reads the history from disk
+func (m *Manager) load() error {
+	data, err := os.ReadFile(m.filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &m.history)
+}
+
+// save writes the history to disk
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %v", err)
+	}
+
+	return os.WriteFile(m.filePath, data, 0644)
+}
@@ -0,0 +1,387 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestNewManager(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	// Check if .lighttr directory was created
+	lighttrDir := filepath.Join(tmpDir, ".lighttr")
+	if _, err := os.Stat(lighttrDir); os.IsNotExist(err) {
+		t.Error("Expected .lighttr directory to be created")
+	}
+
+	// Check if history file path is set correctly
+	expectedPath := filepath.Join(lighttrDir, "history.json")
+	if manager.filePath != expectedPath {
+		t.Errorf("Expected file path %s, got %s", expectedPath, manager.filePath)
+	}
+
+	// Check if history slice is initialized
+	if manager.history == nil {
+		t.Error("Expected history slice to be initialized")
+	}
+}
+
+func TestManager_AddAndGetAll(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	// Create test request data
+	req1 := request.RequestData{
+		Method:    "GET",
+		URL:       "https://api.example.com/1",
+		Timestamp: time.Now(),
+	}
+	req2 := request.RequestData{
+		Method:    "POST",
+		URL:       "https://api.example.com/2",
+		Timestamp: time.Now(),
+	}
+
+	// Add requests to history
+	if err := manager.Add(req1); err != nil {
+		t.Errorf("Add() error = %v", err)
+	}
+	if err := manager.Add(req2); err != nil {
+		t.Errorf("Add() error = %v", err)
+	}
+
+	// Get all requests
+	history := manager.GetAll()
+	if len(history) != 2 {
+		t.Errorf("Expected 2 items in history, got %d", len(history))
+	}
+
+	// Verify the requests were saved correctly
+	if history[0].Method != req1.Method || history[0].URL != req1.URL {
+		t.Error("First request not saved correctly")
+	}
+	if history[1].Method != req2.Method || history[1].URL != req2.URL {
+		t.Error("Second request not saved correctly")
+	}
+
+	// Verify the history was persisted to disk
+	data, err := os.ReadFile(manager.filePath)
+	if err != nil {
+		t.Fatalf("Failed to read history file: %v", err)
+	}
+
+	var savedHistory []request.RequestData
+	if err := json.Unmarshal(data, &savedHistory); err != nil {
+		t.Fatalf("Failed to unmarshal history file: %v", err)
+	}
+
+	if len(savedHistory) != 2 {
+		t.Errorf("Expected 2 items in saved history, got %d", len(savedHistory))
+	}
+}
+
+func TestManager_Clear(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	// Add a request to history
+	req := request.RequestData{
+		Method:    "GET",
+		URL:       "https://api.example.com",
+		Timestamp: time.Now(),
+	}
+	if err := manager.Add(req); err != nil {
+		t.Errorf("Add() error = %v", err)
+	}
+
+	// Clear history
+	if err := manager.Clear(); err != nil {
+		t.Errorf("Clear() error = %v", err)
+	}
+
+	// Verify history is empty
+	history := manager.GetAll()
+	if len(history) != 0 {
+		t.Errorf("Expected empty history after clear, got %d items", len(history))
+	}
+
+	// Verify history file is empty array
+	data, err := os.ReadFile(manager.filePath)
+	if err != nil {
+		t.Fatalf("Failed to read history file: %v", err)
+	}
+
+	if string(data) != "[]" {
+		t.Errorf("Expected empty array in history file, got %s", string(data))
+	}
+}
+
+func TestManager_ResponseHashes(t *testing.T) {
+	// Create a temporary directory for testing
+	tmpDir, err := os.MkdirTemp("", "lighttr-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Override home directory for testing
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	manager, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	users := request.RequestData{Method: "GET", URL: "https://api.example.com/users"}
+	orders := request.RequestData{Method: "GET", URL: "https://api.example.com/orders"}
+	runs := []struct {
+		req  request.RequestData
+		resp *request.ResponseData
+	}{
+		{users, &request.ResponseData{StatusCode: 200, Body: `[{"id":1}]`, Headers: map[string]string{"Content-Type": "application/json"},
+			Protocol: "HTTP/2.0", Timing: &request.Timing{TTFB: 5 * time.Millisecond}}},
+		{orders, &request.ResponseData{StatusCode: 200, Body: `[]`}},
+		{users, &request.ResponseData{StatusCode: 200, Body: `[{"id":1}]`}},
+		{users, &request.ResponseData{StatusCode: 200, Body: `[{"id":1},{"id":2}]`}},
+	}
+	for _, run := range runs {
+		if err := manager.AddWithResponse(run.req, run.resp); err != nil {
+			t.Fatalf("AddWithResponse() error = %v", err)
+		}
+	}
+
+	entries := manager.GetAll()
+	if entries[0].Response.BodyHash != HashBody(`[{"id":1}]`) || entries[0].Response.BodySize != 10 {
+		t.Errorf("Unexpected response summary: %+v", entries[0].Response)
+	}
+	if manager.Previous(2) != 0 || manager.Previous(0) != -1 {
+		t.Errorf("Expected previous run of the same request to be found")
+	}
+
+	for index, want := range []bool{false, false, true, false} {
+		if got := manager.SameAsPrevious(index); got != want {
+			t.Errorf("SameAsPrevious(%d) = %v, want %v", index, got, want)
+		}
+	}
+
+	// Hashes survive a reload
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if !reloaded.SameAsPrevious(2) {
+		t.Error("Expected response hashes to be persisted")
+	}
+	first := reloaded.GetAll()[0].Response
+	if first.Headers["Content-Type"] != "application/json" || first.Protocol != "HTTP/2.0" || first.Timing == nil || first.Timing.TTFB != 5*time.Millisecond {
+		t.Errorf("Expected headers, protocol, and timing to be persisted, got %+v", first)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.json")
+	manager, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := manager.Add(request.RequestData{Method: "GET", URL: "https://api.example.com/users"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	entries := reopened.GetAll()
+	if len(entries) != 1 || entries[0].URL != "https://api.example.com/users" {
+		t.Errorf("Expected the entry to be reloaded from %s, got %+v", path, entries)
+	}
+}
+
+func TestManager_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	manager, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := request.RequestData{Method: "GET", URL: "https://api.example.com/users"}
+			if err := manager.AddWithResponse(req, &request.ResponseData{StatusCode: 200, Body: "ok"}); err != nil {
+				t.Errorf("AddWithResponse() error = %v", err)
+			}
+			manager.GetAll()
+		}()
+	}
+	wg.Wait()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if entries := reopened.GetAll(); len(entries) != 20 {
+		t.Errorf("Expected every entry to be recorded, got %d", len(entries))
+	}
+}
+
+func TestManager_TimesAndLatency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, []byte(`[{"method":"GET","url":"https://api.example.com/old"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	before := time.Now()
+	resp := &request.ResponseData{StatusCode: 200, Body: "ok", ResponseTime: 150 * time.Millisecond}
+	if err := manager.AddWithResponse(request.RequestData{Method: "GET", URL: "https://api.example.com/new"}, resp); err != nil {
+		t.Fatalf("AddWithResponse() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	entries := reopened.GetAll()
+	if !entries[0].Time.IsZero() {
+		t.Errorf("Expected entries recorded before times were kept to have none, got %v", entries[0].Time)
+	}
+	if entries[1].Time.Before(before.Truncate(time.Second)) || entries[1].Response.ResponseTime != 150*time.Millisecond {
+		t.Errorf("Expected the time and latency to be recorded, got %v and %v", entries[1].Time, entries[1].Response.ResponseTime)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	tests := map[string]string{
+		"today":      "2026-10-16",
+		"Yesterday":  "2026-10-15",
+		"-7":         "2026-10-09",
+		"2026-03-01": "2026-03-01",
+	}
+	for value, want := range tests {
+		if got, err := ParseDate(value, now); err != nil || got != want {
+			t.Errorf("ParseDate(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"last week", "16/10/2026", "-x"} {
+		if _, err := ParseDate(value, now); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestManager_Tag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	manager, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Add(request.RequestData{Method: "GET", URL: "https://api.example.com/users"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Tag(0, "bug-42", "slow"); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	if err := manager.Tag(0, "bug-42"); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	if err := manager.Tag(1, "bug-42"); err == nil || err.Error() != "no entry 2 in history" {
+		t.Errorf("Expected a missing entry to be an error, got %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := reopened.GetAll()[0]
+	if len(entry.Tags) != 2 || !entry.HasTag("bug-42") || !entry.HasTag("slow") || entry.HasTag("other") {
+		t.Errorf("Expected the tags to be saved once each, got %v", entry.Tags)
+	}
+}
+
+func TestManager_Snapshot(t *testing.T) {
+	manager, err := Open(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := request.RequestData{Method: "GET", URL: "https://api.example.com/users/{id}", PathParams: map[string]string{"id": "7"}}
+	if err := manager.AddWithResponse(req, &request.ResponseData{StatusCode: 404, Headers: map[string]string{"X-Reason": "gone"}, Body: "not found"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.AddWithResponse(request.RequestData{Method: "GET", URL: "https://api.example.com/down"}, &request.ResponseData{Error: "connection refused"}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := manager.Snapshot(time.Time{})
+	if snapshot.Len() != 1 {
+		t.Fatalf("Expected only the response to be kept, got %d", snapshot.Len())
+	}
+	resp, err := snapshot.RoundTrip(httptest.NewRequest("GET", "https://api.example.com/users/7", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if resp.StatusCode != 404 || resp.Header.Get("X-Reason") != "gone" {
+		t.Errorf("Expected the recorded status and headers, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if manager.Snapshot(time.Now().Add(-time.Hour)).Len() != 0 {
+		t.Error("Expected the responses recorded after the date to be left out")
+	}
+}
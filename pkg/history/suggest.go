@@ -0,0 +1,102 @@
+package history
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+// Suggestion is the setup recent requests to a host had in common, offered
+// when a request to the host is started
+type Suggestion struct {
+	Host string
+
+	// Requests is how many recent requests to the host it is drawn from
+	Requests int
+
+	// Headers are those at least half of the requests sent, with the
+	// value the most recent of them sent
+	Headers map[string]string
+
+	// Auth is the auth at least half of the requests used, as the most
+	// recent of them used it; nil if they had none in common
+	Auth *request.AuthData
+}
+
+// Host returns the host, with any port, that a URL as typed is sent to, in
+// lower case, or "" if it has none, such as when it starts with a {{var}}
+// placeholder
+func Host(rawURL string) string {
+	normalized, _ := request.NormalizeURL(rawURL, "")
+	u, err := url.Parse(normalized)
+	if err != nil || strings.Contains(u.Host, "{{") {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// Suggest returns the headers and auth that at least half of the last limit
+// requests to host had in common, or nil if there were no requests to the
+// host or they had nothing in common
+func (m *Manager) Suggest(host string, limit int) *Suggestion {
+	host = strings.ToLower(host)
+	if host == "" || limit <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	var recent []Entry
+	for i := len(m.history) - 1; i >= 0 && len(recent) < limit; i-- {
+		if Host(m.history[i].URL) == host {
+			recent = append(recent, m.history[i])
+		}
+	}
+	m.mu.Unlock()
+	if len(recent) == 0 {
+		return nil
+	}
+
+	suggestion := &Suggestion{Host: host, Requests: len(recent), Headers: make(map[string]string)}
+	headerCounts := make(map[string]int)
+	latest := make(map[string]string)
+	authCounts := make(map[request.AuthType]int)
+	latestAuth := make(map[request.AuthType]request.AuthData)
+	for _, entry := range recent {
+		for name, value := range entry.Headers {
+			name = http.CanonicalHeaderKey(name)
+			headerCounts[name]++
+			if _, ok := latest[name]; !ok {
+				latest[name] = value
+			}
+		}
+		authType := entry.Auth.Type
+		if authType == "" || authType == request.NoAuth {
+			continue
+		}
+		authCounts[authType]++
+		if _, ok := latestAuth[authType]; !ok {
+			latestAuth[authType] = entry.Auth
+		}
+	}
+	for name, count := range headerCounts {
+		if count*2 >= len(recent) {
+			suggestion.Headers[name] = latest[name]
+		}
+	}
+	// Of auth types used equally often, the most recent wins
+	best := 0
+	for _, entry := range recent {
+		count := authCounts[entry.Auth.Type]
+		if count*2 >= len(recent) && count > best {
+			auth := latestAuth[entry.Auth.Type]
+			suggestion.Auth = &auth
+			best = count
+		}
+	}
+
+	if len(suggestion.Headers) == 0 && suggestion.Auth == nil {
+		return nil
+	}
+	return suggestion
+}
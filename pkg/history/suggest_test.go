@@ -0,0 +1,66 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nshekhawat/lighttr/pkg/request"
+)
+
+func TestHost(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://API.GitHub.com/user":  "api.github.com",
+		"api.github.com/repos":         "api.github.com",
+		"http://localhost:8080/health": "localhost:8080",
+		"{{base_url}}/users":           "",
+		"https://{{host}}/users":       "",
+		"":                             "",
+	} {
+		if got := Host(rawURL); got != want {
+			t.Errorf("Host(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestManager_Suggest(t *testing.T) {
+	manager, err := Open(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := request.AuthData{Type: request.BearerAuth, Token: "ghp_new"}
+	for _, req := range []request.RequestData{
+		{URL: "https://api.github.com/old", Headers: map[string]string{"X-Old": "1"}},
+		{URL: "https://api.github.com/user", Headers: map[string]string{"accept": "application/json"}, Auth: request.AuthData{Type: request.BearerAuth, Token: "ghp_old"}},
+		{URL: "https://api.example.com/other", Headers: map[string]string{"Accept": "text/plain"}, Auth: request.AuthData{Type: request.BasicAuth}},
+		{URL: "https://api.github.com/repos", Headers: map[string]string{"Accept": "application/vnd.github+json", "X-Once": "1"}, Auth: token},
+		{URL: "api.github.com/issues", Headers: map[string]string{"Accept": "application/vnd.github+json"}, Auth: request.AuthData{Type: request.BasicAuth}},
+		{URL: "https://API.github.com/gists", Auth: token},
+	} {
+		if err := manager.Add(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	suggestion := manager.Suggest("api.github.com", 4)
+	if suggestion == nil {
+		t.Fatal("Expected a suggestion")
+	}
+	if suggestion.Host != "api.github.com" || suggestion.Requests != 4 {
+		t.Errorf("Expected the last 4 requests to api.github.com, got %+v", suggestion)
+	}
+	if len(suggestion.Headers) != 1 || suggestion.Headers["Accept"] != "application/vnd.github+json" {
+		t.Errorf("Expected the latest Accept header only, got %v", suggestion.Headers)
+	}
+	if suggestion.Auth == nil || *suggestion.Auth != token {
+		t.Errorf("Expected the most common auth as last used, got %+v", suggestion.Auth)
+	}
+
+	if s := manager.Suggest("API.GITHUB.COM", 1); s == nil || s.Auth == nil || len(s.Headers) != 0 {
+		t.Errorf("Expected only the latest request's auth, got %+v", s)
+	}
+	for _, host := range []string{"api.gitlab.com", ""} {
+		if s := manager.Suggest(host, 5); s != nil {
+			t.Errorf("Expected no suggestion for %q, got %+v", host, s)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package request
+
+import (
+	"net/url"
+	"strings"
+)
+
+// APIKeyParam returns where API key auth sends the key: the name of the
+// header or query parameter, its value with any prefix, and whether it is
+// a query parameter
+func (a AuthData) APIKeyParam() (name, value string, query bool) {
+	if a.APIKeyQuery != "" {
+		return a.APIKeyQuery, a.APIKey, true
+	}
+	name, prefix := a.APIKeyHeader, strings.TrimSpace(a.APIKeyPrefix)
+	if name == "" {
+		name = "Authorization"
+		if prefix == "" {
+			prefix = "Bearer"
+		}
+	}
+	if prefix != "" {
+		return name, prefix + " " + a.APIKey, false
+	}
+	return name, a.APIKey, false
+}
+
+// addQueryParam appends a parameter to the URL's query, keeping the order
+// and encoding of the parameters already there
+func addQueryParam(u *url.URL, name, value string) {
+	param := url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	if u.RawQuery == "" {
+		u.RawQuery = param
+	} else {
+		u.RawQuery += "&" + param
+	}
+}
@@ -0,0 +1,62 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthData_APIKeyParam(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      AuthData
+		wantName  string
+		wantValue string
+		wantQuery bool
+	}{
+		{"default", AuthData{APIKey: "k"}, "Authorization", "Bearer k", false},
+		{"custom header", AuthData{APIKey: "k", APIKeyHeader: "X-API-Key"}, "X-API-Key", "k", false},
+		{"bare authorization", AuthData{APIKey: "k", APIKeyHeader: "Authorization"}, "Authorization", "k", false},
+		{"custom prefix", AuthData{APIKey: "k", APIKeyPrefix: "Token"}, "Authorization", "Token k", false},
+		{"header and prefix", AuthData{APIKey: "k", APIKeyHeader: "X-Auth", APIKeyPrefix: "Key"}, "X-Auth", "Key k", false},
+		{"query", AuthData{APIKey: "k", APIKeyQuery: "api_key"}, "api_key", "k", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, value, query := tt.auth.APIKeyParam()
+			if name != tt.wantName || value != tt.wantValue || query != tt.wantQuery {
+				t.Errorf("APIKeyParam() = %q, %q, %v, want %q, %q, %v", name, value, query, tt.wantName, tt.wantValue, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestRequestData_APIKeyPlacement(t *testing.T) {
+	var seen *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+	}))
+	defer server.Close()
+
+	req := &RequestData{Method: "GET", URL: server.URL + "/search", QueryParams: map[string]string{"q": "a b"},
+		Auth: AuthData{Type: APIKeyAuth, APIKey: "s3cr&t", APIKeyQuery: "api_key"}}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if seen.URL.Query().Get("api_key") != "s3cr&t" || seen.URL.Query().Get("q") != "a b" || seen.Header.Get("Authorization") != "" {
+		t.Errorf("Expected the key in the query only, got %s with %v", seen.URL, seen.Header)
+	}
+
+	req.Auth = AuthData{Type: APIKeyAuth, APIKey: "s3cret", APIKeyHeader: "X-API-Key"}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if seen.Header.Get("X-API-Key") != "s3cret" || seen.Header.Get("Authorization") != "" || seen.URL.Query().Has("api_key") {
+		t.Errorf("Expected the key in the X-API-Key header only, got %s with %v", seen.URL, seen.Header)
+	}
+
+	req.Auth = AuthData{Type: APIKeyAuth, APIKey: "s3cret", APIKeyHeader: "X-API-Key", APIKeyQuery: "api_key"}
+	if err := req.Validate(); err == nil {
+		t.Error("Expected a key in both a header and the query to be rejected")
+	}
+}
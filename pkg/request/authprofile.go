@@ -0,0 +1,55 @@
+package request
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AuthProfileFunc returns the credentials saved as the named auth profile
+type AuthProfileFunc func(name string) (AuthData, error)
+
+// authProfiles holds the lookup of the auth profile store
+var authProfiles struct {
+	sync.RWMutex
+	lookup AuthProfileFunc
+}
+
+// RegisterAuthProfiles sets how requests with profile auth find the
+// credentials of their profile. The lighttr command registers its auth
+// profile store at startup.
+func RegisterAuthProfiles(lookup AuthProfileFunc) {
+	authProfiles.Lock()
+	defer authProfiles.Unlock()
+	authProfiles.lookup = lookup
+}
+
+// ResolveProfile returns the auth a request sends. For profile auth, that
+// is the credentials of the profile, with Profile still naming it, and the
+// request's CA and certificate verification settings unless the profile
+// has its own. Other auth is returned as is.
+func (a AuthData) ResolveProfile() (AuthData, error) {
+	if a.Type != ProfileAuth {
+		return a, nil
+	}
+	authProfiles.RLock()
+	lookup := authProfiles.lookup
+	authProfiles.RUnlock()
+	if lookup == nil {
+		return a, fmt.Errorf("auth profiles are not available: no profile store is registered")
+	}
+
+	resolved, err := lookup(a.Profile)
+	if err != nil {
+		return a, err
+	}
+	if resolved.Type == ProfileAuth {
+		return a, fmt.Errorf("auth profile %s refers to another profile", a.Profile)
+	}
+	resolved.Profile = a.Profile
+	if resolved.CAFile == "" {
+		resolved.CAFile = a.CAFile
+		resolved.CAFileOnly = a.CAFileOnly
+	}
+	resolved.InsecureSkipVerify = resolved.InsecureSkipVerify || a.InsecureSkipVerify
+	return resolved, nil
+}
@@ -0,0 +1,93 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAuthProfiles registers the profiles as the auth profile store for
+// the test
+func fakeAuthProfiles(t *testing.T, profiles map[string]AuthData) {
+	RegisterAuthProfiles(func(name string) (AuthData, error) {
+		auth, ok := profiles[name]
+		if !ok {
+			return AuthData{}, fmt.Errorf("auth profile not found: %s", name)
+		}
+		return auth, nil
+	})
+	t.Cleanup(func() { RegisterAuthProfiles(nil) })
+}
+
+func TestRequestData_Execute_AuthProfile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		fmt.Fprintf(w, "%s:%s", user, password)
+	}))
+	defer server.Close()
+
+	fakeAuthProfiles(t, map[string]AuthData{
+		"billing": {Type: BasicAuth, Username: "jane", Password: "s3cret"},
+		"loop":    {Type: ProfileAuth, Profile: "billing"},
+	})
+
+	// The request's certificate verification settings still apply
+	req := &RequestData{
+		Method: "GET",
+		URL:    server.URL,
+		Auth:   AuthData{Type: ProfileAuth, Profile: "billing", InsecureSkipVerify: true},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Body != "jane:s3cret" {
+		t.Errorf("Expected the profile's credentials to be sent, got %q %q", resp.Error, resp.Body)
+	}
+	if req.Auth.Type != ProfileAuth || req.Auth.Password != "" {
+		t.Errorf("Expected the request to keep referencing the profile, got %+v", req.Auth)
+	}
+
+	for profile, want := range map[string]string{
+		"":        "profile is required for profile authentication",
+		"missing": "auth profile not found: missing",
+		"loop":    "auth profile loop refers to another profile",
+	} {
+		req.Auth.Profile = profile
+		if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for profile %q, got %v", want, profile, err)
+		}
+	}
+}
+
+func TestAuthData_ResolveProfile(t *testing.T) {
+	auth := AuthData{Type: ProfileAuth, Profile: "billing", CAFile: "/etc/ca.pem", CAFileOnly: true}
+	if _, err := auth.ResolveProfile(); err == nil || !strings.Contains(err.Error(), "no profile store is registered") {
+		t.Errorf("Expected an error without a profile store, got %v", err)
+	}
+
+	fakeAuthProfiles(t, map[string]AuthData{
+		"billing": {Type: BearerAuth, Token: "t"},
+		"private": {Type: MutualTLSAuth, CertFile: "client.p12", CAFile: "/etc/private-ca.pem"},
+	})
+	resolved, err := auth.ResolveProfile()
+	if err != nil {
+		t.Fatalf("ResolveProfile() error = %v", err)
+	}
+	want := AuthData{Type: BearerAuth, Token: "t", Profile: "billing", CAFile: "/etc/ca.pem", CAFileOnly: true}
+	if resolved != want {
+		t.Errorf("ResolveProfile() = %+v, want %+v", resolved, want)
+	}
+
+	auth.Profile = "private"
+	if resolved, _ := auth.ResolveProfile(); resolved.CAFile != "/etc/private-ca.pem" || resolved.CAFileOnly {
+		t.Errorf("Expected the profile's own CA file to be kept, got %+v", resolved)
+	}
+
+	basic := AuthData{Type: BasicAuth, Username: "jane"}
+	if resolved, err := basic.ResolveProfile(); err != nil || resolved != basic {
+		t.Errorf("Expected other auth to be returned as is, got %+v, %v", resolved, err)
+	}
+}
@@ -0,0 +1,63 @@
+package request
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// providedAuth are the auth types whose credentials come from a registered
+// provider rather than from the request
+var providedAuth = []AuthType{OAuth2Auth, GCPAuth, AzureAuth, NegotiateAuth}
+
+// authProviders holds the middleware registered for auth types
+var authProviders struct {
+	sync.RWMutex
+	middleware map[AuthType]Middleware
+}
+
+// RegisterAuth sets the middleware that adds the credentials of requests
+// with the auth type, replacing any registered before. It runs for every
+// attempt, right after the retry stage. Requests with oauth2, gcp, azure,
+// or negotiate auth fail before they are sent unless a provider is
+// registered for their type; the lighttr command registers its token
+// stores at startup.
+func RegisterAuth(authType AuthType, middleware Middleware) {
+	authProviders.Lock()
+	defer authProviders.Unlock()
+	if middleware == nil {
+		delete(authProviders.middleware, authType)
+		return
+	}
+	if authProviders.middleware == nil {
+		authProviders.middleware = make(map[AuthType]Middleware)
+	}
+	authProviders.middleware[authType] = middleware
+}
+
+// authProvider returns the middleware registered for the auth type, or nil
+func authProvider(authType AuthType) Middleware {
+	authProviders.RLock()
+	defer authProviders.RUnlock()
+	return authProviders.middleware[authType]
+}
+
+// checkAuthProvider returns an error when the auth type needs a provider
+// and none is registered
+func checkAuthProvider(authType AuthType) error {
+	if slices.Contains(providedAuth, authType) && authProvider(authType) == nil {
+		return fmt.Errorf("no provider registered for %s auth", authType)
+	}
+	return nil
+}
+
+// providedCredentials runs the provider registered for the request's auth
+// type, if any
+func providedCredentials(next Handler) Handler {
+	return func(x *Exchange) error {
+		if provider := authProvider(x.Data.Auth.Type); provider != nil {
+			return provider(next)(x)
+		}
+		return next(x)
+	}
+}
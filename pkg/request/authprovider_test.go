@@ -0,0 +1,64 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterAuth(t *testing.T) {
+	var requests int
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	// Without a provider the request is not sent rather than sent without
+	// credentials
+	for _, auth := range []AuthData{
+		{Type: OAuth2Auth, OAuth2Profile: "github"},
+		{Type: GCPAuth},
+		{Type: AzureAuth},
+		{Type: NegotiateAuth},
+	} {
+		req := NewRequestData()
+		req.URL = server.URL
+		req.Auth = auth
+		want := "no provider registered for " + string(auth.Type) + " auth"
+		if _, err := req.Execute(); err == nil || err.Error() != want {
+			t.Errorf("Execute() with %s auth error = %v, want %s", auth.Type, err, want)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to be sent, got %d", requests)
+	}
+
+	RegisterAuth(GCPAuth, func(next Handler) Handler {
+		return func(x *Exchange) error {
+			x.Request.Header.Set("Authorization", "Bearer ya29")
+			return next(x)
+		}
+	})
+	t.Cleanup(func() { RegisterAuth(GCPAuth, nil) })
+
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Auth = AuthData{Type: GCPAuth}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if authorization != "Bearer ya29" {
+		t.Errorf("Expected the provider's token to be sent, got %q", authorization)
+	}
+
+	// Providers only see requests with their auth type
+	req.Auth = AuthData{Type: BearerAuth, Token: "abc"}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if authorization != "Bearer abc" {
+		t.Errorf("Expected the request's own token, got %q", authorization)
+	}
+}
@@ -0,0 +1,632 @@
+package request
+
+import (
+	"bytes"
+	"cmp"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// avroMagic starts an Avro object container file
+var avroMagic = []byte("Obj\x01")
+
+// avroType is a type of an Avro schema
+type avroType struct {
+	kind string // a primitive, record, enum, array, map, union, or fixed
+	name string // full name of records, enums, and fixed types
+
+	fields   []avroField
+	symbols  []string
+	items    *avroType // of arrays and the values of maps
+	branches []*avroType
+	size     int
+}
+
+// avroField is a field of a record
+type avroField struct {
+	name       string
+	typ        *avroType
+	hasDefault bool
+}
+
+// avroPrimitives are the Avro types without attributes
+var avroPrimitives = []string{"null", "boolean", "int", "long", "float", "double", "bytes", "string"}
+
+// String returns the name of the type
+func (t *avroType) String() string {
+	switch t.kind {
+	case "record", "enum", "fixed":
+		return t.name
+	case "array":
+		return "array<" + t.items.String() + ">"
+	case "map":
+		return "map<" + t.items.String() + ">"
+	case "union":
+		names := make([]string, len(t.branches))
+		for i, branch := range t.branches {
+			names[i] = branch.String()
+		}
+		return "[" + strings.Join(names, ", ") + "]"
+	}
+	return t.kind
+}
+
+// parseAvroSchema parses an .avsc schema. When name is given, the named
+// type it defines is returned instead of the schema itself.
+func parseAvroSchema(data []byte, name string) (*avroType, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	named := make(map[string]*avroType)
+	t, err := parseAvroType(raw, "", named)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return t, nil
+	}
+	if t, ok := named[name]; ok {
+		return t, nil
+	}
+	for full, t := range named {
+		if strings.HasSuffix(full, "."+name) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("type %s not found", name)
+}
+
+// parseAvroType parses a type definition. Named types are added to named
+// as they are defined, so that they can be referenced by name afterwards
+// and from within themselves.
+func parseAvroType(raw interface{}, namespace string, named map[string]*avroType) (*avroType, error) {
+	switch v := raw.(type) {
+	case string:
+		if slices.Contains(avroPrimitives, v) {
+			return &avroType{kind: v}, nil
+		}
+		for _, candidate := range []string{avroFullName(v, namespace), v} {
+			if t, ok := named[candidate]; ok {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown type %q", v)
+
+	case []interface{}:
+		union := &avroType{kind: "union"}
+		for _, branch := range v {
+			t, err := parseAvroType(branch, namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			if t.kind == "union" {
+				return nil, fmt.Errorf("unions cannot contain unions")
+			}
+			union.branches = append(union.branches, t)
+		}
+		return union, nil
+
+	case map[string]interface{}:
+		kind, _ := v["type"].(string)
+		switch kind {
+		case "record", "error", "enum", "fixed":
+		case "array", "map":
+			attribute := map[string]string{"array": "items", "map": "values"}[kind]
+			items, err := parseAvroType(v[attribute], namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			return &avroType{kind: kind, items: items}, nil
+		default:
+			// A primitive with attributes such as a logicalType, or a
+			// nested definition
+			return parseAvroType(v["type"], namespace, named)
+		}
+
+		name, _ := v["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s without a name", kind)
+		}
+		if ns, ok := v["namespace"].(string); ok && !strings.Contains(name, ".") {
+			namespace = ns
+		}
+		t := &avroType{kind: kind, name: avroFullName(name, namespace)}
+		if i := strings.LastIndex(t.name, "."); i >= 0 {
+			namespace = t.name[:i]
+		}
+		named[t.name] = t
+
+		switch kind {
+		case "record", "error":
+			t.kind = "record"
+			fields, _ := v["fields"].([]interface{})
+			for _, rawField := range fields {
+				field, _ := rawField.(map[string]interface{})
+				fieldName, _ := field["name"].(string)
+				if fieldName == "" {
+					return nil, fmt.Errorf("field of %s without a name", t.name)
+				}
+				typ, err := parseAvroType(field["type"], namespace, named)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %v", t.name, fieldName, err)
+				}
+				_, hasDefault := field["default"]
+				t.fields = append(t.fields, avroField{name: fieldName, typ: typ, hasDefault: hasDefault})
+			}
+		case "enum":
+			symbols, _ := v["symbols"].([]interface{})
+			for _, symbol := range symbols {
+				s, _ := symbol.(string)
+				t.symbols = append(t.symbols, s)
+			}
+		case "fixed":
+			size, _ := v["size"].(float64)
+			t.size = int(size)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("invalid type definition %v", raw)
+}
+
+// avroFullName qualifies a name with a namespace unless it already has one
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// avroDecoder reads values in the Avro binary encoding
+type avroDecoder struct {
+	data []byte
+	pos  int
+
+	empty int64 // items read so far that take no bytes
+}
+
+// maxAvroEmptyItems caps the items that take no bytes, such as nulls, in a
+// body. Their count is all that is encoded, so nothing else bounds it.
+const maxAvroEmptyItems = 1 << 20
+
+// errAvroTruncated is returned when the data ends in the middle of a value
+var errAvroTruncated = fmt.Errorf("unexpected end of data")
+
+func (d *avroDecoder) long() (int64, error) {
+	value, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errAvroTruncated
+	}
+	d.pos += n
+	return value, nil
+}
+
+// items checks the count of a block of items of the given type against
+// the size bytes left for them
+func (d *avroDecoder) items(count int64, t *avroType, size int) error {
+	if count < 0 {
+		return fmt.Errorf("invalid block count %d", count)
+	}
+	if !t.empty(nil) {
+		if count > int64(size) {
+			return fmt.Errorf("block of %d items exceeds the remaining %d bytes", count, size)
+		}
+		return nil
+	}
+	if count > maxAvroEmptyItems-d.empty {
+		return fmt.Errorf("more than %d items of %s", maxAvroEmptyItems, t)
+	}
+	d.empty += count
+	return nil
+}
+
+// empty reports whether values of the type are encoded in no bytes
+func (t *avroType) empty(seen map[*avroType]bool) bool {
+	switch t.kind {
+	case "null":
+		return true
+	case "fixed":
+		return t.size == 0
+	case "record":
+		if seen[t] {
+			return true
+		}
+		if seen == nil {
+			seen = make(map[*avroType]bool)
+		}
+		seen[t] = true
+		for _, field := range t.fields {
+			if !field.typ.empty(seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (d *avroDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errAvroTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// validateAvroBinary checks a single datum, or an object container file,
+// in the Avro binary encoding
+func validateAvroBinary(t *avroType, data []byte, errs *schemaErrors) {
+	if bytes.HasPrefix(data, avroMagic) {
+		validateAvroContainer(t, data, errs)
+		return
+	}
+	d := &avroDecoder{data: data}
+	if d.validate(t, "", errs) && d.pos < len(data) {
+		errs.add("", "%d bytes left after the %s value", len(data)-d.pos, t)
+	}
+}
+
+// validateAvroContainer checks every datum in an object container file
+// against the schema, rather than the one the file was written with. The
+// null and deflate codecs are supported.
+func validateAvroContainer(t *avroType, data []byte, errs *schemaErrors) {
+	d := &avroDecoder{data: data, pos: len(avroMagic)}
+	metadata, err := d.metadata()
+	if err != nil {
+		errs.add("", "invalid container header: %v", err)
+		return
+	}
+	codec := cmp.Or(metadata["avro.codec"], "null")
+	if codec != "null" && codec != "deflate" {
+		errs.add("", "unsupported Avro codec %s", codec)
+		return
+	}
+	if _, err := d.read(16); err != nil {
+		errs.add("", "truncated container header")
+		return
+	}
+
+	index := 0
+	for d.pos < len(data) {
+		count, block, err := d.block(codec)
+		if err != nil {
+			errs.add("", "block after item %d: %v", index, err)
+			return
+		}
+		if err := d.items(count, t, len(block)); err != nil {
+			errs.add("", "block after item %d: %v", index, err)
+			return
+		}
+		blockDecoder := &avroDecoder{data: block, empty: d.empty}
+		for range count {
+			if !blockDecoder.validate(t, "["+strconv.Itoa(index)+"]", errs) {
+				return
+			}
+			index++
+		}
+		d.empty = blockDecoder.empty
+	}
+}
+
+// block reads a block of an object container file, returning the number
+// of items in it and their decompressed encoding
+func (d *avroDecoder) block(codec string) (int64, []byte, error) {
+	count, err := d.long()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := d.long()
+	if err != nil {
+		return 0, nil, err
+	}
+	block, err := d.read(int(size))
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := d.read(16); err != nil {
+		return 0, nil, err
+	}
+	if codec == "deflate" {
+		if block, err = io.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+			return 0, nil, err
+		}
+	}
+	return count, block, nil
+}
+
+// metadata reads the map of an object container file header
+func (d *avroDecoder) metadata() (map[string]string, error) {
+	metadata := make(map[string]string)
+	for {
+		count, err := d.long()
+		if err != nil || count == 0 {
+			return metadata, err
+		}
+		if count < 0 {
+			count = -count
+			if _, err := d.long(); err != nil {
+				return nil, err
+			}
+		}
+		for range count {
+			var entry [2]string
+			for i := range entry {
+				length, err := d.long()
+				if err != nil {
+					return nil, err
+				}
+				b, err := d.read(int(length))
+				if err != nil {
+					return nil, err
+				}
+				entry[i] = string(b)
+			}
+			metadata[entry[0]] = entry[1]
+		}
+	}
+}
+
+// validate decodes a value of the type, reporting the first mismatch and
+// returning false when decoding cannot go on
+func (d *avroDecoder) validate(t *avroType, path string, errs *schemaErrors) bool {
+	fail := func(err error) bool {
+		errs.add(path, "%s: %v", t, err)
+		return false
+	}
+
+	switch t.kind {
+	case "null":
+	case "boolean":
+		b, err := d.read(1)
+		if err != nil {
+			return fail(err)
+		}
+		if b[0] > 1 {
+			errs.add(path, "invalid boolean byte %d", b[0])
+		}
+	case "int", "long":
+		n, err := d.long()
+		if err != nil {
+			return fail(err)
+		}
+		if t.kind == "int" && (n < math.MinInt32 || n > math.MaxInt32) {
+			errs.add(path, "value %d out of range for int", n)
+		}
+	case "float":
+		if _, err := d.read(4); err != nil {
+			return fail(err)
+		}
+	case "double":
+		if _, err := d.read(8); err != nil {
+			return fail(err)
+		}
+	case "bytes", "string":
+		length, err := d.long()
+		if err != nil {
+			return fail(err)
+		}
+		b, err := d.read(int(length))
+		if err != nil {
+			return fail(fmt.Errorf("length %d exceeds the remaining %d bytes", length, len(d.data)-d.pos))
+		}
+		if t.kind == "string" && !utf8.Valid(b) {
+			errs.add(path, "string is not valid UTF-8")
+		}
+	case "fixed":
+		if _, err := d.read(t.size); err != nil {
+			return fail(err)
+		}
+	case "enum":
+		index, err := d.long()
+		if err != nil {
+			return fail(err)
+		}
+		if index < 0 || index >= int64(len(t.symbols)) {
+			errs.add(path, "enum index %d out of range for %s", index, t.name)
+		}
+	case "record":
+		for _, field := range t.fields {
+			if !d.validate(field.typ, path+"."+field.name, errs) {
+				return false
+			}
+		}
+	case "union":
+		index, err := d.long()
+		if err != nil {
+			return fail(err)
+		}
+		if index < 0 || index >= int64(len(t.branches)) {
+			return fail(fmt.Errorf("union index %d out of range", index))
+		}
+		return d.validate(t.branches[index], path, errs)
+	case "array", "map":
+		for i := 0; ; {
+			count, err := d.long()
+			if err != nil {
+				return fail(err)
+			}
+			if count == 0 {
+				break
+			}
+			if count < 0 {
+				// A negative count is followed by the size of the block
+				count = -count
+				if _, err := d.long(); err != nil {
+					return fail(err)
+				}
+			}
+			// Map items start with their key, so only arrays can hold
+			// items that take no bytes
+			items := t.items
+			if t.kind == "map" {
+				items = &avroType{kind: "string"}
+			}
+			if err := d.items(count, items, len(d.data)-d.pos); err != nil {
+				return fail(err)
+			}
+			for range count {
+				itemPath := path + "[" + strconv.Itoa(i) + "]"
+				if t.kind == "map" {
+					key := d.pos
+					if !d.validate(&avroType{kind: "string"}, itemPath, errs) {
+						return false
+					}
+					itemPath = path + "[" + strconv.Quote(avroMapKey(d.data[key:d.pos])) + "]"
+				}
+				if !d.validate(t.items, itemPath, errs) {
+					return false
+				}
+				i++
+			}
+		}
+	}
+	return true
+}
+
+// avroMapKey returns the text of an encoded map key
+func avroMapKey(encoded []byte) string {
+	_, n := binary.Varint(encoded)
+	return string(encoded[n:])
+}
+
+// validateJSON checks a value against the type. Union values may be
+// wrapped in an object naming the branch, as in the Avro JSON encoding, or
+// written bare as most APIs do.
+func (t *avroType) validateJSON(value interface{}, path string, errs *schemaErrors) {
+	switch t.kind {
+	case "null":
+		if value != nil {
+			errs.add(path, "expected null, got %s", jsonKind(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs.add(path, "expected a boolean, got %s", jsonKind(value))
+		}
+	case "int", "long":
+		n, ok := value.(json.Number)
+		if !ok {
+			errs.add(path, "expected %s, got %s", t.kind, jsonKind(value))
+			return
+		}
+		bits := 64
+		if t.kind == "int" {
+			bits = 32
+		}
+		if _, err := strconv.ParseInt(string(n), 10, bits); err != nil {
+			errs.add(path, "%s is not a valid %s", n, t.kind)
+		}
+	case "float", "double":
+		if _, ok := value.(json.Number); !ok {
+			errs.add(path, "expected a number, got %s", jsonKind(value))
+		}
+	case "string", "bytes":
+		if _, ok := value.(string); !ok {
+			errs.add(path, "expected a string, got %s", jsonKind(value))
+		}
+	case "fixed":
+		s, ok := value.(string)
+		if !ok {
+			errs.add(path, "expected a string, got %s", jsonKind(value))
+		} else if n := utf8.RuneCountInString(s); n != t.size {
+			errs.add(path, "expected %d bytes for %s, got %d", t.size, t.name, n)
+		}
+	case "enum":
+		s, ok := value.(string)
+		if !ok || !slices.Contains(t.symbols, s) {
+			errs.add(path, "expected one of %s, got %s", strings.Join(t.symbols, ", "), formatJSONValue(value))
+		}
+	case "record":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			errs.add(path, "expected %s object, got %s", t.name, jsonKind(value))
+			return
+		}
+		for _, field := range t.fields {
+			fieldValue, present := object[field.name]
+			if !present {
+				if !field.hasDefault {
+					errs.add(path+"."+field.name, "missing field without a default")
+				}
+				continue
+			}
+			field.typ.validateJSON(fieldValue, path+"."+field.name, errs)
+		}
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			if !slices.ContainsFunc(t.fields, func(f avroField) bool { return f.name == key }) {
+				errs.add(path+"."+key, "unknown field of %s", t.name)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			errs.add(path, "expected an array, got %s", jsonKind(value))
+			return
+		}
+		for i, item := range items {
+			t.items.validateJSON(item, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case "map":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			errs.add(path, "expected an object, got %s", jsonKind(value))
+			return
+		}
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			t.items.validateJSON(object[key], path+"["+strconv.Quote(key)+"]", errs)
+		}
+	case "union":
+		t.validateUnionJSON(value, path, errs)
+	}
+}
+
+// validateUnionJSON accepts a value matching any branch of a union
+func (t *avroType) validateUnionJSON(value interface{}, path string, errs *schemaErrors) {
+	if object, ok := value.(map[string]interface{}); ok && len(object) == 1 {
+		for key, wrapped := range object {
+			for _, branch := range t.branches {
+				if branch.String() == key || branch.kind == "record" && strings.HasSuffix(branch.name, "."+key) {
+					branch.validateJSON(wrapped, path, errs)
+					return
+				}
+			}
+		}
+	}
+
+	// When only one branch has the value's shape, report what is wrong
+	// inside it rather than just the mismatch
+	var nested []schemaErrors
+	for _, branch := range t.branches {
+		var branchErrs schemaErrors
+		branch.validateJSON(value, path, &branchErrs)
+		if len(branchErrs) == 0 {
+			return
+		}
+		if branchErrs[0].Path != path {
+			nested = append(nested, branchErrs)
+		}
+	}
+	if len(nested) == 1 {
+		*errs = append(*errs, nested[0]...)
+		return
+	}
+	errs.add(path, "%s matches no branch of %s", jsonKind(value), t)
+}
+
+// formatJSONValue renders a decoded JSON value in errors
+func formatJSONValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
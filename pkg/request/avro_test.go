@@ -0,0 +1,179 @@
+package request
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testAvroSchema = `{
+  "type": "record", "name": "User", "namespace": "shop",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "email", "type": ["null", "string"], "default": null},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+    {"name": "manager", "type": ["null", "User"], "default": null}
+  ]
+}`
+
+// avroString appends a string in the Avro binary encoding
+func avroString(b []byte, s string) []byte {
+	return append(binary.AppendVarint(b, int64(len(s))), s...)
+}
+
+// testAvroUser encodes a User without a manager
+func testAvroUser() []byte {
+	b := binary.AppendVarint(nil, 42)
+	b = avroString(b, "Jane")
+	b = binary.AppendVarint(b, 1)
+	b = avroString(b, "jane@example.com")
+	b = binary.AppendVarint(b, 2)
+	b = avroString(avroString(b, "a"), "b")
+	b = binary.AppendVarint(b, 0)
+	b = binary.AppendVarint(b, 1)
+	return binary.AppendVarint(b, 0)
+}
+
+func loadTestAvro(t *testing.T) *Schema {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "user.avsc")
+	if err := os.WriteFile(path, []byte(testAvroSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	if schema.String() != "shop.User" {
+		t.Errorf("Expected shop.User, got %s", schema)
+	}
+	return schema
+}
+
+func TestSchema_AvroBinary(t *testing.T) {
+	schema := loadTestAvro(t)
+
+	if errs := schema.Validate(string(testAvroUser()), "avro/binary"); len(errs) != 0 {
+		t.Errorf("Expected a valid datum, got %v", errs)
+	}
+
+	user := testAvroUser()
+	if got := schemaErrorStrings(schema.Validate(string(user[:10]), "avro/binary")); len(got) != 1 || got[0] != ".email: string: length 16 exceeds the remaining 2 bytes" {
+		t.Errorf("Expected a truncation error, got %q", got)
+	}
+	if got := schemaErrorStrings(schema.Validate(string(append(user, 0, 0)), "avro/binary")); len(got) != 1 || got[0] != "2 bytes left after the shop.User value" {
+		t.Errorf("Expected trailing bytes to be reported, got %q", got)
+	}
+	bad := binary.AppendVarint(nil, 1)
+	bad = avroString(bad, "Jo")
+	bad = binary.AppendVarint(bad, 5)
+	if got := schemaErrorStrings(schema.Validate(string(bad), "avro/binary")); len(got) != 1 || got[0] != ".email: [null, string]: union index 5 out of range" {
+		t.Errorf("Expected a union error, got %q", got)
+	}
+
+	// An object container file with two users
+	file := append([]byte{}, avroMagic...)
+	file = binary.AppendVarint(file, 1)
+	file = avroString(avroString(file, "avro.codec"), "null")
+	file = binary.AppendVarint(file, 0)
+	sync := make([]byte, 16)
+	file = append(file, sync...)
+	block := append(testAvroUser(), testAvroUser()...)
+	file = binary.AppendVarint(file, 2)
+	file = binary.AppendVarint(file, int64(len(block)))
+	file = append(append(file, block...), sync...)
+	if errs := schema.Validate(string(file), "application/octet-stream"); len(errs) != 0 {
+		t.Errorf("Expected a valid container file, got %v", errs)
+	}
+}
+
+func TestSchema_AvroBlockCounts(t *testing.T) {
+	load := func(schema string) *Schema {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "items.avsc")
+		if err := os.WriteFile(path, []byte(schema), 0644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := LoadSchema(path)
+		if err != nil {
+			t.Fatalf("LoadSchema() error = %v", err)
+		}
+		return s
+	}
+	nulls := load(`{"type": "array", "items": "null"}`)
+	longs := load(`{"type": "array", "items": "long"}`)
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		data   []byte
+		want   string
+	}{
+		{
+			name:   "items that take no bytes are capped",
+			schema: nulls,
+			data:   binary.AppendVarint(nil, 1<<40),
+			want:   "array<null>: more than 1048576 items of null",
+		},
+		{
+			name:   "count beyond the data",
+			schema: longs,
+			data:   binary.AppendVarint(nil, 1<<40),
+			want:   "array<long>: block of 1099511627776 items exceeds the remaining 0 bytes",
+		},
+		{
+			name:   "negative count that cannot be negated",
+			schema: longs,
+			data:   binary.AppendVarint(binary.AppendVarint(nil, math.MinInt64), 0),
+			want:   "array<long>: invalid block count -9223372036854775808",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schemaErrorStrings(tt.schema.Validate(string(tt.data), "avro/binary"))
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A few nulls are still fine
+	data := binary.AppendVarint(binary.AppendVarint(nil, 3), 0)
+	if errs := nulls.Validate(string(data), "avro/binary"); len(errs) != 0 {
+		t.Errorf("Expected a valid array of nulls, got %v", errs)
+	}
+}
+
+func TestSchema_AvroJSON(t *testing.T) {
+	schema := loadTestAvro(t)
+
+	for _, body := range []string{
+		`{"id": 42, "name": "Jane", "email": {"string": "jane@example.com"}, "tags": [], "role": "USER", "manager": null}`,
+		`{"id": 42, "name": "Jane", "email": "jane@example.com", "tags": ["a"], "role": "ADMIN",
+		  "manager": {"id": 1, "name": "Ann", "tags": [], "role": "ADMIN"}}`,
+	} {
+		if errs := schema.Validate(body, "application/json"); len(errs) != 0 {
+			t.Errorf("Expected %s to be valid, got %v", body, errs)
+		}
+	}
+
+	invalid := `{"id": 1.5, "name": 3, "email": 5, "tags": ["a", 1], "role": "ROOT", "extra": true,
+		"manager": {"id": 1, "name": "Ann", "tags": []}}`
+	want := []string{
+		".id: 1.5 is not a valid long",
+		".name: expected a string, got a number",
+		".email: a number matches no branch of [null, string]",
+		".tags[1]: expected a string, got a number",
+		`.role: expected one of ADMIN, USER, got "ROOT"`,
+		".manager.role: missing field without a default",
+		".extra: unknown field of shop.User",
+	}
+	if got := schemaErrorStrings(schema.Validate(invalid, "")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package request
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// hexPreviewSize is how much of a binary body HexPreview shows
+const hexPreviewSize = 256
+
+// binaryMediaTypes lists the non-text application types; image, audio,
+// video and font types are always binary
+var binaryMediaTypes = []string{
+	"application/octet-stream", "application/pdf", "application/zip", "application/gzip",
+	"application/x-gzip", "application/x-tar", "application/x-7z-compressed", "application/wasm",
+	"application/x-protobuf", "application/protobuf", "application/vnd.ms-excel", "application/msword",
+}
+
+// isBinaryBody reports whether a body should be treated as bytes rather than
+// text, going by its Content-Type and, when that is missing or unknown, by
+// looking at the start of the body
+func isBinaryBody(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if isTextMediaType(mediaType) {
+		return false
+	}
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	if slices.Contains(binaryMediaTypes, mediaType) || strings.HasPrefix(mediaType, "application/vnd.openxmlformats") {
+		return true
+	}
+	return !looksLikeText(body)
+}
+
+// looksLikeText reports whether the start of a body is UTF-8 text without
+// control characters, or has a byte order mark
+func looksLikeText(body []byte) bool {
+	// UTF-16 text is full of zero bytes but starts with a byte order mark
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(body, bom.mark) {
+			return true
+		}
+	}
+	head := body[:min(len(body), 512)]
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size <= 1 {
+			// A character cut off by the end of the sample is fine
+			return len(head) < len(body) && !utf8.FullRune(head[i:])
+		}
+		if r < ' ' && !strings.ContainsRune("\t\n\r\f\x1b", r) || r == 0x7f {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// ContentType returns the Content-Type header of the response
+func (r *ResponseData) ContentType() string {
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, "Content-Type") {
+			return value
+		}
+	}
+	return ""
+}
+
+// BinarySummary describes a binary body, e.g. "image/png, 5120 bytes"
+func (r *ResponseData) BinarySummary() string {
+	contentType := r.ContentType()
+	if contentType == "" {
+		contentType = "unknown type"
+	}
+	return fmt.Sprintf("%s, %d bytes", contentType, len(r.Body))
+}
+
+// HexPreview returns a hexdump of the start of the body
+func (r *ResponseData) HexPreview() string {
+	preview := hex.Dump([]byte(r.Body[:min(len(r.Body), hexPreviewSize)]))
+	if more := len(r.Body) - hexPreviewSize; more > 0 {
+		preview += fmt.Sprintf("... %d more bytes\n", more)
+	}
+	return preview
+}
+
+// SaveBody writes the body bytes to a file
+func (r *ResponseData) SaveBody(path string) error {
+	if err := os.WriteFile(path, []byte(r.Body), 0644); err != nil {
+		return fmt.Errorf("failed to save body: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestIsBinaryBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"image", "image/png", pngHeader, true},
+		{"pdf", "application/pdf", "%PDF-1.7", true},
+		{"octet-stream", "application/octet-stream", "hello", true},
+		{"json", "application/json", `{"name": "Jane"}`, false},
+		{"svg", "image/svg+xml", "<svg/>", false},
+		{"sniffed binary", "", pngHeader, true},
+		{"sniffed text", "", "hello\nworld\n", false},
+		{"unknown type with text", "application/x-custom", "name=Jane", false},
+		{"utf-16 text", "", "\xFF\xFEh\x00i\x00", false},
+		{"utf-8 cut off by the sample", "", strings.Repeat("a", 511) + "é", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBinaryBody(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("isBinaryBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseData_HexPreview(t *testing.T) {
+	resp := &ResponseData{Body: pngHeader}
+	want := "00000000  89 50 4e 47 0d 0a 1a 0a  00 00 00 0d 49 48 44 52  |.PNG........IHDR|\n"
+	if preview := resp.HexPreview(); preview != want {
+		t.Errorf("HexPreview() = %q, want %q", preview, want)
+	}
+
+	resp.Body = strings.Repeat("\x00", hexPreviewSize+10)
+	if preview := resp.HexPreview(); !strings.HasSuffix(preview, "... 10 more bytes\n") {
+		t.Errorf("Expected the preview to note the remaining bytes, got %q", preview)
+	}
+}
+
+func TestRequestData_BinaryResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(pngHeader))
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = server.URL
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !resp.Binary {
+		t.Fatal("Expected the response to be flagged as binary")
+	}
+	if summary := resp.BinarySummary(); summary != "image/png, 16 bytes" {
+		t.Errorf("BinarySummary() = %q", summary)
+	}
+
+	path := filepath.Join(t.TempDir(), "logo.png")
+	if err := resp.SaveBody(path); err != nil {
+		t.Fatalf("SaveBody() error = %v", err)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != pngHeader {
+		t.Errorf("Expected the saved file to hold the body bytes, got %q", saved)
+	}
+}
@@ -0,0 +1,77 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// localAddresses returns the addresses to bind outgoing connections to for
+// an IP address or the name of a network interface
+func localAddresses(spec string) ([]net.IP, error) {
+	if ip := net.ParseIP(strings.Trim(spec, "[]")); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	iface, err := net.InterfaceByName(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interface %q: not an IP address or a network interface", spec)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the addresses of %s: %v", spec, err)
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		// Link-local IPv6 addresses need a zone to be bound
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("interface %s has no address to bind to", spec)
+	}
+	return ips, nil
+}
+
+// bindDialer returns a dial function that connects from one of the local
+// addresses, of the same family as the address dialed. Host names are
+// dialed from an IPv4 address when there is one, which limits them to their
+// IPv4 addresses.
+func bindDialer(ips []net.IP, dialer *net.Dialer) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := *dialer
+		d.LocalAddr = &net.TCPAddr{IP: pickLocalAddress(ips, network, addr)}
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// familyDialer wraps dial so that host names are resolved to, and
+// connections made to, only IPv4 or only IPv6 addresses
+func familyDialer(version int, dial dialFunc) dialFunc {
+	if version == 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" {
+			network = fmt.Sprintf("tcp%d", version)
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// pickLocalAddress chooses the local address for a connection to addr
+func pickLocalAddress(ips []net.IP, network, addr string) net.IP {
+	wantIPv6 := strings.HasSuffix(network, "6")
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			wantIPv6 = ip.To4() == nil
+		}
+	}
+	for _, ip := range ips {
+		if (ip.To4() == nil) == wantIPv6 {
+			return ip
+		}
+	}
+	return ips[0]
+}
@@ -0,0 +1,112 @@
+package request
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestData_Interface(t *testing.T) {
+	var remote string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}))
+	defer server.Close()
+
+	// The whole of 127.0.0.0/8 is loopback on Linux, so a second source
+	// address is available without configuring one
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Interface = "127.0.0.2"
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.Contains(resp.Error, "assign requested address") {
+		t.Skip("127.0.0.2 is not available on this host")
+	}
+	if resp.Error != "" || remote != "127.0.0.2" {
+		t.Errorf("Expected the request to come from 127.0.0.2, got %q (%s)", remote, resp.Error)
+	}
+
+	// An interface name binds its address
+	loopback := loopbackInterface(t)
+	req.Interface = loopback
+	if resp, err := req.Execute(); err != nil || resp.Error != "" || remote != "127.0.0.1" {
+		t.Errorf("Expected the request to come from %s's 127.0.0.1, got %q (%+v, %v)", loopback, remote, resp, err)
+	}
+
+	req.Interface = "no-such-interface0"
+	if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), "invalid interface") {
+		t.Errorf("Expected an unknown interface to be rejected, got %v", err)
+	}
+}
+
+// loopbackInterface returns the name of the loopback interface
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skipf("cannot list interfaces: %v", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
+
+func TestRequestData_IPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	// The address connected to is reported with or without a restriction
+	req := NewRequestData()
+	req.URL = server.URL
+	if resp, err := req.Execute(); err != nil || resp.RemoteAddr != "127.0.0.1:"+port {
+		t.Errorf("Expected the remote address to be reported, got %+v (%v)", resp, err)
+	}
+
+	req.URL = "http://localhost:" + port
+	req.IPVersion = 4
+	if resp, err := req.Execute(); err != nil || resp.Error != "" || resp.RemoteAddr != "127.0.0.1:"+port {
+		t.Errorf("Expected localhost to resolve to 127.0.0.1, got %+v (%v)", resp, err)
+	}
+
+	req.URL = server.URL
+	req.IPVersion = 6
+	if resp, err := req.Execute(); err != nil || resp.Error == "" {
+		t.Errorf("Expected an IPv4 address to be refused over IPv6, got %+v (%v)", resp, err)
+	}
+
+	req.IPVersion = 5
+	if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), "invalid ip_version") {
+		t.Errorf("Expected an unknown IP version to be rejected, got %v", err)
+	}
+}
+
+func TestPickLocalAddress(t *testing.T) {
+	v4, v6 := net.ParseIP("192.0.2.10"), net.ParseIP("2001:db8::10")
+	tests := []struct {
+		ips     []net.IP
+		network string
+		addr    string
+		want    net.IP
+	}{
+		{[]net.IP{v6, v4}, "tcp", "203.0.113.1:443", v4},
+		{[]net.IP{v4, v6}, "tcp", "[2001:db8::1]:443", v6},
+		{[]net.IP{v6, v4}, "tcp", "api.example.com:443", v4},
+		{[]net.IP{v4, v6}, "tcp6", "api.example.com:443", v6},
+		{[]net.IP{v6}, "tcp", "api.example.com:443", v6},
+	}
+	for _, tt := range tests {
+		if got := pickLocalAddress(tt.ips, tt.network, tt.addr); !got.Equal(tt.want) {
+			t.Errorf("pickLocalAddress(%v, %s, %s) = %v, want %v", tt.ips, tt.network, tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// setBodyFile streams the file as the request body. GetBody reopens the
+// file so redirects and retries can send it again. Without a Content-Type
+// header, one is guessed from the file extension.
+func setBodyFile(req *http.Request, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to open body file: %v", err)
+	}
+
+	open := func() (io.ReadCloser, error) {
+		if info.Size() == 0 {
+			return http.NoBody, nil
+		}
+		return os.Open(path)
+	}
+	body, err := open()
+	if err != nil {
+		return fmt.Errorf("failed to open body file: %v", err)
+	}
+	req.Body = body
+	req.GetBody = open
+	req.ContentLength = info.Size()
+
+	if req.Header.Get("Content-Type") == "" {
+		if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+	}
+	return nil
+}
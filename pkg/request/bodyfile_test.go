@@ -0,0 +1,106 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestData_BodyFile(t *testing.T) {
+	var bodies []string
+	var contentTypes []string
+	var lengths []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		lengths = append(lengths, r.ContentLength)
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "payload.json")
+	payload := `{"items": [` + strings.Repeat(`"x",`, 1000) + `"x"]}`
+	if err := os.WriteFile(file, []byte(payload), 0644); err != nil {
+		t.Fatalf("Failed to write body file: %v", err)
+	}
+
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL
+	req.BodyFile = file
+	req.Retry = &RetryPolicy{MaxAttempts: 2}
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(bodies) != 2 {
+		t.Fatalf("Expected the retry to succeed, got %d after %d attempts", resp.StatusCode, len(bodies))
+	}
+	for i := range bodies {
+		if bodies[i] != payload {
+			t.Errorf("Attempt %d sent %d bytes, want the whole file", i+1, len(bodies[i]))
+		}
+		if lengths[i] != int64(len(payload)) {
+			t.Errorf("Attempt %d Content-Length = %d, want %d", i+1, lengths[i], len(payload))
+		}
+		if contentTypes[i] != "application/json" {
+			t.Errorf("Attempt %d Content-Type = %q, want guessed application/json", i+1, contentTypes[i])
+		}
+	}
+
+	// An explicit Content-Type is kept
+	bodies, contentTypes = nil, nil
+	req.Retry = nil
+	req.Headers["Content-Type"] = "application/octet-stream"
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if contentTypes[0] != "application/octet-stream" {
+		t.Errorf("Content-Type = %q, want the request header", contentTypes[0])
+	}
+}
+
+func TestRequestData_ValidateBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "payload.bin")
+	os.WriteFile(file, []byte{0, 1, 2}, 0644)
+
+	tests := []struct {
+		name     string
+		body     string
+		bodyFile string
+		wantErr  string
+	}{
+		{"existing file", "", file, ""},
+		{"missing file", "", filepath.Join(dir, "missing.bin"), "body file does not exist"},
+		{"directory", "", dir, "body file is a directory"},
+		{"body and file", "inline", file, "cannot both be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.URL = "https://api.example.com"
+			req.Body = tt.body
+			req.BodyFile = tt.bodyFile
+			err := req.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
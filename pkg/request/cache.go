@@ -0,0 +1,330 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheHeaders lists, in the order they are worth reading, the response
+// headers that decide how caches and CDNs treat a response
+var CacheHeaders = []string{
+	"Cache-Control", "CDN-Cache-Control", "Surrogate-Control", "Pragma", "Expires",
+	"Date", "Age", "ETag", "Last-Modified", "Vary", "Set-Cookie",
+	"X-Cache", "CF-Cache-Status", "X-Cache-Status",
+}
+
+// heuristicStatuses are the statuses caches may store without explicit
+// freshness, guessing a lifetime (RFC 9110, section 15.1)
+var heuristicStatuses = map[int]bool{
+	200: true, 203: true, 204: true, 206: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// CacheAnalysis explains how a shared cache, such as a CDN or a caching
+// proxy, treats the response to a request
+type CacheAnalysis struct {
+	StatusCode int `json:"status_code"`
+
+	// Headers holds the response headers of CacheHeaders that were set
+	Headers map[string]string `json:"headers"`
+
+	// Shared is whether a shared cache would store the response, and
+	// SharedReason why
+	Shared       bool   `json:"shared"`
+	SharedReason string `json:"shared_reason"`
+
+	// Lifetime is how long the response is fresh for in a shared cache and
+	// LifetimeSource where that comes from. Age is how old the response
+	// already was when received, and TTL how long it stays fresh; once the
+	// TTL runs out caches revalidate the response before reusing it.
+	Lifetime       time.Duration `json:"lifetime"`
+	LifetimeSource string        `json:"lifetime_source"`
+	Age            time.Duration `json:"age"`
+	TTL            time.Duration `json:"ttl"`
+
+	// Revalidation is what sending the request again with the response's
+	// validators got
+	Revalidation CacheRevalidation `json:"revalidation"`
+
+	// Notes points out headers that commonly make caching go wrong
+	Notes []string `json:"notes,omitempty"`
+}
+
+// CacheRevalidation is the result of sending a request again, conditional
+// on the validators of the first response
+type CacheRevalidation struct {
+	// Validators holds the conditional headers sent, If-None-Match and
+	// If-Modified-Since; without them the request was sent unchanged
+	Validators map[string]string `json:"validators,omitempty"`
+
+	StatusCode int `json:"status_code,omitempty"`
+
+	// Succeeded is set when the server answered 304 Not Modified, so a
+	// cache can keep serving its copy
+	Succeeded bool `json:"succeeded"`
+
+	// Result explains the response
+	Result string `json:"result"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// AnalyzeCaching sends the request, explains whether and for how long a
+// shared cache would keep the response, then sends it again with the
+// response's ETag and Last-Modified to check that the server revalidates
+// it. The middleware runs on both requests.
+func (r *RequestData) AnalyzeCaching(middleware ...Middleware) (*CacheAnalysis, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	authorized := false
+	checkAuthorization := func(next Handler) Handler {
+		return func(x *Exchange) error {
+			if x.Request.Header.Get("Authorization") != "" {
+				authorized = true
+			}
+			return next(x)
+		}
+	}
+	resp, err := r.ExecuteWith(append(append([]Middleware{}, middleware...), checkAuthorization)...)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	analysis := analyzeCaching(strings.ToUpper(r.Method), authorized, resp, time.Now())
+
+	validators := make(map[string]string)
+	if etag := resp.Headers["Etag"]; etag != "" {
+		validators["If-None-Match"] = etag
+	}
+	if modified := resp.Headers["Last-Modified"]; modified != "" {
+		validators["If-Modified-Since"] = modified
+	}
+	conditional := func(next Handler) Handler {
+		return func(x *Exchange) error {
+			for name, value := range validators {
+				x.Request.Header.Set(name, value)
+			}
+			return next(x)
+		}
+	}
+	again, err := r.ExecuteWith(append(append([]Middleware{}, middleware...), conditional)...)
+	if err != nil {
+		return nil, err
+	}
+	analysis.Revalidation = revalidation(validators, resp, again)
+	return analysis, nil
+}
+
+// analyzeCaching explains how a shared cache treats resp, received at now
+// for a request with method, which carried an Authorization header when
+// authorized is set
+func analyzeCaching(method string, authorized bool, resp *ResponseData, now time.Time) *CacheAnalysis {
+	a := &CacheAnalysis{StatusCode: resp.StatusCode, Headers: make(map[string]string)}
+	for _, name := range CacheHeaders {
+		if value := resp.Headers[http.CanonicalHeaderKey(name)]; value != "" {
+			a.Headers[name] = value
+		}
+	}
+	directives := parseCacheControl(a.Headers["Cache-Control"])
+
+	date, hasDate := parseHTTPDate(a.Headers["Date"])
+	if !hasDate {
+		date = now
+	}
+	a.Lifetime, a.LifetimeSource = freshnessLifetime(directives, a.Headers, resp.StatusCode, date, &a.Notes)
+	if age, err := strconv.Atoi(a.Headers["Age"]); err == nil && age > 0 {
+		a.Age = time.Duration(age) * time.Second
+	}
+	apparent := now.Sub(date).Truncate(time.Second)
+	if hasDate && (apparent-a.Age > time.Minute || apparent < -time.Minute) {
+		a.Notes = append(a.Notes, fmt.Sprintf("the server's Date is %v off this machine's clock, which skews the age caches compute", (apparent-a.Age).Abs()))
+	}
+	a.Age = max(a.Age, apparent)
+	a.TTL = a.Lifetime - a.Age
+
+	explicit := a.LifetimeSource != "" && !strings.HasPrefix(a.LifetimeSource, "heuristic")
+	switch {
+	case method != "GET" && method != "HEAD":
+		a.SharedReason = method + " responses are not stored by shared caches"
+	case directives.has("no-store"):
+		a.SharedReason = "Cache-Control: no-store"
+	case directives.has("private"):
+		a.SharedReason = "Cache-Control: private allows only the browser's own cache to store it"
+	case authorized && !directives.has("public") && !directives.has("s-maxage") && !directives.has("must-revalidate"):
+		a.SharedReason = "the request has an Authorization header, and responses to it are only shared with public, s-maxage, or must-revalidate"
+	case directives.has("no-cache"):
+		a.Shared = true
+		a.SharedReason = "stored, but Cache-Control: no-cache requires revalidating it before every reuse"
+	case directives.has("public"):
+		a.Shared = true
+		a.SharedReason = "Cache-Control: public"
+	case explicit:
+		a.Shared = true
+		a.SharedReason = "it has an explicit lifetime from " + a.LifetimeSource
+	case heuristicStatuses[resp.StatusCode]:
+		a.Shared = true
+		a.SharedReason = fmt.Sprintf("status %d is cacheable by default, for a lifetime the cache guesses", resp.StatusCode)
+	default:
+		a.SharedReason = fmt.Sprintf("it has no lifetime, and status %d is not cacheable by default", resp.StatusCode)
+	}
+
+	if a.Shared && a.Headers["Set-Cookie"] != "" {
+		a.Notes = append(a.Notes, "Set-Cookie on a response shared caches store: some CDNs serve the cookie to every user, others refuse to cache the response")
+	}
+	for _, name := range splitList(a.Headers["Vary"]) {
+		switch strings.ToLower(name) {
+		case "*":
+			a.Notes = append(a.Notes, "Vary: * keeps caches from ever reusing the response")
+		case "cookie", "user-agent", "authorization":
+			a.Notes = append(a.Notes, fmt.Sprintf("Vary: %s splits the cache per client, so it is rarely hit", name))
+		}
+	}
+	if a.Headers["Pragma"] != "" && a.Headers["Cache-Control"] == "" {
+		a.Notes = append(a.Notes, "Pragma is ignored on responses: use Cache-Control")
+	}
+	for _, name := range []string{"CDN-Cache-Control", "Surrogate-Control"} {
+		if a.Headers[name] != "" {
+			a.Notes = append(a.Notes, fmt.Sprintf("CDNs that support %s follow it instead of Cache-Control", name))
+		}
+	}
+	if a.Shared && a.Headers["ETag"] == "" && a.Headers["Last-Modified"] == "" {
+		a.Notes = append(a.Notes, "no ETag or Last-Modified: once stale, the response can't be revalidated and is downloaded again")
+	}
+	return a
+}
+
+// freshnessLifetime returns how long a shared cache keeps the response
+// fresh and where that comes from, or an empty source when it has no
+// lifetime. Headers caches can't parse are noted.
+func freshnessLifetime(directives cacheDirectives, headers map[string]string, status int, date time.Time, notes *[]string) (time.Duration, string) {
+	if directives.has("no-cache") {
+		return 0, "no-cache"
+	}
+	for _, name := range []string{"s-maxage", "max-age"} {
+		value, ok := directives[name]
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			*notes = append(*notes, fmt.Sprintf("%s=%s is not a number of seconds, so caches treat the response as stale", name, value))
+			return 0, name
+		}
+		return time.Duration(seconds) * time.Second, name
+	}
+	if value := headers["Expires"]; value != "" {
+		expires, ok := parseHTTPDate(value)
+		if !ok {
+			*notes = append(*notes, fmt.Sprintf("Expires %q is not an HTTP date, so caches treat the response as stale", value))
+			return 0, "Expires"
+		}
+		return max(0, expires.Sub(date)), "Expires"
+	}
+	if modified, ok := parseHTTPDate(headers["Last-Modified"]); ok && heuristicStatuses[status] && modified.Before(date) {
+		return (date.Sub(modified) / 10).Truncate(time.Second), "heuristic: 10% of the time since Last-Modified"
+	}
+	return 0, ""
+}
+
+// revalidation explains the response again to a request sent with the
+// validators of the first response
+func revalidation(validators map[string]string, first, again *ResponseData) CacheRevalidation {
+	rv := CacheRevalidation{StatusCode: again.StatusCode}
+	if len(validators) > 0 {
+		rv.Validators = validators
+	}
+	if again.Error != "" {
+		rv.Error = again.Error
+		rv.Result = "the request failed"
+		return rv
+	}
+
+	etag, newETag := first.Headers["Etag"], again.Headers["Etag"]
+	switch {
+	case len(validators) == 0:
+		rv.Result = "the response has no ETag or Last-Modified to revalidate it with"
+		if etag == "" && newETag != "" {
+			rv.Result += fmt.Sprintf(", but the second response has ETag %s", newETag)
+		}
+	case again.StatusCode == http.StatusNotModified:
+		rv.Succeeded = true
+		rv.Result = "304 Not Modified: caches can keep serving their copy"
+		if etag != "" && newETag != "" && newETag != etag {
+			rv.Result += fmt.Sprintf(", though the 304 has ETag %s instead of %s", newETag, etag)
+		}
+	case again.StatusCode == http.StatusPreconditionFailed:
+		rv.Result = "412 Precondition Failed: the server applied If-None-Match as a precondition instead of a cache validator"
+	case etag != "" && newETag != "" && newETag != etag:
+		rv.Result = fmt.Sprintf("%d with a new ETag %s: the ETag changes between requests, e.g. because servers behind a load balancer or compression generate their own, so revalidation never succeeds", again.StatusCode, newETag)
+	case again.StatusCode >= 200 && again.StatusCode < 300:
+		rv.Result = fmt.Sprintf("%d with the full response: the server ignores the validators, so caches download it again instead of revalidating it", again.StatusCode)
+	default:
+		rv.Result = fmt.Sprintf("%d: the response changed", again.StatusCode)
+	}
+	return rv
+}
+
+// cacheDirectives maps the lowercase directives of a Cache-Control header
+// to their values, without quotes
+type cacheDirectives map[string]string
+
+func (d cacheDirectives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// parseCacheControl parses a Cache-Control header, keeping the first of
+// repeated directives
+func parseCacheControl(value string) cacheDirectives {
+	directives := make(cacheDirectives)
+	for _, item := range splitList(value) {
+		name, arg, _ := strings.Cut(item, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, seen := directives[name]; seen || name == "" {
+			continue
+		}
+		directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// splitList splits a comma separated header value, keeping the commas in
+// quoted strings, such as private="Set-Cookie, Authorization"
+func splitList(value string) []string {
+	var items []string
+	quoted := false
+	start := 0
+	for i := 0; i <= len(value); i++ {
+		if i < len(value) {
+			if value[i] == '"' {
+				quoted = !quoted
+			}
+			if value[i] != ',' || quoted {
+				continue
+			}
+		}
+		if item := strings.TrimSpace(value[start:i]); item != "" {
+			items = append(items, item)
+		}
+		start = i + 1
+	}
+	return items
+}
+
+// parseHTTPDate parses the date of a Date, Expires, or Last-Modified
+// header
+func parseHTTPDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
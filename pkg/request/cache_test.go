@@ -0,0 +1,257 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeCaching_Headers(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	date := now.Format(http.TimeFormat)
+
+	tests := []struct {
+		name       string
+		method     string
+		authorized bool
+		status     int
+		headers    map[string]string
+		shared     bool
+		reason     string
+		lifetime   time.Duration
+		source     string
+		ttl        time.Duration
+		note       string
+	}{
+		{
+			name:     "s-maxage wins over max-age",
+			headers:  map[string]string{"Cache-Control": "public, max-age=60, s-maxage=600", "Date": date, "Age": "100"},
+			shared:   true,
+			reason:   "Cache-Control: public",
+			lifetime: 10 * time.Minute,
+			source:   "s-maxage",
+			ttl:      500 * time.Second,
+			note:     "no ETag or Last-Modified",
+		},
+		{
+			name:     "Expires",
+			headers:  map[string]string{"Expires": now.Add(time.Hour).Format(http.TimeFormat), "Date": date, "Etag": `"v1"`},
+			shared:   true,
+			reason:   "it has an explicit lifetime from Expires",
+			lifetime: time.Hour,
+			source:   "Expires",
+			ttl:      time.Hour,
+		},
+		{
+			name:    "invalid Expires",
+			headers: map[string]string{"Expires": "0", "Date": date, "Etag": `"v1"`},
+			shared:  true,
+			reason:  "it has an explicit lifetime from Expires",
+			source:  "Expires",
+			note:    "not an HTTP date",
+		},
+		{
+			name:     "heuristic",
+			headers:  map[string]string{"Date": date, "Last-Modified": now.Add(-100 * time.Hour).Format(http.TimeFormat)},
+			shared:   true,
+			reason:   "status 200 is cacheable by default, for a lifetime the cache guesses",
+			lifetime: 10 * time.Hour,
+			source:   "heuristic: 10% of the time since Last-Modified",
+			ttl:      10 * time.Hour,
+		},
+		{
+			name:     "private",
+			headers:  map[string]string{"Cache-Control": `private="Set-Cookie, X-User", max-age=60`, "Date": date},
+			reason:   "Cache-Control: private allows only the browser's own cache to store it",
+			lifetime: time.Minute,
+			source:   "max-age",
+			ttl:      time.Minute,
+		},
+		{
+			name:    "no-store",
+			headers: map[string]string{"Cache-Control": "no-store", "Pragma": "no-cache"},
+			reason:  "Cache-Control: no-store",
+		},
+		{
+			name:       "authorized",
+			authorized: true,
+			headers:    map[string]string{"Cache-Control": "max-age=60", "Date": date},
+			reason:     "the request has an Authorization header, and responses to it are only shared with public, s-maxage, or must-revalidate",
+			lifetime:   time.Minute,
+			source:     "max-age",
+			ttl:        time.Minute,
+		},
+		{
+			name:    "no-cache",
+			headers: map[string]string{"Cache-Control": "no-cache", "Date": date, "Etag": `"v1"`, "Vary": "Accept-Encoding, User-Agent"},
+			shared:  true,
+			reason:  "stored, but Cache-Control: no-cache requires revalidating it before every reuse",
+			source:  "no-cache",
+			note:    "Vary: User-Agent splits the cache per client",
+		},
+		{
+			name:     "POST",
+			method:   "POST",
+			headers:  map[string]string{"Cache-Control": "max-age=60", "Date": date},
+			reason:   "POST responses are not stored by shared caches",
+			lifetime: time.Minute,
+			source:   "max-age",
+			ttl:      time.Minute,
+		},
+		{
+			name:    "uncacheable status",
+			status:  http.StatusInternalServerError,
+			headers: map[string]string{"Date": date},
+			reason:  "it has no lifetime, and status 500 is not cacheable by default",
+		},
+		{
+			name:     "Set-Cookie",
+			headers:  map[string]string{"Cache-Control": "max-age=60", "Date": date, "Etag": `"v1"`, "Set-Cookie": "session=1"},
+			shared:   true,
+			reason:   "it has an explicit lifetime from max-age",
+			lifetime: time.Minute,
+			source:   "max-age",
+			ttl:      time.Minute,
+			note:     "Set-Cookie",
+		},
+		{
+			name:     "clock skew",
+			headers:  map[string]string{"Cache-Control": "max-age=600", "Date": now.Add(-5 * time.Minute).Format(http.TimeFormat), "Etag": `"v1"`},
+			shared:   true,
+			reason:   "it has an explicit lifetime from max-age",
+			lifetime: 10 * time.Minute,
+			source:   "max-age",
+			ttl:      5 * time.Minute,
+			note:     "off this machine's clock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			a := analyzeCaching(method, tt.authorized, &ResponseData{StatusCode: status, Headers: tt.headers}, now)
+			if a.Shared != tt.shared || a.SharedReason != tt.reason {
+				t.Errorf("Shared = %v (%s), want %v (%s)", a.Shared, a.SharedReason, tt.shared, tt.reason)
+			}
+			if a.Lifetime != tt.lifetime || a.LifetimeSource != tt.source || a.TTL != tt.ttl {
+				t.Errorf("Lifetime = %v (%s), TTL = %v, want %v (%s), TTL = %v", a.Lifetime, a.LifetimeSource, a.TTL, tt.lifetime, tt.source, tt.ttl)
+			}
+			notes := strings.Join(a.Notes, "\n")
+			if (tt.note == "") != (notes == "") || !strings.Contains(notes, tt.note) {
+				t.Errorf("Notes = %q, want one containing %q", a.Notes, tt.note)
+			}
+		})
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	got := parseCacheControl(`Public, max-age=60, private="Set-Cookie, X-User", max-age=5,`)
+	want := cacheDirectives{"public": "", "max-age": "60", "private": "Set-Cookie, X-User"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCacheControl() = %v, want %v", got, want)
+	}
+}
+
+func TestRequestData_AnalyzeCaching(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		validator map[string]string
+		succeeded bool
+		result    string
+	}{
+		{
+			name: "revalidated",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "public, max-age=300")
+				w.Header().Set("ETag", `"v1"`)
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Write([]byte("hello"))
+			},
+			validator: map[string]string{"If-None-Match": `"v1"`},
+			succeeded: true,
+			result:    "304 Not Modified",
+		},
+		{
+			name: "changing ETag",
+			handler: func() http.HandlerFunc {
+				n := 0
+				return func(w http.ResponseWriter, r *http.Request) {
+					n++
+					w.Header().Set("ETag", `"`+strings.Repeat("v", n)+`"`)
+					w.Write([]byte("hello"))
+				}
+			}(),
+			validator: map[string]string{"If-None-Match": `"v"`},
+			result:    `200 with a new ETag "vv"`,
+		},
+		{
+			name: "validators ignored",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Last-Modified", "Mon, 02 Mar 2026 10:00:00 GMT")
+				w.Write([]byte("hello"))
+			},
+			validator: map[string]string{"If-Modified-Since": "Mon, 02 Mar 2026 10:00:00 GMT"},
+			result:    "200 with the full response: the server ignores the validators",
+		},
+		{
+			name: "no validators",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			result: "the response has no ETag or Last-Modified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			req := NewRequestData()
+			req.URL = server.URL
+			analysis, err := req.AnalyzeCaching()
+			if err != nil {
+				t.Fatalf("AnalyzeCaching() error = %v", err)
+			}
+			rv := analysis.Revalidation
+			if !reflect.DeepEqual(rv.Validators, tt.validator) {
+				t.Errorf("Validators = %v, want %v", rv.Validators, tt.validator)
+			}
+			if rv.Succeeded != tt.succeeded || !strings.HasPrefix(rv.Result, tt.result) {
+				t.Errorf("Revalidation = %v (%s), want %v (%s...)", rv.Succeeded, rv.Result, tt.succeeded, tt.result)
+			}
+		})
+	}
+}
+
+func TestRequestData_AnalyzeCaching_Authorization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Auth = AuthData{Type: BearerAuth, Token: "secret"}
+	analysis, err := req.AnalyzeCaching()
+	if err != nil {
+		t.Fatalf("AnalyzeCaching() error = %v", err)
+	}
+	if analysis.Shared || !strings.Contains(analysis.SharedReason, "Authorization") {
+		t.Errorf("Expected a response to an authorized request not to be shared, got %v (%s)", analysis.Shared, analysis.SharedReason)
+	}
+}
@@ -0,0 +1,105 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Where the charset of a body was found
+const (
+	CharsetFromHeader = "Content-Type"
+	CharsetFromBOM    = "byte order mark"
+	CharsetFromMeta   = "document"
+)
+
+var (
+	// metaCharsetPattern matches <meta charset="..."> and the charset in
+	// <meta http-equiv="Content-Type" content="text/html; charset=...">
+	metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([A-Za-z0-9_.:\-]+)`)
+
+	// xmlEncodingPattern matches the encoding of an XML declaration
+	xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]+encoding\s*=\s*["']([A-Za-z0-9_.:\-]+)["']`)
+)
+
+// byteOrderMarks maps byte order marks to their encodings
+var byteOrderMarks = []struct {
+	mark    []byte
+	charset string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// detectCharset returns the charset of a text body and where it was found:
+// the Content-Type charset, a byte order mark, or for markup a <meta> tag or
+// XML declaration near the start. It returns an empty name for bodies that
+// are not text or declare no charset.
+func detectCharset(contentType string, body []byte) (string, string) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if charset := params["charset"]; charset != "" {
+		return strings.ToLower(charset), CharsetFromHeader
+	}
+	if contentType != "" && !isTextMediaType(mediaType) {
+		return "", ""
+	}
+
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(body, bom.mark) {
+			return bom.charset, CharsetFromBOM
+		}
+	}
+
+	head := body[:min(len(body), 1024)]
+	if match := xmlEncodingPattern.FindSubmatch(bytes.TrimSpace(head)); match != nil {
+		return strings.ToLower(string(match[1])), CharsetFromMeta
+	}
+	if match := metaCharsetPattern.FindSubmatch(head); match != nil {
+		return strings.ToLower(string(match[1])), CharsetFromMeta
+	}
+	return "", ""
+}
+
+// isTextMediaType reports whether a media type carries text
+func isTextMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, suffix := range []string{"json", "xml", "javascript", "ecmascript", "x-www-form-urlencoded"} {
+		if strings.HasSuffix(mediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// transcodeBody converts a body in the named charset to UTF-8 and drops a
+// byte order mark. It reports whether the body was converted from another
+// charset; bodies in unknown charsets are returned as they are.
+func transcodeBody(body []byte, charset string) ([]byte, bool) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return body, false
+	}
+	converted := false
+	if name, _ := htmlindex.Name(enc); name != "utf-8" {
+		decoded, err := enc.NewDecoder().Bytes(body)
+		if err != nil {
+			return body, false
+		}
+		converted = !bytes.Equal(decoded, body)
+		body = decoded
+	}
+	return bytes.TrimPrefix(body, byteOrderMarks[0].mark), converted
+}
+
+// CharsetSummary describes the charset the body was converted from, e.g.
+// "shift_jis from Content-Type, converted to UTF-8"
+func (r *ResponseData) CharsetSummary() string {
+	return fmt.Sprintf("%s from %s, converted to UTF-8", r.Charset, r.CharsetSource)
+}
@@ -0,0 +1,108 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		charset     string
+		source      string
+	}{
+		{"header", "text/html; charset=Shift_JIS", "", "shift_jis", CharsetFromHeader},
+		{"header wins over meta", "text/html; charset=utf-8", `<meta charset="iso-8859-1">`, "utf-8", CharsetFromHeader},
+		{"utf-8 bom", "text/plain", "\xEF\xBB\xBFhello", "utf-8", CharsetFromBOM},
+		{"utf-16le bom", "", "\xFF\xFEh\x00", "utf-16le", CharsetFromBOM},
+		{"meta charset", "text/html", `<html><head><meta charset="ISO-8859-1"></head>`, "iso-8859-1", CharsetFromMeta},
+		{"meta http-equiv", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">`, "windows-1252", CharsetFromMeta},
+		{"xml declaration", "application/xml", `<?xml version="1.0" encoding="Shift_JIS"?><a/>`, "shift_jis", CharsetFromMeta},
+		{"binary", "image/png", `<meta charset="iso-8859-1">`, "", ""},
+		{"undeclared", "application/json", `{"name": "Jane"}`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charset, source := detectCharset(tt.contentType, []byte(tt.body))
+			if charset != tt.charset || source != tt.source {
+				t.Errorf("detectCharset() = %q, %q, want %q, %q", charset, source, tt.charset, tt.source)
+			}
+		})
+	}
+}
+
+func TestTranscodeBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		charset   string
+		want      string
+		converted bool
+	}{
+		{"latin-1", "caf\xE9", "iso-8859-1", "café", true},
+		{"shift_jis", "\x93\xFA\x96\x7B", "shift_jis", "日本", true},
+		{"utf-16le", "\xFF\xFEh\x00i\x00", "utf-16le", "hi", true},
+		{"ascii in latin-1", "hello", "iso-8859-1", "hello", false},
+		{"utf-8 bom", "\xEF\xBB\xBFcafé", "utf-8", "café", false},
+		{"unknown charset", "caf\xE9", "x-unknown", "caf\xE9", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, converted := transcodeBody([]byte(tt.body), tt.charset)
+			if string(got) != tt.want || converted != tt.converted {
+				t.Errorf("transcodeBody() = %q, %v, want %q, %v", got, converted, tt.want, tt.converted)
+			}
+		})
+	}
+}
+
+func TestRequestData_TranscodesResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/plain; charset=ISO-8859-1")
+			w.Write([]byte("caf\xE9"))
+		case "/meta":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<meta charset="shift_jis"><p>` + "\x93\xFA\x96\x7B</p>"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name": "café"}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path    string
+		body    string
+		summary string
+	}{
+		{"/latin1", "café", "iso-8859-1 from Content-Type, converted to UTF-8"},
+		{"/meta", `<meta charset="shift_jis"><p>日本</p>`, "shift_jis from document, converted to UTF-8"},
+		{"/utf8", `{"name": "café"}`, ""},
+	}
+
+	for _, tt := range tests {
+		req := NewRequestData()
+		req.URL = server.URL + tt.path
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if resp.Body != tt.body {
+			t.Errorf("%s: Body = %q, want %q", tt.path, resp.Body, tt.body)
+		}
+		if tt.summary == "" {
+			if resp.Charset != "" {
+				t.Errorf("%s: expected no charset, got %q", tt.path, resp.Charset)
+			}
+		} else if summary := resp.CharsetSummary(); summary != tt.summary {
+			t.Errorf("%s: CharsetSummary() = %q, want %q", tt.path, summary, tt.summary)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StoredCookie is a cookie as persisted by a CookieJar, along with the URL
+// of the response that set it
+type StoredCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires,omitzero"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// expired reports whether the cookie should no longer be sent
+func (c StoredCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// key identifies the cookie the way browsers do, so a later Set-Cookie
+// replaces an earlier one
+func (c StoredCookie) key() string {
+	domain := strings.TrimPrefix(c.Domain, ".")
+	if domain == "" {
+		// Host-only cookies belong to the host that set them
+		if u, err := url.Parse(c.URL); err == nil {
+			domain = u.Hostname()
+		}
+	}
+	return strings.ToLower(domain) + ";" + c.Path + ";" + c.Name
+}
+
+func (c StoredCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+}
+
+// CookieJar stores cookies from Set-Cookie responses and sends them on
+// later requests to the same domain. A jar opened with LoadCookieJar can be
+// saved so cookies survive across runs. It is safe for concurrent use.
+type CookieJar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	cookies []StoredCookie
+	path    string
+}
+
+// NewCookieJar creates an empty in-memory cookie jar
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &CookieJar{jar: jar}
+}
+
+// LoadCookieJar opens the cookie jar persisted at path, starting empty if
+// the file does not exist yet
+func LoadCookieJar(path string) (*CookieJar, error) {
+	j := NewCookieJar()
+	j.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return j, nil
+		}
+		return nil, err
+	}
+
+	var cookies []StoredCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, fmt.Errorf("failed to parse cookie jar: %v", err)
+	}
+
+	now := time.Now()
+	for _, c := range cookies {
+		u, err := url.Parse(c.URL)
+		if err != nil || c.expired(now) {
+			continue
+		}
+		j.jar.SetCookies(u, []*http.Cookie{c.cookie()})
+		j.cookies = append(j.cookies, c)
+	}
+	return j, nil
+}
+
+// SetCookies implements http.CookieJar
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, c := range cookies {
+		stored := StoredCookie{
+			URL:      u.String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if stored.Path == "" || !strings.HasPrefix(stored.Path, "/") {
+			stored.Path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case c.MaxAge < 0:
+			stored.Expires = now
+		case c.MaxAge > 0:
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+
+		j.remove(stored.key())
+		if !stored.expired(now) {
+			j.cookies = append(j.cookies, stored)
+		}
+	}
+}
+
+// Cookies implements http.CookieJar
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// Stored returns the cookies in the jar that have not expired
+func (j *CookieJar) Stored() []StoredCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var cookies []StoredCookie
+	for _, c := range j.cookies {
+		if !c.expired(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
+
+// Save writes the jar back to the file it was loaded from. Jars created
+// with NewCookieJar are not persisted and Save does nothing.
+func (j *CookieJar) Save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	cookies := j.Stored()
+	if cookies == nil {
+		cookies = []StoredCookie{}
+	}
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Cookies often carry session credentials
+	return os.WriteFile(j.path, data, 0600)
+}
+
+// remove drops the stored cookie with the given key
+func (j *CookieJar) remove(key string) {
+	for i, c := range j.cookies {
+		if c.key() == key {
+			j.cookies = append(j.cookies[:i], j.cookies[i+1:]...)
+			return
+		}
+	}
+}
+
+// defaultCookiePath is the path a cookie without a Path attribute applies
+// to (RFC 6265 section 5.1.4)
+func defaultCookiePath(urlPath string) string {
+	i := strings.LastIndex(urlPath, "/")
+	if !strings.HasPrefix(urlPath, "/") || i == 0 {
+		return "/"
+	}
+	return urlPath[:i]
+}
+
+// WithCookieJar returns middleware that sends the jar's cookies with the
+// request, including any redirects, and stores the cookies the server sets.
+// A nil jar leaves cookies untouched.
+func WithCookieJar(jar *CookieJar) Middleware {
+	return func(next Handler) Handler {
+		if jar == nil {
+			return next
+		}
+		return func(x *Exchange) error {
+			x.Jar = jar
+			return next(x)
+		}
+	}
+}
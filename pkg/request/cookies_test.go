@@ -0,0 +1,119 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithCookieJar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark", Path: "/", MaxAge: 3600})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", Path: "/", MaxAge: -1})
+		case "/redirect":
+			http.SetCookie(w, &http.Cookie{Name: "step", Value: "1", Path: "/"})
+			http.Redirect(w, r, "/me", http.StatusFound)
+			return
+		}
+		cookies := r.Cookies()
+		names := make([]string, len(cookies))
+		for i, c := range cookies {
+			names[i] = c.Name + "=" + c.Value
+		}
+		w.Write([]byte(strings.Join(names, ";")))
+	}))
+	defer server.Close()
+
+	send := func(jar *CookieJar, path string) string {
+		t.Helper()
+		req := NewRequestData()
+		req.URL = server.URL + path
+		resp, err := req.ExecuteWith(WithCookieJar(jar))
+		if err != nil {
+			t.Fatalf("ExecuteWith() error = %v", err)
+		}
+		return resp.Body
+	}
+
+	file := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := LoadCookieJar(file)
+	if err != nil {
+		t.Fatalf("LoadCookieJar() error = %v", err)
+	}
+
+	send(jar, "/login")
+	if got := send(jar, "/me"); got != "session=abc;theme=dark" {
+		t.Errorf("Cookies sent after login = %q", got)
+	}
+	if got := send(nil, "/me"); got != "" {
+		t.Errorf("Expected no cookies without a jar, got %q", got)
+	}
+	if got := send(jar, "/redirect"); !strings.Contains(got, "step=1") {
+		t.Errorf("Expected cookie set during a redirect to be sent, got %q", got)
+	}
+
+	if err := jar.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Expected cookie jar file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Cookie jar permissions = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A new jar loaded from the file continues the session
+	restored, err := LoadCookieJar(file)
+	if err != nil {
+		t.Fatalf("LoadCookieJar() error = %v", err)
+	}
+	if got := send(restored, "/me"); got != "session=abc;theme=dark;step=1" {
+		t.Errorf("Cookies sent from restored jar = %q", got)
+	}
+
+	// Deleted cookies are dropped from the jar and the file
+	send(restored, "/logout")
+	if got := send(restored, "/me"); got != "theme=dark;step=1" {
+		t.Errorf("Cookies sent after logout = %q", got)
+	}
+	if stored := restored.Stored(); len(stored) != 2 {
+		t.Errorf("Expected 2 stored cookies after logout, got %+v", stored)
+	}
+}
+
+func TestLoadCookieJar_Invalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cookies.json")
+	os.WriteFile(file, []byte("not json"), 0600)
+	if _, err := LoadCookieJar(file); err == nil {
+		t.Error("Expected error for malformed cookie jar")
+	}
+}
+
+func TestNewCookieJar_SaveIsNoop(t *testing.T) {
+	if err := NewCookieJar().Save(); err != nil {
+		t.Errorf("Save() error = %v", err)
+	}
+}
+
+func TestDefaultCookiePath(t *testing.T) {
+	tests := map[string]string{
+		"":          "/",
+		"/":         "/",
+		"/login":    "/",
+		"/api/":     "/api",
+		"/api/auth": "/api",
+	}
+	for urlPath, want := range tests {
+		if got := defaultCookiePath(urlPath); got != want {
+			t.Errorf("defaultCookiePath(%q) = %q, want %q", urlPath, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// ErrorKind classifies why a request could not reach the server
+type ErrorKind string
+
+// Kinds of failure ClassifyError recognizes
+const (
+	ErrorDNS                 ErrorKind = "dns"
+	ErrorRefused             ErrorKind = "connection-refused"
+	ErrorReset               ErrorKind = "connection-reset"
+	ErrorUnreachable         ErrorKind = "unreachable"
+	ErrorConnectTimeout      ErrorKind = "connect-timeout"
+	ErrorTLSTimeout          ErrorKind = "tls-timeout"
+	ErrorHeaderTimeout       ErrorKind = "response-header-timeout"
+	ErrorTimeout             ErrorKind = "timeout"
+	ErrorProxy               ErrorKind = "proxy"
+	ErrorTLSUnknownAuthority ErrorKind = "tls-unknown-authority"
+	ErrorTLSHostname         ErrorKind = "tls-hostname"
+	ErrorTLSExpired          ErrorKind = "tls-expired"
+	ErrorTLS                 ErrorKind = "tls"
+	ErrorSchemeMismatch      ErrorKind = "scheme-mismatch"
+)
+
+// diagnoses describes each kind of failure and what to try about it
+var diagnoses = map[ErrorKind]struct {
+	summary     string
+	suggestions []string
+}{
+	ErrorDNS: {"Host name does not resolve", []string{
+		"Check the host name for typos",
+		"Connect to the VPN if the host is on an internal network",
+		"Point the host at an address with --resolve host:port:address",
+	}},
+	ErrorRefused: {"Connection refused: nothing is listening on the port", []string{
+		"Check that the server is running and the port is right",
+		"Check whether the server expects http:// or https://",
+	}},
+	ErrorReset: {"Connection reset by the server", []string{
+		"The server or a proxy closed the connection; check its logs",
+		"Check whether the server expects http:// or https://",
+		"Retry transient failures with --retries",
+	}},
+	ErrorUnreachable: {"Network or host unreachable", []string{
+		"Check your network connection and VPN",
+		"Check that the address is reachable from this network",
+	}},
+	ErrorConnectTimeout: {"Timed out connecting to the server", []string{
+		"Check that a firewall or missing VPN is not dropping the connection",
+		"Send the request through a proxy with --proxy if the network requires one",
+		"Allow more time with --connect-timeout",
+	}},
+	ErrorTLSTimeout: {"Timed out in the TLS handshake", []string{
+		"Check whether the server speaks plain http:// on this port",
+		"Allow more time with --tls-timeout",
+	}},
+	ErrorHeaderTimeout: {"Server received the request but did not start responding in time", []string{
+		"The server is slow to process the request; check its logs",
+		"Allow more time with --response-header-timeout",
+		"Retry transient failures with --retries",
+	}},
+	ErrorTimeout: {"Server did not respond in time", []string{
+		"Allow more time with --timeout",
+		"Retry transient failures with --retries",
+	}},
+	ErrorProxy: {"Proxy connection failed", []string{
+		"Check the proxy URL and its credentials (--proxy, --proxy-user)",
+		"Reach the host without the proxy with --noproxy",
+	}},
+	ErrorTLSUnknownAuthority: {"Certificate signed by an unknown authority", []string{
+		"Trust the CA that issued the certificate with --ca-file",
+		"Skip certificate verification with --insecure, for testing only",
+	}},
+	ErrorTLSHostname: {"Certificate is not valid for this host name", []string{
+		"Use the host name the certificate was issued for in the URL",
+		"To reach a server by IP address, keep the host name and use --resolve host:port:address",
+	}},
+	ErrorTLSExpired: {"Certificate has expired or is not yet valid", []string{
+		"Check that the system clock is right",
+		"Renew the server certificate",
+		"Skip certificate verification with --insecure, for testing only",
+	}},
+	ErrorSchemeMismatch: {"Server speaks plain HTTP, not HTTPS", []string{
+		"Use http:// instead of https:// in the URL",
+	}},
+	ErrorTLS: {"TLS handshake failed", []string{
+		"Check whether the server speaks plain http:// on this port",
+		"For mutual TLS, check the client certificate and key",
+	}},
+}
+
+// Summary describes the kind of failure, e.g. "Host name does not resolve"
+func (k ErrorKind) Summary() string {
+	return diagnoses[k].summary
+}
+
+// Suggestions lists what to try about the kind of failure
+func (k ErrorKind) Suggestions() []string {
+	return diagnoses[k].suggestions
+}
+
+// ClassifyError returns the kind of a failure to reach the server, or an
+// empty kind for errors it does not recognize
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	// Proxy errors wrap the error of connecting to the proxy
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return ErrorProxy
+	}
+
+	var dnsErr *net.DNSError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, http.ErrSchemeMismatch):
+		return ErrorSchemeMismatch
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.As(err, &unknownAuthority):
+		return ErrorTLSUnknownAuthority
+	case errors.As(err, &hostnameErr):
+		return ErrorTLSHostname
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return ErrorTLSExpired
+	case errors.As(err, &invalidErr), errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return ErrorTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrorReset
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return ErrorUnreachable
+	case errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout():
+		return ErrorConnectTimeout
+	// net/http does not export the errors of its transport's timeouts
+	case errors.As(err, &netErr) && netErr.Timeout() && strings.Contains(netErr.Error(), "TLS handshake timeout"):
+		return ErrorTLSTimeout
+	case errors.As(err, &netErr) && netErr.Timeout() && strings.Contains(netErr.Error(), "timeout awaiting response headers"):
+		return ErrorHeaderTimeout
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	}
+	return ""
+}
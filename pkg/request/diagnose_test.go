@@ -0,0 +1,85 @@
+package request
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://api.example.com", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"dns", wrap(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "api.example.com", IsNotFound: true}}), ErrorDNS},
+		{"unknown authority", wrap(x509.UnknownAuthorityError{}), ErrorTLSUnknownAuthority},
+		{"hostname", wrap(x509.HostnameError{Host: "10.0.0.1", Certificate: &x509.Certificate{}}), ErrorTLSHostname},
+		{"expired", wrap(x509.CertificateInvalidError{Reason: x509.Expired}), ErrorTLSExpired},
+		{"connect timeout", wrap(&net.OpError{Op: "dial", Err: timeoutError{}}), ErrorConnectTimeout},
+		{"read timeout", wrap(&net.OpError{Op: "read", Err: timeoutError{}}), ErrorTimeout},
+		{"proxy", wrap(&net.OpError{Op: "proxyconnect", Err: &os.SyscallError{Syscall: "connect", Err: fmt.Errorf("refused")}}), ErrorProxy},
+		{"scheme mismatch", wrap(http.ErrSchemeMismatch), ErrorSchemeMismatch},
+		{"unrecognized", fmt.Errorf("something else"), ""},
+		{"nil", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("%s: ClassifyError() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequestData_ErrorKind(t *testing.T) {
+	// A port nothing listens on refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plainServer.Close()
+
+	tests := []struct {
+		url  string
+		want ErrorKind
+	}{
+		{closedURL, ErrorRefused},
+		{tlsServer.URL, ErrorTLSUnknownAuthority},
+		{strings.Replace(plainServer.URL, "http://", "https://", 1), ErrorSchemeMismatch},
+	}
+	for _, tt := range tests {
+		req := NewRequestData()
+		req.URL = tt.url
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if resp.ErrorKind != tt.want {
+			t.Errorf("%s: ErrorKind = %q, want %q (%s)", tt.url, resp.ErrorKind, tt.want, resp.Error)
+		}
+		if len(resp.ErrorKind.Suggestions()) == 0 || resp.ErrorKind.Summary() == "" {
+			t.Errorf("%s: expected a summary and suggestions for %q", tt.url, resp.ErrorKind)
+		}
+	}
+}
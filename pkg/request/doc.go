@@ -0,0 +1,76 @@
+// Package request builds, validates, and executes HTTP requests the way the
+// lighttr CLI and TUI do, so other Go programs can embed the same engine.
+//
+// A request is described by RequestData, which serializes to the JSON used
+// for saved requests and history:
+//
+//	req := request.NewRequestData()
+//	req.Method = "GET"
+//	req.URL = "https://api.example.com/users/{id}"
+//	req.PathParams["id"] = "42"
+//	req.Auth = request.AuthData{Type: request.BearerAuth, Token: token}
+//
+//	resp, err := req.Execute()
+//	if err != nil {
+//		// The request is invalid or could not be prepared
+//	}
+//	if resp.Error != "" {
+//		// The server could not be reached
+//	}
+//
+// Execute runs the request through a pipeline of stages: templating builds
+// the http.Request from the URL template, query parameters, and headers,
+// authentication adds credentials, retries re-sends it according to the
+// retry policy, and tracing records the timing breakdown around the
+// transport. ExecuteWith inserts additional Middleware between retries and
+// tracing, where it sees the finished request and the response of every
+// attempt:
+//
+//	logging := func(next request.Handler) request.Handler {
+//		return func(x *request.Exchange) error {
+//			err := next(x)
+//			log.Printf("%s %s: %d", x.Request.Method, x.Request.URL, x.Response.StatusCode)
+//			return err
+//		}
+//	}
+//	resp, err := req.ExecuteWith(logging)
+//
+// Middleware that every request should run, such as request signing or
+// metrics, can be registered once with Register instead of being passed to
+// each call. A Hook is the simpler form for code that only needs to look at
+// or adjust the request before it is sent and the response after:
+//
+//	request.Register(request.WithHook(request.HookFuncs{
+//		AfterFunc: func(resp *request.ResponseData) error {
+//			metrics.Observe(resp.StatusCode, resp.ResponseTime)
+//			return nil
+//		},
+//	}))
+//
+// Requests with oauth2, gcp, azure, or negotiate auth get their credentials
+// from a provider registered with RegisterAuth, and fail without one.
+//
+// WithTransport sends requests with a custom http.RoundTripper, such as an
+// instrumented transport, or a RoundTripperFunc that stubs the network in
+// tests:
+//
+//	stub := request.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+//	})
+//	resp, err := req.ExecuteWith(request.WithTransport(stub))
+//
+// ws:// and wss:// URLs are opened with DialWebSocket rather than sent. It
+// runs the handshake through the same templating, authentication, and
+// registered middleware and returns the connection:
+//
+//	ws, err := req.DialWebSocket()
+//	if err != nil {
+//		return err
+//	}
+//	defer ws.Close()
+//	err = ws.Send(`{"type": "subscribe"}`)
+//	msg, err := ws.Receive()
+//
+// The exported API of this package is kept backwards compatible; new
+// options are added as fields whose zero value keeps the existing behavior.
+package request
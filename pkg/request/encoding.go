@@ -0,0 +1,94 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is sent unless the request sets Accept-Encoding itself. It
+// lists only the codings decodeBody can undo. Asking explicitly turns off
+// the transport's transparent gzip handling so the compressed size can be
+// recorded.
+const acceptEncoding = "gzip, deflate, br"
+
+// setAcceptEncoding asks for the content codings decodeBody understands
+func setAcceptEncoding(req *http.Request) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+}
+
+// decodeBody undoes the content codings listed in a Content-Encoding header,
+// last applied first. When limit is positive, decoding stops after limit
+// bytes and decodeBody reports the body as truncated; a body already
+// truncated when received is decoded as far as it goes.
+func decodeBody(body []byte, contentEncoding string, limit int64, truncated bool) ([]byte, bool, error) {
+	d := &decoder{limit: limit, truncated: truncated}
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		var err error
+		switch coding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			body, err = d.read(gzip.NewReader(bytes.NewReader(body)))
+		case "deflate":
+			// deflate is zlib wrapped, but some servers send raw deflate
+			decoded, zerr := d.read(zlib.NewReader(bytes.NewReader(body)))
+			if zerr != nil {
+				decoded, err = d.read(flate.NewReader(bytes.NewReader(body)), nil)
+			}
+			body = decoded
+		case "br":
+			body, err = d.read(io.NopCloser(brotli.NewReader(bytes.NewReader(body))), nil)
+		default:
+			return nil, false, fmt.Errorf("unsupported content encoding %q", coding)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode %s body: %v", coding, err)
+		}
+	}
+	return body, d.truncated, nil
+}
+
+// decoder reads decompressors up to a size limit
+type decoder struct {
+	limit     int64
+	truncated bool
+}
+
+// read reads a decompressor opened by a constructor that can fail. The
+// stream of a truncated body ends early, which is not an error.
+func (d *decoder) read(reader io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, cut, err := readLimited(reader, d.limit)
+	if err == io.ErrUnexpectedEOF && d.truncated {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.truncated = d.truncated || cut
+	return data, nil
+}
+
+// EncodingSummary describes how the body was compressed, e.g.
+// "gzip (312 bytes, 1400 decompressed)"
+func (r *ResponseData) EncodingSummary() string {
+	if r.DecodeError != "" {
+		return fmt.Sprintf("%s (%d bytes, not decoded: %s)", r.ContentEncoding, r.CompressedSize, r.DecodeError)
+	}
+	return fmt.Sprintf("%s (%d bytes, %d decompressed)", r.ContentEncoding, r.CompressedSize, r.DecompressedSize)
+}
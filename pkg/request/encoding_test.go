@@ -0,0 +1,107 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestRequestData_DecodesCompressedBodies(t *testing.T) {
+	payload := strings.Repeat(`{"name": "Jane"}`, 100)
+	compress := func(encoding string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "raw-deflate":
+			w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		default:
+			return []byte(payload)
+		}
+		w.Write([]byte(payload))
+		w.Close()
+		return buf.Bytes()
+	}
+
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		encoding := r.URL.Query().Get("encoding")
+		header := encoding
+		if encoding == "raw-deflate" {
+			header = "deflate"
+		}
+		w.Header().Set("Content-Encoding", header)
+		w.Write(compress(encoding))
+	}))
+	defer server.Close()
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "br"} {
+		req := NewRequestData()
+		req.URL = server.URL
+		req.QueryParams["encoding"] = encoding
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if acceptEncoding != "gzip, deflate, br" {
+			t.Errorf("Accept-Encoding = %q, want gzip, deflate, br", acceptEncoding)
+		}
+		if resp.Body != payload || resp.DecodeError != "" {
+			t.Errorf("%s: expected the decoded body, got %q (%s)", encoding, resp.Body, resp.DecodeError)
+		}
+		if !strings.HasSuffix(resp.EncodingSummary(), ", 1600 decompressed)") {
+			t.Errorf("%s: unexpected summary %q", encoding, resp.EncodingSummary())
+		}
+		if resp.CompressedSize != int64(len(compress(encoding))) || resp.DecompressedSize != int64(len(payload)) {
+			t.Errorf("%s: sizes = %d/%d", encoding, resp.CompressedSize, resp.DecompressedSize)
+		}
+	}
+
+	// Unsupported codings are shown as received with an explanation
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Headers["Accept-Encoding"] = "zstd"
+	req.QueryParams["encoding"] = "zstd"
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if acceptEncoding != "zstd" {
+		t.Errorf("Expected the request's Accept-Encoding to be kept, got %q", acceptEncoding)
+	}
+	if resp.Body != payload || !strings.Contains(resp.DecodeError, `unsupported content encoding "zstd"`) {
+		t.Errorf("Expected the raw body and a decode error, got %q", resp.DecodeError)
+	}
+}
+
+func TestDecodeBody_Stacked(t *testing.T) {
+	var inner, outer bytes.Buffer
+	w := zlib.NewWriter(&inner)
+	w.Write([]byte("hello"))
+	w.Close()
+	g := gzip.NewWriter(&outer)
+	g.Write(inner.Bytes())
+	g.Close()
+
+	decoded, _, err := decodeBody(outer.Bytes(), "deflate, gzip", 0, false)
+	if err != nil || string(decoded) != "hello" {
+		t.Errorf("decodeBody() = %q, %v", decoded, err)
+	}
+	if _, _, err := decodeBody([]byte("not gzip"), "gzip", 0, false); err == nil {
+		t.Error("Expected an error for a corrupt body")
+	}
+}
@@ -0,0 +1,27 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// FormContentType is the Content-Type of urlencoded form bodies
+const FormContentType = "application/x-www-form-urlencoded"
+
+// EncodedForm returns the form params encoded as a urlencoded body, sorted
+// by name
+func (r *RequestData) EncodedForm() string {
+	form := url.Values{}
+	for name, value := range r.FormParams {
+		form.Set(name, value)
+	}
+	return form.Encode()
+}
+
+// setFormContentType sets the urlencoded Content-Type unless the request
+// headers already chose one, e.g. to add a charset
+func setFormContentType(req *http.Request) {
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", FormContentType)
+	}
+}
@@ -0,0 +1,57 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestData_FormParams(t *testing.T) {
+	var contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL
+	req.FormParams = map[string]string{"user": "jane doe", "note": "a&b=c"}
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if contentType != FormContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, FormContentType)
+	}
+	if body != "note=a%26b%3Dc&user=jane+doe" {
+		t.Errorf("Body = %q", body)
+	}
+
+	// An explicit Content-Type, e.g. with a charset, is kept
+	req.Headers["Content-Type"] = FormContentType + "; charset=UTF-8"
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if contentType != FormContentType+"; charset=UTF-8" {
+		t.Errorf("Content-Type = %q, want the explicit header", contentType)
+	}
+}
+
+func TestRequestData_ValidateFormParams(t *testing.T) {
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = "https://api.example.com"
+	req.FormParams = map[string]string{"user": "jane"}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	req.Body = "raw"
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "form params cannot be combined with a body") {
+		t.Errorf("Validate() error = %v, want a conflict with the body", err)
+	}
+}
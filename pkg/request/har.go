@@ -0,0 +1,396 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// harVersion is the HAR format version written by HARRecorder
+const harVersion = "1.2"
+
+// HARRecorder appends every exchange it sees to an HTTP Archive (HAR) file,
+// rewriting the file after each one so it stays a complete archive while the
+// session goes on. It is safe for concurrent use.
+type HARRecorder struct {
+	mu   sync.Mutex
+	path string
+	har  harFile
+}
+
+// The HAR 1.2 structures; fields starting with an underscore are lighttr
+// extensions the format allows
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string        `json:"mimeType"`
+	Text     string        `json:"text,omitempty"`
+	Params   []harPostPart `json:"params,omitempty"`
+	Comment  string        `json:"comment,omitempty"`
+}
+
+type harPostPart struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type harContent struct {
+	Size        int    `json:"size"`
+	Compression int    `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// OpenHARRecorder starts recording to the HAR file at path. Entries of an
+// existing archive are kept and new ones appended after them.
+func OpenHARRecorder(path string) (*HARRecorder, error) {
+	h := &HARRecorder{path: path, har: harFile{Log: harLog{
+		Version: harVersion,
+		Creator: harCreator{Name: "lighttr", Version: "dev"},
+		Entries: []harEntry{},
+	}}}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to open HAR file: %v", err)
+	default:
+		var existing harFile
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return nil, fmt.Errorf("failed to parse HAR file %s: %v", path, err)
+		}
+		h.har.Log.Entries = append(h.har.Log.Entries, existing.Log.Entries...)
+	}
+
+	// Create the file right away so a bad path fails before any request
+	if err := h.write(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Path returns the file the recorder writes to
+func (h *HARRecorder) Path() string {
+	return h.path
+}
+
+// Len returns the number of entries recorded, including those the file
+// held when it was opened
+func (h *HARRecorder) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.har.Log.Entries)
+}
+
+// Record appends an exchange that started at the given time and writes the
+// archive
+func (h *HARRecorder) Record(x *Exchange, started time.Time) error {
+	entry := newHAREntry(x, started)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.har.Log.Entries = append(h.har.Log.Entries, entry)
+	return h.write()
+}
+
+// write replaces the archive file, through a temporary file so a reader
+// never sees a partial archive
+func (h *HARRecorder) write() error {
+	data, err := json.MarshalIndent(h.har, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".lighttr-har-*")
+	if err != nil {
+		return fmt.Errorf("failed to write HAR file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// Archives hold credentials and cookies
+	if err := tmp.Chmod(0600); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), h.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write HAR file: %v", err)
+	}
+	return nil
+}
+
+// WithHARRecorder returns middleware that records every attempt of the
+// request and its response, or failure, to the archive. A nil recorder
+// records nothing.
+func WithHARRecorder(h *HARRecorder) Middleware {
+	return func(next Handler) Handler {
+		if h == nil {
+			return next
+		}
+		return func(x *Exchange) error {
+			started := time.Now()
+			if err := next(x); err != nil {
+				return err
+			}
+			return h.Record(x, started)
+		}
+	}
+}
+
+// newHAREntry converts an exchange to a HAR entry
+func newHAREntry(x *Exchange, started time.Time) harEntry {
+	req, resp := x.Request, x.Response
+	entry := harEntry{
+		StartedDateTime: started,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harRequestHeaders(x),
+			QueryString: []harNameValue{},
+			PostData:    harRequestBody(x.Data, req.Header.Get("Content-Type")),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{name, value})
+		}
+	}
+	sortNameValues(entry.Request.QueryString)
+	if entry.Request.PostData != nil && entry.Request.PostData.Text != "" {
+		entry.Request.BodySize = len(entry.Request.PostData.Text)
+	} else if req.ContentLength == 0 {
+		entry.Request.BodySize = 0
+	}
+	if resp == nil {
+		return entry
+	}
+
+	entry.Time = milliseconds(resp.ResponseTime)
+	if host, _, err := net.SplitHostPort(resp.RemoteAddr); err == nil {
+		entry.ServerIPAddress = host
+	}
+	entry.Timings.Wait = entry.Time
+	if t := resp.Timing; t != nil {
+		entry.Timings = harTimings{
+			Blocked: -1,
+			DNS:     milliseconds(t.DNS),
+			Connect: milliseconds(t.Connect + t.TLS),
+			SSL:     milliseconds(t.TLS),
+			Wait:    milliseconds(t.TTFB),
+			Receive: milliseconds(t.Transfer),
+		}
+	}
+	if resp.Error != "" {
+		entry.Error = resp.Error
+		return entry
+	}
+
+	if resp.Protocol != "" {
+		entry.Request.HTTPVersion = resp.Protocol
+	}
+	entry.Response.Status = resp.StatusCode
+	entry.Response.StatusText = http.StatusText(resp.StatusCode)
+	entry.Response.HTTPVersion = entry.Request.HTTPVersion
+	for name, value := range resp.Headers {
+		entry.Response.Headers = append(entry.Response.Headers, harNameValue{name, value})
+		if http.CanonicalHeaderKey(name) == "Location" {
+			entry.Response.RedirectURL = value
+		}
+	}
+	sortNameValues(entry.Response.Headers)
+
+	content := harContent{Size: len(resp.Body), MimeType: resp.ContentType(), Text: resp.Body}
+	if resp.Binary {
+		content.Text = base64.StdEncoding.EncodeToString([]byte(resp.Body))
+		content.Encoding = "base64"
+	}
+	entry.Response.BodySize = len(resp.Body)
+	if resp.CompressedSize > 0 && resp.DecodeError == "" {
+		entry.Response.BodySize = int(resp.CompressedSize)
+		content.Compression = len(resp.Body) - int(resp.CompressedSize)
+	}
+	entry.Response.Content = content
+	return entry
+}
+
+// harRequestBody describes the request body: the text of plain and form
+// bodies, the fields of multipart bodies, and only the name of a body
+// streamed from a file or a note for one streamed from a reader
+func harRequestBody(r *RequestData, contentType string) *harPostData {
+	switch {
+	case r.BodyFile != "":
+		return &harPostData{MimeType: contentType, Comment: "body streamed from " + r.BodyFile}
+	case r.BodyReader != nil:
+		return &harPostData{MimeType: contentType, Comment: "body streamed from a reader"}
+	case len(r.Multipart) > 0:
+		post := &harPostData{MimeType: contentType}
+		for _, part := range r.Multipart {
+			param := harPostPart{Name: part.Name, Value: part.Value, ContentType: part.ContentType}
+			if part.File != "" {
+				param.FileName = filepath.Base(part.File)
+			}
+			post.Params = append(post.Params, param)
+		}
+		return post
+	case len(r.FormParams) > 0:
+		return &harPostData{MimeType: contentType, Text: r.EncodedForm()}
+	case r.Body != "":
+		return &harPostData{MimeType: contentType, Text: r.Body}
+	}
+	return nil
+}
+
+// harRequestHeaders lists the request headers as HAR name/value pairs. The
+// tokens of OAuth2 profiles, Google credentials, and Entra ID, minted JWTs,
+// Kerberos tickets, session credentials, and the credentials of auth
+// profiles are redacted: they are obtained by lighttr behind the caller's
+// back, so nobody chose to put them in the archive.
+func harRequestHeaders(x *Exchange) []harNameValue {
+	headers := harHeaders(x.Request.Header)
+	redact := x.Data.Auth.Profile != ""
+	names := []string{"Authorization"}
+	switch x.Data.Auth.Type {
+	case OAuth2Auth, GCPAuth, AzureAuth, JWTAuth, NegotiateAuth:
+		redact = true
+	case SessionAuth:
+		redact = true
+		name, _ := x.Data.Auth.SessionHeaderParam("")
+		names = append(names, http.CanonicalHeaderKey(name), "Cookie")
+	}
+	if !redact {
+		return headers
+	}
+	for i, header := range headers {
+		name := http.CanonicalHeaderKey(header.Name)
+		switch {
+		case name == "Cookie" && slices.Contains(names, name):
+			// Only the names of the cookies are kept
+			cookies := strings.Split(header.Value, ";")
+			for j, cookie := range cookies {
+				cookieName, _, _ := strings.Cut(cookie, "=")
+				cookies[j] = cookieName + "=********"
+			}
+			headers[i].Value = strings.Join(cookies, ";")
+		case slices.Contains(names, name):
+			scheme, _, ok := strings.Cut(header.Value, " ")
+			if !ok {
+				headers[i].Value = "********"
+				break
+			}
+			headers[i].Value = scheme + " ********"
+		}
+	}
+	return headers
+}
+
+// harHeaders lists headers as HAR name/value pairs, sorted by name
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, harNameValue{name, value})
+		}
+	}
+	sortNameValues(headers)
+	return headers
+}
+
+// sortNameValues sorts pairs by name, keeping the order of repeated names
+func sortNameValues(pairs []harNameValue) {
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+}
+
+// milliseconds converts a duration to the fractional milliseconds HAR uses
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,172 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readHAR parses the archive at path
+func readHAR(t *testing.T, path string) harFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("Expected a valid HAR file, got %v:\n%s", err, data)
+	}
+	return har
+}
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(pngHeader))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	recorder, err := OpenHARRecorder(path)
+	if err != nil {
+		t.Fatalf("OpenHARRecorder() error = %v", err)
+	}
+	if har := readHAR(t, path); len(har.Log.Entries) != 0 || har.Log.Version != "1.2" {
+		t.Errorf("Expected an empty archive right away, got %+v", har.Log)
+	}
+
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL + "/users"
+	req.QueryParams["dry_run"] = "true"
+	req.Headers["Content-Type"] = "application/json"
+	req.Body = `{"name": "Jane"}`
+	if _, err := req.ExecuteWith(WithHARRecorder(recorder)); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+
+	har := readHAR(t, path)
+	if len(har.Log.Entries) != 1 {
+		t.Fatalf("Expected the entry to be written right after the request, got %d entries", len(har.Log.Entries))
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != "POST" || entry.Request.URL != server.URL+"/users?dry_run=true" {
+		t.Errorf("Unexpected request %s %s", entry.Request.Method, entry.Request.URL)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0] != (harNameValue{"dry_run", "true"}) {
+		t.Errorf("Unexpected query string %+v", entry.Request.QueryString)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != req.Body || entry.Request.PostData.MimeType != "application/json" {
+		t.Errorf("Expected the request body to be recorded, got %+v", entry.Request.PostData)
+	}
+	if entry.Response.Status != 201 || entry.Response.StatusText != "Created" || entry.Response.Content.Text != `{"id": 1}` {
+		t.Errorf("Unexpected response %+v", entry.Response)
+	}
+	if entry.Response.Content.MimeType != "application/json" || entry.Response.HTTPVersion != "HTTP/1.1" {
+		t.Errorf("Unexpected response content %+v", entry.Response)
+	}
+
+	// A recorder opened on the same file appends to the session
+	recorder, err = OpenHARRecorder(path)
+	if err != nil {
+		t.Fatalf("OpenHARRecorder() error = %v", err)
+	}
+	req = NewRequestData()
+	req.URL = server.URL + "/logo.png"
+	if _, err := req.ExecuteWith(WithHARRecorder(recorder)); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	har = readHAR(t, path)
+	if len(har.Log.Entries) != 2 || recorder.Len() != 2 {
+		t.Fatalf("Expected the entry to be appended, got %d entries", len(har.Log.Entries))
+	}
+	content := har.Log.Entries[1].Response.Content
+	if content.Encoding != "base64" || content.Text != "iVBORw0KGgoAAAANSUhEUg==" || content.Size != 16 {
+		t.Errorf("Expected a binary body in base64, got %+v", content)
+	}
+}
+
+func TestHARRecorder_RedactsOAuth2Tokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	recorder, err := OpenHARRecorder(path)
+	if err != nil {
+		t.Fatalf("OpenHARRecorder() error = %v", err)
+	}
+	for i, tt := range []struct {
+		auth AuthData
+		sent string
+		want string
+	}{
+		{AuthData{Type: OAuth2Auth, OAuth2Profile: "github"}, "Bearer secret", "Bearer ********"},
+		{AuthData{Type: NegotiateAuth}, "Negotiate YIIC", "Negotiate ********"},
+	} {
+		// Stands in for the profile store's or Kerberos provider
+		RegisterAuth(tt.auth.Type, func(next Handler) Handler {
+			return func(x *Exchange) error {
+				x.Request.Header.Set("Authorization", tt.sent)
+				return next(x)
+			}
+		})
+		t.Cleanup(func() { RegisterAuth(tt.auth.Type, nil) })
+
+		req := NewRequestData()
+		req.URL = server.URL
+		req.Auth = tt.auth
+		if _, err := req.ExecuteWith(WithHARRecorder(recorder)); err != nil {
+			t.Fatalf("ExecuteWith() error = %v", err)
+		}
+		var authorization string
+		for _, header := range readHAR(t, path).Log.Entries[i].Request.Headers {
+			if header.Name == "Authorization" {
+				authorization = header.Value
+			}
+		}
+		if authorization != tt.want {
+			t.Errorf("Expected the %s token to be redacted, got %q", tt.auth.Type, authorization)
+		}
+	}
+}
+
+func TestHARRecorder_Failure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	recorder, err := OpenHARRecorder(path)
+	if err != nil {
+		t.Fatalf("OpenHARRecorder() error = %v", err)
+	}
+	req := NewRequestData()
+	req.URL = server.URL
+	if _, err := req.ExecuteWith(WithHARRecorder(recorder)); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+
+	har := readHAR(t, path)
+	if len(har.Log.Entries) != 1 || har.Log.Entries[0].Error == "" || har.Log.Entries[0].Response.Status != 0 {
+		t.Errorf("Expected the failed request to be recorded with its error, got %+v", har.Log.Entries)
+	}
+}
+
+func TestOpenHARRecorder_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.har")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenHARRecorder(path); err == nil {
+		t.Error("Expected an error for a file that is not a HAR archive")
+	}
+}
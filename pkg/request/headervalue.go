@@ -0,0 +1,252 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encodings of the header values DecodeHeaderValue decodes
+const (
+	HeaderEncodingJWT      = "JWT"
+	HeaderEncodingBase64   = "base64"
+	HeaderEncodingGzip     = "gzip, base64"
+	HeaderEncodingProtobuf = "protobuf, base64"
+	HeaderEncodingTraceID  = "AWS X-Ray trace ID"
+)
+
+// DecodedHeader is a header value decoded for reading
+type DecodedHeader struct {
+	// Encoding is how the value was encoded, one of the HeaderEncoding
+	// constants
+	Encoding string
+
+	// Text is the decoded value, with JSON indented
+	Text string
+}
+
+// minEncodedLength keeps short tokens, such as "keep-alive", from being
+// taken for base64; values of gRPC binary headers are decoded however short
+const minEncodedLength = 16
+
+// maxDecodedSize bounds the decompressed size of gzip header values
+const maxDecodedSize = 1 << 20
+
+// DecodeHeaderValue decodes header values that carry encoded data: JWTs,
+// on their own or after an auth scheme such as Bearer; base64 text, JSON,
+// or gzip compressed data; the base64 protobuf of gRPC binary metadata,
+// such as grpc-status-details-bin; and the trace IDs of X-Amzn-Trace-Id. It
+// returns false for values that do not look encoded.
+func DecodeHeaderValue(name, value string) (DecodedHeader, bool) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(name, "X-Amzn-Trace-Id") {
+		return decodeTraceID(value)
+	}
+
+	token := value
+	if scheme, credentials, ok := strings.Cut(value, " "); ok && !strings.ContainsAny(scheme, "=,;") {
+		token = strings.TrimSpace(credentials)
+	}
+	if text, ok := formatJWT(token); ok {
+		return DecodedHeader{Encoding: HeaderEncodingJWT, Text: text}, true
+	}
+
+	binaryHeader := strings.HasSuffix(strings.ToLower(name), "-bin")
+	if !binaryHeader && len(value) < minEncodedLength {
+		return DecodedHeader{}, false
+	}
+	data, ok := decodeBase64(value)
+	if !ok {
+		return DecodedHeader{}, false
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return DecodedHeader{}, false
+		}
+		plain, err := io.ReadAll(io.LimitReader(reader, maxDecodedSize))
+		if err != nil || !readableText(plain) {
+			return DecodedHeader{}, false
+		}
+		return DecodedHeader{Encoding: HeaderEncodingGzip, Text: indentJSON(plain)}, true
+	}
+	if readableText(data) {
+		return DecodedHeader{Encoding: HeaderEncodingBase64, Text: indentJSON(data)}, true
+	}
+	if !binaryHeader {
+		return DecodedHeader{}, false
+	}
+	if text, ok := formatProtoWire(data, ""); ok {
+		return DecodedHeader{Encoding: HeaderEncodingProtobuf, Text: text}, true
+	}
+	return DecodedHeader{Encoding: HeaderEncodingBase64, Text: strings.TrimSuffix(hex.Dump(data), "\n")}, true
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not
+func decodeBase64(value string) ([]byte, bool) {
+	if value == "" || strings.ContainsAny(value, " \t") {
+		return nil, false
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(value); err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// readableText reports whether the data is UTF-8 text without control
+// characters other than whitespace
+func readableText(data []byte) bool {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// indentJSON indents JSON and returns other text as is
+func indentJSON(data []byte) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(data), "", "  "); err == nil {
+		return indented.String()
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// jwtTimeClaims are the claims of a JWT that are times, in the order shown
+var jwtTimeClaims = []struct{ claim, label string }{
+	{"iat", "Issued"},
+	{"nbf", "Not before"},
+	{"exp", "Expires"},
+}
+
+// formatJWT shows the header and claims of a signed JWT, with its times
+// in UTC; the signature is not verified
+func formatJWT(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	header, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[0], "="))
+	if err != nil {
+		return "", false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(header, &fields); err != nil || fields["alg"] == nil {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil || !json.Valid(payload) {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString("Header:\n" + indentJSON(header) + "\n")
+	b.WriteString("Payload:\n" + indentJSON(payload) + "\n")
+	var claims map[string]interface{}
+	if json.Unmarshal(payload, &claims) == nil {
+		for _, c := range jwtTimeClaims {
+			seconds, ok := claims[c.claim].(float64)
+			if !ok {
+				continue
+			}
+			at := time.Unix(int64(seconds), 0).UTC()
+			b.WriteString(fmt.Sprintf("%s: %s", c.label, at.Format(time.DateTime+" MST")))
+			if c.claim == "exp" && at.Before(time.Now()) {
+				b.WriteString(" (expired)")
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("Signature: not verified")
+	return b.String(), true
+}
+
+// decodeTraceID lists the fields of an X-Ray trace header, such as
+// Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1,
+// with the time the trace started, which the root ID begins with
+func decodeTraceID(value string) (DecodedHeader, bool) {
+	var b strings.Builder
+	found := false
+	for _, field := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		b.WriteString(key + ": " + val)
+		if parts := strings.Split(val, "-"); key == "Root" && len(parts) == 3 && len(parts[1]) == 8 {
+			if seconds, err := strconv.ParseUint(parts[1], 16, 32); err == nil {
+				b.WriteString(" (started " + time.Unix(int64(seconds), 0).UTC().Format(time.DateTime+" MST") + ")")
+				found = true
+			}
+		}
+		b.WriteString("\n")
+	}
+	if !found {
+		return DecodedHeader{}, false
+	}
+	return DecodedHeader{Encoding: HeaderEncodingTraceID, Text: strings.TrimSuffix(b.String(), "\n")}, true
+}
+
+// maxProtoDepth bounds how deep formatProtoWire looks into nested messages
+const maxProtoDepth = 8
+
+// formatProtoWire shows protobuf data without its schema, as field numbers
+// and values: strings quoted, nested messages in braces, and other bytes in
+// hex. It returns false when the data is not a protobuf message.
+func formatProtoWire(data []byte, indent string) (string, bool) {
+	if len(data) == 0 || len(indent) > 2*maxProtoDepth {
+		return "", false
+	}
+	var b strings.Builder
+	for len(data) > 0 {
+		tag, n := consumeProtoVarint(data)
+		if n == 0 || tag>>3 == 0 {
+			return "", false
+		}
+		number, wire := tag>>3, int(tag&7)
+		value, rest, err := consumeProtoValue(data[n:], wire)
+		if err != nil {
+			return "", false
+		}
+		data = rest
+
+		b.WriteString(fmt.Sprintf("%s%d: ", indent, number))
+		switch wire {
+		case wireVarint:
+			b.WriteString(strconv.FormatUint(value.varint, 10))
+		case wireFixed32:
+			bits := binary.LittleEndian.Uint32(value.bytes)
+			b.WriteString(fmt.Sprintf("%d (float %g)", bits, math.Float32frombits(bits)))
+		case wireFixed64:
+			bits := binary.LittleEndian.Uint64(value.bytes)
+			b.WriteString(fmt.Sprintf("%d (double %g)", bits, math.Float64frombits(bits)))
+		case wireBytes:
+			if readableText(value.bytes) || len(value.bytes) == 0 {
+				b.WriteString(strconv.Quote(string(value.bytes)))
+			} else if nested, ok := formatProtoWire(value.bytes, indent+"  "); ok {
+				b.WriteString("{\n" + nested + "\n" + indent + "}")
+			} else {
+				b.WriteString(hex.EncodeToString(value.bytes))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), true
+}
@@ -0,0 +1,72 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDecodeHeaderValue(t *testing.T) {
+	b64url := base64.RawURLEncoding.EncodeToString
+	jwt := b64url([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + b64url([]byte(`{"sub":"jane","exp":1700000000}`)) + ".c2lnbmF0dXJl"
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte(`{"region":"eu-west-1"}`))
+	w.Close()
+
+	// A google.rpc.Status with code 5, a message, and an ErrorInfo detail
+	errorInfo := append([]byte{0x0a, 0x07}, "QUOTA_1"...)
+	detail := append([]byte{0x0a, 0x28}, "type.googleapis.com/google.rpc.ErrorInfo"...)
+	detail = append(append(detail, 0x12, byte(len(errorInfo))), errorInfo...)
+	status := append([]byte{0x08, 0x05, 0x12, 0x09}, "not found"...)
+	status = append(append(status, 0x1a, byte(len(detail))), detail...)
+
+	tests := []struct {
+		name, header, value string
+		encoding            string
+		contains            []string
+	}{
+		{"jwt", "Authorization", "Bearer " + jwt, HeaderEncodingJWT, []string{`"alg": "HS256"`, `"sub": "jane"`, "Expires: 2023-11-14 22:13:20 UTC (expired)", "Signature: not verified"}},
+		{"base64 json", "X-User-Info", base64.StdEncoding.EncodeToString([]byte(`{"id":42,"roles":["admin"]}`)), HeaderEncodingBase64, []string{"{\n  \"id\": 42,"}},
+		{"base64 text", "X-Debug", base64.RawURLEncoding.EncodeToString([]byte("served by cache node 7")), HeaderEncodingBase64, []string{"served by cache node 7"}},
+		{"gzip", "X-Context", base64.StdEncoding.EncodeToString(compressed.Bytes()), HeaderEncodingGzip, []string{`"region": "eu-west-1"`}},
+		{"grpc status", "Grpc-Status-Details-Bin", base64.RawStdEncoding.EncodeToString(status), HeaderEncodingProtobuf, []string{
+			"1: 5\n2: \"not found\"\n3: {\n  1: \"type.googleapis.com/google.rpc.ErrorInfo\"\n  2: {\n    1: \"QUOTA_1\"\n  }\n}",
+		}},
+		{"trace id", "X-Amzn-Trace-Id", "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1", HeaderEncodingTraceID, []string{
+			"Root: 1-5759e988-bd862e3fe1be46a994272793 (started 2016-06-09 22:11:20 UTC)", "Sampled: 1",
+		}},
+	}
+	for _, tt := range tests {
+		decoded, ok := DecodeHeaderValue(tt.header, tt.value)
+		if !ok {
+			t.Errorf("%s: DecodeHeaderValue() not decoded", tt.name)
+			continue
+		}
+		if decoded.Encoding != tt.encoding {
+			t.Errorf("%s: Encoding = %q, want %q", tt.name, decoded.Encoding, tt.encoding)
+		}
+		for _, want := range tt.contains {
+			if !strings.Contains(decoded.Text, want) {
+				t.Errorf("%s: Text = %q, want it to contain %q", tt.name, decoded.Text, want)
+			}
+		}
+	}
+
+	// Plain values are left alone
+	for _, plain := range [][2]string{
+		{"Connection", "keep-alive"},
+		{"Etag", "d41d8cd98f00b204e9800998ecf8427e"},
+		{"Content-Type", "application/json; charset=utf-8"},
+		{"Date", "Fri, 16 Oct 2026 09:00:00 GMT"},
+		{"Authorization", "Bearer abc.def.ghi"},
+		{"X-Amzn-Trace-Id", "Self=1-67891234-abcdef"},
+	} {
+		if decoded, ok := DecodeHeaderValue(plain[0], plain[1]); ok {
+			t.Errorf("DecodeHeaderValue(%q, %q) = %+v, want it not decoded", plain[0], plain[1], decoded)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package request
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Hook observes or adjusts requests without writing a Middleware: Before
+// runs with the finished request just before every attempt is sent, and
+// After with the response of the attempt. An error from Before stops the
+// request from being sent; errors from either are returned by ExecuteWith.
+type Hook interface {
+	Before(req *http.Request) error
+	After(resp *ResponseData) error
+}
+
+// HookFuncs implements Hook with functions, either of which may be nil
+type HookFuncs struct {
+	BeforeFunc func(req *http.Request) error
+	AfterFunc  func(resp *ResponseData) error
+}
+
+// Before calls BeforeFunc when set
+func (h HookFuncs) Before(req *http.Request) error {
+	if h.BeforeFunc == nil {
+		return nil
+	}
+	return h.BeforeFunc(req)
+}
+
+// After calls AfterFunc when set
+func (h HookFuncs) After(resp *ResponseData) error {
+	if h.AfterFunc == nil {
+		return nil
+	}
+	return h.AfterFunc(resp)
+}
+
+// WithHook returns middleware that runs the hook around the transport. A
+// nil hook leaves the request untouched.
+func WithHook(h Hook) Middleware {
+	return func(next Handler) Handler {
+		if h == nil {
+			return next
+		}
+		return func(x *Exchange) error {
+			if err := h.Before(x.Request); err != nil {
+				return x.Abort(err)
+			}
+			if err := next(x); err != nil {
+				return err
+			}
+			return h.After(x.Response)
+		}
+	}
+}
+
+// registry holds the middleware registered for every request
+var registry struct {
+	sync.RWMutex
+	middleware []Middleware
+}
+
+// Register adds middleware that every request runs, after the built-in
+// stages and before the middleware passed to ExecuteWith, in the order
+// registered. It is meant for process-wide concerns such as logging,
+// signing, or metrics, set up once at startup:
+//
+//	request.Register(request.WithHook(request.HookFuncs{
+//		BeforeFunc: func(req *http.Request) error {
+//			req.Header.Set("X-Request-ID", newID())
+//			return nil
+//		},
+//	}))
+func Register(middleware ...Middleware) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.middleware = append(registry.middleware, middleware...)
+}
+
+// registered returns a copy of the registered middleware
+func registered() []Middleware {
+	registry.RLock()
+	defer registry.RUnlock()
+	return append([]Middleware{}, registry.middleware...)
+}
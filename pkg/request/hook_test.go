@@ -0,0 +1,94 @@
+package request
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithHook(t *testing.T) {
+	sent := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+		w.Header().Set("X-Seen-Request-ID", r.Header.Get("X-Request-ID"))
+	}))
+	defer server.Close()
+
+	var statuses []int
+	hook := HookFuncs{
+		BeforeFunc: func(req *http.Request) error {
+			req.Header.Set("X-Request-ID", "abc123")
+			return nil
+		},
+		AfterFunc: func(resp *ResponseData) error {
+			statuses = append(statuses, resp.StatusCode)
+			return nil
+		},
+	}
+
+	req := NewRequestData()
+	req.URL = server.URL
+	resp, err := req.ExecuteWith(WithHook(hook))
+	if err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if resp.Headers["X-Seen-Request-Id"] != "abc123" {
+		t.Errorf("Expected Before to add the header, got %v", resp.Headers)
+	}
+	if !reflect.DeepEqual(statuses, []int{http.StatusOK}) {
+		t.Errorf("Expected After to see the response, got %v", statuses)
+	}
+
+	// Errors from Before stop the request, and from After are returned
+	errDenied := errors.New("denied")
+	sent = false
+	if _, err := req.ExecuteWith(WithHook(HookFuncs{BeforeFunc: func(*http.Request) error { return errDenied }})); !errors.Is(err, errDenied) || sent {
+		t.Errorf("Expected Before to stop the request, got %v (sent %v)", err, sent)
+	}
+	if _, err := req.ExecuteWith(WithHook(HookFuncs{AfterFunc: func(*ResponseData) error { return errDenied }})); !errors.Is(err, errDenied) || !sent {
+		t.Errorf("Expected the error from After, got %v (sent %v)", err, sent)
+	}
+
+	// A nil hook changes nothing
+	if _, err := req.ExecuteWith(WithHook(nil)); err != nil {
+		t.Errorf("ExecuteWith() with a nil hook error = %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer func() { registry.middleware = nil }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen", strings.Join(r.Header.Values("X-Order"), ","))
+	}))
+	defer server.Close()
+
+	// Registered middleware runs before the middleware of the call
+	appendOrder := func(name string) Middleware {
+		return WithHook(HookFuncs{BeforeFunc: func(req *http.Request) error {
+			req.Header.Add("X-Order", name)
+			return nil
+		}})
+	}
+	Register(appendOrder("first"), appendOrder("second"))
+
+	req := NewRequestData()
+	req.URL = server.URL
+	resp, err := req.ExecuteWith(appendOrder("call"))
+	if err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if resp.Headers["X-Seen"] != "first,second,call" {
+		t.Errorf("Expected registered middleware to run first, got %q", resp.Headers["X-Seen"])
+	}
+	resp, err = req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Headers["X-Seen"] != "first,second" {
+		t.Errorf("Expected Execute to run registered middleware, got %q", resp.Headers["X-Seen"])
+	}
+}
@@ -0,0 +1,46 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostOverride returns the Host header to send instead of the URL's host:
+// Host, or else a Host entry in the headers. Go ignores a Host entry in
+// the header map, so it has to be set on the request.
+func (r *RequestData) hostOverride() string {
+	if r.Host != "" {
+		return r.Host
+	}
+	for key, value := range r.EffectiveHeaders() {
+		if strings.EqualFold(key, "Host") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// tlsServerName returns the TLS server name to send and verify instead of
+// the URL's host: ServerName, or else the host of the Host override
+func (r *RequestData) tlsServerName() string {
+	if r.ServerName != "" {
+		return r.ServerName
+	}
+	host := r.hostOverride()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.Trim(host, "[]")
+}
+
+// validateHost checks the Host and ServerName overrides
+func (r *RequestData) validateHost() error {
+	if strings.ContainsAny(r.Host, " /") {
+		return fmt.Errorf("invalid host %q: expected host or host:port", r.Host)
+	}
+	if strings.ContainsAny(r.ServerName, " /:") {
+		return fmt.Errorf("invalid server_name %q: expected a host name", r.ServerName)
+	}
+	return nil
+}
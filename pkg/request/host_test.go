@@ -0,0 +1,71 @@
+package request
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequestData_HostOverride(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "host %s, server name %s", r.Host, r.TLS.ServerName)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test certificate is valid for example.com, which is sent as the
+	// Host and verified as the server name while connecting by address
+	tests := []struct {
+		name       string
+		host       string
+		headers    map[string]string
+		serverName string
+		want       string
+		wantErr    string
+	}{
+		{name: "host", host: "example.com", want: "host example.com, server name example.com"},
+		{name: "host header", headers: map[string]string{"host": "example.com:443"}, want: "host example.com:443, server name example.com"},
+		{name: "server name", host: "tenant.internal", serverName: "example.com", want: "host tenant.internal, server name example.com"},
+		{name: "unverified host", host: "tenant.internal", wantErr: "certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.URL = server.URL
+			req.Auth.CAFile = caFile
+			req.Host = tt.host
+			req.ServerName = tt.serverName
+			for key, value := range tt.headers {
+				req.Headers[key] = value
+			}
+			resp, err := req.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if tt.wantErr != "" {
+				if !strings.Contains(resp.Error, tt.wantErr) {
+					t.Errorf("Expected a %s error, got %q", tt.wantErr, resp.Error)
+				}
+				return
+			}
+			if resp.Error != "" || resp.Body != tt.want {
+				t.Errorf("Expected %q, got %q %q", tt.want, resp.Error, resp.Body)
+			}
+		})
+	}
+
+	req := &RequestData{Method: "GET", URL: server.URL, ServerName: "example.com:443"}
+	if err := req.Validate(); err == nil {
+		t.Error("Expected a server name with a port to be rejected")
+	}
+}
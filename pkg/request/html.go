@@ -0,0 +1,311 @@
+package request
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlNode is an element or text node of a parsed HTML document
+type htmlNode struct {
+	tag      string // lower case; empty for text and the document
+	attrs    []htmlAttr
+	text     string
+	parent   *htmlNode
+	children []*htmlNode
+
+	// start and end are the offsets of an element's markup in the document
+	start, end int
+}
+
+// htmlAttr is an element attribute with a lower case name
+type htmlAttr struct {
+	name, value string
+}
+
+// htmlVoidElements never have content or an end tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawTextElements hold text up to their end tag rather than markup
+var htmlRawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+}
+
+// htmlClosesParagraph lists the elements whose start tag ends an open <p>
+var htmlClosesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "div": true, "dl": true,
+	"fieldset": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// htmlImpliedEnds maps elements whose start tag ends an open element of the
+// same kind to those kinds and the containers that bound the search, e.g. a
+// <li> ends the previous <li> of the same list
+var htmlImpliedEnds = map[string]struct{ ends, bounds []string }{
+	"li":     {[]string{"li"}, []string{"ul", "ol"}},
+	"dt":     {[]string{"dt", "dd"}, []string{"dl"}},
+	"dd":     {[]string{"dt", "dd"}, []string{"dl"}},
+	"tr":     {[]string{"tr"}, []string{"table", "thead", "tbody", "tfoot"}},
+	"td":     {[]string{"td", "th"}, []string{"tr", "table"}},
+	"th":     {[]string{"td", "th"}, []string{"tr", "table"}},
+	"thead":  {[]string{"thead", "tbody", "tfoot"}, []string{"table"}},
+	"tbody":  {[]string{"thead", "tbody", "tfoot"}, []string{"table"}},
+	"tfoot":  {[]string{"thead", "tbody", "tfoot"}, []string{"table"}},
+	"option": {[]string{"option"}, []string{"select", "datalist", "optgroup"}},
+}
+
+// parseHTML parses a document leniently the way browsers read tag soup:
+// unknown markup is kept as text, unclosed elements end with their parent,
+// and the common optional end tags (</p>, </li>, </td>, ...) are implied
+func parseHTML(document string) *htmlNode {
+	root := &htmlNode{end: len(document)}
+	current := root
+
+	// closeTo ends the open elements up to and including n at offset
+	closeTo := func(n *htmlNode, offset int) {
+		for current != n.parent {
+			current.end = offset
+			current = current.parent
+		}
+	}
+
+	for i := 0; i < len(document); {
+		if document[i] != '<' {
+			end := strings.IndexByte(document[i:], '<')
+			if end < 0 {
+				end = len(document) - i
+			}
+			appendHTMLText(current, document[i:i+end])
+			i += end
+			continue
+		}
+
+		rest := document[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				i = len(document)
+			} else {
+				i += 4 + end + 3
+			}
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			i += tagEnd(rest)
+		case strings.HasPrefix(rest, "</"):
+			name := strings.ToLower(tagName(rest[2:]))
+			length := tagEnd(rest)
+			if name == "" {
+				i += length
+				continue
+			}
+			for open := current; open != root; open = open.parent {
+				if open.tag == name {
+					closeTo(open, i)
+					open.end = i + length
+					break
+				}
+			}
+			i += length
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			element, length, selfClosing := parseHTMLStartTag(rest)
+			element.start = i
+			i += length
+
+			if htmlClosesParagraph[element.tag] && current.tag == "p" {
+				closeTo(current, element.start)
+			}
+			if implied, ok := htmlImpliedEnds[element.tag]; ok {
+			search:
+				for open := current; open != root; open = open.parent {
+					for _, bound := range implied.bounds {
+						if open.tag == bound {
+							break search
+						}
+					}
+					for _, end := range implied.ends {
+						if open.tag == end {
+							closeTo(open, element.start)
+							break search
+						}
+					}
+				}
+			}
+
+			element.parent = current
+			current.children = append(current.children, element)
+			switch {
+			case htmlVoidElements[element.tag] || selfClosing:
+				element.end = i
+			case htmlRawTextElements[element.tag]:
+				content := len(document) - i
+				if end := indexFold(document[i:], "</"+element.tag); end >= 0 {
+					content = end
+				}
+				text := document[i : i+content]
+				if element.tag == "textarea" || element.tag == "title" {
+					text = html.UnescapeString(text)
+				}
+				if text != "" {
+					element.children = append(element.children, &htmlNode{text: text, parent: element})
+				}
+				i += content
+				if i < len(document) {
+					i += tagEnd(document[i:])
+				}
+				element.end = i
+			default:
+				current = element
+			}
+		default:
+			appendHTMLText(current, "<")
+			i++
+		}
+	}
+	for ; current != root; current = current.parent {
+		current.end = len(document)
+	}
+	return root
+}
+
+// indexFold returns the index of the first ASCII case-insensitive match of
+// substr in s, or -1
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendHTMLText adds text to a node, merging it with a preceding text node
+func appendHTMLText(n *htmlNode, raw string) {
+	text := html.UnescapeString(raw)
+	if last := len(n.children) - 1; last >= 0 && n.children[last].tag == "" {
+		n.children[last].text += text
+		return
+	}
+	n.children = append(n.children, &htmlNode{text: text, parent: n})
+}
+
+// parseHTMLStartTag parses a start tag at the beginning of s and returns the
+// element, the length of the tag, and whether it is self-closing
+func parseHTMLStartTag(s string) (*htmlNode, int, bool) {
+	name := tagName(s[1:])
+	element := &htmlNode{tag: strings.ToLower(name)}
+	i := 1 + len(name)
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '>':
+			return element, i + 1, false
+		case strings.HasPrefix(s[i:], "/>"):
+			return element, i + 2, true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '/':
+			i++
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r\f/>=", rune(s[i])) {
+				i++
+			}
+			attr := htmlAttr{name: strings.ToLower(s[start:i])}
+			for i < len(s) && strings.ContainsRune(" \t\n\r\f", rune(s[i])) {
+				i++
+			}
+			if i < len(s) && s[i] == '=' {
+				i++
+				for i < len(s) && strings.ContainsRune(" \t\n\r\f", rune(s[i])) {
+					i++
+				}
+				start = i
+				if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+					quote := s[i]
+					end := strings.IndexByte(s[i+1:], quote)
+					if end < 0 {
+						end = len(s) - i - 1
+					}
+					attr.value = s[i+1 : i+1+end]
+					i = min(i+end+2, len(s))
+				} else {
+					for i < len(s) && !strings.ContainsRune(" \t\n\r\f>", rune(s[i])) {
+						i++
+					}
+					attr.value = s[start:i]
+				}
+				attr.value = html.UnescapeString(attr.value)
+			}
+			element.attrs = append(element.attrs, attr)
+		}
+	}
+	return element, len(s), false
+}
+
+// tagName returns the tag name at the beginning of s
+func tagName(s string) string {
+	end := 0
+	for end < len(s) && !strings.ContainsRune(" \t\n\r\f/>", rune(s[end])) {
+		end++
+	}
+	return s[:end]
+}
+
+// tagEnd returns the length of the markup up to and including the next >
+func tagEnd(s string) int {
+	if end := strings.IndexByte(s, '>'); end >= 0 {
+		return end + 1
+	}
+	return len(s)
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// attr returns the value of the named attribute
+func (n *htmlNode) attr(name string) (string, bool) {
+	for _, attr := range n.attrs {
+		if attr.name == name {
+			return attr.value, true
+		}
+	}
+	return "", false
+}
+
+// elements returns the element children of the node
+func (n *htmlNode) elements() []*htmlNode {
+	var elements []*htmlNode
+	for _, child := range n.children {
+		if child.tag != "" {
+			elements = append(elements, child)
+		}
+	}
+	return elements
+}
+
+// textContent returns the text of the node and its descendants with runs of
+// whitespace collapsed, leaving out scripts and styles. Line breaks and block
+// elements separate words.
+func (n *htmlNode) textContent() string {
+	var b strings.Builder
+	var walk func(*htmlNode)
+	walk = func(node *htmlNode) {
+		for _, child := range node.children {
+			switch {
+			case child.tag == "":
+				b.WriteString(child.text)
+			case child.tag == "script" || child.tag == "style":
+			case child.tag == "br":
+				b.WriteByte(' ')
+			default:
+				walk(child)
+				if _, ok := htmlImpliedEnds[child.tag]; ok || htmlClosesParagraph[child.tag] {
+					b.WriteByte(' ')
+				}
+			}
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
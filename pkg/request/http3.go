@@ -0,0 +1,204 @@
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// quicHandshakeTimeout is how long a QUIC handshake may go unanswered before
+// an HTTP/3 request falls back, unless the request sets a connect timeout
+const quicHandshakeTimeout = 5 * time.Second
+
+// fallbackTransport sends requests over HTTP/3, falling back to the TCP
+// transport of the request's settings when HTTP/3 can't be used: for http://
+// URLs, through proxies, and when no QUIC connection can be made, e.g.
+// because the server or a firewall doesn't take UDP. Requests are only
+// resent over TCP when no QUIC connection was made, so a request the server
+// may have received is never sent twice.
+type fallbackTransport struct {
+	h3  *http3.Transport
+	tcp *http.Transport
+
+	// proxy is the request's proxy, whose https:// requests the TCP
+	// transport may leave to a tunnel of its dialer
+	proxy *ProxySettings
+
+	// reason explains why the last request fell back, or is empty when it
+	// was sent over HTTP/3
+	reason string
+}
+
+// RoundTrip sends the request over HTTP/3, or over TCP when it can't be
+func (t *fallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if reason := t.unusable(req); reason != "" {
+		t.reason = reason
+		return t.tcp.RoundTrip(req)
+	}
+
+	var connected atomic.Bool
+	trace := &httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) { connected.Store(true) }}
+	resp, err := t.h3.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil {
+		t.reason = ""
+		return resp, nil
+	}
+	if connected.Load() || req.Context().Err() != nil {
+		return nil, err
+	}
+
+	// The HTTP/3 transport closed the body, so it is sent again from a copy
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("HTTP/3 failed (%v), and the streamed body can't be sent again over HTTP/2", err)
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	t.reason = fmt.Sprintf("HTTP/3 failed: %v", err)
+	return t.tcp.RoundTrip(req)
+}
+
+// fallback explains why the request was not sent over HTTP/3, or returns
+// an empty string when it was or HTTP/3 was not asked for (t is nil)
+func (t *fallbackTransport) fallback() string {
+	if t == nil {
+		return ""
+	}
+	return t.reason
+}
+
+// unusable explains why the request can't be sent over HTTP/3, or returns
+// an empty string when it can
+func (t *fallbackTransport) unusable(req *http.Request) string {
+	if req.URL.Scheme != "https" {
+		return "HTTP/3 requires https"
+	}
+	if t.proxy != nil && !bypassesProxy(req.URL, t.proxy.Bypass) {
+		return "HTTP/3 can't be sent through a proxy"
+	}
+	if t.tcp.Proxy != nil {
+		if proxy, err := t.tcp.Proxy(req); err != nil || proxy != nil {
+			return "HTTP/3 can't be sent through a proxy"
+		}
+	}
+	return ""
+}
+
+// newHTTP3Transport returns an HTTP/3 transport for the request's TLS,
+// resolve, interface, IP version, and timeout settings. Its connections
+// close once idle, so those of a transport dropped from the pool don't
+// outlive it.
+func (r *RequestData) newHTTP3Transport() (*http3.Transport, error) {
+	tlsConfig, err := r.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	timeout := quicHandshakeTimeout
+	if r.ConnectTimeout > 0 {
+		timeout = r.ConnectTimeout
+	}
+	var local []net.IP
+	if r.Interface != "" {
+		if local, err = localAddresses(r.Interface); err != nil {
+			return nil, err
+		}
+	}
+	return &http3.Transport{
+		TLSClientConfig: tlsConfig,
+		QUICConfig:      &quic.Config{HandshakeIdleTimeout: timeout},
+		Dial:            quicDialer(resolveOverrides(r.Resolve), local, r.IPVersion),
+	}, nil
+}
+
+// quicDialer returns a dial function for HTTP/3 transports that connects to
+// the addresses host:port is overridden with, from one of the local
+// addresses, over IPv4 or IPv6 only when version is 4 or 6
+func quicDialer(overrides hostOverrides, local []net.IP, version int) func(context.Context, string, *tls.Config, *quic.Config) (*quic.Conn, error) {
+	network := "udp"
+	if version != 0 {
+		network = fmt.Sprintf("udp%d", version)
+	}
+	return func(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+		addresses, port, ok := overrides.lookup(addr)
+		if !ok {
+			return dialQUIC(ctx, network, addr, local, tlsConfig, config)
+		}
+		var lastErr error
+		for _, address := range addresses {
+			conn, err := dialQUIC(ctx, network, net.JoinHostPort(address, port), local, tlsConfig, config)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// dialQUIC opens a QUIC connection to addr from a UDP socket of its own,
+// which is closed with the connection. The handshake sets up the
+// connection and TLS at once, so it is traced as both.
+func dialQUIC(ctx context.Context, network, addr string, local []net.IP, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: addr})
+	}
+	remote, err := net.ResolveUDPAddr(network, addr)
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Err: err})
+	}
+	if err != nil {
+		return nil, err
+	}
+	localAddr := &net.UDPAddr{}
+	if len(local) > 0 {
+		localAddr.IP = pickLocalAddress(local, network, remote.String())
+	}
+	udpConn, err := net.ListenUDP(network, localAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if trace != nil && trace.ConnectStart != nil {
+		trace.ConnectStart(network, remote.String())
+	}
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	transport := &quic.Transport{Conn: udpConn}
+	conn, err := transport.DialEarly(ctx, remote, tlsConfig, config)
+	var state tls.ConnectionState
+	if err == nil {
+		state = conn.ConnectionState().TLS
+	}
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		trace.TLSHandshakeDone(state, err)
+	}
+	if trace != nil && trace.ConnectDone != nil {
+		trace.ConnectDone(network, remote.String(), err)
+	}
+	if err != nil {
+		transport.Close()
+		udpConn.Close()
+		return nil, err
+	}
+	go func() {
+		<-conn.Context().Done()
+		transport.Close()
+		udpConn.Close()
+	}()
+	return conn, nil
+}
@@ -0,0 +1,106 @@
+package request
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newHTTP3Server starts an HTTP/3 server on a UDP port of localhost with
+// the certificate of the TLS server
+func newHTTP3Server(t *testing.T, tlsServer *httptest.Server, handler http.Handler) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP() error = %v", err)
+	}
+	server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: tlsServer.TLS.Certificates}),
+	}
+	go server.Serve(conn)
+	t.Cleanup(func() {
+		server.Close()
+		conn.Close()
+	})
+	return "https://" + conn.LocalAddr().String()
+}
+
+func TestRequestData_HTTP3(t *testing.T) {
+	ResetPool()
+	defer ResetPool()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Proto + " " + string(body)))
+	})
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	h3URL := newHTTP3Server(t, tlsServer, handler)
+
+	send := func(url string) *ResponseData {
+		t.Helper()
+		req := NewRequestData()
+		req.Method = "POST"
+		req.URL = url
+		req.Body = "ping"
+		req.Auth.InsecureSkipVerify = true
+		req.ProtocolVersion = ProtocolHTTP3
+		req.ConnectTimeout = 500 * time.Millisecond
+		resp, err := req.Execute()
+		if err != nil || resp.Error != "" {
+			t.Fatalf("Execute() = %+v, %v", resp, err)
+		}
+		return resp
+	}
+
+	resp := send(h3URL)
+	if resp.Protocol != "HTTP/3.0" || resp.Body != "HTTP/3.0 ping" || resp.ProtocolFallback != "" {
+		t.Errorf("Expected the request over HTTP/3, got %s (%s): %q", resp.Protocol, resp.ProtocolFallback, resp.Body)
+	}
+	if resp.TLS == nil || resp.Timing == nil || resp.Timing.Reused {
+		t.Errorf("Expected the TLS details and timing of a new connection, got %+v %+v", resp.TLS, resp.Timing)
+	}
+	if resp := send(h3URL); resp.Timing == nil || !resp.Timing.Reused {
+		t.Errorf("Expected the second request to reuse the QUIC connection, got %+v", resp.Timing)
+	}
+
+	// The TLS server takes no UDP, so the handshake times out
+	resp = send(tlsServer.URL)
+	if resp.Protocol != "HTTP/2.0" || resp.Body != "HTTP/2.0 ping" || !strings.HasPrefix(resp.ProtocolFallback, "HTTP/3 failed: ") {
+		t.Errorf("Expected the request to fall back to HTTP/2, got %s (%s): %q", resp.Protocol, resp.ProtocolFallback, resp.Body)
+	}
+}
+
+func TestFallbackTransport_Unusable(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+	tests := []struct {
+		name  string
+		url   string
+		proxy func(*http.Request) (*url.URL, error)
+		want  string
+	}{
+		{"https", "https://api.example.com/users", nil, ""},
+		{"http", "http://api.example.com/users", nil, "HTTP/3 requires https"},
+		{"proxy", "https://api.example.com/users", http.ProxyURL(proxyURL), "HTTP/3 can't be sent through a proxy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &fallbackTransport{tcp: &http.Transport{Proxy: tt.proxy}}
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if got := transport.unusable(req); got != tt.want {
+				t.Errorf("unusable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
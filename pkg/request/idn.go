@@ -0,0 +1,302 @@
+package request
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Punycode parameters from RFC 3492
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+// acePrefix marks a punycode encoded host label
+const acePrefix = "xn--"
+
+// nonASCIIEscapes matches runs of percent-encoded non-ASCII bytes
+var nonASCIIEscapes = regexp.MustCompile(`(%[89A-Fa-f][0-9A-Fa-f])+`)
+
+// WireURL returns the URL the request is sent to: path parameters
+// substituted, query parameters added, an internationalized host converted
+// to punycode, and non-ASCII characters in the path and query
+// percent-encoded
+func (r *RequestData) WireURL() (string, error) {
+	u, err := url.Parse(r.ResolvedURL())
+	if err != nil {
+		return "", err
+	}
+	if u.Host, err = hostToASCII(u.Host); err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	for key, value := range r.QueryParams {
+		q.Add(key, value)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// DisplayURL returns a URL the way a browser shows it: punycode hosts in
+// Unicode and percent-encoded non-ASCII characters decoded. URLs that
+// cannot be parsed are returned as they are.
+func DisplayURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	display := nonASCIIEscapes.ReplaceAllStringFunc(rawURL, decodeEscapes)
+	if u.Host != "" {
+		display = strings.Replace(display, u.Host, hostToUnicode(u.Host), 1)
+	}
+	return display
+}
+
+// decodeEscapes decodes a run of percent-encoded bytes, keeping the bytes
+// that are not UTF-8 text escaped
+func decodeEscapes(escaped string) string {
+	decoded, err := url.PathUnescape(escaped)
+	if err != nil {
+		return escaped
+	}
+	var b strings.Builder
+	for i := 0; i < len(decoded); {
+		r, size := utf8.DecodeRuneInString(decoded[i:])
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&b, "%%%02X", decoded[i])
+		} else {
+			b.WriteString(decoded[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// hostToASCII converts the labels of a host that contain non-ASCII
+// characters to punycode, after lower casing and NFC normalizing them the
+// way IDNA maps them. The port and IP literals are left alone.
+func hostToASCII(host string) (string, error) {
+	name, port := splitHostPort(host)
+	if isASCII(name) {
+		return host, nil
+	}
+
+	// Ideographic and fullwidth full stops separate labels too
+	name = strings.NewReplacer("。", ".", "．", ".", "｡", ".").Replace(name)
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		encoded, err := punycodeEncode(norm.NFC.String(strings.ToLower(label)))
+		if err != nil {
+			return "", fmt.Errorf("invalid host %q: %v", host, err)
+		}
+		if len(acePrefix)+len(encoded) > 63 {
+			return "", fmt.Errorf("invalid host %q: label %q is too long", host, label)
+		}
+		labels[i] = acePrefix + encoded
+	}
+	return strings.Join(labels, ".") + port, nil
+}
+
+// hostToUnicode decodes the punycode labels of a host. Labels that fail to
+// decode are kept as they are.
+func hostToUnicode(host string) string {
+	name, port := splitHostPort(host)
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if encoded, ok := cutPrefixFold(label, acePrefix); ok {
+			if decoded, err := punycodeDecode(encoded); err == nil {
+				labels[i] = decoded
+			}
+		}
+	}
+	return strings.Join(labels, ".") + port
+}
+
+// splitHostPort splits a host into the name and a ":port" suffix. IPv6
+// literals are returned whole as the name.
+func splitHostPort(host string) (string, string) {
+	if strings.HasPrefix(host, "[") {
+		return host, ""
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		return host[:i], host[i:]
+	}
+	return host, ""
+}
+
+// isASCII reports whether s only holds 7-bit characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring ASCII case
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// punycodeEncode encodes a label with the Punycode algorithm of RFC 3492
+func punycodeEncode(label string) (string, error) {
+	runes := []rune(label)
+	var out []byte
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := rune(punycodeInitialN), 0, punycodeInitialBias
+	for handled < len(runes) {
+		// The smallest code point not handled yet
+		m := rune(utf8.MaxRune)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		delta += int(m-n) * (handled + 1)
+		if delta < 0 {
+			return "", fmt.Errorf("label %q is too long", label)
+		}
+		n = m
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := punycodeThreshold(k, bias)
+				if q < t {
+					break
+				}
+				out = append(out, punycodeDigit(t+(q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			out = append(out, punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out), nil
+}
+
+// punycodeDecode decodes a label encoded with punycodeEncode
+func punycodeDecode(encoded string) (string, error) {
+	var output []rune
+	rest := encoded
+	if i := strings.LastIndexByte(encoded, '-'); i >= 0 {
+		for _, c := range encoded[:i] {
+			if c >= utf8.RuneSelf {
+				return "", fmt.Errorf("invalid punycode %q", encoded)
+			}
+			output = append(output, c)
+		}
+		rest = encoded[i+1:]
+	}
+
+	n, i, bias := rune(punycodeInitialN), 0, punycodeInitialBias
+	for pos := 0; pos < len(rest); {
+		oldI, w := i, 1
+		for k := punycodeBase; ; k += punycodeBase {
+			if pos >= len(rest) {
+				return "", fmt.Errorf("invalid punycode %q", encoded)
+			}
+			digit, ok := punycodeValue(rest[pos])
+			pos++
+			if !ok {
+				return "", fmt.Errorf("invalid punycode %q", encoded)
+			}
+			i += digit * w
+			t := punycodeThreshold(k, bias)
+			if digit < t {
+				break
+			}
+			w *= punycodeBase - t
+			if i < 0 || w <= 0 {
+				return "", fmt.Errorf("invalid punycode %q", encoded)
+			}
+		}
+		bias = punycodeAdapt(i-oldI, len(output)+1, oldI == 0)
+		n += rune(i / (len(output) + 1))
+		if n > utf8.MaxRune {
+			return "", fmt.Errorf("invalid punycode %q", encoded)
+		}
+		i %= len(output) + 1
+		output = append(output[:i], append([]rune{n}, output[i:]...)...)
+		i++
+	}
+	return string(output), nil
+}
+
+// punycodeThreshold clamps k - bias to [tmin, tmax]
+func punycodeThreshold(k, bias int) int {
+	return min(max(k-bias, punycodeTMin), punycodeTMax)
+}
+
+// punycodeAdapt is the bias adaptation function of RFC 3492 section 6.1
+func punycodeAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+func punycodeValue(c byte) (int, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26, true
+	}
+	return 0, false
+}
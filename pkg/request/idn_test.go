@@ -0,0 +1,119 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPunycode(t *testing.T) {
+	// Samples from RFC 3492 section 7.1 and common hosts
+	tests := []struct {
+		label   string
+		encoded string
+	}{
+		{"bücher", "bcher-kva"},
+		{"münchen", "mnchen-3ya"},
+		{"日本語", "wgv71a119e"},
+		{"他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+		{"почемужеонинеговорятпорусски", "b1abfaaepdrnnbgefbadotcwatmq2g4l"},
+		{"3年b組金八先生", "3b-ww4c5e180e575a65lsy2b"},
+	}
+
+	for _, tt := range tests {
+		encoded, err := punycodeEncode(tt.label)
+		if err != nil || encoded != tt.encoded {
+			t.Errorf("punycodeEncode(%q) = %q, %v, want %q", tt.label, encoded, err, tt.encoded)
+		}
+		decoded, err := punycodeDecode(tt.encoded)
+		if err != nil || decoded != tt.label {
+			t.Errorf("punycodeDecode(%q) = %q, %v, want %q", tt.encoded, decoded, err, tt.label)
+		}
+	}
+
+	if _, err := punycodeDecode("bcher-k!a"); err == nil {
+		t.Error("Expected an error for an invalid punycode digit")
+	}
+}
+
+func TestRequestData_WireURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		query   map[string]string
+		want    string
+		display string
+	}{
+		{
+			name:    "ascii",
+			url:     "https://api.example.com/users?page=2",
+			want:    "https://api.example.com/users?page=2",
+			display: "https://api.example.com/users?page=2",
+		},
+		{
+			name:    "unicode host and path",
+			url:     "https://Bücher.de/straße",
+			want:    "https://xn--bcher-kva.de/stra%C3%9Fe",
+			display: "https://bücher.de/straße",
+		},
+		{
+			name:    "port and ideographic full stop",
+			url:     "https://日本語。jp:8443/検索",
+			query:   map[string]string{"q": "東京"},
+			want:    "https://xn--wgv71a119e.jp:8443/%E6%A4%9C%E7%B4%A2?q=%E6%9D%B1%E4%BA%AC",
+			display: "https://日本語.jp:8443/検索?q=東京",
+		},
+		{
+			name:    "ipv6 literal",
+			url:     "http://[::1]:8080/ü",
+			want:    "http://[::1]:8080/%C3%BC",
+			display: "http://[::1]:8080/ü",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.URL = tt.url
+			for key, value := range tt.query {
+				req.QueryParams[key] = value
+			}
+			got, err := req.WireURL()
+			if err != nil {
+				t.Fatalf("WireURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("WireURL() = %q, want %q", got, tt.want)
+			}
+			if display := DisplayURL(got); display != tt.display {
+				t.Errorf("DisplayURL() = %q, want %q", display, tt.display)
+			}
+		})
+	}
+}
+
+func TestDisplayURL_KeepsInvalidEscapes(t *testing.T) {
+	// %FF is not UTF-8 text, so it stays escaped
+	if got := DisplayURL("https://example.com/%FF%C3%BC"); got != "https://example.com/%FFü" {
+		t.Errorf("DisplayURL() = %q", got)
+	}
+}
+
+func TestRequestData_SendsEncodedUnicodePath(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = server.URL + "/straße/{name}"
+	req.PathParams["name"] = "café"
+	req.QueryParams["q"] = "ü"
+	if _, err := req.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "/stra%C3%9Fe/caf%C3%A9?q=%C3%BC"; requestURI != want {
+		t.Errorf("RequestURI = %q, want %q", requestURI, want)
+	}
+}
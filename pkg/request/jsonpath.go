@@ -0,0 +1,39 @@
+package request
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LookupJSONPath follows a simple JSONPath such as $.data.items[0].id
+// through a document decoded by encoding/json
+func LookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return doc, true
+	}
+
+	current := doc
+	for _, segment := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		if segment == "" {
+			continue
+		}
+		if index, ok := strings.CutPrefix(segment, "["); ok {
+			i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			items, isArray := current.([]interface{})
+			if err != nil || !isArray || i < 0 || i >= len(items) {
+				return nil, false
+			}
+			current = items[i]
+			continue
+		}
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[segment]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,161 @@
+package request
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWTAlgorithms lists the algorithms jwt auth signs with
+var JWTAlgorithms = []string{"HS256", "RS256", "ES256"}
+
+// JWTLifetime is how long the JWTs jwt auth mints are valid when the
+// claims set no expiry
+const JWTLifetime = 5 * time.Minute
+
+// jwtNow is when JWTs are minted; replaced in tests
+var jwtNow = time.Now
+
+// JWTAlgorithm returns the algorithm jwt auth signs with, HS256 when none
+// is set
+func (a AuthData) JWTAlgorithm() string {
+	if a.JWTAlg == "" {
+		return "HS256"
+	}
+	return strings.ToUpper(a.JWTAlg)
+}
+
+// JWTClaimSet parses the claims of jwt auth, a JSON object, keeping numbers
+// as written
+func (a AuthData) JWTClaimSet() (map[string]interface{}, error) {
+	claims := make(map[string]interface{})
+	if strings.TrimSpace(a.JWTClaims) == "" {
+		return claims, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(a.JWTClaims))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid JWT claims: more than one JSON value")
+	}
+	return claims, nil
+}
+
+// MintJWT signs the claims of jwt auth with its key, adding iat and, unless
+// the claims have them, exp JWTLifetime later
+func (a AuthData) MintJWT() (string, error) {
+	claims, err := a.JWTClaimSet()
+	if err != nil {
+		return "", err
+	}
+	now := jwtNow()
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = now.Add(JWTLifetime).Unix()
+	}
+
+	key, err := os.ReadFile(a.JWTKeyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the JWT key: %v", err)
+	}
+	header, err := json.Marshal(map[string]string{"alg": a.JWTAlgorithm(), "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT claims: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signJWT(a.JWTAlgorithm(), key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signJWT signs the JWT signing input with the key file's contents: the
+// shared secret for HS256, a PEM private key otherwise
+func signJWT(alg string, key, input []byte) ([]byte, error) {
+	if alg == "HS256" {
+		// A secret that is a PEM key is a mix-up that would sign without
+		// complaint
+		if bytes.HasPrefix(bytes.TrimSpace(key), []byte("-----BEGIN")) {
+			return nil, fmt.Errorf("the JWT key is a PEM key, but HS256 signs with a shared secret: use RS256 or ES256")
+		}
+		// Editors end files with a newline nobody means as part of the
+		// secret
+		mac := hmac.New(sha256.New, bytes.TrimRight(key, "\r\n"))
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	}
+
+	private, err := parseJWTKey(key)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(input)
+	switch alg {
+	case "RS256":
+		rsaKey, ok := private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("RS256 needs an RSA private key")
+		}
+		return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		ecKey, ok := private.(*ecdsa.PrivateKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 needs a P-256 EC private key")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS signatures are r and s as fixed size big-endian integers
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	}
+	return nil, fmt.Errorf("invalid JWT algorithm %q: use %s", alg, strings.Join(JWTAlgorithms, ", "))
+}
+
+// parseJWTKey reads a PEM PKCS#1 RSA, SEC 1 EC, or PKCS#8 private key
+func parseJWTKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("the JWT key is not a PEM private key")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT key: %v", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT key type %T", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,192 @@
+package request
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKey writes data to a key file in a temporary directory
+func writeKey(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// decodeJWT splits a JWT into its header, claims, signing input, and
+// signature
+func decodeJWT(t *testing.T, token string) (header, claims map[string]interface{}, input string, signature []byte) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT, got %q", token)
+	}
+	for i, v := range []*map[string]interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, claims, parts[0] + "." + parts[1], signature
+}
+
+func TestMintJWT(t *testing.T) {
+	minted := time.Unix(1900000000, 0)
+	jwtNow = func() time.Time { return minted }
+	defer func() { jwtNow = time.Now }()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		alg    string
+		key    []byte
+		verify func(input string, signature []byte) bool
+	}{
+		{
+			name: "HS256",
+			alg:  "",
+			key:  []byte("s3cret\n"),
+			verify: func(input string, signature []byte) bool {
+				mac := hmac.New(sha256.New, []byte("s3cret"))
+				mac.Write([]byte(input))
+				return hmac.Equal(mac.Sum(nil), signature)
+			},
+		},
+		{
+			name: "RS256",
+			alg:  "rs256",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			verify: func(input string, signature []byte) bool {
+				digest := sha256.Sum256([]byte(input))
+				return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature) == nil
+			},
+		},
+		{
+			name: "ES256",
+			alg:  "ES256",
+			key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+			verify: func(input string, signature []byte) bool {
+				digest := sha256.Sum256([]byte(input))
+				r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+				return len(signature) == 64 && ecdsa.Verify(&ecKey.PublicKey, digest[:], r, s)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := AuthData{Type: JWTAuth, JWTClaims: `{"sub": "orders-service", "aud": "billing"}`, JWTKeyFile: writeKey(t, tt.key), JWTAlg: tt.alg}
+			token, err := auth.MintJWT()
+			if err != nil {
+				t.Fatalf("MintJWT() error = %v", err)
+			}
+			header, claims, input, signature := decodeJWT(t, token)
+			if header["alg"] != tt.name || header["typ"] != "JWT" {
+				t.Errorf("Unexpected header %v", header)
+			}
+			if claims["sub"] != "orders-service" || claims["aud"] != "billing" || claims["iat"] != float64(1900000000) || claims["exp"] != float64(1900000300) {
+				t.Errorf("Unexpected claims %v", claims)
+			}
+			if !tt.verify(input, signature) {
+				t.Error("Expected the signature to verify")
+			}
+		})
+	}
+}
+
+func TestMintJWT_ClaimsKeepExpiry(t *testing.T) {
+	auth := AuthData{Type: JWTAuth, JWTClaims: `{"exp": 4102444800, "scope": "read"}`, JWTKeyFile: writeKey(t, []byte("s3cret"))}
+	token, err := auth.MintJWT()
+	if err != nil {
+		t.Fatalf("MintJWT() error = %v", err)
+	}
+	if _, claims, _, _ := decodeJWT(t, token); claims["exp"] != float64(4102444800) || claims["iat"] == nil {
+		t.Errorf("Expected the claims' expiry to be kept, got %v", claims)
+	}
+}
+
+func TestMintJWT_Errors(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	tests := []struct {
+		name    string
+		auth    AuthData
+		wantErr string
+	}{
+		{"PEM key with HS256", AuthData{JWTKeyFile: writeKey(t, rsaPEM)}, "HS256 signs with a shared secret"},
+		{"RSA key with ES256", AuthData{JWTKeyFile: writeKey(t, rsaPEM), JWTAlg: "ES256"}, "ES256 needs a P-256 EC private key"},
+		{"secret with RS256", AuthData{JWTKeyFile: writeKey(t, []byte("s3cret")), JWTAlg: "RS256"}, "not a PEM private key"},
+		{"claims that are not an object", AuthData{JWTClaims: `["sub"]`, JWTKeyFile: writeKey(t, []byte("s3cret"))}, "invalid JWT claims"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.auth.MintJWT(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestExecute_JWTAuth(t *testing.T) {
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Auth = AuthData{Type: JWTAuth, JWTClaims: `{"sub": "orders-service"}`, JWTKeyFile: writeKey(t, []byte("s3cret"))}
+	for i := 0; i < 2; i++ {
+		if _, err := req.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if len(authorizations) != 2 || !strings.HasPrefix(authorizations[0], "Bearer ey") {
+		t.Fatalf("Expected a minted JWT to be sent with each request, got %q", authorizations)
+	}
+	if _, claims, _, _ := decodeJWT(t, strings.TrimPrefix(authorizations[1], "Bearer ")); claims["sub"] != "orders-service" {
+		t.Errorf("Unexpected claims %v", claims)
+	}
+}
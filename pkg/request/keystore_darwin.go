@@ -0,0 +1,188 @@
+//go:build darwin && cgo
+
+package request
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+
+// copyIdentities finds every identity, a certificate with its private key,
+// in the user's keychains
+static OSStatus copyIdentities(CFArrayRef *identities) {
+	const void *keys[] = {kSecClass, kSecReturnRef, kSecMatchLimit};
+	const void *values[] = {kSecClassIdentity, kCFBooleanTrue, kSecMatchLimitAll};
+	CFDictionaryRef query = CFDictionaryCreate(kCFAllocatorDefault, keys, values, 3,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	OSStatus status = SecItemCopyMatching(query, (CFTypeRef *)identities);
+	CFRelease(query);
+	return status;
+}
+
+// copyCertificateData returns the DER certificate of an identity, or NULL
+static CFDataRef copyCertificateData(CFArrayRef identities, CFIndex i) {
+	SecIdentityRef identity = (SecIdentityRef)CFArrayGetValueAtIndex(identities, i);
+	SecCertificateRef cert = NULL;
+	if (SecIdentityCopyCertificate(identity, &cert) != errSecSuccess) {
+		return NULL;
+	}
+	CFDataRef der = SecCertificateCopyData(cert);
+	CFRelease(cert);
+	return der;
+}
+
+// copyPrivateKey returns the private key of an identity, or NULL
+static SecKeyRef copyPrivateKey(CFArrayRef identities, CFIndex i) {
+	SecIdentityRef identity = (SecIdentityRef)CFArrayGetValueAtIndex(identities, i);
+	SecKeyRef key = NULL;
+	if (SecIdentityCopyPrivateKey(identity, &key) != errSecSuccess) {
+		return NULL;
+	}
+	return key;
+}
+
+// signDigest signs a digest with the key, returning NULL and the error on
+// failure
+static CFDataRef signDigest(SecKeyRef key, SecKeyAlgorithm algorithm, const UInt8 *digest, CFIndex length, CFErrorRef *error) {
+	CFDataRef data = CFDataCreate(kCFAllocatorDefault, digest, length);
+	CFDataRef sig = SecKeyCreateSignature(key, algorithm, data, error);
+	CFRelease(data);
+	return sig;
+}
+*/
+import "C"
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// KeystoreSupported reports whether AuthData.CertSubject can load client
+// certificates from the system keystore
+const KeystoreSupported = true
+
+// loadKeystoreCertificate finds an identity in the user's keychains whose
+// certificate subject contains the filter and returns it with a signer
+// backed by its (possibly non-extractable) private key. The identities are
+// matched by the same subject string as on Windows, so a filter such as
+// CN=jane.doe selects the same certificate on both.
+func loadKeystoreCertificate(subject string) (tls.Certificate, error) {
+	var identities C.CFArrayRef
+	switch status := C.copyIdentities(&identities); status {
+	case C.errSecSuccess:
+	case C.errSecItemNotFound:
+		return tls.Certificate{}, fmt.Errorf("no certificate matching subject %q found in the system keystore", subject)
+	default:
+		return tls.Certificate{}, fmt.Errorf("failed to search the keychain: OSStatus %d", status)
+	}
+	defer C.CFRelease(C.CFTypeRef(identities))
+
+	filter := strings.ToLower(subject)
+	for i := C.CFIndex(0); i < C.CFArrayGetCount(identities); i++ {
+		data := C.copyCertificateData(identities, i)
+		if data == 0 {
+			continue
+		}
+		der := C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(data)), C.int(C.CFDataGetLength(data)))
+		C.CFRelease(C.CFTypeRef(data))
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil || time.Now().After(cert.NotAfter) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(cert.Subject.String()), filter) {
+			continue
+		}
+		key := C.copyPrivateKey(identities, i)
+		if key == 0 {
+			continue
+		}
+
+		signer := &keystoreSigner{key: key, public: cert.PublicKey}
+		runtime.SetFinalizer(signer, func(s *keystoreSigner) { C.CFRelease(C.CFTypeRef(s.key)) })
+		return tls.Certificate{
+			Certificate: [][]byte{der},
+			PrivateKey:  signer,
+			Leaf:        cert,
+		}, nil
+	}
+	return tls.Certificate{}, fmt.Errorf("no certificate matching subject %q found in the system keystore", subject)
+}
+
+// keystoreSigner signs TLS handshakes with a keychain key
+type keystoreSigner struct {
+	key    C.SecKeyRef
+	public crypto.PublicKey
+}
+
+func (s *keystoreSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *keystoreSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	algorithm, err := signatureAlgorithm(s.public, opts)
+	if err != nil {
+		return nil, err
+	}
+	var cfErr C.CFErrorRef
+	sig := C.signDigest(s.key, algorithm, (*C.UInt8)(unsafe.Pointer(&digest[0])), C.CFIndex(len(digest)), &cfErr)
+	runtime.KeepAlive(s)
+	if sig == 0 {
+		var code C.CFIndex
+		if cfErr != 0 {
+			code = C.CFErrorGetCode(cfErr)
+			C.CFRelease(C.CFTypeRef(cfErr))
+		}
+		return nil, fmt.Errorf("SecKeyCreateSignature failed: error %d", code)
+	}
+	defer C.CFRelease(C.CFTypeRef(sig))
+	// ECDSA signatures come ASN.1 encoded, as TLS expects
+	return C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(sig)), C.int(C.CFDataGetLength(sig))), nil
+}
+
+// signatureAlgorithm returns the Security framework algorithm that signs a
+// digest of the hash with the key. PSS salts are as long as the hash, as
+// TLS requires.
+func signatureAlgorithm(public crypto.PublicKey, opts crypto.SignerOpts) (C.SecKeyAlgorithm, error) {
+	var algorithms map[crypto.Hash]C.SecKeyAlgorithm
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if _, pss := opts.(*rsa.PSSOptions); pss {
+			algorithms = map[crypto.Hash]C.SecKeyAlgorithm{
+				crypto.SHA256: C.kSecKeyAlgorithmRSASignatureDigestPSSSHA256,
+				crypto.SHA384: C.kSecKeyAlgorithmRSASignatureDigestPSSSHA384,
+				crypto.SHA512: C.kSecKeyAlgorithmRSASignatureDigestPSSSHA512,
+			}
+		} else {
+			algorithms = map[crypto.Hash]C.SecKeyAlgorithm{
+				crypto.SHA1:   C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA1,
+				crypto.SHA256: C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA256,
+				crypto.SHA384: C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA384,
+				crypto.SHA512: C.kSecKeyAlgorithmRSASignatureDigestPKCS1v15SHA512,
+			}
+		}
+	case *ecdsa.PublicKey:
+		algorithms = map[crypto.Hash]C.SecKeyAlgorithm{
+			crypto.SHA1:   C.kSecKeyAlgorithmECDSASignatureDigestX962SHA1,
+			crypto.SHA256: C.kSecKeyAlgorithmECDSASignatureDigestX962SHA256,
+			crypto.SHA384: C.kSecKeyAlgorithmECDSASignatureDigestX962SHA384,
+			crypto.SHA512: C.kSecKeyAlgorithmECDSASignatureDigestX962SHA512,
+		}
+	default:
+		return 0, fmt.Errorf("unsupported keychain key type %T", public)
+	}
+	algorithm, ok := algorithms[opts.HashFunc()]
+	if !ok {
+		return 0, fmt.Errorf("unsupported hash algorithm: %v", opts.HashFunc())
+	}
+	return algorithm, nil
+}
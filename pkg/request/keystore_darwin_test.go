@@ -0,0 +1,15 @@
+//go:build darwin && cgo
+
+package request
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadKeystoreCertificate_NoMatch(t *testing.T) {
+	_, err := loadKeystoreCertificate("CN=lighttr-test-no-such-subject")
+	if err == nil || !strings.Contains(err.Error(), `no certificate matching subject "CN=lighttr-test-no-such-subject"`) {
+		t.Errorf("Expected no certificate to match, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows && !(darwin && cgo)
+
+package request
+
+import (
+	"crypto/tls"
+	"fmt"
+	"runtime"
+)
+
+// KeystoreSupported reports whether AuthData.CertSubject can load client
+// certificates from the system keystore
+const KeystoreSupported = false
+
+// loadKeystoreCertificate is only implemented on Windows and on macOS, where
+// accessing the keychain requires cgo and the Security framework
+func loadKeystoreCertificate(subject string) (tls.Certificate, error) {
+	return tls.Certificate{}, fmt.Errorf("system keystore certificates are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,24 @@
+//go:build !windows && !(darwin && cgo)
+
+package request
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadKeystoreCertificate_Unsupported(t *testing.T) {
+	req := &RequestData{
+		Method: "GET",
+		URL:    "https://api.example.com",
+		Auth: AuthData{
+			Type:        MutualTLSAuth,
+			CertSubject: "CN=jane.doe",
+		},
+	}
+
+	_, err := req.Execute()
+	if err == nil || !strings.Contains(err.Error(), "system keystore certificates are not supported") {
+		t.Errorf("Expected unsupported keystore error, got %v", err)
+	}
+}
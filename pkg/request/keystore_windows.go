@@ -0,0 +1,187 @@
+//go:build windows
+
+package request
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// KeystoreSupported reports whether AuthData.CertSubject can load client
+// certificates from the system keystore
+const KeystoreSupported = true
+
+var (
+	ncrypt             = windows.NewLazySystemDLL("ncrypt.dll")
+	procNCryptSignHash = ncrypt.NewProc("NCryptSignHash")
+)
+
+// NCrypt padding flags
+const (
+	bcryptPadPKCS1 = 0x00000002
+	bcryptPadPSS   = 0x00000008
+)
+
+type bcryptPKCS1PaddingInfo struct {
+	algID *uint16
+}
+
+type bcryptPSSPaddingInfo struct {
+	algID *uint16
+	salt  uint32
+}
+
+// loadKeystoreCertificate finds a certificate in the current user's personal
+// store whose subject contains the filter and returns it with a signer backed
+// by its (possibly non-exportable) private key
+func loadKeystoreCertificate(subject string) (tls.Certificate, error) {
+	storeName, err := windows.UTF16PtrFromString("MY")
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	store, err := windows.CertOpenStore(windows.CERT_STORE_PROV_SYSTEM, 0, 0,
+		windows.CERT_SYSTEM_STORE_CURRENT_USER|windows.CERT_STORE_READONLY_FLAG,
+		uintptr(unsafe.Pointer(storeName)))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to open certificate store: %v", err)
+	}
+	defer windows.CertCloseStore(store, 0)
+
+	filter := strings.ToLower(subject)
+	var ctx *windows.CertContext
+	for {
+		ctx, err = windows.CertEnumCertificatesInStore(store, ctx)
+		if err != nil || ctx == nil {
+			return tls.Certificate{}, fmt.Errorf("no certificate matching subject %q found in the system keystore", subject)
+		}
+
+		der := unsafe.Slice(ctx.EncodedCert, ctx.Length)
+		cert, err := x509.ParseCertificate(der)
+		if err != nil || time.Now().After(cert.NotAfter) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(cert.Subject.String()), filter) {
+			continue
+		}
+
+		var key windows.Handle
+		var keySpec uint32
+		var callerFree bool
+		flags := uint32(windows.CRYPT_ACQUIRE_CACHE_FLAG | windows.CRYPT_ACQUIRE_ONLY_NCRYPT_KEY_FLAG)
+		if err := windows.CryptAcquireCertificatePrivateKey(ctx, flags, nil, &key, &keySpec, &callerFree); err != nil {
+			continue
+		}
+
+		// Keep the context alive for the lifetime of the cached key handle
+		windows.CertDuplicateCertificateContext(ctx)
+
+		return tls.Certificate{
+			Certificate: [][]byte{append([]byte(nil), der...)},
+			PrivateKey:  &keystoreSigner{key: key, public: cert.PublicKey},
+			Leaf:        cert,
+		}, nil
+	}
+}
+
+// keystoreSigner signs TLS handshakes with an NCrypt key handle
+type keystoreSigner struct {
+	key    windows.Handle
+	public crypto.PublicKey
+}
+
+func (s *keystoreSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *keystoreSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var padding unsafe.Pointer
+	var flags uint32
+
+	if _, ok := s.public.(*rsa.PublicKey); ok {
+		algID, err := hashAlgorithmID(opts.HashFunc())
+		if err != nil {
+			return nil, err
+		}
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			salt := pss.SaltLength
+			if salt == rsa.PSSSaltLengthEqualsHash || salt == rsa.PSSSaltLengthAuto {
+				salt = opts.HashFunc().Size()
+			}
+			padding = unsafe.Pointer(&bcryptPSSPaddingInfo{algID: algID, salt: uint32(salt)})
+			flags = bcryptPadPSS
+		} else {
+			padding = unsafe.Pointer(&bcryptPKCS1PaddingInfo{algID: algID})
+			flags = bcryptPadPKCS1
+		}
+	}
+
+	var size uint32
+	if err := ncryptSignHash(s.key, padding, digest, nil, &size, flags); err != nil {
+		return nil, err
+	}
+	sig := make([]byte, size)
+	if err := ncryptSignHash(s.key, padding, digest, sig, &size, flags); err != nil {
+		return nil, err
+	}
+	sig = sig[:size]
+
+	// NCrypt returns ECDSA signatures as r||s; TLS expects ASN.1
+	if _, ok := s.public.(*ecdsa.PublicKey); ok {
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(sig[:half]),
+			S: new(big.Int).SetBytes(sig[half:]),
+		})
+	}
+	return sig, nil
+}
+
+// ncryptSignHash calls NCryptSignHash, querying the size when sig is nil
+func ncryptSignHash(key windows.Handle, padding unsafe.Pointer, digest, sig []byte, size *uint32, flags uint32) error {
+	var sigPtr *byte
+	if len(sig) > 0 {
+		sigPtr = &sig[0]
+	}
+	r, _, _ := procNCryptSignHash.Call(
+		uintptr(key),
+		uintptr(padding),
+		uintptr(unsafe.Pointer(&digest[0])),
+		uintptr(len(digest)),
+		uintptr(unsafe.Pointer(sigPtr)),
+		uintptr(len(sig)),
+		uintptr(unsafe.Pointer(size)),
+		uintptr(flags),
+	)
+	if r != 0 {
+		return fmt.Errorf("NCryptSignHash failed: 0x%x", r)
+	}
+	return nil
+}
+
+// hashAlgorithmID returns the CNG algorithm identifier for a hash
+func hashAlgorithmID(hash crypto.Hash) (*uint16, error) {
+	switch hash {
+	case crypto.SHA1:
+		return windows.UTF16PtrFromString("SHA1")
+	case crypto.SHA256:
+		return windows.UTF16PtrFromString("SHA256")
+	case crypto.SHA384:
+		return windows.UTF16PtrFromString("SHA384")
+	case crypto.SHA512:
+		return windows.UTF16PtrFromString("SHA512")
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %v", hash)
+	}
+}
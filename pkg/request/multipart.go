@@ -0,0 +1,153 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FormPart is a field of a multipart/form-data body: a plain value, or a
+// file streamed from disk when File is set
+type FormPart struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+	File  string `json:"file,omitempty"`
+
+	// ContentType overrides the part's type; files default to a type
+	// guessed from the extension
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// ParseFormPart parses a part in curl's -F syntax: name=value for a field,
+// name=@path for a file, optionally followed by ;type=<content type>
+func ParseFormPart(spec string) (FormPart, error) {
+	name, value, ok := strings.Cut(spec, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return FormPart{}, fmt.Errorf("invalid form part %q: expected name=value or name=@file", spec)
+	}
+
+	part := FormPart{Name: strings.TrimSpace(name)}
+	if i := strings.LastIndex(value, ";type="); i >= 0 {
+		part.ContentType = value[i+len(";type="):]
+		value = value[:i]
+	}
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		part.File = path
+	} else {
+		part.Value = value
+	}
+	return part, nil
+}
+
+// String formats the part in curl's -F syntax
+func (p FormPart) String() string {
+	s := p.Name + "=" + p.Value
+	if p.File != "" {
+		s = p.Name + "=@" + p.File
+	}
+	if p.ContentType != "" {
+		s += ";type=" + p.ContentType
+	}
+	return s
+}
+
+// validateMultipart checks that the parts are named and their files exist
+func validateMultipart(parts []FormPart) error {
+	for _, part := range parts {
+		if part.Name == "" {
+			return fmt.Errorf("form parts must have a name")
+		}
+		if part.File == "" {
+			continue
+		}
+		info, err := os.Stat(part.File)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("form file does not exist: %s", part.File)
+		}
+		if err == nil && info.IsDir() {
+			return fmt.Errorf("form file is a directory: %s", part.File)
+		}
+	}
+	return nil
+}
+
+// setMultipartBody streams the parts as a multipart/form-data body with a
+// generated boundary. GetBody writes the parts again with the same boundary
+// so redirects and retries can resend them.
+func setMultipartBody(req *http.Request, parts []FormPart) {
+	boundary := multipart.NewWriter(nil).Boundary()
+	open := func() (io.ReadCloser, error) {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(writeMultipart(writer, boundary, parts))
+		}()
+		return reader, nil
+	}
+
+	req.Body, _ = open()
+	req.GetBody = open
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+}
+
+// quoteEscaper escapes quoted Content-Disposition parameters
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// writeMultipart writes the parts, copying files as they are read
+func writeMultipart(w io.Writer, boundary string, parts []FormPart) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader)
+		disposition := fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(part.Name))
+		contentType := part.ContentType
+		if part.File != "" {
+			disposition += fmt.Sprintf(`; filename="%s"`, quoteEscaper.Replace(filepath.Base(part.File)))
+			if contentType == "" {
+				contentType = mime.TypeByExtension(filepath.Ext(part.File))
+			}
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+		}
+		header.Set("Content-Disposition", disposition)
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if part.File == "" {
+			if _, err := io.WriteString(pw, part.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyFile(pw, part.File); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// copyFile writes the content of the named file to w
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open form file: %v", err)
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
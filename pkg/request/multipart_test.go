@@ -0,0 +1,156 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFormPart(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    FormPart
+		wantErr bool
+	}{
+		{spec: "name=Jane", want: FormPart{Name: "name", Value: "Jane"}},
+		{spec: "note=a=b", want: FormPart{Name: "note", Value: "a=b"}},
+		{spec: "avatar=@/tmp/me.png", want: FormPart{Name: "avatar", File: "/tmp/me.png"}},
+		{spec: "doc=@report.bin;type=application/pdf", want: FormPart{Name: "doc", File: "report.bin", ContentType: "application/pdf"}},
+		{spec: "meta={};type=application/json", want: FormPart{Name: "meta", Value: "{}", ContentType: "application/json"}},
+		{spec: "novalue", wantErr: true},
+		{spec: "=value", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormPart(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormPart(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormPart(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+		if !tt.wantErr && got.String() != tt.spec {
+			t.Errorf("String() = %q, want %q", got.String(), tt.spec)
+		}
+	}
+}
+
+func TestRequestData_Multipart(t *testing.T) {
+	type received struct {
+		fields   map[string]string
+		files    map[string]string
+		types    map[string]string
+		boundary bool
+	}
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := received{fields: map[string]string{}, files: map[string]string{}, types: map[string]string{}}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("MultipartReader() error = %v", err)
+			return
+		}
+		got.boundary = strings.Contains(r.Header.Get("Content-Type"), "boundary=")
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("NextPart() error = %v", err)
+				return
+			}
+			data, _ := io.ReadAll(part)
+			if part.FileName() != "" {
+				got.files[part.FormName()] = part.FileName() + ":" + string(data)
+			} else {
+				got.fields[part.FormName()] = string(data)
+			}
+			got.types[part.FormName()] = part.Header.Get("Content-Type")
+		}
+		requests = append(requests, got)
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	avatar := filepath.Join(dir, "avatar.png")
+	os.WriteFile(avatar, []byte("PNGDATA"), 0644)
+	blob := filepath.Join(dir, "blob")
+	os.WriteFile(blob, []byte("raw"), 0644)
+
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL
+	req.Headers["Content-Type"] = "application/json"
+	req.Multipart = []FormPart{
+		{Name: "name", Value: "Jane"},
+		{Name: "meta", Value: `{"admin": true}`, ContentType: "application/json"},
+		{Name: "avatar", File: avatar},
+		{Name: "blob", File: blob},
+	}
+	req.Retry = &RetryPolicy{MaxAttempts: 2}
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(requests) != 2 {
+		t.Fatalf("Expected the retry to resend the form, got %d after %d requests", resp.StatusCode, len(requests))
+	}
+	for i, got := range requests {
+		if !got.boundary {
+			t.Errorf("Request %d: expected a multipart Content-Type with a boundary", i+1)
+		}
+		if got.fields["name"] != "Jane" || got.fields["meta"] != `{"admin": true}` {
+			t.Errorf("Request %d: unexpected fields %v", i+1, got.fields)
+		}
+		if got.files["avatar"] != "avatar.png:PNGDATA" || got.files["blob"] != "blob:raw" {
+			t.Errorf("Request %d: unexpected files %v", i+1, got.files)
+		}
+		if got.types["meta"] != "application/json" || got.types["avatar"] != "image/png" || got.types["blob"] != "application/octet-stream" {
+			t.Errorf("Request %d: unexpected part types %v", i+1, got.types)
+		}
+	}
+}
+
+func TestRequestData_ValidateMultipart(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		body    string
+		parts   []FormPart
+		wantErr string
+	}{
+		{"fields", "", []FormPart{{Name: "a", Value: "1"}}, ""},
+		{"unnamed part", "", []FormPart{{Value: "1"}}, "must have a name"},
+		{"missing file", "", []FormPart{{Name: "f", File: filepath.Join(dir, "missing")}}, "form file does not exist"},
+		{"directory", "", []FormPart{{Name: "f", File: dir}}, "form file is a directory"},
+		{"with body", "raw", []FormPart{{Name: "a", Value: "1"}}, "cannot be combined with a body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.Method = "POST"
+			req.URL = "https://api.example.com"
+			req.Body = tt.body
+			req.Multipart = tt.parts
+			err := req.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package request
+
+import (
+	"net/http"
+	"strings"
+)
+
+// acceptAliases maps short names to media types for negotiation tests
+var acceptAliases = map[string]string{
+	"json": "application/json",
+	"xml":  "application/xml",
+	"html": "text/html",
+	"csv":  "text/csv",
+	"text": "text/plain",
+	"yaml": "application/yaml",
+	"any":  "*/*",
+}
+
+// NegotiationResult summarizes the response to one Accept value
+type NegotiationResult struct {
+	Accept      string `json:"accept"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ParseAcceptList splits a comma separated list of media types or short
+// names (json, xml, html, csv, text, yaml, any) into media types
+func ParseAcceptList(value string) []string {
+	var accepts []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if mediaType, ok := acceptAliases[strings.ToLower(item)]; ok {
+			item = mediaType
+		}
+		accepts = append(accepts, item)
+	}
+	return accepts
+}
+
+// Negotiate sends the request once per Accept value and summarizes what the
+// server returned for each
+func (r *RequestData) Negotiate(accepts []string) ([]NegotiationResult, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make([]NegotiationResult, 0, len(accepts))
+	for _, accept := range accepts {
+		variant := r.Clone()
+		for key := range variant.Headers {
+			if http.CanonicalHeaderKey(key) == "Accept" {
+				delete(variant.Headers, key)
+			}
+		}
+		variant.Headers["Accept"] = accept
+
+		result := NegotiationResult{Accept: accept}
+		resp, err := variant.Execute()
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case resp.Error != "":
+			result.Error = resp.Error
+		default:
+			result.StatusCode = resp.StatusCode
+			result.ContentType = resp.Headers["Content-Type"]
+			result.Size = len(resp.Body)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,56 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptList(t *testing.T) {
+	got := ParseAcceptList("json, xml,text/vnd.custom+json,,CSV")
+	want := []string{"application/json", "application/xml", "text/vnd.custom+json", "text/csv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAcceptList() = %v, want %v", got, want)
+	}
+}
+
+func TestRequestData_Negotiate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Accept") {
+		case "application/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		case "text/csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("ok\ntrue\n"))
+		default:
+			w.WriteHeader(http.StatusNotAcceptable)
+		}
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: map[string]string{"accept": "text/html"},
+		Auth:    AuthData{Type: NoAuth},
+	}
+
+	results, err := req.Negotiate([]string{"application/json", "application/xml", "text/csv"})
+	if err != nil {
+		t.Fatalf("Negotiate() error = %v", err)
+	}
+
+	want := []NegotiationResult{
+		{Accept: "application/json", StatusCode: 200, ContentType: "application/json", Size: 11},
+		{Accept: "application/xml", StatusCode: 406, ContentType: "", Size: 0},
+		{Accept: "text/csv", StatusCode: 200, ContentType: "text/csv", Size: 8},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Negotiate() = %+v, want %+v", results, want)
+	}
+	if req.Headers["accept"] != "text/html" {
+		t.Error("Expected original request headers to be untouched")
+	}
+}
@@ -0,0 +1,86 @@
+package request
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultScheme is added to URLs typed without one
+const DefaultScheme = "https"
+
+// schemePattern matches a URL that starts with a scheme
+var schemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+
+// schemeTypos lists mistyped scheme separators and the scheme they stand for
+var schemeTypos = []struct{ typo, scheme string }{
+	{"https//", "https"},
+	{"https:/", "https"},
+	{"http//", "http"},
+	{"http:/", "http"},
+}
+
+// NormalizeURL tidies a URL as typed: it trims whitespace, adds scheme (or
+// DefaultScheme when empty) to URLs without one, repairs a mistyped "://",
+// and encodes spaces. It returns the URL with a warning for each mistake it
+// found. URLs starting with a {{var}} placeholder keep their scheme as is.
+func NormalizeURL(rawURL, scheme string) (string, []string) {
+	if scheme == "" {
+		scheme = DefaultScheme
+	}
+	normalized := strings.TrimSpace(rawURL)
+	if normalized == "" || strings.HasPrefix(normalized, "{{") {
+		return normalized, nil
+	}
+
+	var warnings []string
+	lower := strings.ToLower(normalized)
+	switch {
+	case schemePattern.MatchString(normalized):
+		if name := lower[:strings.Index(lower, "://")]; name != "http" && name != "https" && name != "ws" && name != "wss" {
+			warnings = append(warnings, fmt.Sprintf("unsupported scheme %q; use http, https, ws, or wss", name))
+		}
+	case strings.HasPrefix(normalized, "//"):
+		normalized = scheme + ":" + normalized
+	default:
+		repaired := false
+		for _, t := range schemeTypos {
+			if strings.HasPrefix(lower, t.typo) {
+				normalized = t.scheme + "://" + strings.TrimLeft(normalized[len(t.typo):], "/")
+				warnings = append(warnings, fmt.Sprintf("repaired %q to %q", t.typo, t.scheme+"://"))
+				repaired = true
+				break
+			}
+		}
+		if !repaired {
+			normalized = scheme + "://" + normalized
+		}
+	}
+
+	if strings.ContainsAny(normalized, " \t") {
+		normalized = strings.NewReplacer(" ", "%20", "\t", "%09").Replace(normalized)
+		warnings = append(warnings, "URL contains whitespace; it is sent percent-encoded")
+	}
+	if hasStrayBraces(normalized) {
+		warnings = append(warnings, "URL contains { or } outside of {name} path templates; they are sent encoded as %7B and %7D")
+	}
+	return normalized, warnings
+}
+
+// hasStrayBraces reports whether a URL has braces that are not part of a
+// {name} path template or {{var}} placeholder
+func hasStrayBraces(rawURL string) bool {
+	stripped := scanPathParams(rawURL, func(string) string { return "" })
+	for {
+		start := strings.Index(stripped, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(stripped[start:], "}}")
+		if end < 0 {
+			break
+		}
+		stripped = stripped[:start] + stripped[start+end+2:]
+	}
+	return strings.ContainsAny(stripped, "{}")
+}
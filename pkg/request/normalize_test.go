@@ -0,0 +1,57 @@
+package request
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		scheme   string
+		want     string
+		warnings []string
+	}{
+		{"complete", "https://api.example.com/users", "", "https://api.example.com/users", nil},
+		{"default scheme", "  api.example.com/users\n", "", "https://api.example.com/users", nil},
+		{"configured scheme", "localhost:8080/users", "http", "http://localhost:8080/users", nil},
+		{"scheme relative", "//api.example.com/users", "", "https://api.example.com/users", nil},
+		{"url in query", "api.example.com/login?next=https://example.com", "", "https://api.example.com/login?next=https://example.com", nil},
+		{"placeholder", "{{base_url}}/users", "", "{{base_url}}/users", nil},
+		{"path template", "api.example.com/users/{id}", "", "https://api.example.com/users/{id}", nil},
+		{
+			"missing colon", "https//api.example.com", "",
+			"https://api.example.com", []string{`repaired "https//" to "https://"`},
+		},
+		{
+			"single slash", "http:/api.example.com", "",
+			"http://api.example.com", []string{`repaired "http:/" to "http://"`},
+		},
+		{
+			"spaces", "api.example.com/search?q=red shoes", "",
+			"https://api.example.com/search?q=red%20shoes", []string{"URL contains whitespace; it is sent percent-encoded"},
+		},
+		{
+			"stray braces", `api.example.com/search?filter={"a":1}`, "",
+			`https://api.example.com/search?filter={"a":1}`,
+			[]string{"URL contains { or } outside of {name} path templates; they are sent encoded as %7B and %7D"},
+		},
+		{
+			"unsupported scheme", "ftp://files.example.com", "",
+			"ftp://files.example.com", []string{`unsupported scheme "ftp"; use http, https, ws, or wss`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := NormalizeURL(tt.url, tt.scheme)
+			if got != tt.want {
+				t.Errorf("NormalizeURL() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("NormalizeURL() warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
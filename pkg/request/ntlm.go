@@ -0,0 +1,339 @@
+package request
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// NTLM negotiate flags (MS-NLMP 2.2.2.5)
+const (
+	ntlmNegotiateUnicode    = 0x00000001
+	ntlmRequestTarget       = 0x00000004
+	ntlmNegotiateNTLM       = 0x00000200
+	ntlmAlwaysSign          = 0x00008000
+	ntlmExtendedSecurity    = 0x00080000
+	ntlmNegotiateTargetInfo = 0x00800000
+	ntlmNegotiate128        = 0x20000000
+	ntlmNegotiate56         = 0x80000000
+)
+
+// ntlmSignature starts every NTLM message
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmAvTimestamp is the AV pair of the target info that holds the
+// server's time
+const ntlmAvTimestamp = 7
+
+// ntlmEpoch is the Unix epoch in Windows FILETIME, 100ns intervals since
+// 1601
+const ntlmEpoch = 116444736000000000
+
+// For testing
+var (
+	ntlmNow    = time.Now
+	ntlmRandom = rand.Read
+)
+
+// NTLMCredentials splits the username of ntlm auth into the domain and
+// user: DOMAIN\user is split, while user@domain.com and a bare user are
+// sent whole with no domain, as Windows does
+func (a AuthData) NTLMCredentials() (domain, user string) {
+	if domain, user, ok := strings.Cut(a.Username, `\`); ok {
+		return domain, user
+	}
+	return "", a.Username
+}
+
+// ntlmNegotiateMessage returns the first message of the handshake, which
+// offers NTLMv2 with Unicode strings
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateUnicode|ntlmRequestTarget|ntlmNegotiateNTLM|
+		ntlmAlwaysSign|ntlmExtendedSecurity|ntlmNegotiate128|ntlmNegotiate56)
+	// The domain and workstation fields are left empty
+	return msg
+}
+
+// ntlmChallenge is the second message of the handshake, sent by the server
+type ntlmChallenge struct {
+	flags      uint32
+	challenge  []byte
+	targetInfo []byte
+}
+
+// parseNTLMChallenge decodes the server's challenge message
+func parseNTLMChallenge(msg []byte) (*ntlmChallenge, error) {
+	if len(msg) < 32 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return nil, fmt.Errorf("invalid NTLM challenge")
+	}
+	c := &ntlmChallenge{
+		flags:     binary.LittleEndian.Uint32(msg[20:]),
+		challenge: msg[24:32],
+	}
+	if c.flags&ntlmNegotiateTargetInfo != 0 && len(msg) >= 48 {
+		length := int(binary.LittleEndian.Uint16(msg[40:]))
+		offset := int(binary.LittleEndian.Uint32(msg[44:]))
+		if offset+length > len(msg) {
+			return nil, fmt.Errorf("invalid NTLM challenge: target info out of range")
+		}
+		c.targetInfo = msg[offset : offset+length]
+	}
+	return c, nil
+}
+
+// timestamp returns the server's time from the target info, if it sent one
+func (c *ntlmChallenge) timestamp() ([]byte, bool) {
+	info := c.targetInfo
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		length := int(binary.LittleEndian.Uint16(info[2:]))
+		if id == 0 || len(info) < 4+length {
+			break
+		}
+		if id == ntlmAvTimestamp && length == 8 {
+			return info[4:12], true
+		}
+		info = info[4+length:]
+	}
+	return nil, false
+}
+
+// ntowfv2 derives the NTLMv2 key from the password, user, and domain
+func ntowfv2(domain, user, password string) []byte {
+	hash := md4.New()
+	hash.Write(utf16le(password))
+	mac := hmac.New(md5.New, hash.Sum(nil))
+	mac.Write(utf16le(strings.ToUpper(user) + domain))
+	return mac.Sum(nil)
+}
+
+// ntlmv2Responses computes the LMv2 and NTLMv2 responses to the challenge
+// with the client challenge at the time, a FILETIME
+func ntlmv2Responses(c *ntlmChallenge, key, clientChallenge, timestamp []byte) (lm, nt []byte) {
+	temp := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+	temp = append(temp, timestamp...)
+	temp = append(temp, clientChallenge...)
+	temp = append(temp, 0, 0, 0, 0)
+	temp = append(temp, c.targetInfo...)
+	temp = append(temp, 0, 0, 0, 0)
+
+	mac := hmac.New(md5.New, key)
+	mac.Write(c.challenge)
+	mac.Write(temp)
+	nt = append(mac.Sum(nil), temp...)
+
+	mac = hmac.New(md5.New, key)
+	mac.Write(c.challenge)
+	mac.Write(clientChallenge)
+	lm = append(mac.Sum(nil), clientChallenge...)
+	return lm, nt
+}
+
+// ntlmAuthenticateMessage answers the challenge with the NTLMv2 response
+// of the credentials
+func ntlmAuthenticateMessage(c *ntlmChallenge, domain, user, password string) ([]byte, error) {
+	clientChallenge := make([]byte, 8)
+	if _, err := ntlmRandom(clientChallenge); err != nil {
+		return nil, err
+	}
+	timestamp, fromServer := c.timestamp()
+	if !fromServer {
+		timestamp = binary.LittleEndian.AppendUint64(nil, uint64(ntlmNow().UnixNano()/100+ntlmEpoch))
+	}
+	lm, nt := ntlmv2Responses(c, ntowfv2(domain, user, password), clientChallenge, timestamp)
+	if fromServer {
+		// A server that sends its time expects no LMv2 response
+		lm = make([]byte, 24)
+	}
+
+	fields := [][]byte{lm, nt, utf16le(domain), utf16le(user), nil, nil}
+	const headerSize = 64
+	msg := make([]byte, headerSize)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	offset := headerSize
+	for i, field := range fields {
+		header := msg[12+8*i:]
+		binary.LittleEndian.PutUint16(header, uint16(len(field)))
+		binary.LittleEndian.PutUint16(header[2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(header[4:], uint32(offset))
+		msg = append(msg, field...)
+		offset += len(field)
+	}
+	flags := c.flags &^ (ntlmNegotiateTargetInfo | ntlmRequestTarget)
+	binary.LittleEndian.PutUint32(msg[60:], flags|ntlmNegotiateUnicode|ntlmNegotiateNTLM)
+	return msg, nil
+}
+
+// utf16le encodes the string as little-endian UTF-16, as NTLM does with
+// Unicode negotiated
+func utf16le(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], unit)
+	}
+	return b
+}
+
+// ntlmHeaders name the headers and status of an NTLM handshake with a
+// server or, for proxy authentication, with a proxy
+type ntlmHeaders struct {
+	challenge     string
+	authorization string
+	status        int
+}
+
+var (
+	serverNTLM = ntlmHeaders{challenge: "WWW-Authenticate", authorization: "Authorization", status: http.StatusUnauthorized}
+	proxyNTLM  = ntlmHeaders{challenge: "Proxy-Authenticate", authorization: "Proxy-Authorization", status: http.StatusProxyAuthRequired}
+)
+
+// token returns the NTLM message a challenge header carries for the
+// scheme, and whether the server offers the scheme at all
+func (h ntlmHeaders) token(header http.Header, scheme string) (token []byte, offered bool) {
+	for _, value := range header.Values(h.challenge) {
+		name, param, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(name, scheme) {
+			continue
+		}
+		offered = true
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(param)); err == nil && len(decoded) > 0 {
+			return decoded, true
+		}
+	}
+	return nil, offered
+}
+
+// ntlmHandshake authenticates with the NTLM handshake, calling send with
+// the authorization header value of each leg. The legs must be sent on the
+// same connection, since NTLM authenticates the connection rather than the
+// request. The messages are sent with the NTLM scheme, or the Negotiate
+// scheme to servers that only offer that, such as IIS with Windows
+// authentication in its default configuration. It returns the response to
+// the last leg, which is the first when no credentials are asked for.
+func ntlmHandshake(auth AuthData, headers ntlmHeaders, send func(authorization string) (*http.Response, error)) (*http.Response, error) {
+	leg := func(scheme string, msg []byte) (*http.Response, error) {
+		return send(scheme + " " + base64.StdEncoding.EncodeToString(msg))
+	}
+
+	scheme := "NTLM"
+	resp, err := leg(scheme, ntlmNegotiateMessage())
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != headers.status {
+		// The server did not ask for credentials
+		return resp, nil
+	}
+	token, _ := headers.token(resp.Header, scheme)
+	if _, negotiate := headers.token(resp.Header, "Negotiate"); token == nil && negotiate {
+		drain(resp)
+		scheme = "Negotiate"
+		if resp, err = leg(scheme, ntlmNegotiateMessage()); err != nil {
+			return nil, err
+		}
+		token, _ = headers.token(resp.Header, scheme)
+	}
+	if token == nil {
+		// The server does not take NTLM or rejected the negotiation
+		return resp, nil
+	}
+	drain(resp)
+
+	challenge, err := parseNTLMChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+	domain, user := auth.NTLMCredentials()
+	authenticate, err := ntlmAuthenticateMessage(challenge, domain, user, auth.Password)
+	if err != nil {
+		return nil, err
+	}
+	return leg(scheme, authenticate)
+}
+
+// ntlmTransport authenticates requests to a host, or to the proxy they are
+// sent through, with the NTLM handshake, sending its legs on the same
+// keep-alive connection
+type ntlmTransport struct {
+	next http.RoundTripper
+	auth AuthData
+
+	// host is the only host credentials are sent to, so redirects to other
+	// hosts do not get them
+	host string
+
+	// proxy, when set, makes the transport authenticate to the proxy
+	// instead, on the http:// requests sent through it. Those to https://
+	// URLs are authenticated by the CONNECT of their tunnel.
+	proxy *ProxySettings
+}
+
+// RoundTrip sends the request, authenticating it when it is to the host
+// or through the proxy
+func (t *ntlmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := serverNTLM
+	if t.proxy != nil {
+		if req.URL.Scheme != "http" || bypassesProxy(req.URL, t.proxy.Bypass) {
+			return t.next.RoundTrip(req)
+		}
+		headers = proxyNTLM
+	} else if req.URL.Host != t.host {
+		return t.next.RoundTrip(req)
+	}
+	getBody := req.GetBody
+	if req.Body != nil && req.Body != http.NoBody {
+		if getBody == nil {
+			// A stream is kept in memory to send with every leg
+			data, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			getBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		} else {
+			// Every leg sends a copy of its own
+			req.Body.Close()
+		}
+	}
+	return ntlmHandshake(t.auth, headers, func(authorization string) (*http.Response, error) {
+		return t.send(req, getBody, headers.authorization, authorization)
+	})
+}
+
+// send sends a leg of the handshake: a copy of the request with the NTLM
+// message in the header and a fresh copy of the body
+func (t *ntlmTransport) send(req *http.Request, getBody func() (io.ReadCloser, error), header, value string) (*http.Response, error) {
+	leg := req.Clone(req.Context())
+	if getBody != nil {
+		body, err := getBody()
+		if err != nil {
+			return nil, err
+		}
+		leg.Body = body
+	}
+	leg.Header.Set(header, value)
+	return t.next.RoundTrip(leg)
+}
+
+// drain reads what is left of a response the handshake goes past and
+// closes it, so that its connection is kept alive for the next leg
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+}
@@ -0,0 +1,303 @@
+package request
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ntlmTargetInfo is the target info of the MS-NLMP 4.2.4 example: the
+// NetBIOS domain and computer names
+var ntlmTargetInfo = []byte{
+	0x02, 0x00, 0x0c, 0x00, 'D', 0, 'o', 0, 'm', 0, 'a', 0, 'i', 0, 'n', 0,
+	0x01, 0x00, 0x0c, 0x00, 'S', 0, 'e', 0, 'r', 0, 'v', 0, 'e', 0, 'r', 0,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+func TestNTLMv2Responses(t *testing.T) {
+	// MS-NLMP 4.2.4
+	key := ntowfv2("Domain", "User", "Password")
+	if got := hex.EncodeToString(key); got != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Errorf("Unexpected NTOWFv2 %s", got)
+	}
+	challenge := &ntlmChallenge{challenge: []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}, targetInfo: ntlmTargetInfo}
+	clientChallenge := bytes.Repeat([]byte{0xaa}, 8)
+	lm, nt := ntlmv2Responses(challenge, key, clientChallenge, make([]byte, 8))
+	if got := hex.EncodeToString(nt[:16]); got != "68cd0ab851e51c96aabc927bebef6a1c" {
+		t.Errorf("Unexpected NTProofStr %s", got)
+	}
+	if got := hex.EncodeToString(lm); got != "86c35097ac9cec102554764a57cccc19aaaaaaaaaaaaaaaa" {
+		t.Errorf("Unexpected LMv2 response %s", got)
+	}
+}
+
+func TestAuthData_NTLMCredentials(t *testing.T) {
+	tests := []struct{ username, domain, user string }{
+		{`CORP\jane`, "CORP", "jane"},
+		{"jane@corp.example.com", "", "jane@corp.example.com"},
+		{"jane", "", "jane"},
+	}
+	for _, tt := range tests {
+		if domain, user := (AuthData{Username: tt.username}).NTLMCredentials(); domain != tt.domain || user != tt.user {
+			t.Errorf("NTLMCredentials(%q) = %q, %q, want %q, %q", tt.username, domain, user, tt.domain, tt.user)
+		}
+	}
+}
+
+// ntlmServer is an IIS-like server with Windows authentication for one
+// account, offering the schemes given
+type ntlmServer struct {
+	t        *testing.T
+	schemes  []string
+	password string
+
+	// challenged holds the connections that were sent a challenge
+	challenged map[string]bool
+
+	// proxy makes the server authenticate as a proxy, with
+	// Proxy-Authenticate and 407, and serve authenticated requests with it
+	proxy http.HandlerFunc
+}
+
+// field returns a security buffer of an NTLM message
+func field(msg []byte, offset int) []byte {
+	length := int(binary.LittleEndian.Uint16(msg[offset:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+4:]))
+	return msg[start : start+length]
+}
+
+func (s *ntlmServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	headers := serverNTLM
+	if s.proxy != nil {
+		headers = proxyNTLM
+	}
+	body, _ := io.ReadAll(r.Body)
+	scheme, token, _ := strings.Cut(r.Header.Get(headers.authorization), " ")
+	msg, _ := base64.StdEncoding.DecodeString(token)
+	offered := false
+	for _, s := range s.schemes {
+		offered = offered || s == scheme
+	}
+
+	switch {
+	case offered && len(msg) > 12 && binary.LittleEndian.Uint32(msg[8:]) == 1:
+		challenge := make([]byte, 48)
+		copy(challenge, ntlmSignature)
+		binary.LittleEndian.PutUint32(challenge[8:], 2)
+		binary.LittleEndian.PutUint32(challenge[20:], ntlmNegotiateUnicode|ntlmNegotiateNTLM|ntlmExtendedSecurity|ntlmNegotiateTargetInfo)
+		copy(challenge[24:], "\x01\x23\x45\x67\x89\xab\xcd\xef")
+		binary.LittleEndian.PutUint16(challenge[40:], uint16(len(ntlmTargetInfo)))
+		binary.LittleEndian.PutUint16(challenge[42:], uint16(len(ntlmTargetInfo)))
+		binary.LittleEndian.PutUint32(challenge[44:], 48)
+		challenge = append(challenge, ntlmTargetInfo...)
+		s.challenged[r.RemoteAddr] = true
+		w.Header().Set(headers.challenge, scheme+" "+base64.StdEncoding.EncodeToString(challenge))
+		w.WriteHeader(headers.status)
+		return
+
+	case offered && len(msg) > 12 && binary.LittleEndian.Uint32(msg[8:]) == 3:
+		if !s.challenged[r.RemoteAddr] {
+			s.t.Errorf("Expected the authenticate message on the challenged connection, got %s", r.RemoteAddr)
+		}
+		nt := field(msg, 20)
+		domain, user := field(msg, 28), field(msg, 36)
+		key := ntowfv2(decodeUTF16(domain), decodeUTF16(user), s.password)
+		mac := hmac.New(md5.New, key)
+		mac.Write([]byte("\x01\x23\x45\x67\x89\xab\xcd\xef"))
+		mac.Write(nt[16:])
+		if decodeUTF16(domain) == "CORP" && decodeUTF16(user) == "jane" && hmac.Equal(mac.Sum(nil), nt[:16]) {
+			if s.proxy != nil {
+				s.proxy(w, r)
+				return
+			}
+			w.Write([]byte("hello CORP\\jane: " + string(body)))
+			return
+		}
+	}
+	for _, scheme := range s.schemes {
+		w.Header().Add(headers.challenge, scheme)
+	}
+	w.WriteHeader(headers.status)
+}
+
+// decodeUTF16 decodes little-endian UTF-16 ASCII
+func decodeUTF16(b []byte) string {
+	var s strings.Builder
+	for i := 0; i+1 < len(b); i += 2 {
+		s.WriteByte(b[i])
+	}
+	return s.String()
+}
+
+func TestExecute_NTLMAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		schemes  []string
+		password string
+		status   int
+	}{
+		{"NTLM", []string{"Negotiate", "NTLM"}, "s3cret", http.StatusOK},
+		{"Negotiate only", []string{"Negotiate"}, "s3cret", http.StatusOK},
+		{"wrong password", []string{"NTLM"}, "hunter2", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(&ntlmServer{t: t, schemes: tt.schemes, password: tt.password, challenged: make(map[string]bool)})
+			defer server.Close()
+
+			req := NewRequestData()
+			req.Method = "POST"
+			req.URL = server.URL
+			req.Body = `{"report": "q3"}`
+			req.Auth = AuthData{Type: NTLMAuth, Username: `CORP\jane`, Password: "s3cret"}
+			resp, err := req.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, resp.StatusCode, resp.Error)
+			}
+			if tt.status == http.StatusOK && resp.Body != `hello CORP\jane: {"report": "q3"}` {
+				t.Errorf("Expected the body to be sent with the authenticated request, got %q", resp.Body)
+			}
+			if resp.Protocol != "HTTP/1.1" {
+				t.Errorf("Expected HTTP/1.1, got %s", resp.Protocol)
+			}
+		})
+	}
+}
+
+func TestExecute_NTLMAuthNotRequired(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("public"))
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Auth = AuthData{Type: NTLMAuth, Username: "jane", Password: "s3cret"}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != "public" || requests != 1 {
+		t.Errorf("Expected a single request to a server without authentication, got %d requests: %d %q", requests, resp.StatusCode, resp.Body)
+	}
+}
+
+func TestExecute_NTLMConnectionNotShared(t *testing.T) {
+	ResetPool()
+	defer ResetPool()
+
+	ntlm := &ntlmServer{t: t, schemes: []string{"NTLM"}, password: "s3cret", challenged: make(map[string]bool)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && ntlm.challenged[r.RemoteAddr] {
+			t.Errorf("Expected a request without credentials not to be sent on the authenticated connection %s", r.RemoteAddr)
+		}
+		ntlm.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = server.URL
+	req.Auth = AuthData{Type: NTLMAuth, Username: `CORP\jane`, Password: "s3cret"}
+	if resp, err := req.Execute(); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Execute() = %+v, %v", resp, err)
+	}
+
+	// A request with the same connection settings and no credentials
+	anonymous := NewRequestData()
+	anonymous.URL = server.URL
+	anonymous.ProtocolVersion = ProtocolHTTP1
+	resp, err := anonymous.Execute()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the request without credentials to be refused, got %+v, %v", resp, err)
+	}
+	if resp != nil && resp.Timing != nil && resp.Timing.Reused {
+		t.Error("Expected the request without credentials to open a connection of its own")
+	}
+}
+
+// ntlmProxy starts a forward proxy that authenticates with NTLM, passing
+// http:// requests on with their URL in the body and opening CONNECT
+// tunnels
+func ntlmProxy(t *testing.T, password string) *httptest.Server {
+	ntlm := &ntlmServer{t: t, schemes: []string{"NTLM"}, password: password, challenged: make(map[string]bool)}
+	ntlm.proxy = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.Write([]byte("proxied " + r.URL.String()))
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		buf.Flush()
+		go func() {
+			io.Copy(target, conn)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	}
+	proxy := httptest.NewServer(ntlm)
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestExecute_NTLMProxy(t *testing.T) {
+	ResetPool()
+	defer ResetPool()
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tunneled"))
+	}))
+	defer target.Close()
+	proxy := ntlmProxy(t, "s3cret")
+
+	tests := []struct {
+		name     string
+		url      string
+		password string
+		status   int
+		body     string
+		err      string
+	}{
+		{"http", "http://api.example.test/users", "s3cret", http.StatusOK, "proxied http://api.example.test/users", ""},
+		{"https", target.URL, "s3cret", http.StatusOK, "tunneled", ""},
+		{"http wrong password", "http://api.example.test/users", "hunter2", http.StatusProxyAuthRequired, "", ""},
+		{"https wrong password", target.URL, "hunter2", 0, "", "proxy refused CONNECT to " + strings.TrimPrefix(target.URL, "https://") + ": 407"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.URL = tt.url
+			req.Auth.InsecureSkipVerify = true
+			req.Proxy = &ProxySettings{URL: proxy.URL, Username: `CORP\jane`, Password: tt.password, AuthType: NTLMAuth}
+			resp, err := req.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if resp.StatusCode != tt.status || resp.Body != tt.body && tt.body != "" || !strings.Contains(resp.Error, tt.err) {
+				t.Errorf("Expected %d %q, got %d %q (%s)", tt.status, tt.body, resp.StatusCode, resp.Body, resp.Error)
+			}
+		})
+	}
+}
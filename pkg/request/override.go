@@ -0,0 +1,33 @@
+package request
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultMethodOverrideHeader is the conventional method override header
+const DefaultMethodOverrideHeader = "X-HTTP-Method-Override"
+
+// overridableMethods are rewritten to POST when a method override header is set
+var overridableMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// WireMethod returns the method actually sent on the wire, which is POST
+// when the request's method is tunneled through a method override header
+func (r *RequestData) WireMethod() string {
+	if r.MethodOverrideHeader != "" && overridableMethods[strings.ToUpper(r.Method)] {
+		return http.MethodPost
+	}
+	return r.Method
+}
+
+// applyMethodOverride sets the override header when the wire method differs
+// from the request method
+func (r *RequestData) applyMethodOverride(req *http.Request) {
+	if wire := r.WireMethod(); wire != r.Method {
+		req.Header.Set(r.MethodOverrideHeader, strings.ToUpper(r.Method))
+	}
+}
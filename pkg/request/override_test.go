@@ -0,0 +1,56 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestData_WireMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		header string
+		want   string
+	}{
+		{method: "PUT", header: "", want: "PUT"},
+		{method: "PUT", header: DefaultMethodOverrideHeader, want: "POST"},
+		{method: "delete", header: DefaultMethodOverrideHeader, want: "POST"},
+		{method: "PATCH", header: "X-Method", want: "POST"},
+		{method: "GET", header: DefaultMethodOverrideHeader, want: "GET"},
+		{method: "POST", header: DefaultMethodOverrideHeader, want: "POST"},
+	}
+
+	for _, tt := range tests {
+		req := &RequestData{Method: tt.method, MethodOverrideHeader: tt.header}
+		if got := req.WireMethod(); got != tt.want {
+			t.Errorf("WireMethod(%s, %q) = %s, want %s", tt.method, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRequestData_Execute_MethodOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected wire method POST, got %s", r.Method)
+		}
+		if r.Header.Get("X-Method") != "DELETE" {
+			t.Errorf("Expected X-Method: DELETE, got %q", r.Header.Get("X-Method"))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method:               "DELETE",
+		URL:                  server.URL,
+		MethodOverrideHeader: "X-Method",
+		Auth:                 AuthData{Type: NoAuth},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,100 @@
+package request
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PathParamNames returns the names of {name} path templates in rawURL in
+// the order they first appear. Double-brace {{var}} placeholders are ignored.
+func PathParamNames(rawURL string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	scanPathParams(rawURL, func(name string) string {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		return "{" + name + "}"
+	})
+	return names
+}
+
+// MissingPathParams returns the path template names that have no value
+func (r *RequestData) MissingPathParams() []string {
+	var missing []string
+	for _, name := range PathParamNames(r.URL) {
+		if r.PathParams[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// ResolvedURL returns the URL with {name} path templates substituted by
+// their escaped values. Templates without a value are left untouched.
+func (r *RequestData) ResolvedURL() string {
+	return scanPathParams(r.URL, func(name string) string {
+		if value := r.PathParams[name]; value != "" {
+			return url.PathEscape(value)
+		}
+		return "{" + name + "}"
+	})
+}
+
+// validatePathParams ensures every path template has a value
+func (r *RequestData) validatePathParams() error {
+	if missing := r.MissingPathParams(); len(missing) > 0 {
+		return fmt.Errorf("missing path parameters: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// scanPathParams walks s and replaces each single-brace {name} template with
+// the result of replace. Names may contain letters, digits, '_' and '-'.
+func scanPathParams(s string, replace func(name string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '{' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		// Copy {{var}} placeholders through unchanged
+		if strings.HasPrefix(s[i:], "{{") {
+			end := strings.Index(s[i:], "}}")
+			if end < 0 {
+				b.WriteString(s[i:])
+				break
+			}
+			b.WriteString(s[i : i+end+2])
+			i += end + 2
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 || !isPathParamName(s[i+1:i+end]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		b.WriteString(replace(s[i+1 : i+end]))
+		i += end + 1
+	}
+	return b.String()
+}
+
+// isPathParamName reports whether name is a valid path template name
+func isPathParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPathParamNames(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{name: "no templates", url: "https://api.example.com/users", want: nil},
+		{name: "single template", url: "https://api.example.com/users/{id}", want: []string{"id"}},
+		{name: "repeated template", url: "https://api.example.com/{org}/users/{id}/{org}", want: []string{"org", "id"}},
+		{name: "placeholders ignored", url: "{{base_url}}/users/{user-id}", want: []string{"user-id"}},
+		{name: "invalid names ignored", url: "https://api.example.com/{a b}/{}", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PathParamNames(tt.url)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PathParamNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestData_ResolvedURL(t *testing.T) {
+	req := &RequestData{
+		URL: "https://api.example.com/orgs/{org}/users/{id}",
+		PathParams: map[string]string{
+			"org": "acme inc",
+		},
+	}
+
+	if got := req.MissingPathParams(); !reflect.DeepEqual(got, []string{"id"}) {
+		t.Errorf("MissingPathParams() = %v, want [id]", got)
+	}
+	if got := req.ResolvedURL(); got != "https://api.example.com/orgs/acme%20inc/users/{id}" {
+		t.Errorf("ResolvedURL() = %s", got)
+	}
+
+	req.PathParams["id"] = "42"
+	if got := req.MissingPathParams(); len(got) != 0 {
+		t.Errorf("MissingPathParams() = %v, want none", got)
+	}
+	if got := req.ResolvedURL(); got != "https://api.example.com/orgs/acme%20inc/users/42" {
+		t.Errorf("ResolvedURL() = %s", got)
+	}
+}
+
+func TestRequestData_Execute_PathParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/42" {
+			t.Errorf("Expected path /users/42, got %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method:     "GET",
+		URL:        server.URL + "/users/{id}",
+		PathParams: map[string]string{"id": "42"},
+		Auth:       AuthData{Type: NoAuth},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	req.PathParams = nil
+	if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), "missing path parameters: id") {
+		t.Errorf("Expected missing path parameter error, got %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+package request
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Exchange is a request on its way through the execution pipeline
+type Exchange struct {
+	// Data is the request configuration being executed
+	Data *RequestData
+
+	// Request is the outgoing HTTP request, built by the templating stage
+	// and completed by the stages after it
+	Request *http.Request
+
+	// Response is set by the transport stage
+	Response *ResponseData
+
+	// Jar, when set, supplies cookies to the request and its redirects and
+	// stores the cookies the server sets
+	Jar http.CookieJar
+
+	// Transport, when set, sends the request instead of a transport built
+	// from the request's TLS, proxy, resolve, interface, IP version,
+	// connect timeout, and protocol settings, which it is then responsible
+	// for
+	Transport http.RoundTripper
+}
+
+// Abort closes the body of a request that is not sent, which is then ours
+// to close, and returns err. Stages that stop the exchange return
+// x.Abort(err).
+func (x *Exchange) Abort(err error) error {
+	if x.Request != nil && x.Request.Body != nil {
+		x.Request.Body.Close()
+	}
+	return err
+}
+
+// Handler runs an exchange and fills in its Response. Errors that keep the
+// request from being sent are returned; failures to reach the server are
+// reported in Response.Error.
+type Handler func(x *Exchange) error
+
+// Middleware wraps a Handler with cross-cutting behavior such as signing,
+// caching, or logging
+type Middleware func(next Handler) Handler
+
+// Chain wraps h in middleware so that the first middleware runs first
+func Chain(h Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// WithTransport returns middleware that sends the request with rt instead
+// of a transport built from the request's settings, e.g. to instrument the
+// connection or to stub the network in tests. A nil rt leaves the transport
+// untouched.
+func WithTransport(rt http.RoundTripper) Middleware {
+	return func(next Handler) Handler {
+		if rt == nil {
+			return next
+		}
+		return func(x *Exchange) error {
+			x.Transport = rt
+			return next(x)
+		}
+	}
+}
+
+// RoundTripperFunc adapts a function to http.RoundTripper, e.g. to stub
+// responses:
+//
+//	stub := request.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//		return &http.Response{
+//			StatusCode: http.StatusOK,
+//			Header:     http.Header{"Content-Type": {"application/json"}},
+//			Body:       io.NopCloser(strings.NewReader(`{"id": 42}`)),
+//		}, nil
+//	})
+//	resp, err := req.ExecuteWith(request.WithTransport(stub))
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// pipeline lists the built-in stages, in order, that run before any caller
+// supplied middleware; transportStages run after it, right around the
+// transport
+var (
+	pipeline        = []Middleware{templating, authentication, retries, providedCredentials}
+	transportStages = []Middleware{tracing}
+)
+
+// ExecuteWith sends the request through the built-in stages, then the
+// registered middleware, then the given middleware, then the transport.
+// The credentials of profile auth are looked up first, into a copy of the
+// request, so they are never saved with it.
+func (r *RequestData) ExecuteWith(middleware ...Middleware) (*ResponseData, error) {
+	r, err := r.executable()
+	if err != nil {
+		return nil, err
+	}
+	if r.IsWebSocket() {
+		return nil, errWebSocketURL
+	}
+
+	stages := append(append([]Middleware{}, pipeline...), registered()...)
+	stages = append(stages, middleware...)
+	stages = append(stages, transportStages...)
+	x := &Exchange{Data: r}
+	if err := Chain(transport, stages...)(x); err != nil {
+		return nil, err
+	}
+	return x.Response, nil
+}
+
+// executable validates the request, looking up the credentials of profile
+// auth and of the proxy's auth profile into a copy of it
+func (r *RequestData) executable() (*RequestData, error) {
+	proxyProfile := r.Proxy != nil && r.Proxy.Profile != ""
+	if r.Auth.Type == ProfileAuth || proxyProfile {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		resolved := *r
+		if r.Auth.Type == ProfileAuth {
+			auth, err := r.Auth.ResolveProfile()
+			if err != nil {
+				return nil, err
+			}
+			resolved.Auth = auth
+		}
+		if proxyProfile {
+			proxy, err := r.Proxy.resolveProfile()
+			if err != nil {
+				return nil, err
+			}
+			resolved.Proxy = proxy
+		}
+		r = &resolved
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// templating builds the outgoing request from the URL template, query
+// parameters, method override, headers, and body
+func templating(next Handler) Handler {
+	return func(x *Exchange) error {
+		r := x.Data
+
+		// Substitute path parameters, add query parameters, and encode
+		// internationalized hosts and paths
+		wireURL, err := r.WireURL()
+		if err != nil {
+			return err
+		}
+
+		body := r.Body
+		if len(r.FormParams) > 0 {
+			body = r.EncodedForm()
+		}
+		req, err := http.NewRequest(r.WireMethod(), wireURL, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		r.applyMethodOverride(req)
+
+		for key, value := range r.EffectiveHeaders() {
+			req.Header.Add(key, value)
+		}
+		if host := r.hostOverride(); host != "" {
+			req.Host = host
+		}
+		if len(r.FormParams) > 0 {
+			setFormContentType(req)
+		}
+		setAcceptEncoding(req)
+		if r.BodyFile != "" {
+			if err := setBodyFile(req, r.BodyFile); err != nil {
+				return err
+			}
+		}
+		if r.BodyReader != nil {
+			setBodyReader(req, r.BodyReader)
+		}
+		if len(r.Multipart) > 0 {
+			setMultipartBody(req, r.Multipart)
+		}
+		if err := applyBodyFraming(req, r.BodyFraming); err != nil {
+			return err
+		}
+
+		x.Request = req
+		return next(x)
+	}
+}
+
+// authentication adds the request's credentials, minting the JWT of jwt
+// auth and logging in for session auth. Mutual TLS and the NTLM handshake
+// are handled by the transport, and OAuth2, Google, and Entra ID tokens and
+// Kerberos tickets by the providers registered with RegisterAuth, so the
+// request fails here when its type has none.
+func authentication(next Handler) Handler {
+	return func(x *Exchange) error {
+		auth := x.Data.Auth
+		if err := checkAuthProvider(auth.Type); err != nil {
+			return x.Abort(err)
+		}
+		switch auth.Type {
+		case BasicAuth:
+			x.Request.SetBasicAuth(auth.Username, auth.Password)
+
+		case BearerAuth:
+			if auth.Token != "" {
+				x.Request.Header.Add("Authorization", "Bearer "+auth.Token)
+			}
+
+		case APIKeyAuth:
+			if auth.APIKey == "" {
+				break
+			}
+			name, value, query := auth.APIKeyParam()
+			if query {
+				addQueryParam(x.Request.URL, name, value)
+			} else {
+				x.Request.Header.Add(name, value)
+			}
+
+		case JWTAuth:
+			token, err := auth.MintJWT()
+			if err != nil {
+				return x.Abort(err)
+			}
+			x.Request.Header.Add("Authorization", "Bearer "+token)
+
+		case SessionAuth:
+			session, err := x.Data.session()
+			if err != nil {
+				return x.Abort(err)
+			}
+			session.apply(x.Request, auth)
+			err = next(x)
+			if x.Response != nil && x.Response.StatusCode == http.StatusUnauthorized {
+				// The server ended the session, so the next request logs
+				// in again
+				auth.forgetSession()
+			}
+			return err
+		}
+		return next(x)
+	}
+}
+
+// customTransport reports whether the request has TLS, server name, proxy,
+// resolve, interface, IP version, timeout phase, or protocol settings that
+// the default transport does not provide
+func (r *RequestData) customTransport() bool {
+	return r.Auth.Type == MutualTLSAuth || len(r.Pins) > 0 || r.Auth.CAFile != "" || r.Auth.InsecureSkipVerify ||
+		r.ConnectTimeout > 0 || r.TLSTimeout > 0 || r.ResponseHeaderTimeout > 0 || r.protocolVersion() != ProtocolAuto || r.Proxy != nil || len(r.Resolve) > 0 ||
+		r.Interface != "" || r.IPVersion != 0 || r.tlsServerName() != ""
+}
+
+// newTransport returns a transport for the request's TLS, proxy, resolve,
+// timeout phase, and protocol settings, or nil when the default transport
+// serves
+func (r *RequestData) newTransport() (*http.Transport, error) {
+	if !r.customTransport() {
+		return nil, nil
+	}
+	tlsConfig, err := r.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if r.Proxy != nil {
+		transport.Proxy = r.Proxy.proxyFunc()
+	}
+	transport.TLSClientConfig = tlsConfig
+	if r.TLSTimeout > 0 {
+		transport.TLSHandshakeTimeout = r.TLSTimeout
+	}
+	transport.ResponseHeaderTimeout = r.ResponseHeaderTimeout
+	if r.ConnectTimeout > 0 || len(r.Resolve) > 0 || r.Interface != "" || r.IPVersion != 0 {
+		// The default transport allows 30 seconds to connect
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if r.ConnectTimeout > 0 {
+			dialer.Timeout = r.ConnectTimeout
+		}
+		dial := dialer.DialContext
+		if r.Interface != "" {
+			ips, err := localAddresses(r.Interface)
+			if err != nil {
+				return nil, err
+			}
+			dial = bindDialer(ips, dialer)
+		}
+		transport.DialContext = familyDialer(r.IPVersion, resolveDialer(r.Resolve, dial))
+	}
+	if r.Proxy != nil && r.Proxy.ntlm() {
+		transport.DialContext = r.Proxy.ntlmTunnel(transport.DialContext, tlsConfig)
+	}
+	transport.Protocols = r.protocolVersion().protocols()
+	return transport, nil
+}
+
+// transport sends the request with a client configured for the request's
+// TLS, timeout, and redirect settings and reads the response. Requests with
+// the same connection settings share a transport, so keep-alive connections
+// are reused between them.
+func transport(x *Exchange) error {
+	r := x.Data
+
+	var redirects []RedirectHop
+	var http3Fallback *fallbackTransport
+	client := &http.Client{Timeout: r.Timeout, CheckRedirect: r.checkRedirect(&redirects), Jar: x.Jar}
+	if x.Transport != nil {
+		client.Transport = x.Transport
+	} else {
+		transport, err := r.sharedTransport()
+		if err != nil {
+			return x.Abort(err)
+		}
+		if transport != nil {
+			client.Transport = transport
+		}
+		if transport != nil && r.protocolVersion() == ProtocolHTTP3 {
+			h3, err := r.sharedHTTP3Transport()
+			if err != nil {
+				return x.Abort(err)
+			}
+			http3Fallback = &fallbackTransport{h3: h3, tcp: transport, proxy: r.Proxy}
+			client.Transport = http3Fallback
+		}
+	}
+	if r.Proxy != nil && r.Proxy.ntlm() {
+		next := client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		proxyAuth := AuthData{Type: NTLMAuth, Username: r.Proxy.Username, Password: r.Proxy.Password}
+		client.Transport = &ntlmTransport{next: next, auth: proxyAuth, proxy: r.Proxy}
+	}
+	if r.Auth.Type == NTLMAuth {
+		next := client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		client.Transport = &ntlmTransport{next: next, auth: r.Auth, host: x.Request.URL.Host}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(x.Request)
+	duration := time.Since(start)
+
+	if err != nil {
+		x.Response = &ResponseData{
+			Error:        err.Error(),
+			ErrorKind:    ClassifyError(err),
+			ResponseTime: duration,
+			Redirects:    redirects,
+		}
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// Stop reading once the body outgrows the limit instead of holding all
+	// of it in memory
+	bodyBytes, truncated, err := readLimited(resp.Body, r.MaxResponseSize)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+
+	x.Response = &ResponseData{
+		StatusCode:       resp.StatusCode,
+		Protocol:         resp.Proto,
+		ProtocolFallback: http3Fallback.fallback(),
+		Headers:          headers,
+		Body:             string(bodyBytes),
+		ResponseTime:     duration,
+		RetryAfter:       retryAfter(resp, time.Now()),
+		Redirects:        redirects,
+		Truncated:        truncated,
+	}
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && len(bodyBytes) > 0 {
+		x.Response.ContentEncoding = encoding
+		x.Response.CompressedSize = int64(len(bodyBytes))
+		if decoded, truncated, err := decodeBody(bodyBytes, encoding, r.MaxResponseSize, truncated); err != nil {
+			x.Response.DecodeError = err.Error()
+		} else {
+			x.Response.Body = string(decoded)
+			x.Response.DecompressedSize = int64(len(decoded))
+			x.Response.Truncated = truncated
+		}
+	}
+	body := []byte(x.Response.Body)
+	x.Response.Binary = isBinaryBody(resp.Header.Get("Content-Type"), body)
+	if x.Response.DecodeError == "" && !x.Response.Binary {
+		if charset, source := detectCharset(resp.Header.Get("Content-Type"), body); charset != "" {
+			text, converted := transcodeBody(body, charset)
+			x.Response.Body = string(text)
+			if converted {
+				x.Response.Charset = charset
+				x.Response.CharsetSource = source
+			}
+		}
+	}
+	if resp.TLS != nil {
+		x.Response.TLS = newTLSInfo(resp.TLS, r.CheckRevocation)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(x *Exchange) error {
+				order = append(order, name+" before")
+				err := next(x)
+				order = append(order, name+" after")
+				return err
+			}
+		}
+	}
+
+	handler := Chain(func(x *Exchange) error {
+		order = append(order, "handler")
+		return nil
+	}, trace("outer"), trace("inner"))
+
+	if err := handler(&Exchange{}); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+	want := []string{"outer before", "inner before", "handler", "inner after", "outer after"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Chain order = %v, want %v", order, want)
+	}
+}
+
+func TestRequestData_ExecuteWith(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Signature", r.Header.Get("X-Signature"))
+		w.Header().Set("X-Seen-Authorization", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	// Middleware sees the fully built request, including credentials, and
+	// the response once the transport is done
+	var seenStatus int
+	sign := func(next Handler) Handler {
+		return func(x *Exchange) error {
+			x.Request.Header.Set("X-Signature", x.Request.Method+" "+x.Request.URL.Path)
+			err := next(x)
+			seenStatus = x.Response.StatusCode
+			return err
+		}
+	}
+
+	req := &RequestData{
+		Method: "GET",
+		URL:    server.URL + "/orders/{id}",
+		PathParams: map[string]string{
+			"id": "7",
+		},
+		Auth: AuthData{Type: APIKeyAuth, APIKey: "secret"},
+	}
+	resp, err := req.ExecuteWith(sign)
+	if err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if resp.Headers["X-Seen-Signature"] != "GET /orders/7" {
+		t.Errorf("Expected signature header, got %q", resp.Headers["X-Seen-Signature"])
+	}
+	if resp.Headers["X-Seen-Authorization"] != "Bearer secret" {
+		t.Errorf("Expected credentials to be applied, got %q", resp.Headers["X-Seen-Authorization"])
+	}
+	if seenStatus != http.StatusOK {
+		t.Errorf("Expected middleware to see the response, got status %d", seenStatus)
+	}
+
+	// Middleware can stop a request before it is sent
+	errBlocked := errors.New("blocked")
+	block := func(next Handler) Handler {
+		return func(x *Exchange) error { return errBlocked }
+	}
+	if _, err := req.ExecuteWith(block); !errors.Is(err, errBlocked) {
+		t.Errorf("Expected middleware error, got %v", err)
+	}
+}
+
+// closeRecorder is a request body that records being closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestExchange_Abort(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("{}")}
+	req, _ := http.NewRequest("POST", "http://example.com", body)
+	x := &Exchange{Request: req}
+
+	errBlocked := errors.New("blocked")
+	if err := x.Abort(errBlocked); err != errBlocked {
+		t.Errorf("Abort() = %v, want %v", err, errBlocked)
+	}
+	if !body.closed {
+		t.Error("Expected the request body to be closed")
+	}
+
+	// Stages before templating have no request yet
+	if err := (&Exchange{}).Abort(errBlocked); err != errBlocked {
+		t.Errorf("Abort() = %v, want %v", err, errBlocked)
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	var sent *http.Request
+	stub := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"id": 42}`)),
+			Request:    req,
+		}, nil
+	})
+
+	// The stub answers without a server, even with settings that would
+	// otherwise build a transport
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = "https://api.invalid/users"
+	req.Body = `{"name": "Jane"}`
+	req.Auth = AuthData{Type: APIKeyAuth, APIKey: "token"}
+	req.ConnectTimeout = time.Second
+	resp, err := req.ExecuteWith(WithTransport(stub))
+	if err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if resp.Error != "" || resp.StatusCode != http.StatusCreated || resp.Body != `{"id": 42}` {
+		t.Errorf("Expected the stubbed response, got %+v", resp)
+	}
+	if sent == nil || sent.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected the stub to get the finished request, got %v", sent)
+	}
+
+	// Transport errors are reported like network failures
+	failing := RoundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("stubbed failure")
+	})
+	resp, err = req.ExecuteWith(WithTransport(failing))
+	if err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if !strings.Contains(resp.Error, "stubbed failure") {
+		t.Errorf("Expected the transport error in the response, got %q", resp.Error)
+	}
+
+	// A nil transport leaves the request to the default one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	req = NewRequestData()
+	req.URL = server.URL
+	if resp, err := req.ExecuteWith(WithTransport(nil)); err != nil || resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected the request to reach the server, got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,622 @@
+package request
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/bits"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// errWrongPassword is returned when a PKCS#12 bundle or an encrypted key
+// does not decrypt with the password given
+var errWrongPassword = errors.New("wrong password")
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidPBEWithSHA3DES    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHA128RC2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHA40RC2   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBES2             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1      = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256    = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384    = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512    = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC        = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	pkcs12HashAlgorithms = []struct {
+		oid  asn1.ObjectIdentifier
+		hash func() hash.Hash
+	}{
+		{oidSHA1, sha1.New}, {oidSHA256, sha256.New}, {oidSHA384, sha512.New384}, {oidSHA512, sha512.New},
+	}
+	pbkdf2PRFs = []struct {
+		oid  asn1.ObjectIdentifier
+		hash func() hash.Hash
+	}{
+		{oidHMACWithSHA1, sha1.New}, {oidHMACWithSHA256, sha256.New}, {oidHMACWithSHA384, sha512.New384}, {oidHMACWithSHA512, sha512.New},
+	}
+)
+
+// The ASN.1 structures of PKCS#12 (RFC 7292), PKCS#7, and PKCS#5 (RFC 8018)
+type (
+	pfxPDU struct {
+		Version  int
+		AuthSafe contentInfo
+		MacData  macData `asn1:"optional"`
+	}
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+	}
+	macData struct {
+		Mac        digestInfo
+		MacSalt    []byte
+		Iterations int `asn1:"optional,default:1"`
+	}
+	digestInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		Digest    []byte
+	}
+	encryptedData struct {
+		Version              int
+		EncryptedContentInfo encryptedContentInfo
+	}
+	encryptedContentInfo struct {
+		ContentType                asn1.ObjectIdentifier
+		ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedContent           []byte `asn1:"tag:0,optional"`
+	}
+	safeBag struct {
+		ID         asn1.ObjectIdentifier
+		Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+		Attributes []pkcs12Attribute `asn1:"set,optional"`
+	}
+	pkcs12Attribute struct {
+		ID    asn1.ObjectIdentifier
+		Value asn1.RawValue `asn1:"set"`
+	}
+	certBag struct {
+		ID   asn1.ObjectIdentifier
+		Data []byte `asn1:"tag:0,explicit"`
+	}
+	encryptedPrivateKeyInfo struct {
+		Algorithm     pkix.AlgorithmIdentifier
+		EncryptedData []byte
+	}
+	pbeParams struct {
+		Salt       []byte
+		Iterations int
+	}
+	pbes2Params struct {
+		KeyDerivationFunc pkix.AlgorithmIdentifier
+		EncryptionScheme  pkix.AlgorithmIdentifier
+	}
+	pbkdf2Params struct {
+		Salt       []byte
+		Iterations int
+		KeyLength  int                      `asn1:"optional"`
+		PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+	}
+)
+
+// decodePKCS12 returns the client certificate of a PKCS#12 bundle (.p12 or
+// .pfx): the certificate of its private key, followed by the other
+// certificates of the bundle as its chain. Bundles encrypted with AES, as
+// OpenSSL 3 and recent Windows versions export them, and with the 3DES and
+// RC2 of older exports are supported.
+func decodePKCS12(data []byte, password string) (tls.Certificate, error) {
+	data, err := berToDER(data)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+	}
+	var pfx pfxPDU
+	if rest, err := asn1.Unmarshal(data, &pfx); err != nil || len(rest) > 0 {
+		return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+	}
+	if pfx.Version != 3 || !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return tls.Certificate{}, fmt.Errorf("unsupported PKCS#12 bundle: only password integrity is supported")
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+	}
+	if len(pfx.MacData.Mac.Digest) > 0 {
+		if err := pfx.MacData.verify(authSafe, password); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+	}
+	var keys []crypto.PrivateKey
+	var certs []*x509.Certificate
+	for _, content := range contents {
+		var safe []byte
+		switch {
+		case content.ContentType.Equal(oidData):
+			if _, err := asn1.Unmarshal(content.Content.Bytes, &safe); err != nil {
+				return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+			}
+		case content.ContentType.Equal(oidEncryptedData):
+			var encrypted encryptedData
+			if _, err := asn1.Unmarshal(content.Content.Bytes, &encrypted); err != nil {
+				return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+			}
+			info := encrypted.EncryptedContentInfo
+			if safe, err = pbeDecrypt(info.ContentEncryptionAlgorithm, password, info.EncryptedContent); err != nil {
+				return tls.Certificate{}, err
+			}
+		default:
+			// Public-key privacy, which needs a key of its own, is not
+			// supported
+			continue
+		}
+
+		var bags []safeBag
+		if _, err := asn1.Unmarshal(safe, &bags); err != nil {
+			return tls.Certificate{}, fmt.Errorf("invalid PKCS#12 bundle: %v", err)
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidShroudedKeyBag):
+				der := bag.Value.Bytes
+				if bag.ID.Equal(oidShroudedKeyBag) {
+					if der, err = decryptPrivateKeyInfo(der, password); err != nil {
+						return tls.Certificate{}, err
+					}
+				}
+				key, err := x509.ParsePKCS8PrivateKey(der)
+				if err != nil {
+					return tls.Certificate{}, fmt.Errorf("invalid private key in PKCS#12 bundle: %v", err)
+				}
+				keys = append(keys, key)
+			case bag.ID.Equal(oidCertBag):
+				var bagCert certBag
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &bagCert); err != nil || !bagCert.ID.Equal(oidX509Certificate) {
+					continue
+				}
+				cert, err := x509.ParseCertificate(bagCert.Data)
+				if err != nil {
+					return tls.Certificate{}, fmt.Errorf("invalid certificate in PKCS#12 bundle: %v", err)
+				}
+				certs = append(certs, cert)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return tls.Certificate{}, fmt.Errorf("no private key in PKCS#12 bundle")
+	}
+	key := keys[0]
+	public, ok := key.(crypto.Signer)
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("unsupported private key type %T in PKCS#12 bundle", key)
+	}
+	leaf := -1
+	for i, cert := range certs {
+		if equal, ok := public.Public().(interface{ Equal(crypto.PublicKey) bool }); ok && equal.Equal(cert.PublicKey) {
+			leaf = i
+			break
+		}
+	}
+	if leaf < 0 {
+		return tls.Certificate{}, fmt.Errorf("no certificate for the private key in PKCS#12 bundle")
+	}
+	result := tls.Certificate{PrivateKey: key, Leaf: certs[leaf], Certificate: [][]byte{certs[leaf].Raw}}
+	for i, cert := range certs {
+		if i != leaf {
+			result.Certificate = append(result.Certificate, cert.Raw)
+		}
+	}
+	return result, nil
+}
+
+// verify checks the MAC of the bundle's contents, which tells a wrong
+// password apart from a damaged bundle
+func (m macData) verify(content []byte, password string) error {
+	var newHash func() hash.Hash
+	for _, alg := range pkcs12HashAlgorithms {
+		if m.Mac.Algorithm.Algorithm.Equal(alg.oid) {
+			newHash = alg.hash
+		}
+	}
+	if newHash == nil {
+		return fmt.Errorf("unsupported PKCS#12 MAC algorithm %v", m.Mac.Algorithm.Algorithm)
+	}
+	// An empty password is encoded as nothing by some tools and as an
+	// empty string by others
+	candidates := [][]byte{bmpString(password)}
+	if password == "" {
+		candidates = append(candidates, nil)
+	}
+	for _, candidate := range candidates {
+		key := pkcs12KDF(newHash, candidate, m.MacSalt, m.Iterations, 3, newHash().Size())
+		mac := hmac.New(newHash, key)
+		mac.Write(content)
+		if hmac.Equal(mac.Sum(nil), m.Mac.Digest) {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to open PKCS#12 bundle: %v", errWrongPassword)
+}
+
+// decryptPrivateKeyInfo decrypts an EncryptedPrivateKeyInfo, as found in
+// PKCS#12 bundles and ENCRYPTED PRIVATE KEY PEM blocks, to the DER of its
+// PKCS#8 key
+func decryptPrivateKeyInfo(der []byte, password string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key: %v", err)
+	}
+	return pbeDecrypt(info.Algorithm, password, info.EncryptedData)
+}
+
+// pbeDecrypt decrypts data encrypted with a password: with PBES2 and AES or
+// 3DES, or with the SHA-1 based 3DES and RC2 schemes of PKCS#12
+func pbeDecrypt(alg pkix.AlgorithmIdentifier, password string, data []byte) ([]byte, error) {
+	var block cipher.Block
+	var iv []byte
+	var err error
+	switch {
+	case alg.Algorithm.Equal(oidPBES2):
+		block, iv, err = pbes2Cipher(alg.Parameters.FullBytes, password)
+	case alg.Algorithm.Equal(oidPBEWithSHA3DES), alg.Algorithm.Equal(oidPBEWithSHA128RC2), alg.Algorithm.Equal(oidPBEWithSHA40RC2):
+		var params pbeParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("invalid PBE parameters: %v", err)
+		}
+		passwordBytes := bmpString(password)
+		iv = pkcs12KDF(sha1.New, passwordBytes, params.Salt, params.Iterations, 2, 8)
+		switch {
+		case alg.Algorithm.Equal(oidPBEWithSHA3DES):
+			block, err = des.NewTripleDESCipher(pkcs12KDF(sha1.New, passwordBytes, params.Salt, params.Iterations, 1, 24))
+		case alg.Algorithm.Equal(oidPBEWithSHA128RC2):
+			block = newRC2(pkcs12KDF(sha1.New, passwordBytes, params.Salt, params.Iterations, 1, 16), 128)
+		default:
+			block = newRC2(pkcs12KDF(sha1.New, passwordBytes, params.Salt, params.Iterations, 1, 5), 40)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %v", alg.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 || len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid encrypted data")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	// A wrong password shows as invalid padding
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("failed to decrypt: %v", errWrongPassword)
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+// pbes2Cipher returns the cipher and IV of PBES2 parameters, with the key
+// derived from the password with PBKDF2. The password is used as UTF-8, as
+// OpenSSL does, not as the BMPString of the older schemes.
+func pbes2Cipher(der []byte, password string) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(der, &params); err != nil {
+		return nil, nil, fmt.Errorf("invalid PBES2 parameters: %v", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("invalid PBKDF2 parameters: %v", err)
+	}
+	prf := sha1.New
+	if len(kdf.PRF.Algorithm) > 0 {
+		prf = nil
+		for _, candidate := range pbkdf2PRFs {
+			if kdf.PRF.Algorithm.Equal(candidate.oid) {
+				prf = candidate.hash
+			}
+		}
+		if prf == nil {
+			return nil, nil, fmt.Errorf("unsupported PBKDF2 function %v", kdf.PRF.Algorithm)
+		}
+	}
+
+	var keySize int
+	scheme := params.EncryptionScheme.Algorithm
+	switch {
+	case scheme.Equal(oidAES128CBC):
+		keySize = 16
+	case scheme.Equal(oidAES192CBC), scheme.Equal(oidDESEDE3CBC):
+		keySize = 24
+	case scheme.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, nil, fmt.Errorf("unsupported encryption scheme %v", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("invalid IV: %v", err)
+	}
+
+	key := pbkdf2.Key([]byte(password), kdf.Salt, kdf.Iterations, keySize, prf)
+	if scheme.Equal(oidDESEDE3CBC) {
+		block, err := des.NewTripleDESCipher(key)
+		return block, iv, err
+	}
+	block, err := aes.NewCipher(key)
+	return block, iv, err
+}
+
+// bmpString encodes a password as the NUL terminated UTF-16 of PKCS#12
+func bmpString(s string) []byte {
+	var b []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		b = binary.BigEndian.AppendUint16(b, r)
+	}
+	return append(b, 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material for the purpose id (1 for
+// keys, 2 for IVs, 3 for MAC keys) from a password (RFC 7292 B.2)
+func pkcs12KDF(newHash func() hash.Hash, password, salt []byte, iterations, id, size int) []byte {
+	h := newHash()
+	u, v := h.Size(), h.BlockSize()
+	fill := func(data []byte) []byte {
+		if len(data) == 0 {
+			return nil
+		}
+		filled := make([]byte, v*((len(data)+v-1)/v))
+		for i := range filled {
+			filled[i] = data[i%len(data)]
+		}
+		return filled
+	}
+	d := bytes.Repeat([]byte{byte(id)}, v)
+	input := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < size {
+		h.Reset()
+		h.Write(d)
+		h.Write(input)
+		a := h.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		// Each block of the input becomes block + B + 1, where B is A
+		// repeated to v bytes
+		b := make([]byte, v)
+		for i := range b {
+			b[i] = a[i%u]
+		}
+		for j := 0; j < len(input); j += v {
+			carry := 1
+			for i := v - 1; i >= 0; i-- {
+				sum := int(input[j+i]) + int(b[i]) + carry
+				input[j+i] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out[:size]
+}
+
+// berToDER converts the indefinite lengths of BER, with which some tools
+// write PKCS#12 bundles, to the definite lengths encoding/asn1 reads
+func berToDER(data []byte) ([]byte, error) {
+	out, rest, err := convertBER(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data")
+	}
+	return out, nil
+}
+
+// convertBER converts the element at the start of data, returning the rest
+func convertBER(data []byte) ([]byte, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("truncated element")
+	}
+	// Identifier octets, with the high tag numbers of long form
+	header := 1
+	if data[0]&0x1f == 0x1f {
+		for header < len(data) && data[header]&0x80 != 0 {
+			header++
+		}
+		header++
+	}
+	if header >= len(data) {
+		return nil, nil, fmt.Errorf("truncated element")
+	}
+	tag := data[:header]
+	constructed := data[0]&0x20 != 0
+
+	if data[header] == 0x80 {
+		// Indefinite length: contents run until an end-of-contents element
+		if !constructed {
+			return nil, nil, fmt.Errorf("indefinite length of a primitive element")
+		}
+		rest := data[header+1:]
+		var contents []byte
+		for {
+			if len(rest) >= 2 && rest[0] == 0 && rest[1] == 0 {
+				return append(append(append([]byte{}, tag...), derLength(len(contents))...), contents...), rest[2:], nil
+			}
+			element, after, err := convertBER(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, element...)
+			rest = after
+		}
+	}
+
+	length, lengthSize := int(data[header]), 1
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n > 4 || header+1+n > len(data) {
+			return nil, nil, fmt.Errorf("invalid length")
+		}
+		length = 0
+		for _, b := range data[header+1 : header+1+n] {
+			length = length<<8 | int(b)
+		}
+		lengthSize += n
+	}
+	start := header + lengthSize
+	if length < 0 || length > len(data)-start {
+		return nil, nil, fmt.Errorf("truncated element")
+	}
+	contents, rest := data[start:start+length], data[start+length:]
+	if !constructed {
+		return append(append(append([]byte{}, tag...), derLength(len(contents))...), contents...), rest, nil
+	}
+	var converted []byte
+	for len(contents) > 0 {
+		element, after, err := convertBER(contents)
+		if err != nil {
+			return nil, nil, err
+		}
+		converted = append(converted, element...)
+		contents = after
+	}
+	return append(append(append([]byte{}, tag...), derLength(len(converted))...), converted...), rest, nil
+}
+
+// derLength encodes a length in the definite form
+func derLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// rc2PITable is the permutation of the RC2 key expansion (RFC 2268)
+var rc2PITable = [256]byte{
+	0xd9, 0x78, 0xf9, 0xc4, 0x19, 0xdd, 0xb5, 0xed, 0x28, 0xe9, 0xfd, 0x79, 0x4a, 0xa0, 0xd8, 0x9d,
+	0xc6, 0x7e, 0x37, 0x83, 0x2b, 0x76, 0x53, 0x8e, 0x62, 0x4c, 0x64, 0x88, 0x44, 0x8b, 0xfb, 0xa2,
+	0x17, 0x9a, 0x59, 0xf5, 0x87, 0xb3, 0x4f, 0x13, 0x61, 0x45, 0x6d, 0x8d, 0x09, 0x81, 0x7d, 0x32,
+	0xbd, 0x8f, 0x40, 0xeb, 0x86, 0xb7, 0x7b, 0x0b, 0xf0, 0x95, 0x21, 0x22, 0x5c, 0x6b, 0x4e, 0x82,
+	0x54, 0xd6, 0x65, 0x93, 0xce, 0x60, 0xb2, 0x1c, 0x73, 0x56, 0xc0, 0x14, 0xa7, 0x8c, 0xf1, 0xdc,
+	0x12, 0x75, 0xca, 0x1f, 0x3b, 0xbe, 0xe4, 0xd1, 0x42, 0x3d, 0xd4, 0x30, 0xa3, 0x3c, 0xb6, 0x26,
+	0x6f, 0xbf, 0x0e, 0xda, 0x46, 0x69, 0x07, 0x57, 0x27, 0xf2, 0x1d, 0x9b, 0xbc, 0x94, 0x43, 0x03,
+	0xf8, 0x11, 0xc7, 0xf6, 0x90, 0xef, 0x3e, 0xe7, 0x06, 0xc3, 0xd5, 0x2f, 0xc8, 0x66, 0x1e, 0xd7,
+	0x08, 0xe8, 0xea, 0xde, 0x80, 0x52, 0xee, 0xf7, 0x84, 0xaa, 0x72, 0xac, 0x35, 0x4d, 0x6a, 0x2a,
+	0x96, 0x1a, 0xd2, 0x71, 0x5a, 0x15, 0x49, 0x74, 0x4b, 0x9f, 0xd0, 0x5e, 0x04, 0x18, 0xa4, 0xec,
+	0xc2, 0xe0, 0x41, 0x6e, 0x0f, 0x51, 0xcb, 0xcc, 0x24, 0x91, 0xaf, 0x50, 0xa1, 0xf4, 0x70, 0x39,
+	0x99, 0x7c, 0x3a, 0x85, 0x23, 0xb8, 0xb4, 0x7a, 0xfc, 0x02, 0x36, 0x5b, 0x25, 0x55, 0x97, 0x31,
+	0x2d, 0x5d, 0xfa, 0x98, 0xe3, 0x8a, 0x92, 0xae, 0x05, 0xdf, 0x29, 0x10, 0x67, 0x6c, 0xba, 0xc9,
+	0xd3, 0x00, 0xe6, 0xcf, 0xe1, 0x9e, 0xa8, 0x2c, 0x63, 0x16, 0x01, 0x3f, 0x58, 0xe2, 0x89, 0xa9,
+	0x0d, 0x38, 0x34, 0x1b, 0xab, 0x33, 0xff, 0xb0, 0xbb, 0x48, 0x0c, 0x5f, 0xb9, 0xb1, 0xcd, 0x2e,
+	0xc5, 0xf3, 0xdb, 0x47, 0xe5, 0xa5, 0x9c, 0x77, 0x0a, 0xa6, 0x20, 0x68, 0xfe, 0x7f, 0xc1, 0xad,
+}
+
+// rc2 is the RC2 cipher (RFC 2268) of older PKCS#12 bundles, which still
+// encrypt their certificates with 40-bit RC2; only decryption is needed
+type rc2 struct {
+	k [64]uint16
+}
+
+// newRC2 expands the key for the effective key length in bits
+func newRC2(key []byte, effectiveBits int) *rc2 {
+	var l [128]byte
+	copy(l[:], key)
+	t := len(key)
+	t8 := (effectiveBits + 7) / 8
+	modulus := 1 << (8 + effectiveBits - 8*t8)
+	tm := byte(255 % modulus)
+	for i := t; i < 128; i++ {
+		l[i] = rc2PITable[l[i-1]+l[i-t]]
+	}
+	l[128-t8] = rc2PITable[l[128-t8]&tm]
+	for i := 127 - t8; i >= 0; i-- {
+		l[i] = rc2PITable[l[i+1]^l[i+t8]]
+	}
+	c := &rc2{}
+	for i := range c.k {
+		c.k[i] = binary.LittleEndian.Uint16(l[2*i:])
+	}
+	return c
+}
+
+func (c *rc2) BlockSize() int { return 8 }
+
+func (c *rc2) Encrypt(dst, src []byte) {
+	panic("rc2: encryption is not supported")
+}
+
+// Decrypt undoes the five mixing rounds, mashing round, six mixing rounds,
+// mashing round, and five mixing rounds of encryption
+func (c *rc2) Decrypt(dst, src []byte) {
+	var r [4]uint16
+	for i := range r {
+		r[i] = binary.LittleEndian.Uint16(src[2*i:])
+	}
+	shifts := [4]int{1, 2, 3, 5}
+	j := 63
+	mix := func() {
+		for i := 3; i >= 0; i-- {
+			r[i] = bits.RotateLeft16(r[i], -shifts[i])
+			r[i] -= c.k[j] + (r[(i+3)%4] & r[(i+2)%4]) + (^r[(i+3)%4] & r[(i+1)%4])
+			j--
+		}
+	}
+	mash := func() {
+		for i := 3; i >= 0; i-- {
+			r[i] -= c.k[r[(i+3)%4]&63]
+		}
+	}
+	for round := 0; round < 16; round++ {
+		mix()
+		if round == 4 || round == 10 {
+			mash()
+		}
+	}
+	for i := range r {
+		binary.LittleEndian.PutUint16(dst[2*i:], r[i])
+	}
+}
@@ -0,0 +1,196 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// maxTransports bounds the transports kept for reuse; when it is reached
+// they are closed and the pool starts over
+const maxTransports = 32
+
+// transports keeps the transports built for requests with custom TLS,
+// proxy, resolve, connect timeout, or protocol settings, so that repeated
+// requests with the same settings reuse their keep-alive connections the
+// way requests on the default transport do
+var transports struct {
+	sync.Mutex
+	byKey map[string]*http.Transport
+
+	// http3ByKey keeps the HTTP/3 transports of ProtocolHTTP3 requests
+	http3ByKey map[string]*http3.Transport
+}
+
+// connections counts the connections requests were sent on
+var connections struct {
+	opened, reused atomic.Int64
+}
+
+// PoolStats counts the connections requests were sent on since the process
+// started or ResetPool was called
+type PoolStats struct {
+	// Opened connections were dialed for a request and Reused ones were
+	// kept alive from an earlier request
+	Opened int64 `json:"opened"`
+	Reused int64 `json:"reused"`
+
+	// Transports is the number of transports kept for requests with custom
+	// connection settings
+	Transports int `json:"transports"`
+}
+
+// ConnectionStats returns the connection pool counts
+func ConnectionStats() PoolStats {
+	transports.Lock()
+	defer transports.Unlock()
+	return PoolStats{
+		Opened:     connections.opened.Load(),
+		Reused:     connections.reused.Load(),
+		Transports: len(transports.byKey) + len(transports.http3ByKey),
+	}
+}
+
+// ResetPool closes the idle connections of every transport, so that the
+// next requests open new ones, and resets ConnectionStats
+func ResetPool() {
+	transports.Lock()
+	defer transports.Unlock()
+	closeTransports()
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	connections.opened.Store(0)
+	connections.reused.Store(0)
+}
+
+// countConnection records a connection a request was sent on
+func countConnection(reused bool) {
+	if reused {
+		connections.reused.Add(1)
+	} else {
+		connections.opened.Add(1)
+	}
+}
+
+// sharedTransport returns the transport for the request's connection
+// settings, building it on first use, or nil when the default transport
+// serves
+func (r *RequestData) sharedTransport() (*http.Transport, error) {
+	if !r.customTransport() {
+		return nil, nil
+	}
+	key, err := r.transportKey()
+	if err != nil {
+		return nil, err
+	}
+
+	transports.Lock()
+	defer transports.Unlock()
+	if transport, ok := transports.byKey[key]; ok {
+		return transport, nil
+	}
+	transport, err := r.newTransport()
+	if err != nil {
+		return nil, err
+	}
+	if len(transports.byKey)+len(transports.http3ByKey) >= maxTransports {
+		closeTransports()
+	}
+	if transports.byKey == nil {
+		transports.byKey = make(map[string]*http.Transport)
+	}
+	transports.byKey[key] = transport
+	return transport, nil
+}
+
+// sharedHTTP3Transport returns the HTTP/3 transport for the request's
+// connection settings, building it on first use
+func (r *RequestData) sharedHTTP3Transport() (*http3.Transport, error) {
+	key, err := r.transportKey()
+	if err != nil {
+		return nil, err
+	}
+
+	transports.Lock()
+	defer transports.Unlock()
+	if transport, ok := transports.http3ByKey[key]; ok {
+		return transport, nil
+	}
+	transport, err := r.newHTTP3Transport()
+	if err != nil {
+		return nil, err
+	}
+	if len(transports.byKey)+len(transports.http3ByKey) >= maxTransports {
+		closeTransports()
+	}
+	if transports.http3ByKey == nil {
+		transports.http3ByKey = make(map[string]*http3.Transport)
+	}
+	transports.http3ByKey[key] = transport
+	return transport, nil
+}
+
+// closeTransports closes the idle connections of the kept transports and
+// drops them; the caller holds the transports lock
+func closeTransports() {
+	for _, transport := range transports.byKey {
+		transport.CloseIdleConnections()
+	}
+	for _, transport := range transports.http3ByKey {
+		// Requests still in flight on an HTTP/3 transport fail when it is
+		// closed, so only its idle connections are
+		transport.CloseIdleConnections()
+	}
+	transports.byKey = nil
+	transports.http3ByKey = nil
+}
+
+// transportKey identifies the settings newTransport builds a transport
+// from. Certificate files are identified by name, so a transport built
+// before a file changed keeps the old certificate until ResetPool. NTLM
+// authenticates the connection rather than the request, so ntlm auth
+// requests have a transport per account, whose connections no request
+// without the account's credentials is sent on.
+func (r *RequestData) transportKey() (string, error) {
+	settings := struct {
+		Auth            AuthData
+		Pins            []string
+		ConnectTimeout  time.Duration
+		TLSTimeout      time.Duration
+		HeaderTimeout   time.Duration
+		ProtocolVersion ProtocolVersion
+		Proxy           *ProxySettings
+		Resolve         map[string]string
+		Interface       string
+		IPVersion       int
+		ServerName      string
+	}{
+		Auth:            AuthData{CAFile: r.Auth.CAFile, CAFileOnly: r.Auth.CAFileOnly, InsecureSkipVerify: r.Auth.InsecureSkipVerify},
+		Pins:            r.Pins,
+		ConnectTimeout:  r.ConnectTimeout,
+		TLSTimeout:      r.TLSTimeout,
+		HeaderTimeout:   r.ResponseHeaderTimeout,
+		ProtocolVersion: r.protocolVersion(),
+		Proxy:           r.Proxy,
+		Resolve:         r.Resolve,
+		Interface:       r.Interface,
+		IPVersion:       r.IPVersion,
+		ServerName:      r.tlsServerName(),
+	}
+	if r.Auth.Type == MutualTLSAuth {
+		settings.Auth.CertFile = r.Auth.CertFile
+		settings.Auth.KeyFile = r.Auth.KeyFile
+		settings.Auth.Password = r.Auth.Password
+		settings.Auth.CertSubject = r.Auth.CertSubject
+	}
+	if r.Auth.Type == NTLMAuth {
+		settings.Auth.Type = NTLMAuth
+		settings.Auth.Username = r.Auth.Username
+		settings.Auth.Password = r.Auth.Password
+	}
+	data, err := json.Marshal(settings)
+	return string(data), err
+}
@@ -0,0 +1,92 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSharedTransport(t *testing.T) {
+	ResetPool()
+	defer ResetPool()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	send := func() *ResponseData {
+		t.Helper()
+		req := NewRequestData()
+		req.URL = server.URL
+		req.ConnectTimeout = 5 * time.Second
+		resp, err := req.Execute()
+		if err != nil || resp.Error != "" {
+			t.Fatalf("Execute() = %+v, %v", resp, err)
+		}
+		return resp
+	}
+
+	// Requests with the same custom settings share a transport and its
+	// keep-alive connections
+	if resp := send(); resp.Timing == nil || resp.Timing.Reused {
+		t.Errorf("Expected the first request to open a connection, got %+v", resp.Timing)
+	}
+	if resp := send(); resp.Timing == nil || !resp.Timing.Reused {
+		t.Errorf("Expected the second request to reuse the connection, got %+v", resp.Timing)
+	}
+	stats := ConnectionStats()
+	if stats != (PoolStats{Opened: 1, Reused: 1, Transports: 1}) {
+		t.Errorf("ConnectionStats() = %+v", stats)
+	}
+
+	ResetPool()
+	if resp := send(); resp.Timing.Reused {
+		t.Error("Expected a new connection after ResetPool")
+	}
+	if stats := ConnectionStats(); stats.Opened != 1 || stats.Reused != 0 {
+		t.Errorf("Expected the counts to be reset, got %+v", stats)
+	}
+}
+
+func TestRequestData_transportKey(t *testing.T) {
+	key := func(r *RequestData) string {
+		t.Helper()
+		k, err := r.transportKey()
+		if err != nil {
+			t.Fatalf("transportKey() error = %v", err)
+		}
+		return k
+	}
+
+	base := &RequestData{ConnectTimeout: time.Second}
+	withCert := &RequestData{ConnectTimeout: time.Second, Auth: AuthData{Type: BasicAuth, CertFile: "client.pem", Username: "jane"}}
+	if key(base) != key(withCert) {
+		t.Error("Expected settings that do not affect the connection to share a key")
+	}
+	mtls := &RequestData{ConnectTimeout: time.Second, Auth: AuthData{Type: MutualTLSAuth, CertFile: "client.pem", KeyFile: "client.key"}}
+	for _, other := range []*RequestData{
+		mtls,
+		{ConnectTimeout: 2 * time.Second},
+		{ConnectTimeout: time.Second, Resolve: map[string]string{"api.example.com:443": "127.0.0.1"}},
+		{ConnectTimeout: time.Second, Proxy: &ProxySettings{URL: "http://proxy:3128"}},
+		{ConnectTimeout: time.Second, Pins: []string{"sha256//abc"}},
+		{ConnectTimeout: time.Second, Auth: AuthData{Type: NTLMAuth, Username: `CORP\jane`, Password: "s3cret"}},
+	} {
+		if key(base) == key(other) {
+			t.Errorf("Expected %+v to need its own transport", other)
+		}
+	}
+
+	jane := &RequestData{Auth: AuthData{Type: NTLMAuth, Username: `CORP\jane`, Password: "s3cret"}}
+	for _, other := range []*RequestData{
+		{ProtocolVersion: ProtocolHTTP1},
+		{Auth: AuthData{Type: NTLMAuth, Username: `CORP\john`, Password: "s3cret"}},
+		{Auth: AuthData{Type: NTLMAuth, Username: `CORP\jane`, Password: "hunter2"}},
+	} {
+		if key(jane) == key(other) {
+			t.Errorf("Expected %+v not to share the connections of an NTLM account", other)
+		}
+	}
+}
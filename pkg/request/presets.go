@@ -0,0 +1,31 @@
+package request
+
+// HeaderPreset is a named group of headers that are commonly applied together
+type HeaderPreset struct {
+	Name    string
+	Headers map[string]string
+}
+
+// HeaderPresets lists the built-in header presets
+var HeaderPresets = []HeaderPreset{
+	{
+		Name: "JSON API",
+		Headers: map[string]string{
+			"Accept":       "application/json",
+			"Content-Type": "application/json",
+		},
+	},
+	{
+		Name: "No cache",
+		Headers: map[string]string{
+			"Cache-Control": "no-cache",
+			"Pragma":        "no-cache",
+		},
+	},
+	{
+		Name: "Form",
+		Headers: map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		},
+	},
+}
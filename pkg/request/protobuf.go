@@ -0,0 +1,1040 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoWireTypes maps the scalar field types to the wire type they are
+// encoded with; messages, maps, strings, and bytes use wireBytes
+var protoWireTypes = map[string]int{
+	"int32": wireVarint, "int64": wireVarint, "uint32": wireVarint, "uint64": wireVarint,
+	"sint32": wireVarint, "sint64": wireVarint, "bool": wireVarint, "enum": wireVarint,
+	"fixed64": wireFixed64, "sfixed64": wireFixed64, "double": wireFixed64,
+	"fixed32": wireFixed32, "sfixed32": wireFixed32, "float": wireFixed32,
+	"string": wireBytes, "bytes": wireBytes, "message": wireBytes, "map": wireBytes,
+}
+
+// protoMessage is a message type read from a .proto file
+type protoMessage struct {
+	name     string // fully qualified
+	fields   []*protoField
+	byNumber map[int]*protoField
+}
+
+// protoField is a field of a message
+type protoField struct {
+	name     string
+	jsonName string
+	number   int
+	label    string // optional, required, repeated, or empty
+	typeName string // as written, or "map"
+	oneof    string
+
+	// scope is the message the field is declared in, for resolving typeName
+	scope string
+
+	// Resolved type: a message, an enum, a well-known type that is not
+	// checked, or a map with its entry message
+	message   *protoMessage
+	enum      *protoEnum
+	wellKnown bool
+	mapKey    *protoField
+	mapValue  *protoField
+	mapEntry  *protoMessage
+}
+
+// protoEnum is an enum type; closed (proto2) enums accept only the values
+// they declare
+type protoEnum struct {
+	name    string
+	values  map[string]int
+	numbers map[int]bool
+	closed  bool
+}
+
+// kind returns the type name used for wire and JSON checks
+func (f *protoField) kind() string {
+	switch {
+	case f.mapEntry != nil:
+		return "map"
+	case f.message != nil, f.wellKnown:
+		return "message"
+	case f.enum != nil:
+		return "enum"
+	}
+	return f.typeName
+}
+
+// protoFile is a parsed .proto file
+type protoFile struct {
+	path     string
+	syntax   string
+	pkg      string
+	imports  []string
+	messages []*protoMessage
+	topLevel []*protoMessage
+	enums    []*protoEnum
+}
+
+// loadProtoMessage reads a .proto file and its imports and returns the
+// named message, which may be given by its full name or its last part
+func loadProtoMessage(path, name string) (*protoMessage, error) {
+	files := make(map[string]*protoFile)
+	root, err := loadProtoFile(path, []string{filepath.Dir(path)}, files)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(map[string]*protoMessage)
+	enums := make(map[string]*protoEnum)
+	for _, file := range files {
+		for _, m := range file.messages {
+			messages[m.name] = m
+		}
+		for _, e := range file.enums {
+			enums[e.name] = e
+		}
+	}
+	for _, m := range messages {
+		for _, field := range m.fields {
+			if err := resolveProtoField(field, messages, enums); err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+		}
+	}
+
+	if name == "" {
+		if len(root.topLevel) != 1 {
+			return nil, fmt.Errorf("%s defines %d messages: name one as %s:Message", path, len(root.topLevel), path)
+		}
+		return root.topLevel[0], nil
+	}
+	if m, ok := messages[strings.TrimPrefix(name, ".")]; ok {
+		return m, nil
+	}
+	var matches []*protoMessage
+	for full, m := range messages {
+		if strings.HasSuffix(full, "."+name) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("%s: message %s not found", path, name)
+	}
+	return matches[0], nil
+}
+
+// loadProtoFile parses a .proto file and, recursively, its imports, which
+// are looked up relative to the directories given. Imports of the
+// google/protobuf well-known types need not be present.
+func loadProtoFile(path string, dirs []string, files map[string]*protoFile) (*protoFile, error) {
+	if file, ok := files[path]; ok {
+		return file, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := parseProto(path, string(data))
+	if err != nil {
+		return nil, err
+	}
+	files[path] = file
+
+	for _, imported := range file.imports {
+		found := ""
+		for _, dir := range append([]string{filepath.Dir(path)}, dirs...) {
+			candidate := filepath.Join(dir, filepath.FromSlash(imported))
+			if _, err := os.Stat(candidate); err == nil {
+				found = candidate
+				break
+			}
+		}
+		if found == "" {
+			if strings.HasPrefix(imported, "google/protobuf/") {
+				continue
+			}
+			return nil, fmt.Errorf("%s: import %s not found", path, imported)
+		}
+		if _, err := loadProtoFile(found, dirs, files); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// resolveProtoField finds the message or enum a field refers to, searching
+// outwards from the message it is declared in as protoc does
+func resolveProtoField(field *protoField, messages map[string]*protoMessage, enums map[string]*protoEnum) error {
+	if field.mapEntry != nil {
+		for _, part := range field.mapEntry.fields {
+			if err := resolveProtoField(part, messages, enums); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := protoWireTypes[field.typeName]; ok {
+		return nil
+	}
+
+	var candidates []string
+	if name, ok := strings.CutPrefix(field.typeName, "."); ok {
+		candidates = []string{name}
+	} else {
+		for scope := field.scope; ; {
+			candidates = append(candidates, joinProtoName(scope, field.typeName))
+			if scope == "" {
+				break
+			}
+			scope = scope[:max(strings.LastIndex(scope, "."), 0)]
+		}
+	}
+	for _, name := range candidates {
+		if m, ok := messages[name]; ok {
+			field.message = m
+			return nil
+		}
+		if e, ok := enums[name]; ok {
+			field.enum = e
+			return nil
+		}
+		if strings.HasPrefix(name, "google.protobuf.") {
+			field.wellKnown = true
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown type %s of field %s.%s", field.typeName, field.scope, field.name)
+}
+
+// joinProtoName qualifies a name with a package or message scope
+func joinProtoName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// protoToken is a word, string literal, or symbol of a .proto file
+type protoToken struct {
+	text string
+	line int
+}
+
+// tokenizeProto splits a .proto file into tokens, dropping comments
+func tokenizeProto(src string) ([]protoToken, error) {
+	var tokens []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			tokens = append(tokens, protoToken{text: src[i : j+1], line: line})
+			i = j + 1
+		case isProtoWordByte(c) || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+			for j < len(src) && (isProtoWordByte(src[j]) || (src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			tokens = append(tokens, protoToken{text: src[i:j], line: line})
+			i = j
+		default:
+			tokens = append(tokens, protoToken{text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isProtoWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+// protoParser reads the declarations of a .proto file. Services,
+// extensions, and options are skipped.
+type protoParser struct {
+	tokens []protoToken
+	pos    int
+	file   *protoFile
+}
+
+// parseProto parses the messages and enums of a .proto file
+func parseProto(path, src string) (*protoFile, error) {
+	tokens, err := tokenizeProto(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	p := &protoParser{tokens: tokens, file: &protoFile{path: path, syntax: "proto2"}}
+	if err := p.parseFile(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p.file, nil
+}
+
+func (p *protoParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *protoParser) next() string {
+	text := p.peek()
+	p.pos++
+	return text
+}
+
+// errorf reports an error at the current token
+func (p *protoParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *protoParser) expect(text string) error {
+	if p.peek() != text {
+		return p.errorf("expected %q, got %q", text, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// word reads an identifier or number
+func (p *protoParser) word() (string, error) {
+	text := p.peek()
+	if text == "" || !isProtoWordByte(text[0]) && text[0] != '-' {
+		return "", p.errorf("expected a name, got %q", text)
+	}
+	p.pos++
+	return text, nil
+}
+
+// str reads a string literal
+func (p *protoParser) str() (string, error) {
+	text := p.peek()
+	if text == "" || text[0] != '"' && text[0] != '\'' {
+		return "", p.errorf("expected a string, got %q", text)
+	}
+	p.pos++
+	if text[0] == '\'' {
+		text = `"` + strings.ReplaceAll(text[1:len(text)-1], `"`, `\"`) + `"`
+	}
+	value, err := strconv.Unquote(text)
+	if err != nil {
+		return "", p.errorf("invalid string %s", text)
+	}
+	return value, nil
+}
+
+// skipStatement skips to the end of a statement, including any braces
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return p.errorf("unexpected end of file")
+}
+
+func (p *protoParser) parseFile() error {
+	for p.pos < len(p.tokens) {
+		var err error
+		switch p.next() {
+		case "syntax", "edition":
+			if err = p.expect("="); err == nil {
+				p.file.syntax, err = p.str()
+			}
+			if err == nil {
+				err = p.expect(";")
+			}
+		case "package":
+			if p.file.pkg, err = p.word(); err == nil {
+				err = p.expect(";")
+			}
+		case "import":
+			if p.peek() == "public" || p.peek() == "weak" {
+				p.pos++
+			}
+			var imported string
+			if imported, err = p.str(); err == nil {
+				p.file.imports = append(p.file.imports, imported)
+				err = p.expect(";")
+			}
+		case "message":
+			var m *protoMessage
+			if m, err = p.parseMessage(p.file.pkg); err == nil {
+				p.file.topLevel = append(p.file.topLevel, m)
+			}
+		case "enum":
+			err = p.parseEnum(p.file.pkg)
+		case "option", "service", "extend":
+			err = p.skipStatement()
+		case ";":
+		default:
+			p.pos--
+			err = p.errorf("unexpected %q", p.peek())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMessage parses a message declaration after the message keyword
+func (p *protoParser) parseMessage(scope string) (*protoMessage, error) {
+	name, err := p.word()
+	if err != nil {
+		return nil, err
+	}
+	m := &protoMessage{name: joinProtoName(scope, name), byNumber: make(map[int]*protoField)}
+	p.file.messages = append(p.file.messages, m)
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseMessageBody(m, ""); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseMessageBody parses the declarations of a message, or of a oneof
+// inside it, up to the closing brace
+func (p *protoParser) parseMessageBody(m *protoMessage, oneof string) error {
+	for {
+		var err error
+		switch p.peek() {
+		case "}":
+			p.pos++
+			return nil
+		case "":
+			return p.errorf("unexpected end of file")
+		case ";":
+			p.pos++
+		case "message":
+			p.pos++
+			_, err = p.parseMessage(m.name)
+		case "enum":
+			p.pos++
+			err = p.parseEnum(m.name)
+		case "oneof":
+			p.pos++
+			var name string
+			if name, err = p.word(); err == nil {
+				if err = p.expect("{"); err == nil {
+					err = p.parseMessageBody(m, name)
+				}
+			}
+		case "option", "reserved", "extensions", "extend":
+			err = p.skipStatement()
+		default:
+			err = p.parseField(m, oneof)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseField parses a field or map field declaration
+func (p *protoParser) parseField(m *protoMessage, oneof string) error {
+	field := &protoField{scope: m.name, oneof: oneof}
+	switch p.peek() {
+	case "optional", "required", "repeated":
+		field.label = p.next()
+	}
+
+	if p.peek() == "map" && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "<" {
+		p.pos += 2
+		key, err := p.word()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		value, err := p.word()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+		field.typeName = "map"
+		field.mapKey = &protoField{name: "key", jsonName: "key", number: 1, typeName: key, scope: m.name}
+		field.mapValue = &protoField{name: "value", jsonName: "value", number: 2, typeName: value, scope: m.name}
+		field.mapEntry = &protoMessage{
+			fields:   []*protoField{field.mapKey, field.mapValue},
+			byNumber: map[int]*protoField{1: field.mapKey, 2: field.mapValue},
+		}
+	} else {
+		typeName, err := p.word()
+		if err != nil {
+			return err
+		}
+		if typeName == "group" {
+			return p.errorf("groups are not supported")
+		}
+		field.typeName = typeName
+	}
+
+	var err error
+	if field.name, err = p.word(); err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	number, err := p.word()
+	if err != nil {
+		return err
+	}
+	if field.number, err = strconv.Atoi(number); err != nil {
+		return p.errorf("invalid field number %s", number)
+	}
+	field.jsonName = protoJSONName(field.name)
+	if field.mapEntry != nil {
+		field.mapEntry.name = m.name + "." + strings.ToUpper(field.jsonName[:1]) + field.jsonName[1:] + "Entry"
+	}
+	if p.peek() == "[" {
+		jsonName, err := p.parseOptions()
+		if err != nil {
+			return err
+		}
+		if jsonName != "" {
+			field.jsonName = jsonName
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return err
+	}
+
+	m.fields = append(m.fields, field)
+	m.byNumber[field.number] = field
+	return nil
+}
+
+// parseOptions skips the bracketed options of a field or enum value,
+// returning the json_name option if there is one
+func (p *protoParser) parseOptions() (string, error) {
+	jsonName := ""
+	p.pos++
+	for depth := 1; depth > 0; {
+		switch text := p.next(); text {
+		case "":
+			return "", p.errorf("unexpected end of file")
+		case "[", "{":
+			depth++
+		case "]", "}":
+			depth--
+		case "json_name":
+			if depth == 1 && p.peek() == "=" {
+				p.pos++
+				name, err := p.str()
+				if err != nil {
+					return "", err
+				}
+				jsonName = name
+			}
+		}
+	}
+	return jsonName, nil
+}
+
+// parseEnum parses an enum declaration after the enum keyword
+func (p *protoParser) parseEnum(scope string) error {
+	name, err := p.word()
+	if err != nil {
+		return err
+	}
+	e := &protoEnum{
+		name:    joinProtoName(scope, name),
+		values:  make(map[string]int),
+		numbers: make(map[int]bool),
+		closed:  p.file.syntax == "proto2",
+	}
+	p.file.enums = append(p.file.enums, e)
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch p.peek() {
+		case "}":
+			p.pos++
+			return nil
+		case "":
+			return p.errorf("unexpected end of file")
+		case ";":
+			p.pos++
+			continue
+		case "option", "reserved":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := p.word()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		number, err := p.word()
+		if err != nil {
+			return err
+		}
+		n, err := strconv.ParseInt(number, 0, 32)
+		if err != nil {
+			return p.errorf("invalid enum value %s", number)
+		}
+		e.values[value] = int(n)
+		e.numbers[int(n)] = true
+		if p.peek() == "[" {
+			if _, err := p.parseOptions(); err != nil {
+				return err
+			}
+		}
+		if err := p.expect(";"); err != nil {
+			return err
+		}
+	}
+}
+
+// protoJSONName returns the lowerCamelCase name protoc gives a field in
+// JSON
+func protoJSONName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// protoWireValue is a field value read from the wire
+type protoWireValue struct {
+	wire   int
+	varint uint64
+	bytes  []byte
+}
+
+// consumeProtoVarint reads a base 128 varint, returning the number of bytes
+// read or 0 when the data ends first
+func consumeProtoVarint(data []byte) (uint64, int) {
+	value, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, 0
+	}
+	return value, n
+}
+
+// consumeProtoValue reads a value of the wire type from the start of data
+func consumeProtoValue(data []byte, wire int) (protoWireValue, []byte, error) {
+	v := protoWireValue{wire: wire}
+	switch wire {
+	case wireVarint:
+		value, n := consumeProtoVarint(data)
+		if n == 0 {
+			return v, nil, fmt.Errorf("truncated varint")
+		}
+		v.varint = value
+		return v, data[n:], nil
+	case wireFixed64, wireFixed32:
+		size := 8
+		if wire == wireFixed32 {
+			size = 4
+		}
+		if len(data) < size {
+			return v, nil, fmt.Errorf("truncated %d-byte value", size)
+		}
+		v.bytes = data[:size]
+		return v, data[size:], nil
+	case wireBytes:
+		length, n := consumeProtoVarint(data)
+		if n == 0 || length > uint64(len(data)-n) {
+			return v, nil, fmt.Errorf("truncated length-delimited value")
+		}
+		v.bytes = data[n : n+int(length)]
+		return v, data[n+int(length):], nil
+	}
+	return v, nil, fmt.Errorf("unsupported wire type %d", wire)
+}
+
+// protoWireName describes a wire type in errors
+func protoWireName(wire int) string {
+	switch wire {
+	case wireVarint:
+		return "varint"
+	case wireFixed64:
+		return "64-bit"
+	case wireBytes:
+		return "length-delimited"
+	case wireFixed32:
+		return "32-bit"
+	}
+	return "wire type " + strconv.Itoa(wire)
+}
+
+// validateBinary checks a message in the protobuf binary encoding
+func (m *protoMessage) validateBinary(data []byte, path string, errs *schemaErrors) {
+	seen := make(map[int]int)
+	for len(data) > 0 {
+		tag, n := consumeProtoVarint(data)
+		if n == 0 {
+			errs.add(path, "truncated field tag")
+			return
+		}
+		number, wire := int(tag>>3), int(tag&7)
+		value, rest, err := consumeProtoValue(data[n:], wire)
+		if err != nil {
+			errs.add(path, "field %d: %v", number, err)
+			return
+		}
+		data = rest
+
+		field := m.byNumber[number]
+		if field == nil {
+			errs.add(path, "unknown field number %d (%s) in %s", number, protoWireName(wire), m.name)
+			continue
+		}
+		fieldPath := path + "." + field.name
+		if field.label == "repeated" || field.mapEntry != nil {
+			fieldPath += "[" + strconv.Itoa(seen[number]) + "]"
+		}
+		seen[number]++
+		field.validateBinary(value, fieldPath, errs)
+	}
+	for _, field := range m.fields {
+		if field.label == "required" && seen[field.number] == 0 {
+			errs.add(path+"."+field.name, "missing required field")
+		}
+	}
+}
+
+// validateBinary checks a single value of the field read from the wire.
+// Repeated scalars may be packed into one length-delimited value.
+func (f *protoField) validateBinary(v protoWireValue, path string, errs *schemaErrors) {
+	kind := f.kind()
+	expected := protoWireTypes[kind]
+	if v.wire == wireBytes && expected != wireBytes && f.label == "repeated" {
+		for data := v.bytes; len(data) > 0; {
+			item, rest, err := consumeProtoValue(data, expected)
+			if err != nil {
+				errs.add(path, "packed %s: %v", kind, err)
+				return
+			}
+			f.validateScalarBinary(item, path, errs)
+			data = rest
+		}
+		return
+	}
+	if v.wire != expected {
+		errs.add(path, "%s field encoded as %s", f.describe(), protoWireName(v.wire))
+		return
+	}
+
+	switch kind {
+	case "message":
+		if f.message != nil {
+			f.message.validateBinary(v.bytes, path, errs)
+		}
+	case "map":
+		f.mapEntry.validateBinary(v.bytes, path, errs)
+	case "string":
+		if !utf8.Valid(v.bytes) {
+			errs.add(path, "string is not valid UTF-8")
+		}
+	default:
+		f.validateScalarBinary(v, path, errs)
+	}
+}
+
+// validateScalarBinary checks the range of a numeric or enum value
+func (f *protoField) validateScalarBinary(v protoWireValue, path string, errs *schemaErrors) {
+	switch f.kind() {
+	case "int32":
+		if n := int64(v.varint); n < math.MinInt32 || n > math.MaxInt32 {
+			errs.add(path, "value %d out of range for int32", n)
+		}
+	case "uint32":
+		if v.varint > math.MaxUint32 {
+			errs.add(path, "value %d out of range for uint32", v.varint)
+		}
+	case "sint32":
+		if n := int64(v.varint>>1) ^ -int64(v.varint&1); n < math.MinInt32 || n > math.MaxInt32 {
+			errs.add(path, "value %d out of range for sint32", n)
+		}
+	case "enum":
+		if n := int64(v.varint); f.enum.closed && !f.enum.numbers[int(n)] {
+			errs.add(path, "unknown value %d of enum %s", n, f.enum.name)
+		}
+	}
+}
+
+// describe names the field's type in errors
+func (f *protoField) describe() string {
+	switch {
+	case f.message != nil:
+		return f.message.name
+	case f.enum != nil:
+		return f.enum.name
+	}
+	return f.typeName
+}
+
+// validateJSON checks a message in the protobuf JSON mapping, where fields
+// go by their JSON or original names and null stands for the default
+func (m *protoMessage) validateJSON(value interface{}, path string, errs *schemaErrors) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		errs.add(path, "expected %s object, got %s", m.name, jsonKind(value))
+		return
+	}
+
+	oneofs := make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(object)) {
+		fieldPath := path + "." + key
+		field := m.fieldByJSONName(key)
+		if field == nil {
+			errs.add(fieldPath, "unknown field of %s", m.name)
+			continue
+		}
+		if object[key] == nil {
+			continue
+		}
+		if field.oneof != "" {
+			if other, ok := oneofs[field.oneof]; ok {
+				errs.add(fieldPath, "oneof %s is already set by %s", field.oneof, other)
+			}
+			oneofs[field.oneof] = key
+		}
+		field.validateJSON(object[key], fieldPath, errs)
+	}
+	for _, field := range m.fields {
+		if field.label == "required" && object[field.jsonName] == nil && object[field.name] == nil {
+			errs.add(path+"."+field.jsonName, "missing required field")
+		}
+	}
+}
+
+// fieldByJSONName finds a field by its JSON name or the name it is declared
+// with
+func (m *protoMessage) fieldByJSONName(name string) *protoField {
+	for _, field := range m.fields {
+		if field.jsonName == name || field.name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// validateJSON checks the JSON value of a field
+func (f *protoField) validateJSON(value interface{}, path string, errs *schemaErrors) {
+	switch {
+	case f.mapEntry != nil:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			errs.add(path, "expected an object for map field, got %s", jsonKind(value))
+			return
+		}
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			entryPath := path + "[" + strconv.Quote(key) + "]"
+			f.mapKey.validateJSONKey(key, entryPath, errs)
+			if object[key] != nil {
+				f.mapValue.validateJSONScalar(object[key], entryPath, errs)
+			}
+		}
+	case f.label == "repeated":
+		items, ok := value.([]interface{})
+		if !ok {
+			errs.add(path, "expected an array for repeated field, got %s", jsonKind(value))
+			return
+		}
+		for i, item := range items {
+			f.validateJSONScalar(item, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	default:
+		f.validateJSONScalar(value, path, errs)
+	}
+}
+
+// validateJSONScalar checks a single JSON value of the field's type.
+// Integers may be numbers or strings, 64-bit ones usually being strings.
+func (f *protoField) validateJSONScalar(value interface{}, path string, errs *schemaErrors) {
+	kind := f.kind()
+	switch kind {
+	case "message":
+		if f.message != nil {
+			f.message.validateJSON(value, path, errs)
+		}
+	case "enum":
+		switch v := value.(type) {
+		case string:
+			if _, ok := f.enum.values[v]; !ok {
+				errs.add(path, "unknown value %q of enum %s", v, f.enum.name)
+			}
+		case json.Number:
+			n, err := strconv.ParseInt(string(v), 10, 32)
+			if err != nil || f.enum.closed && !f.enum.numbers[int(n)] {
+				errs.add(path, "unknown value %s of enum %s", v, f.enum.name)
+			}
+		default:
+			errs.add(path, "expected %s name or number, got %s", f.enum.name, jsonKind(value))
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs.add(path, "expected a string, got %s", jsonKind(value))
+		}
+	case "bytes":
+		s, ok := value.(string)
+		if !ok {
+			errs.add(path, "expected a base64 string, got %s", jsonKind(value))
+			return
+		}
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if _, err := encoding.DecodeString(s); err == nil {
+				return
+			}
+		}
+		errs.add(path, "invalid base64 for bytes field")
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			errs.add(path, "expected a boolean, got %s", jsonKind(value))
+		}
+	case "float", "double":
+		switch v := value.(type) {
+		case json.Number:
+		case string:
+			if _, err := strconv.ParseFloat(v, 64); err != nil && v != "NaN" && v != "Infinity" && v != "-Infinity" {
+				errs.add(path, "expected a number, got %q", v)
+			}
+		default:
+			errs.add(path, "expected a number, got %s", jsonKind(value))
+		}
+	default:
+		var text string
+		switch v := value.(type) {
+		case json.Number:
+			text = string(v)
+		case string:
+			text = v
+		default:
+			errs.add(path, "expected %s, got %s", kind, jsonKind(value))
+			return
+		}
+		bits := 64
+		if strings.HasSuffix(kind, "32") {
+			bits = 32
+		}
+		var err error
+		if strings.HasPrefix(kind, "uint") || strings.HasPrefix(kind, "fixed") {
+			_, err = strconv.ParseUint(text, 10, bits)
+		} else {
+			_, err = strconv.ParseInt(text, 10, bits)
+		}
+		if err != nil {
+			errs.add(path, "%s is not a valid %s", text, kind)
+		}
+	}
+}
+
+// validateJSONKey checks a map key, which JSON always writes as a string
+func (f *protoField) validateJSONKey(key, path string, errs *schemaErrors) {
+	switch f.typeName {
+	case "string":
+	case "bool":
+		if key != "true" && key != "false" {
+			errs.add(path, "map key %q is not a boolean", key)
+		}
+	default:
+		f.validateJSONScalar(key, path, errs)
+	}
+}
+
+// jsonKind describes the type of a decoded JSON value in errors
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case json.Number, float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
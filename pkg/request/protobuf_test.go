@@ -0,0 +1,175 @@
+package request
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testProto = `syntax = "proto3";
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+
+/* A customer */
+message User {
+  int64 id = 1;
+  string name = 2 [json_name = "displayName"];
+  repeated int32 scores = 3;
+  Address address = 4;
+  map<string, int32> counts = 5;
+  Role role = 6;
+  oneof contact {
+    string email = 7;
+    string phone = 8;
+  }
+  google.protobuf.Timestamp created_at = 9;
+
+  message Address {
+    string city = 1; // required by the shipping service
+  }
+  enum Role {
+    ROLE_UNSPECIFIED = 0;
+    ADMIN = 1 [deprecated = true];
+  }
+}
+`
+
+// protoAppend appends a field to a protobuf message in the binary encoding
+func protoAppend(b []byte, number, wire int, value interface{}) []byte {
+	b = binary.AppendUvarint(b, uint64(number<<3|wire))
+	switch v := value.(type) {
+	case int:
+		return binary.AppendUvarint(b, uint64(v))
+	case string:
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		return append(b, v...)
+	case []byte:
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		return append(b, v...)
+	}
+	panic("unsupported value")
+}
+
+func loadTestProto(t *testing.T) *Schema {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "user.proto")
+	if err := os.WriteFile(path, []byte(testProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := LoadSchema(path + ":User")
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	if schema.String() != "shop.v1.User" {
+		t.Errorf("Expected shop.v1.User, got %s", schema)
+	}
+	return schema
+}
+
+func schemaErrorStrings(errs []SchemaError) []string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+func TestSchema_ProtobufBinary(t *testing.T) {
+	schema := loadTestProto(t)
+
+	var body []byte
+	body = protoAppend(body, 1, wireVarint, 42)
+	body = protoAppend(body, 2, wireBytes, "Jane")
+	body = protoAppend(body, 3, wireBytes, []byte{1, 2})
+	body = protoAppend(body, 3, wireVarint, 3)
+	body = protoAppend(body, 4, wireBytes, protoAppend(nil, 1, wireBytes, "Oslo"))
+	body = protoAppend(body, 5, wireBytes, protoAppend(protoAppend(nil, 1, wireBytes, "a"), 2, wireVarint, 1))
+	body = protoAppend(body, 6, wireVarint, 7)
+	body = protoAppend(body, 9, wireBytes, protoAppend(nil, 1, wireVarint, 1700000000))
+	if errs := schema.Validate(string(body), "application/x-protobuf"); len(errs) != 0 {
+		t.Errorf("Expected a valid message, got %v", errs)
+	}
+
+	body = protoAppend(nil, 2, wireVarint, 1)
+	body = protoAppend(body, 3, wireVarint, 1<<40)
+	body = protoAppend(body, 4, wireBytes, protoAppend(nil, 1, wireVarint, 5))
+	body = protoAppend(body, 5, wireBytes, protoAppend(nil, 3, wireVarint, 1))
+	body = protoAppend(body, 15, wireVarint, 1)
+	want := []string{
+		".name: string field encoded as varint",
+		".scores[0]: value 1099511627776 out of range for int32",
+		".address.city: string field encoded as varint",
+		".counts[0]: unknown field number 3 (varint) in shop.v1.User.CountsEntry",
+		"unknown field number 15 (varint) in shop.v1.User",
+	}
+	if got := schemaErrorStrings(schema.Validate(string(body), "application/x-protobuf")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+
+	body = protoAppend(nil, 2, wireBytes, "Jane")
+	if got := schemaErrorStrings(schema.Validate(string(body[:len(body)-1]), "")); len(got) != 1 || got[0] != "field 2: truncated length-delimited value" {
+		t.Errorf("Expected a truncation error, got %q", got)
+	}
+}
+
+func TestSchema_ProtobufJSON(t *testing.T) {
+	schema := loadTestProto(t)
+
+	valid := `{"id": "42", "displayName": "Jane", "scores": [1, "2"], "address": {"city": "Oslo"},
+		"counts": {"a": 1}, "role": "ADMIN", "email": "jane@example.com", "phone": null,
+		"createdAt": "2024-01-01T00:00:00Z"}`
+	if errs := schema.Validate(valid, "application/json"); len(errs) != 0 {
+		t.Errorf("Expected a valid message, got %v", errs)
+	}
+
+	invalid := `{"id": "x", "scores": [1, 2.5], "address": {"town": "Oslo"}, "counts": {"a": "b"},
+		"role": "OWNER", "email": "a", "phone": "b"}`
+	want := []string{
+		".address.town: unknown field of shop.v1.User.Address",
+		`.counts["a"]: b is not a valid int32`,
+		".id: x is not a valid int64",
+		".phone: oneof contact is already set by email",
+		`.role: unknown value "OWNER" of enum shop.v1.User.Role`,
+		".scores[1]: 2.5 is not a valid int32",
+	}
+	if got := schemaErrorStrings(schema.Validate(invalid, "")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadSchema_ProtoErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	common := write("common.proto", `syntax = "proto2"; package common; message Money { required int64 units = 1; }`)
+	order := write("order.proto", `syntax = "proto3"; import "common.proto"; message Order { common.Money total = 1; }`)
+	schema, err := LoadSchema(order)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	body := protoAppend(nil, 1, wireBytes, []byte{})
+	if got := schemaErrorStrings(schema.Validate(string(body), "application/protobuf")); len(got) != 1 || got[0] != ".total.units: missing required field" {
+		t.Errorf("Expected the imported required field to be checked, got %q", got)
+	}
+
+	if _, err := LoadSchema(common + ":Missing"); err == nil {
+		t.Error("Expected an error for an unknown message")
+	}
+	two := write("two.proto", `message A {} message B {}`)
+	if _, err := LoadSchema(two); err == nil {
+		t.Error("Expected an error when the message is ambiguous")
+	}
+	broken := write("broken.proto", `message A { Unknown x = 1; }`)
+	if _, err := LoadSchema(broken); err == nil {
+		t.Error("Expected an error for an unknown field type")
+	}
+}
@@ -0,0 +1,115 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProtocolVersion selects the HTTP version a request is sent with
+type ProtocolVersion string
+
+const (
+	// ProtocolAuto negotiates HTTP/2 over TLS when the server offers it and
+	// uses HTTP/1.1 otherwise
+	ProtocolAuto ProtocolVersion = ""
+
+	// ProtocolHTTP1 never upgrades to HTTP/2
+	ProtocolHTTP1 ProtocolVersion = "HTTP/1.1"
+
+	// ProtocolHTTP2 requires HTTP/2, negotiated over TLS or, for http://
+	// URLs, spoken without an upgrade (h2c prior knowledge)
+	ProtocolHTTP2 ProtocolVersion = "HTTP/2"
+
+	// ProtocolHTTP3 sends https requests over HTTP/3 (QUIC). Requests that
+	// can't be, such as http:// URLs, requests through proxies, and requests
+	// to servers no QUIC connection can be made to, fall back to HTTP/2 (or
+	// HTTP/1.1 when the server does not offer it) and
+	// ResponseData.ProtocolFallback says why.
+	ProtocolHTTP3 ProtocolVersion = "HTTP/3"
+)
+
+// HTTP3Supported reports whether ProtocolHTTP3 requests are sent over
+// HTTP/3 rather than always falling back
+const HTTP3Supported = true
+
+// ParseProtocolVersion parses an HTTP version such as "1.1", "HTTP/2" or
+// "h2"; an empty value or "auto" negotiates the version
+func ParseProtocolVersion(value string) (ProtocolVersion, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return ProtocolAuto, nil
+	case "1.1", "1", "http/1.1", "http1.1", "h1":
+		return ProtocolHTTP1, nil
+	case "2", "2.0", "http/2", "http/2.0", "http2", "h2":
+		return ProtocolHTTP2, nil
+	case "3", "3.0", "http/3", "http/3.0", "http3", "h3":
+		return ProtocolHTTP3, nil
+	}
+	return "", fmt.Errorf("invalid HTTP version %q: use 1.1, 2, 3, or auto", value)
+}
+
+// protocols returns the protocols a transport may use for the version
+func (v ProtocolVersion) protocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	switch v {
+	case ProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case ProtocolHTTP2:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil
+	}
+	return protocols
+}
+
+// protocolVersion returns the version the request is sent with: HTTP/1.1
+// for ntlm auth unless another is forced, since NTLM authenticates the
+// connection, which HTTP/2 does not allow
+func (r *RequestData) protocolVersion() ProtocolVersion {
+	if r.Auth.Type == NTLMAuth && r.ProtocolVersion == ProtocolAuto {
+		return ProtocolHTTP1
+	}
+	return r.ProtocolVersion
+}
+
+// validate rejects unknown versions
+func (v ProtocolVersion) validate() error {
+	switch v {
+	case ProtocolAuto, ProtocolHTTP1, ProtocolHTTP2, ProtocolHTTP3:
+		return nil
+	}
+	return fmt.Errorf("invalid protocol version %q: use %s, %s, or %s", v, ProtocolHTTP1, ProtocolHTTP2, ProtocolHTTP3)
+}
+
+// ProtocolSummary describes the protocol of the response, e.g.
+// "HTTP/2.0 (HTTP/3 failed: timeout: no recent network activity), server
+// offers HTTP/3"
+func (r *ResponseData) ProtocolSummary() string {
+	summary := r.Protocol
+	if r.ProtocolFallback != "" {
+		summary += " (" + r.ProtocolFallback + ")"
+	}
+	if r.OffersHTTP3() {
+		summary += ", server offers HTTP/3"
+	}
+	return summary
+}
+
+// OffersHTTP3 reports whether the server advertised HTTP/3 in an Alt-Svc
+// header, e.g. h3=":443"; ma=86400
+func (r *ResponseData) OffersHTTP3() bool {
+	for key, value := range r.Headers {
+		if !strings.EqualFold(key, "Alt-Svc") {
+			continue
+		}
+		for _, service := range strings.Split(value, ",") {
+			protocol, _, _ := strings.Cut(strings.TrimSpace(service), "=")
+			if protocol == "h3" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseProtocolVersion(t *testing.T) {
+	tests := map[string]ProtocolVersion{
+		"":         ProtocolAuto,
+		"auto":     ProtocolAuto,
+		"1.1":      ProtocolHTTP1,
+		"HTTP/1.1": ProtocolHTTP1,
+		"2":        ProtocolHTTP2,
+		"h2":       ProtocolHTTP2,
+		"HTTP/2.0": ProtocolHTTP2,
+		"h3":       ProtocolHTTP3,
+	}
+	for value, want := range tests {
+		if got, err := ParseProtocolVersion(value); err != nil || got != want {
+			t.Errorf("ParseProtocolVersion(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseProtocolVersion("spdy"); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+}
+
+func TestRequestData_ProtocolVersion(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	// A cleartext server that also speaks HTTP/2 with prior knowledge
+	plainServer := httptest.NewUnstartedServer(handler)
+	plainServer.Config.Protocols = new(http.Protocols)
+	plainServer.Config.Protocols.SetHTTP1(true)
+	plainServer.Config.Protocols.SetUnencryptedHTTP2(true)
+	plainServer.Start()
+	defer plainServer.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		version ProtocolVersion
+		want    string
+	}{
+		{"negotiated over TLS", tlsServer.URL, ProtocolAuto, "HTTP/2.0"},
+		{"forced HTTP/1.1 over TLS", tlsServer.URL, ProtocolHTTP1, "HTTP/1.1"},
+		{"forced HTTP/2 over TLS", tlsServer.URL, ProtocolHTTP2, "HTTP/2.0"},
+		{"cleartext", plainServer.URL, ProtocolAuto, "HTTP/1.1"},
+		{"forced HTTP/2 cleartext", plainServer.URL, ProtocolHTTP2, "HTTP/2.0"},
+		{"HTTP/3 over http falls back", plainServer.URL, ProtocolHTTP3, "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.URL = tt.url
+			req.Auth.InsecureSkipVerify = true
+			req.ProtocolVersion = tt.version
+			resp, err := req.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if resp.Error != "" {
+				t.Fatalf("Expected a response, got error %s", resp.Error)
+			}
+			if resp.Protocol != tt.want || resp.Body != tt.want {
+				t.Errorf("Protocol = %q (server saw %q), want %q", resp.Protocol, resp.Body, tt.want)
+			}
+			if fallback := resp.ProtocolFallback != ""; fallback != (tt.version == ProtocolHTTP3) {
+				t.Errorf("ProtocolFallback = %q", resp.ProtocolFallback)
+			}
+		})
+	}
+}
+
+func TestRequestData_ValidateProtocolVersion(t *testing.T) {
+	req := NewRequestData()
+	req.URL = "https://example.com"
+	req.ProtocolVersion = "SPDY/3"
+	if err := req.Validate(); err == nil {
+		t.Error("Expected an unknown protocol version to be rejected")
+	}
+}
+
+func TestResponseData_ProtocolSummary(t *testing.T) {
+	resp := &ResponseData{Protocol: "HTTP/1.1"}
+	if summary := resp.ProtocolSummary(); summary != "HTTP/1.1" {
+		t.Errorf("ProtocolSummary() = %q", summary)
+	}
+
+	resp = &ResponseData{
+		Protocol:         "HTTP/2.0",
+		ProtocolFallback: "HTTP/3 requires https",
+		Headers:          map[string]string{"Alt-Svc": `h3=":443"; ma=86400, h3-29=":443"`},
+	}
+	want := "HTTP/2.0 (HTTP/3 requires https), server offers HTTP/3"
+	if summary := resp.ProtocolSummary(); summary != want {
+		t.Errorf("ProtocolSummary() = %q, want %q", summary, want)
+	}
+}
@@ -0,0 +1,305 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProxySettings route a request through a proxy instead of the one named
+// by the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables
+type ProxySettings struct {
+	// URL of the proxy, e.g. http://proxy.corp:3128; http, https, and
+	// socks5 proxies are supported and the scheme defaults to http
+	URL string `json:"url"`
+
+	// Username and Password authenticate to the proxy with Basic
+	// Proxy-Authorization, or with NTLM when AuthType is ntlm, for plain
+	// requests and for the CONNECT of https:// requests alike
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// AuthType is basic, the default, or ntlm, which http and https
+	// proxies take only
+	AuthType AuthType `json:"auth_type,omitempty"`
+
+	// Profile names an auth profile with basic or ntlm auth whose
+	// credentials and type authenticate to the proxy in place of Username,
+	// Password, and AuthType
+	Profile string `json:"profile,omitempty"`
+
+	// Bypass lists the hosts reached directly, in NO_PROXY syntax:
+	// example.com matches the domain and its subdomains, .example.com and
+	// *.example.com only subdomains, 10.0.0.0/8 an address range, a
+	// :port suffix limits a rule to that port, and * matches every host
+	Bypass []string `json:"bypass,omitempty"`
+}
+
+// ProxyCredentials are stored credentials for a proxy, so requests can
+// name a proxy without embedding them in its URL. Profile names an auth
+// profile to take them from instead.
+type ProxyCredentials struct {
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	AuthType AuthType `json:"auth_type,omitempty"`
+	Profile  string   `json:"profile,omitempty"`
+}
+
+// ParseProxyBypass splits a comma or space separated list of bypass rules
+func ParseProxyBypass(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// ApplyCredentials fills in the credentials stored for the proxy host,
+// looked up as host:port and then host, unless the settings or the proxy
+// URL already carry credentials or name a profile. It does nothing on nil
+// settings.
+func (p *ProxySettings) ApplyCredentials(stored map[string]ProxyCredentials) {
+	if p == nil || p.Username != "" || p.Profile != "" || len(stored) == 0 {
+		return
+	}
+	u, err := p.parseURL()
+	if err != nil || u.User != nil {
+		return
+	}
+	for _, key := range []string{u.Host, u.Hostname()} {
+		if creds, ok := stored[strings.ToLower(key)]; ok {
+			p.Username = creds.Username
+			p.Password = creds.Password
+			if p.AuthType == "" {
+				p.AuthType = creds.AuthType
+			}
+			p.Profile = creds.Profile
+			return
+		}
+	}
+}
+
+// resolveProfile returns a copy of the settings with the credentials and
+// auth type of the auth profile they name, which must have basic or ntlm
+// auth
+func (p *ProxySettings) resolveProfile() (*ProxySettings, error) {
+	auth, err := AuthData{Type: ProfileAuth, Profile: p.Profile}.ResolveProfile()
+	if err != nil {
+		return nil, err
+	}
+	if auth.Type != BasicAuth && auth.Type != NTLMAuth {
+		return nil, fmt.Errorf("auth profile %s has %s auth, but proxies only take basic or ntlm", p.Profile, auth.Type)
+	}
+	resolved := *p
+	resolved.Username = auth.Username
+	resolved.Password = auth.Password
+	resolved.AuthType = auth.Type
+	return &resolved, nil
+}
+
+// ntlm reports whether the proxy is authenticated with NTLM
+func (p *ProxySettings) ntlm() bool {
+	return p.AuthType == NTLMAuth && p.Username != ""
+}
+
+// parseURL parses the proxy URL, defaulting the scheme to http
+func (p *ProxySettings) parseURL() (*url.URL, error) {
+	raw := strings.TrimSpace(p.URL)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	return u, nil
+}
+
+// validate checks the proxy URL and bypass rules
+func (p *ProxySettings) validate() error {
+	u, err := p.parseURL()
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("invalid proxy URL: unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy URL: must include a host")
+	}
+	switch p.AuthType {
+	case "", BasicAuth:
+	case NTLMAuth:
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid proxy auth: %s proxies do not take ntlm", u.Scheme)
+		}
+	default:
+		return fmt.Errorf("invalid proxy auth type %q: must be basic or ntlm", p.AuthType)
+	}
+	for _, rule := range p.Bypass {
+		if strings.Contains(rule, "/") {
+			if _, _, err := net.ParseCIDR(rule); err != nil {
+				return fmt.Errorf("invalid proxy bypass rule %q: %v", rule, err)
+			}
+		}
+	}
+	return nil
+}
+
+// proxyFunc returns the transport Proxy function: the proxy, with Basic
+// credentials set, for every request whose host is not bypassed. With
+// NTLM, https:// requests are left to the tunnels of ntlmTunnel.
+func (p *ProxySettings) proxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassesProxy(req.URL, p.Bypass) {
+			return nil, nil
+		}
+		if p.ntlm() && req.URL.Scheme != "http" {
+			return nil, nil
+		}
+		proxyURL, err := p.parseURL()
+		if err != nil {
+			return nil, err
+		}
+		if p.Username != "" && !p.ntlm() {
+			proxyURL.User = url.UserPassword(p.Username, p.Password)
+		}
+		return proxyURL, nil
+	}
+}
+
+// ntlmTunnel returns a dial function that reaches hosts through a CONNECT
+// tunnel of the proxy, authenticated with the NTLM handshake on the
+// connection the tunnel opens on. The transport can't keep a connection
+// for the legs of the handshake when it opens tunnels itself, so with NTLM
+// the dialer does. Connections to the proxy itself, made for http://
+// requests, and to bypassed hosts are dialed directly.
+func (p *ProxySettings) ntlmTunnel(dial dialFunc, tlsConfig *tls.Config) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		proxyURL, err := p.parseURL()
+		if err != nil {
+			return nil, err
+		}
+		proxyAddr := proxyURL.Host
+		if proxyURL.Port() == "" {
+			port := "80"
+			if proxyURL.Scheme == "https" {
+				port = "443"
+			}
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+		}
+		if addr == proxyAddr || bypassesProxy(&url.URL{Scheme: "https", Host: addr}, p.Bypass) {
+			return dial(ctx, network, addr)
+		}
+
+		conn, err := dial(ctx, network, proxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "https" {
+			config := &tls.Config{}
+			if tlsConfig != nil {
+				config = tlsConfig.Clone()
+			}
+			config.ServerName = proxyURL.Hostname()
+			config.NextProtos = nil
+			tlsConn := tls.Client(conn, config)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+		if err := p.connectNTLM(ctx, conn, addr); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// connectNTLM opens a tunnel to addr on the connection to the proxy,
+// sending the legs of the NTLM handshake as CONNECT requests
+func (p *ProxySettings) connectNTLM(ctx context.Context, conn net.Conn, addr string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	reader := bufio.NewReader(conn)
+	auth := AuthData{Type: NTLMAuth, Username: p.Username, Password: p.Password}
+	resp, err := ntlmHandshake(auth, proxyNTLM, func(authorization string) (*http.Response, error) {
+		connect := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: http.Header{"Proxy-Authorization": {authorization}},
+		}
+		if err := connect.Write(conn); err != nil {
+			return nil, err
+		}
+		return http.ReadResponse(reader, connect)
+	})
+	if err != nil {
+		return fmt.Errorf("proxy CONNECT to %s failed: %v", addr, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// bypassesProxy reports whether a URL matches one of the bypass rules
+func bypassesProxy(u *url.URL, rules []string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		switch {
+		case rule == "":
+			continue
+		case rule == "*":
+			return true
+		case strings.Contains(rule, "/"):
+			if _, network, err := net.ParseCIDR(rule); err == nil {
+				if ip := net.ParseIP(host); ip != nil && network.Contains(ip) {
+					return true
+				}
+			}
+			continue
+		}
+
+		ruleHost, rulePort := rule, ""
+		if h, p, err := net.SplitHostPort(rule); err == nil {
+			ruleHost, rulePort = h, p
+		}
+		if rulePort != "" && rulePort != port {
+			continue
+		}
+		ruleHost = strings.Trim(ruleHost, "[]")
+		switch {
+		case strings.HasPrefix(ruleHost, "*."):
+			if strings.HasSuffix(host, ruleHost[1:]) {
+				return true
+			}
+		case strings.HasPrefix(ruleHost, "."):
+			if strings.HasSuffix(host, ruleHost) {
+				return true
+			}
+		case host == ruleHost || strings.HasSuffix(host, "."+ruleHost):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package request
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBypassesProxy(t *testing.T) {
+	rules := []string{"localhost", ".internal.corp", "*.svc.local", "10.0.0.0/8", "api.example.com:8443", "::1"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://localhost:8080/", true},
+		{"https://billing.internal.corp/", true},
+		{"https://internal.corp/", false},
+		{"http://db.svc.local/", true},
+		{"http://10.1.2.3/", true},
+		{"http://11.1.2.3/", false},
+		{"https://api.example.com:8443/", true},
+		{"https://api.example.com/", false},
+		{"http://[::1]:9000/", true},
+		{"https://example.org/", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := bypassesProxy(u, rules); got != tt.want {
+			t.Errorf("bypassesProxy(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	u, _ := url.Parse("https://example.org/")
+	if !bypassesProxy(u, []string{"*"}) {
+		t.Error("Expected * to bypass every host")
+	}
+}
+
+func TestProxySettings_ApplyCredentials(t *testing.T) {
+	stored := map[string]ProxyCredentials{
+		"proxy.corp:3128": {Username: "jane", Password: "secret"},
+		"other.corp":      {Username: "john", Password: "hunter2"},
+	}
+
+	proxy := &ProxySettings{URL: "proxy.corp:3128"}
+	proxy.ApplyCredentials(stored)
+	if proxy.Username != "jane" || proxy.Password != "secret" {
+		t.Errorf("Expected the credentials stored for host:port, got %q/%q", proxy.Username, proxy.Password)
+	}
+
+	proxy = &ProxySettings{URL: "http://other.corp:8080"}
+	proxy.ApplyCredentials(stored)
+	if proxy.Username != "john" {
+		t.Errorf("Expected the credentials stored for the host, got %q", proxy.Username)
+	}
+
+	proxy = &ProxySettings{URL: "http://admin:pw@proxy.corp:3128"}
+	proxy.ApplyCredentials(stored)
+	if proxy.Username != "" {
+		t.Errorf("Expected credentials in the URL to win, got %q", proxy.Username)
+	}
+
+	var unset *ProxySettings
+	unset.ApplyCredentials(stored) // must not panic
+}
+
+func TestRequestData_Proxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer target.Close()
+
+	// A forward proxy that answers for every host it is asked for
+	var proxyAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		fmt.Fprintf(w, "proxied %s", r.URL)
+	}))
+	defer proxy.Close()
+
+	req := NewRequestData()
+	req.URL = "http://api.example.test/users"
+	req.Proxy = &ProxySettings{URL: proxy.URL, Username: "jane", Password: "secret"}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Body != "proxied http://api.example.test/users" {
+		t.Errorf("Expected the proxy to answer, got %q (%s)", resp.Body, resp.Error)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("jane:secret")); proxyAuth != want {
+		t.Errorf("Proxy-Authorization = %q, want %q", proxyAuth, want)
+	}
+
+	req = NewRequestData()
+	req.URL = target.URL
+	req.Proxy = &ProxySettings{URL: proxy.URL, Bypass: []string{"127.0.0.1"}}
+	resp, err = req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Body != "direct" {
+		t.Errorf("Expected a bypassed host to be reached directly, got %q", resp.Body)
+	}
+}
+
+func TestRequestData_ValidateProxy(t *testing.T) {
+	for _, proxy := range []*ProxySettings{
+		{URL: "ftp://proxy.corp"},
+		{URL: "http://"},
+		{URL: "proxy.corp:3128", Bypass: []string{"10.0.0.0/33"}},
+		{URL: "socks5://proxy.corp:1080", Username: "jane", AuthType: NTLMAuth},
+		{URL: "proxy.corp:3128", AuthType: BearerAuth},
+	} {
+		req := NewRequestData()
+		req.URL = "https://example.com"
+		req.Proxy = proxy
+		if err := req.Validate(); err == nil {
+			t.Errorf("Expected proxy %+v to be rejected", proxy)
+		}
+	}
+}
+
+func TestRequestData_ProxyProfile(t *testing.T) {
+	ResetPool()
+	defer ResetPool()
+	proxy := ntlmProxy(t, "s3cret")
+	fakeAuthProfiles(t, map[string]AuthData{
+		"corp":   {Type: NTLMAuth, Username: `CORP\jane`, Password: "s3cret"},
+		"bearer": {Type: BearerAuth, Token: "t-123"},
+	})
+
+	req := NewRequestData()
+	req.URL = "http://api.example.test/users"
+	req.Proxy = &ProxySettings{URL: proxy.URL, Profile: "corp"}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != "proxied http://api.example.test/users" {
+		t.Errorf("Expected the profile's NTLM credentials to authenticate to the proxy, got %d %q (%s)", resp.StatusCode, resp.Body, resp.Error)
+	}
+	if req.Proxy.Username != "" || req.Proxy.AuthType != "" {
+		t.Errorf("Expected the request to keep referencing the profile, got %+v", req.Proxy)
+	}
+
+	req.Proxy.Profile = "bearer"
+	if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), "auth profile bearer has bearer auth, but proxies only take basic or ntlm") {
+		t.Errorf("Expected a profile without basic or ntlm auth to be rejected, got %v", err)
+	}
+
+	// Stored proxy credentials may name a profile too
+	settings := &ProxySettings{URL: proxy.URL}
+	settings.ApplyCredentials(map[string]ProxyCredentials{strings.TrimPrefix(proxy.URL, "http://"): {Profile: "corp"}})
+	if settings.Profile != "corp" {
+		t.Errorf("Expected the stored profile to be applied, got %+v", settings)
+	}
+}
@@ -0,0 +1,46 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// DefaultMaxRedirects is the number of redirects followed when MaxRedirects
+// is not set, matching net/http
+const DefaultMaxRedirects = 10
+
+// RedirectHop is one redirect response followed on the way to the final
+// response
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// checkRedirect returns a redirect policy that records each hop in hops and
+// enforces the request's redirect settings
+func (r *RequestData) checkRedirect(hops *[]RedirectHop) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if r.DisableRedirects {
+			// Return the redirect response itself
+			return http.ErrUseLastResponse
+		}
+
+		if resp := req.Response; resp != nil {
+			*hops = append(*hops, RedirectHop{
+				URL:        resp.Request.URL.String(),
+				StatusCode: resp.StatusCode,
+				Location:   resp.Header.Get("Location"),
+			})
+		}
+
+		limit := r.MaxRedirects
+		if limit == 0 {
+			limit = DefaultMaxRedirects
+		}
+		if len(via) > limit {
+			return fmt.Errorf("stopped after %d redirects", limit)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRedirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/end", http.StatusFound)
+	})
+	mux.HandleFunc("/end", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestData_Execute_Redirects(t *testing.T) {
+	server := newRedirectServer(t)
+
+	req := &RequestData{Method: "GET", URL: server.URL + "/start", Auth: AuthData{Type: NoAuth}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != "done" {
+		t.Fatalf("Expected final response, got %d %q", resp.StatusCode, resp.Body)
+	}
+
+	want := []RedirectHop{
+		{URL: server.URL + "/start", StatusCode: http.StatusMovedPermanently, Location: "/middle"},
+		{URL: server.URL + "/middle", StatusCode: http.StatusFound, Location: "/end"},
+	}
+	if len(resp.Redirects) != len(want) {
+		t.Fatalf("Expected %d redirects, got %+v", len(want), resp.Redirects)
+	}
+	for i, hop := range want {
+		if resp.Redirects[i] != hop {
+			t.Errorf("Redirect %d = %+v, want %+v", i, resp.Redirects[i], hop)
+		}
+	}
+}
+
+func TestRequestData_Execute_DisableRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+
+	req := &RequestData{Method: "GET", URL: server.URL + "/start", DisableRedirects: true, Auth: AuthData{Type: NoAuth}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Headers["Location"] != "/middle" {
+		t.Errorf("Expected the redirect response itself, got %d %v", resp.StatusCode, resp.Headers)
+	}
+	if len(resp.Redirects) != 0 {
+		t.Errorf("Expected no redirects to be followed, got %+v", resp.Redirects)
+	}
+}
+
+func TestRequestData_Execute_MaxRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+
+	req := &RequestData{Method: "GET", URL: server.URL + "/start", MaxRedirects: 1, Auth: AuthData{Type: NoAuth}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(resp.Error, "stopped after 1 redirects") {
+		t.Errorf("Expected redirect limit error, got %q", resp.Error)
+	}
+	if len(resp.Redirects) != 2 {
+		t.Errorf("Expected the chain up to the refused redirect, got %+v", resp.Redirects)
+	}
+
+	req.MaxRedirects = -1
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "max redirects cannot be negative") {
+		t.Errorf("Expected negative max redirects to be rejected, got %v", err)
+	}
+}
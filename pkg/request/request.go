@@ -0,0 +1,587 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AuthType selects how a request authenticates
+type AuthType string
+
+// Supported authentication types
+const (
+	NoAuth        AuthType = "none"
+	BasicAuth     AuthType = "basic"
+	BearerAuth    AuthType = "bearer"
+	APIKeyAuth    AuthType = "apikey"
+	MutualTLSAuth AuthType = "mtls"
+	OAuth2Auth    AuthType = "oauth2"
+	GCPAuth       AuthType = "gcp"
+	AzureAuth     AuthType = "azure"
+	JWTAuth       AuthType = "jwt"
+	NTLMAuth      AuthType = "ntlm"
+	NegotiateAuth AuthType = "negotiate"
+	ProfileAuth   AuthType = "profile"
+	SessionAuth   AuthType = "session"
+)
+
+// AuthData represents authentication configuration
+type AuthData struct {
+	Type     AuthType `json:"type"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	APIKey   string   `json:"api_key,omitempty"`
+
+	// CertFile and KeyFile are the PEM client certificate and key of mtls
+	// auth, or CertFile is a PKCS#12 bundle (.p12 or .pfx) holding both.
+	// Password is the passphrase of an encrypted key or of the bundle.
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+
+	// Token is sent as Authorization: Bearer <token> with bearer auth
+	Token string `json:"token,omitempty"`
+
+	// APIKeyHeader and APIKeyPrefix send the API key as "<header>: <prefix>
+	// <key>", e.g. X-API-Key with no prefix, and APIKeyQuery sends it as
+	// this query parameter instead. Without any of them the key is sent as
+	// Authorization: Bearer <key>, as it was before they were added.
+	APIKeyHeader string `json:"api_key_header,omitempty"`
+	APIKeyPrefix string `json:"api_key_prefix,omitempty"`
+	APIKeyQuery  string `json:"api_key_query,omitempty"`
+
+	// CertSubject selects the mutual TLS client certificate from the system
+	// keystore by subject instead of CertFile and KeyFile
+	CertSubject string `json:"cert_subject,omitempty"`
+
+	// OAuth2Profile names the OAuth2 profile whose access token is sent as
+	// a Bearer token with oauth2 auth. The token is attached by middleware
+	// of the profile store, registered by the lighttr command.
+	OAuth2Profile string `json:"oauth2_profile,omitempty"`
+
+	// Profile names the auth profile whose saved credentials profile auth
+	// sends, so secrets are not kept with every request. The profile is
+	// looked up in the store registered with RegisterAuthProfiles when the
+	// request is executed.
+	Profile string `json:"profile,omitempty"`
+
+	// GCPAudience makes gcp auth send an ID token for the audience, such as
+	// the URL of a Cloud Run service, instead of an access token. The
+	// tokens are obtained with Google's Application Default Credentials by
+	// middleware the lighttr command registers.
+	GCPAudience string `json:"gcp_audience,omitempty"`
+
+	// AzureTenant, AzureClientID, and AzureScope select the Microsoft Entra
+	// ID application and the scope of the token azure auth sends, Microsoft
+	// Graph's by default. With AzureClientSecret the token is obtained for
+	// the application with the client credentials grant; without, the token
+	// of the user who logged in with lighttr azure login is sent. Middleware
+	// the lighttr command registers obtains the tokens.
+	AzureTenant       string `json:"azure_tenant,omitempty"`
+	AzureClientID     string `json:"azure_client_id,omitempty"`
+	AzureClientSecret string `json:"azure_client_secret,omitempty"`
+	AzureScope        string `json:"azure_scope,omitempty"`
+
+	// JWTClaims, a JSON object, is signed with the key in JWTKeyFile using
+	// JWTAlg by jwt auth, which sends a JWT minted for every request as a
+	// Bearer token. The key file holds the shared secret for HS256 and a
+	// PEM private key for RS256 and ES256.
+	JWTClaims  string `json:"jwt_claims,omitempty"`
+	JWTKeyFile string `json:"jwt_key_file,omitempty"`
+	JWTAlg     string `json:"jwt_alg,omitempty"`
+
+	// SessionLoginURL is where session auth logs in before the request,
+	// POSTing SessionLoginBody with its {{username}} and {{password}}
+	// replaced by Username and Password, or without a body sending them
+	// with basic auth. SessionExtract takes the credential from the login
+	// response: "$.path" from its JSON body, "header:<name>", or
+	// "cookie:<name>". A token is sent in SessionHeader, "Header: Prefix"
+	// and Authorization: Bearer by default, and a cookie as a cookie. The
+	// credential is reused until it expires or a response is 401.
+	SessionLoginURL  string `json:"session_login_url,omitempty"`
+	SessionLoginBody string `json:"session_login_body,omitempty"`
+	SessionExtract   string `json:"session_extract,omitempty"`
+	SessionHeader    string `json:"session_header,omitempty"`
+
+	// CAFile is a PEM bundle of additional certificate authorities trusted
+	// for the server certificate
+	CAFile string `json:"ca_file,omitempty"`
+
+	// CAFileOnly trusts only the certificate authorities of CAFile, not
+	// the system's, as for internal services with a private CA
+	CAFileOnly bool `json:"ca_file_only,omitempty"`
+
+	// InsecureSkipVerify disables server certificate verification
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// RequestData represents a complete HTTP request configuration
+type RequestData struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers"`
+	QueryParams map[string]string `json:"query_params"`
+	PathParams  map[string]string `json:"path_params,omitempty"`
+	Body        string            `json:"body"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Auth        AuthData          `json:"auth"`
+
+	// DisabledHeaders are kept with the request but not sent
+	DisabledHeaders map[string]string `json:"disabled_headers,omitempty"`
+
+	// MethodOverrideHeader, when set, sends PUT, PATCH, and DELETE requests
+	// as POST with the original method in this header
+	MethodOverrideHeader string `json:"method_override_header,omitempty"`
+
+	// Pins are sha256//<base64> public key hashes; when set, the server's
+	// certificate must match one of them
+	Pins []string `json:"pins,omitempty"`
+
+	// CheckRevocation queries the certificate's OCSP responder or CRL when
+	// the server did not staple an OCSP response
+	CheckRevocation bool `json:"check_revocation,omitempty"`
+
+	// DefaultHeaders come from the global configuration. Headers with the
+	// same name in Headers override them and in DisabledHeaders remove them.
+	DefaultHeaders map[string]string `json:"default_headers,omitempty"`
+
+	// Timeout bounds the whole exchange, including reading the body;
+	// ConnectTimeout bounds establishing the connection, TLSTimeout the TLS
+	// handshake, and ResponseHeaderTimeout the wait for the response headers
+	// once the request is sent, so that slow networks can be told apart
+	// from slow servers. Zero means no limit, except for the 30 seconds to
+	// connect and 10 for the handshake that Go allows by default.
+	Timeout               time.Duration `json:"timeout,omitempty"`
+	ConnectTimeout        time.Duration `json:"connect_timeout,omitempty"`
+	TLSTimeout            time.Duration `json:"tls_timeout,omitempty"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
+
+	// DisableRedirects returns redirect responses as-is instead of following
+	// them; otherwise up to MaxRedirects (DefaultMaxRedirects when zero) are
+	// followed
+	DisableRedirects bool `json:"disable_redirects,omitempty"`
+	MaxRedirects     int  `json:"max_redirects,omitempty"`
+
+	// Retry re-sends the request on connection errors and retryable status
+	// codes; nil sends it once
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// BodyFile streams the named file as the body instead of Body, without
+	// reading it into memory
+	BodyFile string `json:"body_file,omitempty"`
+
+	// BodyReader streams from the reader as the body instead of Body, e.g.
+	// a payload piped to stdin. It can be read only once, so it is not
+	// saved, cannot be retried, and is not resent on redirects.
+	BodyReader io.Reader `json:"-"`
+
+	// BodyFraming forces chunked transfer encoding or a Content-Length for
+	// the body; FramingAuto picks whichever the body allows
+	BodyFraming BodyFraming `json:"body_framing,omitempty"`
+
+	// Multipart sends the parts as a multipart/form-data body instead of
+	// Body, with a generated boundary
+	Multipart []FormPart `json:"multipart,omitempty"`
+
+	// FormParams sends the fields as an application/x-www-form-urlencoded
+	// body instead of Body
+	FormParams map[string]string `json:"form_params,omitempty"`
+
+	// ProtocolVersion forces HTTP/1.1 or HTTP/2; ProtocolAuto negotiates
+	ProtocolVersion ProtocolVersion `json:"protocol_version,omitempty"`
+
+	// Proxy routes the request through a proxy; nil uses the proxy from the
+	// environment
+	Proxy *ProxySettings `json:"proxy,omitempty"`
+
+	// Resolve overrides DNS for specific hosts, mapping host:port to the IP
+	// address (or comma separated addresses) to connect to, like curl's
+	// --resolve. The Host header and TLS server name keep the host name.
+	Resolve map[string]string `json:"resolve,omitempty"`
+
+	// Host is sent as the Host header instead of the URL's host, e.g. to
+	// reach a virtual-hosted service through a load balancer address in the
+	// URL; a Host entry in Headers does the same. TLS sends and verifies it
+	// as the server name unless ServerName is set.
+	Host string `json:"host,omitempty"`
+
+	// ServerName is the TLS server name (SNI) to send and verify the
+	// certificate against instead of the URL's host
+	ServerName string `json:"server_name,omitempty"`
+
+	// Interface binds outgoing connections to a local IP address, or to the
+	// address of a network interface such as "eth1", on multi-homed hosts
+	Interface string `json:"interface,omitempty"`
+
+	// IPVersion restricts connections to IPv4 (4) or IPv6 (6) addresses,
+	// like curl's -4 and -6; zero uses either
+	IPVersion int `json:"ip_version,omitempty"`
+
+	// MaxResponseSize stops reading the response body after this many
+	// bytes, of the body as received and after decompression, and marks the
+	// response Truncated; zero means no limit
+	MaxResponseSize int64 `json:"max_response_size,omitempty"`
+}
+
+// ResponseData represents the HTTP response
+type ResponseData struct {
+	StatusCode   int               `json:"status_code"`
+	Headers      map[string]string `json:"headers"`
+	Body         string            `json:"body"`
+	ResponseTime time.Duration     `json:"response_time"`
+	Error        string            `json:"error,omitempty"`
+	TLS          *TLSInfo          `json:"tls,omitempty"`
+
+	// ErrorKind classifies Error, when it is a recognized failure to reach
+	// the server
+	ErrorKind ErrorKind `json:"error_kind,omitempty"`
+
+	// RetryAfter is the delay requested by a 429 or 503 response
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+
+	// Redirects lists the redirects followed, in order
+	Redirects []RedirectHop `json:"redirects,omitempty"`
+
+	// Timing breaks ResponseTime down by phase
+	Timing *Timing `json:"timing,omitempty"`
+
+	// RemoteAddr is the address the request was sent to, as ip:port, or
+	// the proxy's address when it went through one
+	RemoteAddr string `json:"remote_addr,omitempty"`
+
+	// Attempts lists every try when the request has a retry policy
+	Attempts []Attempt `json:"attempts,omitempty"`
+
+	// ContentEncoding is the Content-Encoding the body was decoded from.
+	// CompressedSize is the size of the body as received and
+	// DecompressedSize its size after decoding.
+	ContentEncoding  string `json:"content_encoding,omitempty"`
+	CompressedSize   int64  `json:"compressed_size,omitempty"`
+	DecompressedSize int64  `json:"decompressed_size,omitempty"`
+
+	// DecodeError explains why a compressed body is shown as received
+	DecodeError string `json:"decode_error,omitempty"`
+
+	// Charset is the character set a non-UTF-8 body was converted from and
+	// CharsetSource where it was declared
+	Charset       string `json:"charset,omitempty"`
+	CharsetSource string `json:"charset_source,omitempty"`
+
+	// Protocol is the HTTP version the response was received with, e.g.
+	// "HTTP/2.0" or "HTTP/3.0"
+	Protocol string `json:"protocol,omitempty"`
+
+	// ProtocolFallback explains why the requested ProtocolVersion was not
+	// used
+	ProtocolFallback string `json:"protocol_fallback,omitempty"`
+
+	// Binary is set when the body holds bytes, such as an image or PDF,
+	// rather than text
+	Binary bool `json:"binary,omitempty"`
+
+	// Truncated is set when the body was cut off at the request's
+	// MaxResponseSize
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// NewRequestData creates a new RequestData with initialized maps
+func NewRequestData() *RequestData {
+	return &RequestData{
+		Method:      "GET",
+		Headers:     make(map[string]string),
+		QueryParams: make(map[string]string),
+		PathParams:  make(map[string]string),
+		Timestamp:   time.Now(),
+		Auth:        AuthData{Type: NoAuth},
+	}
+}
+
+// Clone returns a deep copy of the request with all maps initialized
+func (r *RequestData) Clone() *RequestData {
+	clone := *r
+	clone.Headers = copyMap(r.Headers)
+	clone.QueryParams = copyMap(r.QueryParams)
+	clone.PathParams = copyMap(r.PathParams)
+	if r.DisabledHeaders != nil {
+		clone.DisabledHeaders = copyMap(r.DisabledHeaders)
+	}
+	if r.DefaultHeaders != nil {
+		clone.DefaultHeaders = copyMap(r.DefaultHeaders)
+	}
+	if r.Retry != nil {
+		retry := *r.Retry
+		retry.RetryOn = append([]int(nil), r.Retry.RetryOn...)
+		clone.Retry = &retry
+	}
+	if r.Multipart != nil {
+		clone.Multipart = append([]FormPart(nil), r.Multipart...)
+	}
+	if r.FormParams != nil {
+		clone.FormParams = copyMap(r.FormParams)
+	}
+	if r.Proxy != nil {
+		proxy := *r.Proxy
+		proxy.Bypass = append([]string(nil), r.Proxy.Bypass...)
+		clone.Proxy = &proxy
+	}
+	if r.Resolve != nil {
+		clone.Resolve = copyMap(r.Resolve)
+	}
+	return &clone
+}
+
+// copyMap returns a copy of m, never nil
+func copyMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// Execute sends the HTTP request and returns the response
+func (r *RequestData) Execute() (*ResponseData, error) {
+	return r.ExecuteWith()
+}
+
+// EffectiveHeaders returns the headers that will be sent: the applicable
+// default headers merged with the request's own headers
+func (r *RequestData) EffectiveHeaders() map[string]string {
+	headers := make(map[string]string)
+	for key, value := range r.DefaultHeaders {
+		if r.ActiveDefaultHeader(key) {
+			headers[key] = value
+		}
+	}
+	for key, value := range r.Headers {
+		headers[key] = value
+	}
+	return headers
+}
+
+// ActiveDefaultHeader reports whether the named default header is sent,
+// i.e. it is neither overridden nor disabled by the request
+func (r *RequestData) ActiveDefaultHeader(name string) bool {
+	if _, ok := r.DefaultHeaders[name]; !ok {
+		return false
+	}
+	for _, headers := range []map[string]string{r.Headers, r.DisabledHeaders} {
+		for key := range headers {
+			if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Validate checks if the request data is valid
+func (r *RequestData) Validate() error {
+	if r.Method == "" {
+		return fmt.Errorf("method cannot be empty")
+	}
+	if r.URL == "" {
+		return fmt.Errorf("URL cannot be empty")
+	}
+	parsedURL, err := url.Parse(r.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return fmt.Errorf("invalid URL: must include scheme and host")
+	}
+	if err := r.validatePathParams(); err != nil {
+		return err
+	}
+	if err := validatePins(r.Pins); err != nil {
+		return err
+	}
+	if r.Timeout < 0 || r.ConnectTimeout < 0 || r.TLSTimeout < 0 || r.ResponseHeaderTimeout < 0 {
+		return fmt.Errorf("timeouts cannot be negative")
+	}
+	if r.MaxRedirects < 0 {
+		return fmt.Errorf("max redirects cannot be negative")
+	}
+	if r.MaxResponseSize < 0 {
+		return fmt.Errorf("max response size cannot be negative")
+	}
+	if err := r.ProtocolVersion.validate(); err != nil {
+		return err
+	}
+	if r.Proxy != nil {
+		if err := r.Proxy.validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateResolve(r.Resolve); err != nil {
+		return err
+	}
+	if err := r.validateHost(); err != nil {
+		return err
+	}
+	if r.Interface != "" {
+		if _, err := localAddresses(r.Interface); err != nil {
+			return err
+		}
+	}
+	if r.IPVersion != 0 && r.IPVersion != 4 && r.IPVersion != 6 {
+		return fmt.Errorf("invalid ip_version %d: use 4 or 6", r.IPVersion)
+	}
+	if r.Retry != nil {
+		if err := r.Retry.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.BodyFile != "" {
+		if r.Body != "" {
+			return fmt.Errorf("body and body file cannot both be set")
+		}
+		info, err := os.Stat(r.BodyFile)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("body file does not exist: %s", r.BodyFile)
+		}
+		if err == nil && info.IsDir() {
+			return fmt.Errorf("body file is a directory: %s", r.BodyFile)
+		}
+	}
+
+	if r.BodyReader != nil {
+		if r.Body != "" || r.BodyFile != "" {
+			return fmt.Errorf("body reader cannot be combined with a body or body file")
+		}
+		if r.Retry != nil && r.Retry.MaxAttempts > 1 {
+			return fmt.Errorf("a body read from a stream cannot be retried")
+		}
+	}
+	if err := r.BodyFraming.validate(); err != nil {
+		return err
+	}
+
+	if len(r.Multipart) > 0 {
+		if r.Body != "" || r.BodyFile != "" || r.BodyReader != nil {
+			return fmt.Errorf("multipart form cannot be combined with a body")
+		}
+		if err := validateMultipart(r.Multipart); err != nil {
+			return err
+		}
+	}
+
+	if len(r.FormParams) > 0 && (r.Body != "" || r.BodyFile != "" || r.BodyReader != nil || len(r.Multipart) > 0) {
+		return fmt.Errorf("form params cannot be combined with a body")
+	}
+
+	if r.Auth.CAFile != "" {
+		if _, err := os.Stat(r.Auth.CAFile); os.IsNotExist(err) {
+			return fmt.Errorf("CA file does not exist: %s", r.Auth.CAFile)
+		}
+	} else if r.Auth.CAFileOnly {
+		return fmt.Errorf("a CA file is required to trust only its certificate authorities")
+	}
+
+	// Validate authentication configuration
+	switch r.Auth.Type {
+	case BasicAuth:
+		if r.Auth.Username == "" {
+			return fmt.Errorf("username is required for basic authentication")
+		}
+		if r.Auth.Password == "" {
+			return fmt.Errorf("password is required for basic authentication")
+		}
+	case BearerAuth:
+		if r.Auth.Token == "" {
+			return fmt.Errorf("token is required for bearer authentication")
+		}
+	case APIKeyAuth:
+		if r.Auth.APIKey == "" {
+			return fmt.Errorf("API key is required for API key authentication")
+		}
+		if r.Auth.APIKeyQuery != "" && (r.Auth.APIKeyHeader != "" || r.Auth.APIKeyPrefix != "") {
+			return fmt.Errorf("an API key sent as a query parameter cannot have a header or prefix")
+		}
+	case OAuth2Auth:
+		if r.Auth.OAuth2Profile == "" {
+			return fmt.Errorf("profile is required for OAuth2 authentication")
+		}
+	case MutualTLSAuth:
+		if r.Auth.CertSubject != "" {
+			// The certificate is looked up in the system keystore
+			break
+		}
+		if r.Auth.CertFile == "" {
+			return fmt.Errorf("certificate file is required for mutual TLS authentication")
+		}
+		// Check if cert and key files exist
+		if _, err := os.Stat(r.Auth.CertFile); os.IsNotExist(err) {
+			return fmt.Errorf("certificate file does not exist: %s", r.Auth.CertFile)
+		}
+		if r.Auth.KeyFile == "" {
+			if isPKCS12(r.Auth.CertFile) {
+				// The bundle holds the key
+				break
+			}
+			return fmt.Errorf("key file is required for mutual TLS authentication")
+		}
+		if _, err := os.Stat(r.Auth.KeyFile); os.IsNotExist(err) {
+			return fmt.Errorf("key file does not exist: %s", r.Auth.KeyFile)
+		}
+	case GCPAuth:
+		// Credentials are found at send time, and an empty audience asks
+		// for an access token
+	case AzureAuth:
+		// The application may be given by AZURE_CLIENT_ID and the other
+		// variables of the Azure SDKs, read at send time
+	case NTLMAuth:
+		if r.Auth.Username == "" {
+			return fmt.Errorf("username is required for NTLM authentication")
+		}
+		if r.Auth.Password == "" {
+			return fmt.Errorf("password is required for NTLM authentication")
+		}
+		if r.ProtocolVersion == ProtocolHTTP2 || r.ProtocolVersion == ProtocolHTTP3 {
+			return fmt.Errorf("NTLM authentication needs HTTP/1.1: it authenticates the connection")
+		}
+	case NegotiateAuth:
+		// The Kerberos ticket comes from the credential cache of kinit at
+		// send time
+	case ProfileAuth:
+		// The profile's credentials are validated once they are looked up
+		if r.Auth.Profile == "" {
+			return fmt.Errorf("profile is required for profile authentication")
+		}
+	case SessionAuth:
+		if r.Auth.SessionLoginURL == "" {
+			return fmt.Errorf("login URL is required for session authentication")
+		}
+		if r.Auth.SessionExtract == "" {
+			return fmt.Errorf("extraction rule is required for session authentication")
+		}
+		if _, _, err := r.Auth.sessionExtractRule(); err != nil {
+			return err
+		}
+	case JWTAuth:
+		if !slices.Contains(JWTAlgorithms, r.Auth.JWTAlgorithm()) {
+			return fmt.Errorf("invalid JWT algorithm %q: use %s", r.Auth.JWTAlg, strings.Join(JWTAlgorithms, ", "))
+		}
+		if _, err := r.Auth.JWTClaimSet(); err != nil {
+			return err
+		}
+		if r.Auth.JWTKeyFile == "" {
+			return fmt.Errorf("key file is required for JWT authentication")
+		}
+		if _, err := os.Stat(r.Auth.JWTKeyFile); os.IsNotExist(err) {
+			return fmt.Errorf("JWT key file does not exist: %s", r.Auth.JWTKeyFile)
+		}
+	case NoAuth, "":
+		// No validation needed for NoAuth
+	default:
+		return fmt.Errorf("invalid authentication type: %s", r.Auth.Type)
+	}
+
+	return nil
+}
@@ -1,11 +1,13 @@
 package request
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewRequestData(t *testing.T) {
@@ -125,6 +127,30 @@ func TestRequestData_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "password is required for basic authentication",
 		},
+		{
+			name: "valid bearer auth",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth: AuthData{
+					Type:  BearerAuth,
+					Token: "test-token",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "bearer auth missing token",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth: AuthData{
+					Type: BearerAuth,
+				},
+			},
+			wantErr: true,
+			errMsg:  "token is required for bearer authentication",
+		},
 		{
 			name: "valid api key auth",
 			req: &RequestData{
@@ -149,6 +175,93 @@ func TestRequestData_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "API key is required for API key authentication",
 		},
+		{
+			name: "oauth2 auth missing profile",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth: AuthData{
+					Type: OAuth2Auth,
+				},
+			},
+			wantErr: true,
+			errMsg:  "profile is required for OAuth2 authentication",
+		},
+		{
+			name: "valid gcp auth without an audience",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://storage.googleapis.com/storage/v1/b",
+				Auth: AuthData{
+					Type: GCPAuth,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "jwt auth without a key file",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth:   AuthData{Type: JWTAuth, JWTClaims: `{"sub": "svc"}`},
+			},
+			wantErr: true,
+			errMsg:  "key file is required for JWT authentication",
+		},
+		{
+			name: "jwt auth with an unknown algorithm",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth:   AuthData{Type: JWTAuth, JWTKeyFile: "key.pem", JWTAlg: "PS512"},
+			},
+			wantErr: true,
+			errMsg:  `invalid JWT algorithm "PS512": use HS256, RS256, ES256`,
+		},
+		{
+			name: "jwt auth with invalid claims",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth:   AuthData{Type: JWTAuth, JWTClaims: `{"sub": `, JWTKeyFile: "key.pem"},
+			},
+			wantErr: true,
+			errMsg:  "invalid JWT claims: unexpected EOF",
+		},
+		{
+			name: "ntlm auth without a password",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://intranet.corp.example.com",
+				Auth:   AuthData{Type: NTLMAuth, Username: `CORP\jane`},
+			},
+			wantErr: true,
+			errMsg:  "password is required for NTLM authentication",
+		},
+		{
+			name: "ntlm auth over HTTP/2",
+			req: &RequestData{
+				Method:          "GET",
+				URL:             "https://intranet.corp.example.com",
+				Auth:            AuthData{Type: NTLMAuth, Username: `CORP\jane`, Password: "s3cret"},
+				ProtocolVersion: ProtocolHTTP2,
+			},
+			wantErr: true,
+			errMsg:  "NTLM authentication needs HTTP/1.1: it authenticates the connection",
+		},
+		{
+			name: "valid azure auth",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://graph.microsoft.com/v1.0/me",
+				Auth: AuthData{
+					Type:          AzureAuth,
+					AzureTenant:   "contoso.onmicrosoft.com",
+					AzureClientID: "00000000-0000-0000-0000-000000000001",
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid mutual TLS auth",
 			req: &RequestData{
@@ -162,6 +275,18 @@ func TestRequestData_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid mutual TLS auth from system keystore",
+			req: &RequestData{
+				Method: "GET",
+				URL:    "https://api.example.com",
+				Auth: AuthData{
+					Type:        MutualTLSAuth,
+					CertSubject: "CN=jane.doe",
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "mutual TLS auth missing cert file",
 			req: &RequestData{
@@ -216,6 +341,17 @@ func TestRequestData_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "key file does not exist",
 		},
+		{
+			name: "negative timeout",
+			req: &RequestData{
+				Method:  "GET",
+				URL:     "https://api.example.com",
+				Timeout: -time.Second,
+				Auth:    AuthData{Type: NoAuth},
+			},
+			wantErr: true,
+			errMsg:  "timeouts cannot be negative",
+		},
 		{
 			name: "invalid auth type",
 			req: &RequestData{
@@ -267,6 +403,15 @@ func TestRequestData_Execute(t *testing.T) {
 	}))
 	defer apiKeyServer.Close()
 
+	bearerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer bearerServer.Close()
+
 	standardServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Test request method
 		if r.Method != "POST" {
@@ -339,6 +484,32 @@ func TestRequestData_Execute(t *testing.T) {
 			wantStatus: http.StatusUnauthorized,
 			wantErr:    false,
 		},
+		{
+			name: "bearer auth success",
+			requestData: &RequestData{
+				Method: "GET",
+				URL:    bearerServer.URL,
+				Auth: AuthData{
+					Type:  BearerAuth,
+					Token: "test-token",
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantErr:    false,
+		},
+		{
+			name: "bearer auth failure",
+			requestData: &RequestData{
+				Method: "GET",
+				URL:    bearerServer.URL,
+				Auth: AuthData{
+					Type:  BearerAuth,
+					Token: "wrong-token",
+				},
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantErr:    false,
+		},
 		{
 			name: "api key auth success",
 			requestData: &RequestData{
@@ -412,3 +583,150 @@ func TestRequestData_Execute_Error(t *testing.T) {
 		t.Error("Expected error response for non-existent server")
 	}
 }
+
+func TestRequestData_Execute_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("late"))
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method:         "GET",
+		URL:            server.URL,
+		Timeout:        50 * time.Millisecond,
+		ConnectTimeout: time.Second,
+		Auth:           AuthData{Type: NoAuth},
+	}
+
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(resp.Error, "Timeout") {
+		t.Errorf("Expected timeout error, got %q", resp.Error)
+	}
+	if resp.ResponseTime >= 200*time.Millisecond {
+		t.Errorf("Expected request to be cut short, took %v", resp.ResponseTime)
+	}
+}
+
+func TestRequestData_Execute_TimeoutPhases(t *testing.T) {
+	// A server that accepts connections but never shakes hands
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	// A server that is slow to start responding, and one that sends its
+	// headers at once but is slow to send the body
+	slowHeaders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slowHeaders.Close()
+	slowBody := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+	defer slowBody.Close()
+
+	tests := []struct {
+		name string
+		req  *RequestData
+		want ErrorKind
+	}{
+		{"tls", &RequestData{URL: "https://" + listener.Addr().String(), TLSTimeout: 50 * time.Millisecond}, ErrorTLSTimeout},
+		{"headers", &RequestData{URL: slowHeaders.URL, ResponseHeaderTimeout: 50 * time.Millisecond}, ErrorHeaderTimeout},
+		{"body after headers", &RequestData{URL: slowBody.URL, ResponseHeaderTimeout: 50 * time.Millisecond}, ""},
+	}
+	for _, tt := range tests {
+		tt.req.Method = "GET"
+		tt.req.Auth.Type = NoAuth
+		resp, err := tt.req.Execute()
+		if err != nil {
+			t.Fatalf("%s: Execute() error = %v", tt.name, err)
+		}
+		if resp.ErrorKind != tt.want {
+			t.Errorf("%s: ErrorKind = %q, want %q (%s)", tt.name, resp.ErrorKind, tt.want, resp.Error)
+		}
+		if tt.want != "" && resp.ResponseTime >= 200*time.Millisecond {
+			t.Errorf("%s: Expected request to be cut short, took %v", tt.name, resp.ResponseTime)
+		}
+	}
+}
+
+func TestRequestData_EffectiveHeaders(t *testing.T) {
+	req := &RequestData{
+		Headers: map[string]string{
+			"accept":  "text/plain",
+			"X-Debug": "1",
+		},
+		DisabledHeaders: map[string]string{
+			"X-Org-Trace": "",
+		},
+		DefaultHeaders: map[string]string{
+			"Accept":      "application/json",
+			"User-Agent":  "lighttr",
+			"X-Org-Trace": "on",
+		},
+	}
+
+	headers := req.EffectiveHeaders()
+	want := map[string]string{
+		"accept":     "text/plain",
+		"X-Debug":    "1",
+		"User-Agent": "lighttr",
+	}
+	if len(headers) != len(want) {
+		t.Fatalf("EffectiveHeaders() = %v, want %v", headers, want)
+	}
+	for key, value := range want {
+		if headers[key] != value {
+			t.Errorf("EffectiveHeaders()[%s] = %q, want %q", key, headers[key], value)
+		}
+	}
+
+	if req.ActiveDefaultHeader("Accept") {
+		t.Error("Expected overridden default header to be inactive")
+	}
+	if req.ActiveDefaultHeader("X-Org-Trace") {
+		t.Error("Expected disabled default header to be inactive")
+	}
+	if !req.ActiveDefaultHeader("User-Agent") {
+		t.Error("Expected User-Agent default header to be active")
+	}
+}
+
+func TestRequestData_Clone(t *testing.T) {
+	req := &RequestData{
+		Method:  "POST",
+		URL:     "https://api.example.com",
+		Headers: map[string]string{"X-Debug": "1"},
+	}
+
+	clone := req.Clone()
+	clone.Headers["X-Debug"] = "2"
+	clone.QueryParams["page"] = "1"
+
+	if req.Headers["X-Debug"] != "1" {
+		t.Error("Expected clone headers to be independent of the original")
+	}
+	if req.QueryParams != nil {
+		t.Error("Expected original query params to be untouched")
+	}
+	if clone.PathParams == nil {
+		t.Error("Expected clone path params to be initialized")
+	}
+}
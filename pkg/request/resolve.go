@@ -0,0 +1,100 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseResolve parses a host override in curl's --resolve syntax,
+// host:port:address, into the Resolve key and address. The address may be
+// a comma separated list, tried in order, and IPv6 addresses may be given
+// in brackets.
+func ParseResolve(spec string) (string, string, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid resolve %q: expected host:port:address", spec)
+	}
+	return net.JoinHostPort(parts[0], parts[1]), parts[2], nil
+}
+
+// validateResolve checks that the overrides map host:port keys to IP
+// addresses
+func validateResolve(resolve map[string]string) error {
+	for key, value := range resolve {
+		if _, _, err := net.SplitHostPort(key); err != nil {
+			return fmt.Errorf("invalid resolve %q: expected host:port", key)
+		}
+		for _, address := range resolveAddresses(value) {
+			if net.ParseIP(address) == nil {
+				return fmt.Errorf("invalid resolve address %q for %s: expected an IP address", address, key)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveAddresses splits a list of override addresses, dropping IPv6
+// brackets
+func resolveAddresses(value string) []string {
+	var addresses []string
+	for _, address := range strings.Split(value, ",") {
+		addresses = append(addresses, strings.Trim(strings.TrimSpace(address), "[]"))
+	}
+	return addresses
+}
+
+// dialFunc is the signature of net.Dialer.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// resolveDialer wraps dial so that connections to an overridden host:port
+// go to its addresses instead of the ones DNS returns. The URL keeps the
+// host name, so the Host header and TLS server name are unchanged.
+func resolveDialer(resolve map[string]string, dial dialFunc) dialFunc {
+	if len(resolve) == 0 {
+		return dial
+	}
+	overrides := resolveOverrides(resolve)
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addresses, port, ok := overrides.lookup(addr)
+		if !ok {
+			return dial(ctx, network, addr)
+		}
+		var lastErr error
+		for _, address := range addresses {
+			conn, err := dial(ctx, network, net.JoinHostPort(address, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// hostOverrides maps lowercase host:port keys to the addresses to dial
+// instead
+type hostOverrides map[string][]string
+
+// resolveOverrides indexes host overrides for lookup
+func resolveOverrides(resolve map[string]string) hostOverrides {
+	o := make(hostOverrides, len(resolve))
+	for key, value := range resolve {
+		if host, port, err := net.SplitHostPort(key); err == nil {
+			o[net.JoinHostPort(strings.ToLower(host), port)] = resolveAddresses(value)
+		}
+	}
+	return o
+}
+
+// lookup returns the addresses addr is overridden with and its port
+func (o hostOverrides) lookup(addr string) (addresses []string, port string, ok bool) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, "", false
+	}
+	addresses, ok = o[net.JoinHostPort(strings.ToLower(host), port)]
+	return addresses, port, ok
+}
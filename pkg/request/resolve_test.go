@@ -0,0 +1,91 @@
+package request
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantKey  string
+		wantAddr string
+		wantErr  bool
+	}{
+		{"api.example.com:443:10.0.0.5", "api.example.com:443", "10.0.0.5", false},
+		{"api.example.com:443:[2001:db8::1]", "api.example.com:443", "[2001:db8::1]", false},
+		{"api.example.com:80:10.0.0.5,10.0.0.6", "api.example.com:80", "10.0.0.5,10.0.0.6", false},
+		{"api.example.com:443", "", "", true},
+		{"api.example.com::10.0.0.5", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			key, addr, err := ParseResolve(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if key != tt.wantKey || addr != tt.wantAddr {
+				t.Errorf("ParseResolve() = %q, %q, want %q, %q", key, addr, tt.wantKey, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestRequestData_Validate_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		resolve map[string]string
+		wantErr bool
+	}{
+		{"valid", map[string]string{"api.example.com:443": "10.0.0.5"}, false},
+		{"ipv6", map[string]string{"api.example.com:443": "[::1], 127.0.0.1"}, false},
+		{"missing port", map[string]string{"api.example.com": "10.0.0.5"}, true},
+		{"host name address", map[string]string{"api.example.com:443": "staging.internal"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &RequestData{Method: "GET", URL: "https://api.example.com", Resolve: tt.resolve}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestData_Resolve(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "host %s, server name %s", r.Host, r.TLS.ServerName)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test certificate is valid for example.com, which is sent to the
+	// local server instead of its real address, after the first address
+	// refuses the connection
+	u, _ := url.Parse(server.URL)
+	req := NewRequestData()
+	req.URL = "https://example.com:" + u.Port() + "/"
+	req.Auth.CAFile = caFile
+	req.Resolve = map[string]string{"Example.com:" + u.Port(): "[::1], 127.0.0.1"}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "host example.com:" + u.Port() + ", server name example.com"
+	if resp.Error != "" || resp.Body != want {
+		t.Errorf("Expected the request to reach the overridden address, got %q %q", resp.Error, resp.Body)
+	}
+}
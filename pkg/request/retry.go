@@ -0,0 +1,175 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryBackoff is the wait before the first retry when a policy sets
+// no backoff; each further retry doubles it
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// DefaultMaxRetryAfter is the longest Retry-After a policy without
+// MaxBackoff waits for
+const DefaultMaxRetryAfter = 2 * time.Minute
+
+// DefaultRetryStatuses are the status codes retried when a policy lists none
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// sleep is replaced in tests
+var sleep = time.Sleep
+
+// RetryPolicy retries requests that fail to connect or get a retryable
+// status, waiting with exponential backoff or as long as Retry-After asks
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int `json:"max_attempts"`
+
+	// Backoff is the wait before the first retry (DefaultRetryBackoff when
+	// zero) and MaxBackoff caps the doubled waits when set. A Retry-After
+	// longer than MaxBackoff (DefaultMaxRetryAfter when zero) is not waited
+	// for: the request is not retried, and the response reports it.
+	Backoff    time.Duration `json:"backoff,omitempty"`
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+
+	// RetryOn lists the status codes to retry; DefaultRetryStatuses when empty
+	RetryOn []int `json:"retry_on,omitempty"`
+}
+
+// Attempt records one try of a request sent with a retry policy
+type Attempt struct {
+	StatusCode   int           `json:"status_code,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	ResponseTime time.Duration `json:"response_time"`
+
+	// Wait is how long was waited before the next attempt
+	Wait time.Duration `json:"wait,omitempty"`
+}
+
+// String summarizes the attempt, e.g. "503 (12ms), retried after 500ms"
+func (a Attempt) String() string {
+	outcome := strconv.Itoa(a.StatusCode)
+	if a.Error != "" {
+		outcome = "error: " + a.Error
+	}
+	summary := fmt.Sprintf("%s (%v)", outcome, a.ResponseTime.Round(time.Millisecond))
+	if a.Wait > 0 {
+		summary += fmt.Sprintf(", retried after %v", a.Wait)
+	}
+	return summary
+}
+
+// validate checks the policy's limits
+func (p *RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 || p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("retry settings cannot be negative")
+	}
+	for _, status := range p.RetryOn {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("invalid retry status code: %d", status)
+		}
+	}
+	return nil
+}
+
+// shouldRetry reports whether the response is worth another attempt
+func (p *RetryPolicy) shouldRetry(resp *ResponseData) bool {
+	if resp.Error != "" {
+		return true
+	}
+	statuses := p.RetryOn
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns the delay after the given failed attempt (1-based),
+// preferring the server's Retry-After, and false when the server asks for
+// a longer wait than the policy allows
+func (p *RetryPolicy) wait(attempt int, resp *ResponseData) (time.Duration, bool) {
+	if resp.RetryAfter > 0 {
+		limit := p.MaxBackoff
+		if limit == 0 {
+			limit = DefaultMaxRetryAfter
+		}
+		return resp.RetryAfter, resp.RetryAfter <= limit
+	}
+	backoff := p.Backoff
+	if backoff == 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff, true
+}
+
+// retries re-sends the request according to the request's retry policy and
+// records every attempt in the response
+func retries(next Handler) Handler {
+	return func(x *Exchange) error {
+		policy := x.Data.Retry
+		if policy == nil || policy.MaxAttempts <= 1 {
+			return next(x)
+		}
+
+		// Attempts send fresh copies of the body, so release the original
+		original := x.Request
+		if original.Body != nil {
+			defer original.Body.Close()
+		}
+		var attempts []Attempt
+		for attempt := 1; ; attempt++ {
+			// Each attempt needs its own copy of the request and body
+			x.Request = original.Clone(original.Context())
+			if original.GetBody != nil {
+				body, err := original.GetBody()
+				if err != nil {
+					return err
+				}
+				x.Request.Body = body
+			}
+			x.Response = nil
+
+			if err := next(x); err != nil {
+				return err
+			}
+			attempts = append(attempts, Attempt{
+				StatusCode:   x.Response.StatusCode,
+				Error:        x.Response.Error,
+				ResponseTime: x.Response.ResponseTime,
+			})
+			if attempt >= policy.MaxAttempts || !policy.shouldRetry(x.Response) {
+				break
+			}
+
+			wait, ok := policy.wait(attempt, x.Response)
+			if !ok {
+				break
+			}
+			attempts[len(attempts)-1].Wait = wait
+			sleep(wait)
+		}
+		x.Response.Attempts = attempts
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Wait(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, expected := range want {
+		if got, ok := policy.wait(i+1, &ResponseData{StatusCode: 503}); got != expected || !ok {
+			t.Errorf("wait(%d) = %v, %v, want %v", i+1, got, ok, expected)
+		}
+	}
+
+	// Retry-After takes precedence over the backoff, up to MaxBackoff
+	policy.MaxBackoff = 3 * time.Second
+	if got, ok := policy.wait(1, &ResponseData{StatusCode: 429, RetryAfter: 2 * time.Second}); got != 2*time.Second || !ok {
+		t.Errorf("Expected Retry-After to be honored, got %v, %v", got, ok)
+	}
+	if _, ok := policy.wait(1, &ResponseData{StatusCode: 429, RetryAfter: 5 * time.Second}); ok {
+		t.Error("Expected a Retry-After past MaxBackoff not to be waited for")
+	}
+	if _, ok := (&RetryPolicy{}).wait(1, &ResponseData{StatusCode: 429, RetryAfter: time.Hour}); ok {
+		t.Error("Expected a Retry-After past DefaultMaxRetryAfter not to be waited for")
+	}
+
+	if got, _ := (&RetryPolicy{}).wait(2, &ResponseData{}); got != 2*DefaultRetryBackoff {
+		t.Errorf("Expected default backoff to double, got %v", got)
+	}
+}
+
+func TestAttempt_String(t *testing.T) {
+	attempt := Attempt{StatusCode: 503, ResponseTime: 12 * time.Millisecond, Wait: 500 * time.Millisecond}
+	if got := attempt.String(); got != "503 (12ms), retried after 500ms" {
+		t.Errorf("String() = %q", got)
+	}
+	attempt = Attempt{Error: "connection refused", ResponseTime: time.Millisecond}
+	if got := attempt.String(); got != "error: connection refused (1ms)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		resp   ResponseData
+		want   bool
+	}{
+		{name: "connection error", resp: ResponseData{Error: "connection refused"}, want: true},
+		{name: "default status", resp: ResponseData{StatusCode: 503}, want: true},
+		{name: "success", resp: ResponseData{StatusCode: 200}, want: false},
+		{name: "client error", resp: ResponseData{StatusCode: 404}, want: false},
+		{name: "configured status", policy: RetryPolicy{RetryOn: []int{500}}, resp: ResponseData{StatusCode: 500}, want: true},
+		{name: "status not configured", policy: RetryPolicy{RetryOn: []int{500}}, resp: ResponseData{StatusCode: 503}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.shouldRetry(&tt.resp); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestData_Execute_Retries(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch len(bodies) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method: "POST",
+		URL:    server.URL,
+		Body:   `{"id":1}`,
+		Auth:   AuthData{Type: NoAuth},
+		Retry:  &RetryPolicy{MaxAttempts: 5, Backoff: 10 * time.Millisecond},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != "ok" {
+		t.Fatalf("Expected the last attempt to succeed, got %d %q", resp.StatusCode, resp.Body)
+	}
+
+	if len(resp.Attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %+v", resp.Attempts)
+	}
+	if resp.Attempts[0].StatusCode != 503 || resp.Attempts[1].StatusCode != 429 || resp.Attempts[2].Wait != 0 {
+		t.Errorf("Unexpected attempts: %+v", resp.Attempts)
+	}
+	if len(waits) != 2 || waits[0] != 10*time.Millisecond || waits[1] != time.Second {
+		t.Errorf("Expected backoff then Retry-After waits, got %v", waits)
+	}
+	for i, body := range bodies {
+		if body != `{"id":1}` {
+			t.Errorf("Expected attempt %d to resend the body, got %q", i+1, body)
+		}
+	}
+}
+
+func TestRequestData_Execute_RetryAfterTooLong(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method: "GET",
+		URL:    server.URL,
+		Auth:   AuthData{Type: NoAuth},
+		Retry:  &RetryPolicy{MaxAttempts: 3, MaxBackoff: time.Minute},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(resp.Attempts) != 1 || len(waits) != 0 || resp.Attempts[0].Wait != 0 {
+		t.Errorf("Expected no retry, got %+v after waiting %v", resp.Attempts, waits)
+	}
+	if resp.RetryAfter != time.Hour {
+		t.Errorf("Expected the Retry-After to be reported, got %v", resp.RetryAfter)
+	}
+}
+
+func TestRequestData_Execute_RetriesExhausted(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	req := &RequestData{
+		Method: "GET",
+		URL:    "http://localhost:12345",
+		Auth:   AuthData{Type: NoAuth},
+		Retry:  &RetryPolicy{MaxAttempts: 3},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Error == "" || len(resp.Attempts) != 3 {
+		t.Errorf("Expected 3 failed attempts, got %q %+v", resp.Error, resp.Attempts)
+	}
+
+	req.Retry = &RetryPolicy{MaxAttempts: 2, RetryOn: []int{999}}
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "invalid retry status code") {
+		t.Errorf("Expected invalid status code error, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: " 0 ", want: 0, wantOK: true},
+		{value: "Wed, 01 Jan 2025 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{value: "Wed, 01 Jan 2025 11:00:00 GMT", want: 0, wantOK: true},
+		{value: "-5", want: 0, wantOK: false},
+		{value: "soon", want: 0, wantOK: false},
+		{value: "", want: 0, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRequestData_Execute_RetryAfter(t *testing.T) {
+	status := http.StatusTooManyRequests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	req := &RequestData{Method: "GET", URL: server.URL, Auth: AuthData{Type: NoAuth}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.RetryAfter != 7*time.Second {
+		t.Errorf("Expected RetryAfter 7s, got %v", resp.RetryAfter)
+	}
+
+	// Retry-After is only meaningful for 429 and 503 responses
+	status = http.StatusOK
+	resp, err = req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.RetryAfter != 0 {
+		t.Errorf("Expected no RetryAfter for 200, got %v", resp.RetryAfter)
+	}
+}
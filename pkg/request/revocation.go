@@ -0,0 +1,201 @@
+package request
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// expiryWarningWindow is how close to expiry a certificate must be to be flagged
+const expiryWarningWindow = 30 * 24 * time.Hour
+
+// revocationTimeout bounds OCSP and CRL lookups
+const revocationTimeout = 10 * time.Second
+
+// Revocation statuses reported in TLSInfo
+const (
+	RevocationGood    = "good"
+	RevocationRevoked = "revoked"
+	RevocationUnknown = "unknown"
+)
+
+// TLSInfo describes the TLS connection and server certificate of a response
+type TLSInfo struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipher_suite"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"not_after"`
+
+	// OCSPStapled reports whether the server stapled an OCSP response
+	OCSPStapled bool `json:"ocsp_stapled"`
+
+	// Revocation is the certificate status from the stapled response or an
+	// explicit OCSP/CRL check, and RevocationSource says where it came from
+	Revocation       string `json:"revocation,omitempty"`
+	RevocationSource string `json:"revocation_source,omitempty"`
+
+	// Warnings flags revoked, expired, or soon-to-expire certificates
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// newTLSInfo summarizes the connection state, checking the stapled OCSP
+// response and, if requested, querying OCSP or CRL endpoints directly
+func newTLSInfo(state *tls.ConnectionState, checkRevocation bool) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		OCSPStapled: len(state.OCSPResponse) > 0,
+	}
+	if len(state.PeerCertificates) == 0 {
+		return info
+	}
+
+	leaf := state.PeerCertificates[0]
+	info.Subject = leaf.Subject.String()
+	info.Issuer = leaf.Issuer.String()
+	info.NotAfter = leaf.NotAfter
+
+	if remaining := time.Until(leaf.NotAfter); remaining <= 0 {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339)))
+	} else if remaining < expiryWarningWindow {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("certificate expires in %d days", int(remaining.Hours()/24)))
+	}
+
+	issuer := issuerOf(state)
+	if info.OCSPStapled && issuer != nil {
+		if resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer); err == nil {
+			info.Revocation = ocspStatus(resp.Status)
+			info.RevocationSource = "stapled OCSP"
+		}
+	}
+
+	if checkRevocation && info.Revocation == "" {
+		status, source, err := queryRevocation(leaf, issuer)
+		if err != nil {
+			info.Revocation = RevocationUnknown
+			info.Warnings = append(info.Warnings, fmt.Sprintf("revocation check failed: %v", err))
+		} else {
+			info.Revocation = status
+			info.RevocationSource = source
+		}
+	}
+
+	if info.Revocation == RevocationRevoked {
+		info.Warnings = append(info.Warnings, "certificate has been revoked")
+	}
+	return info
+}
+
+// issuerOf returns the issuer of the leaf certificate, if the server sent it
+func issuerOf(state *tls.ConnectionState) *x509.Certificate {
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 1 {
+		return state.VerifiedChains[0][1]
+	}
+	if len(state.PeerCertificates) > 1 {
+		return state.PeerCertificates[1]
+	}
+	return nil
+}
+
+// ocspStatus converts an OCSP status code to a revocation status
+func ocspStatus(status int) string {
+	switch status {
+	case ocsp.Good:
+		return RevocationGood
+	case ocsp.Revoked:
+		return RevocationRevoked
+	default:
+		return RevocationUnknown
+	}
+}
+
+// queryRevocation checks the leaf certificate against its OCSP responder,
+// falling back to its CRL distribution points
+func queryRevocation(leaf, issuer *x509.Certificate) (status, source string, err error) {
+	if issuer == nil {
+		return "", "", fmt.Errorf("issuer certificate not available")
+	}
+
+	client := &http.Client{Timeout: revocationTimeout}
+
+	if len(leaf.OCSPServer) > 0 {
+		status, err := queryOCSP(client, leaf.OCSPServer[0], leaf, issuer)
+		if err == nil {
+			return status, "OCSP " + leaf.OCSPServer[0], nil
+		}
+		if len(leaf.CRLDistributionPoints) == 0 {
+			return "", "", err
+		}
+	}
+
+	if len(leaf.CRLDistributionPoints) > 0 {
+		status, err := queryCRL(client, leaf.CRLDistributionPoints[0], leaf, issuer)
+		if err != nil {
+			return "", "", err
+		}
+		return status, "CRL " + leaf.CRLDistributionPoints[0], nil
+	}
+
+	return "", "", fmt.Errorf("certificate has no OCSP responder or CRL distribution point")
+}
+
+// queryOCSP asks the OCSP responder for the status of the leaf certificate
+func queryOCSP(client *http.Client, server string, leaf, issuer *x509.Certificate) (string, error) {
+	body, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Post(server, "application/ocsp-request", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("OCSP request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(data, leaf, issuer)
+	if err != nil {
+		return "", fmt.Errorf("invalid OCSP response: %v", err)
+	}
+	return ocspStatus(parsed.Status), nil
+}
+
+// queryCRL downloads the CRL and looks for the leaf certificate serial number
+func queryCRL(client *http.Client, url string, leaf, issuer *x509.Certificate) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("CRL download failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid CRL: %v", err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return "", fmt.Errorf("invalid CRL signature: %v", err)
+	}
+
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			return RevocationRevoked, nil
+		}
+	}
+	return RevocationGood, nil
+}
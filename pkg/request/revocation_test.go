@@ -0,0 +1,185 @@
+package request
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testCertificates creates a CA and a leaf certificate issued by it
+func testCertificates(t *testing.T, notAfter time.Time, ocspServer, crlURL string) (leaf, issuer *x509.Certificate, issuerKey crypto.Signer) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	issuer, _ = x509.ParseCertificate(caDER)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate leaf key: %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "api.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	if ocspServer != "" {
+		leafTemplate.OCSPServer = []string{ocspServer}
+	}
+	if crlURL != "" {
+		leafTemplate.CRLDistributionPoints = []string{crlURL}
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, issuer, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("Failed to create leaf certificate: %v", err)
+	}
+	leaf, _ = x509.ParseCertificate(leafDER)
+
+	return leaf, issuer, caKey
+}
+
+// ocspResponse creates an OCSP response for leaf signed by the issuer
+func ocspResponse(t *testing.T, leaf, issuer *x509.Certificate, key crypto.Signer, status int) []byte {
+	t.Helper()
+
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-time.Minute)
+	}
+	resp, err := ocsp.CreateResponse(issuer, issuer, template, key)
+	if err != nil {
+		t.Fatalf("Failed to create OCSP response: %v", err)
+	}
+	return resp
+}
+
+func TestNewTLSInfo_Stapled(t *testing.T) {
+	leaf, issuer, key := testCertificates(t, time.Now().Add(10*24*time.Hour), "", "")
+
+	state := &tls.ConnectionState{
+		Version:          tls.VersionTLS13,
+		CipherSuite:      tls.TLS_AES_128_GCM_SHA256,
+		PeerCertificates: []*x509.Certificate{leaf, issuer},
+		OCSPResponse:     ocspResponse(t, leaf, issuer, key, ocsp.Revoked),
+	}
+
+	info := newTLSInfo(state, false)
+	if info.Version != "TLS 1.3" {
+		t.Errorf("Expected TLS 1.3, got %s", info.Version)
+	}
+	if !info.OCSPStapled {
+		t.Error("Expected OCSP response to be reported as stapled")
+	}
+	if info.Revocation != RevocationRevoked || info.RevocationSource != "stapled OCSP" {
+		t.Errorf("Expected revoked status from stapled OCSP, got %s from %s", info.Revocation, info.RevocationSource)
+	}
+
+	warnings := strings.Join(info.Warnings, "; ")
+	if !strings.Contains(warnings, "certificate expires in") {
+		t.Errorf("Expected expiry warning, got %v", info.Warnings)
+	}
+	if !strings.Contains(warnings, "revoked") {
+		t.Errorf("Expected revocation warning, got %v", info.Warnings)
+	}
+}
+
+func TestNewTLSInfo_NoStaple(t *testing.T) {
+	leaf, issuer, _ := testCertificates(t, time.Now().Add(365*24*time.Hour), "", "")
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, issuer}}
+
+	info := newTLSInfo(state, false)
+	if info.OCSPStapled || info.Revocation != "" {
+		t.Errorf("Expected no revocation information, got %+v", info)
+	}
+	if len(info.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", info.Warnings)
+	}
+}
+
+func TestNewTLSInfo_CheckRevocation(t *testing.T) {
+	var leaf, issuer *x509.Certificate
+	var key crypto.Signer
+
+	ocspServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Write(ocspResponse(t, leaf, issuer, key, ocsp.Good))
+	}))
+	defer ocspServer.Close()
+
+	leaf, issuer, key = testCertificates(t, time.Now().Add(365*24*time.Hour), ocspServer.URL, "")
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, issuer}}
+
+	info := newTLSInfo(state, true)
+	if info.Revocation != RevocationGood {
+		t.Errorf("Expected good status, got %s (%v)", info.Revocation, info.Warnings)
+	}
+	if !strings.HasPrefix(info.RevocationSource, "OCSP ") {
+		t.Errorf("Expected OCSP source, got %s", info.RevocationSource)
+	}
+}
+
+func TestNewTLSInfo_CheckRevocationCRL(t *testing.T) {
+	var leaf, issuer *x509.Certificate
+	var key crypto.Signer
+
+	crlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: time.Now().Add(-time.Hour),
+			NextUpdate: time.Now().Add(time.Hour),
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{SerialNumber: leaf.SerialNumber, RevocationTime: time.Now().Add(-time.Minute)},
+			},
+		}, issuer, key)
+		if err != nil {
+			t.Errorf("Failed to create CRL: %v", err)
+		}
+		w.Write(crl)
+	}))
+	defer crlServer.Close()
+
+	leaf, issuer, key = testCertificates(t, time.Now().Add(365*24*time.Hour), "", crlServer.URL)
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, issuer}}
+
+	info := newTLSInfo(state, true)
+	if info.Revocation != RevocationRevoked {
+		t.Errorf("Expected revoked status, got %s (%v)", info.Revocation, info.Warnings)
+	}
+	if !strings.HasPrefix(info.RevocationSource, "CRL ") {
+		t.Errorf("Expected CRL source, got %s", info.RevocationSource)
+	}
+}
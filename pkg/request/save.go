@@ -0,0 +1,165 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultFilename names a saved body when neither the response nor the URL
+// suggest a name
+const defaultFilename = "response"
+
+// preferredExtensions picks the usual extension for types the mime package
+// knows several extensions for
+var preferredExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"text/html":       ".html",
+	"text/plain":      ".txt",
+	"text/xml":        ".xml",
+	"application/xml": ".xml",
+	"audio/mpeg":      ".mp3",
+	"video/mpeg":      ".mpeg",
+	"image/tiff":      ".tiff",
+}
+
+// SuggestedFilename returns a file name for the body: the Content-Disposition
+// filename, else the last segment of the URL path after any redirects, else
+// "response". Names without an extension get the one of the Content-Type.
+func (r *ResponseData) SuggestedFilename(rawURL string) string {
+	name := sanitizeFilename(r.dispositionFilename())
+	if name == "" {
+		name = sanitizeFilename(urlFilename(r.finalURL(rawURL)))
+	}
+	if name == "" {
+		name = defaultFilename
+	}
+	if path.Ext(name) == "" {
+		name += extensionForType(r.ContentType())
+	}
+	return name
+}
+
+// SaveBodyAs writes the body into dir under its SuggestedFilename. An
+// existing file is never overwritten: the name is numbered instead, e.g.
+// "report (1).pdf". It returns the absolute path written.
+func (r *ResponseData) SaveBodyAs(dir, rawURL string) (string, error) {
+	return r.SaveBodyNew(filepath.Join(dir, r.SuggestedFilename(rawURL)))
+}
+
+// SaveBodyNew writes the body to path, or when a file already exists
+// there, to the first numbered variant of path that is free. It returns the
+// absolute path written.
+func (r *ResponseData) SaveBodyNew(path string) (string, error) {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	candidate := path
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save body: %v", err)
+		}
+		_, err = f.WriteString(r.Body)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save body: %v", err)
+		}
+		if abs, err := filepath.Abs(candidate); err == nil {
+			candidate = abs
+		}
+		return candidate, nil
+	}
+}
+
+// dispositionFilename returns the filename of the Content-Disposition
+// header; filename* in RFC 5987 form is decoded and takes precedence
+func (r *ResponseData) dispositionFilename() string {
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, "Content-Disposition") {
+			_, params, err := mime.ParseMediaType(value)
+			if err != nil {
+				return ""
+			}
+			return params["filename"]
+		}
+	}
+	return ""
+}
+
+// finalURL returns the URL the body was served from: rawURL, or the target
+// of the last redirect followed
+func (r *ResponseData) finalURL(rawURL string) string {
+	if len(r.Redirects) == 0 {
+		return rawURL
+	}
+	hop := r.Redirects[len(r.Redirects)-1]
+	base, err := url.Parse(hop.URL)
+	if err != nil {
+		return hop.Location
+	}
+	target, err := base.Parse(hop.Location)
+	if err != nil {
+		return hop.Location
+	}
+	return target.String()
+}
+
+// urlFilename returns the last segment of a URL path
+func urlFilename(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+// sanitizeFilename reduces a suggested name to a plain file name: no
+// directories, control characters, or leading dots that would hide it
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = path.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "/" {
+		return ""
+	}
+	return name
+}
+
+// extensionForType returns the file extension for a Content-Type, or an
+// empty string for unknown and generic binary types
+func extensionForType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/octet-stream" {
+		return ""
+	}
+	if ext, ok := preferredExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return ".json"
+	case strings.HasSuffix(mediaType, "+xml"):
+		return ".xml"
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package request
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResponseData_SuggestedFilename(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		headers map[string]string
+		want    string
+	}{
+		{"url path", "https://example.com/files/report.pdf?v=2", nil, "report.pdf"},
+		{"extension from content type", "https://example.com/users/42", map[string]string{"Content-Type": "application/json; charset=utf-8"}, "42.json"},
+		{"preferred extension", "https://example.com/photo", map[string]string{"Content-Type": "image/jpeg"}, "photo.jpg"},
+		{"structured suffix", "https://example.com/problem", map[string]string{"Content-Type": "application/problem+json"}, "problem.json"},
+		{"no path", "https://example.com", map[string]string{"Content-Type": "text/html"}, "response.html"},
+		{"octet-stream", "https://example.com/", map[string]string{"Content-Type": "application/octet-stream"}, "response"},
+		{"content disposition", "https://example.com/download?id=7", map[string]string{"Content-Disposition": `attachment; filename="Q3 report.xlsx"`}, "Q3 report.xlsx"},
+		{"encoded content disposition", "https://example.com/download", map[string]string{"content-disposition": `attachment; filename="fallback.txt"; filename*=UTF-8''%E2%82%AC%20rates.txt`}, "€ rates.txt"},
+		{"directories stripped", "https://example.com/download", map[string]string{"Content-Disposition": `attachment; filename="../../etc/passwd"`}, "passwd"},
+		{"hidden name", "https://example.com/download", map[string]string{"Content-Disposition": `attachment; filename=".bashrc"`}, "bashrc"},
+		{"unicode path", "https://example.com/b%C3%BCcher.csv", nil, "bücher.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &ResponseData{Headers: tt.headers}
+			if got := resp.SuggestedFilename(tt.url); got != tt.want {
+				t.Errorf("SuggestedFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseData_SuggestedFilenameAfterRedirect(t *testing.T) {
+	resp := &ResponseData{Redirects: []RedirectHop{
+		{URL: "https://example.com/latest", StatusCode: 302, Location: "/releases/v2.tar.gz"},
+	}}
+	if got := resp.SuggestedFilename("https://example.com/latest"); got != "v2.tar.gz" {
+		t.Errorf("SuggestedFilename() = %q, want the name of the redirect target", got)
+	}
+}
+
+func TestResponseData_SaveBodyAs(t *testing.T) {
+	dir := t.TempDir()
+	resp := &ResponseData{
+		Headers: map[string]string{"Content-Type": "text/csv"},
+		Body:    "id,name\n1,Jane\n",
+	}
+
+	for _, want := range []string{"users.csv", "users (1).csv", "users (2).csv"} {
+		path, err := resp.SaveBodyAs(dir, "https://example.com/users")
+		if err != nil {
+			t.Fatalf("SaveBodyAs() error = %v", err)
+		}
+		if path != filepath.Join(dir, want) {
+			t.Errorf("SaveBodyAs() = %q, want %q", path, filepath.Join(dir, want))
+		}
+		if saved, _ := os.ReadFile(path); string(saved) != resp.Body {
+			t.Errorf("Expected the file to hold the body, got %q", saved)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Schema is a protobuf message type or an Avro schema that response bodies
+// are validated against. Both the binary encodings and the JSON mappings
+// are understood.
+type Schema struct {
+	name  string
+	proto *protoMessage
+	avro  *avroType
+}
+
+// SchemaError is a place where a body does not match its schema. Path
+// leads to the offending value, e.g. .user.addresses[1].zip, and is empty
+// for the body as a whole.
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// LoadSchema reads a schema given as file.proto:package.Message or as
+// file.avsc, optionally followed by :namespace.Name to pick a named type
+// from the file. The message may be left out of a .proto file that defines
+// a single top-level message.
+func LoadSchema(spec string) (*Schema, error) {
+	path, name := spec, ""
+	for _, ext := range []string{".proto", ".avsc"} {
+		if i := strings.LastIndex(strings.ToLower(spec), ext+":"); i >= 0 {
+			path, name = spec[:i+len(ext)], spec[i+len(ext)+1:]
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".proto":
+		message, err := loadProtoMessage(path, name)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{name: message.name, proto: message}, nil
+	case ".avsc":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseAvroSchema(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Avro schema %s: %v", path, err)
+		}
+		return &Schema{name: t.String(), avro: t}, nil
+	}
+	return nil, fmt.Errorf("unsupported schema %s: expected a .proto or .avsc file", path)
+}
+
+// String returns the name of the message or type validated against
+func (s *Schema) String() string {
+	return s.name
+}
+
+// Validate checks a body against the schema, reading it as JSON when the
+// Content-Type says so or, without one, when it looks like JSON, and in the
+// binary encoding otherwise. It returns every mismatch found; a binary body
+// that cannot be decoded further stops at the first one.
+func (s *Schema) Validate(body, contentType string) []SchemaError {
+	var errs schemaErrors
+	if isJSONEncoded(body, contentType) {
+		decoder := json.NewDecoder(strings.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return []SchemaError{{Message: fmt.Sprintf("body is not valid JSON: %v", err)}}
+		}
+		if s.proto != nil {
+			s.proto.validateJSON(value, "", &errs)
+		} else {
+			s.avro.validateJSON(value, "", &errs)
+		}
+		return errs
+	}
+
+	if s.proto != nil {
+		s.proto.validateBinary([]byte(body), "", &errs)
+	} else {
+		validateAvroBinary(s.avro, []byte(body), &errs)
+	}
+	return errs
+}
+
+// ValidateResponse checks the response body against the schema
+func (r *ResponseData) ValidateResponse(schema *Schema) []SchemaError {
+	return schema.Validate(r.Body, r.ContentType())
+}
+
+// schemaErrors collects the mismatches found while validating
+type schemaErrors []SchemaError
+
+func (e *schemaErrors) add(path, format string, args ...interface{}) {
+	*e = append(*e, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// isJSONEncoded reports whether a body should be read with the JSON
+// mapping of its schema rather than the binary encoding
+func isJSONEncoded(body, contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return true
+	case mediaType != "" && mediaType != "text/plain":
+		return false
+	}
+	trimmed := bytes.TrimSpace([]byte(body))
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[' || trimmed[0] == '"') && json.Valid(trimmed)
+}
@@ -0,0 +1,57 @@
+package request
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSchema_Spec(t *testing.T) {
+	dir := t.TempDir()
+	avsc := filepath.Join(dir, "types.avsc")
+	types := `[{"type": "enum", "name": "Color", "namespace": "paint", "symbols": ["RED"]},
+		{"type": "record", "name": "Can", "namespace": "paint", "fields": [{"name": "color", "type": "Color"}]}]`
+	if err := os.WriteFile(avsc, []byte(types), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := LoadSchema(avsc + ":Can")
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	if schema.String() != "paint.Can" {
+		t.Errorf("Expected paint.Can, got %s", schema)
+	}
+	if errs := schema.Validate(`{"color": "BLUE"}`, "text/plain"); len(errs) != 1 || errs[0].Path != ".color" {
+		t.Errorf("Expected an error at .color, got %v", errs)
+	}
+	if errs := schema.Validate("\x00", "application/octet-stream"); len(errs) != 0 {
+		t.Errorf("Expected the binary encoding to be valid, got %v", errs)
+	}
+
+	if _, err := LoadSchema(filepath.Join(dir, "schema.json")); err == nil {
+		t.Error("Expected an error for a file that is not .proto or .avsc")
+	}
+	if _, err := LoadSchema(avsc + ":Bucket"); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+func TestIsJSONEncoded(t *testing.T) {
+	tests := []struct {
+		body, contentType string
+		want              bool
+	}{
+		{`{"a": 1}`, "application/json", true},
+		{`{"a": 1}`, "application/vnd.api+json; charset=utf-8", true},
+		{`{"a": 1}`, "", true},
+		{`{"a": 1}`, "application/x-protobuf", false},
+		{"\x08\x2a", "", false},
+		{"{not json", "text/plain", false},
+	}
+	for _, tt := range tests {
+		if got := isJSONEncoded(tt.body, tt.contentType); got != tt.want {
+			t.Errorf("isJSONEncoded(%q, %q) = %v, want %v", tt.body, tt.contentType, got, tt.want)
+		}
+	}
+}
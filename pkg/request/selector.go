@@ -0,0 +1,528 @@
+package request
+
+import (
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled CSS selector for extracting elements from HTML
+// responses. It supports the selectors commonly used for scraping:
+//
+//	table.prices td, #main, a[href^='https'], input[type=checkbox]
+//	ul > li, h2 + p, h2 ~ p                    child and sibling combinators
+//	li:first-child, li:last-child, tr:nth-child(odd), p:nth-of-type(2)
+//	li:not(.done), td:empty
+//
+// A comma separates alternatives, e.g. h1, h2.
+type Selector struct {
+	source string
+	groups []complexSelector
+}
+
+// complexSelector is a chain of compound selectors joined by combinators;
+// combinators[i] relates parts[i] to parts[i+1]
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+// compoundSelector matches a single element, e.g. a.external[href]
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+	pseudos []pseudoSelector
+}
+
+// attrSelector matches an attribute; an empty op tests for presence
+type attrSelector struct {
+	name, op, value string
+}
+
+// pseudoSelector is a structural pseudo-class. a and b are the an+b
+// arguments of the nth pseudo-classes and not holds the argument of :not().
+type pseudoSelector struct {
+	name string
+	a, b int
+	not  *compoundSelector
+}
+
+// CompileSelector parses a CSS selector
+func CompileSelector(selector string) (*Selector, error) {
+	s := &Selector{source: strings.TrimSpace(selector)}
+	for _, group := range splitSelectorGroups(s.source) {
+		complex, err := parseComplexSelector(strings.TrimSpace(group))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+		s.groups = append(s.groups, complex)
+	}
+	return s, nil
+}
+
+// String returns the source selector
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Query returns the matching elements of an HTML document in document
+// order. Elements with text yield the text with whitespace collapsed;
+// elements without text, such as images, yield their markup.
+func (s *Selector) Query(document string) ([]string, error) {
+	var values []string
+	var walk func(*htmlNode)
+	walk = func(node *htmlNode) {
+		for _, child := range node.elements() {
+			if s.matches(child) {
+				if text := child.textContent(); text != "" {
+					values = append(values, text)
+				} else {
+					values = append(values, strings.TrimSpace(document[child.start:child.end]))
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(parseHTML(document))
+	return values, nil
+}
+
+// matches reports whether an element matches any of the alternatives
+func (s *Selector) matches(element *htmlNode) bool {
+	for _, group := range s.groups {
+		if group.matches(element, len(group.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHTML reports whether the response body is an HTML document, judged by
+// the Content-Type or, failing that, a doctype or <html> tag
+func (r *ResponseData) IsHTML() bool {
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, "Content-Type") {
+			mediaType, _, _ := mime.ParseMediaType(value)
+			return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+		}
+	}
+	body := strings.ToLower(strings.TrimSpace(r.Body))
+	return strings.HasPrefix(body, "<!doctype html") || strings.HasPrefix(body, "<html")
+}
+
+// splitSelectorGroups splits a selector list at top-level commas
+func splitSelectorGroups(selector string) []string {
+	var groups []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			groups = append(groups, selector[start:i])
+			start = i + 1
+		}
+	}
+	return append(groups, selector[start:])
+}
+
+// parseComplexSelector parses compound selectors and the combinators
+// between them
+func parseComplexSelector(s string) (complexSelector, error) {
+	var complex complexSelector
+	if s == "" {
+		return complex, fmt.Errorf("empty selector")
+	}
+
+	i := 0
+	for {
+		compound, next, err := parseCompoundSelector(s, i)
+		if err != nil {
+			return complex, err
+		}
+		complex.parts = append(complex.parts, compound)
+		i = next
+
+		// A combinator is >, +, ~, or plain whitespace for descendants
+		combinator := byte(0)
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+			combinator = ' '
+			i++
+		}
+		if i == len(s) {
+			return complex, nil
+		}
+		if c := s[i]; c == '>' || c == '+' || c == '~' {
+			combinator = c
+			i++
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+				i++
+			}
+		}
+		if combinator == 0 {
+			return complex, fmt.Errorf("unexpected %q", s[i:])
+		}
+		if i == len(s) {
+			return complex, fmt.Errorf("missing selector after %q", combinator)
+		}
+		complex.combinators = append(complex.combinators, combinator)
+	}
+}
+
+// parseCompoundSelector parses the compound selector starting at s[i] and
+// returns the index after it
+func parseCompoundSelector(s string, i int) (compoundSelector, int, error) {
+	var compound compoundSelector
+	start := i
+	if i < len(s) && s[i] == '*' {
+		compound.tag = "*"
+		i++
+	} else if name := cssIdentifier(s[i:]); name != "" {
+		compound.tag = strings.ToLower(name)
+		i += len(name)
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			name := cssIdentifier(s[i+1:])
+			if name == "" {
+				return compound, i, fmt.Errorf("missing name after %q", s[i])
+			}
+			if s[i] == '#' {
+				compound.id = name
+			} else {
+				compound.classes = append(compound.classes, name)
+			}
+			i += 1 + len(name)
+		case '[':
+			end := matchingBracket(s[i:])
+			if end < 0 {
+				return compound, i, fmt.Errorf("unbalanced brackets")
+			}
+			attr, err := parseAttrSelector(s[i+1 : i+end])
+			if err != nil {
+				return compound, i, err
+			}
+			compound.attrs = append(compound.attrs, attr)
+			i += end + 1
+		case ':':
+			pseudo, length, err := parsePseudoSelector(s[i:])
+			if err != nil {
+				return compound, i, err
+			}
+			compound.pseudos = append(compound.pseudos, pseudo)
+			i += length
+		default:
+			if i == start {
+				return compound, i, fmt.Errorf("unexpected %q", s[i:])
+			}
+			return compound, i, nil
+		}
+	}
+	if i == start {
+		return compound, i, fmt.Errorf("missing selector")
+	}
+	return compound, i, nil
+}
+
+// cssIdentifier returns the identifier at the beginning of s
+func cssIdentifier(s string) string {
+	end := 0
+	for end < len(s) {
+		c := s[end]
+		if c == '-' || c == '_' || c >= '0' && c <= '9' || isASCIILetter(c) || c >= 0x80 {
+			end++
+			continue
+		}
+		break
+	}
+	return s[:end]
+}
+
+// attrSelectorOps are the attribute operators, tested before plain =
+var attrSelectorOps = []string{"~=", "|=", "^=", "$=", "*=", "="}
+
+// parseAttrSelector parses the inside of [name], [name=value], and the
+// other attribute operators
+func parseAttrSelector(s string) (attrSelector, error) {
+	for _, op := range attrSelectorOps {
+		name, value, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, ok := unquote(value); ok {
+			value = unquoted
+		} else if strings.ContainsAny(value, `'" `) {
+			return attrSelector{}, fmt.Errorf("invalid attribute value %q", value)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return attrSelector{}, fmt.Errorf("missing attribute name in [%s]", s)
+		}
+		return attrSelector{name: strings.ToLower(name), op: op, value: value}, nil
+	}
+
+	name := strings.TrimSpace(s)
+	if name == "" || cssIdentifier(name) != name {
+		return attrSelector{}, fmt.Errorf("invalid attribute selector [%s]", s)
+	}
+	return attrSelector{name: strings.ToLower(name)}, nil
+}
+
+// parsePseudoSelector parses a pseudo-class at the beginning of s and
+// returns it with its length
+func parsePseudoSelector(s string) (pseudoSelector, int, error) {
+	name := cssIdentifier(s[1:])
+	pseudo := pseudoSelector{name: strings.ToLower(name)}
+	length := 1 + len(name)
+
+	var argument string
+	if strings.HasPrefix(s[length:], "(") {
+		end, depth := -1, 0
+		for j := length; j < len(s) && end < 0; j++ {
+			switch s[j] {
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth == 0 {
+					end = j - length
+				}
+			}
+		}
+		if end < 0 {
+			return pseudo, 0, fmt.Errorf("unbalanced parentheses")
+		}
+		argument = strings.TrimSpace(s[length+1 : length+end])
+		length += end + 1
+	}
+
+	switch pseudo.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "empty":
+		if argument != "" {
+			return pseudo, 0, fmt.Errorf(":%s takes no argument", pseudo.name)
+		}
+	case "nth-child", "nth-last-child", "nth-of-type":
+		a, b, err := parseNth(argument)
+		if err != nil {
+			return pseudo, 0, err
+		}
+		pseudo.a, pseudo.b = a, b
+	case "not":
+		not, next, err := parseCompoundSelector(argument, 0)
+		if err != nil {
+			return pseudo, 0, err
+		}
+		if next != len(argument) {
+			return pseudo, 0, fmt.Errorf(":not() takes a single compound selector")
+		}
+		pseudo.not = &not
+	default:
+		return pseudo, 0, fmt.Errorf("unsupported pseudo-class :%s", name)
+	}
+	return pseudo, length, nil
+}
+
+// parseNth parses an an+b argument such as odd, even, 3, 2n+1, or -n+3
+func parseNth(s string) (int, int, error) {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	switch s {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+
+	coefficient, offset, hasN := strings.Cut(s, "n")
+	if !hasN {
+		b, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth argument %q", s)
+		}
+		return 0, b, nil
+	}
+
+	a := 1
+	switch coefficient {
+	case "", "+":
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth argument %q", s)
+		}
+	}
+	b := 0
+	if offset != "" {
+		var err error
+		if b, err = strconv.Atoi(offset); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth argument %q", s)
+		}
+	}
+	return a, b, nil
+}
+
+// matches reports whether the element matches parts[i] and the parts before
+// it are satisfied by its ancestors and siblings
+func (c complexSelector) matches(element *htmlNode, i int) bool {
+	if !c.parts[i].matches(element) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch c.combinators[i-1] {
+	case '>':
+		parent := element.parent
+		return parent != nil && parent.tag != "" && c.matches(parent, i-1)
+	case '+':
+		siblings, index := elementSiblings(element)
+		return index > 0 && c.matches(siblings[index-1], i-1)
+	case '~':
+		siblings, index := elementSiblings(element)
+		for _, sibling := range siblings[:index] {
+			if c.matches(sibling, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+	for ancestor := element.parent; ancestor != nil && ancestor.tag != ""; ancestor = ancestor.parent {
+		if c.matches(ancestor, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a single element matches the compound selector
+func (c compoundSelector) matches(element *htmlNode) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != element.tag {
+		return false
+	}
+	if c.id != "" {
+		if id, _ := element.attr("id"); id != c.id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		value, _ := element.attr("class")
+		classes := strings.Fields(value)
+		for _, class := range c.classes {
+			if !slices.Contains(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.matches(element) {
+			return false
+		}
+	}
+	for _, pseudo := range c.pseudos {
+		if !pseudo.matches(element) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches tests the attribute selector against an element
+func (a attrSelector) matches(element *htmlNode) bool {
+	value, ok := element.attr(a.name)
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "=":
+		return value == a.value
+	case "~=":
+		return slices.Contains(strings.Fields(value), a.value)
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return true
+}
+
+// matches tests the pseudo-class against an element
+func (p pseudoSelector) matches(element *htmlNode) bool {
+	if p.name == "not" {
+		return !p.not.matches(element)
+	}
+	if p.name == "empty" {
+		return len(element.children) == 0
+	}
+
+	siblings, index := elementSiblings(element)
+	if strings.HasSuffix(p.name, "-of-type") {
+		var sameType []*htmlNode
+		for _, sibling := range siblings {
+			if sibling.tag == element.tag {
+				if sibling == element {
+					index = len(sameType)
+				}
+				sameType = append(sameType, sibling)
+			}
+		}
+		siblings = sameType
+	}
+
+	switch p.name {
+	case "first-child", "first-of-type":
+		return index == 0
+	case "last-child", "last-of-type":
+		return index == len(siblings)-1
+	case "only-child":
+		return len(siblings) == 1
+	case "nth-last-child":
+		return nthMatches(p.a, p.b, len(siblings)-index)
+	}
+	return nthMatches(p.a, p.b, index+1)
+}
+
+// nthMatches reports whether the 1-based position is a*n+b for some n >= 0
+func nthMatches(a, b, position int) bool {
+	if a == 0 {
+		return position == b
+	}
+	n := position - b
+	return n%a == 0 && n/a >= 0
+}
+
+// elementSiblings returns the element children of the element's parent and
+// the element's index among them
+func elementSiblings(element *htmlNode) ([]*htmlNode, int) {
+	if element.parent == nil {
+		return []*htmlNode{element}, 0
+	}
+	siblings := element.parent.elements()
+	for i, sibling := range siblings {
+		if sibling == element {
+			return siblings, i
+		}
+	}
+	return siblings, 0
+}
@@ -0,0 +1,89 @@
+package request
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testHTMLPage = `<!DOCTYPE html>
+<html>
+<head><title>Prices &amp; more</title><style>td { color: red }</style></head>
+<body>
+<h1 id="main">Fruit <em>prices</em></h1>
+<p class="intro lead">Updated daily<br>in EUR
+<table class="prices">
+  <tr><th>Name<th>Price
+  <tr class="row"><td>Apple<td data-unit="kg">1.20
+  <tr class="row sale"><td>Banana<td data-unit="bunch">0.99
+  <tr class="row"><td>Cherry<td data-unit="kg">4.50
+</table>
+<ul>
+  <li><a href="https://example.com/a">A</a>
+  <li class="done"><a href="/b">B</a>
+  <li><img src="c.png" alt="C">
+</ul>
+<script>var x = "<td>not a cell</td>";</script>
+</body>
+</html>`
+
+func TestSelector_Query(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"table.prices td", []string{"Apple", "1.20", "Banana", "0.99", "Cherry", "4.50"}},
+		{"#main", []string{"Fruit prices"}},
+		{"title", []string{"Prices & more"}},
+		{"p.lead", []string{"Updated daily in EUR"}},
+		{"tr.sale > td:first-child", []string{"Banana"}},
+		{"tr:nth-child(even) td:last-child", []string{"1.20", "4.50"}},
+		{"tr.row:not(.sale) td:nth-of-type(1)", []string{"Apple", "Cherry"}},
+		{"td[data-unit=kg]", []string{"1.20", "4.50"}},
+		{"td[data-unit^='bu']", []string{"0.99"}},
+		{"a[href^=https]", []string{"A"}},
+		{"li.done ~ li img", []string{`<img src="c.png" alt="C">`}},
+		{"li:first-child + li a", []string{"B"}},
+		{"h1 em, th", []string{"prices", "Name", "Price"}},
+		{"ul > *:last-child", []string{`<li><img src="c.png" alt="C">`}},
+		{"li:not(:nth-child(2)) a", []string{"A"}},
+		{"div", nil},
+	}
+	for _, tt := range tests {
+		s, err := CompileSelector(tt.selector)
+		if err != nil {
+			t.Errorf("CompileSelector(%q) error = %v", tt.selector, err)
+			continue
+		}
+		got, _ := s.Query(testHTMLPage)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%q) = %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestCompileSelector_Invalid(t *testing.T) {
+	for _, selector := range []string{"", "td,", "tr >", "td[", "a[href=x y]", "li:hover", "td:nth-child(x)", "#", "td!"} {
+		if _, err := CompileSelector(selector); err == nil {
+			t.Errorf("CompileSelector(%q) expected an error", selector)
+		}
+	}
+}
+
+func TestResponseData_IsHTML(t *testing.T) {
+	tests := []struct {
+		headers map[string]string
+		body    string
+		want    bool
+	}{
+		{map[string]string{"Content-Type": "text/html; charset=utf-8"}, "<p>hi", true},
+		{map[string]string{"Content-Type": "application/json"}, "<html>", false},
+		{nil, "<!DOCTYPE html><html></html>", true},
+		{nil, "<Envelope/>", false},
+	}
+	for _, tt := range tests {
+		resp := &ResponseData{Headers: tt.headers, Body: tt.body}
+		if got := resp.IsHTML(); got != tt.want {
+			t.Errorf("IsHTML() for %v %q = %v, want %v", tt.headers, tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package request
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionSkew is how long before it expires a session credential is
+// replaced, so it does not expire in flight
+const sessionSkew = 30 * time.Second
+
+// For testing
+var sessionNow = time.Now
+
+// session is the credential a session login obtained
+type session struct {
+	// value is the token, or the value of the cookie when cookie is set
+	value  string
+	cookie string
+
+	// expires is zero when the login response did not say
+	expires time.Time
+}
+
+// sessions caches the credentials of session auth by login, held while
+// logging in so concurrent requests log in once
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[string]session)
+)
+
+// sessionExtractRule splits the extraction rule of session auth into where
+// the credential is taken from, "json", "header", or "cookie", and the
+// JSONPath or name
+func (a AuthData) sessionExtractRule() (source, name string, err error) {
+	rule := strings.TrimSpace(a.SessionExtract)
+	if strings.HasPrefix(rule, "$") {
+		return "json", rule, nil
+	}
+	source, name, ok := strings.Cut(rule, ":")
+	source = strings.ToLower(strings.TrimSpace(source))
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || (source != "header" && source != "cookie") {
+		return "", "", fmt.Errorf("invalid session extraction rule %q: use $.path, header:<name>, or cookie:<name>", a.SessionExtract)
+	}
+	return source, name, nil
+}
+
+// SessionHeaderParam returns the header a session token is sent in and its
+// value with any prefix; SessionHeader is "Header: Prefix", Authorization:
+// Bearer by default
+func (a AuthData) SessionHeaderParam(token string) (name, value string) {
+	name, prefix, _ := strings.Cut(a.SessionHeader, ":")
+	name, prefix = strings.TrimSpace(name), strings.TrimSpace(prefix)
+	if name == "" {
+		name, prefix = "Authorization", "Bearer"
+	}
+	if prefix != "" {
+		return name, prefix + " " + token
+	}
+	return name, token
+}
+
+// sessionLoginBody fills the {{username}} and {{password}} placeholders of
+// the login body template, escaped for a JSON body or a form
+func (a AuthData) sessionLoginBody() (body, contentType string) {
+	template := strings.TrimSpace(a.SessionLoginBody)
+	if template == "" {
+		return "", ""
+	}
+	escape, contentType := url.QueryEscape, "application/x-www-form-urlencoded"
+	if strings.HasPrefix(template, "{") || strings.HasPrefix(template, "[") {
+		contentType = "application/json"
+		escape = func(s string) string {
+			quoted, _ := json.Marshal(s)
+			return string(quoted[1 : len(quoted)-1])
+		}
+	}
+	body = strings.NewReplacer("{{username}}", escape(a.Username), "{{password}}", escape(a.Password)).Replace(template)
+	return body, contentType
+}
+
+// sessionKey identifies a login, so requests logging in the same way share
+// the credential
+func (a AuthData) sessionKey() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{a.SessionLoginURL, a.SessionLoginBody, a.Username, a.Password, a.SessionExtract}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// session returns the credential of session auth, logging in when there is
+// none yet or it has expired
+func (r *RequestData) session() (session, error) {
+	key := r.Auth.sessionKey()
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[key]; ok && (s.expires.IsZero() || sessionNow().Add(sessionSkew).Before(s.expires)) {
+		return s, nil
+	}
+	s, err := r.login()
+	if err != nil {
+		return session{}, err
+	}
+	sessions[key] = s
+	return s, nil
+}
+
+// forgetSession drops the credential of session auth, so the next request
+// logs in again
+func (a AuthData) forgetSession() {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	delete(sessions, a.sessionKey())
+}
+
+// login sends the login request of session auth over the request's
+// connection settings and extracts the credential from its response
+func (r *RequestData) login() (session, error) {
+	auth := r.Auth
+	source, name, err := auth.sessionExtractRule()
+	if err != nil {
+		return session{}, err
+	}
+
+	login := &RequestData{
+		Method:                "POST",
+		URL:                   auth.SessionLoginURL,
+		Headers:               map[string]string{},
+		DefaultHeaders:        r.DefaultHeaders,
+		Timeout:               r.Timeout,
+		ConnectTimeout:        r.ConnectTimeout,
+		TLSTimeout:            r.TLSTimeout,
+		ResponseHeaderTimeout: r.ResponseHeaderTimeout,
+		ProtocolVersion:       r.ProtocolVersion,
+		Proxy:                 r.Proxy,
+		Resolve:               r.Resolve,
+		Interface:             r.Interface,
+		IPVersion:             r.IPVersion,
+		Auth: AuthData{
+			Type:               NoAuth,
+			CAFile:             auth.CAFile,
+			CAFileOnly:         auth.CAFileOnly,
+			InsecureSkipVerify: auth.InsecureSkipVerify,
+		},
+	}
+	body, contentType := auth.sessionLoginBody()
+	if body != "" {
+		login.Body = body
+		login.Headers["Content-Type"] = contentType
+	} else if auth.Username != "" {
+		// Without a body the login takes the credentials by basic auth
+		login.Auth.Type = BasicAuth
+		login.Auth.Username = auth.Username
+		login.Auth.Password = auth.Password
+	}
+
+	if err := login.Validate(); err != nil {
+		return session{}, fmt.Errorf("session login: %v", err)
+	}
+	// The login skips the registered middleware, which is for the
+	// requests it authenticates
+	jar := NewCookieJar()
+	x := &Exchange{Data: login, Jar: jar}
+	if err := Chain(transport, templating, authentication)(x); err != nil {
+		return session{}, fmt.Errorf("session login to %s: %v", auth.SessionLoginURL, err)
+	}
+	resp := x.Response
+	if resp.Error != "" {
+		return session{}, fmt.Errorf("session login to %s failed: %s", auth.SessionLoginURL, resp.Error)
+	}
+	if resp.StatusCode >= 400 {
+		return session{}, fmt.Errorf("session login to %s failed: %d %s", auth.SessionLoginURL, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	switch source {
+	case "cookie":
+		for _, c := range jar.Stored() {
+			if c.Name == name {
+				return session{value: c.Value, cookie: c.Name, expires: c.Expires}, nil
+			}
+		}
+		return session{}, fmt.Errorf("session login to %s set no %s cookie", auth.SessionLoginURL, name)
+
+	case "header":
+		for key, value := range resp.Headers {
+			if strings.EqualFold(key, name) && value != "" {
+				return session{value: value, expires: tokenExpiry(value, "")}, nil
+			}
+		}
+		return session{}, fmt.Errorf("session login to %s returned no %s header", auth.SessionLoginURL, name)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(resp.Body), &doc); err != nil {
+		return session{}, fmt.Errorf("session login to %s did not return JSON: %v", auth.SessionLoginURL, err)
+	}
+	value, ok := LookupJSONPath(doc, name)
+	token, isString := value.(string)
+	if !ok || !isString || token == "" {
+		return session{}, fmt.Errorf("session login to %s returned no token at %s", auth.SessionLoginURL, name)
+	}
+	return session{value: token, expires: tokenExpiry(token, resp.Body)}, nil
+}
+
+// tokenExpiry returns when a token expires, from the expires_in seconds of
+// the JSON body it came in or the exp claim of a JWT, or zero if neither
+// says
+func tokenExpiry(token, body string) time.Time {
+	var fields struct {
+		ExpiresIn json.Number `json:"expires_in"`
+	}
+	if json.Unmarshal([]byte(body), &fields) == nil {
+		if seconds, err := fields.ExpiresIn.Int64(); err == nil && seconds > 0 {
+			return sessionNow().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil {
+		return time.Time{}
+	}
+	if exp, err := claims.Exp.Int64(); err == nil && exp > 0 {
+		return time.Unix(exp, 0)
+	}
+	return time.Time{}
+}
+
+// apply sends the credential with the request
+func (s session) apply(req *http.Request, auth AuthData) {
+	if s.cookie != "" {
+		req.AddCookie(&http.Cookie{Name: s.cookie, Value: s.value})
+		return
+	}
+	req.Header.Set(auth.SessionHeaderParam(s.value))
+}
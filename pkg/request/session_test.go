@@ -0,0 +1,198 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// forgetSessions clears the session credentials cached by the test
+func forgetSessions(t *testing.T) {
+	t.Cleanup(func() {
+		sessionsMu.Lock()
+		defer sessionsMu.Unlock()
+		sessions = make(map[string]session)
+	})
+}
+
+func TestRequestData_Execute_SessionAuth(t *testing.T) {
+	forgetSessions(t)
+	logins := 0
+	valid := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			body, _ := io.ReadAll(r.Body)
+			var creds map[string]string
+			if r.Header.Get("Content-Type") != "application/json" || json.Unmarshal(body, &creds) != nil || creds["password"] != `s3"cret` {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			logins++
+			valid = fmt.Sprintf("token-%d", logins)
+			fmt.Fprintf(w, `{"data": {"token": %q}, "expires_in": 3600}`, valid)
+		default:
+			if r.Header.Get("Authorization") != "Bearer "+valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer server.Close()
+
+	req := &RequestData{
+		Method: "GET",
+		URL:    server.URL + "/orders",
+		Auth: AuthData{
+			Type:             SessionAuth,
+			Username:         "jane",
+			Password:         `s3"cret`,
+			SessionLoginURL:  server.URL + "/login",
+			SessionLoginBody: `{"username": "{{username}}", "password": "{{password}}"}`,
+			SessionExtract:   "$.data.token",
+		},
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected the session token to be accepted, got %d", resp.StatusCode)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("Expected the token to be reused, got %d logins", logins)
+	}
+
+	// A 401 ends the session, so the next request logs in again
+	valid = "revoked"
+	if resp, _ := req.Execute(); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected the revoked token to be refused, got %d", resp.StatusCode)
+	}
+	if resp, err := req.Execute(); err != nil || resp.StatusCode != http.StatusOK || logins != 2 {
+		t.Errorf("Expected a new login after the 401, got %v, %d logins", err, logins)
+	}
+
+	// So does the token expiring
+	sessionNow = func() time.Time { return time.Now().Add(time.Hour) }
+	defer func() { sessionNow = time.Now }()
+	if _, err := req.Execute(); err != nil || logins != 3 {
+		t.Errorf("Expected a new login once the token expired, got %v, %d logins", err, logins)
+	}
+
+	req.Auth.Password = "wrong"
+	if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), "failed: 403 Forbidden") {
+		t.Errorf("Expected the failed login to be reported, got %v", err)
+	}
+}
+
+func TestRequestData_Execute_SessionCookie(t *testing.T) {
+	forgetSessions(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			user, password, _ := r.BasicAuth()
+			if user != "jane" || password != "s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Value: "abc", MaxAge: 600})
+			w.Header().Set("X-Auth-Token", "xyz")
+		default:
+			cookie, _ := r.Cookie("SESSIONID")
+			if cookie != nil {
+				fmt.Fprintf(w, "cookie %s", cookie.Value)
+			}
+			fmt.Fprint(w, r.Header.Get("X-Token"))
+		}
+	}))
+	defer server.Close()
+
+	for rule, want := range map[string]string{"cookie:SESSIONID": "cookie abc", "header:x-auth-token": "Token xyz"} {
+		req := &RequestData{
+			Method: "GET",
+			URL:    server.URL + "/me",
+			Auth: AuthData{
+				Type:            SessionAuth,
+				Username:        "jane",
+				Password:        "s3cret",
+				SessionLoginURL: server.URL + "/login",
+				SessionExtract:  rule,
+				SessionHeader:   "X-Token: Token",
+			},
+		}
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if resp.Body != want {
+			t.Errorf("Expected %q for %s, got %q", want, rule, resp.Body)
+		}
+	}
+
+	req := &RequestData{Method: "GET", URL: server.URL + "/me", Auth: AuthData{Type: SessionAuth, SessionLoginURL: server.URL + "/login", SessionExtract: "cookie:missing"}}
+	if _, err := req.Execute(); err == nil || !strings.Contains(err.Error(), "failed: 401 Unauthorized") {
+		t.Errorf("Expected the failed login to be reported, got %v", err)
+	}
+}
+
+func TestRequestData_Validate_SessionAuth(t *testing.T) {
+	for auth, want := range map[AuthData]string{
+		{Type: SessionAuth, SessionExtract: "$.token"}:                                             "login URL is required",
+		{Type: SessionAuth, SessionLoginURL: "https://example.com/login"}:                          "extraction rule is required",
+		{Type: SessionAuth, SessionLoginURL: "https://example.com/login", SessionExtract: "token"}: `invalid session extraction rule "token"`,
+	} {
+		req := &RequestData{Method: "GET", URL: "https://example.com", Auth: auth}
+		if err := req.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q for %+v, got %v", want, auth, err)
+		}
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	jwt := "eyJhbGciOiJIUzI1NiJ9.eyJleHAiOjE4OTM0NTYwMDB9.sig"
+	if got := tokenExpiry(jwt, ""); !got.Equal(time.Unix(1893456000, 0)) {
+		t.Errorf("Expected the JWT's exp claim, got %v", got)
+	}
+	if got := tokenExpiry("opaque", `{"token": "opaque"}`); !got.IsZero() {
+		t.Errorf("Expected no expiry, got %v", got)
+	}
+}
+
+func TestHARRecorder_RedactsSessionCredentials(t *testing.T) {
+	forgetSessions(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Value: "abc"})
+			fmt.Fprint(w, `{"token": "xyz"}`)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	recorder, err := OpenHARRecorder(path)
+	if err != nil {
+		t.Fatalf("OpenHARRecorder() error = %v", err)
+	}
+	for i, extract := range []string{"$.token", "cookie:SESSIONID"} {
+		req := NewRequestData()
+		req.URL = server.URL + "/me"
+		req.Auth = AuthData{Type: SessionAuth, SessionLoginURL: server.URL + "/login", SessionExtract: extract, SessionHeader: "X-Auth-Token"}
+		if _, err := req.ExecuteWith(WithHARRecorder(recorder)); err != nil {
+			t.Fatalf("ExecuteWith() error = %v", err)
+		}
+		for _, header := range readHAR(t, path).Log.Entries[i].Request.Headers {
+			if strings.Contains(header.Value, "abc") || strings.Contains(header.Value, "xyz") {
+				t.Errorf("Expected the session credential to be redacted, got %s: %s", header.Name, header.Value)
+			}
+		}
+	}
+}
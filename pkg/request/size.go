@@ -0,0 +1,72 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the suffixes ParseSize accepts to their multipliers; KB
+// and friends are decimal, KiB and friends binary
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+}
+
+// ParseSize parses a byte count such as "512", "100MB" or "1.5GiB"
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	value, err := strconv.ParseFloat(number, 64)
+	multiplier, ok := sizeUnits[unit]
+	if err != nil || !ok || value < 0 {
+		return 0, fmt.Errorf("invalid size %q: expected bytes or a size such as 100MB", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatSize formats a byte count for display, e.g. "100 MB", "1.5 KB" or
+// "512 bytes"
+func FormatSize(n int64) string {
+	for _, unit := range []struct {
+		name string
+		size int64
+	}{{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3}} {
+		if n >= unit.size {
+			value := strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/float64(unit.size)), ".0")
+			return value + " " + unit.name
+		}
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// readLimited reads r to the end, or up to limit bytes when limit is
+// positive, and reports whether there was more to read
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		data, err := io.ReadAll(r)
+		return data, false, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(data)) > limit {
+		return data[:limit], true, err
+	}
+	return data, false, err
+}
+
+// TruncatedSummary describes a truncated body, e.g. "showing the first
+// 100 MB of the body"
+func (r *ResponseData) TruncatedSummary() string {
+	return fmt.Sprintf("showing the first %s of the body", FormatSize(int64(len(r.Body))))
+}
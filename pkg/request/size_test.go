@@ -0,0 +1,110 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"512", 512},
+		{"100MB", 100 * 1000 * 1000},
+		{"1.5 KiB", 1536},
+		{"2gib", 2 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"", "MB", "10 parsecs", "-1"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:           "512 bytes",
+		1500:          "1.5 KB",
+		100_000_000:   "100 MB",
+		2_340_000_000: "2.3 GB",
+	}
+	for n, want := range tests {
+		if got := FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRequestData_MaxResponseSize(t *testing.T) {
+	payload := strings.Repeat("0123456789", 1000)
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte(payload))
+	w.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Query().Get("gzip") != "" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+			return
+		}
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	send := func(limit int64, compress bool) *ResponseData {
+		t.Helper()
+		req := NewRequestData()
+		req.URL = server.URL
+		req.MaxResponseSize = limit
+		if compress {
+			req.QueryParams["gzip"] = "1"
+		}
+		resp, err := req.Execute()
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return resp
+	}
+
+	resp := send(0, false)
+	if resp.Truncated || resp.Body != payload {
+		t.Errorf("Expected the whole body without a limit, got %d bytes (truncated %v)", len(resp.Body), resp.Truncated)
+	}
+	resp = send(int64(len(payload)), false)
+	if resp.Truncated || resp.Body != payload {
+		t.Errorf("Expected a body of exactly the limit to be whole, got %d bytes (truncated %v)", len(resp.Body), resp.Truncated)
+	}
+	resp = send(2500, false)
+	if !resp.Truncated || resp.Body != payload[:2500] {
+		t.Errorf("Expected the body cut off at 2500 bytes, got %d bytes (truncated %v)", len(resp.Body), resp.Truncated)
+	}
+	if got := resp.TruncatedSummary(); got != "showing the first 2.5 KB of the body" {
+		t.Errorf("TruncatedSummary() = %q", got)
+	}
+
+	// The limit applies to the decompressed body, so a small compressed
+	// body cannot expand without bound
+	resp = send(5000, true)
+	if !resp.Truncated || resp.Body != payload[:5000] || resp.DecodeError != "" {
+		t.Errorf("Expected the decompressed body cut off at 5000 bytes, got %d bytes (truncated %v, %s)", len(resp.Body), resp.Truncated, resp.DecodeError)
+	}
+
+	// A compressed body cut off on the wire decodes as far as it goes
+	resp = send(int64(compressed.Len()/2), true)
+	if !resp.Truncated || resp.DecodeError != "" || !strings.HasPrefix(payload, resp.Body) {
+		t.Errorf("Expected a partially decoded body, got %d bytes (truncated %v, %s)", len(resp.Body), resp.Truncated, resp.DecodeError)
+	}
+}
@@ -0,0 +1,181 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot holds responses recorded earlier, from a HAR archive or request
+// history, to serve in place of the live API, e.g. to reproduce how a chain
+// of requests behaved before an upstream change. Each request is answered
+// with the latest response recorded for its method and URL. It is safe for
+// concurrent use.
+type Snapshot struct {
+	mu        sync.Mutex
+	until     time.Time
+	responses map[string]snapshotResponse
+}
+
+// snapshotResponse is a recorded response and when it was recorded
+type snapshotResponse struct {
+	recorded time.Time
+	status   int
+	protocol string
+	headers  map[string]string
+	body     []byte
+}
+
+// NewSnapshot returns an empty snapshot that keeps the responses recorded
+// before until, or all of them if until is zero
+func NewSnapshot(until time.Time) *Snapshot {
+	return &Snapshot{until: until, responses: make(map[string]snapshotResponse)}
+}
+
+// LoadHARSnapshot returns a snapshot of the responses in the HAR archive at
+// path recorded before until. Entries that failed to reach the server are
+// skipped.
+func LoadHARSnapshot(path string, until time.Time) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open HAR file: %v", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file %s: %v", path, err)
+	}
+
+	s := NewSnapshot(until)
+	for _, entry := range har.Log.Entries {
+		if entry.Error != "" || entry.Response.Status == 0 {
+			continue
+		}
+		resp := &ResponseData{
+			StatusCode: entry.Response.Status,
+			Protocol:   entry.Response.HTTPVersion,
+			Headers:    make(map[string]string),
+			Body:       entry.Response.Content.Text,
+		}
+		for _, header := range entry.Response.Headers {
+			if existing := resp.Headers[header.Name]; existing != "" {
+				header.Value = existing + ", " + header.Value
+			}
+			resp.Headers[header.Name] = header.Value
+		}
+		if entry.Response.Content.Encoding == "base64" {
+			body, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse HAR file %s: response body of %s: %v", path, entry.Request.URL, err)
+			}
+			resp.Body = string(body)
+		}
+		s.Add(entry.Request.Method, entry.Request.URL, entry.StartedDateTime, resp)
+	}
+	return s, nil
+}
+
+// Add records the response to a request unless it was recorded after the
+// snapshot's date or an already recorded response to the same request is
+// more recent
+func (s *Snapshot) Add(method, rawURL string, recorded time.Time, resp *ResponseData) {
+	if !s.until.IsZero() && !recorded.Before(s.until) {
+		return
+	}
+	key := snapshotKey(method, rawURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.responses[key]; ok && existing.recorded.After(recorded) {
+		return
+	}
+	s.responses[key] = snapshotResponse{
+		recorded: recorded,
+		status:   resp.StatusCode,
+		protocol: resp.Protocol,
+		headers:  resp.Headers,
+		body:     []byte(resp.Body),
+	}
+}
+
+// Len returns the number of requests the snapshot has a response to
+func (s *Snapshot) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.responses)
+}
+
+// RoundTrip answers the request with its recorded response, or fails it if
+// none was recorded
+func (s *Snapshot) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	s.mu.Lock()
+	recorded, ok := s.responses[snapshotKey(req.Method, req.URL.String())]
+	s.mu.Unlock()
+	if !ok {
+		if !s.until.IsZero() {
+			return nil, fmt.Errorf("no response to %s %s was recorded before %s", req.Method, req.URL, s.until.Format("2006-01-02 15:04"))
+		}
+		return nil, fmt.Errorf("no response to %s %s was recorded", req.Method, req.URL)
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.status, http.StatusText(recorded.status)),
+		StatusCode:    recorded.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(strings.NewReader(string(recorded.body))),
+		ContentLength: int64(len(recorded.body)),
+		Request:       req,
+	}
+	if recorded.protocol != "" {
+		resp.Proto = recorded.protocol
+	}
+	for name, value := range recorded.headers {
+		// Recorded bodies are decoded, and framed anew here
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Encoding", "Content-Length", "Transfer-Encoding":
+			continue
+		}
+		resp.Header.Set(name, value)
+	}
+	return resp, nil
+}
+
+// WithSnapshot returns middleware that answers requests from the snapshot
+// instead of sending them. A nil snapshot leaves requests going to the
+// live API.
+func WithSnapshot(s *Snapshot) Middleware {
+	if s == nil {
+		return WithTransport(nil)
+	}
+	return WithTransport(s)
+}
+
+// snapshotKey identifies a request by its method and URL, with the scheme
+// and host lowercased and the query parameters sorted
+func snapshotKey(method, rawURL string) string {
+	method = strings.ToUpper(method)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.RawQuery = u.Query().Encode()
+	u.Fragment = ""
+	return method + " " + u.String()
+}
@@ -0,0 +1,76 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadHARSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": %q}`, r.URL.Query().Get("id"))
+	}))
+	path := filepath.Join(t.TempDir(), "session.har")
+	recorder, err := OpenHARRecorder(path)
+	if err != nil {
+		t.Fatalf("OpenHARRecorder() error = %v", err)
+	}
+	req := NewRequestData()
+	req.Method = "POST"
+	req.URL = server.URL + "/orders?id=42&b=1"
+	if _, err := req.ExecuteWith(WithHARRecorder(recorder)); err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	server.Close()
+
+	snapshot, err := LoadHARSnapshot(path, time.Time{})
+	if err != nil {
+		t.Fatalf("LoadHARSnapshot() error = %v", err)
+	}
+	// The query parameters may come in any order
+	req.URL = server.URL + "/orders?b=1&id=42"
+	resp, err := req.ExecuteWith(WithSnapshot(snapshot))
+	if err != nil {
+		t.Fatalf("ExecuteWith() error = %v", err)
+	}
+	if resp.Error != "" || resp.StatusCode != http.StatusCreated || resp.Body != `{"id": "42"}` || resp.Headers["X-Request-Id"] != "abc" {
+		t.Errorf("Expected the recorded response, got %+v", resp)
+	}
+
+	req.URL = server.URL + "/orders?id=43"
+	if resp, err := req.ExecuteWith(WithSnapshot(snapshot)); err != nil || !strings.Contains(resp.Error, "no response to POST "+server.URL+"/orders?id=43 was recorded") {
+		t.Errorf("Expected no recorded response, got %v, %+v", err, resp)
+	}
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if snapshot, err := LoadHARSnapshot(path, yesterday); err != nil || snapshot.Len() != 0 {
+		t.Errorf("Expected the response recorded today to be left out, got %v", err)
+	}
+}
+
+func TestSnapshot_Add(t *testing.T) {
+	until := time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC)
+	snapshot := NewSnapshot(until)
+	for day, status := range map[int]int{1: http.StatusOK, 5: http.StatusGone, 31: http.StatusAccepted} {
+		snapshot.Add("get", "HTTPS://API.example.com:443/users", until.AddDate(0, 0, -day), &ResponseData{StatusCode: status})
+	}
+	snapshot.Add("GET", "https://api.example.com/users", until, &ResponseData{StatusCode: http.StatusInternalServerError})
+
+	resp, err := snapshot.RoundTrip(httptest.NewRequest("GET", "https://api.example.com/users", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the latest response recorded before the date, got %d", resp.StatusCode)
+	}
+	if _, err := snapshot.RoundTrip(httptest.NewRequest("DELETE", "https://api.example.com/users", nil)); err == nil || !strings.Contains(err.Error(), "was recorded before 2026-09-02") {
+		t.Errorf("Expected no response to another method, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// BodyFraming selects how the end of the request body is conveyed over
+// HTTP/1.1. HTTP/2 frames bodies itself and ignores it.
+type BodyFraming string
+
+const (
+	// FramingAuto sends a Content-Length when the size of the body is known
+	// up front and chunked transfer encoding otherwise
+	FramingAuto BodyFraming = ""
+
+	// FramingChunked always streams the body with chunked transfer encoding
+	FramingChunked BodyFraming = "chunked"
+
+	// FramingContentLength always sends a Content-Length, buffering bodies
+	// of unknown size, such as a BodyReader, in memory to measure them
+	FramingContentLength BodyFraming = "content-length"
+)
+
+// ParseBodyFraming parses "chunked", "content-length" (or "length"), or
+// "auto"
+func ParseBodyFraming(value string) (BodyFraming, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return FramingAuto, nil
+	case "chunked":
+		return FramingChunked, nil
+	case "content-length", "length":
+		return FramingContentLength, nil
+	}
+	return "", fmt.Errorf("invalid body framing %q: use chunked, content-length, or auto", value)
+}
+
+// validate rejects unknown framings
+func (f BodyFraming) validate() error {
+	switch f {
+	case FramingAuto, FramingChunked, FramingContentLength:
+		return nil
+	}
+	return fmt.Errorf("invalid body framing %q: use %s or %s", f, FramingChunked, FramingContentLength)
+}
+
+// setBodyReader streams the reader as the request body. It can be read only
+// once, so there is no GetBody and the size is unknown.
+func setBodyReader(req *http.Request, reader io.Reader) {
+	req.Body = io.NopCloser(reader)
+	req.GetBody = nil
+	req.ContentLength = -1
+}
+
+// applyBodyFraming switches the request to the framing. Buffering a body
+// for its Content-Length replaces it with an in-memory copy that GetBody
+// can resend.
+func applyBodyFraming(req *http.Request, framing BodyFraming) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	switch framing {
+	case FramingChunked:
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+	case FramingContentLength:
+		if req.ContentLength >= 0 {
+			return nil
+		}
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read body: %v", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+		req.ContentLength = int64(len(data))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseBodyFraming(t *testing.T) {
+	tests := map[string]BodyFraming{
+		"":               FramingAuto,
+		"auto":           FramingAuto,
+		"Chunked":        FramingChunked,
+		"content-length": FramingContentLength,
+		"length":         FramingContentLength,
+	}
+	for input, want := range tests {
+		if got, err := ParseBodyFraming(input); err != nil || got != want {
+			t.Errorf("ParseBodyFraming(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseBodyFraming("gzip"); err == nil {
+		t.Error("Expected an error for an unknown framing")
+	}
+}
+
+func TestRequestData_BodyReaderAndFraming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		framing := "length " + strconv.FormatInt(r.ContentLength, 10)
+		if len(r.TransferEncoding) > 0 {
+			framing = strings.Join(r.TransferEncoding, ",")
+		}
+		w.Write([]byte(framing + ": " + string(body)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		setup   func(r *RequestData)
+		framing BodyFraming
+		want    string
+	}{
+		{"string body", func(r *RequestData) { r.Body = "hello" }, FramingAuto, "length 5: hello"},
+		{"reader", func(r *RequestData) { r.BodyReader = strings.NewReader("streamed") }, FramingAuto, "chunked: streamed"},
+		{"forced chunked", func(r *RequestData) { r.Body = "hello" }, FramingChunked, "chunked: hello"},
+		{"buffered reader", func(r *RequestData) { r.BodyReader = strings.NewReader("streamed") }, FramingContentLength, "length 8: streamed"},
+		{"buffered multipart", func(r *RequestData) { r.Multipart = []FormPart{{Name: "a", Value: "1"}} }, FramingContentLength, "length "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewRequestData()
+			req.Method = "POST"
+			req.URL = server.URL
+			req.BodyFraming = tt.framing
+			tt.setup(req)
+			resp, err := req.Execute()
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !strings.HasPrefix(resp.Body, tt.want) {
+				t.Errorf("Expected the server to receive %q, got %q", tt.want, resp.Body)
+			}
+		})
+	}
+}
+
+func TestRequestData_ValidateBodyReader(t *testing.T) {
+	req := NewRequestData()
+	req.URL = "https://example.com"
+	req.BodyReader = strings.NewReader("payload")
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	req.Body = "also a body"
+	if err := req.Validate(); err == nil {
+		t.Error("Expected an error for a body reader with a body")
+	}
+	req.Body = ""
+	req.Retry = &RetryPolicy{MaxAttempts: 3}
+	if err := req.Validate(); err == nil {
+		t.Error("Expected an error for retrying a body read from a stream")
+	}
+	req.Retry = nil
+	req.BodyFraming = "gzip"
+	if err := req.Validate(); err == nil {
+		t.Error("Expected an error for an unknown body framing")
+	}
+}
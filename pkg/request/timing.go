@@ -0,0 +1,104 @@
+package request
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks the response time down into the phases of the exchange.
+// Phases that did not happen, such as DNS and connecting on a reused
+// connection, are zero.
+type Timing struct {
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	TLS      time.Duration `json:"tls"`
+	TTFB     time.Duration `json:"ttfb"`
+	Transfer time.Duration `json:"transfer"`
+	Reused   bool          `json:"reused,omitempty"`
+
+	// IdleTime is how long a reused connection sat idle in the pool
+	IdleTime time.Duration `json:"idle_time,omitempty"`
+}
+
+// TimingPhase is a named phase of a Timing, for display
+type TimingPhase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Phases returns the phases in the order they happen
+func (t *Timing) Phases() []TimingPhase {
+	return []TimingPhase{
+		{Name: "DNS Lookup", Duration: t.DNS},
+		{Name: "TCP Connect", Duration: t.Connect},
+		{Name: "TLS Handshake", Duration: t.TLS},
+		{Name: "Time to First Byte", Duration: t.TTFB},
+		{Name: "Content Transfer", Duration: t.Transfer},
+	}
+}
+
+// tracing records the timing breakdown of the exchange. TTFB is measured
+// from the request being written to the first response byte, so it reflects
+// server processing time.
+func tracing(next Handler) Handler {
+	return func(x *Exchange) error {
+		var (
+			mu                               sync.Mutex
+			dnsStart, connectStart, tlsStart time.Time
+			wroteRequest, firstByte          time.Time
+			timing                           Timing
+			remoteAddr                       string
+		)
+		record := func(f func()) {
+			mu.Lock()
+			defer mu.Unlock()
+			f()
+		}
+
+		trace := &httptrace.ClientTrace{
+			DNSStart: func(httptrace.DNSStartInfo) { record(func() { dnsStart = time.Now() }) },
+			DNSDone: func(httptrace.DNSDoneInfo) {
+				record(func() { timing.DNS = time.Since(dnsStart) })
+			},
+			ConnectStart: func(string, string) { record(func() { connectStart = time.Now() }) },
+			ConnectDone: func(string, string, error) {
+				record(func() { timing.Connect = time.Since(connectStart) })
+			},
+			TLSHandshakeStart: func() { record(func() { tlsStart = time.Now() }) },
+			TLSHandshakeDone: func(tls.ConnectionState, error) {
+				record(func() { timing.TLS = time.Since(tlsStart) })
+			},
+			GotConn: func(info httptrace.GotConnInfo) {
+				countConnection(info.Reused)
+				record(func() {
+					timing.Reused, timing.IdleTime = info.Reused, info.IdleTime
+					remoteAddr = info.Conn.RemoteAddr().String()
+				})
+			},
+			WroteRequest: func(httptrace.WroteRequestInfo) {
+				record(func() { wroteRequest = time.Now() })
+			},
+			GotFirstResponseByte: func() { record(func() { firstByte = time.Now() }) },
+		}
+		x.Request = x.Request.WithContext(httptrace.WithClientTrace(x.Request.Context(), trace))
+
+		err := next(x)
+		done := time.Now()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if x.Response != nil {
+			x.Response.RemoteAddr = remoteAddr
+		}
+		if x.Response != nil && !firstByte.IsZero() {
+			if !wroteRequest.IsZero() {
+				timing.TTFB = firstByte.Sub(wroteRequest)
+			}
+			timing.Transfer = done.Sub(firstByte)
+			x.Response.Timing = &timing
+		}
+		return err
+	}
+}
@@ -0,0 +1,56 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestData_Execute_Timing(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("slow"))
+	}))
+	defer server.Close()
+
+	req := &RequestData{Method: "GET", URL: server.URL, Auth: AuthData{Type: NoAuth, InsecureSkipVerify: true}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	timing := resp.Timing
+	if timing == nil {
+		t.Fatal("Expected timing breakdown")
+	}
+	if timing.Connect <= 0 || timing.TLS <= 0 {
+		t.Errorf("Expected connect and TLS handshake times, got %+v", timing)
+	}
+	if timing.TTFB < 30*time.Millisecond {
+		t.Errorf("Expected TTFB to include server processing time, got %v", timing.TTFB)
+	}
+	if timing.TTFB > resp.ResponseTime {
+		t.Errorf("Expected TTFB %v to be within the response time %v", timing.TTFB, resp.ResponseTime)
+	}
+	// The URL is an IP address, so there is nothing to look up
+	if timing.DNS != 0 {
+		t.Errorf("Expected no DNS lookup, got %v", timing.DNS)
+	}
+
+	phases := timing.Phases()
+	if len(phases) != 5 || phases[0].Name != "DNS Lookup" || phases[3].Duration != timing.TTFB {
+		t.Errorf("Unexpected phases: %+v", phases)
+	}
+}
+
+func TestRequestData_Execute_TimingError(t *testing.T) {
+	req := &RequestData{Method: "GET", URL: "http://localhost:12345", Auth: AuthData{Type: NoAuth}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Timing != nil {
+		t.Errorf("Expected no timing without a response, got %+v", resp.Timing)
+	}
+}
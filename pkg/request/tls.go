@@ -0,0 +1,209 @@
+package request
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pinPrefix is the curl-compatible prefix of a public key pin
+const pinPrefix = "sha256//"
+
+// tlsConfig builds the TLS configuration for the request, or returns nil when
+// the defaults are sufficient
+func (r *RequestData) tlsConfig() (*tls.Config, error) {
+	serverName := r.tlsServerName()
+	if r.Auth.Type != MutualTLSAuth && len(r.Pins) == 0 && r.Auth.CAFile == "" && !r.Auth.InsecureSkipVerify && serverName == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: r.Auth.InsecureSkipVerify, ServerName: serverName}
+
+	if r.Auth.CAFile != "" {
+		roots, err := loadCAFile(r.Auth.CAFile, r.Auth.CAFileOnly)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if r.Auth.Type == MutualTLSAuth {
+		// Load client certificate from the system keystore or PEM files
+		var cert tls.Certificate
+		var err error
+		if r.Auth.CertSubject != "" {
+			cert, err = loadKeystoreCertificate(r.Auth.CertSubject)
+		} else {
+			cert, err = loadClientCertificate(r.Auth.CertFile, r.Auth.KeyFile, r.Auth.Password)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(r.Pins) > 0 {
+		tlsConfig.VerifyConnection = verifyPins(r.Pins)
+	}
+
+	return tlsConfig, nil
+}
+
+// loadClientCertificate loads the client certificate of mtls auth from a
+// PKCS#12 bundle or from PEM files, decrypting the key with the password
+// when it is encrypted
+func loadClientCertificate(certFile, keyFile, password string) (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if keyFile == "" && isPKCS12(certFile) {
+		return decodePKCS12(certPEM, password)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if keyPEM, err = decryptKeyPEM(keyPEM, password); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// decryptKeyPEM replaces the first private key block of PEM data with its
+// decrypted PKCS#8 or traditional form when it is encrypted, as keys written
+// by openssl with -aes256 or without -nodes are
+func decryptKeyPEM(data []byte, password string) ([]byte, error) {
+	rest := data
+	for {
+		block, next := pem.Decode(rest)
+		if block == nil {
+			// tls.X509KeyPair reports the missing key
+			return data, nil
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			rest = next
+			continue
+		}
+
+		var decrypted *pem.Block
+		switch {
+		case block.Type == "ENCRYPTED PRIVATE KEY":
+			if password == "" {
+				return nil, fmt.Errorf("the private key is encrypted: a password is required")
+			}
+			der, err := decryptPrivateKeyInfo(block.Bytes, password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key: %v", err)
+			}
+			decrypted = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+		case x509.IsEncryptedPEMBlock(block):
+			// Deprecated as insecure, but how openssl -traditional and older
+			// tools still encrypt keys
+			if password == "" {
+				return nil, fmt.Errorf("the private key is encrypted: a password is required")
+			}
+			der, err := x509.DecryptPEMBlock(block, []byte(password))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key: %v", errWrongPassword)
+			}
+			decrypted = &pem.Block{Type: block.Type, Bytes: der}
+		default:
+			return data, nil
+		}
+		return pem.EncodeToMemory(decrypted), nil
+	}
+}
+
+// isPKCS12 reports whether a certificate file is a PKCS#12 bundle, by its
+// extension or by being DER rather than PEM
+func isPKCS12(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".p12", ".pfx":
+		return true
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return false
+	}
+	// A PFX is a DER SEQUENCE
+	return data[0] == 0x30 && !bytes.Contains(data, []byte("-----BEGIN"))
+}
+
+// loadCAFile returns the system roots extended with the certificates in a
+// PEM bundle, or only the bundle's certificates
+func loadCAFile(path string, only bool) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !only {
+		if system, err := x509.SystemCertPool(); err == nil {
+			roots = system
+		}
+	}
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA file: %s", path)
+	}
+	return roots, nil
+}
+
+// PublicKeyPin returns the sha256//<base64> pin of a DER encoded
+// SubjectPublicKeyInfo
+func PublicKeyPin(rawSubjectPublicKeyInfo []byte) string {
+	sum := sha256.Sum256(rawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParsePins splits a ';' separated list of pins as accepted by curl's
+// --pinnedpubkey
+func ParsePins(value string) []string {
+	var pins []string
+	for _, pin := range strings.Split(value, ";") {
+		if pin = strings.TrimSpace(pin); pin != "" {
+			pins = append(pins, pin)
+		}
+	}
+	return pins
+}
+
+// validatePins checks that every pin is a sha256//<base64> hash
+func validatePins(pins []string) error {
+	for _, pin := range pins {
+		encoded, ok := strings.CutPrefix(pin, pinPrefix)
+		if !ok {
+			return fmt.Errorf("invalid pin %q: must start with %s", pin, pinPrefix)
+		}
+		hash, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("invalid pin %q: must be a base64 encoded SHA-256 hash", pin)
+		}
+	}
+	return nil
+}
+
+// verifyPins returns a connection check that fails unless the server's leaf
+// certificate public key matches one of the pins
+func verifyPins(pins []string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("public key pin mismatch: server presented no certificate")
+		}
+
+		actual := PublicKeyPin(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if pin == actual {
+				return nil
+			}
+		}
+		return fmt.Errorf("public key pin mismatch: server key is %s", actual)
+	}
+}
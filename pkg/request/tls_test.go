@@ -0,0 +1,196 @@
+package request
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePins(t *testing.T) {
+	got := ParsePins(" sha256//abc= ; sha256//def=;")
+	want := []string{"sha256//abc=", "sha256//def="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePins() = %v, want %v", got, want)
+	}
+}
+
+func TestValidatePins(t *testing.T) {
+	valid := PublicKeyPin([]byte("key"))
+
+	tests := []struct {
+		name    string
+		pins    []string
+		wantErr bool
+	}{
+		{name: "no pins", pins: nil, wantErr: false},
+		{name: "valid pin", pins: []string{valid}, wantErr: false},
+		{name: "missing prefix", pins: []string{strings.TrimPrefix(valid, "sha256//")}, wantErr: true},
+		{name: "wrong length", pins: []string{"sha256//YWJj"}, wantErr: true},
+		{name: "invalid base64", pins: []string{"sha256//not base64!"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePins(tt.pins); (err != nil) != tt.wantErr {
+				t.Errorf("validatePins() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyPins(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cert := server.Certificate()
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	pin := PublicKeyPin(cert.RawSubjectPublicKeyInfo)
+
+	if err := verifyPins([]string{PublicKeyPin([]byte("other")), pin})(state); err != nil {
+		t.Errorf("Expected matching pin to pass, got %v", err)
+	}
+
+	err := verifyPins([]string{PublicKeyPin([]byte("other"))})(state)
+	if err == nil || !strings.Contains(err.Error(), "public key pin mismatch") {
+		t.Errorf("Expected pin mismatch error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), pin) {
+		t.Errorf("Expected error to report the actual pin %s, got %v", pin, err)
+	}
+
+	if err := verifyPins([]string{pin})(tls.ConnectionState{}); err == nil {
+		t.Error("Expected error when no certificate is presented")
+	}
+}
+
+func TestRequestData_Execute_CustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("trusted"))
+	}))
+	defer server.Close()
+
+	// The test server's self-signed certificate is not trusted by default
+	req := &RequestData{Method: "GET", URL: server.URL, Auth: AuthData{Type: NoAuth}}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(resp.Error, "certificate") {
+		t.Fatalf("Expected certificate verification error, got %q", resp.Error)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	req.Auth.CAFile = caFile
+	resp, err = req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Error != "" || resp.Body != "trusted" {
+		t.Errorf("Expected request to succeed with the CA file, got %q %q", resp.Error, resp.Body)
+	}
+
+	req.Auth = AuthData{Type: NoAuth, InsecureSkipVerify: true}
+	resp, err = req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Error != "" || resp.Body != "trusted" {
+		t.Errorf("Expected request to succeed without verification, got %q %q", resp.Error, resp.Body)
+	}
+}
+
+func TestRequestData_Validate_CAFile(t *testing.T) {
+	req := &RequestData{
+		Method: "GET",
+		URL:    "https://internal.example.com",
+		Auth:   AuthData{Type: NoAuth, CAFile: "/non/existent/ca.pem"},
+	}
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "CA file does not exist") {
+		t.Errorf("Expected missing CA file error, got %v", err)
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0644)
+	req.Auth.CAFile = notPEM
+	if _, err := req.tlsConfig(); err == nil || !strings.Contains(err.Error(), "no certificates found") {
+		t.Errorf("Expected invalid CA file error, got %v", err)
+	}
+}
+
+func TestRequestData_Execute_MutualTLSPrivateCA(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// The test server's certificate is issued for example.com and 127.0.0.1
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	bundle := writeFixture(t, "client.p12", string(decodeFixture(t, modernBundle)))
+
+	req := &RequestData{
+		Method:     "GET",
+		URL:        server.URL,
+		ServerName: "example.com",
+		Auth:       AuthData{Type: MutualTLSAuth, CertFile: bundle, Password: "secret", CAFile: caFile, CAFileOnly: true},
+	}
+	resp, err := req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if resp.Error != "" || resp.Body != "client" {
+		t.Errorf("Expected the private CA to be trusted for example.com, got %q %q", resp.Error, resp.Body)
+	}
+
+	req.ServerName = "orders.internal.test"
+	resp, err = req.Execute()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(resp.Error, "orders.internal.test") {
+		t.Errorf("Expected the certificate to be verified for the server name, got %q", resp.Error)
+	}
+}
+
+func TestLoadCAFile_Only(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	roots, err := loadCAFile(caFile, true)
+	if err != nil {
+		t.Fatalf("loadCAFile() error = %v", err)
+	}
+	want := x509.NewCertPool()
+	want.AddCert(server.Certificate())
+	if !roots.Equal(want) {
+		t.Error("Expected only the CA file's certificate to be trusted")
+	}
+
+	req := &RequestData{Method: "GET", URL: server.URL, Auth: AuthData{Type: NoAuth, CAFileOnly: true}}
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "CA file is required") {
+		t.Errorf("Expected an error for CA only without a CA file, got %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// websocketGUID is appended to the handshake key to compute the accept key
+// of RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// WebSocket is a WebSocket connection opened by DialWebSocket. Messages are
+// sent and received whole; pings are answered while receiving. Send may be
+// called while another goroutine receives.
+type WebSocket struct {
+	// Response is the handshake response
+	Response *ResponseData
+
+	// Subprotocol is the subprotocol the server chose from those offered
+	// in Sec-WebSocket-Protocol, if any
+	Subprotocol string
+
+	conn    io.ReadWriteCloser
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	closed  atomic.Bool
+
+	// mask is set for the client side, which masks the frames it sends
+	mask bool
+
+	// maxSize limits the size of a received message; zero means no limit
+	maxSize int64
+}
+
+// WebSocketMessage is a text or binary message
+type WebSocketMessage struct {
+	Binary bool
+	Data   string
+}
+
+// WebSocketCloseError is returned by Receive when the peer closes the
+// connection, with the status code and reason it gave
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *WebSocketCloseError) Error() string {
+	if e.Code == 0 {
+		return "closed by the server"
+	}
+	if e.Reason == "" {
+		return fmt.Sprintf("closed by the server: %d", e.Code)
+	}
+	return fmt.Sprintf("closed by the server: %d %s", e.Code, e.Reason)
+}
+
+// IsWebSocket reports whether the request's URL is a ws:// or wss:// URL,
+// which is opened with DialWebSocket rather than sent
+func (r *RequestData) IsWebSocket() bool {
+	u, err := url.Parse(r.ResolvedURL())
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "ws" || scheme == "wss"
+}
+
+// DialWebSocket opens the ws:// or wss:// URL of the request with its
+// headers, auth, and connection settings, through the templating and
+// authentication stages, then the registered middleware, then the given
+// middleware. The request's method and body are not used; its timeout
+// applies to the handshake only.
+func (r *RequestData) DialWebSocket(middleware ...Middleware) (*WebSocket, error) {
+	r, err := r.executable()
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsWebSocket() {
+		return nil, fmt.Errorf("%s is not a WebSocket URL: use ws:// or wss://", r.URL)
+	}
+
+	var ws *WebSocket
+	dial := func(x *Exchange) error {
+		var err error
+		ws, err = dialWebSocket(x)
+		return err
+	}
+	stages := append([]Middleware{templating, authentication, providedCredentials}, registered()...)
+	stages = append(stages, middleware...)
+	x := &Exchange{Data: r}
+	if err := Chain(dial, stages...)(x); err != nil {
+		return nil, err
+	}
+	switch resp := x.Response; {
+	case resp == nil:
+		return nil, fmt.Errorf("WebSocket handshake failed")
+	case resp.Error != "":
+		return nil, fmt.Errorf("WebSocket handshake failed: %s", resp.Error)
+	case ws == nil:
+		return nil, fmt.Errorf("WebSocket handshake failed: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return ws, nil
+}
+
+// dialWebSocket sends the handshake and, when the server switches
+// protocols, returns the connection. Like the transport stage, it reports
+// failures to reach the server, and here invalid handshakes, in the
+// exchange's Response.Error, and a refused handshake as the response.
+func dialWebSocket(x *Exchange) (*WebSocket, error) {
+	r, req := x.Data, x.Request
+	switch strings.ToLower(req.URL.Scheme) {
+	case "ws":
+		req.URL.Scheme = "http"
+	case "wss":
+		req.URL.Scheme = "https"
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req.Method = http.MethodGet
+	req.Body, req.ContentLength = http.NoBody, 0
+	req.Header.Del("Content-Type")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	client := &http.Client{
+		Jar: x.Jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if x.Transport != nil {
+		client.Transport = x.Transport
+	} else {
+		transport, err := r.sharedTransport()
+		if err != nil {
+			return nil, err
+		}
+		if transport != nil {
+			client.Transport = transport
+		}
+	}
+	if r.Timeout > 0 {
+		// The timer only runs until the server answers, so the connection
+		// outlives it
+		ctx, cancel := context.WithCancel(req.Context())
+		timer := time.AfterFunc(r.Timeout, cancel)
+		defer timer.Stop()
+		req = req.WithContext(ctx)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		x.Response = &ResponseData{Error: err.Error(), ErrorKind: ClassifyError(err), ResponseTime: duration}
+		return nil, nil
+	}
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	x.Response = &ResponseData{StatusCode: resp.StatusCode, Protocol: resp.Proto, Headers: headers, ResponseTime: duration}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		body, _, _ := readLimited(resp.Body, 64<<10)
+		x.Response.Body = string(body)
+		return nil, nil
+	}
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		resp.Body.Close()
+		x.Response.Error = fmt.Sprintf("the server switched to %q", resp.Header.Get("Upgrade"))
+		return nil, nil
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		conn.Close()
+		x.Response.Error = "invalid Sec-WebSocket-Accept"
+		return nil, nil
+	}
+	ws := newWebSocket(conn, true, r.MaxResponseSize)
+	ws.Response = x.Response
+	ws.Subprotocol = resp.Header.Get("Sec-WebSocket-Protocol")
+	return ws, nil
+}
+
+// AcceptWebSocket answers a WebSocket handshake on the server side, e.g. to
+// stub a WebSocket server in tests
+func AcceptWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("not a WebSocket handshake")
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws := newWebSocket(conn, false, 0)
+	ws.reader = rw.Reader
+	return ws, nil
+}
+
+// newWebSocket wraps an upgraded connection
+func newWebSocket(conn io.ReadWriteCloser, mask bool, maxSize int64) *WebSocket {
+	return &WebSocket{conn: conn, reader: bufio.NewReader(conn), mask: mask, maxSize: maxSize}
+}
+
+// websocketAccept returns the Sec-WebSocket-Accept value for a handshake key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Send sends a text message
+func (ws *WebSocket) Send(text string) error {
+	return ws.writeFrame(opText, []byte(text))
+}
+
+// SendBinary sends a binary message
+func (ws *WebSocket) SendBinary(data []byte) error {
+	return ws.writeFrame(opBinary, data)
+}
+
+// Receive waits for the next text or binary message, answering pings
+// meanwhile. It returns a *WebSocketCloseError when the peer closes the
+// connection and io.EOF once Close was called.
+func (ws *WebSocket) Receive() (WebSocketMessage, error) {
+	var message []byte
+	var opcode byte
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			if ws.closed.Load() {
+				return WebSocketMessage{}, io.EOF
+			}
+			return WebSocketMessage{}, err
+		}
+
+		switch op {
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return WebSocketMessage{}, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &WebSocketCloseError{}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+				payload = payload[:2]
+			}
+			// Echo the status code, then drop the connection
+			ws.writeFrame(opClose, payload)
+			ws.closed.Store(true)
+			ws.conn.Close()
+			return WebSocketMessage{}, closeErr
+		case opText, opBinary:
+			if opcode != 0 {
+				return WebSocketMessage{}, fmt.Errorf("WebSocket protocol error: a new message started inside a fragmented one")
+			}
+			opcode = op
+		case opContinuation:
+			if opcode == 0 {
+				return WebSocketMessage{}, fmt.Errorf("WebSocket protocol error: continuation frame without a message")
+			}
+		default:
+			return WebSocketMessage{}, fmt.Errorf("WebSocket protocol error: unknown opcode %#x", op)
+		}
+
+		message = append(message, payload...)
+		if ws.maxSize > 0 && int64(len(message)) > ws.maxSize {
+			return WebSocketMessage{}, fmt.Errorf("WebSocket message larger than %d bytes", ws.maxSize)
+		}
+		if fin {
+			return WebSocketMessage{Binary: opcode == opBinary, Data: string(message)}, nil
+		}
+	}
+}
+
+// Close sends a normal closure to the peer and closes the connection
+func (ws *WebSocket) Close() error {
+	if ws.closed.Swap(true) {
+		return nil
+	}
+	ws.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, 1000))
+	return ws.conn.Close()
+}
+
+// readFrame reads one frame and unmasks its payload
+func (ws *WebSocket) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("WebSocket protocol error: unexpected extension bits")
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0f
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(ws.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > math.MaxInt32 || (ws.maxSize > 0 && length > uint64(ws.maxSize)) {
+		return false, 0, nil, fmt.Errorf("WebSocket frame of %d bytes is too large", length)
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(ws.reader, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeFrame sends a message or control frame unfragmented, masked on the
+// client side
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode, 0}
+	switch length := len(payload); {
+	case length < 126:
+		frame[1] = byte(length)
+	case length <= math.MaxUint16:
+		frame[1] = 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame[1] = 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	if ws.mask {
+		frame[1] |= 0x80
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= key[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if _, err := ws.conn.Write(frame); err != nil {
+		return err
+	}
+	return nil
+}
+
+// errWebSocketURL is returned when a ws:// or wss:// URL is sent as a
+// request
+var errWebSocketURL = errors.New("WebSocket URLs are opened with DialWebSocket, e.g. by lighttr ws, rather than sent")
@@ -0,0 +1,124 @@
+package request
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoWebSocket serves a WebSocket that echoes every message, pings the
+// client before answering "ping", and closes with the reason given in a
+// "close <reason>" message
+func echoWebSocket(t *testing.T) *httptest.Server {
+	return httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		ws, err := AcceptWebSocket(w, r)
+		if err != nil {
+			t.Errorf("AcceptWebSocket() error = %v", err)
+			return
+		}
+		defer ws.Close()
+		for {
+			msg, err := ws.Receive()
+			if err != nil {
+				return
+			}
+			switch {
+			case msg.Data == "ping":
+				ws.writeFrame(opPing, []byte("are you there"))
+			case strings.HasPrefix(msg.Data, "close "):
+				ws.writeFrame(opClose, append(binary.BigEndian.AppendUint16(nil, 4001), strings.TrimPrefix(msg.Data, "close ")...))
+				continue
+			}
+			ws.writeFrame(map[bool]byte{false: opText, true: opBinary}[msg.Binary], []byte(msg.Data))
+		}
+	}))
+}
+
+func TestRequestData_DialWebSocket(t *testing.T) {
+	server := echoWebSocket(t)
+	server.Start()
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = "ws" + strings.TrimPrefix(server.URL, "http") + "/echo"
+	req.Timeout = 100 * time.Millisecond
+	if _, err := req.DialWebSocket(); err == nil || err.Error() != "WebSocket handshake failed: 401 Unauthorized" {
+		t.Errorf("Expected the refused handshake to be reported, got %v", err)
+	}
+
+	req.Auth = AuthData{Type: BearerAuth, Token: "s3cret"}
+	ws, err := req.DialWebSocket()
+	if err != nil {
+		t.Fatalf("DialWebSocket() error = %v", err)
+	}
+	defer ws.Close()
+	if ws.Response.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected the handshake response, got %+v", ws.Response)
+	}
+
+	// The connection outlives the handshake timeout
+	time.Sleep(2 * req.Timeout)
+	large := strings.Repeat("x", 70000)
+	for _, sent := range []WebSocketMessage{{Data: "hello"}, {Data: "ping"}, {Data: large}, {Binary: true, Data: "\x00\x01"}} {
+		if sent.Binary {
+			err = ws.SendBinary([]byte(sent.Data))
+		} else {
+			err = ws.Send(sent.Data)
+		}
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		got, err := ws.Receive()
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		if got != sent {
+			t.Errorf("Expected the echo of %.20q, got %.20q", sent.Data, got.Data)
+		}
+	}
+
+	ws.Send("close going away")
+	var closeErr *WebSocketCloseError
+	if _, err := ws.Receive(); !errors.As(err, &closeErr) || closeErr.Code != 4001 || closeErr.Reason != "going away" {
+		t.Errorf("Expected the server to close the connection, got %v", err)
+	}
+	if _, err := ws.Receive(); err != io.EOF {
+		t.Errorf("Expected io.EOF once closed, got %v", err)
+	}
+
+	if _, err := req.Execute(); err != errWebSocketURL {
+		t.Errorf("Expected WebSocket URLs not to be sent as requests, got %v", err)
+	}
+}
+
+func TestRequestData_DialWebSocket_TLS(t *testing.T) {
+	server := echoWebSocket(t)
+	// WebSockets go over HTTP/1.1 even where HTTP/2 is offered
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	req := NewRequestData()
+	req.URL = "wss" + strings.TrimPrefix(server.URL, "https")
+	req.Auth = AuthData{Type: BearerAuth, Token: "s3cret", InsecureSkipVerify: true}
+	ws, err := req.DialWebSocket()
+	if err != nil {
+		t.Fatalf("DialWebSocket() error = %v", err)
+	}
+	defer ws.Close()
+	if err := ws.Send("hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got, err := ws.Receive(); err != nil || got.Data != "hello" {
+		t.Errorf("Expected the echo, got %q, %v", got.Data, err)
+	}
+}
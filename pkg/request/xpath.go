@@ -0,0 +1,557 @@
+package request
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XPath is a compiled XPath expression for extracting values from XML and
+// SOAP responses. It supports the subset of XPath 1.0 that is useful for
+// picking values out of a document:
+//
+//	/Envelope/Body/GetPriceResponse/Price   absolute location paths
+//	//Price, //item/@id, //item/text()       descendants, attributes, text
+//	*, ., ..                                 wildcards, self, and parent
+//	//item[2], //item[last()]                positional predicates
+//	//item[@id='7'], //item[price>10]        comparisons with =, !=, <, <=, >, >=
+//	//item[contains(name,'x')]               contains() and starts-with()
+//	count(//item)                            counting matches
+//
+// Names match the local part of element and attribute names, so namespace
+// prefixes such as soap:Body may be written or left out.
+type XPath struct {
+	expr  string
+	count bool
+	path  []xpathStep
+}
+
+// xpathStep is one location step of a path
+type xpathStep struct {
+	descendant bool // preceded by //
+	axis       string
+	name       string
+	predicates []string
+}
+
+// XPath step axes
+const (
+	axisChild     = "child"
+	axisAttribute = "attribute"
+	axisSelf      = "self"
+	axisParent    = "parent"
+	axisText      = "text"
+)
+
+// CompileXPath parses an XPath expression
+func CompileXPath(expr string) (*XPath, error) {
+	x := &XPath{expr: strings.TrimSpace(expr)}
+	path := x.expr
+	if inner, ok := strings.CutPrefix(path, "count("); ok && strings.HasSuffix(inner, ")") {
+		x.count = true
+		path = strings.TrimSpace(strings.TrimSuffix(inner, ")"))
+	}
+
+	steps, err := parseXPathSteps(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath %q: %v", expr, err)
+	}
+	x.path = steps
+	return x, nil
+}
+
+// String returns the source expression
+func (x *XPath) String() string {
+	return x.expr
+}
+
+// Query evaluates the expression against an XML document and returns the
+// matches in document order. Attributes, text nodes, and elements that only
+// hold text yield their text; other elements yield their markup. count()
+// yields the number of matches.
+func (x *XPath) Query(document string) ([]string, error) {
+	root, err := parseXMLDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := evalXPathSteps(x.path, []*xmlNode{root})
+	if x.count {
+		return []string{strconv.Itoa(len(nodes))}, nil
+	}
+	values := make([]string, len(nodes))
+	for i, node := range nodes {
+		values[i] = node.display(document)
+	}
+	return values, nil
+}
+
+// IsXML reports whether the response body is XML, judged by the Content-Type
+// or, failing that, an XML declaration
+func (r *ResponseData) IsXML() bool {
+	for key, value := range r.Headers {
+		if !strings.EqualFold(key, "Content-Type") {
+			continue
+		}
+		mediaType, _, _ := mime.ParseMediaType(value)
+		if mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml") {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.TrimSpace(r.Body), "<?xml")
+}
+
+// parseXPathSteps splits a location path into steps
+func parseXPathSteps(path string) ([]xpathStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	var steps []xpathStep
+	for i := 0; i < len(path); {
+		descendant := false
+		if strings.HasPrefix(path[i:], "//") {
+			descendant = true
+			i += 2
+		} else if path[i] == '/' {
+			i++
+		}
+		if i == len(path) {
+			if descendant || len(steps) > 0 {
+				return nil, fmt.Errorf("missing step after /")
+			}
+			break // "/" selects the document itself
+		}
+
+		end, err := stepEnd(path, i)
+		if err != nil {
+			return nil, err
+		}
+		step, err := parseXPathStep(strings.TrimSpace(path[i:end]))
+		if err != nil {
+			return nil, err
+		}
+		step.descendant = descendant
+		steps = append(steps, step)
+		i = end
+	}
+	return steps, nil
+}
+
+// stepEnd returns the index of the / ending the step that starts at i,
+// skipping slashes inside predicates and string literals
+func stepEnd(path string, i int) (int, error) {
+	depth := 0
+	var quote byte
+	for ; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '/' && depth == 0:
+			return i, nil
+		}
+	}
+	if quote != 0 {
+		return 0, fmt.Errorf("unterminated string")
+	}
+	if depth != 0 {
+		return 0, fmt.Errorf("unbalanced brackets")
+	}
+	return i, nil
+}
+
+// parseXPathStep parses a node test followed by its predicates
+func parseXPathStep(s string) (xpathStep, error) {
+	test := s
+	var predicates []string
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		test = strings.TrimSpace(s[:i])
+		rest := s[i:]
+		for rest != "" {
+			if rest[0] != '[' {
+				return xpathStep{}, fmt.Errorf("unexpected %q", rest)
+			}
+			end := matchingBracket(rest)
+			if end < 0 {
+				return xpathStep{}, fmt.Errorf("unbalanced brackets")
+			}
+			predicates = append(predicates, strings.TrimSpace(rest[1:end]))
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+	}
+
+	step := xpathStep{axis: axisChild, predicates: predicates}
+	switch {
+	case test == ".":
+		step.axis = axisSelf
+	case test == "..":
+		step.axis = axisParent
+	case test == "text()":
+		step.axis = axisText
+	case test == "node()" || test == "*":
+		step.name = "*"
+	case strings.HasPrefix(test, "@"):
+		step.axis = axisAttribute
+		step.name = localName(test[1:])
+	case test == "" || strings.Contains(test, "::") || strings.ContainsAny(test, "()=<>!,' \""):
+		return xpathStep{}, fmt.Errorf("unsupported step %q", s)
+	default:
+		step.name = localName(test)
+	}
+	if step.name == "" && (step.axis == axisChild || step.axis == axisAttribute) {
+		return xpathStep{}, fmt.Errorf("unsupported step %q", s)
+	}
+	return step, nil
+}
+
+// matchingBracket returns the index of the ] closing the [ at s[0]
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// localName strips a namespace prefix
+func localName(name string) string {
+	if _, local, ok := strings.Cut(name, ":"); ok {
+		return local
+	}
+	return name
+}
+
+// xmlNode is a node of a parsed XML document
+type xmlNode struct {
+	axis     string // child for elements, attribute, text, or empty for the document
+	name     string
+	text     string
+	parent   *xmlNode
+	children []*xmlNode
+	attrs    []*xmlNode
+	order    int
+
+	// start and end are the offsets of an element's markup in the document
+	start, end int64
+}
+
+// parseXMLDocument parses a document into a tree of nodes
+func parseXMLDocument(document string) (*xmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	// Bodies are matched as they were received; charsets are not converted
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	root := &xmlNode{}
+	current := root
+	order := 1
+	hasElement := false
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("body is not XML: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			hasElement = true
+			element := &xmlNode{axis: axisChild, name: t.Name.Local, parent: current, order: order, start: offset}
+			order++
+			for _, attr := range t.Attr {
+				element.attrs = append(element.attrs, &xmlNode{axis: axisAttribute, name: attr.Name.Local, text: attr.Value, parent: element, order: order})
+				order++
+			}
+			current.children = append(current.children, element)
+			current = element
+		case xml.EndElement:
+			current.end = decoder.InputOffset()
+			current = current.parent
+		case xml.CharData:
+			if current == root || strings.TrimSpace(string(t)) == "" {
+				continue
+			}
+			current.children = append(current.children, &xmlNode{axis: axisText, text: string(t), parent: current, order: order})
+			order++
+		}
+	}
+	if !hasElement {
+		return nil, fmt.Errorf("body is not XML: no root element")
+	}
+	return root, nil
+}
+
+// value returns the XPath string value of the node: the text of attributes
+// and text nodes, and the concatenated text of elements
+func (n *xmlNode) value() string {
+	if n.axis == axisAttribute || n.axis == axisText {
+		return n.text
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(child.value())
+	}
+	return b.String()
+}
+
+// display renders a match: markup for elements with child elements, text
+// for everything else
+func (n *xmlNode) display(document string) string {
+	if n.axis == axisChild {
+		for _, child := range n.children {
+			if child.axis == axisChild {
+				return document[n.start:n.end]
+			}
+		}
+	}
+	if n.axis == "" {
+		return strings.TrimSpace(document)
+	}
+	return strings.TrimSpace(n.value())
+}
+
+// descendantsOrSelf returns the node and all nodes below it in document order
+func (n *xmlNode) descendantsOrSelf() []*xmlNode {
+	nodes := []*xmlNode{n}
+	for _, child := range n.children {
+		nodes = append(nodes, child.descendantsOrSelf()...)
+	}
+	return nodes
+}
+
+// evalXPathSteps applies the steps in turn, starting from the context nodes
+func evalXPathSteps(steps []xpathStep, context []*xmlNode) []*xmlNode {
+	for _, step := range steps {
+		var next []*xmlNode
+		seen := make(map[*xmlNode]bool)
+		for _, node := range context {
+			candidates := []*xmlNode{node}
+			if step.descendant {
+				candidates = node.descendantsOrSelf()
+			}
+			for _, candidate := range candidates {
+				for _, match := range step.apply(candidate) {
+					if !seen[match] {
+						seen[match] = true
+						next = append(next, match)
+					}
+				}
+			}
+		}
+		sort.SliceStable(next, func(i, j int) bool { return next[i].order < next[j].order })
+		context = next
+	}
+	return context
+}
+
+// apply selects the step's nodes relative to a context node and filters
+// them through the predicates
+func (s xpathStep) apply(node *xmlNode) []*xmlNode {
+	var selected []*xmlNode
+	switch s.axis {
+	case axisSelf:
+		selected = []*xmlNode{node}
+	case axisParent:
+		if node.parent != nil {
+			selected = []*xmlNode{node.parent}
+		}
+	case axisText:
+		for _, child := range node.children {
+			if child.axis == axisText {
+				selected = append(selected, child)
+			}
+		}
+	case axisAttribute:
+		for _, attr := range node.attrs {
+			if s.name == "*" || attr.name == s.name {
+				selected = append(selected, attr)
+			}
+		}
+	default:
+		for _, child := range node.children {
+			if child.axis == axisChild && (s.name == "*" || child.name == s.name) {
+				selected = append(selected, child)
+			}
+		}
+	}
+
+	for _, predicate := range s.predicates {
+		var kept []*xmlNode
+		for i, candidate := range selected {
+			if matchPredicate(predicate, candidate, i+1, len(selected)) {
+				kept = append(kept, candidate)
+			}
+		}
+		selected = kept
+	}
+	return selected
+}
+
+// xpathOperators are the comparison operators, longest first so <= is not
+// read as <
+var xpathOperators = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// matchPredicate evaluates a predicate for the node at the given 1-based
+// position among size candidates
+func matchPredicate(predicate string, node *xmlNode, position, size int) bool {
+	if n, err := strconv.Atoi(predicate); err == nil {
+		return position == n
+	}
+	if predicate == "last()" {
+		return position == size
+	}
+
+	for _, function := range []string{"contains", "starts-with"} {
+		args, ok := strings.CutPrefix(predicate, function+"(")
+		if !ok || !strings.HasSuffix(args, ")") {
+			continue
+		}
+		path, literal, ok := splitFunctionArgs(strings.TrimSuffix(args, ")"))
+		if !ok {
+			return false
+		}
+		for _, value := range relativeValues(path, node) {
+			if function == "contains" && strings.Contains(value, literal) ||
+				function == "starts-with" && strings.HasPrefix(value, literal) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if path, op, literal, ok := splitComparison(predicate); ok {
+		for _, value := range relativeValues(path, node) {
+			if compareXPathValues(value, op, literal) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A bare path tests for existence
+	return len(relativeValues(predicate, node)) > 0
+}
+
+// splitFunctionArgs splits "path, 'literal'" into its arguments
+func splitFunctionArgs(args string) (string, string, bool) {
+	path, literal, ok := strings.Cut(args, ",")
+	if !ok {
+		return "", "", false
+	}
+	value, ok := unquote(strings.TrimSpace(literal))
+	return strings.TrimSpace(path), value, ok
+}
+
+// splitComparison splits "path op literal" outside string literals
+func splitComparison(predicate string) (string, string, string, bool) {
+	var quote byte
+	for i := 0; i < len(predicate); i++ {
+		c := predicate[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			continue
+		}
+		for _, op := range xpathOperators {
+			if strings.HasPrefix(predicate[i:], op) {
+				literal := strings.TrimSpace(predicate[i+len(op):])
+				if value, ok := unquote(literal); ok {
+					literal = value
+				}
+				return strings.TrimSpace(predicate[:i]), op, literal, true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+// unquote strips matching single or double quotes from a string literal
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	return s, false
+}
+
+// relativeValues evaluates a relative path from the node and returns the
+// string values of the matches
+func relativeValues(path string, node *xmlNode) []string {
+	steps, err := parseXPathSteps(path)
+	if err != nil {
+		return nil
+	}
+	var values []string
+	for _, match := range evalXPathSteps(steps, []*xmlNode{node}) {
+		values = append(values, match.value())
+	}
+	return values
+}
+
+// compareXPathValues compares numerically when both sides are numbers and
+// as strings otherwise
+func compareXPathValues(value, op, literal string) bool {
+	a, aerr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	b, berr := strconv.ParseFloat(literal, 64)
+	if aerr == nil && berr == nil {
+		switch op {
+		case "=":
+			return a == b
+		case "!=":
+			return a != b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		}
+	}
+
+	switch op {
+	case "=":
+		return value == literal
+	case "!=":
+		return value != literal
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package request
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSOAPResponse = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="http://example.com/stock">
+  <soap:Body>
+    <m:GetPriceResponse>
+      <m:Price currency="USD">34.5</m:Price>
+      <m:Items>
+        <m:Item id="1"><m:Name>apple</m:Name><m:Qty>3</m:Qty></m:Item>
+        <m:Item id="2"><m:Name>banana</m:Name><m:Qty>12</m:Qty></m:Item>
+        <m:Item id="3"><m:Name>cherry</m:Name><m:Qty>7</m:Qty></m:Item>
+      </m:Items>
+    </m:GetPriceResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestXPath_Query(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"/Envelope/Body/GetPriceResponse/Price", []string{"34.5"}},
+		{"/soap:Envelope/soap:Body/m:GetPriceResponse/m:Price", []string{"34.5"}},
+		{"//Price/@currency", []string{"USD"}},
+		{"//Price/text()", []string{"34.5"}},
+		{"//Item/Name", []string{"apple", "banana", "cherry"}},
+		{"//Item[2]/Name", []string{"banana"}},
+		{"//Item[last()]/@id", []string{"3"}},
+		{"//Item[@id='1']/Qty", []string{"3"}},
+		{"//Item[Qty>5]/Name", []string{"banana", "cherry"}},
+		{"//Item[Name!='apple'][Qty<=7]/Name", []string{"cherry"}},
+		{"//Item[contains(Name,'an')]/@id", []string{"2"}},
+		{"//Item[starts-with(Name, 'ch')]/Qty", []string{"7"}},
+		{"//Name[.='cherry']/../@id", []string{"3"}},
+		{"//Item[@id]/Name", []string{"apple", "banana", "cherry"}},
+		{"//Items/*[1]", []string{`<m:Item id="1"><m:Name>apple</m:Name><m:Qty>3</m:Qty></m:Item>`}},
+		{"count(//Item)", []string{"3"}},
+		{"count(//Missing)", []string{"0"}},
+		{"//Missing", []string{}},
+	}
+	for _, tt := range tests {
+		x, err := CompileXPath(tt.expr)
+		if err != nil {
+			t.Errorf("CompileXPath(%q) error = %v", tt.expr, err)
+			continue
+		}
+		got, err := x.Query(testSOAPResponse)
+		if err != nil {
+			t.Errorf("Query(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileXPath_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "//", "/a/", "//a[1", "//a[@id='1]", "child::a", "a b"} {
+		if _, err := CompileXPath(expr); err == nil {
+			t.Errorf("CompileXPath(%q) expected an error", expr)
+		}
+	}
+}
+
+func TestXPath_QueryNotXML(t *testing.T) {
+	x, err := CompileXPath("//a")
+	if err != nil {
+		t.Fatalf("CompileXPath() error = %v", err)
+	}
+	for _, body := range []string{`{"a": 1}`, "", "<a><b></a>"} {
+		if _, err := x.Query(body); err == nil {
+			t.Errorf("Query(%q) expected an error", body)
+		}
+	}
+}
+
+func TestResponseData_IsXML(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"application/xml", "<a/>", true},
+		{"text/xml; charset=utf-8", "<a/>", true},
+		{"application/soap+xml", "<a/>", true},
+		{"application/json", `{"a": 1}`, false},
+		{"text/plain", "<?xml version=\"1.0\"?><a/>", true},
+		{"text/html", "<html></html>", false},
+	}
+	for _, tt := range tests {
+		resp := &ResponseData{Headers: map[string]string{"Content-Type": tt.contentType}, Body: tt.body}
+		if got := resp.IsXML(); got != tt.want {
+			t.Errorf("IsXML() for %s = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}